@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/AlexTLDR/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+// accessTokenResponse is the access token half of a login or refresh
+// response. Token is the original field; AccessToken, TokenType and
+// ExpiresIn repeat it in the shape OAuth clients expect.
+type accessTokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	// ExpiresIn is the token's lifetime in seconds
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// makeAccessToken issues userID an access token valid for
+// cfg.accessTokenTTL, limited to scopes unless it is nil.
+func (cfg *apiConfig) makeAccessToken(userID uuid.UUID, scopes []string) (accessTokenResponse, error) {
+	token, err := auth.MakeJWT(userID, cfg.jwtSecrets[0], cfg.accessTokenTTL, scopes...)
+	if err != nil {
+		return accessTokenResponse{}, err
+	}
+	return accessTokenResponse{
+		Token:       token,
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(cfg.accessTokenTTL.Seconds()),
+	}, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"html/template"
+	"time"
+)
+
+//go:embed admin_metrics.html
+var adminMetricsHTML string
+
+// metricsTemplate renders the HTML form of GET /admin/metrics. Paths come
+// straight from request URLs; html/template escapes them.
+var metricsTemplate = template.Must(template.New("metrics").Parse(adminMetricsHTML))
+
+// Uptime is how long the server has been running, to the second.
+func (m Metrics) Uptime() time.Duration {
+	return time.Duration(m.UptimeSeconds) * time.Second
+}
+
+// MeanLatency is the average time a call to the query took.
+func (q QueryMetrics) MeanLatency() time.Duration {
+	if q.Calls == 0 {
+		return 0
+	}
+	mean := time.Duration(q.TotalSeconds / float64(q.Calls) * float64(time.Second))
+	return mean.Round(time.Microsecond)
+}
+
+// metricsTotal runs one of the aggregate queries behind the metrics page.
+// A failure is logged and yields nil, shown as n/a, so a struggling
+// database does not take the page down with it.
+func metricsTotal(ctx context.Context, name string, count func(context.Context) (int64, error)) *int64 {
+	n, err := count(ctx)
+	if err != nil {
+		logf(ctx, "Counting %s for the metrics page: %v", name, err)
+		return nil
+	}
+	return &n
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	auditReset          = "reset"
+	auditChirpDelete    = "chirp.delete"
+	auditChirpPurge     = "chirp.purge"
+	auditReportsDismiss = "reports.dismiss"
+	auditReportsDelete  = "reports.delete"
+	auditUserUpgrade    = "user.upgrade"
+	auditAdminGrant     = "admin.grant"
+	auditAdminRevoke    = "admin.revoke"
+	auditUserBan        = "user.ban"
+	auditUserUnban      = "user.unban"
+	auditUserDelete     = "user.delete"
+	// auditRefreshTokenReuse is not an action the actor chose but a
+	// security event: a revoked refresh token of theirs was presented
+	auditRefreshTokenReuse = "refresh_token.reuse"
+)
+
+// auditActorPolka identifies the Polka webhook, which acts with an API key
+// rather than as a user.
+const auditActorPolka = "polka"
+
+// auditEntry records a privileged action. The actor is either a user
+// (ActorID) or a non-user caller identified by ActorToken.
+type auditEntry struct {
+	ActorID    uuid.UUID
+	ActorToken string
+	Action     string
+	Target     string
+}
+
+// audit writes entry to the audit log on behalf of request r. The action it
+// describes has already happened, so failures are only logged.
+func (cfg *apiConfig) audit(r *http.Request, entry auditEntry) {
+	cfg.writeAudit(r.Context(), requestID(r.Context()), entry)
+}
+
+// writeAudit is audit for work done outside a request, such as by the
+// webhook worker, where there may be no request ID.
+func (cfg *apiConfig) writeAudit(ctx context.Context, requestID string, entry auditEntry) {
+	err := cfg.dbQueries.CreateAuditLogEntry(ctx, database.CreateAuditLogEntryParams{
+		ActorID:    uuid.NullUUID{UUID: entry.ActorID, Valid: entry.ActorID != uuid.Nil},
+		ActorToken: sql.NullString{String: entry.ActorToken, Valid: entry.ActorToken != ""},
+		Action:     entry.Action,
+		Target:     entry.Target,
+		RequestID:  requestID,
+	})
+	if err != nil {
+		logf(ctx, "Error writing %s audit entry for %s: %v", entry.Action, entry.Target, err)
+	}
+}
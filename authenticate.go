@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/AlexTLDR/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+// requireUser authenticates the request's bearer access token and returns
+// the caller's user ID. On failure it writes a 401 response and returns
+// false, so handlers can simply return.
+func (cfg *apiConfig) requireUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, ok := cfg.viewerID(r)
+	if !ok {
+		cfg.respondUnauthorized(w, r)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// Error codes of a 401 caused by sending one kind of token where the other
+// is expected.
+const (
+	errCodeExpectedAccessToken  = "expected_access_token"
+	errCodeExpectedRefreshToken = "expected_refresh_token"
+)
+
+// respondUnauthorized writes the 401 for a request without a valid access
+// token. A refresh token sent in its place is called out with
+// errCodeExpectedAccessToken, so clients can tell that mistake apart from
+// an expired or forged token.
+func (cfg *apiConfig) respondUnauthorized(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err == nil {
+		_, err = auth.ValidateJWTWithSecrets(token, cfg.jwtSecrets)
+		if errors.Is(err, auth.ErrNotAccessToken) {
+			respondWithErrorCode(w, r, http.StatusUnauthorized, errCodeExpectedAccessToken, "Expected an access token")
+			return
+		}
+	}
+	respondWithErrorCode(w, r, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+}
+
+// requireRefreshToken returns the request's bearer refresh token. On
+// failure it writes a 401 and returns false; an access token sent in its
+// place gets errCodeExpectedRefreshToken.
+func requireRefreshToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithErrorCode(w, r, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return "", false
+	}
+	if auth.IsJWT(token) {
+		respondWithErrorCode(w, r, http.StatusUnauthorized, errCodeExpectedRefreshToken, "Expected a refresh token, not an access token")
+		return "", false
+	}
+	return token, true
+}
+
+// requireAdmin guards the /admin endpoints and other privileged actions.
+// The admin flag is read from the database on every request rather than
+// trusted from the token, so revoking it takes effect immediately. On
+// failure it writes a 401, 403 or 500 response and returns false.
+func (cfg *apiConfig) requireAdmin(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	isAdmin, err := cfg.isAdmin(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return uuid.Nil, false
+	}
+	if !isAdmin {
+		respondWithError(w, r, http.StatusForbidden, "Forbidden")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// isAdmin reports whether userID belongs to an admin. A user that no longer
+// exists is not one; any other lookup error is returned.
+func (cfg *apiConfig) isAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return user.IsAdmin, err
+}
+
+// viewerID returns the ID of the user making the request when a valid
+// access token is presented, or an API key on routes wrapped in
+// middlewareAPIKey. Anonymous requests are not an error.
+func (cfg *apiConfig) viewerID(r *http.Request) (uuid.UUID, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return cfg.apiKeyUserID(r)
+	}
+
+	claims, err := auth.ValidateJWTWithSecrets(token, cfg.jwtSecrets)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return claims.UserID, true
+}
+
+// errCodeInsufficientScope is the error code of a request whose access
+// token lacks the scope its route requires.
+const errCodeInsufficientScope = "insufficient_scope"
+
+// requireScope rejects requests whose access token does not grant scope
+// with a 403 naming it, and requests by a banned or deleted user, whether
+// with an access token or an API key (see requireActiveAccount). Requests
+// without valid credentials are passed through: the handler decides
+// whether anonymous callers or API keys are allowed.
+func (cfg *apiConfig) requireScope(scope string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err == nil {
+			claims, err := auth.ValidateJWTWithSecrets(token, cfg.jwtSecrets)
+			if err == nil && !claims.HasScope(scope) {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scope))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(ErrorResponse{
+					Error:     localizeError(w, r, errCodeInsufficientScope, fmt.Sprintf("Access token lacks the %s scope", scope), scope),
+					RequestID: requestID(r.Context()),
+					Code:      errCodeInsufficientScope,
+					Scope:     scope,
+				})
+				return
+			}
+		}
+		if userID, ok := cfg.viewerID(r); ok && !cfg.requireActiveAccount(w, r, userID) {
+			return
+		}
+		next(w, r)
+	})
+}
+
+type apiKeyAllowedKey struct{}
+
+// middlewareAPIKey lets the handlers behind it authenticate callers with
+// "Authorization: ApiKey <key>" as well as an access token. Keys are meant
+// for bots posting and reading chirps, so they are not accepted elsewhere;
+// in particular a key cannot be used to manage keys or the account.
+func middlewareAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyAllowedKey{}, true)))
+	})
+}
+
+// apiKeyUserID resolves the request's API key to the owner's ID, if the
+// route accepts API keys and the key exists.
+func (cfg *apiConfig) apiKeyUserID(r *http.Request) (uuid.UUID, bool) {
+	if allowed, _ := r.Context().Value(apiKeyAllowedKey{}).(bool); !allowed {
+		return uuid.Nil, false
+	}
+	key, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	userID, err := cfg.dbQueries.GetUserIDByAPIKey(r.Context(), auth.HashAPIKey(key))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logf(r.Context(), "Error looking up API key: %v", err)
+		}
+		return uuid.Nil, false
+	}
+	return userID, true
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// chirpListCache keeps recent GET /api/chirps results in memory, keyed by
+// the query parameters that shape the list. Entries are viewer-independent:
+// liked_by_me is filled in per request after a lookup. A nil cache, or one
+// with a zero TTL, caches nothing.
+type chirpListCache struct {
+	ttl time.Duration
+
+	mu          sync.Mutex
+	entries     map[string]chirpListEntry
+	generation  uint64
+	invalidated time.Time
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type chirpListEntry struct {
+	chirps  []Chirp
+	expires time.Time
+}
+
+func newChirpListCache(ttl time.Duration) *chirpListCache {
+	return &chirpListCache{
+		ttl:     ttl,
+		entries: make(map[string]chirpListEntry),
+	}
+}
+
+// get returns a copy of the cached list for key. On a miss it returns the
+// current generation, which must be passed back to put so a list read
+// before a concurrent write is never stored after that write's invalidate.
+func (c *chirpListCache) get(key string) (chirps []Chirp, generation uint64, ok bool) {
+	if c == nil || c.ttl <= 0 {
+		return nil, 0, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		c.misses.Add(1)
+		return nil, c.generation, false
+	}
+
+	c.hits.Add(1)
+	return slices.Clone(entry.chirps), c.generation, true
+}
+
+// put stores chirps under key unless the cache was invalidated since the
+// matching get.
+func (c *chirpListCache) put(key string, generation uint64, chirps []Chirp) {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	c.entries[key] = chirpListEntry{
+		chirps:  slices.Clone(chirps),
+		expires: time.Now().Add(c.ttl),
+	}
+}
+
+// invalidate drops every cached list. Call it after any successful write
+// that can change what GET /api/chirps returns, including ones that only
+// change who may see a chirp: the time of the call also dates the lists.
+func (c *chirpListCache) invalidate() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.invalidated = time.Now()
+	clear(c.entries)
+}
+
+// lastInvalidated returns when invalidate was last called, or the zero time
+// if it has not been since the server started. Only writes made through this
+// server count.
+func (c *chirpListCache) lastInvalidated() time.Time {
+	if c == nil {
+		return time.Time{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.invalidated
+}
+
+// stats returns the hit and miss counters for the metrics page.
+func (c *chirpListCache) stats() (hits, misses int64) {
+	if c == nil {
+		return 0, 0
+	}
+	return c.hits.Load(), c.misses.Load()
+}
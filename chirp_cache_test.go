@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestChirpListCacheInvalidation(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+	db.addChirp(t, user.ID, "First")
+
+	listLen := func() int {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		cfg.handlerGetChirps(rr, httptest.NewRequest(http.MethodGet, "/api/chirps", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		return len(decodeChirpIDs(t, rr))
+	}
+
+	if n := listLen(); n != 1 {
+		t.Fatalf("got %d chirps, want 1", n)
+	}
+
+	// A write that bypasses the handlers is not seen while the entry is fresh
+	db.addChirp(t, user.ID, "Sneaky")
+	if n := listLen(); n != 1 {
+		t.Fatalf("got %d chirps, want the cached 1", n)
+	}
+	if hits, misses := cfg.chirpCache.stats(); hits != 1 || misses != 1 {
+		t.Errorf("stats = %d hits, %d misses, want 1 and 1", hits, misses)
+	}
+
+	// Creating a chirp through the handler invalidates the cache
+	postChirp(t, cfg, token, map[string]any{"body": "Third"})
+	if n := listLen(); n != 3 {
+		t.Errorf("got %d chirps after create, want 3", n)
+	}
+
+	// Cached entries never carry another viewer's liked_by_me
+	rr := httptest.NewRecorder()
+	cfg.handlerGetChirps(rr, httptest.NewRequest(http.MethodGet, "/api/chirps?sort=desc", nil))
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps?sort=desc", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	cfg.handlerGetChirps(rr, req)
+	rr = httptest.NewRecorder()
+	cfg.handlerGetChirps(rr, httptest.NewRequest(http.MethodGet, "/api/chirps?sort=desc", nil))
+	var chirps []Chirp
+	if err := json.NewDecoder(rr.Body).Decode(&chirps); err != nil {
+		t.Fatalf("Failed to decode chirps: %v", err)
+	}
+	for _, chirp := range chirps {
+		if chirp.LikedByMe != nil {
+			t.Fatalf("anonymous response leaked liked_by_me from a cached entry")
+		}
+	}
+}
+
+func TestChirpListCacheConcurrentAccess(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				rr := httptest.NewRecorder()
+				cfg.handlerGetChirps(rr, httptest.NewRequest(http.MethodGet, "/api/chirps", nil))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			postChirp(t, cfg, token, map[string]any{"body": "concurrent"})
+		}()
+	}
+	wg.Wait()
+
+	rr := httptest.NewRecorder()
+	cfg.handlerGetChirps(rr, httptest.NewRequest(http.MethodGet, "/api/chirps", nil))
+	if n := len(decodeChirpIDs(t, rr)); n != 8 {
+		t.Errorf("got %d chirps after all writes, want 8", n)
+	}
+}
+
+func TestChirpListCacheDisabled(t *testing.T) {
+	var nilCache *chirpListCache
+	nilCache.put("k", 0, []Chirp{{}})
+	if _, _, ok := nilCache.get("k"); ok {
+		t.Error("nil cache should never hit")
+	}
+
+	cache := newChirpListCache(0)
+	_, generation, _ := cache.get("k")
+	cache.put("k", generation, []Chirp{{}})
+	if _, _, ok := cache.get("k"); ok {
+		t.Error("zero-TTL cache should never hit")
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// chirpField is a field of Chirp that ?fields= may select, by its JSON
+// name.
+type chirpField struct {
+	name  string
+	value func(c *Chirp) any
+}
+
+// chirpFields are the selectable fields in Chirp's order. Only these are
+// ever copied into a sparse chirp.
+var chirpFields = []chirpField{
+	{"id", func(c *Chirp) any { return c.ID }},
+	{"created_at", func(c *Chirp) any { return c.CreatedAt }},
+	{"updated_at", func(c *Chirp) any { return c.UpdatedAt }},
+	{"body", func(c *Chirp) any { return c.Body }},
+	{"user_id", func(c *Chirp) any { return c.UserID }},
+	{"parent_chirp_id", func(c *Chirp) any { return c.ParentChirpID }},
+	{"edited", func(c *Chirp) any { return c.Edited }},
+	{"like_count", func(c *Chirp) any { return c.LikeCount }},
+	{"reply_count", func(c *Chirp) any { return c.ReplyCount }},
+	{"view_count", func(c *Chirp) any { return c.ViewCount }},
+	{"media_url", func(c *Chirp) any { return c.MediaURL }},
+	{"media_alt", func(c *Chirp) any { return c.MediaAlt }},
+	{"mentions", func(c *Chirp) any { return c.Mentions }},
+	{"liked_by_me", func(c *Chirp) any { return c.LikedByMe }},
+	{"entities", func(c *Chirp) any { return c.Entities }},
+	{"author", func(c *Chirp) any { return c.Author }},
+}
+
+// parseChirpFields reads the fields query parameter, a comma-separated list
+// of chirp fields to return instead of the whole chirp, as indexes into
+// chirpFields. It returns nil when the parameter is absent.
+func parseChirpFields(r *http.Request) ([]int, error) {
+	value := r.URL.Query().Get("fields")
+	if value == "" {
+		return nil, nil
+	}
+
+	var fields []int
+	for _, name := range strings.Split(value, ",") {
+		i := slices.IndexFunc(chirpFields, func(f chirpField) bool { return f.name == name })
+		if i < 0 {
+			names := make([]string, len(chirpFields))
+			for j, f := range chirpFields {
+				names[j] = f.name
+			}
+			return nil, fmt.Errorf("Invalid fields: %q is not a chirp field; valid fields are %s", name, strings.Join(names, ", "))
+		}
+		if !slices.Contains(fields, i) {
+			fields = append(fields, i)
+		}
+	}
+	return fields, nil
+}
+
+// sparseChirp returns chirp as the response body, reduced to fields unless
+// they are nil.
+func sparseChirp(chirp *Chirp, fields []int) any {
+	if fields == nil {
+		return chirp
+	}
+	sparse := make(map[string]any, len(fields))
+	for _, i := range fields {
+		sparse[chirpFields[i].name] = chirpFields[i].value(chirp)
+	}
+	return sparse
+}
+
+// sparseChirps is sparseChirp for a list of chirps.
+func sparseChirps(chirps []Chirp, fields []int) any {
+	if fields == nil {
+		return chirps
+	}
+	sparse := make([]any, len(chirps))
+	for i := range chirps {
+		sparse[i] = sparseChirp(&chirps[i], fields)
+	}
+	return sparse
+}
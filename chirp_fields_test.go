@@ -0,0 +1,73 @@
+package main
+
+import (
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChirpFields(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	user := db.addUser(t, "alice@example.com")
+	token := makeTestToken(t, user.ID)
+	chirp := db.addChirp(t, user.ID, "See https://example.com")
+	db.addChirp(t, user.ID, "Another one")
+
+	t.Run("subset", func(t *testing.T) {
+		var chirps []map[string]any
+		if resp := call(t, srv, http.MethodGet, "/api/chirps?fields=id,body,created_at", "", nil, &chirps); resp.StatusCode != http.StatusOK {
+			t.Fatalf("got status %v want %v", resp.StatusCode, http.StatusOK)
+		}
+		if len(chirps) != 2 {
+			t.Fatalf("got %d chirps, want 2", len(chirps))
+		}
+		for _, c := range chirps {
+			if keys := slices.Sorted(maps.Keys(c)); !slices.Equal(keys, []string{"body", "created_at", "id"}) {
+				t.Errorf("got fields %v, want body, created_at and id", keys)
+			}
+		}
+
+		var one map[string]any
+		if resp := call(t, srv, http.MethodGet, "/api/chirps/"+chirp.ID.String()+"?fields=body", "", nil, &one); resp.StatusCode != http.StatusOK {
+			t.Fatalf("single chirp: got status %v want %v", resp.StatusCode, http.StatusOK)
+		}
+		if len(one) != 1 || one["body"] != chirp.Body {
+			t.Errorf("single chirp = %v, want only body %q", one, chirp.Body)
+		}
+	})
+
+	t.Run("full set", func(t *testing.T) {
+		// With a viewer and ?include=author no field is omitted, so asking
+		// for all of them must give the same chirps as asking for none
+		names := make([]string, len(chirpFields))
+		for i, f := range chirpFields {
+			names[i] = f.name
+		}
+		for _, path := range []string{"/api/chirps", "/api/chirps/" + chirp.ID.String()} {
+			var full, sparse any
+			call(t, srv, http.MethodGet, path+"?include=author", token, nil, &full)
+			if resp := call(t, srv, http.MethodGet, path+"?include=author&fields="+strings.Join(names, ","), token, nil, &sparse); resp.StatusCode != http.StatusOK {
+				t.Fatalf("GET %s: got status %v want %v", path, resp.StatusCode, http.StatusOK)
+			}
+			if !reflect.DeepEqual(full, sparse) {
+				t.Errorf("GET %s: all fields gave\n%v\nwant\n%v", path, sparse, full)
+			}
+		}
+	})
+
+	t.Run("invalid field", func(t *testing.T) {
+		for _, path := range []string{"/api/chirps", "/api/chirps/" + chirp.ID.String()} {
+			var errResp ErrorResponse
+			if resp := call(t, srv, http.MethodGet, path+"?fields=id,hashed_password", "", nil, &errResp); resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("GET %s: got status %v want %v", path, resp.StatusCode, http.StatusBadRequest)
+			}
+			if !strings.Contains(errResp.Error, `"hashed_password"`) || !strings.Contains(errResp.Error, "id, created_at, updated_at, body") {
+				t.Errorf("GET %s: error %q does not name the field and the valid set", path, errResp.Error)
+			}
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// chirpSubscriberBuffer is how many chirps may queue up for a single
+// subscriber before it is considered too slow and evicted.
+const chirpSubscriberBuffer = 16
+
+// chirpHub fans newly created chirps out to live subscribers (WebSocket and
+// SSE clients) within this process.
+type chirpHub struct {
+	mu          sync.Mutex
+	subscribers map[*chirpSubscriber]struct{}
+	closed      bool
+}
+
+// chirpSubscriber receives chirps on C until the channel is closed, which
+// happens when the subscriber unsubscribes, falls too far behind, or the
+// hub shuts down.
+type chirpSubscriber struct {
+	C        chan Chirp
+	authorID uuid.NullUUID
+}
+
+func newChirpHub() *chirpHub {
+	return &chirpHub{
+		subscribers: make(map[*chirpSubscriber]struct{}),
+	}
+}
+
+// subscribe registers a new subscriber, optionally only interested in chirps
+// by authorID. It returns false once the hub has been closed.
+func (h *chirpHub) subscribe(authorID uuid.NullUUID) (*chirpSubscriber, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, false
+	}
+
+	sub := &chirpSubscriber{
+		C:        make(chan Chirp, chirpSubscriberBuffer),
+		authorID: authorID,
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub, true
+}
+
+// unsubscribe removes sub from the hub. It is safe to call more than once
+// and after the subscriber has been evicted.
+func (h *chirpHub) unsubscribe(sub *chirpSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(sub)
+}
+
+// publish delivers chirp to every matching subscriber without blocking.
+// Subscribers whose buffer is full are evicted so one slow client cannot
+// hold up chirp creation. A nil hub drops the chirp.
+func (h *chirpHub) publish(chirp Chirp) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		if sub.authorID.Valid && sub.authorID.UUID != chirp.UserID {
+			continue
+		}
+		select {
+		case sub.C <- chirp:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// close disconnects every subscriber and rejects new ones. It is meant to
+// be registered with http.Server.RegisterOnShutdown, since hijacked and
+// streaming connections are not drained by Shutdown.
+func (h *chirpHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subscribers {
+		h.remove(sub)
+	}
+}
+
+// remove must be called with h.mu held.
+func (h *chirpHub) remove(sub *chirpSubscriber) {
+	if _, ok := h.subscribers[sub]; !ok {
+		return
+	}
+	delete(h.subscribers, sub)
+	close(sub.C)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// maxMediaURLLength caps the image URL a chirp may link to
+	maxMediaURLLength = 2048
+	// maxMediaAltLength caps the image alt text, in characters
+	maxMediaAltLength = 300
+)
+
+// validateChirpMedia checks the image a new chirp links to and its alt
+// text, adding any problems to invalid, and returns the values to store.
+// The URL must be an absolute http or https URL, or with
+// chirpMediaLocalOnly a file uploaded to this server, given either as a
+// /media/ path like avatar_url or as a URL on this server; either way the
+// path is stored, so the file is known to be in use. Alt text is cleaned of
+// profanity like the body, and needs an image to describe.
+func (cfg *apiConfig) validateChirpMedia(r *http.Request, invalid *fieldErrors, mediaURL, mediaAlt string) (sql.NullString, sql.NullString) {
+	if mediaURL == "" {
+		if mediaAlt != "" {
+			invalid.add("media_alt", "media_alt", "requires media_url")
+		}
+		return sql.NullString{}, sql.NullString{}
+	}
+
+	mediaURL, reason := cfg.checkMediaURL(r, mediaURL)
+	if reason != "" {
+		invalid.add("media_url", "media_url", reason)
+	}
+	if err := validateProfileText(mediaAlt, maxMediaAltLength); err != nil {
+		invalid.add("media_alt", "media_alt", err.Error())
+	}
+	alt := cleanProfanity(mediaAlt)
+	return sql.NullString{String: mediaURL, Valid: true}, sql.NullString{String: alt, Valid: alt != ""}
+}
+
+// checkMediaURL returns the value to store for a chirp's image at
+// mediaURL, or why it cannot be one.
+func (cfg *apiConfig) checkMediaURL(r *http.Request, mediaURL string) (stored, reason string) {
+	if len(mediaURL) > maxMediaURLLength {
+		return "", "is too long"
+	}
+	if cfg.chirpMediaLocalOnly {
+		name, ok := strings.CutPrefix(mediaURL, mediaURLPrefix)
+		if !ok {
+			name, ok = strings.CutPrefix(mediaURL, cfg.baseURL(r)+mediaURLPrefix)
+		}
+		if !ok || !mediaFileName.MatchString(name) {
+			return "", "must be an image uploaded to this server under " + mediaURLPrefix
+		}
+		return mediaURLPrefix + name, ""
+	}
+
+	u, err := url.Parse(mediaURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "must be an absolute http or https URL"
+	}
+	return mediaURL, ""
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestChirpMedia(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	user := db.addUser(t, "alice@example.com")
+	token := makeTestToken(t, user.ID)
+
+	rr := postChirp(t, cfg, token, map[string]string{
+		"body":      "Look at this",
+		"media_url": "https://images.example.com/cat.jpg",
+		"media_alt": "A cat in a kerfuffle",
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create: got status %v want %v: %s", rr.Code, http.StatusCreated, rr.Body)
+	}
+	var created Chirp
+	json.NewDecoder(rr.Body).Decode(&created)
+	if created.MediaURL == nil || *created.MediaURL != "https://images.example.com/cat.jpg" {
+		t.Errorf("media_url = %v, want the given URL", created.MediaURL)
+	}
+	if created.MediaAlt == nil || *created.MediaAlt != "A cat in a ****" {
+		t.Errorf("media_alt = %v, want it cleaned of profanity", created.MediaAlt)
+	}
+
+	got, code := getChirp(t, cfg, created.ID.String())
+	if code != http.StatusOK || got.MediaURL == nil || *got.MediaURL != *created.MediaURL || got.MediaAlt == nil || *got.MediaAlt != *created.MediaAlt {
+		t.Errorf("detail: got status %v with %v and %v, want the stored media", code, got.MediaURL, got.MediaAlt)
+	}
+
+	// Chirps without an image have null fields rather than missing ones
+	plain := db.addChirp(t, user.ID, "No picture")
+	var listed []map[string]any
+	call(t, srv, http.MethodGet, "/api/chirps", "", nil, &listed)
+	if len(listed) != 2 {
+		t.Fatalf("listed %d chirps, want 2", len(listed))
+	}
+	for _, c := range listed {
+		wantURL, wantAlt := any("https://images.example.com/cat.jpg"), any("A cat in a ****")
+		if c["id"] == plain.ID.String() {
+			wantURL, wantAlt = nil, nil
+		}
+		if url, ok := c["media_url"]; !ok || url != wantURL {
+			t.Errorf("chirp %v: media_url = %v, want %v", c["id"], url, wantURL)
+		}
+		if alt, ok := c["media_alt"]; !ok || alt != wantAlt {
+			t.Errorf("chirp %v: media_alt = %v, want %v", c["id"], alt, wantAlt)
+		}
+	}
+}
+
+func TestChirpMediaRejected(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "alice@example.com")
+	token := makeTestToken(t, user.ID)
+
+	tests := []struct {
+		name    string
+		payload map[string]string
+		field   string
+	}{
+		{"relative URL", map[string]string{"media_url": "cat.jpg"}, "media_url"},
+		{"other scheme", map[string]string{"media_url": "javascript:alert(1)"}, "media_url"},
+		{"no host", map[string]string{"media_url": "https:///cat.jpg"}, "media_url"},
+		{"URL too long", map[string]string{"media_url": "https://example.com/" + strings.Repeat("a", maxMediaURLLength)}, "media_url"},
+		{"alt too long", map[string]string{"media_url": "https://example.com/cat.jpg", "media_alt": strings.Repeat("a", maxMediaAltLength+1)}, "media_alt"},
+		{"alt control characters", map[string]string{"media_url": "https://example.com/cat.jpg", "media_alt": "A\x1b[2Jcat"}, "media_alt"},
+		{"alt without an image", map[string]string{"media_alt": "A cat"}, "media_alt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.payload["body"] = "Look at this"
+			rr := postChirp(t, cfg, token, tt.payload)
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("got status %v want %v", rr.Code, http.StatusBadRequest)
+			}
+			var resp ErrorResponse
+			json.NewDecoder(rr.Body).Decode(&resp)
+			if !strings.HasPrefix(resp.Error, tt.field+" ") {
+				t.Errorf("error = %q, want it to name %s", resp.Error, tt.field)
+			}
+		})
+	}
+
+	// Exactly at the cap is fine
+	rr := postChirp(t, cfg, token, map[string]string{"body": "Look", "media_url": "https://example.com/cat.jpg", "media_alt": strings.Repeat("é", maxMediaAltLength)})
+	if rr.Code != http.StatusCreated {
+		t.Errorf("alt at the cap: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+}
+
+func TestChirpMediaLocalOnly(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.chirpMediaLocalOnly = true
+	cfg.publicURL = "https://chirpy.example.com"
+	user := db.addUser(t, "alice@example.com")
+	token := makeTestToken(t, user.ID)
+	avatarURL := *decodeUser(t, uploadAvatar(t, cfg, token, testPNG(t, 0))).AvatarURL
+
+	for _, mediaURL := range []string{avatarURL, cfg.publicURL + avatarURL} {
+		rr := postChirp(t, cfg, token, map[string]string{"body": "My new avatar", "media_url": mediaURL})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("%s: got status %v want %v", mediaURL, rr.Code, http.StatusCreated)
+		}
+		var chirp Chirp
+		json.NewDecoder(rr.Body).Decode(&chirp)
+		if chirp.MediaURL == nil || *chirp.MediaURL != avatarURL {
+			t.Errorf("%s: stored %v, want the path %s", mediaURL, chirp.MediaURL, avatarURL)
+		}
+	}
+
+	for _, mediaURL := range []string{
+		"https://images.example.com/cat.jpg",
+		"https://elsewhere.example.com" + avatarURL,
+		"/media/../secret.png",
+		"/media/cat.jpg",
+	} {
+		if rr := postChirp(t, cfg, token, map[string]string{"body": "Look", "media_url": mediaURL}); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %v want %v", mediaURL, rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	// A chirp showing the file keeps it when the avatar moves on
+	uploadAvatar(t, cfg, token, testPNG(t, 200))
+	if rr := fetchMedia(t, cfg, avatarURL); rr.Code != http.StatusOK {
+		t.Errorf("file still used by a chirp: got status %v want %v", rr.Code, http.StatusOK)
+	}
+}
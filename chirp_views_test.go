@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// viewCount reads a chirp's flushed view count straight from the fake.
+func viewCount(db *fakeStore, chirpID uuid.UUID) int64 {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, c := range db.chirps {
+		if c.ID == chirpID {
+			return c.ViewCount
+		}
+	}
+	return -1
+}
+
+// brokenViewStore fails every view flush, as a lost connection would.
+type brokenViewStore struct {
+	store
+}
+
+func (brokenViewStore) AddChirpViews(ctx context.Context, arg database.AddChirpViewsParams) error {
+	return errors.New("connection reset by peer")
+}
+
+func TestChirpViewCounterFlush(t *testing.T) {
+	db := newFakeStore()
+	user := db.addUser(t, "alice@example.com")
+	first := db.addChirp(t, user.ID, "First")
+	second := db.addChirp(t, user.ID, "Second")
+	views := newChirpViewCounter(db, time.Hour)
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			views.record(first.ID, second.ID)
+			views.record(first.ID)
+		}()
+	}
+	wg.Wait()
+	if got := viewCount(db, first.ID); got != 0 {
+		t.Fatalf("views were written before a flush: %d", got)
+	}
+
+	views.flush(t.Context())
+	if a, b := viewCount(db, first.ID), viewCount(db, second.ID); a != 100 || b != 50 {
+		t.Errorf("after flush: %d and %d views, want 100 and 50", a, b)
+	}
+
+	// Flushed views are not written again, and failed flushes keep theirs
+	views.record(second.ID)
+	views.db = brokenViewStore{db}
+	views.flush(t.Context())
+	views.db = db
+	views.flush(t.Context())
+	views.flush(t.Context())
+	if a, b := viewCount(db, first.ID), viewCount(db, second.ID); a != 100 || b != 51 {
+		t.Errorf("after a failed flush and a retry: %d and %d views, want 100 and 51", a, b)
+	}
+}
+
+func TestChirpViewCounterWorker(t *testing.T) {
+	db := newFakeStore()
+	user := db.addUser(t, "alice@example.com")
+	chirp := db.addChirp(t, user.ID, "Watched")
+
+	t.Run("ticker", func(t *testing.T) {
+		views := newChirpViewCounter(db, 10*time.Millisecond)
+		views.start()
+		defer views.stop()
+		views.record(chirp.ID)
+
+		deadline := time.Now().Add(2 * time.Second)
+		for viewCount(db, chirp.ID) != 1 {
+			if time.Now().After(deadline) {
+				t.Fatal("the view was not flushed by the ticker")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		views := newChirpViewCounter(db, time.Hour)
+		views.start()
+		views.record(chirp.ID, chirp.ID)
+		views.stop()
+		if got := viewCount(db, chirp.ID); got != 3 {
+			t.Errorf("after stop: %d views, want 3", got)
+		}
+	})
+}
+
+func TestChirpViewCount(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.chirpViews = newChirpViewCounter(db, time.Hour)
+	srv := newTestServer(t, cfg)
+	user := db.addUser(t, "alice@example.com")
+	chirp := db.addChirp(t, user.ID, "Popular")
+	path := "/api/chirps/" + chirp.ID.String()
+
+	for range 2 {
+		call(t, srv, http.MethodGet, path, "", nil, nil)
+	}
+	call(t, srv, http.MethodHead, path, "", nil, nil)
+	// Lists only count with countListViews
+	call(t, srv, http.MethodGet, "/api/chirps", "", nil, nil)
+	cfg.chirpViews.flush(t.Context())
+
+	var got map[string]any
+	if resp := call(t, srv, http.MethodGet, path, "", nil, &got); resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if got["view_count"] != 2.0 {
+		t.Errorf("view_count = %v, want 2", got["view_count"])
+	}
+
+	cfg.countListViews = true
+	call(t, srv, http.MethodGet, "/api/chirps", "", nil, nil)
+	cfg.chirpViews.flush(t.Context())
+	// The cached list is from before the flush. The fetch above counted
+	// as a view too.
+	cfg.chirpCache.invalidate()
+	var chirps []Chirp
+	call(t, srv, http.MethodGet, "/api/chirps", "", nil, &chirps)
+	if len(chirps) != 1 || chirps[0].ViewCount != 4 {
+		t.Errorf("listed %+v, want the chirp with 4 views", chirps)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// cleanupWorker periodically deletes rows that are only kept for a while,
+// such as expired idempotency keys and login links, and old login history.
+type cleanupWorker struct {
+	db       store
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newCleanupWorker(db store) *cleanupWorker {
+	return &cleanupWorker{db: db, interval: time.Hour}
+}
+
+// start runs the worker until stop is called.
+func (w *cleanupWorker) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			w.runOnce(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stop shuts the worker down and waits for a running cleanup to finish.
+func (w *cleanupWorker) stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	<-w.done
+}
+
+// runOnce deletes everything that has expired. Each step runs even if an
+// earlier one failed.
+func (w *cleanupWorker) runOnce(ctx context.Context) {
+	n, err := w.db.DeleteExpiredIdempotencyKeys(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error deleting expired idempotency keys: %v", err)
+		}
+	} else if n > 0 {
+		log.Printf("Deleted %d expired idempotency keys", n)
+	}
+
+	n, err = w.db.DeleteExpiredMagicLinkTokens(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error deleting expired login links: %v", err)
+		}
+	} else if n > 0 {
+		log.Printf("Deleted %d expired login links", n)
+	}
+
+	n, err = w.db.PruneLoginHistory(ctx, loginHistoryLimit)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error pruning login history: %v", err)
+		}
+	} else if n > 0 {
+		log.Printf("Pruned %d old login history entries", n)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// errCodeDatabaseUnavailable is the error code of requests turned away
+// while the database is down.
+const errCodeDatabaseUnavailable = "database_unavailable"
+
+// pinger checks the database connection. *sql.DB satisfies it.
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// dbStatus is the outcome of the latest database ping.
+type dbStatus struct {
+	OK bool
+	// Err is the ping error while the database is down
+	Err string
+	// Since is when the database went down or came back
+	Since     time.Time
+	CheckedAt time.Time
+}
+
+// dbHealth pings the database periodically so requests can be refused
+// quickly while it is down, rather than each one waiting for a connection
+// to time out. The status flips back on the first successful ping.
+type dbHealth struct {
+	db       pinger
+	interval time.Duration
+	timeout  time.Duration
+	status   atomic.Pointer[dbStatus]
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newDBHealth(db pinger) *dbHealth {
+	return &dbHealth{db: db, interval: 5 * time.Second, timeout: 2 * time.Second}
+}
+
+// start pings the database once, so the server starts out with a known
+// status, then keeps pinging in the background until stop is called.
+func (h *dbHealth) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	h.done = make(chan struct{})
+	h.check(ctx)
+
+	go func() {
+		defer close(h.done)
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.check(ctx)
+			}
+		}
+	}()
+}
+
+// stop shuts the pinger down.
+func (h *dbHealth) stop() {
+	if h.cancel == nil {
+		return
+	}
+	h.cancel()
+	<-h.done
+}
+
+// check pings the database once and records the result, logging when the
+// database goes down or comes back.
+func (h *dbHealth) check(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	err := h.db.PingContext(pingCtx)
+	if ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	next := dbStatus{OK: err == nil, Since: now, CheckedAt: now}
+	if err != nil {
+		next.Err = err.Error()
+	}
+	prev := h.status.Load()
+	if prev != nil && prev.OK == next.OK {
+		next.Since = prev.Since
+	}
+	h.status.Store(&next)
+
+	switch {
+	case !next.OK && (prev == nil || prev.OK):
+		log.Printf("Database unavailable: %v", err)
+	case next.OK && prev != nil && !prev.OK:
+		log.Printf("Database available again after %v", now.Sub(prev.Since).Round(time.Second))
+	}
+}
+
+// current returns the latest status. Until the first ping, and without a
+// pinger at all, the database is assumed to be up.
+func (h *dbHealth) current() dbStatus {
+	if h == nil {
+		return dbStatus{OK: true}
+	}
+	if status := h.status.Load(); status != nil {
+		return *status
+	}
+	return dbStatus{OK: true}
+}
+
+// middlewareDBAvailable answers 503 while the database is down, with a
+// Retry-After of the time until the next ping.
+func (cfg *apiConfig) middlewareDBAvailable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.dbHealth.current().OK {
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(cfg.dbHealth.interval.Seconds())))))
+			respondWithErrorCode(w, r, http.StatusServiceUnavailable, errCodeDatabaseUnavailable, "The database is unavailable; try again shortly")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handlerReadyz reports whether the server can serve requests: "ok", or
+// "degraded" with a 503 and the last ping error while the database is down.
+func (cfg *apiConfig) handlerReadyz(w http.ResponseWriter, r *http.Request) {
+	status := cfg.dbHealth.current()
+	resp := Readiness{Status: "ok"}
+	code := http.StatusOK
+	if !status.OK {
+		code = http.StatusServiceUnavailable
+		resp.Status = "degraded"
+		resp.Database = &DatabaseHealth{Error: status.Err, Since: status.Since, CheckedAt: status.CheckedAt}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
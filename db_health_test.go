@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// stubPinger fails its pings with err, when set.
+type stubPinger struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (p *stubPinger) PingContext(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *stubPinger) set(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func TestDBHealthTransitions(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "alice@example.com")
+	pinger := &stubPinger{}
+	cfg.dbHealth = newDBHealth(pinger)
+	mux := newTestMux(t, cfg, false)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+makeTestToken(t, user.ID))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	readyz := func() (int, Readiness) {
+		rr := get("/api/v1/readyz")
+		var resp Readiness
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return rr.Code, resp
+	}
+
+	cfg.dbHealth.check(t.Context())
+	if code, resp := readyz(); code != http.StatusOK || resp.Status != "ok" || resp.Database != nil {
+		t.Fatalf("healthy readyz = %v %+v, want 200 ok", code, resp)
+	}
+
+	// Down: database routes fail fast, the rest keep working
+	pinger.set(errors.New("connection refused"))
+	cfg.dbHealth.check(t.Context())
+	code, resp := readyz()
+	if code != http.StatusServiceUnavailable || resp.Status != "degraded" || resp.Database == nil || resp.Database.Error != "connection refused" {
+		t.Fatalf("degraded readyz = %v %+v, want 503 with the ping error", code, resp)
+	}
+	since := resp.Database.Since
+	rr := get("/api/v1/chirps")
+	var got ErrorResponse
+	json.NewDecoder(rr.Body).Decode(&got)
+	if rr.Code != http.StatusServiceUnavailable || got.Code != errCodeDatabaseUnavailable || rr.Header().Get("Retry-After") != "5" {
+		t.Errorf("chirps while down = %v %+v (Retry-After %q), want 503 database_unavailable after 5s", rr.Code, got, rr.Header().Get("Retry-After"))
+	}
+	if rr := get("/api/v1/healthz"); rr.Code != http.StatusOK {
+		t.Errorf("healthz while down: got status %v want %v", rr.Code, http.StatusOK)
+	}
+
+	// The outage keeps its start time across failed pings
+	cfg.dbHealth.check(t.Context())
+	if _, resp := readyz(); resp.Database == nil || !resp.Database.Since.Equal(since) || !resp.Database.CheckedAt.After(since) {
+		t.Errorf("second failed ping reported %+v, want the outage since %v", resp.Database, since)
+	}
+
+	// Recovered
+	pinger.set(nil)
+	cfg.dbHealth.check(t.Context())
+	if code, resp := readyz(); code != http.StatusOK || resp.Status != "ok" {
+		t.Errorf("recovered readyz = %v %+v, want 200 ok", code, resp)
+	}
+	if rr := get("/api/v1/chirps"); rr.Code != http.StatusOK {
+		t.Errorf("chirps after recovery: got status %v want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestDBHealthWorker(t *testing.T) {
+	pinger := &stubPinger{err: errors.New("connection refused")}
+	h := newDBHealth(pinger)
+	h.start()
+	h.stop()
+	if status := h.current(); status.OK || status.Err != "connection refused" {
+		t.Errorf("status after the first ping = %+v, want down", status)
+	}
+}
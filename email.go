@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/mail"
+)
+
+// mailTimeout bounds how long a single email may take to send.
+const mailTimeout = 30 * time.Second
+
+// sendWelcomeEmail mails a new user in the background so a slow SMTP relay
+// cannot hold up signup. The account already exists, so failures are only
+// logged.
+func (cfg *apiConfig) sendWelcomeEmail(email string) {
+	cfg.sendEmail("welcome", email, mail.Welcome)
+}
+
+// sendAccountExistsEmail tells the owner of email that someone tried to
+// sign up with it, in the background like sendWelcomeEmail.
+func (cfg *apiConfig) sendAccountExistsEmail(email string) {
+	cfg.sendEmail("account exists", email, mail.AccountExists)
+}
+
+// sendEmailChangeConfirmation sends the link that confirms a change of
+// email to the new address, in the background like sendWelcomeEmail.
+func (cfg *apiConfig) sendEmailChangeConfirmation(email, link string) {
+	cfg.sendEmail("email change confirmation", email, func(email string) (mail.Message, error) {
+		return mail.ConfirmEmailChange(email, link)
+	})
+}
+
+// sendEmailChangedNotice tells the old address of an account that its email
+// is now newEmail, in the background like sendWelcomeEmail.
+func (cfg *apiConfig) sendEmailChangedNotice(oldEmail, newEmail string) {
+	cfg.sendEmail("email changed", oldEmail, func(email string) (mail.Message, error) {
+		return mail.EmailChanged(email, newEmail)
+	})
+}
+
+// sendMagicLink mails email the link that logs them in, in the background
+// like sendWelcomeEmail.
+func (cfg *apiConfig) sendMagicLink(email, link string) {
+	cfg.sendEmail("login link", email, func(email string) (mail.Message, error) {
+		return mail.MagicLink(email, link)
+	})
+}
+
+// link returns the absolute URL of path with query, for an email sent while
+// handling r. It is based on publicURL, or without it on the host r was
+// sent to.
+func (cfg *apiConfig) link(r *http.Request, path string, query url.Values) string {
+	return cfg.baseURL(r) + path + "?" + query.Encode()
+}
+
+// baseURL is the address users reach the server at: publicURL, or without
+// it the scheme and host r was sent to.
+func (cfg *apiConfig) baseURL(r *http.Request) string {
+	if cfg.publicURL != "" {
+		return cfg.publicURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// sendEmail renders a message with build and sends it to email in the
+// background. kind names the message in logs.
+func (cfg *apiConfig) sendEmail(kind, email string, build func(email string) (mail.Message, error)) {
+	if cfg.mailer == nil {
+		return
+	}
+
+	msg, err := build(email)
+	if err != nil {
+		log.Printf("Error rendering %s email: %v", kind, err)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := cfg.mailer.Send(ctx, msg); err != nil {
+			log.Printf("Error sending %s email to %s: %v", kind, email, err)
+		}
+	}()
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// entityURL is the chirp_entities type of a link.
+const entityURL = "url"
+
+// indexURLs replaces the stored links of a chirp with the ones found in its
+// body and returns them.
+func (cfg *apiConfig) indexURLs(ctx context.Context, chirpID uuid.UUID, body string) ([]URLEntity, error) {
+	stored := []URLEntity{}
+
+	err := cfg.dbQueries.DeleteChirpEntities(ctx, chirpID)
+	if err != nil {
+		return stored, err
+	}
+
+	for _, link := range extractURLs(body) {
+		err = cfg.dbQueries.AddChirpEntity(ctx, database.AddChirpEntityParams{
+			ChirpID:    chirpID,
+			Type:       entityURL,
+			StartIndex: int32(link.Start),
+			EndIndex:   int32(link.End),
+			Value:      link.URL,
+		})
+		if err != nil {
+			return stored, err
+		}
+		stored = append(stored, link)
+	}
+
+	return stored, nil
+}
+
+// attachEntities fills in Entities on each chirp using a single query for
+// the whole slice.
+func (cfg *apiConfig) attachEntities(r *http.Request, chirps []Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	chirpIDs := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		chirpIDs[i] = chirp.ID
+	}
+
+	entities, err := cfg.dbQueries.GetChirpEntities(r.Context(), chirpIDs)
+	if err != nil {
+		return err
+	}
+
+	byChirp := make(map[uuid.UUID][]URLEntity)
+	for _, entity := range entities {
+		if entity.Type != entityURL {
+			continue
+		}
+		byChirp[entity.ChirpID] = append(byChirp[entity.ChirpID], URLEntity{
+			URL:   entity.Value,
+			Start: int(entity.StartIndex),
+			End:   int(entity.EndIndex),
+		})
+	}
+
+	for i := range chirps {
+		if urls, ok := byChirp[chirps[i].ID]; ok {
+			chirps[i].Entities.URLs = urls
+		}
+	}
+
+	return nil
+}
+
+// extractURLs returns the http and https links in text in order, each with
+// its normalized form and the character offsets of the literal text. A link
+// runs from the scheme to the next whitespace; trailing punctuation, and a
+// closing bracket without a matching opening one inside the link, are left
+// out as belonging to the sentence. Offsets count characters like the
+// chirp length limit does, so a link costs exactly its own length.
+func extractURLs(text string) []URLEntity {
+	var urls []URLEntity
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if i > 0 && (unicode.IsLetter(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+			continue
+		}
+		if !hasURLScheme(runes[i:]) {
+			continue
+		}
+
+		end := i
+		for end < len(runes) && isURLRune(runes[end]) {
+			end++
+		}
+		end = trimURLEnd(runes[i:end]) + i
+
+		if normalized, ok := normalizeURL(string(runes[i:end])); ok {
+			urls = append(urls, URLEntity{URL: normalized, Start: i, End: end})
+		}
+		i = end
+	}
+
+	return urls
+}
+
+// hasURLScheme reports whether runes start with http:// or https://, in any
+// case.
+func hasURLScheme(runes []rune) bool {
+	prefix := strings.ToLower(string(runes[:min(len(runes), len("https://"))]))
+	return strings.HasPrefix(prefix, "http://") || strings.HasPrefix(prefix, "https://")
+}
+
+func isURLRune(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsControl(r) && !strings.ContainsRune(`<>"`, r)
+}
+
+// trimURLEnd returns the length of link without the punctuation that ends
+// the sentence around it.
+func trimURLEnd(link []rune) int {
+	end := len(link)
+	for end > 0 {
+		last := link[end-1]
+		switch {
+		case strings.ContainsRune(`.,:;!?'*`, last):
+		case last == ')' && unbalanced(link[:end], '(', ')'):
+		case last == ']' && unbalanced(link[:end], '[', ']'):
+		default:
+			return end
+		}
+		end--
+	}
+	return end
+}
+
+// unbalanced reports whether link closes more brackets than it opens.
+func unbalanced(link []rune, open, close rune) bool {
+	depth := 0
+	for _, r := range link {
+		switch r {
+		case open:
+			depth++
+		case close:
+			depth--
+		}
+	}
+	return depth < 0
+}
+
+// normalizeURL parses an extracted link and returns it with the scheme and
+// host lowercased, or false if it is not a usable http(s) URL.
+func normalizeURL(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return "", false
+	}
+	u.Host = strings.ToLower(u.Host)
+	return u.String(), true
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes a client can act on, from
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pqUniqueViolation      = "23505"
+	pqForeignKeyViolation  = "23503"
+	pqStringDataTruncation = "22001"
+	pqSerializationFailure = "40001"
+)
+
+const errCodeDatabaseBusy = "database_busy"
+
+// serializationRetryAfter is the Retry-After, in seconds, of a request
+// whose transaction lost a serialization conflict. The conflicting
+// transaction is usually done well within it.
+const serializationRetryAfter = "1"
+
+// respondWithLookupError answers a failed lookup of a single row: a 404
+// with notFound when there is no such row, and otherwise as
+// respondWithDBError, so a database failure is never reported as a missing
+// row.
+func respondWithLookupError(w http.ResponseWriter, r *http.Request, err error, notFound string) {
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, http.StatusNotFound, notFound)
+		return
+	}
+	respondWithDBError(w, r, err)
+}
+
+// respondWithDBError answers a database error the handler has no more
+// specific answer for. Postgres errors the client can do something about
+// get a status saying so: a duplicate is a 409, a reference to a missing
+// row or a value too long for its column a 400, and a serialization
+// failure a 503 to retry. Anything else is logged with its code and
+// answered with a 500.
+func respondWithDBError(w http.ResponseWriter, r *http.Request, err error) {
+	respondWithPQError(w, r, err, http.StatusBadRequest, "Referenced record does not exist")
+}
+
+// respondWithReferenceError is respondWithDBError for a write whose foreign
+// key is the resource the request is about, such as the parent of a reply:
+// when that row is gone by the time of the write, the answer is a 404 with
+// notFound.
+func respondWithReferenceError(w http.ResponseWriter, r *http.Request, err error, notFound string) {
+	respondWithPQError(w, r, err, http.StatusNotFound, notFound)
+}
+
+// respondWithPQError maps err to a response, answering a foreign key
+// violation with missingRefCode and missingRef.
+func respondWithPQError(w http.ResponseWriter, r *http.Request, err error, missingRefCode int, missingRef string) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		logf(r.Context(), "Database error in %s %s: %v", r.Method, r.URL.Path, err)
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	switch pqErr.Code {
+	case pqUniqueViolation:
+		respondWithError(w, r, http.StatusConflict, "Conflicts with an existing record")
+	case pqForeignKeyViolation:
+		respondWithError(w, r, missingRefCode, missingRef)
+	case pqStringDataTruncation:
+		respondWithError(w, r, http.StatusBadRequest, "A value is too long")
+	case pqSerializationFailure:
+		w.Header().Set("Retry-After", serializationRetryAfter)
+		respondWithErrorCode(w, r, http.StatusServiceUnavailable, errCodeDatabaseBusy, "The database is busy; try again shortly")
+	default:
+		logf(r.Context(), "Database error %s in %s %s: %v", pqErr.Code, r.Method, r.URL.Path, err)
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+	}
+}
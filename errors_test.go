@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// pqFailures is a fakeStore whose named write fails with a wrapped
+// Postgres error of the given code.
+type pqFailures struct {
+	*fakeStore
+	failing string
+	code    pq.ErrorCode
+}
+
+func (s pqFailures) err(method string) error {
+	if s.failing != method {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", method, &pq.Error{Code: s.code, Message: "injected"})
+}
+
+func (s pqFailures) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	if err := s.err("CreateChirp"); err != nil {
+		return database.Chirp{}, err
+	}
+	return s.fakeStore.CreateChirp(ctx, arg)
+}
+
+func (s pqFailures) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error) {
+	if err := s.err("UpdateUser"); err != nil {
+		return database.User{}, err
+	}
+	return s.fakeStore.UpdateUser(ctx, arg)
+}
+
+func (s pqFailures) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	if err := s.err("CreateRefreshToken"); err != nil {
+		return database.RefreshToken{}, err
+	}
+	return s.fakeStore.CreateRefreshToken(ctx, arg)
+}
+
+func (s pqFailures) RevokeRefreshToken(ctx context.Context, token string) (int64, error) {
+	if err := s.err("RevokeRefreshToken"); err != nil {
+		return 0, err
+	}
+	return s.fakeStore.RevokeRefreshToken(ctx, token)
+}
+
+func (s pqFailures) LikeChirp(ctx context.Context, arg database.LikeChirpParams) (int64, error) {
+	if err := s.err("LikeChirp"); err != nil {
+		return 0, err
+	}
+	return s.fakeStore.LikeChirp(ctx, arg)
+}
+
+func (s pqFailures) CreateFollow(ctx context.Context, arg database.CreateFollowParams) (int64, error) {
+	if err := s.err("CreateFollow"); err != nil {
+		return 0, err
+	}
+	return s.fakeStore.CreateFollow(ctx, arg)
+}
+
+func (s pqFailures) CreateChirpReport(ctx context.Context, arg database.CreateChirpReportParams) (int64, error) {
+	if err := s.err("CreateChirpReport"); err != nil {
+		return 0, err
+	}
+	return s.fakeStore.CreateChirpReport(ctx, arg)
+}
+
+func (s pqFailures) SetUserPinnedChirp(ctx context.Context, arg database.SetUserPinnedChirpParams) (int64, error) {
+	if err := s.err("SetUserPinnedChirp"); err != nil {
+		return 0, err
+	}
+	return s.fakeStore.SetUserPinnedChirp(ctx, arg)
+}
+
+func (s pqFailures) GetFollowers(ctx context.Context, arg database.GetFollowersParams) ([]database.GetFollowersRow, error) {
+	if err := s.err("GetFollowers"); err != nil {
+		return nil, err
+	}
+	return s.fakeStore.GetFollowers(ctx, arg)
+}
+
+func TestPostgresErrorStatuses(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	postUser(t, cfg, map[string]string{"email": "alice@example.com", "password": "password123"})
+	alice, _ := db.GetUserByEmail(t.Context(), "alice@example.com")
+	chirp := db.addChirp(t, alice.ID, "Hello")
+	bob := db.addUser(t, "bob@example.com")
+	bobChirp := db.addChirp(t, bob.ID, "Hi")
+	token := makeTestToken(t, alice.ID)
+	mux := newTestMux(t, cfg, false)
+
+	var logs bytes.Buffer
+	previous := log.Writer()
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(previous) })
+
+	jsonBody := func(v any) []byte {
+		body, _ := json.Marshal(v)
+		return body
+	}
+	tests := []struct {
+		name          string
+		failing       string
+		code          pq.ErrorCode
+		method, path  string
+		token         string
+		body          []byte
+		wantStatus    int
+		wantError     string
+		wantErrorCode string
+	}{
+		{"duplicate chirp", "CreateChirp", pqUniqueViolation, http.MethodPost, "/api/v1/chirps", "", jsonBody(map[string]string{"body": "Hi"}), http.StatusConflict, "Conflicts with an existing record", ""},
+		{"parent deleted", "CreateChirp", pqForeignKeyViolation, http.MethodPost, "/api/v1/chirps", "", jsonBody(map[string]string{"body": "Hi", "parent_chirp_id": chirp.ID.String()}), http.StatusNotFound, "Parent chirp not found", ""},
+		{"user deleted at login", "CreateRefreshToken", pqForeignKeyViolation, http.MethodPost, "/api/v1/login", "none", jsonBody(map[string]string{"email": "alice@example.com", "password": "password123"}), http.StatusBadRequest, "Referenced record does not exist", ""},
+		{"value too long", "UpdateUser", pqStringDataTruncation, http.MethodPut, "/api/v1/users", "", jsonBody(map[string]string{"display_name": "Alice"}), http.StatusBadRequest, "A value is too long", ""},
+		{"serialization failure", "RevokeRefreshToken", pqSerializationFailure, http.MethodPost, "/api/v1/refresh", "refresh", nil, http.StatusServiceUnavailable, "The database is busy; try again shortly", errCodeDatabaseBusy},
+		{"unknown code", "RevokeRefreshToken", "53300", http.MethodPost, "/api/v1/refresh", "refresh", nil, http.StatusInternalServerError, "Something went wrong", errCodeInternal},
+
+		// The same mapping on other handlers
+		{"follow conflict", "CreateFollow", pqUniqueViolation, http.MethodPost, "/api/v1/users/" + bob.ID.String() + "/follow", "", nil, http.StatusConflict, "Conflicts with an existing record", ""},
+		{"liked chirp deleted", "LikeChirp", pqForeignKeyViolation, http.MethodPost, "/api/v1/chirps/" + bobChirp.ID.String() + "/like", "", nil, http.StatusNotFound, "Chirp not found", ""},
+		{"report reason too long", "CreateChirpReport", pqStringDataTruncation, http.MethodPost, "/api/v1/chirps/" + bobChirp.ID.String() + "/report", "", jsonBody(map[string]string{"reason": "spam"}), http.StatusBadRequest, "A value is too long", ""},
+		{"pin serialization failure", "SetUserPinnedChirp", pqSerializationFailure, http.MethodPost, "/api/v1/chirps/" + chirp.ID.String() + "/pin", "", nil, http.StatusServiceUnavailable, "The database is busy; try again shortly", errCodeDatabaseBusy},
+		{"followers unknown code", "GetFollowers", "XX000", http.MethodGet, "/api/v1/users/" + bob.ID.String() + "/followers", "", nil, http.StatusInternalServerError, "Something went wrong", errCodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			cfg.dbQueries = pqFailures{fakeStore: db, failing: tt.failing, code: tt.code}
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewReader(tt.body))
+			switch tt.token {
+			case "":
+				req.Header.Set("Authorization", "Bearer "+token)
+			case "refresh":
+				refreshToken := uuid.NewString()
+				db.CreateRefreshToken(t.Context(), database.CreateRefreshTokenParams{
+					Token:     refreshToken,
+					UserID:    alice.ID,
+					ExpiresAt: time.Now().Add(time.Hour),
+					FamilyID:  uuid.New(),
+				})
+				req.Header.Set("Authorization", "Bearer "+refreshToken)
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			var errResp ErrorResponse
+			json.Unmarshal(rr.Body.Bytes(), &errResp)
+			if rr.Code != tt.wantStatus || errResp.Error != tt.wantError || errResp.Code != tt.wantErrorCode {
+				t.Fatalf("got status %v %+v, want %v %q (%q)", rr.Code, errResp, tt.wantStatus, tt.wantError, tt.wantErrorCode)
+			}
+			if tt.wantStatus == http.StatusServiceUnavailable && rr.Header().Get("Retry-After") != serializationRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", rr.Header().Get("Retry-After"), serializationRetryAfter)
+			}
+			if logged := strings.Contains(logs.String(), string(tt.code)); logged != (tt.wantStatus == http.StatusInternalServerError) {
+				t.Errorf("log = %q, want the code logged only for a 500", logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// writeJSONWithETag encodes v as the 200 response body with a weak ETag
+// derived from the encoded bytes and, unless lastModified is zero, a
+// Last-Modified header. It writes 304 Not Modified with no body instead when
+// the request's If-None-Match already names that ETag or, for a request
+// without If-None-Match, when If-Modified-Since is no older than
+// lastModified.
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, v any, lastModified time.Time) {
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(v)
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	sum := sha256.Sum256(body.Bytes())
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	// liked_by_me makes the body depend on who is asking
+	w.Header().Add("Vary", "Authorization")
+	w.Header().Set("ETag", etag)
+
+	notModified := etagMatches(r.Header.Get("If-None-Match"), etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+		// RFC 9110 ignores If-Modified-Since when If-None-Match is present
+		if r.Header.Get("If-None-Match") == "" {
+			notModified = notModifiedSince(r.Header.Get("If-Modified-Since"), lastModified)
+		}
+	}
+
+	if notModified {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
+
+// notModifiedSince reports whether an If-Modified-Since header value is at
+// or after lastModified. HTTP dates have whole seconds, so lastModified is
+// truncated to match; an unparseable date is ignored, as RFC 9110 requires.
+func notModifiedSince(ifModifiedSince string, lastModified time.Time) bool {
+	if ifModifiedSince == "" {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// listLastModified returns the Last-Modified of a list of chirps: the latest
+// updated_at among them, or invalidated if that is later. A delete, a like
+// or a follow approval changes a list without moving any updated_at in it,
+// so invalidated is when the chirp cache last saw such a write. An empty
+// list has no date and gets the zero time.
+func listLastModified(chirps []Chirp, invalidated time.Time) time.Time {
+	if len(chirps) == 0 {
+		return time.Time{}
+	}
+	latest := invalidated
+	for _, chirp := range chirps {
+		if chirp.UpdatedAt.After(latest) {
+			latest = chirp.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag
+// using the weak comparison RFC 9110 prescribes for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestChirpsETag(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+	chirp := db.addChirp(t, user.ID, "First")
+
+	getChirps := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		cfg.handlerGetChirps(rr, req)
+		return rr
+	}
+
+	rr := getChirps("")
+	etag := rr.Header().Get("ETag")
+	if rr.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with an ETag, got %v %q", rr.Code, etag)
+	}
+
+	rr = getChirps(etag)
+	if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Fatalf("replay returned %v with %d body bytes, want 304 and no body", rr.Code, rr.Body.Len())
+	}
+	if rr := getChirps(`"other", ` + etag); rr.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match list containing the ETag returned %v, want 304", rr.Code)
+	}
+
+	// Creating a chirp changes the list
+	postChirp(t, cfg, token, map[string]any{"body": "Second"})
+	rr = getChirps(etag)
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") == etag {
+		t.Fatalf("after create: got %v with ETag %q, want 200 with a new ETag", rr.Code, rr.Header().Get("ETag"))
+	}
+	etag = rr.Header().Get("ETag")
+
+	// So does deleting one
+	req := httptest.NewRequest(http.MethodDelete, "/api/chirps/"+chirp.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.SetPathValue("chirpID", chirp.ID.String())
+	cfg.handlerDeleteChirp(httptest.NewRecorder(), req)
+	if rr := getChirps(etag); rr.Code != http.StatusOK {
+		t.Errorf("after delete: got %v, want 200", rr.Code)
+	}
+}
+
+func TestChirpByIDETag(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	chirp := db.addChirp(t, user.ID, "Hello")
+
+	getChirp := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+chirp.ID.String(), nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		req.SetPathValue("chirpID", chirp.ID.String())
+		cfg.handlerGetChirpByID(rr, req)
+		return rr
+	}
+
+	etag := getChirp("").Header().Get("ETag")
+	if rr := getChirp(etag); rr.Code != http.StatusNotModified {
+		t.Fatalf("replay returned %v, want 304", rr.Code)
+	}
+
+	// A new like changes like_count and therefore the ETag
+	cfg.handlerLikeChirp(httptest.NewRecorder(), likeRequest(t, http.MethodPost, chirp.ID, makeTestToken(t, user.ID)))
+	rr := getChirp(etag)
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") == etag {
+		t.Errorf("after like: got %v with ETag %q, want 200 with a new ETag", rr.Code, rr.Header().Get("ETag"))
+	}
+}
+
+func TestChirpsLastModified(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+	// Written an hour ago, so an edit now is newer even at second precision
+	chirp, err := db.CreateChirpAt(t.Context(), database.CreateChirpAtParams{
+		CreatedAt: time.Now().Add(-time.Hour),
+		Body:      "Hello",
+		UserID:    user.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		maps.Copy(req.Header, header)
+		rr := httptest.NewRecorder()
+		if chirpID, ok := strings.CutPrefix(req.URL.Path, "/api/chirps/"); ok {
+			req.SetPathValue("chirpID", chirpID)
+			cfg.handlerGetChirpByID(rr, req)
+		} else {
+			cfg.handlerGetChirps(rr, req)
+		}
+		return rr
+	}
+
+	for _, path := range []string{"/api/chirps", "/api/chirps/" + chirp.ID.String()} {
+		rr := get(path, nil)
+		lastModified := rr.Header().Get("Last-Modified")
+		if want := chirp.UpdatedAt.UTC().Format(http.TimeFormat); rr.Code != http.StatusOK || lastModified != want {
+			t.Fatalf("GET %s: got %v with Last-Modified %q, want 200 with %q", path, rr.Code, lastModified, want)
+		}
+
+		// The header drops the fraction of a second updated_at has
+		if rr := get(path, http.Header{"If-Modified-Since": {lastModified}}); rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+			t.Errorf("GET %s since Last-Modified: got %v with %d body bytes, want 304 and no body", path, rr.Code, rr.Body.Len())
+		}
+		earlier := chirp.UpdatedAt.Add(-time.Minute).UTC().Format(http.TimeFormat)
+		if rr := get(path, http.Header{"If-Modified-Since": {earlier}}); rr.Code != http.StatusOK {
+			t.Errorf("GET %s since a minute before: got %v want 200", path, rr.Code)
+		}
+		if rr := get(path, http.Header{"If-Modified-Since": {"yesterday"}}); rr.Code != http.StatusOK {
+			t.Errorf("GET %s with an invalid date: got %v want 200", path, rr.Code)
+		}
+		// A stale ETag wins over a current date
+		if rr := get(path, http.Header{"If-None-Match": {`"stale"`}, "If-Modified-Since": {lastModified}}); rr.Code != http.StatusOK {
+			t.Errorf("GET %s with a stale ETag: got %v want 200", path, rr.Code)
+		}
+	}
+
+	listModified := get("/api/chirps", nil).Header().Get("Last-Modified")
+	chirpModified := get("/api/chirps/"+chirp.ID.String(), nil).Header().Get("Last-Modified")
+	if rr := putChirp(t, cfg, chirp.ID.String(), token, map[string]string{"body": "Hello again"}); rr.Code != http.StatusOK {
+		t.Fatalf("edit: got status %v", rr.Code)
+	}
+	for path, since := range map[string]string{"/api/chirps": listModified, "/api/chirps/" + chirp.ID.String(): chirpModified} {
+		rr := get(path, http.Header{"If-Modified-Since": {since}})
+		if rr.Code != http.StatusOK || rr.Header().Get("Last-Modified") == since {
+			t.Errorf("GET %s after edit: got %v with Last-Modified %q, want 200 with a newer one", path, rr.Code, rr.Header().Get("Last-Modified"))
+		}
+	}
+
+	// An empty list has nothing to date
+	if rr := get("/api/chirps?author_id="+uuid.NewString(), nil); rr.Header().Get("Last-Modified") != "" {
+		t.Errorf("empty list: got Last-Modified %q", rr.Header().Get("Last-Modified"))
+	}
+}
+
+func TestChirpsLastModifiedAfterDelete(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	var chirps []database.Chirp
+	for _, body := range []string{"Kept", "Deleted"} {
+		chirp, err := db.CreateChirpAt(t.Context(), database.CreateChirpAtParams{
+			CreatedAt: time.Now().Add(-time.Hour),
+			Body:      body,
+			UserID:    user.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		chirps = append(chirps, chirp)
+	}
+
+	get := func(since string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+		req.Header.Set("If-Modified-Since", since)
+		rr := httptest.NewRecorder()
+		cfg.handlerGetChirps(rr, req)
+		return rr
+	}
+	since := get("").Header().Get("Last-Modified")
+	if rr := get(since); rr.Code != http.StatusNotModified {
+		t.Fatalf("before delete: got %v want 304", rr.Code)
+	}
+
+	// Deleting leaves the updated_at of every remaining chirp alone
+	req := httptest.NewRequest(http.MethodDelete, "/api/chirps/"+chirps[1].ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+makeTestToken(t, user.ID))
+	req.SetPathValue("chirpID", chirps[1].ID.String())
+	rr := httptest.NewRecorder()
+	cfg.handlerDeleteChirp(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("delete: got status %v", rr.Code)
+	}
+
+	rr = get(since)
+	if rr.Code != http.StatusOK || rr.Header().Get("Last-Modified") == since || strings.Contains(rr.Body.String(), "Deleted") {
+		t.Errorf("after delete: got %v with Last-Modified %q and body %s, want 200 with a newer date and only the kept chirp", rr.Code, rr.Header().Get("Last-Modified"), rr.Body)
+	}
+}
@@ -11,3 +11,23 @@ require (
 	github.com/joho/godotenv v1.5.1
 	golang.org/x/crypto v0.45.0
 )
+
+require github.com/gorilla/websocket v1.5.3
+
+require golang.org/x/text v0.31.0
+
+require golang.org/x/sys v0.38.0 // indirect
+
+require (
+	github.com/getkin/kin-openapi v0.133.0
+	github.com/go-openapi/jsonpointer v0.21.0 // indirect
+	github.com/go-openapi/swag v0.23.0 // indirect
+	github.com/josharian/intern v1.0.0 // indirect
+	github.com/mailru/easyjson v0.7.7 // indirect
+	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826 // indirect
+	github.com/oasdiff/yaml v0.0.0-20250309154309-f31be36b4037 // indirect
+	github.com/oasdiff/yaml3 v0.0.0-20250309153720-d2182401db90 // indirect
+	github.com/perimeterx/marshmallow v1.1.5 // indirect
+	github.com/woodsbury/decimal128 v1.3.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
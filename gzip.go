@@ -0,0 +1,180 @@
+package main
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gzipMinSize is the smallest body worth compressing; below it the gzip
+// framing overhead outweighs the savings.
+const gzipMinSize = 1024
+
+var gzipContentTypes = []string{
+	"application/json",
+	"text/html",
+	"text/plain",
+	"text/css",
+	"text/javascript",
+	"application/javascript",
+}
+
+// middlewareGzip compresses JSON, HTML and other text responses of at least
+// gzipMinSize bytes for clients that accept gzip. Streaming responses (SSE),
+// WebSocket upgrades and bodies that already carry a Content-Encoding pass
+// through untouched.
+func middlewareGzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		// "gzip;q=0" explicitly refuses it
+		name, value, _ := strings.Cut(strings.TrimSpace(params), "=")
+		if strings.TrimSpace(name) != "q" {
+			return true
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return err == nil && q > 0
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a response until it knows whether
+// the body is big enough to compress, then commits the headers either way.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	buf         []byte
+	decided     bool
+	gz          *gzip.Writer
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.decided {
+		g.ResponseWriter.WriteHeader(status)
+		return
+	}
+	// Informational responses go straight out; only the final status waits
+	if status >= 100 && status < 200 {
+		g.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if !g.wroteHeader {
+		g.status = status
+		g.wroteHeader = true
+	}
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	g.wroteHeader = true
+	if g.decided {
+		if g.gz != nil {
+			return g.gz.Write(p)
+		}
+		return g.ResponseWriter.Write(p)
+	}
+
+	g.buf = append(g.buf, p...)
+	if len(g.buf) >= gzipMinSize {
+		if err := g.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush commits to a decision with whatever is buffered so streaming
+// handlers are never held back.
+func (g *gzipResponseWriter) Flush() {
+	if !g.decided {
+		g.decide()
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+func (g *gzipResponseWriter) decide() error {
+	g.decided = true
+
+	h := g.Header()
+	if g.shouldCompress() {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+		g.ResponseWriter.WriteHeader(g.status)
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+		_, err := g.gz.Write(g.buf)
+		g.buf = nil
+		return err
+	}
+
+	g.ResponseWriter.WriteHeader(g.status)
+	_, err := g.ResponseWriter.Write(g.buf)
+	g.buf = nil
+	return err
+}
+
+func (g *gzipResponseWriter) shouldCompress() bool {
+	h := g.Header()
+	if len(g.buf) < gzipMinSize || h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	if g.status < 200 || g.status == http.StatusNoContent || g.status == http.StatusNotModified || g.status == http.StatusPartialContent {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	for _, contentType := range gzipContentTypes {
+		if mediaType == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *gzipResponseWriter) close() {
+	if !g.decided {
+		if !g.wroteHeader {
+			// The handler wrote nothing; let net/http send its default 200
+			return
+		}
+		g.decide()
+	}
+	if g.gz != nil {
+		g.gz.Close()
+	}
+}
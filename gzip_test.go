@@ -0,0 +1,130 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareGzip(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	for i := 0; i < 50; i++ {
+		db.addChirp(t, user.ID, fmt.Sprintf("chirp number %d with enough text to pad the list", i))
+	}
+	handler := middlewareGzip(newTestMux(t, cfg, false))
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/api/v1/chirps", "gzip, deflate")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if got := rr.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := rr.Header().Values("Vary"); !strings.Contains(strings.Join(got, ","), "Accept-Encoding") {
+		t.Errorf("Vary = %q, want it to include Accept-Encoding", got)
+	}
+	zr, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("Failed to open gzip body: %v", err)
+	}
+	var chirps []Chirp
+	if err := json.NewDecoder(zr).Decode(&chirps); err != nil {
+		t.Fatalf("Failed to decode gzipped response: %v", err)
+	}
+	if len(chirps) != 50 {
+		t.Errorf("got %d chirps, want 50", len(chirps))
+	}
+
+	// Clients that don't ask for gzip, or refuse it, get plain JSON
+	for _, acceptEncoding := range []string{"", "gzip;q=0", "br"} {
+		rr := get("/api/v1/chirps", acceptEncoding)
+		if got := rr.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Accept-Encoding %q: Content-Encoding = %q, want none", acceptEncoding, got)
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &chirps); err != nil {
+			t.Errorf("Accept-Encoding %q: body is not plain JSON: %v", acceptEncoding, err)
+		}
+	}
+
+	// Small bodies are not worth compressing
+	rr = get("/api/v1/chirps/not-a-uuid", "gzip")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	if got := rr.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("small response Content-Encoding = %q, want none", got)
+	}
+}
+
+func TestMiddlewareGzipPreservesStatus(t *testing.T) {
+	body := strings.Repeat(`{"error":"padding"}`, 100)
+	handler := middlewareGzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, body)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	if got := rr.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	zr, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("Failed to open gzip body: %v", err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Failed to read gzip body: %v", err)
+	}
+	if string(got) != body {
+		t.Error("decompressed body does not match what the handler wrote")
+	}
+}
+
+func TestMiddlewareGzipSkipsStreams(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+
+	srv := httptest.NewServer(middlewareGzip(newTestMux(t, cfg, true)))
+	defer srv.Close()
+
+	// The Go client asks for gzip by default, so the stream must come back
+	// uncompressed and flushed event by event
+	events := openChirpStream(t, srv, "")
+	postChirp(t, cfg, makeTestToken(t, user.ID), map[string]any{"body": "through the middleware"})
+	if got := nextChirpEvent(t, events); got.Body != "through the middleware" {
+		t.Errorf("event = %+v, want the new chirp", got)
+	}
+
+	conn := dialChirpsWebSocket(t, srv, "")
+	postChirp(t, cfg, makeTestToken(t, user.ID), map[string]any{"body": "over the socket"})
+	if got := readChirpFrame(t, conn); got.Body != "over the socket" {
+		t.Errorf("frame = %+v, want the new chirp", got)
+	}
+
+	// End the open streams so the server can shut down
+	cfg.chirpHub.close()
+}
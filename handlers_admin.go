@@ -1,54 +1,294 @@
 package main
 
 import (
-	"fmt"
+	"bytes"
+	"database/sql"
+	"encoding/json"
 	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
 )
 
+// handlerMetrics shows the hit counters as an HTML page, or as JSON when the
+// client asks for application/json.
 func (cfg *apiConfig) handlerMetrics(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+	if _, ok := cfg.requireAdmin(w, r); !ok {
 		return
 	}
+
+	ctx := r.Context()
+	metrics := Metrics{
+		UptimeSeconds:       int64(time.Since(cfg.startedAt).Seconds()),
+		GoVersion:           runtime.Version(),
+		Users:               metricsTotal(ctx, "users", cfg.dbQueries.CountUsers),
+		Chirps:              metricsTotal(ctx, "chirps", cfg.dbQueries.CountChirps),
+		ActiveRefreshTokens: metricsTotal(ctx, "refresh tokens", cfg.dbQueries.CountActiveRefreshTokens),
+		FileserverHits:      cfg.fileserverHits.Load(),
+		Paths:               cfg.pathHits.snapshot(),
+		RefreshTokenReuse:   cfg.refreshTokenReuse.Load(),
+		Queries:             cfg.queryMetrics.snapshot(),
+	}
+	metrics.ChirpCache.Hits, metrics.ChirpCache.Misses = cfg.chirpCache.stats()
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(metrics)
+		return
+	}
+
+	// Rendered in full first, so a template error cannot leave half a page
+	var page bytes.Buffer
+	err := metricsTemplate.Execute(&page, metrics)
+	if err != nil {
+		logf(ctx, "Rendering the metrics page: %v", err)
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
 	w.Header().Add("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
-	htmlTemplate := `<html>
-  <body>
-    <h1>Welcome, Chirpy Admin</h1>
-    <p>Chirpy has been visited %d times!</p>
-  </body>
-</html>`
-	fmt.Fprintf(w, htmlTemplate, cfg.fileserverHits.Load())
+	w.Write(page.Bytes())
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cfg.fileserverHits.Add(1)
+		cfg.pathHits.add(r.URL.Path)
 		next.ServeHTTP(w, r)
 	})
 }
 
 func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+	adminID, ok := cfg.requireAdmin(w, r)
+	if !ok {
 		return
 	}
 
+	// Wiping the database stays a development-only tool, even for admins
 	if cfg.platform != "dev" {
-		w.WriteHeader(http.StatusForbidden)
+		respondWithError(w, r, http.StatusForbidden, "Reset is only allowed in dev")
 		return
 	}
 
 	cfg.fileserverHits.Store(0)
-	
+	cfg.pathHits.reset()
+	cfg.refreshTokenReuse.Store(0)
+	cfg.queryMetrics.reset()
+	cfg.chirpCache.invalidate()
+
 	// Delete users - CASCADE will automatically delete chirps and refresh_tokens
 	err := cfg.dbQueries.DeleteAllUsers(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Error deleting users"))
+		respondWithDBError(w, r, err)
 		return
 	}
+	cfg.audit(r, auditEntry{ActorID: adminID, Action: auditReset})
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Hits reset to 0 and database reset to empty"))
-}
\ No newline at end of file
+}
+func (cfg *apiConfig) handlerGrantAdmin(w http.ResponseWriter, r *http.Request) {
+	cfg.setAdmin(w, r, true)
+}
+
+func (cfg *apiConfig) handlerRevokeAdmin(w http.ResponseWriter, r *http.Request) {
+	cfg.setAdmin(w, r, false)
+}
+
+// setAdmin changes the admin flag of the user in the path. This, -seed and
+// the database itself are the only ways to make someone an admin.
+func (cfg *apiConfig) setAdmin(w http.ResponseWriter, r *http.Request, isAdmin bool) {
+	adminID, ok := cfg.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	updated, err := cfg.dbQueries.SetUserAdmin(r.Context(), database.SetUserAdminParams{
+		ID:      userID,
+		IsAdmin: isAdmin,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, r, http.StatusNotFound, "User not found")
+		return
+	}
+
+	action := auditAdminRevoke
+	if isAdmin {
+		action = auditAdminGrant
+	}
+	cfg.audit(r, auditEntry{ActorID: adminID, Action: action, Target: userID.String()})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerDeleteUserKeepChirps deletes the user in the path but keeps their
+// chirps, which stay readable under a tombstone (see DeleteUserKeepChirps).
+func (cfg *apiConfig) handlerDeleteUserKeepChirps(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := cfg.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if userID == adminID {
+		respondWithError(w, r, http.StatusBadRequest, "You cannot delete yourself")
+		return
+	}
+
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err == nil && dbUser.DeletedAt.Valid {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		respondWithLookupError(w, r, err, "User not found")
+		return
+	}
+
+	deleted, err := cfg.dbQueries.DeleteUserKeepChirps(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, r, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if dbUser.AvatarUrl.Valid {
+		cfg.removeUnusedMedia(r, dbUser.AvatarUrl)
+	}
+	// Cached pages may still carry the author's profile
+	cfg.chirpCache.invalidate()
+	cfg.audit(r, auditEntry{ActorID: adminID, Action: auditUserDelete, Target: userID.String()})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondUserDeleted writes the 410 for a user whose account was deleted
+// with their chirps kept.
+func respondUserDeleted(w http.ResponseWriter, r *http.Request) {
+	respondWithError(w, r, http.StatusGone, "User has been deleted")
+}
+
+// handlerGetAuditLog lists audit entries newest first, optionally only
+// those with the given ?action=.
+func (cfg *apiConfig) handlerGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireAdmin(w, r); !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	action := r.URL.Query().Get("action")
+	dbEntries, err := cfg.dbQueries.GetAuditLog(r.Context(), database.GetAuditLogParams{
+		Action: sql.NullString{String: action, Valid: action != ""},
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	entries := make([]AuditLogEntry, len(dbEntries))
+	for i, e := range dbEntries {
+		entries[i] = AuditLogEntry{
+			ID:        e.ID,
+			CreatedAt: e.CreatedAt,
+			Action:    e.Action,
+			Target:    e.Target,
+			RequestID: e.RequestID,
+		}
+		if e.ActorID.Valid {
+			entries[i].ActorID = &e.ActorID.UUID
+		}
+		if e.ActorToken.Valid {
+			entries[i].ActorToken = &e.ActorToken.String
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+// handlerGetWebhookEvents lists stored webhook deliveries newest first,
+// optionally only those with the given ?status=, such as "dead".
+func (cfg *apiConfig) handlerGetWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireAdmin(w, r); !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", webhookPending, webhookProcessed, webhookDead:
+	default:
+		respondWithError(w, r, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	dbEvents, err := cfg.dbQueries.GetWebhookEvents(r.Context(), database.GetWebhookEventsParams{
+		Status: sql.NullString{String: status, Valid: status != ""},
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	events := make([]WebhookEvent, len(dbEvents))
+	for i, e := range dbEvents {
+		events[i] = WebhookEvent{
+			ID:            e.ID,
+			CreatedAt:     e.CreatedAt,
+			UpdatedAt:     e.UpdatedAt,
+			Source:        e.Source,
+			Event:         e.Event,
+			Payload:       e.Payload,
+			Status:        e.Status,
+			Attempts:      e.Attempts,
+			NextAttemptAt: e.NextAttemptAt,
+			LastError:     e.LastError,
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+}
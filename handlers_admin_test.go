@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	admin := db.addAdmin(t, "admin@example.com")
+	user := db.addUser(t, "user@example.com")
+	mux := newTestMux(t, cfg, false)
+
+	// A token that claims admin rights the database does not back
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":       "chirpy",
+		"sub":       user.ID.String(),
+		"exp":       time.Now().Add(time.Hour).Unix(),
+		"token_use": "access",
+		"is_admin":  true,
+	}).SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"admin", makeTestToken(t, admin.ID), http.StatusOK},
+		{"regular user", makeTestToken(t, user.ID), http.StatusForbidden},
+		{"forged claim", forged, http.StatusForbidden},
+		{"no token", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		for _, path := range []string{"/admin/metrics", "/admin/reports"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			if rr.Code != tt.want {
+				t.Errorf("%s GET %s: got status %v want %v", tt.name, path, rr.Code, tt.want)
+			}
+		}
+	}
+}
+
+func TestGrantAndRevokeAdmin(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	admin := db.addAdmin(t, "admin@example.com")
+	user := db.addUser(t, "user@example.com")
+	adminToken := makeTestToken(t, admin.ID)
+	userToken := makeTestToken(t, user.ID)
+	mux := newTestMux(t, cfg, false)
+
+	serve := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	path := "/admin/users/" + user.ID.String() + "/admin"
+
+	// Regular users cannot promote themselves
+	if rr := serve(http.MethodPost, path, userToken); rr.Code != http.StatusForbidden {
+		t.Errorf("self-promotion: got status %v want %v", rr.Code, http.StatusForbidden)
+	}
+
+	if rr := serve(http.MethodPost, path, adminToken); rr.Code != http.StatusNoContent {
+		t.Fatalf("grant returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	if rr := serve(http.MethodGet, "/admin/reports", userToken); rr.Code != http.StatusOK {
+		t.Errorf("new admin: got status %v want %v", rr.Code, http.StatusOK)
+	}
+
+	// Revocation applies to tokens that were already issued
+	if rr := serve(http.MethodDelete, path, adminToken); rr.Code != http.StatusNoContent {
+		t.Fatalf("revoke returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	if rr := serve(http.MethodGet, "/admin/reports", userToken); rr.Code != http.StatusForbidden {
+		t.Errorf("revoked admin: got status %v want %v", rr.Code, http.StatusForbidden)
+	}
+
+	if rr := serve(http.MethodPost, "/admin/users/nope/admin", adminToken); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid ID: got status %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	if rr := serve(http.MethodPost, "/admin/users/"+uuid.NewString()+"/admin", adminToken); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown user: got status %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestCreateUserIgnoresIsAdmin(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	body, _ := json.Marshal(map[string]any{
+		"email":    "sneaky@example.com",
+		"password": "hunter22",
+		"is_admin": true,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/users", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	cfg.handlerCreateUser(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+
+	var user User
+	json.NewDecoder(rr.Body).Decode(&user)
+	stored, err := db.GetUserByID(t.Context(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.IsAdmin || stored.IsAdmin {
+		t.Error("is_admin from the request body must be ignored")
+	}
+}
+
+func TestAdminCanDeleteAnyChirp(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	admin := db.addAdmin(t, "admin@example.com")
+	author := db.addUser(t, "author@example.com")
+	other := db.addUser(t, "other@example.com")
+	mux := newTestMux(t, cfg, false)
+
+	for _, tt := range []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"another user", makeTestToken(t, other.ID), http.StatusForbidden},
+		{"admin", makeTestToken(t, admin.ID), http.StatusNoContent},
+	} {
+		chirp := db.addChirp(t, author.ID, "Hello")
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/chirps/"+chirp.ID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("%s: got status %v want %v", tt.name, rr.Code, tt.want)
+		}
+	}
+}
+
+// failingAuditStore is a fakeStore whose audit log cannot be written.
+type failingAuditStore struct {
+	*fakeStore
+}
+
+func (failingAuditStore) CreateAuditLogEntry(ctx context.Context, arg database.CreateAuditLogEntryParams) error {
+	return errors.New("audit log unavailable")
+}
+
+func TestAuditLog(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	admin := db.addAdmin(t, "admin@example.com")
+	user := db.addUser(t, "user@example.com")
+	reporter := db.addUser(t, "reporter@example.com")
+	adminToken := makeTestToken(t, admin.ID)
+	mux := newTestMux(t, cfg, false)
+
+	serve := func(method, path, auth string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", auth)
+		req.Header.Set("X-Request-ID", "req-"+method+path)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code >= 300 {
+			t.Fatalf("%s %s: unexpected status %v", method, path, rr.Code)
+		}
+		return rr
+	}
+
+	// Only actions on someone else's chirp are audited
+	own := db.addChirp(t, admin.ID, "Mine")
+	serve(http.MethodDelete, "/api/v1/chirps/"+own.ID.String(), "Bearer "+adminToken, nil)
+	deleted := db.addChirp(t, user.ID, "Rude")
+	serve(http.MethodDelete, "/api/v1/chirps/"+deleted.ID.String(), "Bearer "+adminToken, nil)
+
+	dismissed := db.addChirp(t, user.ID, "Borderline")
+	removed := db.addChirp(t, user.ID, "Spam")
+	for _, c := range []string{dismissed.ID.String(), removed.ID.String()} {
+		reportChirp(t, cfg, c, makeTestToken(t, reporter.ID), nil)
+	}
+	serve(http.MethodPost, "/admin/reports/"+dismissed.ID.String()+"/resolve", "Bearer "+adminToken, map[string]string{"action": "dismiss"})
+	serve(http.MethodPost, "/admin/reports/"+removed.ID.String()+"/resolve", "Bearer "+adminToken, map[string]string{"action": "delete"})
+
+	serve(http.MethodPost, "/admin/users/"+user.ID.String()+"/admin", "Bearer "+adminToken, nil)
+	serve(http.MethodDelete, "/admin/users/"+user.ID.String()+"/admin", "Bearer "+adminToken, nil)
+	serve(http.MethodPost, "/api/v1/polka/webhooks", "ApiKey "+cfg.polkaKey, map[string]any{
+		"event": "user.upgraded",
+		"data":  map[string]string{"user_id": user.ID.String()},
+	})
+	cfg.webhooks.runOnce(t.Context())
+	serve(http.MethodPost, "/admin/reset", "Bearer "+adminToken, nil)
+
+	// The log survives the reset, so read it directly
+	entries, err := db.GetAuditLog(t.Context(), database.GetAuditLogParams{Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		action string
+		target string
+	}{
+		{auditReset, ""},
+		{auditUserUpgrade, user.ID.String()},
+		{auditAdminRevoke, user.ID.String()},
+		{auditAdminGrant, user.ID.String()},
+		{auditReportsDelete, removed.ID.String()},
+		{auditReportsDismiss, dismissed.ID.String()},
+		{auditChirpDelete, deleted.ID.String()},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d audit entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		if entries[i].Action != w.action || entries[i].Target != w.target {
+			t.Errorf("entry %d = %s %s, want %s %s", i, entries[i].Action, entries[i].Target, w.action, w.target)
+		}
+		// The webhook worker applies upgrades outside any request
+		if entries[i].RequestID == "" && w.action != auditUserUpgrade {
+			t.Errorf("entry %d has no request ID", i)
+		}
+	}
+	if upgrade := entries[1]; upgrade.ActorID.Valid || upgrade.ActorToken.String != auditActorPolka {
+		t.Errorf("webhook entry actor = %v/%v, want the polka token", upgrade.ActorID, upgrade.ActorToken)
+	}
+	if reset := entries[0]; reset.ActorID.UUID != admin.ID || reset.ActorToken.Valid {
+		t.Errorf("reset entry actor = %v/%v, want the admin", reset.ActorID, reset.ActorToken)
+	}
+
+	// Filtering through the endpoint, with a fresh admin after the reset
+	newAdmin := db.addAdmin(t, "admin2@example.com")
+	var filtered []AuditLogEntry
+	rr := serve(http.MethodGet, "/admin/audit?action="+auditAdminGrant, "Bearer "+makeTestToken(t, newAdmin.ID), nil)
+	json.NewDecoder(rr.Body).Decode(&filtered)
+	if len(filtered) != 1 || filtered[0].Action != auditAdminGrant || *filtered[0].ActorID != admin.ID || filtered[0].ActorToken != nil {
+		t.Errorf("filtered entries = %+v, want the single grant by the first admin", filtered)
+	}
+}
+
+func TestAuditFailureDoesNotFailAction(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.dbQueries = failingAuditStore{db}
+	admin := db.addAdmin(t, "admin@example.com")
+	user := db.addUser(t, "user@example.com")
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/users/"+user.ID.String()+"/admin", nil)
+	req.SetPathValue("userID", user.ID.String())
+	req.Header.Set("Authorization", "Bearer "+makeTestToken(t, admin.ID))
+	rr := httptest.NewRecorder()
+	cfg.handlerGrantAdmin(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("grant with a broken audit log: got status %v want %v", rr.Code, http.StatusNoContent)
+	}
+	if stored, _ := db.GetUserByID(t.Context(), user.ID); !stored.IsAdmin {
+		t.Error("grant should have been applied")
+	}
+}
+
+func TestDeleteUserKeepChirps(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	admin := db.addAdmin(t, "admin@example.com")
+	alice := db.addUser(t, "alice.private@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	adminToken := makeTestToken(t, admin.ID)
+	aliceToken := makeTestToken(t, alice.ID)
+	bobToken := makeTestToken(t, bob.ID)
+
+	// Everything below identifies Alice and must not be served again
+	_, err := db.UpdateUser(context.Background(), database.UpdateUserParams{
+		ID:          alice.ID,
+		DisplayName: sql.NullString{String: "Alice Secretname", Valid: true},
+		Bio:         sql.NullString{String: "Lives at 1 Hidden Lane", Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.SetUserAvatar(context.Background(), database.SetUserAvatarParams{
+		ID:        alice.ID,
+		AvatarUrl: sql.NullString{String: "/media/alice-face.png", Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pii := []string{alice.Email, alice.Username, "Alice Secretname", "1 Hidden Lane", "alice-face"}
+
+	aliceChirp := db.addChirp(t, alice.ID, "Kept after I am gone")
+	bobChirp := db.addChirp(t, bob.ID, "Hello from Bob")
+	for _, step := range []struct{ method, path, token string }{
+		{http.MethodPost, "/api/users/" + bob.ID.String() + "/follow", aliceToken},
+		{http.MethodPost, "/api/users/" + alice.ID.String() + "/follow", bobToken},
+		{http.MethodPost, "/api/chirps/" + bobChirp.ID.String() + "/like", aliceToken},
+		{http.MethodPost, "/api/chirps/" + aliceChirp.ID.String() + "/like", bobToken},
+	} {
+		if resp := call(t, srv, step.method, step.path, step.token, nil, nil); resp.StatusCode >= 300 {
+			t.Fatalf("%s %s: got status %v", step.method, step.path, resp.StatusCode)
+		}
+	}
+	reply := map[string]any{"body": "Reply from Alice", "parent_chirp_id": bobChirp.ID}
+	if resp := call(t, srv, http.MethodPost, "/api/chirps", aliceToken, reply, nil); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("reply: got status %v want %v", resp.StatusCode, http.StatusCreated)
+	}
+
+	path := "/admin/users/" + alice.ID.String()
+	if resp := call(t, srv, http.MethodDelete, path, aliceToken, nil, nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("delete by non-admin: got status %v want %v", resp.StatusCode, http.StatusForbidden)
+	}
+	if resp := call(t, srv, http.MethodDelete, path, adminToken, nil, nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete: got status %v want %v", resp.StatusCode, http.StatusNoContent)
+	}
+	if resp := call(t, srv, http.MethodDelete, path, adminToken, nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("second delete: got status %v want %v", resp.StatusCode, http.StatusNotFound)
+	}
+	tombstone, err := db.GetUserByID(context.Background(), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := normalizeUsername(tombstone.Username); err != nil {
+		t.Errorf("tombstone username %q: %v", tombstone.Username, err)
+	}
+
+	if resp := call(t, srv, http.MethodGet, "/api/users/"+alice.ID.String(), "", nil, nil); resp.StatusCode != http.StatusGone {
+		t.Errorf("profile: got status %v want %v", resp.StatusCode, http.StatusGone)
+	}
+	if resp := call(t, srv, http.MethodPost, "/api/users/"+alice.ID.String()+"/follow", bobToken, nil, nil); resp.StatusCode != http.StatusGone {
+		t.Errorf("follow: got status %v want %v", resp.StatusCode, http.StatusGone)
+	}
+	if resp := call(t, srv, http.MethodGet, "/api/chirps", aliceToken, nil, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request with the deleted user's token: got status %v want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	// The chirp stays, with an author that says nothing about Alice
+	var chirp struct {
+		Body   string         `json:"body"`
+		Author map[string]any `json:"author"`
+	}
+	if resp := call(t, srv, http.MethodGet, "/api/chirps/"+aliceChirp.ID.String()+"?include=author", "", nil, &chirp); resp.StatusCode != http.StatusOK {
+		t.Fatalf("kept chirp: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if chirp.Body != aliceChirp.Body || len(chirp.Author) != 1 || chirp.Author["deleted"] != true {
+		t.Errorf("kept chirp = %q by %v, want %q by {deleted: true}", chirp.Body, chirp.Author, aliceChirp.Body)
+	}
+
+	reads := []struct{ path, token string }{
+		{"/api/chirps?include=author", ""},
+		{"/api/chirps?author_id=" + alice.ID.String(), ""},
+		{"/api/chirps/" + bobChirp.ID.String() + "/replies", ""},
+		{"/api/chirps/" + bobChirp.ID.String() + "/likes", ""},
+		{"/api/chirps/" + aliceChirp.ID.String() + "/likes", ""},
+		{"/api/users/" + bob.ID.String() + "/followers", ""},
+		{"/api/users/" + bob.ID.String() + "/following", ""},
+		{"/api/notifications", bobToken},
+		{"/api/feed", bobToken},
+		{"/admin/audit", adminToken},
+	}
+	for _, read := range reads {
+		var body json.RawMessage
+		if resp := call(t, srv, http.MethodGet, read.path, read.token, nil, &body); resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s: got status %v want %v", read.path, resp.StatusCode, http.StatusOK)
+		}
+		for _, s := range pii {
+			if bytes.Contains(body, []byte(s)) {
+				t.Errorf("GET %s still contains %q: %s", read.path, s, body)
+			}
+		}
+	}
+}
+
+// failingAdminWrites is a fakeStore whose admin writes fail with a
+// database error.
+type failingAdminWrites struct {
+	*fakeStore
+}
+
+func (s failingAdminWrites) DeleteAllUsers(ctx context.Context) error {
+	return errConnectionReset
+}
+
+func (s failingAdminWrites) SetUserAdmin(ctx context.Context, arg database.SetUserAdminParams) (int64, error) {
+	return 0, errConnectionReset
+}
+
+func (s failingAdminWrites) DeleteUserKeepChirps(ctx context.Context, id uuid.UUID) (int64, error) {
+	return 0, errConnectionReset
+}
+
+func TestAdminDatabaseErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.platform = "dev"
+	admin := db.addAdmin(t, "admin@example.com")
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, admin.ID)
+	mux := newTestMux(t, cfg, false)
+	cfg.dbQueries = failingAdminWrites{fakeStore: db}
+
+	for _, tt := range []struct{ method, path string }{
+		{http.MethodPost, "/admin/reset"},
+		{http.MethodPost, "/admin/users/" + user.ID.String() + "/admin"},
+		{http.MethodDelete, "/admin/users/" + user.ID.String() + "/admin"},
+		{http.MethodDelete, "/admin/users/" + user.ID.String()},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		var errResp ErrorResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &errResp); err != nil || rr.Code != http.StatusInternalServerError || errResp.RequestID == "" {
+			t.Errorf("%s %s: got status %v %q, want a JSON 500 with a request ID", tt.method, tt.path, rr.Code, rr.Body)
+		}
+	}
+}
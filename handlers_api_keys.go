@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AlexTLDR/chirpy/internal/auth"
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxAPIKeyLabelLength = 50
+
+// apiKeyPrefixLength is how much of a key is kept in plain text for owners
+// to recognise it by: the fixed prefix and a few random characters.
+const apiKeyPrefixLength = len(auth.APIKeyPrefix) + 6
+
+func apiKeyFromDB(key database.ApiKey) APIKey {
+	return APIKey{
+		ID:        key.ID,
+		Label:     key.Label,
+		Prefix:    key.Prefix,
+		CreatedAt: key.CreatedAt,
+	}
+}
+
+// handlerCreateAPIKey generates an API key for the caller. Only its hash is
+// stored, so the response is the one time the key itself is shown.
+func (cfg *apiConfig) handlerCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Label string `json:"label"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	reqBody := requestBody{}
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+
+	var invalid fieldErrors
+	if reqBody.Label == "" {
+		invalid.add("label", "Label", "is required")
+	} else if err := validateProfileText(reqBody.Label, maxAPIKeyLabelLength); err != nil {
+		invalid.add("label", "Label", err.Error())
+	}
+	if invalid.respond(w, r) {
+		return
+	}
+
+	key, err := auth.MakeAPIKey()
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	dbKey, err := cfg.dbQueries.CreateAPIKey(r.Context(), database.CreateAPIKeyParams{
+		UserID:  userID,
+		Label:   reqBody.Label,
+		Prefix:  key[:apiKeyPrefixLength],
+		KeyHash: auth.HashAPIKey(key),
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreatedAPIKey{APIKey: apiKeyFromDB(dbKey), Key: key})
+}
+
+// handlerGetAPIKeys lists the caller's API keys, oldest first, identified
+// by their prefixes.
+func (cfg *apiConfig) handlerGetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	dbKeys, err := cfg.dbQueries.GetAPIKeys(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	keys := make([]APIKey, len(dbKeys))
+	for i, dbKey := range dbKeys {
+		keys[i] = apiKeyFromDB(dbKey)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+// handlerDeleteAPIKey revokes one of the caller's API keys. Other users'
+// keys are reported as not found.
+func (cfg *apiConfig) handlerDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	keyID, err := uuid.Parse(r.PathValue("keyID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	deleted, err := cfg.dbQueries.DeleteAPIKey(r.Context(), database.DeleteAPIKeyParams{
+		ID:     keyID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, r, http.StatusNotFound, "API key not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlexTLDR/chirpy/internal/auth"
+)
+
+// apiKeyRequest sends a request through the full router, authenticated
+// with "Authorization: <authorization>".
+func apiKeyRequest(t *testing.T, mux http.Handler, method, path, authorization string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Authorization", authorization)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAPIKeys(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	mux := newTestMux(t, cfg, false)
+	user := db.addUser(t, "bot@example.com")
+	bearer := "Bearer " + makeTestToken(t, user.ID)
+
+	rr := apiKeyRequest(t, mux, http.MethodPost, "/api/v1/api_keys", bearer, map[string]string{"label": "weather bot"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body)
+	}
+	var created CreatedAPIKey
+	json.NewDecoder(rr.Body).Decode(&created)
+	if !strings.HasPrefix(created.Key, created.Prefix) || created.Label != "weather bot" {
+		t.Fatalf("created key = %+v, want the key starting with its prefix", created)
+	}
+	stored, _ := db.GetAPIKeys(t.Context(), user.ID)
+	if len(stored) != 1 || stored[0].KeyHash != auth.HashAPIKey(created.Key) || strings.Contains(stored[0].KeyHash, created.Key) {
+		t.Fatalf("stored keys = %+v, want only the hash of the key", stored)
+	}
+
+	// The listing never shows the key again
+	rr = apiKeyRequest(t, mux, http.MethodGet, "/api/v1/api_keys", bearer, nil)
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), created.Key) {
+		t.Fatalf("list = %v %s, want 200 without the key", rr.Code, rr.Body)
+	}
+	var listed []APIKey
+	json.NewDecoder(rr.Body).Decode(&listed)
+	if len(listed) != 1 || listed[0] != created.APIKey {
+		t.Errorf("listed keys = %+v, want %+v", listed, created.APIKey)
+	}
+
+	// The key posts chirps as its owner
+	apiKey := "ApiKey " + created.Key
+	rr = apiKeyRequest(t, mux, http.MethodPost, "/api/v1/chirps", apiKey, map[string]string{"body": "Sunny today"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("chirp with API key: got status %v want %v: %s", rr.Code, http.StatusCreated, rr.Body)
+	}
+	var chirp Chirp
+	json.NewDecoder(rr.Body).Decode(&chirp)
+	if chirp.UserID != user.ID {
+		t.Errorf("chirp posted as %v, want %v", chirp.UserID, user.ID)
+	}
+
+	// but cannot manage keys or the account
+	for _, tt := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/api_keys"},
+		{http.MethodPost, "/api/v1/api_keys"},
+		{http.MethodPut, "/api/v1/users"},
+	} {
+		if rr := apiKeyRequest(t, mux, tt.method, tt.path, apiKey, map[string]string{"label": "x"}); rr.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s with API key: got status %v want %v", tt.method, tt.path, rr.Code, http.StatusUnauthorized)
+		}
+	}
+
+	// Revoked keys stop working
+	rr = apiKeyRequest(t, mux, http.MethodDelete, "/api/v1/api_keys/"+created.ID.String(), bearer, nil)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("delete returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	rr = apiKeyRequest(t, mux, http.MethodPost, "/api/v1/chirps", apiKey, map[string]string{"body": "Rain later"})
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key: got status %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+	rr = apiKeyRequest(t, mux, http.MethodDelete, "/api/v1/api_keys/"+created.ID.String(), bearer, nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("deleting twice: got status %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestAPIKeyRejected(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	mux := newTestMux(t, cfg, false)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+
+	unknown, _ := auth.MakeAPIKey()
+	for _, key := range []string{unknown, "not-a-key"} {
+		rr := apiKeyRequest(t, mux, http.MethodPost, "/api/v1/chirps", "ApiKey "+key, map[string]string{"body": "hello"})
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("key %q: got status %v want %v", key, rr.Code, http.StatusUnauthorized)
+		}
+	}
+
+	checkFieldErrors(t, "missing label", apiKeyRequest(t, mux, http.MethodPost, "/api/v1/api_keys", "Bearer "+makeTestToken(t, alice.ID), map[string]string{}), map[string]string{
+		"label": "is required",
+	})
+
+	// Someone else's key cannot be revoked
+	rr := apiKeyRequest(t, mux, http.MethodPost, "/api/v1/api_keys", "Bearer "+makeTestToken(t, alice.ID), map[string]string{"label": "mine"})
+	var created CreatedAPIKey
+	json.NewDecoder(rr.Body).Decode(&created)
+	rr = apiKeyRequest(t, mux, http.MethodDelete, "/api/v1/api_keys/"+created.ID.String(), "Bearer "+makeTestToken(t, bob.ID), nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("deleting another user's key: got status %v want %v", rr.Code, http.StatusNotFound)
+	}
+	if keys, _ := db.GetAPIKeys(t.Context(), alice.ID); len(keys) != 1 {
+		t.Errorf("alice has %d keys, want 1", len(keys))
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxAvatarBytes = 1 << 20
+	// maxAvatarRequestBytes leaves room for the multipart headers and
+	// boundaries around the image itself
+	maxAvatarRequestBytes = maxAvatarBytes + 64<<10
+
+	mediaURLPrefix = "/media/"
+)
+
+// avatarExtensions maps the image types accepted as avatars, as detected
+// from the file's content, to the extension they are stored with.
+var avatarExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+}
+
+// mediaFileName matches the names avatars are stored under: the SHA-256 of
+// the content plus an extension.
+var mediaFileName = regexp.MustCompile(`^[0-9a-f]{64}\.(png|jpg)$`)
+
+// handlerUploadAvatar sets the caller's avatar from the "avatar" file of a
+// multipart/form-data request. The type is sniffed from the content; the
+// client's filename and Content-Type are ignored.
+func (cfg *apiConfig) handlerUploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarRequestBytes)
+	err := r.ParseMultipartForm(maxAvatarRequestBytes)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithError(w, r, http.StatusRequestEntityTooLarge, "Avatar must be at most 1MB")
+			return
+		}
+		respondWithError(w, r, http.StatusBadRequest, "Expected a multipart/form-data request")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "An \"avatar\" file is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxAvatarBytes+1))
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if len(data) > maxAvatarBytes {
+		respondWithError(w, r, http.StatusRequestEntityTooLarge, "Avatar must be at most 1MB")
+		return
+	}
+
+	ext, ok := avatarExtensions[http.DetectContentType(data)]
+	if !ok {
+		respondWithError(w, r, http.StatusUnsupportedMediaType, "Avatar must be a PNG or JPEG image")
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:]) + ext
+	err = cfg.writeMediaFile(name, data)
+	if err != nil {
+		logf(r.Context(), "Error storing avatar %s: %v", name, err)
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	dbUser, ok := cfg.setAvatar(w, r, userID, sql.NullString{String: mediaURLPrefix + name, Valid: true})
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(userFromDB(dbUser))
+}
+
+// handlerDeleteAvatar removes the caller's avatar.
+func (cfg *apiConfig) handlerDeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	_, ok = cfg.setAvatar(w, r, userID, sql.NullString{})
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setAvatar points userID's avatar_url at avatarURL and deletes the file of
+// the avatar it replaces once no user refers to it any more. Files are named
+// by content, so several users may share one. On failure it writes an error
+// response and returns false.
+func (cfg *apiConfig) setAvatar(w http.ResponseWriter, r *http.Request, userID uuid.UUID, avatarURL sql.NullString) (database.User, bool) {
+	previous, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return database.User{}, false
+	}
+
+	dbUser, err := cfg.dbQueries.SetUserAvatar(r.Context(), database.SetUserAvatarParams{
+		ID:        userID,
+		AvatarUrl: avatarURL,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return database.User{}, false
+	}
+
+	if previous.AvatarUrl.Valid && previous.AvatarUrl != avatarURL {
+		cfg.removeUnusedMedia(r, previous.AvatarUrl)
+	}
+	return dbUser, true
+}
+
+// removeUnusedMedia deletes the file behind avatarURL if no user's avatar
+// or chirp's image refers to it. Failures only leave a stray file behind, so they are logged.
+func (cfg *apiConfig) removeUnusedMedia(r *http.Request, avatarURL sql.NullString) {
+	name := strings.TrimPrefix(avatarURL.String, mediaURLPrefix)
+	if !mediaFileName.MatchString(name) {
+		return
+	}
+
+	count, err := cfg.dbQueries.CountUsersWithAvatar(r.Context(), avatarURL)
+	if err != nil {
+		logf(r.Context(), "Error checking whether %s is in use: %v", name, err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+	count, err = cfg.dbQueries.CountChirpsWithMedia(r.Context(), avatarURL)
+	if err != nil {
+		logf(r.Context(), "Error checking whether %s is in use: %v", name, err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	err = os.Remove(filepath.Join(cfg.mediaDir, name))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logf(r.Context(), "Error removing %s: %v", name, err)
+	}
+}
+
+// writeMediaFile stores data in the media directory under name. It writes
+// to a temporary file first so a reader never sees a partial image.
+func (cfg *apiConfig) writeMediaFile(name string, data []byte) error {
+	err := os.MkdirAll(cfg.mediaDir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(cfg.mediaDir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(tmp.Name(), 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(cfg.mediaDir, name))
+}
+
+// handlerMedia serves uploaded files. Only names in the stored format are
+// accepted, so nothing else in the media directory is reachable, and since
+// a name never changes content the response can be cached indefinitely.
+func (cfg *apiConfig) handlerMedia(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !mediaFileName.MatchString(name) {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeFile(w, r, filepath.Join(cfg.mediaDir, name))
+}
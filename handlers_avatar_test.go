@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// testPNG encodes a small image whose content depends on shade.
+func testPNG(t *testing.T, shade uint8) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for x := range 4 {
+		for y := range 4 {
+			img.Set(x, y, color.RGBA{R: shade, G: uint8(x * 60), B: uint8(y * 60), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// uploadAvatar posts data as the "avatar" file, claiming it is a PNG named
+// avatar.png whatever it really is.
+func uploadAvatar(t *testing.T, cfg *apiConfig, token string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("avatar", "avatar.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users/me/avatar", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerUploadAvatar(rr, req)
+	return rr
+}
+
+func fetchMedia(t *testing.T, cfg *apiConfig, url string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req.SetPathValue("name", strings.TrimPrefix(url, mediaURLPrefix))
+	rr := httptest.NewRecorder()
+	cfg.handlerMedia(rr, req)
+	return rr
+}
+
+func TestAvatarUpload(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+	img := testPNG(t, 0)
+
+	rr := uploadAvatar(t, cfg, token, img)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upload returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	got := decodeUser(t, rr)
+	if got.AvatarURL == nil || !strings.HasPrefix(*got.AvatarURL, mediaURLPrefix) || !strings.HasSuffix(*got.AvatarURL, ".png") {
+		t.Fatalf("avatar_url = %v, want a /media/ PNG URL", got.AvatarURL)
+	}
+	avatarURL := *got.AvatarURL
+
+	rr = fetchMedia(t, cfg, avatarURL)
+	if rr.Code != http.StatusOK || !bytes.Equal(rr.Body.Bytes(), img) {
+		t.Errorf("fetching the avatar = %v with %d bytes, want the uploaded image", rr.Code, rr.Body.Len())
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Content-Type = %q, want image/png", ct)
+	}
+
+	// The public profile carries the URL too
+	profile, _ := getProfile(t, cfg, user.ID.String())
+	if profile.AvatarURL == nil || *profile.AvatarURL != avatarURL {
+		t.Errorf("profile avatar_url = %v, want %s", profile.AvatarURL, avatarURL)
+	}
+
+	// Replacing the avatar removes the old file
+	rr = uploadAvatar(t, cfg, token, testPNG(t, 200))
+	if newURL := *decodeUser(t, rr).AvatarURL; newURL == avatarURL {
+		t.Fatal("a different image should get a different URL")
+	}
+	if rr := fetchMedia(t, cfg, avatarURL); rr.Code != http.StatusNotFound {
+		t.Errorf("replaced avatar: got status %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestAvatarDelete(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	img := testPNG(t, 0)
+
+	// Identical uploads share one file
+	avatarURL := *decodeUser(t, uploadAvatar(t, cfg, makeTestToken(t, alice.ID), img)).AvatarURL
+	uploadAvatar(t, cfg, makeTestToken(t, bob.ID), img)
+
+	deleteAvatar := func(userID uuid.UUID) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodDelete, "/api/users/me/avatar", nil)
+		req.Header.Set("Authorization", "Bearer "+makeTestToken(t, userID))
+		rr := httptest.NewRecorder()
+		cfg.handlerDeleteAvatar(rr, req)
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("delete returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+		}
+	}
+
+	deleteAvatar(alice.ID)
+	if user, _ := db.GetUserByID(t.Context(), alice.ID); user.AvatarUrl.Valid {
+		t.Errorf("avatar_url = %q after delete, want NULL", user.AvatarUrl.String)
+	}
+	if rr := fetchMedia(t, cfg, avatarURL); rr.Code != http.StatusOK {
+		t.Errorf("file still used by bob: got status %v want %v", rr.Code, http.StatusOK)
+	}
+
+	deleteAvatar(bob.ID)
+	if rr := fetchMedia(t, cfg, avatarURL); rr.Code != http.StatusNotFound {
+		t.Errorf("unused file: got status %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestAvatarRejected(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	// A PNG signature followed by junk pushes the file over the limit
+	oversized := append(testPNG(t, 0), make([]byte, maxAvatarBytes)...)
+
+	tests := []struct {
+		name string
+		data []byte
+		code int
+	}{
+		{"text named .png", []byte("definitely not an image"), http.StatusUnsupportedMediaType},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), http.StatusUnsupportedMediaType},
+		{"too large", oversized, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		if rr := uploadAvatar(t, cfg, token, tt.data); rr.Code != tt.code {
+			t.Errorf("%s: got status %v want %v", tt.name, rr.Code, tt.code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users/me/avatar", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerUploadAvatar(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("non-multipart body: got status %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	if rr := uploadAvatar(t, cfg, "not-a-token", testPNG(t, 0)); rr.Code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated: got status %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+
+	if entries, _ := os.ReadDir(cfg.mediaDir); len(entries) != 0 {
+		t.Errorf("rejected uploads left files behind: %v", entries)
+	}
+}
+
+func TestMediaOnlyServesStoredNames(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	os.WriteFile(filepath.Join(cfg.mediaDir, "secret.txt"), []byte("secret"), 0o644)
+
+	for _, name := range []string{"secret.txt", "..%2fsecret.txt", strings.Repeat("a", 64) + ".gif"} {
+		if rr := fetchMedia(t, cfg, mediaURLPrefix+name); rr.Code != http.StatusNotFound {
+			t.Errorf("%s: got status %v want %v", name, rr.Code, http.StatusNotFound)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// errCodeAccountSuspended is the error code of a request by, or for details
+// of, a user who is banned.
+const errCodeAccountSuspended = "account_suspended"
+
+// handlerBanUser bans the user in the path, for duration_seconds or until
+// unbanned when it is omitted. Their refresh tokens are revoked, and
+// requireScope turns away the access tokens they still hold.
+func (cfg *apiConfig) handlerBanUser(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		DurationSeconds *int   `json:"duration_seconds"`
+		Reason          string `json:"reason"`
+	}
+
+	adminID, ok := cfg.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if userID == adminID {
+		respondWithError(w, r, http.StatusBadRequest, "You cannot ban yourself")
+		return
+	}
+
+	// Without a body the ban is permanent and has no reason
+	reqBody := requestBody{}
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+
+	var invalid fieldErrors
+	if reqBody.DurationSeconds != nil && *reqBody.DurationSeconds <= 0 {
+		invalid.add("duration_seconds", "Duration", "must be positive")
+	}
+	if invalid.respond(w, r) {
+		return
+	}
+
+	params := database.BanUserParams{
+		ID:        userID,
+		BanReason: sql.NullString{String: reqBody.Reason, Valid: reqBody.Reason != ""},
+	}
+	if reqBody.DurationSeconds != nil {
+		params.BannedUntil = sql.NullTime{
+			Time:  time.Now().UTC().Add(time.Duration(*reqBody.DurationSeconds) * time.Second),
+			Valid: true,
+		}
+	}
+
+	banned, err := cfg.dbQueries.BanUser(r.Context(), params)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if banned == 0 {
+		respondWithError(w, r, http.StatusNotFound, "User not found")
+		return
+	}
+
+	err = cfg.dbQueries.RevokeUserRefreshTokens(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	cfg.chirpCache.invalidate()
+	cfg.audit(r, auditEntry{ActorID: adminID, Action: auditUserBan, Target: userID.String()})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerUnbanUser lifts the ban on the user in the path, if there is one.
+// They have to log in again, as their refresh tokens were revoked.
+func (cfg *apiConfig) handlerUnbanUser(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := cfg.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	unbanned, err := cfg.dbQueries.UnbanUser(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if unbanned == 0 {
+		respondWithError(w, r, http.StatusNotFound, "User not found")
+		return
+	}
+
+	cfg.chirpCache.invalidate()
+	cfg.audit(r, auditEntry{ActorID: adminID, Action: auditUserUnban, Target: userID.String()})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireActiveAccount checks that userID, who has presented valid
+// credentials, may still use them. A deleted account gets the 401 of
+// invalid credentials, and a banned one a 403 with errCodeAccountSuspended.
+// A ban past its banned_until is over without anyone lifting it.
+func (cfg *apiConfig) requireActiveAccount(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	status, err := cfg.dbQueries.GetAccountStatus(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Handlers already deal with tokens of users that are gone
+		return true
+	}
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return false
+	}
+	if status.Deleted {
+		respondWithErrorCode(w, r, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return false
+	}
+	if status.Banned {
+		respondWithErrorCode(w, r, http.StatusForbidden, errCodeAccountSuspended, "This account is suspended")
+		return false
+	}
+	return true
+}
+
+// isSuspended reports whether userID is banned right now.
+func (cfg *apiConfig) isSuspended(r *http.Request, userID uuid.UUID) (bool, error) {
+	status, err := cfg.dbQueries.GetAccountStatus(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return status.Banned, err
+}
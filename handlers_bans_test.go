@@ -0,0 +1,176 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// banFixture is a user with a chirp and a logged-in session, and an admin
+// to ban them.
+type banFixture struct {
+	srv        *httptest.Server
+	db         *fakeStore
+	user       User
+	chirp      database.Chirp
+	adminToken string
+	// The user's tokens from logging in before the ban
+	token, refreshToken string
+}
+
+func newBanFixture(t *testing.T) banFixture {
+	t.Helper()
+	cfg, db := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	admin := db.addAdmin(t, "admin@example.com")
+
+	fx := banFixture{srv: srv, db: db, adminToken: makeTestToken(t, admin.ID)}
+	if resp := call(t, srv, http.MethodPost, "/api/users", "", banCredentials, &fx.user); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("signup: got status %v want %v", resp.StatusCode, http.StatusCreated)
+	}
+	var login struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if resp := call(t, srv, http.MethodPost, "/api/login", "", banCredentials, &login); resp.StatusCode != http.StatusOK {
+		t.Fatalf("login: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	fx.token, fx.refreshToken = login.Token, login.RefreshToken
+	fx.chirp = db.addChirp(t, fx.user.ID, "Soon to be hidden")
+	return fx
+}
+
+var banCredentials = map[string]string{"email": "troll@example.com", "password": "password123"}
+
+func (fx banFixture) ban(t *testing.T, body any) {
+	t.Helper()
+	path := "/admin/users/" + fx.user.ID.String() + "/ban"
+	if resp := call(t, fx.srv, http.MethodPost, path, fx.adminToken, body, nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("ban: got status %v want %v", resp.StatusCode, http.StatusNoContent)
+	}
+}
+
+// assertSuspended checks that the user's requests and logins are refused,
+// and that their chirp is hidden from everyone else.
+func (fx banFixture) assertSuspended(t *testing.T) {
+	t.Helper()
+	var errResp ErrorResponse
+	if resp := call(t, fx.srv, http.MethodGet, "/api/chirps", fx.token, nil, &errResp); resp.StatusCode != http.StatusForbidden || errResp.Code != errCodeAccountSuspended {
+		t.Errorf("request with existing token: got status %v code %q, want %v %q", resp.StatusCode, errResp.Code, http.StatusForbidden, errCodeAccountSuspended)
+	}
+	errResp = ErrorResponse{}
+	if resp := call(t, fx.srv, http.MethodPost, "/api/login", "", banCredentials, &errResp); resp.StatusCode != http.StatusForbidden || errResp.Code != errCodeAccountSuspended {
+		t.Errorf("login: got status %v code %q, want %v %q", resp.StatusCode, errResp.Code, http.StatusForbidden, errCodeAccountSuspended)
+	}
+	if fx.listed(t) {
+		t.Error("the banned user's chirp is still listed")
+	}
+	if resp := call(t, fx.srv, http.MethodGet, "/api/chirps/"+fx.chirp.ID.String(), "", nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("the banned user's chirp: got status %v want %v", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+// assertActive checks that the user's existing token works again and that
+// their chirp is listed.
+func (fx banFixture) assertActive(t *testing.T) {
+	t.Helper()
+	if resp := call(t, fx.srv, http.MethodGet, "/api/chirps", fx.token, nil, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("request with existing token: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if !fx.listed(t) {
+		t.Error("the user's chirp is not listed")
+	}
+}
+
+func (fx banFixture) listed(t *testing.T) bool {
+	t.Helper()
+	var chirps []Chirp
+	if resp := call(t, fx.srv, http.MethodGet, "/api/chirps", "", nil, &chirps); resp.StatusCode != http.StatusOK {
+		t.Fatalf("list chirps: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	return slices.ContainsFunc(chirps, func(c Chirp) bool { return c.ID == fx.chirp.ID })
+}
+
+func TestPermanentBan(t *testing.T) {
+	fx := newBanFixture(t)
+	fx.ban(t, map[string]string{"reason": "spam"})
+	fx.assertSuspended(t)
+
+	// Refresh tokens were revoked at ban time
+	req, _ := http.NewRequest(http.MethodPost, fx.srv.URL+"/api/refresh", nil)
+	req.Header.Set("Authorization", "Bearer "+fx.refreshToken)
+	resp, err := fx.srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("refresh: got status %v want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	var errResp ErrorResponse
+	if resp := call(t, fx.srv, http.MethodGet, "/api/users/"+fx.user.ID.String()+"/stats", "", nil, &errResp); resp.StatusCode != http.StatusForbidden || errResp.Code != errCodeAccountSuspended {
+		t.Errorf("stats: got status %v code %q, want %v %q", resp.StatusCode, errResp.Code, http.StatusForbidden, errCodeAccountSuspended)
+	}
+
+	// Only an unban lifts it
+	if resp := call(t, fx.srv, http.MethodDelete, "/admin/users/"+fx.user.ID.String()+"/ban", fx.adminToken, nil, nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unban: got status %v want %v", resp.StatusCode, http.StatusNoContent)
+	}
+	fx.assertActive(t)
+	if resp := call(t, fx.srv, http.MethodPost, "/api/login", "", banCredentials, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("login after unban: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestTemporaryBanExpires(t *testing.T) {
+	fx := newBanFixture(t)
+	fx.ban(t, map[string]int{"duration_seconds": 3600})
+	fx.assertSuspended(t)
+
+	// An hour later
+	fx.db.mu.Lock()
+	for i, u := range fx.db.users {
+		if u.ID == fx.user.ID {
+			if want := time.Now().Add(time.Hour); u.BannedUntil.Time.Sub(want).Abs() > time.Minute {
+				t.Errorf("banned until %v, want about %v", u.BannedUntil.Time, want)
+			}
+			fx.db.users[i].BannedUntil.Time = time.Now().Add(-time.Second)
+		}
+	}
+	fx.db.mu.Unlock()
+
+	fx.assertActive(t)
+	if resp := call(t, fx.srv, http.MethodPost, "/api/login", "", banCredentials, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("login after the ban expired: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestBanUserErrors(t *testing.T) {
+	fx := newBanFixture(t)
+	path := "/admin/users/" + fx.user.ID.String() + "/ban"
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		body  any
+		want  int
+	}{
+		{"not an admin", path, fx.token, nil, http.StatusForbidden},
+		{"zero duration", path, fx.adminToken, map[string]int{"duration_seconds": 0}, http.StatusBadRequest},
+		{"invalid ID", "/admin/users/nope/ban", fx.adminToken, nil, http.StatusBadRequest},
+		{"unknown user", "/admin/users/" + uuid.NewString() + "/ban", fx.adminToken, nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if resp := call(t, fx.srv, http.MethodPost, tt.path, tt.token, tt.body, nil); resp.StatusCode != tt.want {
+			t.Errorf("%s: got status %v want %v", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+	fx.assertActive(t)
+}
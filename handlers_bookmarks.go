@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+)
+
+// handlerBookmarkChirp saves a chirp to the caller's private bookmarks.
+func (cfg *apiConfig) handlerBookmarkChirp(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, chirp, ok := cfg.chirpActionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Bookmarking twice is a no-op thanks to the (user_id, chirp_id) key
+	err := cfg.dbQueries.CreateBookmark(r.Context(), database.CreateBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		respondWithReferenceError(w, r, err, "Chirp not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnbookmarkChirp(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, chirp, ok := cfg.chirpActionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.dbQueries.DeleteBookmark(r.Context(), database.DeleteBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerGetBookmarks lists the caller's bookmarked chirps, most recently
+// bookmarked first. Bookmarks are private, so there is no way to list
+// anyone else's and chirps carry no bookmark count.
+func (cfg *apiConfig) handlerGetBookmarks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dbChirps, err := cfg.dbQueries.GetBookmarkedChirps(r.Context(), database.GetBookmarkedChirpsParams{
+		UserID: userID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	chirps := make([]Chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		chirps[i] = chirpFromRow(database.GetChirpsRow(dbChirp))
+	}
+
+	err = cfg.populateChirps(r, chirps)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(chirps)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func bookmarkChirp(t *testing.T, cfg *apiConfig, method string, chirpID uuid.UUID, token string) int {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/chirps/"+chirpID.String()+"/bookmark", nil)
+	req.SetPathValue("chirpID", chirpID.String())
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	if method == http.MethodPost {
+		cfg.handlerBookmarkChirp(rr, req)
+	} else {
+		cfg.handlerUnbookmarkChirp(rr, req)
+	}
+	return rr.Code
+}
+
+func getBookmarks(t *testing.T, cfg *apiConfig, token, query string) []uuid.UUID {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/bookmarks"+query, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerGetBookmarks(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("bookmarks returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	return decodeChirpIDs(t, rr)
+}
+
+func TestBookmarks(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	author := db.addUser(t, "author@example.com")
+	me := db.addUser(t, "me@example.com")
+	other := db.addUser(t, "other@example.com")
+	token := makeTestToken(t, me.ID)
+	first := db.addChirp(t, author.ID, "First")
+	second := db.addChirp(t, author.ID, "Second")
+	third := db.addChirp(t, author.ID, "Third")
+
+	// Bookmarked out of posting order; re-bookmarking is a no-op
+	for _, id := range []uuid.UUID{second.ID, first.ID, third.ID, first.ID} {
+		if code := bookmarkChirp(t, cfg, http.MethodPost, id, token); code != http.StatusNoContent {
+			t.Fatalf("bookmark returned wrong status code: got %v want %v", code, http.StatusNoContent)
+		}
+	}
+	bookmarkChirp(t, cfg, http.MethodPost, second.ID, makeTestToken(t, other.ID))
+
+	if got, want := getBookmarks(t, cfg, token, ""), []uuid.UUID{third.ID, first.ID, second.ID}; !slices.Equal(got, want) {
+		t.Errorf("bookmarks = %v, want %v newest bookmark first", got, want)
+	}
+	if got, want := getBookmarks(t, cfg, token, "?limit=1&offset=1"), []uuid.UUID{first.ID}; !slices.Equal(got, want) {
+		t.Errorf("second page = %v, want %v", got, want)
+	}
+	if got, want := getBookmarks(t, cfg, makeTestToken(t, other.ID), ""), []uuid.UUID{second.ID}; !slices.Equal(got, want) {
+		t.Errorf("other user's bookmarks = %v, want %v", got, want)
+	}
+
+	// Bookmarks are private, so chirps carry no count
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+second.ID.String(), nil)
+	req.SetPathValue("chirpID", second.ID.String())
+	cfg.handlerGetChirpByID(rr, req)
+	if strings.Contains(rr.Body.String(), "bookmark") {
+		t.Errorf("chirp JSON mentions bookmarks: %s", rr.Body)
+	}
+
+	// A deleted chirp drops out of the list
+	if err := db.DeleteChirp(t.Context(), third.ID); err != nil {
+		t.Fatal(err)
+	}
+	bookmarkChirp(t, cfg, http.MethodDelete, second.ID, token)
+	if got, want := getBookmarks(t, cfg, token, ""), []uuid.UUID{first.ID}; !slices.Equal(got, want) {
+		t.Errorf("bookmarks = %v, want %v after deleting and unbookmarking", got, want)
+	}
+}
+
+func TestBookmarkErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	for _, method := range []string{http.MethodPost, http.MethodDelete} {
+		if code := bookmarkChirp(t, cfg, method, uuid.New(), token); code != http.StatusNotFound {
+			t.Errorf("%s unknown chirp: got status %v want %v", method, code, http.StatusNotFound)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	cfg.handlerGetBookmarks(rr, httptest.NewRequest(http.MethodGet, "/api/bookmarks", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated list: got status %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+}
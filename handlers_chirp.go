@@ -1,271 +1,716 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
 	"slices"
-	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
-	"github.com/AlexTLDR/chirpy/internal/auth"
 	"github.com/AlexTLDR/chirpy/internal/database"
 	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
 )
 
-func (cfg *apiConfig) handlerChirps(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodPost:
-		cfg.handlerCreateChirp(w, r)
-	case http.MethodGet:
-		// Parse the path to see if we have an ID
-		pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-
-		if len(pathParts) == 2 && pathParts[0] == "api" && pathParts[1] == "chirps" {
-			// Get all chirps
-			cfg.handlerGetChirps(w, r)
-		} else if len(pathParts) == 3 && pathParts[0] == "api" && pathParts[1] == "chirps" {
-			// Get specific chirp by ID
-			chirpIDStr := pathParts[2]
-			cfg.handlerGetChirpByID(w, r, chirpIDStr)
-		} else {
-			w.WriteHeader(http.StatusNotFound)
-		}
-	case http.MethodDelete:
-		// Parse the path to get chirp ID
-		pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-		
-		if len(pathParts) == 3 && pathParts[0] == "api" && pathParts[1] == "chirps" {
-			// Delete specific chirp by ID
-			chirpIDStr := pathParts[2]
-			cfg.handlerDeleteChirp(w, r, chirpIDStr)
-		} else {
-			w.WriteHeader(http.StatusNotFound)
-		}
-	default:
-		w.WriteHeader(http.StatusMethodNotAllowed)
-	}
-}
+// Chirp body limits. Chirpy Red members get the longer one.
+const (
+	maxChirpLength    = 140
+	maxRedChirpLength = 280
+)
 
 func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, r *http.Request) {
 	type requestBody struct {
-		Body string `json:"body"`
+		Body          string     `json:"body"`
+		ParentChirpID *uuid.UUID `json:"parent_chirp_id"`
+		MediaURL      string     `json:"media_url"`
+		MediaAlt      string     `json:"media_alt"`
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 
-	// Extract and validate JWT token
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	decoder := json.NewDecoder(r.Body)
+	reqBody := requestBody{}
+	err := decoder.Decode(&reqBody)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	reqBody := requestBody{}
-	err = decoder.Decode(&reqBody)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+	// A retried request with the same Idempotency-Key gets the first response
+	idempotent, ok := cfg.beginIdempotentRequest(w, r, userID, reqBody)
+	if !ok {
 		return
 	}
+	defer idempotent.abandon(r)
 
-	if reqBody.Body == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Body is required"})
+	limit, err := cfg.chirpLengthLimit(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
 		return
 	}
 
-	if len(reqBody.Body) > 140 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Chirp is too long"})
+	// Every invalid field is reported at once
+	var invalid fieldErrors
+	cleanedBody, err := validateChirp(reqBody.Body, limit)
+	if err != nil {
+		invalidChirpBody(&invalid, err)
+	}
+
+	// Replies must point at an existing chirp the caller can see
+	parentChirpID := uuid.NullUUID{}
+	var parentAuthorID uuid.UUID
+	if reqBody.ParentChirpID != nil {
+		parent, err := cfg.getVisibleChirp(r, *reqBody.ParentChirpID)
+		if errors.Is(err, sql.ErrNoRows) {
+			invalid.add("parent_chirp_id", "Parent chirp", "not found")
+		} else if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+		parentChirpID = uuid.NullUUID{UUID: *reqBody.ParentChirpID, Valid: true}
+		parentAuthorID = parent.Chirp.UserID
+	}
+
+	mediaURL, mediaAlt := cfg.validateChirpMedia(r, &invalid, reqBody.MediaURL, reqBody.MediaAlt)
+
+	if invalid.respond(w, r) {
 		return
 	}
 
-	// Clean profane words
-	cleanedBody := cleanProfanity(reqBody.Body)
+	// Double submits are compared after cleaning, like the stored body
+	if cfg.duplicateChirpWindow > 0 {
+		duplicate, err := cfg.dbQueries.HasRecentDuplicateChirp(r.Context(), database.HasRecentDuplicateChirpParams{
+			UserID:        userID,
+			Body:          cleanedBody,
+			ParentChirpID: parentChirpID,
+			Since:         time.Now().UTC().Add(-cfg.duplicateChirpWindow),
+		})
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+		if duplicate {
+			respondWithError(w, r, http.StatusConflict, "Duplicate chirp: you posted the same text recently")
+			return
+		}
+	}
+
+	// Only a chirp about to be stored counts; replays and rejected
+	// attempts above are free
+	if !cfg.allowChirps(w, r, userID, 1) {
+		return
+	}
 
 	dbChirp, err := cfg.dbQueries.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleanedBody,
-		UserID: userID,
+		Body:          cleanedBody,
+		UserID:        userID,
+		ParentChirpID: parentChirpID,
+		MediaUrl:      mediaURL,
+		MediaAlt:      mediaAlt,
 	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+		respondWithReferenceError(w, r, err, "Parent chirp not found")
 		return
 	}
 
-	chirp := Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
-	}
+	chirp := cfg.announceChirp(r.Context(), dbChirp, parentAuthorID)
+	cfg.chirpCache.invalidate()
+
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(chirp)
+	idempotent.complete(r, body.Bytes())
 
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(chirp)
+	w.Write(body.Bytes())
+}
+
+// announceChirp does the follow-up work for a newly stored chirp: indexing
+// its hashtags, mentions and links, notifying the users it replies to or mentions,
+// and publishing it to live streams if its author is public. parentAuthorID
+// is only used for replies. The chirp is already stored, so failures only
+// cost discoverability and are logged. Callers invalidate the chirp list cache.
+func (cfg *apiConfig) announceChirp(ctx context.Context, dbChirp database.Chirp, parentAuthorID uuid.UUID) Chirp {
+	err := cfg.indexHashtags(ctx, dbChirp.ID, dbChirp.Body)
+	if err != nil {
+		logf(ctx, "Error indexing hashtags for chirp %s: %v", dbChirp.ID, err)
+	}
+
+	chirp := chirpFromDB(dbChirp)
+
+	// Unresolvable mentions are not an error; a failed lookup is only logged
+	chirp.Mentions, err = cfg.storeMentions(ctx, dbChirp.ID, dbChirp.Body)
+	if err != nil {
+		logf(ctx, "Error storing mentions for chirp %s: %v", dbChirp.ID, err)
+	}
+
+	chirp.Entities.URLs, err = cfg.indexURLs(ctx, dbChirp.ID, dbChirp.Body)
+	if err != nil {
+		logf(ctx, "Error indexing links for chirp %s: %v", dbChirp.ID, err)
+	}
+
+	isReply := dbChirp.ParentChirpID.Valid
+	chirpRef := uuid.NullUUID{UUID: dbChirp.ID, Valid: true}
+	if isReply {
+		cfg.notify(ctx, notificationEvent{
+			Type:    notificationReply,
+			UserID:  parentAuthorID,
+			ActorID: dbChirp.UserID,
+			ChirpID: chirpRef,
+		})
+	}
+	for _, mentionedID := range chirp.Mentions {
+		// A reply that also mentions the parent's author only notifies once
+		if isReply && mentionedID == parentAuthorID {
+			continue
+		}
+		cfg.notify(ctx, notificationEvent{
+			Type:    notificationMention,
+			UserID:  mentionedID,
+			ActorID: dbChirp.UserID,
+			ChirpID: chirpRef,
+		})
+	}
+
+	// Live streams are anonymous, so they only carry public chirps
+	public, err := cfg.dbQueries.CanViewUser(ctx, database.CanViewUserParams{AuthorID: dbChirp.UserID})
+	if err != nil {
+		logf(ctx, "Error checking visibility of chirp %s: %v", dbChirp.ID, err)
+	} else if public {
+		cfg.chirpHub.publish(chirp)
+	}
+	return chirp
 }
 
 func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Check for author_id query parameter
+	// Check for author_id and sort query parameters
 	authorIDStr := r.URL.Query().Get("author_id")
-	
-	var dbChirps []database.Chirp
-	var err error
-	
+	sortParam := r.URL.Query().Get("sort")
+
+	if sortParam != "" && sortParam != "asc" && sortParam != "desc" {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid sort: use asc or desc")
+		return
+	}
+
+	var authorID uuid.UUID
 	if authorIDStr != "" {
 		// Parse the author ID
-		authorID, parseErr := uuid.Parse(authorIDStr)
-		if parseErr != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid author ID"})
+		var err error
+		authorID, err = uuid.Parse(authorIDStr)
+		if err != nil {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid author ID")
 			return
 		}
-		
-		// Get chirps by specific author
-		dbChirps, err = cfg.dbQueries.GetChirpsByUserID(r.Context(), authorID)
-	} else {
-		// Get all chirps
-		dbChirps, err = cfg.dbQueries.GetChirps(r.Context())
 	}
-	
+
+	createdFrom, createdBefore, err := parseCreatedRange(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	chirps := make([]Chirp, len(dbChirps))
-	for i, dbChirp := range dbChirps {
-		chirps[i] = Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    dbChirp.UserID,
+	includeAuthor, err := parseIncludeAuthor(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fields, err := parseChirpFields(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// pinned_first puts the author's pinned chirp at the top
+	pinnedFirst := false
+	if pinnedFirstStr := r.URL.Query().Get("pinned_first"); pinnedFirstStr != "" {
+		pinnedFirst, err = strconv.ParseBool(pinnedFirstStr)
+		if err != nil {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid pinned_first")
+			return
+		}
+		if pinnedFirst && authorIDStr == "" {
+			respondWithError(w, r, http.StatusBadRequest, "pinned_first requires author_id")
+			return
 		}
 	}
 
-	// Check for sort query parameter
-	sortParam := r.URL.Query().Get("sort")
-	
-	// Sort chirps based on the sort parameter (default is ascending)
-	if sortParam == "desc" {
-		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[i].CreatedAt.After(chirps[j].CreatedAt)
-		})
-	} else {
-		// Default to ascending order (asc or no parameter)
-		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[i].CreatedAt.Before(chirps[j].CreatedAt)
-		})
+	cacheKey := url.Values{
+		"author_id": {authorIDStr},
+		"sort":      {sortParam},
+		"from":      {r.URL.Query().Get("from")},
+		"to":        {r.URL.Query().Get("to")},
+		"include":   {r.URL.Query().Get("include")},
+	}.Encode()
+	chirps, generation, cached := cfg.chirpCache.get(cacheKey)
+	if !cached {
+		var dbChirps []database.GetChirpsRow
+		var authors []ChirpAuthor
+		var err error
+
+		if includeAuthor {
+			// Authors are joined in so clients need not fetch each one
+			var rows []database.GetChirpsWithAuthorsRow
+			rows, err = cfg.dbQueries.GetChirpsWithAuthors(r.Context(), database.GetChirpsWithAuthorsParams{
+				AuthorID:      uuid.NullUUID{UUID: authorID, Valid: authorIDStr != ""},
+				CreatedFrom:   createdFrom,
+				CreatedBefore: createdBefore,
+			})
+			for _, row := range rows {
+				dbChirps = append(dbChirps, database.GetChirpsRow{Chirp: row.Chirp, LikeCount: row.LikeCount, ReplyCount: row.ReplyCount})
+				authors = append(authors, cfg.authorFromRow(row))
+			}
+		} else if authorIDStr != "" {
+			// Get chirps by specific author
+			var authorChirps []database.GetChirpsByUserIDRow
+			authorChirps, err = cfg.dbQueries.GetChirpsByUserID(r.Context(), database.GetChirpsByUserIDParams{
+				UserID:        authorID,
+				CreatedFrom:   createdFrom,
+				CreatedBefore: createdBefore,
+			})
+			for _, row := range authorChirps {
+				dbChirps = append(dbChirps, database.GetChirpsRow(row))
+			}
+		} else {
+			// Get all chirps
+			dbChirps, err = cfg.dbQueries.GetChirps(r.Context(), database.GetChirpsParams{
+				CreatedFrom:   createdFrom,
+				CreatedBefore: createdBefore,
+			})
+		}
+
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+
+		chirps = make([]Chirp, len(dbChirps))
+		for i, dbChirp := range dbChirps {
+			chirps[i] = chirpFromRow(dbChirp)
+			if authors != nil {
+				chirps[i].Author = &authors[i]
+			}
+		}
+
+		err = cfg.attachMentions(r, chirps)
+		if err == nil {
+			err = cfg.attachEntities(r, chirps)
+		}
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+
+		// The queries return newest first with the ID as tiebreaker;
+		// ascending is the exact reverse, so ties are stable both ways
+		if sortParam == "asc" {
+			slices.Reverse(chirps)
+		}
+
+		cfg.chirpCache.put(cacheKey, generation, chirps)
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(chirps)
+	// Private accounts depend on the viewer too, so cached lists hold
+	// every chirp and are filtered per request
+	chirps, err = cfg.filterVisible(r, chirps)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	// Pins change without touching any chirp, so they are applied after
+	// the cache
+	if pinnedFirst {
+		chirps, err = cfg.pinnedChirpFirst(r.Context(), authorID, chirps)
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+	}
+
+	// liked_by_me depends on the viewer, so it is never cached
+	err = cfg.markLikedByMe(r, chirps)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	if cfg.countListViews {
+		cfg.countViews(r, chirps)
+	}
+
+	writeJSONWithETag(w, r, sparseChirps(chirps, fields), listLastModified(chirps, cfg.chirpCache.lastInvalidated()))
 }
 
-func (cfg *apiConfig) handlerGetChirpByID(w http.ResponseWriter, r *http.Request, chirpIDStr string) {
+// parseIncludeAuthor reads the include query parameter, a comma-separated
+// list of related objects to embed in each chirp. Only "author" exists.
+func parseIncludeAuthor(r *http.Request) (bool, error) {
+	include := r.URL.Query().Get("include")
+	if include == "" {
+		return false, nil
+	}
+	for _, name := range strings.Split(include, ",") {
+		if name != "author" {
+			return false, fmt.Errorf("Invalid include: %q is not supported", name)
+		}
+	}
+	return true, nil
+}
+
+// parseCreatedRange reads the from and to query parameters, each an RFC 3339
+// timestamp or a YYYY-MM-DD date in UTC. Both bounds are inclusive and a
+// bare date as to covers that whole day, so the result is returned as the
+// half-open range [from, before).
+func parseCreatedRange(r *http.Request) (from, before sql.NullTime, err error) {
+	parse := func(name string, wholeDay bool) (sql.NullTime, error) {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			return sql.NullTime{}, nil
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err == nil {
+			if wholeDay {
+				// Stored timestamps have microsecond precision
+				t = t.Add(time.Microsecond)
+			}
+			return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+		}
+		t, err = time.Parse(time.DateOnly, value)
+		if err != nil {
+			return sql.NullTime{}, fmt.Errorf("Invalid %s: expected an RFC 3339 timestamp or a YYYY-MM-DD date", name)
+		}
+		if wholeDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return sql.NullTime{Time: t, Valid: true}, nil
+	}
+
+	from, err = parse("from", false)
+	if err != nil {
+		return sql.NullTime{}, sql.NullTime{}, err
+	}
+	before, err = parse("to", true)
+	if err != nil {
+		return sql.NullTime{}, sql.NullTime{}, err
+	}
+	if from.Valid && before.Valid && !from.Time.Before(before.Time) {
+		return sql.NullTime{}, sql.NullTime{}, errors.New("Invalid to: must not be before from")
+	}
+	return from, before, nil
+}
+
+func (cfg *apiConfig) handlerGetChirpByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	chirpID, err := uuid.Parse(chirpIDStr)
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid chirp ID"})
+		respondWithError(w, r, http.StatusBadRequest, "Invalid chirp ID")
 		return
 	}
 
-	dbChirp, err := cfg.dbQueries.GetChirpByID(r.Context(), chirpID)
+	includeAuthor, err := parseIncludeAuthor(r)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Chirp not found"})
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	chirp := Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
+	fields, err := parseChirpFields(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(chirp)
+	var chirps []Chirp
+	if includeAuthor {
+		row, err := cfg.dbQueries.GetChirpByIDWithAuthor(r.Context(), chirpID)
+		if err == nil {
+			var visible bool
+			visible, err = cfg.canView(r, row.Chirp.UserID)
+			if err == nil && !visible {
+				err = sql.ErrNoRows
+			}
+		}
+		if err != nil {
+			respondWithLookupError(w, r, err, "Chirp not found")
+			return
+		}
+		author := cfg.authorFromRow(database.GetChirpsWithAuthorsRow(row))
+		chirps = []Chirp{chirpFromRow(database.GetChirpsRow{Chirp: row.Chirp, LikeCount: row.LikeCount, ReplyCount: row.ReplyCount})}
+		chirps[0].Author = &author
+	} else {
+		dbChirp, err := cfg.getVisibleChirp(r, chirpID)
+		if err != nil {
+			respondWithLookupError(w, r, err, "Chirp not found")
+			return
+		}
+		chirps = []Chirp{chirpFromRow(database.GetChirpsRow(dbChirp))}
+	}
+
+	err = cfg.populateChirps(r, chirps)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	cfg.countViews(r, chirps)
+
+	writeJSONWithETag(w, r, sparseChirp(&chirps[0], fields), chirps[0].UpdatedAt)
 }
 
-func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request, chirpIDStr string) {
+func (cfg *apiConfig) handlerGetChirpReplies(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Extract and validate JWT token
-	token, err := auth.GetBearerToken(r.Header)
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Deleting a parent detaches its replies (parent_chirp_id is set to NULL),
+	// so a deleted parent simply 404s here while the replies live on.
+	_, err = cfg.getVisibleChirp(r, chirpID)
+	if err != nil {
+		respondWithLookupError(w, r, err, "Chirp not found")
+		return
+	}
+
+	dbReplies, err := cfg.dbQueries.GetChirpReplies(r.Context(), database.GetChirpRepliesParams{
+		ParentID: chirpID,
+		ViewerID: cfg.viewer(r),
+		Limit:    limit,
+		Offset:   offset,
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		respondWithDBError(w, r, err)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	replies := make([]Chirp, len(dbReplies))
+	for i, dbReply := range dbReplies {
+		replies[i] = chirpFromRow(database.GetChirpsRow(dbReply))
+	}
+
+	err = cfg.populateChirps(r, replies)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(replies)
+}
+
+func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
 		return
 	}
 
 	// Parse chirp ID
-	chirpID, err := uuid.Parse(chirpIDStr)
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid chirp ID"})
+		respondWithError(w, r, http.StatusBadRequest, "Invalid chirp ID")
 		return
 	}
 
 	// Get the chirp to check if it exists and if user owns it
-	dbChirp, err := cfg.dbQueries.GetChirpByID(r.Context(), chirpID)
+	dbChirp, err := cfg.dbQueries.GetChirpByID(withPrimary(r.Context()), chirpID)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Chirp not found"})
+		respondWithLookupError(w, r, err, "Chirp not found")
 		return
 	}
 
-	// Check if the user is the author of the chirp
-	if dbChirp.UserID != userID {
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "You can only delete your own chirps"})
-		return
+	// Only the author or an admin may delete the chirp
+	byAdmin := dbChirp.Chirp.UserID != userID
+	if byAdmin {
+		isAdmin, err := cfg.isAdmin(r.Context(), userID)
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+		if !isAdmin {
+			respondWithError(w, r, http.StatusForbidden, "You can only delete your own chirps")
+			return
+		}
 	}
 
 	// Delete the chirp
 	err = cfg.dbQueries.DeleteChirp(r.Context(), chirpID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+		respondWithDBError(w, r, err)
 		return
 	}
+	cfg.chirpCache.invalidate()
+	if byAdmin {
+		cfg.audit(r, auditEntry{ActorID: userID, Action: auditChirpDelete, Target: chirpID.String()})
+	}
 
 	// Return 204 No Content
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// chirpFromDB converts a database chirp into its JSON representation.
+func chirpFromDB(dbChirp database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:        dbChirp.ID,
+		CreatedAt: dbChirp.CreatedAt,
+		UpdatedAt: dbChirp.UpdatedAt,
+		Body:      dbChirp.Body,
+		UserID:    dbChirp.UserID,
+		Edited:    dbChirp.UpdatedAt.After(dbChirp.CreatedAt),
+		ViewCount: dbChirp.ViewCount,
+		MediaURL:  nullableString(dbChirp.MediaUrl),
+		MediaAlt:  nullableString(dbChirp.MediaAlt),
+		Mentions:  []uuid.UUID{},
+		Entities:  ChirpEntities{URLs: []URLEntity{}},
+	}
+	if dbChirp.ParentChirpID.Valid {
+		chirp.ParentChirpID = &dbChirp.ParentChirpID.UUID
+	}
+	return chirp
+}
+
+// chirpFromRow converts a chirp read together with its aggregate counts.
+// The per-query row types share one layout, so callers convert them to
+// database.GetChirpsRow first.
+func chirpFromRow(row database.GetChirpsRow) Chirp {
+	chirp := chirpFromDB(row.Chirp)
+	chirp.LikeCount = row.LikeCount
+	chirp.ReplyCount = row.ReplyCount
+	return chirp
+}
+
+// authorFromRow returns the author profile joined into a chirp row. Nothing
+// of a deleted author's is left to return.
+func (cfg *apiConfig) authorFromRow(row database.GetChirpsWithAuthorsRow) ChirpAuthor {
+	if row.AuthorDeleted {
+		return ChirpAuthor{Deleted: true}
+	}
+	return ChirpAuthor{PublicUser: &PublicUser{
+		ID:          row.Chirp.UserID,
+		Email:       cfg.publicEmail(row.AuthorEmail),
+		Username:    row.AuthorUsername,
+		DisplayName: nullableString(row.AuthorDisplayName),
+		Bio:         nullableString(row.AuthorBio),
+		AvatarURL:   nullableString(row.AuthorAvatarUrl),
+		IsChirpyRed: row.AuthorIsChirpyRed,
+	}}
+}
+
+// populateChirps loads the per-chirp data that is not part of the chirp
+// rows themselves: mentions, entities, and liked_by_me for authenticated
+// requests.
+func (cfg *apiConfig) populateChirps(r *http.Request, chirps []Chirp) error {
+	err := cfg.attachMentions(r, chirps)
+	if err != nil {
+		return err
+	}
+	err = cfg.attachEntities(r, chirps)
+	if err != nil {
+		return err
+	}
+	return cfg.markLikedByMe(r, chirps)
+}
+
+// chirpLengthLimit returns the longest body userID may post.
+func (cfg *apiConfig) chirpLengthLimit(ctx context.Context, userID uuid.UUID) (int, error) {
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if user.IsChirpyRed {
+		return maxRedChirpLength, nil
+	}
+	return maxChirpLength, nil
+}
+
+// errChirpEmpty is returned by validateChirp for an empty body.
+var errChirpEmpty = errors.New("is required")
+
+// errChirpControlChars is returned by validateChirp for a body with control
+// characters other than newlines and tabs.
+var errChirpControlChars = errors.New("must not contain control characters other than newlines and tabs")
+
+// chirpTooLongError is returned by validateChirp for a body over the limit,
+// which it names.
+type chirpTooLongError struct {
+	maxLen int
+}
+
+func (e chirpTooLongError) Error() string {
+	return fmt.Sprintf("is too long (max %d characters)", e.maxLen)
+}
+
+// validateChirp checks a chirp body against maxLen, counted in characters
+// rather than bytes, and returns it with profanity masked. The body is
+// trimmed and NFC-normalized first, so a body of only whitespace or
+// invisible characters counts as empty and a precomposed accent counts the
+// same as its decomposed form. Its errors read as reasons for
+// fieldErrors.add.
+func validateChirp(body string, maxLen int) (cleaned string, err error) {
+	body = norm.NFC.String(strings.TrimFunc(body, isBlankRune))
+	if body == "" {
+		return "", errChirpEmpty
+	}
+	if hasControlChars(body, "\n\t") {
+		return "", errChirpControlChars
+	}
+	if utf8.RuneCountInString(body) > maxLen {
+		return "", chirpTooLongError{maxLen: maxLen}
+	}
+	return cleanProfanity(body), nil
+}
+
+// isBlankRune reports whether r renders as nothing: whitespace, or a format
+// character such as a zero-width space or joiner.
+func isBlankRune(r rune) bool {
+	return unicode.IsSpace(r) || unicode.Is(unicode.Cf, r)
+}
+
+// invalidChirpBody records a validateChirp error against the body field.
+func invalidChirpBody(invalid *fieldErrors, err error) {
+	label := "Body"
+	var tooLong chirpTooLongError
+	if errors.As(err, &tooLong) {
+		label = "Chirp"
+	}
+	invalid.add("body", label, err.Error())
+}
+
+// nonSpace matches the words cleanProfanity looks at; the whitespace
+// between them, newlines and tabs included, is left as it was.
+var nonSpace = regexp.MustCompile(`\S+`)
+
 func cleanProfanity(text string) string {
 	profaneWords := []string{"kerfuffle", "sharbert", "fornax"}
-	words := strings.Fields(text)
-
-	for i, word := range words {
+	return nonSpace.ReplaceAllStringFunc(text, func(word string) string {
 		if slices.Contains(profaneWords, strings.ToLower(word)) {
-			words[i] = "****"
+			return "****"
 		}
-	}
-
-	return strings.Join(words, " ")
+		return word
+	})
 }
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxBulkChirps = 100
+	// maxBulkRequestBytes fits a full batch of the longest chirps even if
+	// every character is escaped, with room for the JSON around them
+	maxBulkRequestBytes = maxBulkChirps*maxRedChirpLength*6 + 64<<10
+)
+
+// handlerCreateChirps creates up to maxBulkChirps chirps for the caller in
+// one all-or-nothing request. Each item is validated like a single chirp;
+// if any is invalid nothing is stored and the 400 lists what is wrong with
+// each item. Replies are not supported in bulk.
+func (cfg *apiConfig) handlerCreateChirps(w http.ResponseWriter, r *http.Request) {
+	type requestItem struct {
+		Body string `json:"body"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkRequestBytes)
+	var items []requestItem
+	err := json.NewDecoder(r.Body).Decode(&items)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithError(w, r, http.StatusRequestEntityTooLarge, "Request body is too large")
+			return
+		}
+		respondWithError(w, r, http.StatusBadRequest, "Expected a JSON array of chirps")
+		return
+	}
+	if len(items) == 0 {
+		respondWithError(w, r, http.StatusBadRequest, "At least one chirp is required")
+		return
+	}
+	if len(items) > maxBulkChirps {
+		respondWithError(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("At most %d chirps can be created at once", maxBulkChirps))
+		return
+	}
+
+	limit, err := cfg.chirpLengthLimit(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	bodies := make([]string, len(items))
+	results := make([]BulkChirpResult, len(items))
+	invalidCount := 0
+	for i, item := range items {
+		bodies[i], err = validateChirp(item.Body, limit)
+		if err != nil {
+			var invalid fieldErrors
+			invalidChirpBody(&invalid, err)
+			results[i] = BulkChirpResult{Error: invalid.messages[0], Fields: invalid.fields}
+			invalidCount++
+		}
+	}
+	if invalidCount > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(BulkChirpsResponse{
+			Error:     fmt.Sprintf("%d of %d chirps are invalid; none were created", invalidCount, len(items)),
+			RequestID: requestID(r.Context()),
+			Results:   results,
+		})
+		return
+	}
+
+	// The whole batch counts against the daily quota and the rate limit,
+	// as if each chirp had been posted on its own
+	if !cfg.allowChirps(w, r, userID, len(items)) {
+		return
+	}
+
+	dbChirps, err := cfg.dbQueries.CreateChirps(r.Context(), database.CreateChirpsParams{
+		UserID: userID,
+		Bodies: bodies,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if len(dbChirps) != len(items) {
+		logf(r.Context(), "Bulk insert stored %d of %d chirps", len(dbChirps), len(items))
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	// created_at follows the order of the bodies, which lines the rows up
+	// with the request items
+	slices.SortFunc(dbChirps, func(a, b database.Chirp) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	for i, dbChirp := range dbChirps {
+		chirp := cfg.announceChirp(r.Context(), dbChirp, uuid.Nil)
+		results[i] = BulkChirpResult{Chirp: &chirp}
+	}
+	cfg.chirpCache.invalidate()
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(BulkChirpsResponse{Results: results})
+}
+
+// handlerDeleteChirps deletes several of the caller's chirps at once, given
+// either up to maxBulkChirps ids or a before time that selects every chirp
+// they posted earlier. It runs as a single statement, so either all of the
+// matching chirps are deleted or none are. Requested IDs that are someone
+// else's, or that do not exist, are skipped rather than failing the batch.
+func (cfg *apiConfig) handlerDeleteChirps(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		IDs    []uuid.UUID `json:"ids"`
+		Before *time.Time  `json:"before"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkRequestBytes)
+	reqBody := requestBody{}
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil && !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithError(w, r, http.StatusRequestEntityTooLarge, "Request body is too large")
+			return
+		}
+		respondWithError(w, r, http.StatusBadRequest, "Expected a JSON object with ids or before")
+		return
+	}
+
+	var deleted []uuid.UUID
+	switch {
+	case reqBody.IDs != nil && reqBody.Before != nil:
+		respondWithError(w, r, http.StatusBadRequest, "Give either ids or before, not both")
+		return
+	case reqBody.Before != nil:
+		deleted, err = cfg.dbQueries.DeleteUserChirpsBefore(r.Context(), database.DeleteUserChirpsBeforeParams{
+			UserID: userID,
+			Before: *reqBody.Before,
+		})
+	case len(reqBody.IDs) > maxBulkChirps:
+		respondWithError(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("At most %d chirps can be deleted at once", maxBulkChirps))
+		return
+	case len(reqBody.IDs) > 0:
+		deleted, err = cfg.dbQueries.DeleteUserChirpsByIDs(r.Context(), database.DeleteUserChirpsByIDsParams{
+			ChirpIds: reqBody.IDs,
+			UserID:   userID,
+		})
+	default:
+		respondWithError(w, r, http.StatusBadRequest, "At least one chirp ID or a before time is required")
+		return
+	}
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if len(deleted) > 0 {
+		cfg.chirpCache.invalidate()
+	}
+
+	skipped := []uuid.UUID{}
+	for _, id := range reqBody.IDs {
+		if !slices.Contains(deleted, id) && !slices.Contains(skipped, id) {
+			skipped = append(skipped, id)
+		}
+	}
+
+	json.NewEncoder(w).Encode(BulkDeleteChirpsResponse{Deleted: len(deleted), Skipped: skipped})
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func postChirps(t *testing.T, cfg *apiConfig, token string, payload any) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/chirps/bulk", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerCreateChirps(rr, req)
+	return rr
+}
+
+func bulkItems(bodies ...string) []map[string]string {
+	items := make([]map[string]string, len(bodies))
+	for i, body := range bodies {
+		items[i] = map[string]string{"body": body}
+	}
+	return items
+}
+
+func TestBulkCreateChirps(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	token := makeTestToken(t, alice.ID)
+
+	rr := postChirps(t, cfg, token, bulkItems("First #archive", "What a kerfuffle", "Hi @"+bob.Username))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got status %v want %v: %s", rr.Code, http.StatusCreated, rr.Body)
+	}
+	var resp BulkChirpsResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if len(resp.Results) != 3 || resp.Error != "" {
+		t.Fatalf("response = %+v, want three created chirps", resp)
+	}
+	wantBodies := []string{"First #archive", "What a ****", "Hi @" + bob.Username}
+	for i, result := range resp.Results {
+		if result.Chirp == nil || result.Chirp.Body != wantBodies[i] || result.Chirp.UserID != alice.ID {
+			t.Errorf("result %d = %+v, want %q by alice", i, result, wantBodies[i])
+		}
+	}
+	if !resp.Results[0].Chirp.CreatedAt.Before(resp.Results[2].Chirp.CreatedAt) {
+		t.Error("chirps should be created in the given order")
+	}
+	if mentions := resp.Results[2].Chirp.Mentions; len(mentions) != 1 || mentions[0] != bob.ID {
+		t.Errorf("mentions = %v, want bob", mentions)
+	}
+
+	// They show up like any other chirp
+	list := httptest.NewRecorder()
+	cfg.handlerGetChirps(list, httptest.NewRequest(http.MethodGet, "/api/chirps", nil))
+	var chirps []Chirp
+	json.NewDecoder(list.Body).Decode(&chirps)
+	if len(chirps) != 3 {
+		t.Errorf("listed %d chirps, want 3", len(chirps))
+	}
+}
+
+func TestBulkCreateChirpsAllOrNothing(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	rr := postChirps(t, cfg, token, bulkItems("Fine", "", strings.Repeat("é", maxChirpLength), strings.Repeat("a", maxChirpLength+1)))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("got status %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	var resp BulkChirpsResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if !strings.Contains(resp.Error, "2 of 4") || len(resp.Results) != 4 {
+		t.Fatalf("response = %+v, want 2 of 4 invalid with a result per item", resp)
+	}
+	want := []string{"", "is required", "", "is too long (max 140 characters)"}
+	for i, result := range resp.Results {
+		if result.Chirp != nil || result.Fields["body"] != want[i] || (want[i] == "") != (result.Error == "") {
+			t.Errorf("result %d = %+v, want body error %q", i, result, want[i])
+		}
+	}
+
+	db.mu.Lock()
+	stored := len(db.chirps)
+	db.mu.Unlock()
+	if stored != 0 {
+		t.Errorf("%d chirps were stored from a rejected batch", stored)
+	}
+}
+
+func TestBulkCreateChirpsLimits(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	full := make([]string, maxBulkChirps)
+	for i := range full {
+		full[i] = "chirp"
+	}
+	if rr := postChirps(t, cfg, token, bulkItems(full...)); rr.Code != http.StatusCreated {
+		t.Errorf("%d chirps: got status %v want %v", maxBulkChirps, rr.Code, http.StatusCreated)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		payload any
+		want    int
+	}{
+		{"too many", token, bulkItems(append(full, "one more")...), http.StatusRequestEntityTooLarge},
+		{"huge body", token, bulkItems(strings.Repeat("a", maxBulkRequestBytes)), http.StatusRequestEntityTooLarge},
+		{"empty", token, bulkItems(), http.StatusBadRequest},
+		{"not an array", token, map[string]string{"body": "hi"}, http.StatusBadRequest},
+		{"no token", "", bulkItems("hi"), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if rr := postChirps(t, cfg, tt.token, tt.payload); rr.Code != tt.want {
+			t.Errorf("%s: got status %v want %v", tt.name, rr.Code, tt.want)
+		}
+	}
+}
+
+func deleteChirps(t *testing.T, cfg *apiConfig, token string, payload any) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/chirps/bulk_delete", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerDeleteChirps(rr, req)
+	return rr
+}
+
+func TestBulkDeleteChirpsSkipsOthers(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	token := makeTestToken(t, alice.ID)
+
+	first := db.addChirp(t, alice.ID, "First")
+	second := db.addChirp(t, alice.ID, "Second")
+	kept := db.addChirp(t, alice.ID, "Kept")
+	bobs := db.addChirp(t, bob.ID, "Not alice's")
+	unknown := uuid.New()
+
+	rr := deleteChirps(t, cfg, token, map[string]any{"ids": []uuid.UUID{first.ID, bobs.ID, second.ID, unknown, first.ID}})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	var resp BulkDeleteChirpsResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Deleted != 2 || !slices.Equal(resp.Skipped, []uuid.UUID{bobs.ID, unknown}) {
+		t.Errorf("response = %+v, want 2 deleted and bob's chirp and the unknown ID skipped", resp)
+	}
+
+	db.mu.Lock()
+	for _, c := range db.chirps {
+		if want := c.ID == first.ID || c.ID == second.ID; c.DeletedAt.Valid != want {
+			t.Errorf("chirp %q: deleted = %v, want %v", c.Body, c.DeletedAt.Valid, want)
+		}
+	}
+	db.mu.Unlock()
+
+	// Deleting them again skips them, as they no longer exist
+	rr = deleteChirps(t, cfg, token, map[string]any{"ids": []uuid.UUID{first.ID, kept.ID}})
+	resp = BulkDeleteChirpsResponse{}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Deleted != 1 || !slices.Equal(resp.Skipped, []uuid.UUID{first.ID}) {
+		t.Errorf("second delete = %+v, want kept deleted and first skipped", resp)
+	}
+}
+
+func TestBulkDeleteChirpsBefore(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	token := makeTestToken(t, alice.ID)
+
+	old := db.addChirp(t, alice.ID, "Old")
+	db.addChirp(t, bob.ID, "Old too, but bob's")
+	cutoff := db.addChirp(t, alice.ID, "Not before the cutoff")
+
+	rr := deleteChirps(t, cfg, token, map[string]any{"before": cutoff.CreatedAt})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	var resp BulkDeleteChirpsResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Deleted != 1 || resp.Skipped == nil || len(resp.Skipped) != 0 {
+		t.Errorf("response = %+v, want 1 deleted and nothing skipped", resp)
+	}
+
+	db.mu.Lock()
+	for _, c := range db.chirps {
+		if want := c.ID == old.ID; c.DeletedAt.Valid != want {
+			t.Errorf("chirp %q: deleted = %v, want %v", c.Body, c.DeletedAt.Valid, want)
+		}
+	}
+	db.mu.Unlock()
+}
+
+func TestBulkDeleteChirpsErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+	chirp := db.addChirp(t, user.ID, "Survives every bad request")
+
+	tooMany := make([]uuid.UUID, maxBulkChirps+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.New()
+	}
+	tests := []struct {
+		name    string
+		token   string
+		payload any
+		want    int
+	}{
+		{"ids and before", token, map[string]any{"ids": []uuid.UUID{chirp.ID}, "before": time.Now()}, http.StatusBadRequest},
+		{"neither", token, map[string]any{}, http.StatusBadRequest},
+		{"no ids", token, map[string]any{"ids": []uuid.UUID{}}, http.StatusBadRequest},
+		{"invalid ID", token, map[string]any{"ids": []string{"nope"}}, http.StatusBadRequest},
+		{"too many", token, map[string]any{"ids": tooMany}, http.StatusRequestEntityTooLarge},
+		{"no token", "", map[string]any{"ids": []uuid.UUID{chirp.ID}}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if rr := deleteChirps(t, cfg, tt.token, tt.payload); rr.Code != tt.want {
+			t.Errorf("%s: got status %v want %v", tt.name, rr.Code, tt.want)
+		}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.chirps[0].DeletedAt.Valid {
+		t.Error("a rejected request deleted the chirp")
+	}
+}
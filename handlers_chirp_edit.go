@@ -0,0 +1,185 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerUpdateChirp(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Body string `json:"body"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+
+	reqBody := requestBody{}
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+
+	limit, err := cfg.chirpLengthLimit(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	var invalid fieldErrors
+	cleanedBody, err := validateChirp(reqBody.Body, limit)
+	if err != nil {
+		invalidChirpBody(&invalid, err)
+	}
+	if invalid.respond(w, r) {
+		return
+	}
+
+	dbChirp, ok := cfg.ownChirp(w, r, chirpID, userID, "You can only edit your own chirps")
+	if !ok {
+		return
+	}
+
+	// The previous body is saved as a revision by the same query
+	updated, err := cfg.dbQueries.UpdateChirp(r.Context(), database.UpdateChirpParams{
+		ID:   chirpID,
+		Body: cleanedBody,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Deleted between the ownership check and the update
+		respondWithError(w, r, http.StatusNotFound, "Chirp not found")
+		return
+	}
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	// As on create, the edit is already stored, so indexing failures are
+	// only logged. Editing does not notify newly mentioned users.
+	err = cfg.indexHashtags(r.Context(), chirpID, updated.Body)
+	if err != nil {
+		logf(r.Context(), "Error indexing hashtags for chirp %s: %v", chirpID, err)
+	}
+	err = cfg.dbQueries.DeleteChirpMentions(r.Context(), chirpID)
+	if err == nil {
+		_, err = cfg.storeMentions(r.Context(), chirpID, updated.Body)
+	}
+	if err != nil {
+		logf(r.Context(), "Error storing mentions for chirp %s: %v", chirpID, err)
+	}
+	_, err = cfg.indexURLs(r.Context(), chirpID, updated.Body)
+	if err != nil {
+		logf(r.Context(), "Error indexing links for chirp %s: %v", chirpID, err)
+	}
+
+	cfg.chirpCache.invalidate()
+
+	chirps := []Chirp{chirpFromRow(database.GetChirpsRow{
+		Chirp:      updated,
+		LikeCount:  dbChirp.LikeCount,
+		ReplyCount: dbChirp.ReplyCount,
+	})}
+	err = cfg.populateChirps(r, chirps)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(chirps[0])
+}
+
+// handlerGetChirpHistory lists the earlier versions of a chirp, newest
+// first. Only the chirp's author and admins may see them.
+func (cfg *apiConfig) handlerGetChirpHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dbChirp, err := cfg.dbQueries.GetChirpByID(withPrimary(r.Context()), chirpID)
+	if err != nil {
+		respondWithLookupError(w, r, err, "Chirp not found")
+		return
+	}
+	if dbChirp.Chirp.UserID != userID {
+		isAdmin, err := cfg.isAdmin(r.Context(), userID)
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+		if !isAdmin {
+			respondWithError(w, r, http.StatusForbidden, "You can only view the history of your own chirps")
+			return
+		}
+	}
+
+	dbRevisions, err := cfg.dbQueries.GetChirpRevisions(r.Context(), database.GetChirpRevisionsParams{
+		ChirpID: chirpID,
+		Limit:   limit,
+		Offset:  offset,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	revisions := make([]ChirpRevision, len(dbRevisions))
+	for i, rev := range dbRevisions {
+		revisions[i] = ChirpRevision{
+			ID:        rev.ID,
+			Body:      rev.Body,
+			CreatedAt: rev.CreatedAt,
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// ownChirp loads chirpID from the primary and checks that userID wrote it. On failure it
+// writes a 404, a 403 with forbidden as the message, or a 500 when the
+// lookup itself fails, and returns false.
+func (cfg *apiConfig) ownChirp(w http.ResponseWriter, r *http.Request, chirpID, userID uuid.UUID, forbidden string) (database.GetChirpByIDRow, bool) {
+	dbChirp, err := cfg.dbQueries.GetChirpByID(withPrimary(r.Context()), chirpID)
+	if err != nil {
+		respondWithLookupError(w, r, err, "Chirp not found")
+		return database.GetChirpByIDRow{}, false
+	}
+
+	if dbChirp.Chirp.UserID != userID {
+		respondWithError(w, r, http.StatusForbidden, forbidden)
+		return database.GetChirpByIDRow{}, false
+	}
+
+	return dbChirp, true
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// putChirp sends an edit request for chirpID with the given JSON payload.
+func putChirp(t *testing.T, cfg *apiConfig, chirpID, token string, payload any) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPut, "/api/chirps/"+chirpID, bytes.NewReader(body))
+	req.SetPathValue("chirpID", chirpID)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerUpdateChirp(rr, req)
+	return rr
+}
+
+// getHistory requests the revisions of chirpID.
+func getHistory(t *testing.T, cfg *apiConfig, chirpID, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+chirpID+"/history", nil)
+	req.SetPathValue("chirpID", chirpID)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerGetChirpHistory(rr, req)
+	return rr
+}
+
+func TestHandlerUpdateChirp(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	var created Chirp
+	json.NewDecoder(postChirp(t, cfg, token, map[string]any{"body": "First #draft"}).Body).Decode(&created)
+	if created.Edited {
+		t.Error("a new chirp should not be marked edited")
+	}
+
+	// Creating a chirp records no revisions
+	rr := getHistory(t, cfg, created.ID.String(), token)
+	if rr.Code != http.StatusOK || rr.Body.String() != "[]\n" {
+		t.Errorf("history of a new chirp = %v %s, want an empty list", rr.Code, rr.Body.String())
+	}
+
+	for _, body := range []string{"Second #final", "Third"} {
+		rr := putChirp(t, cfg, created.ID.String(), token, map[string]any{"body": body})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("edit returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var edited Chirp
+		json.NewDecoder(rr.Body).Decode(&edited)
+		if edited.Body != body || !edited.Edited || edited.ID != created.ID {
+			t.Errorf("edit response = %+v, want edited chirp with body %q", edited, body)
+		}
+	}
+
+	rr = getHistory(t, cfg, created.ID.String(), token)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("history returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var revisions []ChirpRevision
+	if err := json.NewDecoder(rr.Body).Decode(&revisions); err != nil {
+		t.Fatalf("Failed to decode revisions: %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Body != "Second #final" || revisions[1].Body != "First #draft" {
+		t.Fatalf("expected two revisions newest first, got %+v", revisions)
+	}
+	if !revisions[0].CreatedAt.After(revisions[1].CreatedAt) {
+		t.Errorf("revision timestamps out of order: %+v", revisions)
+	}
+
+	// The edit re-indexes hashtags
+	db.mu.Lock()
+	tags := len(db.hashtags)
+	db.mu.Unlock()
+	if tags != 0 {
+		t.Errorf("expected hashtags from earlier bodies to be dropped, got %d", tags)
+	}
+}
+
+func TestHandlerUpdateChirpErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	author := db.addUser(t, "author@example.com")
+	other := db.addUser(t, "other@example.com")
+	chirp := db.addChirp(t, author.ID, "Original")
+	authorToken := makeTestToken(t, author.ID)
+	otherToken := makeTestToken(t, other.ID)
+
+	tests := []struct {
+		name    string
+		chirpID string
+		token   string
+		payload any
+		want    int
+	}{
+		{"no token", chirp.ID.String(), "", map[string]any{"body": "x"}, http.StatusUnauthorized},
+		{"invalid ID", "nope", authorToken, map[string]any{"body": "x"}, http.StatusBadRequest},
+		{"empty body", chirp.ID.String(), authorToken, map[string]any{"body": ""}, http.StatusBadRequest},
+		{"too long", chirp.ID.String(), authorToken, map[string]any{"body": string(bytes.Repeat([]byte("a"), 141))}, http.StatusBadRequest},
+		{"unknown chirp", uuid.NewString(), authorToken, map[string]any{"body": "x"}, http.StatusNotFound},
+		{"not the author", chirp.ID.String(), otherToken, map[string]any{"body": "x"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if rr := putChirp(t, cfg, tt.chirpID, tt.token, tt.payload); rr.Code != tt.want {
+			t.Errorf("%s: got status %v want %v", tt.name, rr.Code, tt.want)
+		}
+	}
+
+	// Only the author and admins may read the history
+	if rr := getHistory(t, cfg, chirp.ID.String(), otherToken); rr.Code != http.StatusForbidden {
+		t.Errorf("history for non-author: got status %v want %v", rr.Code, http.StatusForbidden)
+	}
+	if rr := getHistory(t, cfg, chirp.ID.String(), ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("history without token: got status %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAdminReadsChirpHistory(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	author := db.addUser(t, "author@example.com")
+	admin := db.addAdmin(t, "admin@example.com")
+	chirp := db.addChirp(t, author.ID, "Original")
+	if rr := putChirp(t, cfg, chirp.ID.String(), makeTestToken(t, author.ID), map[string]any{"body": "Edited"}); rr.Code != http.StatusOK {
+		t.Fatalf("edit: got status %v want %v", rr.Code, http.StatusOK)
+	}
+
+	rr := getHistory(t, cfg, chirp.ID.String(), makeTestToken(t, admin.ID))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("history for an admin: got status %v want %v", rr.Code, http.StatusOK)
+	}
+	var revisions []ChirpRevision
+	json.NewDecoder(rr.Body).Decode(&revisions)
+	if len(revisions) != 1 || revisions[0].Body != "Original" {
+		t.Errorf("revisions = %+v, want the original body", revisions)
+	}
+
+	// Admins still cannot edit other people's chirps
+	if rr := putChirp(t, cfg, chirp.ID.String(), makeTestToken(t, admin.ID), map[string]any{"body": "Hijacked"}); rr.Code != http.StatusForbidden {
+		t.Errorf("edit by an admin: got status %v want %v", rr.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,662 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// postChirp sends a create-chirp request with the given JSON payload.
+func postChirp(t *testing.T, cfg *apiConfig, token string, payload any) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/chirps", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerCreateChirp(rr, req)
+	return rr
+}
+
+// getChirp requests a single chirp and decodes it.
+func getChirp(t *testing.T, cfg *apiConfig, chirpID string) (Chirp, int) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+chirpID, nil)
+	req.SetPathValue("chirpID", chirpID)
+	cfg.handlerGetChirpByID(rr, req)
+	var chirp Chirp
+	json.NewDecoder(rr.Body).Decode(&chirp)
+	return chirp, rr.Code
+}
+
+// getReplies requests the direct replies of chirpID.
+func getReplies(t *testing.T, cfg *apiConfig, chirpID, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+chirpID+"/replies"+query, nil)
+	req.SetPathValue("chirpID", chirpID)
+	rr := httptest.NewRecorder()
+	cfg.handlerGetChirpReplies(rr, req)
+	return rr
+}
+
+func TestHandlerCreateChirpReply(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	parent := db.addChirp(t, user.ID, "Parent chirp")
+	token := makeTestToken(t, user.ID)
+
+	rr := postChirp(t, cfg, token, map[string]any{"body": "A reply", "parent_chirp_id": parent.ID})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	var reply Chirp
+	if err := json.NewDecoder(rr.Body).Decode(&reply); err != nil {
+		t.Fatalf("Failed to decode chirp: %v", err)
+	}
+	if reply.ParentChirpID == nil || *reply.ParentChirpID != parent.ID {
+		t.Errorf("parent_chirp_id = %v, want %v", reply.ParentChirpID, parent.ID)
+	}
+
+	rr = postChirp(t, cfg, token, map[string]any{"body": "Orphan", "parent_chirp_id": uuid.New()})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown parent returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	rr = postChirp(t, cfg, token, map[string]any{"body": "Top level"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	if !bytes.Contains(rr.Body.Bytes(), []byte(`"parent_chirp_id":null`)) {
+		t.Errorf("top-level chirp should have a null parent_chirp_id, got %s", rr.Body.String())
+	}
+}
+
+func TestHandlerGetChirpReplies(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	parent := db.addChirp(t, user.ID, "Parent chirp")
+	token := makeTestToken(t, user.ID)
+
+	var replyIDs []uuid.UUID
+	for _, body := range []string{"first", "second", "third"} {
+		rr := postChirp(t, cfg, token, map[string]any{"body": body, "parent_chirp_id": parent.ID})
+		var reply Chirp
+		if err := json.NewDecoder(rr.Body).Decode(&reply); err != nil {
+			t.Fatalf("Failed to decode chirp: %v", err)
+		}
+		replyIDs = append(replyIDs, reply.ID)
+	}
+
+	rr := getReplies(t, cfg, parent.ID.String(), "?limit=2")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var replies []Chirp
+	if err := json.NewDecoder(rr.Body).Decode(&replies); err != nil {
+		t.Fatalf("Failed to decode replies: %v", err)
+	}
+	if len(replies) != 2 || replies[0].ID != replyIDs[0] || replies[1].ID != replyIDs[1] {
+		t.Errorf("expected the two oldest replies first, got %+v", replies)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+parent.ID.String(), nil)
+	rr = httptest.NewRecorder()
+	req.SetPathValue("chirpID", parent.ID.String())
+	cfg.handlerGetChirpByID(rr, req)
+	var got Chirp
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode chirp: %v", err)
+	}
+	if got.ReplyCount != 3 {
+		t.Errorf("reply_count = %d, want 3", got.ReplyCount)
+	}
+
+	if rr := getReplies(t, cfg, replyIDs[0].String(), ""); rr.Body.String() != "[]\n" {
+		t.Errorf("expected empty array for chirp without replies, got %q", rr.Body.String())
+	}
+	if rr := getReplies(t, cfg, "not-a-uuid", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid ID returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestDeletedParentKeepsReplies(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	parent := db.addChirp(t, user.ID, "Parent chirp")
+	token := makeTestToken(t, user.ID)
+
+	rr := postChirp(t, cfg, token, map[string]any{"body": "A reply", "parent_chirp_id": parent.ID})
+	var reply Chirp
+	if err := json.NewDecoder(rr.Body).Decode(&reply); err != nil {
+		t.Fatalf("Failed to decode chirp: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/chirps/"+parent.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	req.SetPathValue("chirpID", parent.ID.String())
+	cfg.handlerDeleteChirp(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("delete returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+
+	// The parent is gone, so its replies listing 404s...
+	if rr := getReplies(t, cfg, parent.ID.String(), ""); rr.Code != http.StatusNotFound {
+		t.Errorf("replies of deleted parent returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+
+	// ...but the reply survives as a top-level chirp
+	req = httptest.NewRequest(http.MethodGet, "/api/chirps/"+reply.ID.String(), nil)
+	rr = httptest.NewRecorder()
+	req.SetPathValue("chirpID", reply.ID.String())
+	cfg.handlerGetChirpByID(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("reply lookup returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var got Chirp
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode chirp: %v", err)
+	}
+	if got.ParentChirpID != nil {
+		t.Errorf("parent_chirp_id = %v, want null after parent deletion", got.ParentChirpID)
+	}
+}
+
+func TestSoftDeleteChirp(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	aliceToken := makeTestToken(t, alice.ID)
+	bobToken := makeTestToken(t, bob.ID)
+	mux := newTestMux(t, cfg, false)
+
+	serve := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	var parent, reply Chirp
+	json.NewDecoder(postChirp(t, cfg, aliceToken, map[string]any{"body": "Learning #go"}).Body).Decode(&parent)
+	json.NewDecoder(postChirp(t, cfg, bobToken, map[string]any{"body": "Same", "parent_chirp_id": parent.ID}).Body).Decode(&reply)
+
+	// Deleting the reply hides the notification it caused
+	if got := getNotifications(t, cfg, aliceToken, ""); got.UnreadCount != 1 || len(got.Notifications) != 1 {
+		t.Fatalf("expected one reply notification, got %+v", got)
+	}
+	if rr := serve(http.MethodDelete, "/api/v1/chirps/"+reply.ID.String(), bobToken); rr.Code != http.StatusNoContent {
+		t.Fatalf("delete returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	if got := getNotifications(t, cfg, aliceToken, ""); got.UnreadCount != 0 || len(got.Notifications) != 0 {
+		t.Errorf("expected no notifications after the reply was deleted, got %+v", got)
+	}
+
+	rr := serve(http.MethodGet, "/api/v1/chirps/"+parent.ID.String(), "")
+	if strings.Contains(rr.Body.String(), "deleted_at") {
+		t.Errorf("chirp JSON leaks deleted_at: %s", rr.Body.String())
+	}
+	var got Chirp
+	json.NewDecoder(rr.Body).Decode(&got)
+	if got.ReplyCount != 0 {
+		t.Errorf("reply_count = %d, want deleted replies excluded", got.ReplyCount)
+	}
+
+	if rr := serve(http.MethodDelete, "/api/v1/chirps/"+parent.ID.String(), aliceToken); rr.Code != http.StatusNoContent {
+		t.Fatalf("delete returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+
+	// Publicly the chirp is gone...
+	for _, path := range []string{
+		"/api/v1/chirps/" + parent.ID.String(),
+		"/api/v1/chirps/" + parent.ID.String() + "/likes",
+		"/api/v1/chirps/" + parent.ID.String() + "/replies",
+	} {
+		if rr := serve(http.MethodGet, path, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("GET %s: got status %v want %v", path, rr.Code, http.StatusNotFound)
+		}
+	}
+	if rr := serve(http.MethodDelete, "/api/v1/chirps/"+parent.ID.String(), aliceToken); rr.Code != http.StatusNotFound {
+		t.Errorf("second delete: got status %v want %v", rr.Code, http.StatusNotFound)
+	}
+	for _, path := range []string{"/api/v1/chirps", "/api/v1/hashtags/go/chirps"} {
+		rr := serve(http.MethodGet, path, "")
+		if strings.TrimSpace(rr.Body.String()) != "[]" {
+			t.Errorf("GET %s = %s, want an empty list", path, rr.Body.String())
+		}
+	}
+
+	// ...but the row is kept for moderation
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, c := range db.chirps {
+		if !c.DeletedAt.Valid {
+			t.Errorf("chirp %s should be soft-deleted, got %+v", c.ID, c)
+		}
+	}
+	if len(db.chirps) != 2 {
+		t.Errorf("expected both chirps to remain stored, got %d", len(db.chirps))
+	}
+}
+
+func TestChirpLengthDependsOnAuthor(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	regular := db.addUser(t, "regular@example.com")
+	red := db.addUser(t, "red@example.com")
+	if err := db.UpgradeUserToChirpyRed(t.Context(), red.ID); err != nil {
+		t.Fatal(err)
+	}
+	regularToken := makeTestToken(t, regular.ID)
+	redToken := makeTestToken(t, red.ID)
+	body200 := strings.Repeat("a", 200)
+
+	rr := postChirp(t, cfg, regularToken, map[string]any{"body": body200})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("regular user, 200 chars: got status %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	var errResp ErrorResponse
+	json.NewDecoder(rr.Body).Decode(&errResp)
+	if !strings.Contains(errResp.Error, "140") {
+		t.Errorf("error %q should state the 140 character limit", errResp.Error)
+	}
+
+	if rr := postChirp(t, cfg, redToken, map[string]any{"body": body200}); rr.Code != http.StatusCreated {
+		t.Errorf("Chirpy Red user, 200 chars: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+	rr = postChirp(t, cfg, redToken, map[string]any{"body": strings.Repeat("a", maxRedChirpLength+1)})
+	json.NewDecoder(rr.Body).Decode(&errResp)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(errResp.Error, "280") {
+		t.Errorf("Chirpy Red user, 281 chars: got %v %q, want 400 stating the 280 limit", rr.Code, errResp.Error)
+	}
+
+	// Upgrading through the webhook unlocks the longer limit as soon as the
+	// delivery is processed
+	payload, _ := json.Marshal(map[string]any{
+		"event": "user.upgraded",
+		"data":  map[string]string{"user_id": regular.ID.String()},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/polka/webhooks", bytes.NewReader(payload))
+	req.Header.Set("Authorization", "ApiKey "+cfg.polkaKey)
+	cfg.handlerPolkaWebhook(httptest.NewRecorder(), req)
+	cfg.webhooks.runOnce(t.Context())
+
+	rr = postChirp(t, cfg, regularToken, map[string]any{"body": body200})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("upgraded user, 200 chars: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+
+	// Edits follow the same limit
+	var created Chirp
+	json.NewDecoder(rr.Body).Decode(&created)
+	if rr := putChirp(t, cfg, created.ID.String(), regularToken, map[string]any{"body": strings.Repeat("b", 250)}); rr.Code != http.StatusOK {
+		t.Errorf("upgraded user, 250 char edit: got status %v want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestValidateChirp(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr error
+	}{
+		{"empty", "", "", errChirpEmpty},
+		{"139 runes", strings.Repeat("é", 139), strings.Repeat("é", 139), nil},
+		{"140 runes", strings.Repeat("é", 140), strings.Repeat("é", 140), nil},
+		{"141 runes", strings.Repeat("é", 141), "", chirpTooLongError{maxLen: 140}},
+		{"profanity", "What a Kerfuffle, or a kerfuffle", "What a Kerfuffle, or a ****", nil},
+		{"only profanity", "fornax SHARBERT", "**** ****", nil},
+		{"spaces", strings.Repeat(" ", 50), "", errChirpEmpty},
+		{"tabs and newlines", "\t\n \r\n\t", "", errChirpEmpty},
+		{"zero-width spaces", "\u200b\u200b", "", errChirpEmpty},
+		{"zero-width mix", " \u200d\ufeff\u2060\n", "", errChirpEmpty},
+		{"trimmed", "\u200b  hello\n\t", "hello", nil},
+		{"decomposed accent", "cafe\u0301", "caf\u00e9", nil},
+		{"140 decomposed accents", strings.Repeat("e\u0301", 140), strings.Repeat("\u00e9", 140), nil},
+		{"newline and tab", "one\ntwo\tthree fornax\n", "one\ntwo\tthree ****", nil},
+		{"NUL", "nul\x00byte", "", errChirpControlChars},
+		{"ANSI escape", "\x1b[31mred\x1b[0m", "", errChirpControlChars},
+		{"C1 control", "csi\u009b31m", "", errChirpControlChars},
+		{"DEL", "del\x7f", "", errChirpControlChars},
+		{"carriage return", "one\r\ntwo", "", errChirpControlChars},
+	}
+	for _, tt := range tests {
+		got, err := validateChirp(tt.body, maxChirpLength)
+		if err != tt.wantErr || got != tt.want {
+			t.Errorf("%s: validateChirp = %q, %v; want %q, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	var tooLong chirpTooLongError
+	if _, err := validateChirp(strings.Repeat("a", 281), maxRedChirpLength); !errors.As(err, &tooLong) || tooLong.maxLen != maxRedChirpLength {
+		t.Errorf("validateChirp over the Red limit = %v, want it to name %d", err, maxRedChirpLength)
+	}
+}
+
+func TestGetChirpsOrderWithTies(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+
+	// Five chirps in the same microsecond, as a bulk insert leaves them,
+	// and one older chirp
+	tied := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var want []uuid.UUID
+	for i := range 5 {
+		chirp, err := db.CreateChirpAt(t.Context(), database.CreateChirpAtParams{CreatedAt: tied, Body: "tied", UserID: alice.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, chirp.ID)
+		if i == 2 {
+			db.CreateChirpAt(t.Context(), database.CreateChirpAtParams{CreatedAt: tied, Body: "tied", UserID: bob.ID})
+		}
+	}
+	slices.SortFunc(want, func(a, b uuid.UUID) int { return bytes.Compare(b[:], a[:]) })
+	older, _ := db.CreateChirpAt(t.Context(), database.CreateChirpAtParams{CreatedAt: tied.Add(-time.Hour), Body: "older", UserID: alice.ID})
+	want = append(want, older.ID)
+
+	ids := func(query string) []uuid.UUID {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		cfg.handlerGetChirps(rr, httptest.NewRequest(http.MethodGet, "/api/chirps?"+query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: got status %v want %v", query, rr.Code, http.StatusOK)
+		}
+		return decodeChirpIDs(t, rr)
+	}
+
+	// Every query, with or without the author, orders by created_at DESC,
+	// id DESC, and ascending is the exact reverse
+	reversed := slices.Clone(want)
+	slices.Reverse(reversed)
+	authorID := "author_id=" + alice.ID.String()
+	for _, tt := range []struct {
+		query string
+		want  []uuid.UUID
+	}{
+		{authorID, want},
+		{authorID + "&sort=desc", want},
+		{authorID + "&include=author", want},
+		{authorID + "&sort=asc", reversed},
+		{authorID + "&include=author&sort=asc", reversed},
+	} {
+		if got := ids(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+	if got := ids(""); len(got) != 7 || got[6] != older.ID {
+		t.Errorf("all chirps = %v, want 7 ending with the older one", got)
+	}
+
+	rr := httptest.NewRecorder()
+	cfg.handlerGetChirps(rr, httptest.NewRequest(http.MethodGet, "/api/chirps?sort=newest", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("sort=newest: got status %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGetChirpsDateRange(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+
+	// Seed chirps at known times, one per author and day
+	for i, at := range []string{
+		"2024-04-30T23:59:59Z",
+		"2024-05-01T00:00:00Z",
+		"2024-05-01T12:00:00Z",
+		"2024-05-02T00:00:00Z",
+		"2024-05-03T08:30:00Z",
+	} {
+		author := alice.ID
+		if i%2 == 1 {
+			author = bob.ID
+		}
+		chirp := db.addChirp(t, author, at)
+		createdAt, _ := time.Parse(time.RFC3339, at)
+		db.mu.Lock()
+		for j := range db.chirps {
+			if db.chirps[j].ID == chirp.ID {
+				db.chirps[j].CreatedAt = createdAt
+			}
+		}
+		db.mu.Unlock()
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"?from=2024-05-01&to=2024-05-01&sort=asc", []string{"2024-05-01T00:00:00Z", "2024-05-01T12:00:00Z"}},
+		{"?from=2024-05-01T12:00:00Z&sort=asc", []string{"2024-05-01T12:00:00Z", "2024-05-02T00:00:00Z", "2024-05-03T08:30:00Z"}},
+		{"?to=2024-05-01T00:00:00Z&sort=asc", []string{"2024-04-30T23:59:59Z", "2024-05-01T00:00:00Z"}},
+		{"?from=2024-05-01T02:00:00%2B02:00&to=2024-05-02&sort=asc", []string{"2024-05-01T00:00:00Z", "2024-05-01T12:00:00Z", "2024-05-02T00:00:00Z"}},
+		{"?from=2024-05-01&sort=desc", []string{"2024-05-03T08:30:00Z", "2024-05-02T00:00:00Z", "2024-05-01T12:00:00Z", "2024-05-01T00:00:00Z"}},
+		{"?from=2024-05-01", []string{"2024-05-03T08:30:00Z", "2024-05-02T00:00:00Z", "2024-05-01T12:00:00Z", "2024-05-01T00:00:00Z"}},
+		{"?from=2024-05-01T06:00:00Z&author_id=" + bob.ID.String(), []string{"2024-05-02T00:00:00Z"}},
+		{"?from=2024-06-01", nil},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		cfg.handlerGetChirps(rr, httptest.NewRequest(http.MethodGet, "/api/chirps"+tt.query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: got status %v want %v", tt.query, rr.Code, http.StatusOK)
+		}
+		var chirps []Chirp
+		json.NewDecoder(rr.Body).Decode(&chirps)
+		var got []string
+		for _, c := range chirps {
+			got = append(got, c.Body)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for query, param := range map[string]string{
+		"?from=yesterday":                                    "from",
+		"?to=2024-13-01":                                     "to",
+		"?from=2024-05-01T00:00:00":                          "from",
+		"?from=2024-05-03&to=2024-05-02":                     "to",
+		"?from=2024-05-01T12:00:01Z&to=2024-05-01T12:00:00Z": "to",
+	} {
+		rr := httptest.NewRecorder()
+		cfg.handlerGetChirps(rr, httptest.NewRequest(http.MethodGet, "/api/chirps"+query, nil))
+		var errResp ErrorResponse
+		json.NewDecoder(rr.Body).Decode(&errResp)
+		if rr.Code != http.StatusBadRequest || !strings.Contains(errResp.Error, param) {
+			t.Errorf("%s: got %v %q, want 400 naming %s", query, rr.Code, errResp.Error, param)
+		}
+	}
+}
+
+func TestIncludeAuthor(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	db.UpgradeUserToChirpyRed(t.Context(), bob.ID)
+	first := db.addChirp(t, alice.ID, "from alice")
+	db.addChirp(t, bob.ID, "from bob")
+
+	getRaw := func(url string) []map[string]any {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		cfg.handlerGetChirps(rr, httptest.NewRequest(http.MethodGet, url, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: got status %v want %v", url, rr.Code, http.StatusOK)
+		}
+		var chirps []map[string]any
+		json.NewDecoder(rr.Body).Decode(&chirps)
+		return chirps
+	}
+
+	// Without include the shape is unchanged
+	for _, c := range getRaw("/api/chirps") {
+		if _, ok := c["author"]; ok {
+			t.Errorf("chirp %v has an author without ?include=author", c["id"])
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	cfg.handlerGetChirps(rr, httptest.NewRequest(http.MethodGet, "/api/chirps?include=author&sort=asc", nil))
+	var chirps []Chirp
+	json.NewDecoder(rr.Body).Decode(&chirps)
+	if len(chirps) != 2 {
+		t.Fatalf("got %d chirps, want 2", len(chirps))
+	}
+	for _, c := range chirps {
+		if c.Author == nil || c.Author.ID != c.UserID {
+			t.Fatalf("chirp %q: author = %+v, want user %s", c.Body, c.Author, c.UserID)
+		}
+	}
+	if a := chirps[0].Author; a.Email != "alice@example.com" || a.Username != "alice" || a.IsChirpyRed {
+		t.Errorf("alice's author = %+v", a)
+	}
+	if a := chirps[1].Author; a.Username != "bob" || !a.IsChirpyRed {
+		t.Errorf("bob's author = %+v, want Chirpy Red", a)
+	}
+
+	// Combined with author_id
+	rr = httptest.NewRecorder()
+	cfg.handlerGetChirps(rr, httptest.NewRequest(http.MethodGet, "/api/chirps?include=author&author_id="+bob.ID.String(), nil))
+	chirps = nil
+	json.NewDecoder(rr.Body).Decode(&chirps)
+	if len(chirps) != 1 || chirps[0].Author == nil || chirps[0].Author.Username != "bob" {
+		t.Errorf("bob's chirps with authors = %+v", chirps)
+	}
+
+	// A single chirp, both ways
+	getOne := func(query string) (Chirp, int) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+first.ID.String()+query, nil)
+		req.SetPathValue("chirpID", first.ID.String())
+		cfg.handlerGetChirpByID(rr, req)
+		var chirp Chirp
+		json.NewDecoder(rr.Body).Decode(&chirp)
+		return chirp, rr.Code
+	}
+	if chirp, code := getOne(""); code != http.StatusOK || chirp.Author != nil {
+		t.Errorf("plain GET by ID = %v with author %+v, want no author", code, chirp.Author)
+	}
+	if chirp, code := getOne("?include=author"); code != http.StatusOK || chirp.Author == nil || chirp.Author.Username != "alice" {
+		t.Errorf("GET by ID with author = %v %+v, want alice", code, chirp.Author)
+	}
+	if _, code := getOne("?include=likes"); code != http.StatusBadRequest {
+		t.Errorf("unknown include: got status %v want %v", code, http.StatusBadRequest)
+	}
+}
+
+func TestCreateChirpRejectsBlankBody(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	for _, body := range []string{"   ", "\t\n", "\u200b"} {
+		rr := postChirp(t, cfg, token, map[string]string{"body": body})
+		var errResp ErrorResponse
+		json.NewDecoder(rr.Body).Decode(&errResp)
+		if rr.Code != http.StatusBadRequest || errResp.Error != "Body is required" {
+			t.Errorf("body %q: got %v %q, want 400 %q", body, rr.Code, errResp.Error, "Body is required")
+		}
+	}
+
+	// Visually identical bodies are stored identically
+	var precomposed, decomposed Chirp
+	json.NewDecoder(postChirp(t, cfg, token, map[string]string{"body": "caf\u00e9"}).Body).Decode(&precomposed)
+	json.NewDecoder(postChirp(t, cfg, token, map[string]string{"body": "cafe\u0301"}).Body).Decode(&decomposed)
+	if precomposed.Body == "" || precomposed.Body != decomposed.Body {
+		t.Errorf("bodies differ: %q and %q", precomposed.Body, decomposed.Body)
+	}
+}
+
+func TestChirpControlCharsRejected(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+	chirp := db.addChirp(t, user.ID, "original")
+
+	body := map[string]string{"body": "\x1b[2Jgotcha"}
+	for name, rr := range map[string]*httptest.ResponseRecorder{
+		"create": postChirp(t, cfg, token, body),
+		"edit":   putChirp(t, cfg, chirp.ID.String(), token, body),
+	} {
+		var errResp ErrorResponse
+		json.NewDecoder(rr.Body).Decode(&errResp)
+		if rr.Code != http.StatusBadRequest || !strings.Contains(errResp.Error, "control characters") {
+			t.Errorf("%s: got %v %q, want 400 about control characters", name, rr.Code, errResp.Error)
+		}
+	}
+
+	rr := postChirp(t, cfg, token, map[string]string{"body": "line one\n\tline two"})
+	var created Chirp
+	json.NewDecoder(rr.Body).Decode(&created)
+	if rr.Code != http.StatusCreated || created.Body != "line one\n\tline two" {
+		t.Errorf("newline and tab: got %v %q, want them kept", rr.Code, created.Body)
+	}
+}
+
+func TestDuplicateChirpRejected(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.duplicateChirpWindow = time.Minute
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	aliceToken := makeTestToken(t, alice.ID)
+
+	if rr := postChirp(t, cfg, aliceToken, map[string]string{"body": "What a kerfuffle"}); rr.Code != http.StatusCreated {
+		t.Fatalf("first chirp: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+
+	// Cleaning maps both spellings to the same stored body
+	rr := postChirp(t, cfg, aliceToken, map[string]string{"body": "  What a Kerfuffle "})
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("duplicate: got status %v want %v", rr.Code, http.StatusConflict)
+	}
+	if !strings.Contains(rr.Body.String(), "Duplicate chirp") {
+		t.Errorf("duplicate error = %s, want it to say Duplicate chirp", rr.Body)
+	}
+
+	// Other users, and replies, may say the same thing
+	if rr := postChirp(t, cfg, makeTestToken(t, bob.ID), map[string]string{"body": "What a kerfuffle"}); rr.Code != http.StatusCreated {
+		t.Errorf("same text by another user: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+	parent := db.addChirp(t, bob.ID, "Did you see that?")
+	reply := map[string]string{"body": "What a kerfuffle", "parent_chirp_id": parent.ID.String()}
+	if rr := postChirp(t, cfg, aliceToken, reply); rr.Code != http.StatusCreated {
+		t.Errorf("same text as a reply: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+
+	// Just outside the window the repost is allowed
+	db.mu.Lock()
+	for i, c := range db.chirps {
+		if c.UserID == alice.ID && !c.ParentChirpID.Valid {
+			db.chirps[i].CreatedAt = time.Now().UTC().Add(-time.Minute - time.Second)
+		}
+	}
+	db.mu.Unlock()
+	if rr := postChirp(t, cfg, aliceToken, map[string]string{"body": "What a kerfuffle"}); rr.Code != http.StatusCreated {
+		t.Errorf("repost after the window: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+
+	// A zero window turns the check off
+	cfg.duplicateChirpWindow = 0
+	if rr := postChirp(t, cfg, aliceToken, map[string]string{"body": "What a kerfuffle"}); rr.Code != http.StatusCreated {
+		t.Errorf("duplicate with the check disabled: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+)
+
+// handlerListUsers serves the user directory: every account that is not
+// deleted or banned, oldest first, with ?is_chirpy_red to filter on Chirpy
+// Red. Private accounts are listed so they can be found, but without the
+// display name, bio and avatar their profile keeps for approved followers.
+func (cfg *apiConfig) handlerListUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if cfg.directoryNeedsAuth {
+		if _, ok := cfg.requireUser(w, r); !ok {
+			return
+		}
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var isChirpyRed sql.NullBool
+	if value := r.URL.Query().Get("is_chirpy_red"); value != "" {
+		isChirpyRed.Bool, err = strconv.ParseBool(value)
+		if err != nil {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid is_chirpy_red")
+			return
+		}
+		isChirpyRed.Valid = true
+	}
+
+	dbUsers, err := cfg.dbQueries.ListUsers(r.Context(), database.ListUsersParams{
+		IsChirpyRed: isChirpyRed,
+		Limit:       limit,
+		Offset:      offset,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	users := make([]PublicUser, len(dbUsers))
+	for i, dbUser := range dbUsers {
+		users[i] = PublicUser{
+			ID:          dbUser.ID,
+			Email:       cfg.publicEmail(dbUser.Email),
+			Username:    dbUser.Username,
+			IsChirpyRed: dbUser.IsChirpyRed,
+		}
+		if !dbUser.IsPrivate {
+			users[i].DisplayName = nullableString(dbUser.DisplayName)
+			users[i].Bio = nullableString(dbUser.Bio)
+			users[i].AvatarURL = nullableString(dbUser.AvatarUrl)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(users)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+)
+
+// usernames lists the usernames of users in order.
+func usernames(users []PublicUser) []string {
+	names := make([]string, len(users))
+	for i, u := range users {
+		names[i] = u.Username
+	}
+	return names
+}
+
+func TestListUsers(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	carol := db.addUser(t, "carol@example.com")
+	dave := db.addUser(t, "dave@example.com")
+	erin := db.addUser(t, "erin@example.com")
+	frank := db.addUser(t, "frank@example.com")
+
+	db.UpdateUser(t.Context(), database.UpdateUserParams{ID: alice.ID, DisplayName: sql.NullString{String: "Alice", Valid: true}})
+	db.UpdateUser(t.Context(), database.UpdateUserParams{ID: bob.ID, DisplayName: sql.NullString{String: "Bob", Valid: true}})
+	db.SetUserPrivate(t.Context(), database.SetUserPrivateParams{ID: bob.ID, IsPrivate: true})
+	db.UpgradeUserToChirpyRed(t.Context(), carol.ID)
+	db.UpgradeUserToChirpyRed(t.Context(), frank.ID)
+	db.BanUser(t.Context(), database.BanUserParams{ID: dave.ID})
+	db.DeleteUserKeepChirps(t.Context(), erin.ID)
+
+	var users []PublicUser
+	if resp := call(t, srv, http.MethodGet, "/api/users", "", nil, &users); resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if got, want := usernames(users), []string{"alice", "bob", "carol", "frank"}; !slices.Equal(got, want) {
+		t.Fatalf("listed %v, want %v without the banned and deleted users", got, want)
+	}
+	if users[0].Email != "alice@example.com" || users[0].DisplayName == nil || *users[0].DisplayName != "Alice" {
+		t.Errorf("alice = %+v, want her email and display name", users[0])
+	}
+	if users[1].DisplayName != nil {
+		t.Errorf("private bob's display name = %q, want it hidden", *users[1].DisplayName)
+	}
+
+	var page []PublicUser
+	call(t, srv, http.MethodGet, "/api/users?limit=2&offset=1", "", nil, &page)
+	if got, want := usernames(page), []string{"bob", "carol"}; !slices.Equal(got, want) {
+		t.Errorf("second page = %v, want %v", got, want)
+	}
+
+	var red, notRed []PublicUser
+	call(t, srv, http.MethodGet, "/api/users?is_chirpy_red=true", "", nil, &red)
+	call(t, srv, http.MethodGet, "/api/users?is_chirpy_red=false", "", nil, &notRed)
+	if got, want := usernames(red), []string{"carol", "frank"}; !slices.Equal(got, want) {
+		t.Errorf("is_chirpy_red=true listed %v, want %v", got, want)
+	}
+	if got, want := usernames(notRed), []string{"alice", "bob"}; !slices.Equal(got, want) {
+		t.Errorf("is_chirpy_red=false listed %v, want %v", got, want)
+	}
+
+	for _, query := range []string{"?is_chirpy_red=maybe", "?limit=0", "?offset=-1"} {
+		if resp := call(t, srv, http.MethodGet, "/api/users"+query, "", nil, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: got status %v want %v", query, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestListUsersRequiresAuth(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.directoryNeedsAuth = true
+	srv := newTestServer(t, cfg)
+	alice := db.addUser(t, "alice@example.com")
+
+	if resp := call(t, srv, http.MethodGet, "/api/users", "", nil, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("anonymous: got status %v want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+	var users []PublicUser
+	if resp := call(t, srv, http.MethodGet, "/api/users", makeTestToken(t, alice.ID), nil, &users); resp.StatusCode != http.StatusOK || len(users) != 1 {
+		t.Errorf("signed in: got status %v and %d users, want %v and 1", resp.StatusCode, len(users), http.StatusOK)
+	}
+}
+
+func TestHideEmails(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.hideEmails = true
+	srv := newTestServer(t, cfg)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	db.CreateFollow(t.Context(), database.CreateFollowParams{FollowerID: bob.ID, FolloweeID: alice.ID})
+	db.addChirp(t, alice.ID, "Hello")
+	token := makeTestToken(t, bob.ID)
+
+	// Every public view of a user, decoded loosely so a stray email shows
+	var directory, followers, chirps []map[string]any
+	var profile map[string]any
+	call(t, srv, http.MethodGet, "/api/users", "", nil, &directory)
+	call(t, srv, http.MethodGet, "/api/users/"+alice.ID.String()+"/followers", token, nil, &followers)
+	call(t, srv, http.MethodGet, "/api/users/"+alice.ID.String(), token, nil, &profile)
+	call(t, srv, http.MethodGet, "/api/chirps?include=author", "", nil, &chirps)
+	if len(directory) != 2 || len(followers) != 1 || profile == nil || len(chirps) != 1 {
+		t.Fatalf("got %d users, %d followers, profile %v and %d chirps", len(directory), len(followers), profile, len(chirps))
+	}
+	users := append(append(directory, followers...), profile, chirps[0]["author"].(map[string]any))
+	for _, u := range users {
+		if email, ok := u["email"]; ok {
+			t.Errorf("%v: email = %v, want it left out", u["username"], email)
+		}
+		if u["username"] == nil {
+			t.Errorf("%v: no username to show instead", u)
+		}
+	}
+
+	// Users still see their own email
+	var me User
+	call(t, srv, http.MethodGet, "/api/users/me/export", token, nil, &struct {
+		User *User `json:"user"`
+	}{&me})
+	if me.Email != "bob@example.com" {
+		t.Errorf("own email = %q, want it kept", me.Email)
+	}
+}
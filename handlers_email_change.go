@@ -0,0 +1,80 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/auth"
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// emailChangeTTL is how long the link confirming a new email works.
+const emailChangeTTL = 24 * time.Hour
+
+// errCodeInvalidEmailToken is the error code of a confirmation link that is
+// unknown, already used or expired.
+const errCodeInvalidEmailToken = "invalid_email_token"
+
+// startEmailChange records email as userID's pending email and sends the
+// link confirming it there. Until the link is followed the account keeps
+// its old email, for logins too, so a stolen session cannot quietly move
+// the account to an address its owner does not control. A new request
+// replaces the previous one, whose link stops working.
+func (cfg *apiConfig) startEmailChange(r *http.Request, userID uuid.UUID, email string) (database.User, error) {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return database.User{}, err
+	}
+	// The token is random, so like an API key it needs no salt or slow hash
+	dbUser, err := cfg.dbQueries.SetUserPendingEmail(r.Context(), database.SetUserPendingEmailParams{
+		ID:           userID,
+		PendingEmail: email,
+		TokenHash:    auth.HashAPIKey(token),
+		ExpiresAt:    time.Now().UTC().Add(emailChangeTTL),
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	cfg.sendEmailChangeConfirmation(email, cfg.link(r, "/api/users/me/confirm_email", url.Values{"token": {token}}))
+	return dbUser, nil
+}
+
+// handlerConfirmEmail makes a pending email change with the token from the
+// confirmation link, and tells the old address about it. The link is
+// opened from the email, so the token is all that identifies the user.
+func (cfg *apiConfig) handlerConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		respondWithError(w, r, http.StatusBadRequest, "Missing token")
+		return
+	}
+
+	changed, err := cfg.dbQueries.ConfirmPendingEmail(r.Context(), auth.HashAPIKey(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithErrorCode(w, r, http.StatusBadRequest, errCodeInvalidEmailToken, "Invalid or expired token")
+		return
+	}
+	// Someone else registered the address since the change was asked for
+	if isUniqueViolation(err, emailConstraint) {
+		respondWithError(w, r, http.StatusConflict, "Email is already registered")
+		return
+	}
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	cfg.sendEmailChangedNotice(changed.OldEmail, changed.Email)
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ConfirmEmailResponse{Email: changed.Email})
+}
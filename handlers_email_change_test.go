@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/mail"
+)
+
+// confirmLink matches the confirmation link in an email change message.
+var confirmLink = regexp.MustCompile(`https://chirpy\.example\.com(/api/users/me/confirm_email\?token=\w+)`)
+
+// confirmPath returns the path and query of the link in msg.
+func confirmPath(t *testing.T, msg mail.Message) string {
+	t.Helper()
+	m := confirmLink.FindStringSubmatch(msg.Text)
+	if m == nil {
+		t.Fatalf("no confirmation link in %q", msg.Text)
+	}
+	return m[1]
+}
+
+func TestEmailChange(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.publicURL = "https://chirpy.example.com"
+	sender := newFakeSender()
+	cfg.mailer = sender
+	srv := newTestServer(t, cfg)
+
+	credentials := map[string]string{"email": "alice@example.com", "password": "password123"}
+	var user User
+	call(t, srv, http.MethodPost, "/api/users", "", credentials, &user)
+	sender.next(t) // welcome
+	token := makeTestToken(t, user.ID)
+
+	change := map[string]string{"email": "alice@example.org", "current_password": "password123"}
+	if resp := call(t, srv, http.MethodPut, "/api/users", token, change, &user); resp.StatusCode != http.StatusOK {
+		t.Fatalf("change: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	msg := sender.next(t)
+	if msg.To != "alice@example.org" {
+		t.Errorf("confirmation sent to %q, want the new address", msg.To)
+	}
+	path := confirmPath(t, msg)
+
+	// Logins keep the old email until the change is confirmed
+	login := func(email string) int {
+		t.Helper()
+		return call(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": email, "password": "password123"}, nil).StatusCode
+	}
+	if old, pending := login("alice@example.com"), login("alice@example.org"); old != http.StatusOK || pending != http.StatusUnauthorized {
+		t.Errorf("before confirming: login with the old email %v, the new one %v", old, pending)
+	}
+
+	var confirmed ConfirmEmailResponse
+	if resp := call(t, srv, http.MethodGet, path, "", nil, &confirmed); resp.StatusCode != http.StatusOK {
+		t.Fatalf("confirm: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if confirmed.Email != "alice@example.org" {
+		t.Errorf("confirmed %q, want alice@example.org", confirmed.Email)
+	}
+	if msg := sender.next(t); msg.To != "alice@example.com" || !strings.Contains(msg.Text, "alice@example.org") {
+		t.Errorf("notice sent to %q: %q, want the old address told of the new one", msg.To, msg.Text)
+	}
+	if old, changed := login("alice@example.com"), login("alice@example.org"); old != http.StatusUnauthorized || changed != http.StatusOK {
+		t.Errorf("after confirming: login with the old email %v, the new one %v", old, changed)
+	}
+	stored, _ := db.GetUserByID(t.Context(), user.ID)
+	if stored.PendingEmail.Valid || stored.PendingEmailTokenHash.Valid {
+		t.Errorf("pending change left behind: %+v", stored)
+	}
+
+	// The token only works once
+	var errResp ErrorResponse
+	if resp := call(t, srv, http.MethodGet, path, "", nil, &errResp); resp.StatusCode != http.StatusBadRequest || errResp.Code != errCodeInvalidEmailToken {
+		t.Errorf("reused token: got status %v code %q, want %v %q", resp.StatusCode, errResp.Code, http.StatusBadRequest, errCodeInvalidEmailToken)
+	}
+}
+
+func TestEmailChangeTokenExpiry(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.publicURL = "https://chirpy.example.com"
+	sender := newFakeSender()
+	cfg.mailer = sender
+	srv := newTestServer(t, cfg)
+	var user User
+	call(t, srv, http.MethodPost, "/api/users", "", map[string]string{"email": "alice@example.com", "password": "password123"}, &user)
+	sender.next(t) // welcome
+	token := makeTestToken(t, user.ID)
+
+	requestChange := func(email string) string {
+		t.Helper()
+		change := map[string]string{"email": email, "current_password": "password123"}
+		if resp := call(t, srv, http.MethodPut, "/api/users", token, change, nil); resp.StatusCode != http.StatusOK {
+			t.Fatalf("change to %s: got status %v want %v", email, resp.StatusCode, http.StatusOK)
+		}
+		return confirmPath(t, sender.next(t))
+	}
+	confirm := func(path string) int {
+		t.Helper()
+		return call(t, srv, http.MethodGet, path, "", nil, nil).StatusCode
+	}
+
+	// A newer request replaces the older one
+	first := requestChange("first@example.com")
+	second := requestChange("second@example.com")
+	if code := confirm(first); code != http.StatusBadRequest {
+		t.Errorf("replaced token: got status %v want %v", code, http.StatusBadRequest)
+	}
+
+	db.mu.Lock()
+	db.users[0].PendingEmailExpiresAt.Time = time.Now().Add(-time.Minute)
+	db.mu.Unlock()
+	if code := confirm(second); code != http.StatusBadRequest {
+		t.Errorf("expired token: got status %v want %v", code, http.StatusBadRequest)
+	}
+	if stored, _ := db.GetUserByID(t.Context(), user.ID); stored.Email != "alice@example.com" {
+		t.Errorf("email = %q after an expired confirmation", stored.Email)
+	}
+
+	// Someone else took the address in the meantime
+	third := requestChange("taken@example.com")
+	db.addUser(t, "taken@example.com")
+	if code := confirm(third); code != http.StatusConflict {
+		t.Errorf("taken address: got status %v want %v", code, http.StatusConflict)
+	}
+
+	for _, path := range []string{"/api/users/me/confirm_email", "/api/users/me/confirm_email?token=" + url.QueryEscape("not a token")} {
+		var errResp json.RawMessage
+		if resp := call(t, srv, http.MethodGet, path, "", nil, &errResp); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: got status %v want %v", path, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// exportPageSize is how many chirps an export reads from the database at a
+// time.
+const exportPageSize = 500
+
+// handlerExportUser returns everything stored about the caller as one JSON
+// document: their profile, active sessions, login history and chirps. Password hashes and
+// refresh tokens are credentials, not data about the user, and are left
+// out. Chirps are streamed a page at a time so a large account never sits
+// in memory; if reading one fails part way the document is cut short,
+// which leaves it invalid JSON rather than silently incomplete.
+func (cfg *apiConfig) handlerExportUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	dbSessions, err := cfg.dbQueries.GetUserSessions(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	sessions := make([]Session, len(dbSessions))
+	for i, s := range dbSessions {
+		sessions[i] = Session{CreatedAt: s.CreatedAt, ExpiresAt: s.ExpiresAt}
+	}
+
+	dbLogins, err := cfg.dbQueries.GetLoginHistory(r.Context(), database.GetLoginHistoryParams{
+		UserID: userID,
+		Limit:  loginHistoryLimit,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="chirpy-export.json"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	io.WriteString(w, `{"exported_at":`)
+	enc.Encode(time.Now().UTC())
+	io.WriteString(w, `,"user":`)
+	enc.Encode(userFromDB(dbUser))
+	io.WriteString(w, `,"sessions":`)
+	enc.Encode(sessions)
+	io.WriteString(w, `,"logins":`)
+	enc.Encode(loginsFromDB(dbLogins))
+	io.WriteString(w, `,"chirps":[`)
+
+	params := database.GetUserChirpsAfterParams{UserID: userID, AfterID: uuid.Nil, Limit: exportPageSize}
+	count := 0
+	for {
+		page, err := cfg.dbQueries.GetUserChirpsAfter(r.Context(), params)
+		if err != nil {
+			logf(r.Context(), "Error exporting chirps of user %s: %v", userID, err)
+			return
+		}
+		for _, dbChirp := range page {
+			if count > 0 {
+				io.WriteString(w, ",")
+			}
+			enc.Encode(chirpFromDB(dbChirp))
+			count++
+		}
+		if len(page) < exportPageSize {
+			break
+		}
+		last := page[len(page)-1]
+		params.AfterCreatedAt, params.AfterID = last.CreatedAt, last.ID
+	}
+	io.WriteString(w, "]}\n")
+}
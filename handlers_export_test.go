@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+)
+
+func exportUser(t *testing.T, cfg *apiConfig, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/users/me/export", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerExportUser(rr, req)
+	return rr
+}
+
+func TestExportUser(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	other := db.addUser(t, "other@example.com")
+
+	var want []string
+	for i := range 3 {
+		want = append(want, db.addChirp(t, user.ID, fmt.Sprintf("chirp %d", i)).ID.String())
+	}
+	deleted := db.addChirp(t, user.ID, "gone")
+	db.DeleteChirp(t.Context(), deleted.ID)
+	db.addChirp(t, other.ID, "not mine")
+
+	tokens := []string{"active-token", "revoked-token", "expired-token"}
+	for i, token := range tokens {
+		expiresAt := time.Now().Add(time.Hour)
+		if i == 2 {
+			expiresAt = time.Now().Add(-time.Hour)
+		}
+		db.CreateRefreshToken(t.Context(), database.CreateRefreshTokenParams{Token: token, UserID: user.ID, ExpiresAt: expiresAt})
+	}
+	db.RevokeRefreshToken(t.Context(), "revoked-token")
+	db.CreateLoginHistoryEntry(t.Context(), database.CreateLoginHistoryEntryParams{UserID: user.ID, Ip: "192.0.2.1", UserAgent: "curl/8.0"})
+	db.CreateLoginHistoryEntry(t.Context(), database.CreateLoginHistoryEntryParams{UserID: other.ID, Ip: "192.0.2.2", UserAgent: "curl/8.0"})
+
+	rr := exportUser(t, cfg, makeTestToken(t, user.ID))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("export returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment") {
+		t.Errorf("Content-Disposition = %q, want an attachment", cd)
+	}
+
+	raw := rr.Body.String()
+	for _, secret := range append(tokens, "hashed_password", "unset") {
+		if strings.Contains(raw, secret) {
+			t.Errorf("export contains %q", secret)
+		}
+	}
+
+	var export struct {
+		ExportedAt time.Time `json:"exported_at"`
+		User       User      `json:"user"`
+		Sessions   []Session `json:"sessions"`
+		Logins     []Login   `json:"logins"`
+		Chirps     []Chirp   `json:"chirps"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &export); err != nil {
+		t.Fatalf("export is not valid JSON: %v\n%s", err, raw)
+	}
+	if export.ExportedAt.IsZero() {
+		t.Error("exported_at is missing")
+	}
+	if export.User.ID != user.ID || export.User.Email != user.Email {
+		t.Errorf("user = %+v, want %s", export.User, user.Email)
+	}
+	if len(export.Sessions) != 1 || export.Sessions[0].ExpiresAt.Before(time.Now()) {
+		t.Errorf("sessions = %+v, want only the active one", export.Sessions)
+	}
+	if len(export.Logins) != 1 || export.Logins[0].IP != "192.0.2.1" {
+		t.Errorf("logins = %+v, want only the user's own", export.Logins)
+	}
+	var got []string
+	for _, c := range export.Chirps {
+		got = append(got, c.ID.String())
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("chirps = %v, want %v in creation order", got, want)
+	}
+}
+
+func TestExportUserPagesThroughChirps(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	for i := range exportPageSize + 1 {
+		db.addChirp(t, user.ID, fmt.Sprintf("chirp %d", i))
+	}
+
+	rr := exportUser(t, cfg, makeTestToken(t, user.ID))
+	var export struct {
+		Chirps []Chirp `json:"chirps"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &export); err != nil {
+		t.Fatalf("export is not valid JSON: %v", err)
+	}
+	if len(export.Chirps) != exportPageSize+1 || export.Chirps[exportPageSize].Body != fmt.Sprintf("chirp %d", exportPageSize) {
+		t.Errorf("got %d chirps, want all %d in order", len(export.Chirps), exportPageSize+1)
+	}
+}
+
+func TestExportUserRequiresAuth(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	if rr := exportUser(t, cfg, "not-a-token"); rr.Code != http.StatusUnauthorized {
+		t.Errorf("got status %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+}
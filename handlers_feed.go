@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handlerGetFeed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	includeSelf := false
+	if includeSelfStr := r.URL.Query().Get("include_self"); includeSelfStr != "" {
+		includeSelf, err = strconv.ParseBool(includeSelfStr)
+		if err != nil {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid include_self")
+			return
+		}
+	}
+
+	dbChirps, err := cfg.dbQueries.GetFeed(r.Context(), database.GetFeedParams{
+		UserID:      userID,
+		IncludeSelf: includeSelf,
+		Limit:       limit,
+		Offset:      offset,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	chirps := make([]Chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		chirps[i] = chirpFromRow(database.GetChirpsRow(dbChirp))
+	}
+
+	err = cfg.populateChirps(r, chirps)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(chirps)
+}
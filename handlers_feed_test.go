@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func getFeed(t *testing.T, cfg *apiConfig, token, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/feed"+query, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	cfg.handlerGetFeed(rr, req)
+	return rr
+}
+
+func decodeChirpIDs(t *testing.T, rr *httptest.ResponseRecorder) []uuid.UUID {
+	t.Helper()
+	var chirps []Chirp
+	if err := json.NewDecoder(rr.Body).Decode(&chirps); err != nil {
+		t.Fatalf("Failed to decode chirps: %v", err)
+	}
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+	return ids
+}
+
+func TestHandlerGetFeed(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	me := db.addUser(t, "me@example.com")
+	friend := db.addUser(t, "friend@example.com")
+	other := db.addUser(t, "other@example.com")
+	token := makeTestToken(t, me.ID)
+
+	friendOld := db.addChirp(t, friend.ID, "friend old")
+	mine := db.addChirp(t, me.ID, "mine")
+	db.addChirp(t, other.ID, "stranger")
+	friendNew := db.addChirp(t, friend.ID, "friend new")
+
+	// Following nobody yields an empty array, not null
+	rr := getFeed(t, cfg, token, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if rr.Body.String() != "[]\n" {
+		t.Errorf("expected empty feed, got %q", rr.Body.String())
+	}
+
+	if rr := follow(t, cfg, friend.ID.String(), token); rr.Code != http.StatusNoContent {
+		t.Fatalf("follow returned wrong status code: got %v", rr.Code)
+	}
+
+	got := decodeChirpIDs(t, getFeed(t, cfg, token, ""))
+	if want := []uuid.UUID{friendNew.ID, friendOld.ID}; !slices.Equal(got, want) {
+		t.Errorf("feed = %v, want %v", got, want)
+	}
+
+	got = decodeChirpIDs(t, getFeed(t, cfg, token, "?include_self=true"))
+	if want := []uuid.UUID{friendNew.ID, mine.ID, friendOld.ID}; !slices.Equal(got, want) {
+		t.Errorf("feed with self = %v, want %v", got, want)
+	}
+
+	got = decodeChirpIDs(t, getFeed(t, cfg, token, "?include_self=true&limit=1&offset=1"))
+	if want := []uuid.UUID{mine.ID}; !slices.Equal(got, want) {
+		t.Errorf("paginated feed = %v, want %v", got, want)
+	}
+}
+
+func TestHandlerGetFeedErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	me := db.addUser(t, "me@example.com")
+	token := makeTestToken(t, me.ID)
+
+	tests := []struct {
+		name  string
+		token string
+		query string
+		want  int
+	}{
+		{"missing token", "", "", http.StatusUnauthorized},
+		{"invalid include_self", token, "?include_self=maybe", http.StatusBadRequest},
+		{"invalid limit", token, "?limit=abc", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := getFeed(t, cfg, tt.token, tt.query); rr.Code != tt.want {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.want)
+			}
+		})
+	}
+}
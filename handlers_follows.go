@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerFollowUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	followerID, followee, ok := cfg.userActionRequest(w, r)
+	if !ok {
+		return
+	}
+	followeeID := followee.ID
+
+	if followerID == followeeID {
+		respondWithError(w, r, http.StatusBadRequest, "You cannot follow yourself")
+		return
+	}
+
+	// A suspended account takes no new followers or follow requests
+	suspended, err := cfg.isSuspended(r, followeeID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if suspended {
+		respondWithErrorCode(w, r, http.StatusForbidden, errCodeAccountSuspended, "This account is suspended")
+		return
+	}
+
+	// Following a private account the caller does not follow yet only asks
+	// its owner for approval. Asking twice is a no-op like following twice.
+	following := !followee.IsPrivate
+	if followee.IsPrivate {
+		following, err = cfg.canView(r, followeeID)
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+	}
+	if !following {
+		requested, err := cfg.dbQueries.CreateFollowRequest(r.Context(), database.CreateFollowRequestParams{
+			RequesterID: followerID,
+			TargetID:    followeeID,
+		})
+		if err != nil {
+			respondWithReferenceError(w, r, err, "User not found")
+			return
+		}
+		if requested > 0 {
+			cfg.notify(r.Context(), notificationEvent{
+				Type:    notificationFollowRequest,
+				UserID:  followeeID,
+				ActorID: followerID,
+			})
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// Following twice is a no-op thanks to the primary key
+	followed, err := cfg.dbQueries.CreateFollow(r.Context(), database.CreateFollowParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithReferenceError(w, r, err, "User not found")
+		return
+	}
+
+	if followed > 0 {
+		cfg.notify(r.Context(), notificationEvent{
+			Type:    notificationFollow,
+			UserID:  followeeID,
+			ActorID: followerID,
+		})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	followerID, followee, ok := cfg.userActionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.dbQueries.DeleteFollow(r.Context(), database.DeleteFollowParams{
+		FollowerID: followerID,
+		FolloweeID: followee.ID,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	// Unfollowing also withdraws a request that is still pending
+	_, err = cfg.dbQueries.DeleteFollowRequest(r.Context(), database.DeleteFollowRequestParams{
+		RequesterID: followerID,
+		TargetID:    followee.ID,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	// A private account's chirps drop out of the caller's lists
+	cfg.chirpCache.invalidate()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// userActionRequest authenticates the caller and loads the user being
+// acted on (followed, muted, ...), writing the error response itself when
+// either step fails.
+func (cfg *apiConfig) userActionRequest(w http.ResponseWriter, r *http.Request) (actorID uuid.UUID, target database.User, ok bool) {
+	actorID, ok = cfg.requireUser(w, r)
+	if !ok {
+		return uuid.Nil, database.User{}, false
+	}
+
+	targetID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return uuid.Nil, database.User{}, false
+	}
+
+	target, err = cfg.dbQueries.GetUserByID(r.Context(), targetID)
+	if err != nil {
+		respondWithLookupError(w, r, err, "User not found")
+		return uuid.Nil, database.User{}, false
+	}
+	if target.DeletedAt.Valid {
+		respondUserDeleted(w, r)
+		return uuid.Nil, database.User{}, false
+	}
+
+	return actorID, target, true
+}
+
+func (cfg *apiConfig) handlerGetFollowers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, limit, offset, ok := cfg.followListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	dbUsers, err := cfg.dbQueries.GetFollowers(r.Context(), database.GetFollowersParams{
+		FolloweeID: userID,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	users := make([]PublicUser, len(dbUsers))
+	for i, dbUser := range dbUsers {
+		users[i] = PublicUser{
+			ID:          dbUser.ID,
+			Email:       cfg.publicEmail(dbUser.Email),
+			Username:    dbUser.Username,
+			DisplayName: nullableString(dbUser.DisplayName),
+			Bio:         nullableString(dbUser.Bio),
+			AvatarURL:   nullableString(dbUser.AvatarUrl),
+			IsChirpyRed: dbUser.IsChirpyRed,
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(users)
+}
+
+func (cfg *apiConfig) handlerGetFollowing(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, limit, offset, ok := cfg.followListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	dbUsers, err := cfg.dbQueries.GetFollowing(r.Context(), database.GetFollowingParams{
+		FollowerID: userID,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	users := make([]PublicUser, len(dbUsers))
+	for i, dbUser := range dbUsers {
+		users[i] = PublicUser{
+			ID:          dbUser.ID,
+			Email:       cfg.publicEmail(dbUser.Email),
+			Username:    dbUser.Username,
+			DisplayName: nullableString(dbUser.DisplayName),
+			Bio:         nullableString(dbUser.Bio),
+			AvatarURL:   nullableString(dbUser.AvatarUrl),
+			IsChirpyRed: dbUser.IsChirpyRed,
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(users)
+}
+
+// followListRequest parses the user ID and pagination parameters of a
+// followers/following listing and checks that the user exists and that the
+// caller may see their account, writing the error response itself on
+// failure.
+func (cfg *apiConfig) followListRequest(w http.ResponseWriter, r *http.Request) (userID uuid.UUID, limit, offset int32, ok bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return uuid.Nil, 0, 0, false
+	}
+
+	limit, offset, err = parsePagination(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return uuid.Nil, 0, 0, false
+	}
+
+	_, err = cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithLookupError(w, r, err, "User not found")
+		return uuid.Nil, 0, 0, false
+	}
+
+	if !cfg.requireVisibleAccount(w, r, userID) {
+		return uuid.Nil, 0, 0, false
+	}
+
+	return userID, limit, offset, true
+}
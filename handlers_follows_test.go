@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func followRequest(t *testing.T, method, userID, token string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/users/"+userID+"/follow", nil)
+	req.SetPathValue("userID", userID)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func follow(t *testing.T, cfg *apiConfig, userID, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	cfg.handlerFollowUser(rr, followRequest(t, http.MethodPost, userID, token))
+	return rr
+}
+
+func unfollow(t *testing.T, cfg *apiConfig, userID, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	cfg.handlerUnfollowUser(rr, followRequest(t, http.MethodDelete, userID, token))
+	return rr
+}
+
+func getProfile(t *testing.T, cfg *apiConfig, userID string) (UserProfile, int) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/users/"+userID, nil)
+	req.SetPathValue("userID", userID)
+	rr := httptest.NewRecorder()
+	cfg.handlerGetUser(rr, req)
+
+	var profile UserProfile
+	if rr.Code == http.StatusOK {
+		if err := json.NewDecoder(rr.Body).Decode(&profile); err != nil {
+			t.Fatalf("Failed to decode profile: %v", err)
+		}
+	}
+	return profile, rr.Code
+}
+
+func TestHandlerFollowUser(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	aliceToken := makeTestToken(t, alice.ID)
+
+	// Re-following is idempotent
+	for i := 0; i < 2; i++ {
+		if rr := follow(t, cfg, bob.ID.String(), aliceToken); rr.Code != http.StatusNoContent {
+			t.Fatalf("follow #%d returned wrong status code: got %v want %v", i+1, rr.Code, http.StatusNoContent)
+		}
+	}
+
+	profile, code := getProfile(t, cfg, bob.ID.String())
+	if code != http.StatusOK {
+		t.Fatalf("profile returned wrong status code: got %v want %v", code, http.StatusOK)
+	}
+	if profile.FollowerCount != 1 || profile.FollowingCount != 0 {
+		t.Errorf("bob's counts = %d/%d, want 1/0", profile.FollowerCount, profile.FollowingCount)
+	}
+
+	profile, _ = getProfile(t, cfg, alice.ID.String())
+	if profile.FollowerCount != 0 || profile.FollowingCount != 1 {
+		t.Errorf("alice's counts = %d/%d, want 0/1", profile.FollowerCount, profile.FollowingCount)
+	}
+
+	// Unfollowing is idempotent too
+	for i := 0; i < 2; i++ {
+		if rr := unfollow(t, cfg, bob.ID.String(), aliceToken); rr.Code != http.StatusNoContent {
+			t.Fatalf("unfollow #%d returned wrong status code: got %v want %v", i+1, rr.Code, http.StatusNoContent)
+		}
+	}
+
+	profile, _ = getProfile(t, cfg, bob.ID.String())
+	if profile.FollowerCount != 0 {
+		t.Errorf("bob's follower_count = %d after unfollow, want 0", profile.FollowerCount)
+	}
+}
+
+func TestHandlerFollowUserErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	aliceToken := makeTestToken(t, alice.ID)
+
+	tests := []struct {
+		name   string
+		userID string
+		token  string
+		want   int
+	}{
+		{"follow yourself", alice.ID.String(), aliceToken, http.StatusBadRequest},
+		{"nonexistent user", uuid.New().String(), aliceToken, http.StatusNotFound},
+		{"invalid user ID", "not-a-uuid", aliceToken, http.StatusBadRequest},
+		{"missing token", bob.ID.String(), "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := follow(t, cfg, tt.userID, tt.token); rr.Code != tt.want {
+				t.Errorf("follow returned wrong status code: got %v want %v", rr.Code, tt.want)
+			}
+		})
+	}
+
+	if rr := unfollow(t, cfg, uuid.New().String(), aliceToken); rr.Code != http.StatusNotFound {
+		t.Errorf("unfollow of nonexistent user returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+	if _, code := getProfile(t, cfg, uuid.New().String()); code != http.StatusNotFound {
+		t.Errorf("profile of nonexistent user returned wrong status code: got %v want %v", code, http.StatusNotFound)
+	}
+}
+
+func TestHandlerFollowLists(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	star := db.addUser(t, "star@example.com")
+	loner := db.addUser(t, "loner@example.com")
+
+	var fans []uuid.UUID
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		fan := db.addUser(t, email)
+		if rr := follow(t, cfg, star.ID.String(), makeTestToken(t, fan.ID)); rr.Code != http.StatusNoContent {
+			t.Fatalf("follow returned wrong status code: got %v", rr.Code)
+		}
+		fans = append(fans, fan.ID)
+	}
+
+	list := func(handler http.HandlerFunc, userID, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/users/"+userID+"/list"+query, nil)
+		req.SetPathValue("userID", userID)
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+	decode := func(rr *httptest.ResponseRecorder) []uuid.UUID {
+		var users []PublicUser
+		if err := json.NewDecoder(rr.Body).Decode(&users); err != nil {
+			t.Fatalf("Failed to decode users: %v", err)
+		}
+		ids := make([]uuid.UUID, len(users))
+		for i, u := range users {
+			ids[i] = u.ID
+		}
+		return ids
+	}
+
+	rr := list(cfg.handlerGetFollowers, star.ID.String(), "?limit=2&offset=1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("followers returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if got := decode(rr); len(got) != 2 || got[0] != fans[1] || got[1] != fans[2] {
+		t.Errorf("followers page = %v, want %v", got, fans[1:])
+	}
+
+	rr = list(cfg.handlerGetFollowing, fans[0].String(), "")
+	if got := decode(rr); len(got) != 1 || got[0] != star.ID {
+		t.Errorf("following = %v, want [%v]", got, star.ID)
+	}
+
+	for _, handler := range []http.HandlerFunc{cfg.handlerGetFollowers, cfg.handlerGetFollowing} {
+		if rr := list(handler, loner.ID.String(), ""); rr.Code != http.StatusOK || rr.Body.String() != "[]\n" {
+			t.Errorf("expected 200 with empty array, got %v %q", rr.Code, rr.Body.String())
+		}
+		if rr := list(handler, uuid.New().String(), ""); rr.Code != http.StatusNotFound {
+			t.Errorf("unknown user returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+		if rr := list(handler, "not-a-uuid", ""); rr.Code != http.StatusBadRequest {
+			t.Errorf("invalid user ID returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+		if rr := list(handler, star.ID.String(), "?offset=x"); rr.Code != http.StatusBadRequest {
+			t.Errorf("invalid offset returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestFollowSuspendedUser(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	public := db.addUser(t, "public@example.com")
+	private := db.addUser(t, "private@example.com")
+	db.SetUserPrivate(t.Context(), database.SetUserPrivateParams{ID: private.ID, IsPrivate: true})
+	aliceToken := makeTestToken(t, alice.ID)
+
+	// A ban hides a public account like a private one, but following it
+	// must not turn into a request its owner is notified of
+	for _, user := range []database.User{public, private} {
+		db.BanUser(t.Context(), database.BanUserParams{ID: user.ID})
+		rr := follow(t, cfg, user.ID.String(), aliceToken)
+		var errResp ErrorResponse
+		json.Unmarshal(rr.Body.Bytes(), &errResp)
+		if rr.Code != http.StatusForbidden || errResp.Code != errCodeAccountSuspended {
+			t.Errorf("follow %s: got status %v %+v, want %v %q", user.Email, rr.Code, errResp, http.StatusForbidden, errCodeAccountSuspended)
+		}
+	}
+
+	if len(db.follows) != 0 || len(db.followReqs) != 0 || len(db.notifications) != 0 {
+		t.Errorf("got %d follows, %d follow requests and %d notifications, want none", len(db.follows), len(db.followReqs), len(db.notifications))
+	}
+}
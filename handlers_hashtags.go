@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerGetHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// The tag must be exactly what the extractor would have indexed
+	tag := strings.TrimPrefix(r.PathValue("tag"), "#")
+	tags := extractHashtags("#" + tag)
+	if len(tags) != 1 || tags[0] != strings.ToLower(tag) {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid hashtag")
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dbChirps, err := cfg.dbQueries.GetChirpsByHashtag(r.Context(), database.GetChirpsByHashtagParams{
+		Tag:      tags[0],
+		ViewerID: cfg.viewer(r),
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	chirps := make([]Chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		chirps[i] = chirpFromRow(database.GetChirpsRow(dbChirp))
+	}
+
+	err = cfg.populateChirps(r, chirps)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(chirps)
+}
+
+// indexHashtags replaces the stored hashtags of a chirp with the ones found
+// in body. It is safe to call again after the body changes.
+func (cfg *apiConfig) indexHashtags(ctx context.Context, chirpID uuid.UUID, body string) error {
+	err := cfg.dbQueries.DeleteChirpHashtags(ctx, chirpID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range extractHashtags(body) {
+		err = cfg.dbQueries.AddChirpHashtag(ctx, database.AddChirpHashtagParams{
+			ChirpID: chirpID,
+			Tag:     tag,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractHashtags returns the distinct, lowercased #hashtags in text in the
+// order they first appear. A tag is a '#' that does not follow a word
+// character, followed by one or more unicode letters, digits or underscores.
+func extractHashtags(text string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' || (i > 0 && isHashtagRune(runes[i-1])) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isHashtagRune(runes[end]) {
+			end++
+		}
+		if end == i+1 {
+			continue
+		}
+
+		tag := strings.ToLower(string(runes[i+1 : end]))
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+		i = end - 1
+	}
+
+	return tags
+}
+
+func isHashtagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
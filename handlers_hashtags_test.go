@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"no tags here", nil},
+		{"ends with #golang", []string{"golang"}},
+		{"#start of string", []string{"start"}},
+		{"punctuation #after, and #bang!", []string{"after", "bang"}},
+		{"lonely # sign", nil},
+		{"#", nil},
+		{"case #GoLang and #golang", []string{"golang"}},
+		{"unicode #café #日本語 #tag_2", []string{"café", "日本語", "tag_2"}},
+		{"not an email#tag", nil},
+		{"double ##tag", []string{"tag"}},
+		{"(#paren)", []string{"paren"}},
+	}
+
+	for _, tt := range tests {
+		result := extractHashtags(tt.input)
+		if !slices.Equal(result, tt.expected) {
+			t.Errorf("extractHashtags(%q) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestHandlerGetHashtagChirps(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	var created []Chirp
+	for _, body := range []string{"Learning #Go", "Unrelated", "More #go and #sql"} {
+		rr := postChirp(t, cfg, token, map[string]any{"body": body})
+		var chirp Chirp
+		if err := json.NewDecoder(rr.Body).Decode(&chirp); err != nil {
+			t.Fatalf("Failed to decode chirp: %v", err)
+		}
+		created = append(created, chirp)
+	}
+
+	getTag := func(tag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/hashtags/"+tag+"/chirps", nil)
+		req.SetPathValue("tag", tag)
+		rr := httptest.NewRecorder()
+		cfg.handlerGetHashtagChirps(rr, req)
+		return rr
+	}
+
+	rr := getTag("GO")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var chirps []Chirp
+	if err := json.NewDecoder(rr.Body).Decode(&chirps); err != nil {
+		t.Fatalf("Failed to decode chirps: %v", err)
+	}
+	if len(chirps) != 2 || chirps[0].ID != created[2].ID || chirps[1].ID != created[0].ID {
+		t.Errorf("expected tagged chirps newest first, got %+v", chirps)
+	}
+
+	if rr := getTag("missing"); rr.Body.String() != "[]\n" {
+		t.Errorf("expected empty array for unused tag, got %q", rr.Body.String())
+	}
+	if rr := getTag("bad-tag"); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid tag returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerLikeChirp(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, chirp, ok := cfg.chirpActionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Liking twice is a no-op thanks to the (chirp_id, user_id) constraint
+	liked, err := cfg.dbQueries.LikeChirp(r.Context(), database.LikeChirpParams{
+		ChirpID: chirp.ID,
+		UserID:  userID,
+	})
+	if err != nil {
+		respondWithReferenceError(w, r, err, "Chirp not found")
+		return
+	}
+
+	if liked > 0 {
+		cfg.chirpCache.invalidate()
+		cfg.notify(r.Context(), notificationEvent{
+			Type:    notificationLike,
+			UserID:  chirp.UserID,
+			ActorID: userID,
+			ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, chirp, ok := cfg.chirpActionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.dbQueries.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		ChirpID: chirp.ID,
+		UserID:  userID,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	cfg.chirpCache.invalidate()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// chirpActionRequest authenticates the caller and resolves the chirp being
+// acted on (liked, reported, ...), writing the error response itself when
+// either step fails. Chirps the caller may not see are not found.
+func (cfg *apiConfig) chirpActionRequest(w http.ResponseWriter, r *http.Request) (userID uuid.UUID, chirp database.Chirp, ok bool) {
+	userID, ok = cfg.requireUser(w, r)
+	if !ok {
+		return uuid.Nil, database.Chirp{}, false
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid chirp ID")
+		return uuid.Nil, database.Chirp{}, false
+	}
+
+	dbChirp, err := cfg.getVisibleChirp(r, chirpID)
+	if err != nil {
+		respondWithLookupError(w, r, err, "Chirp not found")
+		return uuid.Nil, database.Chirp{}, false
+	}
+
+	return userID, dbChirp.Chirp, true
+}
+
+// markLikedByMe fills in LikedByMe on each chirp when the request carries a
+// valid access token, using a single query for the whole slice.
+func (cfg *apiConfig) markLikedByMe(r *http.Request, chirps []Chirp) error {
+	userID, ok := cfg.viewerID(r)
+	if !ok || len(chirps) == 0 {
+		return nil
+	}
+
+	chirpIDs := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		chirpIDs[i] = chirp.ID
+	}
+
+	likedIDs, err := cfg.dbQueries.GetLikedChirpIDs(r.Context(), database.GetLikedChirpIDsParams{
+		UserID:   userID,
+		ChirpIds: chirpIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	liked := make(map[uuid.UUID]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+
+	for i := range chirps {
+		likedByMe := liked[chirps[i].ID]
+		chirps[i].LikedByMe = &likedByMe
+	}
+
+	return nil
+}
+
+func (cfg *apiConfig) handlerGetChirpLikes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	_, err = cfg.getVisibleChirp(r, chirpID)
+	if err != nil {
+		respondWithLookupError(w, r, err, "Chirp not found")
+		return
+	}
+
+	dbLikers, err := cfg.dbQueries.GetChirpLikers(r.Context(), database.GetChirpLikersParams{
+		ChirpID: chirpID,
+		Limit:   limit,
+		Offset:  offset,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	// Any per-viewer visibility rules (blocks, private accounts) belong here
+	likers := make([]PublicUser, 0, len(dbLikers))
+	for _, dbLiker := range dbLikers {
+		likers = append(likers, PublicUser{
+			ID:          dbLiker.ID,
+			Email:       cfg.publicEmail(dbLiker.Email),
+			Username:    dbLiker.Username,
+			DisplayName: nullableString(dbLiker.DisplayName),
+			Bio:         nullableString(dbLiker.Bio),
+			AvatarURL:   nullableString(dbLiker.AvatarUrl),
+			IsChirpyRed: dbLiker.IsChirpyRed,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(likers)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func likeRequest(t *testing.T, method string, chirpID uuid.UUID, token string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/chirps/"+chirpID.String()+"/like", nil)
+	req.SetPathValue("chirpID", chirpID.String())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func TestHandlerLikeChirp(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	author := db.addUser(t, "author@example.com")
+	liker := db.addUser(t, "liker@example.com")
+	chirp := db.addChirp(t, author.ID, "Like me")
+	token := makeTestToken(t, liker.ID)
+
+	// Liking twice must be idempotent
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		cfg.handlerLikeChirp(rr, likeRequest(t, http.MethodPost, chirp.ID, token))
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("like #%d returned wrong status code: got %v want %v", i+1, rr.Code, http.StatusNoContent)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+chirp.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	req.SetPathValue("chirpID", chirp.ID.String())
+	cfg.handlerGetChirpByID(rr, req)
+
+	var got Chirp
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode chirp: %v", err)
+	}
+	if got.LikeCount != 1 {
+		t.Errorf("like_count = %d, want 1", got.LikeCount)
+	}
+	if got.LikedByMe == nil || !*got.LikedByMe {
+		t.Errorf("liked_by_me = %v, want true", got.LikedByMe)
+	}
+
+	rr = httptest.NewRecorder()
+	cfg.handlerUnlikeChirp(rr, likeRequest(t, http.MethodDelete, chirp.ID, token))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("unlike returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+
+	rr = httptest.NewRecorder()
+	cfg.handlerGetChirps(rr, httptest.NewRequest(http.MethodGet, "/api/chirps", nil))
+
+	var chirps []Chirp
+	if err := json.NewDecoder(rr.Body).Decode(&chirps); err != nil {
+		t.Fatalf("Failed to decode chirps: %v", err)
+	}
+	if len(chirps) != 1 || chirps[0].LikeCount != 0 {
+		t.Fatalf("expected one chirp with like_count 0, got %+v", chirps)
+	}
+	if chirps[0].LikedByMe != nil {
+		t.Errorf("liked_by_me should be omitted for anonymous requests")
+	}
+}
+
+func TestHandlerLikeChirpErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	chirp := db.addChirp(t, user.ID, "Hello")
+	token := makeTestToken(t, user.ID)
+
+	tests := []struct {
+		name    string
+		chirpID uuid.UUID
+		token   string
+		want    int
+	}{
+		{"missing token", chirp.ID, "", http.StatusUnauthorized},
+		{"invalid token", chirp.ID, "not-a-jwt", http.StatusUnauthorized},
+		{"unknown chirp", uuid.New(), token, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, method := range []string{http.MethodPost, http.MethodDelete} {
+				rr := httptest.NewRecorder()
+				req := likeRequest(t, method, tt.chirpID, tt.token)
+				if method == http.MethodPost {
+					cfg.handlerLikeChirp(rr, req)
+				} else {
+					cfg.handlerUnlikeChirp(rr, req)
+				}
+				if rr.Code != tt.want {
+					t.Errorf("%s returned wrong status code: got %v want %v", method, rr.Code, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerGetChirpLikes(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	author := db.addUser(t, "author@example.com")
+	chirp := db.addChirp(t, author.ID, "Popular chirp")
+	lonely := db.addChirp(t, author.ID, "Nobody likes me")
+
+	var likers []uuid.UUID
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		user := db.addUser(t, email)
+		rr := httptest.NewRecorder()
+		cfg.handlerLikeChirp(rr, likeRequest(t, http.MethodPost, chirp.ID, makeTestToken(t, user.ID)))
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("like returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+		}
+		likers = append(likers, user.ID)
+	}
+
+	getLikes := func(chirpID, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+chirpID+"/likes"+query, nil)
+		req.SetPathValue("chirpID", chirpID)
+		rr := httptest.NewRecorder()
+		cfg.handlerGetChirpLikes(rr, req)
+		return rr
+	}
+
+	rr := getLikes(chirp.ID.String(), "?limit=2&offset=1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var page []PublicUser
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to decode likers: %v", err)
+	}
+	if len(page) != 2 || page[0].ID != likers[1] || page[1].ID != likers[2] {
+		t.Errorf("unexpected page of likers: %+v", page)
+	}
+
+	rr = getLikes(lonely.ID.String(), "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if body := rr.Body.String(); body != "[]\n" {
+		t.Errorf("expected empty array for chirp without likes, got %q", body)
+	}
+
+	tests := []struct {
+		name    string
+		chirpID string
+		query   string
+		want    int
+	}{
+		{"unknown chirp", uuid.New().String(), "", http.StatusNotFound},
+		{"invalid chirp ID", "not-a-uuid", "", http.StatusBadRequest},
+		{"invalid limit", chirp.ID.String(), "?limit=0", http.StatusBadRequest},
+		{"limit too large", chirp.ID.String(), "?limit=1000", http.StatusBadRequest},
+		{"negative offset", chirp.ID.String(), "?offset=-1", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := getLikes(tt.chirpID, tt.query); rr.Code != tt.want {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// loginHistoryLimit is how many logins are kept and listed per user
+	loginHistoryLimit = 50
+	// maxUserAgentLength caps the user agents stored, in bytes
+	maxUserAgentLength = 512
+)
+
+// recordLogin adds a successful login by userID to their login history.
+// The login has already succeeded, so a failure is only logged.
+func (cfg *apiConfig) recordLogin(r *http.Request, userID uuid.UUID) {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+	err := cfg.dbQueries.CreateLoginHistoryEntry(r.Context(), database.CreateLoginHistoryEntryParams{
+		UserID:    userID,
+		Ip:        cfg.clientIP(r),
+		UserAgent: userAgent,
+	})
+	if err != nil {
+		logf(r.Context(), "Error recording login of user %s: %v", userID, err)
+	}
+}
+
+// handlerGetLoginHistory lists the caller's latest successful logins,
+// newest first, so they can spot any that were not them.
+func (cfg *apiConfig) handlerGetLoginHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	dbLogins, err := cfg.dbQueries.GetLoginHistory(r.Context(), database.GetLoginHistoryParams{
+		UserID: userID,
+		Limit:  loginHistoryLimit,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(loginsFromDB(dbLogins))
+}
+
+func loginsFromDB(dbLogins []database.LoginHistory) []Login {
+	logins := make([]Login, len(dbLogins))
+	for i, l := range dbLogins {
+		logins[i] = Login{CreatedAt: l.CreatedAt, IP: l.Ip, UserAgent: l.UserAgent}
+	}
+	return logins
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+)
+
+func TestLoginHistory(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.trustProxy = true
+	user := decodeUser(t, postUser(t, cfg, map[string]string{"email": "alice@example.com", "password": "password123"}))
+
+	login := func(password, userAgent string) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"email": "alice@example.com", "password": password})
+		req := httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body))
+		req.Header.Set("User-Agent", userAgent)
+		req.Header.Set("X-Forwarded-For", "203.0.113.9, 198.51.100.7")
+		cfg.handlerLogin(httptest.NewRecorder(), req)
+	}
+	login("password123", "first")
+	login("wrong-password", "failed")
+	login("password123", strings.Repeat("x", 1000))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/me/logins", nil)
+	req.Header.Set("Authorization", "Bearer "+makeTestToken(t, user.ID))
+	rr := httptest.NewRecorder()
+	cfg.handlerGetLoginHistory(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	var logins []Login
+	if err := json.Unmarshal(rr.Body.Bytes(), &logins); err != nil {
+		t.Fatal(err)
+	}
+	// The failed login is not recorded
+	if len(logins) != 2 {
+		t.Fatalf("got %d logins, want 2: %+v", len(logins), logins)
+	}
+	if len(logins[0].UserAgent) != maxUserAgentLength || logins[1].UserAgent != "first" {
+		t.Errorf("user agents = %q, %q; want the newest first, cut to %d bytes", logins[0].UserAgent, logins[1].UserAgent, maxUserAgentLength)
+	}
+	if logins[1].IP != "198.51.100.7" {
+		t.Errorf("ip = %q, want the address the proxy appended", logins[1].IP)
+	}
+
+	// Nobody else sees them
+	other := db.addUser(t, "bob@example.com")
+	req.Header.Set("Authorization", "Bearer "+makeTestToken(t, other.ID))
+	rr = httptest.NewRecorder()
+	cfg.handlerGetLoginHistory(rr, req)
+	if body := strings.TrimSpace(rr.Body.String()); body != "[]" {
+		t.Errorf("another user's logins = %s, want []", body)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		forwarded  []string
+		want       string
+	}{
+		{"direct", false, nil, "192.0.2.1"},
+		{"header ignored without a proxy", false, []string{"203.0.113.9"}, "192.0.2.1"},
+		{"proxy", true, []string{"203.0.113.9"}, "203.0.113.9"},
+		{"last entry", true, []string{"10.0.0.1, 203.0.113.9"}, "203.0.113.9"},
+		{"last header", true, []string{"10.0.0.1", "203.0.113.9"}, "203.0.113.9"},
+		{"ipv6", true, []string{"2001:db8::1"}, "2001:db8::1"},
+		{"malformed", true, []string{"203.0.113.9, not-an-ip"}, "192.0.2.1"},
+		{"no header", true, nil, "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &apiConfig{trustProxy: tt.trustProxy}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.1:54321"
+			for _, f := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", f)
+			}
+			if got := cfg.clientIP(req); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanupPrunesLoginHistory(t *testing.T) {
+	db := newFakeStore()
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	for i := range loginHistoryLimit + 5 {
+		db.CreateLoginHistoryEntry(t.Context(), database.CreateLoginHistoryEntryParams{UserID: alice.ID, UserAgent: fmt.Sprint(i)})
+		if i < 3 {
+			db.CreateLoginHistoryEntry(t.Context(), database.CreateLoginHistoryEntryParams{UserID: bob.ID, UserAgent: fmt.Sprint(i)})
+		}
+	}
+
+	newCleanupWorker(db).runOnce(t.Context())
+
+	logins, _ := db.GetLoginHistory(t.Context(), database.GetLoginHistoryParams{UserID: alice.ID, Limit: 1000})
+	if len(logins) != loginHistoryLimit {
+		t.Fatalf("kept %d of alice's logins, want %d", len(logins), loginHistoryLimit)
+	}
+	if newest, oldest := logins[0].UserAgent, logins[len(logins)-1].UserAgent; newest != "54" || oldest != "5" {
+		t.Errorf("kept logins %s to %s, want the newest, 54 to 5", newest, oldest)
+	}
+	if logins, _ := db.GetLoginHistory(t.Context(), database.GetLoginHistoryParams{UserID: bob.ID, Limit: 1000}); len(logins) != 3 {
+		t.Errorf("kept %d of bob's logins, want all 3", len(logins))
+	}
+}
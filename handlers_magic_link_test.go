@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/mail"
+)
+
+// magicLink matches the login link in a magic link message.
+var magicLink = regexp.MustCompile(`https://chirpy\.example\.com(/api/login/magic/verify\?token=\w+)`)
+
+// magicLinkPath returns the path and query of the link in msg.
+func magicLinkPath(t *testing.T, msg mail.Message) string {
+	t.Helper()
+	m := magicLink.FindStringSubmatch(msg.Text)
+	if m == nil {
+		t.Fatalf("no login link in %q", msg.Text)
+	}
+	return m[1]
+}
+
+// newMagicLinkServer returns a test server with alice signed up and the
+// sender her emails go to, with the welcome email already taken.
+func newMagicLinkServer(t *testing.T) (*apiConfig, *fakeStore, *fakeSender, func(method, path string, body, out any) *http.Response) {
+	t.Helper()
+	cfg, db := newTestConfig(t)
+	cfg.publicURL = "https://chirpy.example.com"
+	sender := newFakeSender()
+	cfg.mailer = sender
+	srv := newTestServer(t, cfg)
+	do := func(method, path string, body, out any) *http.Response {
+		t.Helper()
+		return call(t, srv, method, path, "", body, out)
+	}
+	do(http.MethodPost, "/api/users", map[string]string{"email": "alice@example.com", "password": "password123"}, nil)
+	sender.next(t)
+	return cfg, db, sender, do
+}
+
+func TestMagicLinkLogin(t *testing.T) {
+	cfg, _, sender, do := newMagicLinkServer(t)
+
+	// An unknown email looks the same but sends nothing
+	requestLink := func(email string) int {
+		t.Helper()
+		return do(http.MethodPost, "/api/login/magic", map[string]string{"email": email}, nil).StatusCode
+	}
+	unknown, known := requestLink("nobody@example.com"), requestLink("alice@example.com")
+	if unknown != http.StatusNoContent || known != http.StatusNoContent {
+		t.Errorf("unknown email got %v, known %v; want %v for both", unknown, known, http.StatusNoContent)
+	}
+	msg := sender.next(t)
+	if msg.To != "alice@example.com" {
+		t.Fatalf("login link sent to %q, want alice@example.com", msg.To)
+	}
+	path := magicLinkPath(t, msg)
+
+	var login struct {
+		User
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if resp := do(http.MethodGet, path, nil, &login); resp.StatusCode != http.StatusOK {
+		t.Fatalf("verify: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if login.Email != "alice@example.com" || login.Token == "" || login.RefreshToken == "" {
+		t.Errorf("verify returned %+v, want alice with both tokens", login)
+	}
+	if _, err := cfg.dbQueries.GetUserFromRefreshToken(t.Context(), login.RefreshToken); err != nil {
+		t.Errorf("refresh token from the link does not work: %v", err)
+	}
+
+	// The link works once
+	var errResp ErrorResponse
+	if resp := do(http.MethodGet, path, nil, &errResp); resp.StatusCode != http.StatusUnauthorized || errResp.Code != errCodeInvalidLoginLink {
+		t.Errorf("reused link: got status %v code %q, want %v %q", resp.StatusCode, errResp.Code, http.StatusUnauthorized, errCodeInvalidLoginLink)
+	}
+
+	// POST works as well as GET
+	requestLink("alice@example.com")
+	if resp := do(http.MethodPost, magicLinkPath(t, sender.next(t)), nil, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("verify by POST: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+
+	for _, body := range []any{map[string]string{}, nil} {
+		if resp := do(http.MethodPost, "/api/login/magic", body, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("request without an email: got status %v want %v", resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+	if resp := do(http.MethodGet, "/api/login/magic/verify", nil, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("verify without a token: got status %v want %v", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestMagicLinkExpiry(t *testing.T) {
+	_, db, sender, do := newMagicLinkServer(t)
+
+	do(http.MethodPost, "/api/login/magic", map[string]string{"email": "alice@example.com"}, nil)
+	path := magicLinkPath(t, sender.next(t))
+	db.mu.Lock()
+	db.magicLinks[0].ExpiresAt = time.Now().Add(-time.Second)
+	db.mu.Unlock()
+
+	if resp := do(http.MethodGet, path, nil, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expired link: got status %v want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	// The cleanup job removes it
+	newCleanupWorker(db).runOnce(t.Context())
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.magicLinks) != 0 {
+		t.Errorf("%d expired login links left after cleanup", len(db.magicLinks))
+	}
+}
+
+func TestMagicLinkSingleUse(t *testing.T) {
+	_, _, sender, do := newMagicLinkServer(t)
+
+	do(http.MethodPost, "/api/login/magic", map[string]string{"email": "alice@example.com"}, nil)
+	path := magicLinkPath(t, sender.next(t))
+
+	// Opened twice at once, the link still logs in only once
+	codes := make([]int, 5)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = do(http.MethodGet, path, nil, nil).StatusCode
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, code := range codes {
+		if code == http.StatusOK {
+			succeeded++
+		} else if code != http.StatusUnauthorized {
+			t.Errorf("got status %v, want %v or %v", code, http.StatusOK, http.StatusUnauthorized)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d of %d concurrent uses succeeded, want 1", succeeded, len(codes))
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// storeMentions resolves the @handles in body to users by username and
+// records a mention row for each one that matches. Unknown handles are
+// ignored. It returns the IDs of the mentioned users.
+func (cfg *apiConfig) storeMentions(ctx context.Context, chirpID uuid.UUID, body string) ([]uuid.UUID, error) {
+	mentioned := []uuid.UUID{}
+
+	handles := extractMentions(body)
+	if len(handles) == 0 {
+		return mentioned, nil
+	}
+
+	rows, err := cfg.dbQueries.ResolveMentionHandles(ctx, handles)
+	if err != nil {
+		return mentioned, err
+	}
+
+	matches := make(map[string][]uuid.UUID)
+	for _, row := range rows {
+		matches[row.Handle] = append(matches[row.Handle], row.ID)
+	}
+
+	for _, handle := range handles {
+		if len(matches[handle]) != 1 {
+			continue
+		}
+		userID := matches[handle][0]
+
+		err = cfg.dbQueries.AddChirpMention(ctx, database.AddChirpMentionParams{
+			ChirpID: chirpID,
+			UserID:  userID,
+		})
+		if err != nil {
+			return mentioned, err
+		}
+		mentioned = append(mentioned, userID)
+	}
+
+	return mentioned, nil
+}
+
+// attachMentions fills in Mentions on each chirp using a single query for
+// the whole slice.
+func (cfg *apiConfig) attachMentions(r *http.Request, chirps []Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	chirpIDs := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		chirpIDs[i] = chirp.ID
+	}
+
+	mentions, err := cfg.dbQueries.GetChirpMentions(r.Context(), chirpIDs)
+	if err != nil {
+		return err
+	}
+
+	byChirp := make(map[uuid.UUID][]uuid.UUID)
+	for _, mention := range mentions {
+		byChirp[mention.ChirpID] = append(byChirp[mention.ChirpID], mention.UserID)
+	}
+
+	for i := range chirps {
+		if userIDs, ok := byChirp[chirps[i].ID]; ok {
+			chirps[i].Mentions = userIDs
+		}
+	}
+
+	return nil
+}
+
+// extractMentions returns the distinct, lowercased @handles in text in the
+// order they first appear. An '@' directly after a word character (as in an
+// email address) does not start a mention, and trailing dots are treated as
+// punctuation rather than part of the handle.
+func extractMentions(text string) []string {
+	var handles []string
+	seen := make(map[string]bool)
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' || (i > 0 && isMentionRune(runes[i-1])) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isMentionRune(runes[end]) {
+			end++
+		}
+
+		handle := strings.ToLower(strings.TrimRight(string(runes[i+1:end]), "."))
+		if handle != "" && !seen[handle] {
+			seen[handle] = true
+			handles = append(handles, handle)
+		}
+		i = end - 1
+	}
+
+	return handles
+}
+
+func isMentionRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("._+-", r)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"no mentions here", nil},
+		{"hello @alice", []string{"alice"}},
+		{"@alice and @bob", []string{"alice", "bob"}},
+		{"hey @Alice, @BOB! (@carol)", []string{"alice", "bob", "carol"}},
+		{"end of sentence @dave.", []string{"dave"}},
+		{"dotted @first.last rocks", []string{"first.last"}},
+		{"repeat @alice @ALICE", []string{"alice"}},
+		{"mail me at alice@example.com", nil},
+		{"lonely @ sign", nil},
+		{"@", nil},
+		{"@@eve", []string{"eve"}},
+	}
+
+	for _, tt := range tests {
+		result := extractMentions(tt.input)
+		if !slices.Equal(result, tt.expected) {
+			t.Errorf("extractMentions(%q) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestHandlerCreateChirpMentions(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	author := db.addUser(t, "author@example.com")
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "Bob@example.org")
+	// Handles are usernames: the second sam is sam_2, and the email's local
+	// part is not a handle
+	db.addUser(t, "sam@example.com")
+	sam2 := db.addUser(t, "sam@example.org")
+	db.addUser(t, "carol.jones@example.com")
+	token := makeTestToken(t, author.ID)
+
+	rr := postChirp(t, cfg, token, map[string]any{
+		"body": "Hi @alice, @bob and @nobody. Also @sam_2, @carol.jones and me @author!",
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	var chirp Chirp
+	if err := json.NewDecoder(rr.Body).Decode(&chirp); err != nil {
+		t.Fatalf("Failed to decode chirp: %v", err)
+	}
+
+	// Self-mentions are kept like any other mention
+	expected := []uuid.UUID{alice.ID, bob.ID, sam2.ID, author.ID}
+	if !slices.Equal(chirp.Mentions, expected) {
+		t.Errorf("mentions = %v, want %v", chirp.Mentions, expected)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+chirp.ID.String(), nil)
+	rr = httptest.NewRecorder()
+	req.SetPathValue("chirpID", chirp.ID.String())
+	cfg.handlerGetChirpByID(rr, req)
+	var got Chirp
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode chirp: %v", err)
+	}
+	if len(got.Mentions) != len(expected) {
+		t.Errorf("stored mentions = %v, want %d entries", got.Mentions, len(expected))
+	}
+
+	rr = postChirp(t, cfg, token, map[string]any{"body": "Nobody mentioned"})
+	var plain map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &plain); err != nil {
+		t.Fatalf("Failed to decode chirp: %v", err)
+	}
+	if mentions, ok := plain["mentions"].([]any); !ok || len(mentions) != 0 {
+		t.Errorf("expected empty mentions array, got %v", plain["mentions"])
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+)
+
+// Mutes only affect what the muter sees in their feed. They are never
+// exposed to the muted user, so these handlers reveal nothing beyond
+// whether the target user exists.
+
+func (cfg *apiConfig) handlerMuteUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	muterID, muted, ok := cfg.userActionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if muterID == muted.ID {
+		respondWithError(w, r, http.StatusBadRequest, "You cannot mute yourself")
+		return
+	}
+
+	// Muting twice is a no-op thanks to the primary key
+	err := cfg.dbQueries.CreateMute(r.Context(), database.CreateMuteParams{
+		MuterID: muterID,
+		MutedID: muted.ID,
+	})
+	if err != nil {
+		respondWithReferenceError(w, r, err, "User not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnmuteUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	muterID, muted, ok := cfg.userActionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.dbQueries.DeleteMute(r.Context(), database.DeleteMuteParams{
+		MuterID: muterID,
+		MutedID: muted.ID,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
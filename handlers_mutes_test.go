@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func muteRequest(t *testing.T, cfg *apiConfig, method, userID, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/users/"+userID+"/mute", nil)
+	req.SetPathValue("userID", userID)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	if method == http.MethodPost {
+		cfg.handlerMuteUser(rr, req)
+	} else {
+		cfg.handlerUnmuteUser(rr, req)
+	}
+	return rr
+}
+
+func TestHandlerMuteUser(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	me := db.addUser(t, "me@example.com")
+	loud := db.addUser(t, "loud@example.com")
+	quiet := db.addUser(t, "quiet@example.com")
+	token := makeTestToken(t, me.ID)
+
+	loudChirp := db.addChirp(t, loud.ID, "LOUD NOISES")
+	quietChirp := db.addChirp(t, quiet.ID, "hello")
+	for _, user := range []string{loud.ID.String(), quiet.ID.String()} {
+		if rr := follow(t, cfg, user, token); rr.Code != http.StatusNoContent {
+			t.Fatalf("follow returned wrong status code: got %v", rr.Code)
+		}
+	}
+
+	// Muting twice must be idempotent
+	for i := 0; i < 2; i++ {
+		if rr := muteRequest(t, cfg, http.MethodPost, loud.ID.String(), token); rr.Code != http.StatusNoContent {
+			t.Fatalf("mute #%d returned wrong status code: got %v want %v", i+1, rr.Code, http.StatusNoContent)
+		}
+	}
+
+	feed := decodeChirpIDs(t, getFeed(t, cfg, token, ""))
+	if slices.Contains(feed, loudChirp.ID) || !slices.Contains(feed, quietChirp.ID) {
+		t.Errorf("feed should only contain the unmuted chirp, got %v", feed)
+	}
+
+	// Direct lookups and profiles are unaffected
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+loudChirp.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	req.SetPathValue("chirpID", loudChirp.ID.String())
+	cfg.handlerGetChirpByID(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("muted chirp lookup returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if _, code := getProfile(t, cfg, loud.ID.String()); code != http.StatusOK {
+		t.Errorf("muted profile returned wrong status code: got %v want %v", code, http.StatusOK)
+	}
+
+	// Muting only affects the muter's feed
+	loudFeed := decodeChirpIDs(t, getFeed(t, cfg, makeTestToken(t, loud.ID), "?include_self=true"))
+	if !slices.Contains(loudFeed, loudChirp.ID) {
+		t.Errorf("muted user's own feed should be unaffected, got %v", loudFeed)
+	}
+
+	if rr := muteRequest(t, cfg, http.MethodDelete, loud.ID.String(), token); rr.Code != http.StatusNoContent {
+		t.Fatalf("unmute returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	feed = decodeChirpIDs(t, getFeed(t, cfg, token, ""))
+	if !slices.Contains(feed, loudChirp.ID) {
+		t.Errorf("unmuted chirp should be back in the feed, got %v", feed)
+	}
+}
+
+func TestHandlerMuteUserErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	me := db.addUser(t, "me@example.com")
+	token := makeTestToken(t, me.ID)
+
+	if rr := muteRequest(t, cfg, http.MethodPost, me.ID.String(), token); rr.Code != http.StatusBadRequest {
+		t.Errorf("self-mute returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	if rr := muteRequest(t, cfg, http.MethodPost, me.ID.String(), ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("missing token returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+	if rr := muteRequest(t, cfg, http.MethodDelete, "not-a-uuid", token); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid user ID returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerGetNotifications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dbNotifications, err := cfg.dbQueries.GetNotifications(r.Context(), database.GetNotificationsParams{
+		UserID: userID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	unreadCount, err := cfg.dbQueries.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	notifications := make([]Notification, len(dbNotifications))
+	for i, dbNotification := range dbNotifications {
+		notifications[i] = Notification{
+			ID:        dbNotification.ID,
+			CreatedAt: dbNotification.CreatedAt,
+			Type:      dbNotification.Type,
+			Actor: PublicUser{
+				ID:          dbNotification.ActorID,
+				Email:       cfg.publicEmail(dbNotification.ActorEmail),
+				Username:    dbNotification.ActorUsername,
+				DisplayName: nullableString(dbNotification.ActorDisplayName),
+				Bio:         nullableString(dbNotification.ActorBio),
+				AvatarURL:   nullableString(dbNotification.ActorAvatarUrl),
+				IsChirpyRed: dbNotification.ActorIsChirpyRed,
+			},
+			Read: dbNotification.ReadAt.Valid,
+		}
+		if dbNotification.ChirpID.Valid {
+			notifications[i].ChirpID = &dbNotification.ChirpID.UUID
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(NotificationsResponse{
+		Notifications: notifications,
+		UnreadCount:   unreadCount,
+	})
+}
+
+func (cfg *apiConfig) handlerMarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		IDs []uuid.UUID `json:"ids"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	// An empty body (or one without "ids") marks everything as read
+	reqBody := requestBody{}
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+
+	if reqBody.IDs == nil {
+		err = cfg.dbQueries.MarkAllNotificationsRead(r.Context(), userID)
+	} else {
+		// IDs belonging to other users are silently ignored by the query
+		err = cfg.dbQueries.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{
+			UserID: userID,
+			Ids:    reqBody.IDs,
+		})
+	}
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
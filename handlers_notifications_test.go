@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func getNotifications(t *testing.T, cfg *apiConfig, token, query string) NotificationsResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/notifications"+query, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerGetNotifications(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var resp NotificationsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode notifications: %v", err)
+	}
+	return resp
+}
+
+func markRead(t *testing.T, cfg *apiConfig, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/notifications/read", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerMarkNotificationsRead(rr, req)
+	return rr
+}
+
+func TestNotificationsFromHandlers(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	aliceToken := makeTestToken(t, alice.ID)
+	bobToken := makeTestToken(t, bob.ID)
+
+	chirp := db.addChirp(t, alice.ID, "Hello world")
+
+	// Repeated follows and likes only notify once
+	for i := 0; i < 2; i++ {
+		follow(t, cfg, alice.ID.String(), bobToken)
+		cfg.handlerLikeChirp(httptest.NewRecorder(), likeRequest(t, http.MethodPost, chirp.ID, bobToken))
+	}
+	postChirp(t, cfg, bobToken, map[string]any{"body": "Nice one", "parent_chirp_id": chirp.ID})
+	postChirp(t, cfg, bobToken, map[string]any{"body": "Hey @alice"})
+
+	// Acting on your own content is not notified
+	cfg.handlerLikeChirp(httptest.NewRecorder(), likeRequest(t, http.MethodPost, chirp.ID, aliceToken))
+	postChirp(t, cfg, aliceToken, map[string]any{"body": "Talking to myself @alice", "parent_chirp_id": chirp.ID})
+
+	resp := getNotifications(t, cfg, aliceToken, "")
+	wantTypes := []string{notificationMention, notificationReply, notificationLike, notificationFollow}
+	if len(resp.Notifications) != len(wantTypes) {
+		t.Fatalf("got %d notifications, want %d: %+v", len(resp.Notifications), len(wantTypes), resp.Notifications)
+	}
+	for i, n := range resp.Notifications {
+		if n.Type != wantTypes[i] {
+			t.Errorf("notification %d type = %q, want %q", i, n.Type, wantTypes[i])
+		}
+		if n.Actor.ID != bob.ID || n.Read {
+			t.Errorf("notification %d = %+v, want unread from bob", i, n)
+		}
+	}
+	if resp.Notifications[2].ChirpID == nil || *resp.Notifications[2].ChirpID != chirp.ID {
+		t.Errorf("like notification chirp_id = %v, want %v", resp.Notifications[2].ChirpID, chirp.ID)
+	}
+	if resp.Notifications[3].ChirpID != nil {
+		t.Errorf("follow notification should have no chirp_id, got %v", resp.Notifications[3].ChirpID)
+	}
+	if resp.UnreadCount != 4 {
+		t.Errorf("unread_count = %d, want 4", resp.UnreadCount)
+	}
+
+	if bobResp := getNotifications(t, cfg, bobToken, ""); len(bobResp.Notifications) != 0 || bobResp.UnreadCount != 0 {
+		t.Errorf("bob should have no notifications, got %+v", bobResp)
+	}
+}
+
+func TestHandlerMarkNotificationsRead(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	aliceToken := makeTestToken(t, alice.ID)
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		fan := db.addUser(t, email)
+		follow(t, cfg, alice.ID.String(), makeTestToken(t, fan.ID))
+	}
+
+	page := getNotifications(t, cfg, aliceToken, "?limit=2&offset=1")
+	if len(page.Notifications) != 2 || page.UnreadCount != 3 {
+		t.Fatalf("expected a page of 2 with unread_count 3, got %+v", page)
+	}
+
+	// Marking someone else's notification does nothing
+	otherToken := makeTestToken(t, uuid.New())
+	body := `{"ids": ["` + page.Notifications[0].ID.String() + `"]}`
+	if rr := markRead(t, cfg, otherToken, body); rr.Code != http.StatusNoContent {
+		t.Fatalf("mark read returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	if resp := getNotifications(t, cfg, aliceToken, ""); resp.UnreadCount != 3 {
+		t.Errorf("unread_count = %d, want 3", resp.UnreadCount)
+	}
+
+	if rr := markRead(t, cfg, aliceToken, body); rr.Code != http.StatusNoContent {
+		t.Fatalf("mark read returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	resp := getNotifications(t, cfg, aliceToken, "")
+	if resp.UnreadCount != 2 {
+		t.Errorf("unread_count = %d, want 2", resp.UnreadCount)
+	}
+	for _, n := range resp.Notifications {
+		if n.Read != (n.ID == page.Notifications[0].ID) {
+			t.Errorf("notification %s read = %v", n.ID, n.Read)
+		}
+	}
+
+	if rr := markRead(t, cfg, aliceToken, ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("mark all read returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	if resp := getNotifications(t, cfg, aliceToken, ""); resp.UnreadCount != 0 {
+		t.Errorf("unread_count = %d, want 0", resp.UnreadCount)
+	}
+
+	if rr := markRead(t, cfg, aliceToken, "not json"); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid body returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
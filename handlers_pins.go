@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerPinChirp pins one of the caller's chirps to their profile,
+// replacing any chirp pinned before.
+func (cfg *apiConfig) handlerPinChirp(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, chirp, ok := cfg.chirpActionRequest(w, r)
+	if !ok {
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, r, http.StatusForbidden, "You can only pin your own chirps")
+		return
+	}
+
+	pinned, err := cfg.dbQueries.SetUserPinnedChirp(r.Context(), database.SetUserPinnedChirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		respondWithReferenceError(w, r, err, "Chirp not found")
+		return
+	}
+	if pinned == 0 {
+		// Deleted since it was looked up
+		respondWithError(w, r, http.StatusNotFound, "Chirp not found")
+		return
+	}
+	cfg.chirpCache.invalidate()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerUnpinChirp removes the pin from one of the caller's chirps. Unpinning
+// a chirp that is not pinned is a no-op.
+func (cfg *apiConfig) handlerUnpinChirp(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, chirp, ok := cfg.chirpActionRequest(w, r)
+	if !ok {
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, r, http.StatusForbidden, "You can only unpin your own chirps")
+		return
+	}
+
+	err := cfg.dbQueries.UnpinChirp(r.Context(), uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	cfg.chirpCache.invalidate()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pinnedChirp returns the chirp dbUser has pinned, or nil if there is none.
+func (cfg *apiConfig) pinnedChirp(r *http.Request, dbUser database.User) (*Chirp, error) {
+	if !dbUser.PinnedChirpID.Valid {
+		return nil, nil
+	}
+
+	row, err := cfg.dbQueries.GetChirpByID(r.Context(), dbUser.PinnedChirpID.UUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	chirps := []Chirp{chirpFromRow(database.GetChirpsRow(row))}
+	err = cfg.populateChirps(r, chirps)
+	if err != nil {
+		return nil, err
+	}
+	return &chirps[0], nil
+}
+
+// pinnedChirpFirst moves authorID's pinned chirp to the front of chirps if
+// it is among them. chirps may be shared with the cache, so the result is a
+// new slice whenever the order changes.
+func (cfg *apiConfig) pinnedChirpFirst(ctx context.Context, authorID uuid.UUID, chirps []Chirp) ([]Chirp, error) {
+	author, err := cfg.dbQueries.GetUserByID(ctx, authorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return chirps, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !author.PinnedChirpID.Valid {
+		return chirps, nil
+	}
+
+	for i, chirp := range chirps {
+		if chirp.ID == author.PinnedChirpID.UUID {
+			reordered := make([]Chirp, 0, len(chirps))
+			reordered = append(reordered, chirp)
+			reordered = append(reordered, chirps[:i]...)
+			return append(reordered, chirps[i+1:]...), nil
+		}
+	}
+	return chirps, nil
+}
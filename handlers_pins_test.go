@@ -0,0 +1,226 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func pinChirp(t *testing.T, cfg *apiConfig, method string, chirpID uuid.UUID, token string) int {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/chirps/"+chirpID.String()+"/pin", nil)
+	req.SetPathValue("chirpID", chirpID.String())
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	if method == http.MethodPost {
+		cfg.handlerPinChirp(rr, req)
+	} else {
+		cfg.handlerUnpinChirp(rr, req)
+	}
+	return rr.Code
+}
+
+// pinnedID returns the ID of the chirp on userID's profile, or uuid.Nil.
+func pinnedID(t *testing.T, cfg *apiConfig, userID uuid.UUID) uuid.UUID {
+	t.Helper()
+	profile, code := getProfile(t, cfg, userID.String())
+	if code != http.StatusOK {
+		t.Fatalf("profile returned wrong status code: got %v want %v", code, http.StatusOK)
+	}
+	if profile.PinnedChirp == nil {
+		return uuid.Nil
+	}
+	return profile.PinnedChirp.ID
+}
+
+func TestPinChirp(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	author := db.addUser(t, "author@example.com")
+	token := makeTestToken(t, author.ID)
+	first := db.addChirp(t, author.ID, "First")
+	second := db.addChirp(t, author.ID, "Second")
+
+	if got := pinnedID(t, cfg, author.ID); got != uuid.Nil {
+		t.Fatalf("pinned chirp = %v before pinning, want none", got)
+	}
+
+	if code := pinChirp(t, cfg, http.MethodPost, first.ID, token); code != http.StatusNoContent {
+		t.Fatalf("pin returned wrong status code: got %v want %v", code, http.StatusNoContent)
+	}
+	profile, _ := getProfile(t, cfg, author.ID.String())
+	if profile.PinnedChirp == nil || profile.PinnedChirp.ID != first.ID || profile.PinnedChirp.Body != "First" {
+		t.Fatalf("pinned chirp = %+v, want the first chirp", profile.PinnedChirp)
+	}
+
+	// Pinning another chirp replaces the pin
+	pinChirp(t, cfg, http.MethodPost, second.ID, token)
+	if got := pinnedID(t, cfg, author.ID); got != second.ID {
+		t.Errorf("pinned chirp = %v, want %v after re-pinning", got, second.ID)
+	}
+
+	// Unpinning a chirp that is not pinned leaves the pin alone
+	if code := pinChirp(t, cfg, http.MethodDelete, first.ID, token); code != http.StatusNoContent {
+		t.Fatalf("unpin returned wrong status code: got %v want %v", code, http.StatusNoContent)
+	}
+	if got := pinnedID(t, cfg, author.ID); got != second.ID {
+		t.Errorf("pinned chirp = %v, want %v after unpinning another chirp", got, second.ID)
+	}
+
+	pinChirp(t, cfg, http.MethodDelete, second.ID, token)
+	if got := pinnedID(t, cfg, author.ID); got != uuid.Nil {
+		t.Errorf("pinned chirp = %v after unpinning, want none", got)
+	}
+}
+
+func TestPinChirpErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	author := db.addUser(t, "author@example.com")
+	other := db.addUser(t, "other@example.com")
+	chirp := db.addChirp(t, author.ID, "Mine")
+	deleted := db.addChirp(t, author.ID, "Gone")
+	if err := db.DeleteChirp(t.Context(), deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		chirpID uuid.UUID
+		userID  uuid.UUID
+		want    int
+	}{
+		{"someone else's chirp", chirp.ID, other.ID, http.StatusForbidden},
+		{"deleted chirp", deleted.ID, author.ID, http.StatusNotFound},
+		{"unknown chirp", uuid.New(), author.ID, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		for _, method := range []string{http.MethodPost, http.MethodDelete} {
+			if code := pinChirp(t, cfg, method, tt.chirpID, makeTestToken(t, tt.userID)); code != tt.want {
+				t.Errorf("%s %s: got status %v want %v", method, tt.name, code, tt.want)
+			}
+		}
+	}
+	if got := pinnedID(t, cfg, other.ID); got != uuid.Nil {
+		t.Errorf("other user pinned %v, want nothing", got)
+	}
+}
+
+func TestDeletingPinnedChirpClearsPin(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	author := db.addUser(t, "author@example.com")
+	token := makeTestToken(t, author.ID)
+	chirp := db.addChirp(t, author.ID, "Pinned, then deleted")
+	pinChirp(t, cfg, http.MethodPost, chirp.ID, token)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/chirps/"+chirp.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	req.SetPathValue("chirpID", chirp.ID.String())
+	cfg.handlerDeleteChirp(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("delete returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+
+	if got := pinnedID(t, cfg, author.ID); got != uuid.Nil {
+		t.Errorf("pinned chirp = %v after deleting it, want none", got)
+	}
+	if user, _ := db.GetUserByID(t.Context(), author.ID); user.PinnedChirpID.Valid {
+		t.Errorf("pinned_chirp_id = %v after delete, want NULL", user.PinnedChirpID.UUID)
+	}
+}
+
+func TestPinStore(t *testing.T) {
+	_, db := newTestConfig(t)
+	author := db.addUser(t, "author@example.com")
+	other := db.addUser(t, "other@example.com")
+	chirp := db.addChirp(t, author.ID, "Hello")
+
+	pin := func(userID, chirpID uuid.UUID) int64 {
+		t.Helper()
+		n, err := db.SetUserPinnedChirp(t.Context(), database.SetUserPinnedChirpParams{UserID: userID, ChirpID: chirpID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	pinned := func(userID uuid.UUID) uuid.NullUUID {
+		t.Helper()
+		user, err := db.GetUserByID(t.Context(), userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user.PinnedChirpID
+	}
+
+	if n := pin(other.ID, chirp.ID); n != 0 || pinned(other.ID).Valid {
+		t.Errorf("pinning someone else's chirp updated %d rows", n)
+	}
+	if n := pin(author.ID, chirp.ID); n != 1 || pinned(author.ID).UUID != chirp.ID {
+		t.Fatalf("pinning own chirp updated %d rows, pin = %v", n, pinned(author.ID))
+	}
+
+	if err := db.UnpinChirp(t.Context(), uuid.NullUUID{UUID: chirp.ID, Valid: true}); err != nil {
+		t.Fatal(err)
+	}
+	if pinned(author.ID).Valid {
+		t.Error("UnpinChirp left the pin in place")
+	}
+
+	pin(author.ID, chirp.ID)
+	if err := db.DeleteChirp(t.Context(), chirp.ID); err != nil {
+		t.Fatal(err)
+	}
+	if pinned(author.ID).Valid {
+		t.Error("DeleteChirp left the pin in place")
+	}
+	if n := pin(author.ID, chirp.ID); n != 0 {
+		t.Errorf("pinning a deleted chirp updated %d rows", n)
+	}
+}
+
+func TestGetChirpsPinnedFirst(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	author := db.addUser(t, "author@example.com")
+	other := db.addUser(t, "other@example.com")
+	first := db.addChirp(t, author.ID, "First")
+	second := db.addChirp(t, author.ID, "Second")
+	third := db.addChirp(t, author.ID, "Third")
+	db.addChirp(t, other.ID, "Not the author")
+	pinChirp(t, cfg, http.MethodPost, second.ID, makeTestToken(t, author.ID))
+
+	getChirps := func(query string) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		cfg.handlerGetChirps(rr, httptest.NewRequest(http.MethodGet, "/api/chirps?"+query, nil))
+		return rr
+	}
+	ids := func(query string) []uuid.UUID {
+		t.Helper()
+		return decodeChirpIDs(t, getChirps(query))
+	}
+
+	authorQuery := "author_id=" + author.ID.String()
+	if got, want := ids(authorQuery), []uuid.UUID{third.ID, second.ID, first.ID}; !slices.Equal(got, want) {
+		t.Errorf("without pinned_first = %v, want %v", got, want)
+	}
+	if got, want := ids(authorQuery+"&pinned_first=true"), []uuid.UUID{second.ID, third.ID, first.ID}; !slices.Equal(got, want) {
+		t.Errorf("pinned_first = %v, want %v", got, want)
+	}
+	if got, want := ids(authorQuery+"&pinned_first=true&sort=asc"), []uuid.UUID{second.ID, first.ID, third.ID}; !slices.Equal(got, want) {
+		t.Errorf("pinned_first ascending = %v, want %v", got, want)
+	}
+
+	// The cached listing must not keep the pinned order
+	if got, want := ids(authorQuery), []uuid.UUID{third.ID, second.ID, first.ID}; !slices.Equal(got, want) {
+		t.Errorf("without pinned_first after a pinned request = %v, want %v", got, want)
+	}
+
+	for _, query := range []string{"pinned_first=true", authorQuery + "&pinned_first=maybe"} {
+		if rr := getChirps(query); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %v want %v", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerSetPrivacy makes the caller's account private or public. An
+// account going public has no one left to approve, so its pending follow
+// requests become follows.
+func (cfg *apiConfig) handlerSetPrivacy(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		IsPrivate *bool `json:"is_private"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	reqBody := requestBody{}
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+	if reqBody.IsPrivate == nil {
+		respondWithError(w, r, http.StatusBadRequest, "is_private is required")
+		return
+	}
+
+	if !*reqBody.IsPrivate {
+		err = cfg.dbQueries.ApproveAllFollowRequests(r.Context(), userID)
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+	}
+
+	dbUser, err := cfg.dbQueries.SetUserPrivate(r.Context(), database.SetUserPrivateParams{
+		ID:        userID,
+		IsPrivate: *reqBody.IsPrivate,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	cfg.chirpCache.invalidate()
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(userFromDB(dbUser))
+}
+
+// handlerGetFollowRequests lists the pending requests to follow the caller,
+// oldest first.
+func (cfg *apiConfig) handlerGetFollowRequests(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dbRequests, err := cfg.dbQueries.GetFollowRequests(r.Context(), database.GetFollowRequestsParams{
+		TargetID: userID,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	requests := make([]FollowRequest, len(dbRequests))
+	for i, dbRequest := range dbRequests {
+		requests[i] = FollowRequest{
+			Requester: PublicUser{
+				ID:          dbRequest.ID,
+				Email:       cfg.publicEmail(dbRequest.Email),
+				Username:    dbRequest.Username,
+				DisplayName: nullableString(dbRequest.DisplayName),
+				Bio:         nullableString(dbRequest.Bio),
+				AvatarURL:   nullableString(dbRequest.AvatarUrl),
+				IsChirpyRed: dbRequest.IsChirpyRed,
+			},
+			RequestedAt: dbRequest.RequestedAt,
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(requests)
+}
+
+// handlerApproveFollowRequest turns a pending request to follow the caller
+// into a follow.
+func (cfg *apiConfig) handlerApproveFollowRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	targetID, requesterID, ok := cfg.followRequestAction(w, r)
+	if !ok {
+		return
+	}
+
+	approved, err := cfg.dbQueries.ApproveFollowRequest(r.Context(), database.ApproveFollowRequestParams{
+		RequesterID: requesterID,
+		TargetID:    targetID,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if approved == 0 {
+		respondWithError(w, r, http.StatusNotFound, "Follow request not found")
+		return
+	}
+	// The requester now sees the caller's chirps in lists
+	cfg.chirpCache.invalidate()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerDenyFollowRequest drops a pending request to follow the caller.
+// The requester is not told, and may ask again.
+func (cfg *apiConfig) handlerDenyFollowRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	targetID, requesterID, ok := cfg.followRequestAction(w, r)
+	if !ok {
+		return
+	}
+
+	denied, err := cfg.dbQueries.DeleteFollowRequest(r.Context(), database.DeleteFollowRequestParams{
+		RequesterID: requesterID,
+		TargetID:    targetID,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if denied == 0 {
+		respondWithError(w, r, http.StatusNotFound, "Follow request not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// followRequestAction authenticates the caller and parses the ID of the
+// user whose request to follow them is being answered, writing the error
+// response itself when either step fails.
+func (cfg *apiConfig) followRequestAction(w http.ResponseWriter, r *http.Request) (targetID, requesterID uuid.UUID, ok bool) {
+	targetID, ok = cfg.requireUser(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	requesterID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return targetID, requesterID, true
+}
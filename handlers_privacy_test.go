@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// privateAccountFixture is a private account with an approved follower and
+// a pending follow request, set up through the API.
+type privateAccountFixture struct {
+	srv   *httptest.Server
+	db    *fakeStore
+	owner database.User
+	// chirp is the owner's, tagged #secret; reply is the owner's reply to
+	// parent, a public user's chirp
+	chirp  Chirp
+	reply  Chirp
+	parent Chirp
+	// Tokens of the owner, the approved follower, the user whose request
+	// is pending and a user who never asked
+	ownerToken, followerToken, pendingToken, strangerToken string
+}
+
+func newPrivateAccountFixture(t *testing.T) privateAccountFixture {
+	t.Helper()
+	cfg, db := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+
+	owner := db.addUser(t, "owner@example.com")
+	follower := db.addUser(t, "follower@example.com")
+	publicUser := db.addUser(t, "public@example.com")
+	fx := privateAccountFixture{
+		srv:           srv,
+		db:            db,
+		ownerToken:    makeTestToken(t, owner.ID),
+		followerToken: makeTestToken(t, follower.ID),
+		pendingToken:  makeTestToken(t, db.addUser(t, "pending@example.com").ID),
+		strangerToken: makeTestToken(t, db.addUser(t, "stranger@example.com").ID),
+	}
+
+	var err error
+	fx.owner, err = db.UpdateUser(context.Background(), database.UpdateUserParams{
+		ID:          owner.ID,
+		DisplayName: sql.NullString{String: "Owner", Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp := call(t, srv, http.MethodPut, "/api/users/me/privacy", fx.ownerToken, map[string]bool{"is_private": true}, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("going private: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+
+	followPath := "/api/users/" + owner.ID.String() + "/follow"
+	for _, token := range []string{fx.followerToken, fx.pendingToken} {
+		if resp := call(t, srv, http.MethodPost, followPath, token, nil, nil); resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("follow request: got status %v want %v", resp.StatusCode, http.StatusAccepted)
+		}
+	}
+	if resp := call(t, srv, http.MethodPost, "/api/follow_requests/"+follower.ID.String()+"/approve", fx.ownerToken, nil, nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("approve: got status %v want %v", resp.StatusCode, http.StatusNoContent)
+	}
+
+	publicToken := makeTestToken(t, publicUser.ID)
+	if resp := call(t, srv, http.MethodPost, "/api/chirps", publicToken, map[string]any{"body": "open to all"}, &fx.parent); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("public chirp: got status %v want %v", resp.StatusCode, http.StatusCreated)
+	}
+	if resp := call(t, srv, http.MethodPost, "/api/chirps", fx.ownerToken, map[string]any{"body": "just us #secret"}, &fx.chirp); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("private chirp: got status %v want %v", resp.StatusCode, http.StatusCreated)
+	}
+	if resp := call(t, srv, http.MethodPost, "/api/chirps", fx.ownerToken, map[string]any{"body": "quiet reply", "parent_chirp_id": fx.parent.ID}, &fx.reply); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("private reply: got status %v want %v", resp.StatusCode, http.StatusCreated)
+	}
+	return fx
+}
+
+func containsChirp(chirps []Chirp, id uuid.UUID) bool {
+	return slices.ContainsFunc(chirps, func(c Chirp) bool { return c.ID == id })
+}
+
+func TestPrivateAccountVisibility(t *testing.T) {
+	fx := newPrivateAccountFixture(t)
+	ownerID := fx.owner.ID.String()
+	chirpID := fx.chirp.ID.String()
+
+	viewers := []struct {
+		name  string
+		token string
+		sees  bool
+	}{
+		{"owner", fx.ownerToken, true},
+		{"approved follower", fx.followerToken, true},
+		{"pending requester", fx.pendingToken, false},
+		{"stranger", fx.strangerToken, false},
+		{"anonymous", "", false},
+	}
+
+	for _, v := range viewers {
+		t.Run(v.name, func(t *testing.T) {
+			var chirps []Chirp
+			call(t, fx.srv, http.MethodGet, "/api/chirps", v.token, nil, &chirps)
+			if got := containsChirp(chirps, fx.chirp.ID); got != v.sees {
+				t.Errorf("GET /api/chirps includes the private chirp = %v, want %v", got, v.sees)
+			}
+			if !containsChirp(chirps, fx.parent.ID) {
+				t.Error("GET /api/chirps is missing the public chirp")
+			}
+
+			chirps = nil
+			call(t, fx.srv, http.MethodGet, "/api/chirps?author_id="+ownerID+"&include=author", v.token, nil, &chirps)
+			if got := containsChirp(chirps, fx.chirp.ID); got != v.sees {
+				t.Errorf("GET /api/chirps?author_id includes the private chirp = %v, want %v", got, v.sees)
+			}
+
+			chirps = nil
+			call(t, fx.srv, http.MethodGet, "/api/hashtags/secret/chirps", v.token, nil, &chirps)
+			if got := containsChirp(chirps, fx.chirp.ID); got != v.sees {
+				t.Errorf("hashtag listing includes the private chirp = %v, want %v", got, v.sees)
+			}
+
+			chirps = nil
+			call(t, fx.srv, http.MethodGet, "/api/chirps/"+fx.parent.ID.String()+"/replies", v.token, nil, &chirps)
+			if got := containsChirp(chirps, fx.reply.ID); got != v.sees {
+				t.Errorf("replies include the private reply = %v, want %v", got, v.sees)
+			}
+
+			wantFound := http.StatusNotFound
+			if v.sees {
+				wantFound = http.StatusOK
+			}
+			for _, path := range []string{"/api/chirps/" + chirpID, "/api/chirps/" + chirpID + "?include=author", "/api/chirps/" + chirpID + "/replies", "/api/chirps/" + chirpID + "/likes"} {
+				if resp := call(t, fx.srv, http.MethodGet, path, v.token, nil, nil); resp.StatusCode != wantFound {
+					t.Errorf("GET %s: got status %v want %v", path, resp.StatusCode, wantFound)
+				}
+			}
+			for _, path := range []string{"/api/users/" + ownerID + "/stats", "/api/users/" + ownerID + "/followers", "/api/users/" + ownerID + "/following"} {
+				if v.sees {
+					if resp := call(t, fx.srv, http.MethodGet, path, v.token, nil, nil); resp.StatusCode != http.StatusOK {
+						t.Errorf("GET %s: got status %v want %v", path, resp.StatusCode, http.StatusOK)
+					}
+					continue
+				}
+				var errResp ErrorResponse
+				if resp := call(t, fx.srv, http.MethodGet, path, v.token, nil, &errResp); resp.StatusCode != http.StatusForbidden || errResp.Code != errCodePrivateAccount {
+					t.Errorf("GET %s: got status %v and code %q, want %v and %q", path, resp.StatusCode, errResp.Code, http.StatusForbidden, errCodePrivateAccount)
+				}
+			}
+
+			var profile UserProfile
+			if resp := call(t, fx.srv, http.MethodGet, "/api/users/"+ownerID, v.token, nil, &profile); resp.StatusCode != http.StatusOK {
+				t.Fatalf("profile: got status %v want %v", resp.StatusCode, http.StatusOK)
+			}
+			if !profile.IsPrivate || profile.Username != fx.owner.Username || profile.FollowerCount != 1 {
+				t.Errorf("profile = %+v, want the private owner's username and one follower", profile)
+			}
+			if got := profile.DisplayName != nil; got != v.sees {
+				t.Errorf("profile shows the display name = %v, want %v", got, v.sees)
+			}
+
+			if v.token != "" {
+				want := http.StatusNotFound
+				if v.sees {
+					want = http.StatusNoContent
+				}
+				for _, action := range []string{"/like", "/bookmark"} {
+					if resp := call(t, fx.srv, http.MethodPost, "/api/chirps/"+chirpID+action, v.token, nil, nil); resp.StatusCode != want {
+						t.Errorf("POST %s: got status %v want %v", action, resp.StatusCode, want)
+					}
+				}
+
+				// Replying to a chirp the caller cannot see is a bad parent
+				want = http.StatusBadRequest
+				if v.sees {
+					want = http.StatusCreated
+				}
+				if resp := call(t, fx.srv, http.MethodPost, "/api/chirps", v.token, map[string]any{"body": "hi " + v.name, "parent_chirp_id": fx.chirp.ID}, nil); resp.StatusCode != want {
+					t.Errorf("reply: got status %v want %v", resp.StatusCode, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFollowRequestFlow(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	owner := db.addUser(t, "owner@example.com")
+	requester := db.addUser(t, "requester@example.com")
+	ownerToken := makeTestToken(t, owner.ID)
+	requesterToken := makeTestToken(t, requester.ID)
+	followPath := "/api/users/" + owner.ID.String() + "/follow"
+	approvePath := "/api/follow_requests/" + requester.ID.String() + "/approve"
+	denyPath := "/api/follow_requests/" + requester.ID.String() + "/deny"
+
+	var user User
+	call(t, srv, http.MethodPut, "/api/users/me/privacy", ownerToken, map[string]bool{"is_private": true}, &user)
+	if !user.IsPrivate {
+		t.Fatal("is_private was not set")
+	}
+
+	// Asking twice leaves one request and one notification
+	for i := 0; i < 2; i++ {
+		if resp := call(t, srv, http.MethodPost, followPath, requesterToken, nil, nil); resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("follow #%d: got status %v want %v", i+1, resp.StatusCode, http.StatusAccepted)
+		}
+	}
+	var requests []FollowRequest
+	call(t, srv, http.MethodGet, "/api/follow_requests", ownerToken, nil, &requests)
+	if len(requests) != 1 || requests[0].Requester.ID != requester.ID {
+		t.Fatalf("follow requests = %+v, want one from the requester", requests)
+	}
+	notifications := getNotifications(t, cfg, ownerToken, "")
+	if len(notifications.Notifications) != 1 || notifications.Notifications[0].Type != notificationFollowRequest {
+		t.Errorf("notifications = %+v, want one follow_request", notifications.Notifications)
+	}
+	if len(db.follows) != 0 {
+		t.Fatalf("a follow exists before approval: %+v", db.follows)
+	}
+
+	// Denied requests are gone and can be made again
+	if resp := call(t, srv, http.MethodPost, denyPath, ownerToken, nil, nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("deny: got status %v want %v", resp.StatusCode, http.StatusNoContent)
+	}
+	if resp := call(t, srv, http.MethodPost, denyPath, ownerToken, nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("second deny: got status %v want %v", resp.StatusCode, http.StatusNotFound)
+	}
+	if resp := call(t, srv, http.MethodPost, approvePath, ownerToken, nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("approving a denied request: got status %v want %v", resp.StatusCode, http.StatusNotFound)
+	}
+
+	// Unfollowing withdraws a pending request
+	call(t, srv, http.MethodPost, followPath, requesterToken, nil, nil)
+	if resp := call(t, srv, http.MethodDelete, followPath, requesterToken, nil, nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unfollow: got status %v want %v", resp.StatusCode, http.StatusNoContent)
+	}
+	requests = nil
+	call(t, srv, http.MethodGet, "/api/follow_requests", ownerToken, nil, &requests)
+	if len(requests) != 0 {
+		t.Errorf("follow requests after withdrawing = %+v, want none", requests)
+	}
+
+	// Approval makes a follow; following again is a plain no-op
+	call(t, srv, http.MethodPost, followPath, requesterToken, nil, nil)
+	if resp := call(t, srv, http.MethodPost, approvePath, ownerToken, nil, nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("approve: got status %v want %v", resp.StatusCode, http.StatusNoContent)
+	}
+	if resp := call(t, srv, http.MethodPost, approvePath, ownerToken, nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("second approve: got status %v want %v", resp.StatusCode, http.StatusNotFound)
+	}
+	if resp := call(t, srv, http.MethodPost, followPath, requesterToken, nil, nil); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("following once approved: got status %v want %v", resp.StatusCode, http.StatusNoContent)
+	}
+	var followers []PublicUser
+	call(t, srv, http.MethodGet, "/api/users/"+owner.ID.String()+"/followers", ownerToken, nil, &followers)
+	if len(followers) != 1 || followers[0].ID != requester.ID {
+		t.Errorf("followers = %+v, want the requester", followers)
+	}
+
+	// Requests only answer to the account they were made to
+	if resp := call(t, srv, http.MethodPost, approvePath, requesterToken, nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("approving someone else's request: got status %v want %v", resp.StatusCode, http.StatusNotFound)
+	}
+	if resp := call(t, srv, http.MethodPost, "/api/follow_requests/not-a-uuid/approve", ownerToken, nil, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid user ID: got status %v want %v", resp.StatusCode, http.StatusBadRequest)
+	}
+	if resp := call(t, srv, http.MethodGet, "/api/follow_requests", "", nil, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("anonymous listing: got status %v want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestSetPrivacyPublicApprovesPending(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	owner := db.addUser(t, "owner@example.com")
+	ownerToken := makeTestToken(t, owner.ID)
+	followPath := "/api/users/" + owner.ID.String() + "/follow"
+
+	call(t, srv, http.MethodPut, "/api/users/me/privacy", ownerToken, map[string]bool{"is_private": true}, nil)
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		call(t, srv, http.MethodPost, followPath, makeTestToken(t, db.addUser(t, email).ID), nil, nil)
+	}
+
+	var user User
+	if resp := call(t, srv, http.MethodPut, "/api/users/me/privacy", ownerToken, map[string]bool{"is_private": false}, &user); resp.StatusCode != http.StatusOK {
+		t.Fatalf("going public: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if user.IsPrivate {
+		t.Error("is_private is still set")
+	}
+	var requests []FollowRequest
+	call(t, srv, http.MethodGet, "/api/follow_requests", ownerToken, nil, &requests)
+	if len(requests) != 0 {
+		t.Errorf("follow requests = %+v, want none", requests)
+	}
+	if profile, _ := getProfile(t, cfg, owner.ID.String()); profile.FollowerCount != 2 {
+		t.Errorf("follower_count = %d, want 2", profile.FollowerCount)
+	}
+
+	// Public accounts are followed straight away
+	if resp := call(t, srv, http.MethodPost, followPath, makeTestToken(t, db.addUser(t, "c@example.com").ID), nil, nil); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("following a public account: got status %v want %v", resp.StatusCode, http.StatusNoContent)
+	}
+
+	for _, body := range []any{map[string]any{}, "not an object"} {
+		if resp := call(t, srv, http.MethodPut, "/api/users/me/privacy", ownerToken, body, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("body %v: got status %v want %v", body, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+	if resp := call(t, srv, http.MethodPut, "/api/users/me/privacy", "", map[string]bool{"is_private": true}, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("anonymous: got status %v want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestPrivateBookmarksNeedFollow(t *testing.T) {
+	fx := newPrivateAccountFixture(t)
+	bookmarkPath := "/api/chirps/" + fx.chirp.ID.String() + "/bookmark"
+	if resp := call(t, fx.srv, http.MethodPost, bookmarkPath, fx.followerToken, nil, nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("bookmark: got status %v want %v", resp.StatusCode, http.StatusNoContent)
+	}
+
+	var chirps []Chirp
+	call(t, fx.srv, http.MethodGet, "/api/bookmarks", fx.followerToken, nil, &chirps)
+	if !containsChirp(chirps, fx.chirp.ID) {
+		t.Fatal("bookmarks are missing the private chirp while following")
+	}
+
+	// Once unfollowed the bookmark stays but is hidden
+	call(t, fx.srv, http.MethodDelete, "/api/users/"+fx.owner.ID.String()+"/follow", fx.followerToken, nil, nil)
+	chirps = nil
+	call(t, fx.srv, http.MethodGet, "/api/bookmarks", fx.followerToken, nil, &chirps)
+	if containsChirp(chirps, fx.chirp.ID) {
+		t.Error("bookmarks still include the private chirp after unfollowing")
+	}
+	if len(fx.db.bookmarks) != 1 {
+		t.Errorf("got %d stored bookmarks, want 1", len(fx.db.bookmarks))
+	}
+}
+
+func TestPrivateChirpsStayOffStreams(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	owner := db.addUser(t, "owner@example.com")
+	if _, err := db.SetUserPrivate(context.Background(), database.SetUserPrivateParams{ID: owner.ID, IsPrivate: true}); err != nil {
+		t.Fatal(err)
+	}
+	sub, _ := cfg.chirpHub.subscribe(uuid.NullUUID{})
+	defer cfg.chirpHub.unsubscribe(sub)
+
+	cfg.announceChirp(context.Background(), db.addChirp(t, owner.ID, "not for streams"), uuid.Nil)
+	select {
+	case chirp := <-sub.C:
+		t.Errorf("private chirp %q was published", chirp.Body)
+	default:
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+)
+
+// handlerPurgeChirps permanently removes every chirp, deleted or not,
+// created more than older_than ago. It deletes purgeBatchSize chirps per
+// statement until none are left, so a large purge never holds one
+// transaction open for long; a purge that fails part way keeps the batches
+// already done. With dry_run it only counts the chirps it would remove.
+func (cfg *apiConfig) handlerPurgeChirps(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		OlderThan string `json:"older_than"`
+		DryRun    bool   `json:"dry_run"`
+	}
+
+	adminID, ok := cfg.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	reqBody := requestBody{}
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+
+	var invalid fieldErrors
+	olderThan, err := time.ParseDuration(reqBody.OlderThan)
+	switch {
+	case reqBody.OlderThan == "":
+		invalid.add("older_than", "older_than", "is required")
+	case err != nil:
+		invalid.add("older_than", "older_than", "must be a duration such as 720h")
+	case olderThan <= 0:
+		invalid.add("older_than", "older_than", "must be positive")
+	}
+	if invalid.respond(w, r) {
+		return
+	}
+
+	resp := PurgeChirpsResponse{
+		Cutoff: time.Now().UTC().Add(-olderThan),
+		DryRun: reqBody.DryRun,
+	}
+
+	if reqBody.DryRun {
+		resp.Deleted, err = cfg.dbQueries.CountChirpsBefore(r.Context(), resp.Cutoff)
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	for {
+		var deleted int64
+		deleted, err = cfg.dbQueries.PurgeChirpsBefore(r.Context(), database.PurgeChirpsBeforeParams{
+			Before: resp.Cutoff,
+			Limit:  int32(cfg.purgeBatchSize),
+		})
+		if err != nil {
+			logf(r.Context(), "Error purging chirps before %v after %d batches: %v", resp.Cutoff, resp.Batches, err)
+			break
+		}
+		resp.Batches++
+		resp.Deleted += deleted
+		if deleted < int64(cfg.purgeBatchSize) {
+			break
+		}
+	}
+	if resp.Deleted > 0 {
+		cfg.chirpCache.invalidate()
+		cfg.audit(r, auditEntry{ActorID: adminID, Action: auditChirpPurge, Target: resp.Cutoff.Format(time.RFC3339)})
+	}
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// backdateChirps sets each chirp's created_at to the given age before now.
+func backdateChirps(db *fakeStore, ages map[uuid.UUID]time.Duration) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	now := time.Now().UTC()
+	for i, c := range db.chirps {
+		if age, ok := ages[c.ID]; ok {
+			db.chirps[i].CreatedAt = now.Add(-age)
+		}
+	}
+}
+
+func chirpIDs(db *fakeStore) []uuid.UUID {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	ids := make([]uuid.UUID, len(db.chirps))
+	for i, c := range db.chirps {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+func TestPurgeChirpsCutoff(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	admin := db.addAdmin(t, "admin@example.com")
+	token := makeTestToken(t, admin.ID)
+	user := db.addUser(t, "user@example.com")
+
+	old := db.addChirp(t, user.ID, "Old")
+	justOlder := db.addChirp(t, user.ID, "Just past the cutoff")
+	justNewer := db.addChirp(t, user.ID, "Just inside the cutoff")
+	deleted := db.addChirp(t, user.ID, "Old and already deleted")
+	if err := db.DeleteChirp(context.Background(), deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := db.CreateChirp(context.Background(), database.CreateChirpParams{
+		Body:          "A recent reply to an old chirp",
+		UserID:        admin.ID,
+		ParentChirpID: uuid.NullUUID{UUID: old.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.LikeChirp(context.Background(), database.LikeChirpParams{ChirpID: old.ID, UserID: admin.ID}); err != nil {
+		t.Fatal(err)
+	}
+	backdateChirps(db, map[uuid.UUID]time.Duration{
+		old.ID:       1000 * time.Hour,
+		justOlder.ID: 720*time.Hour + time.Minute,
+		justNewer.ID: 720*time.Hour - time.Minute,
+		deleted.ID:   800 * time.Hour,
+	})
+
+	body := map[string]any{"older_than": "720h", "dry_run": true}
+	var dryRun PurgeChirpsResponse
+	if resp := call(t, srv, http.MethodPost, "/admin/chirps/purge", token, body, &dryRun); resp.StatusCode != http.StatusOK {
+		t.Fatalf("dry run: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if !dryRun.DryRun || dryRun.Deleted != 3 || dryRun.Batches != 0 {
+		t.Errorf("dry run = %+v, want 3 chirps counted and no batches", dryRun)
+	}
+	if got := len(chirpIDs(db)); got != 5 {
+		t.Fatalf("a dry run left %d of 5 chirps", got)
+	}
+
+	body["dry_run"] = false
+	var purge PurgeChirpsResponse
+	if resp := call(t, srv, http.MethodPost, "/admin/chirps/purge", token, body, &purge); resp.StatusCode != http.StatusOK {
+		t.Fatalf("purge: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if purge.DryRun || purge.Deleted != 3 || purge.Batches != 1 {
+		t.Errorf("purge = %+v, want 3 chirps deleted in 1 batch", purge)
+	}
+	if want := time.Now().Add(-720 * time.Hour); purge.Cutoff.Sub(want).Abs() > time.Minute/2 {
+		t.Errorf("cutoff = %v, want about %v", purge.Cutoff, want)
+	}
+	if ids := chirpIDs(db); !slices.Equal(ids, []uuid.UUID{justNewer.ID, reply.ID}) {
+		t.Errorf("chirps left = %v, want only the one inside the cutoff and the reply", ids)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.likes) != 0 {
+		t.Errorf("%d likes of purged chirps are left", len(db.likes))
+	}
+	if db.chirps[1].ParentChirpID.Valid {
+		t.Error("the reply still points at its purged parent")
+	}
+	if !slices.ContainsFunc(db.auditLog, func(e database.AuditLog) bool { return e.Action == auditChirpPurge }) {
+		t.Error("the purge was not audited")
+	}
+}
+
+func TestPurgeChirpsBatches(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	admin := db.addAdmin(t, "admin@example.com")
+	token := makeTestToken(t, admin.ID)
+	cfg.purgeBatchSize = 2
+
+	tests := []struct {
+		old         int
+		wantBatches int
+	}{
+		{5, 3},
+		// A full last batch takes one more to find nothing is left
+		{4, 3},
+		{0, 1},
+	}
+	for _, tt := range tests {
+		ages := make(map[uuid.UUID]time.Duration)
+		for range tt.old {
+			ages[db.addChirp(t, admin.ID, "Old").ID] = 48 * time.Hour
+		}
+		backdateChirps(db, ages)
+		recent := db.addChirp(t, admin.ID, "Recent")
+
+		var purge PurgeChirpsResponse
+		if resp := call(t, srv, http.MethodPost, "/admin/chirps/purge", token, map[string]string{"older_than": "24h"}, &purge); resp.StatusCode != http.StatusOK {
+			t.Fatalf("%d old chirps: got status %v want %v", tt.old, resp.StatusCode, http.StatusOK)
+		}
+		if purge.Deleted != int64(tt.old) || purge.Batches != tt.wantBatches {
+			t.Errorf("%d old chirps: purge = %+v, want %d deleted in %d batches", tt.old, purge, tt.old, tt.wantBatches)
+		}
+		if ids := chirpIDs(db); !slices.Contains(ids, recent.ID) || len(ids) != 1 {
+			t.Errorf("%d old chirps: chirps left = %v, want only the recent one", tt.old, ids)
+		}
+		db.mu.Lock()
+		db.chirps = nil
+		db.mu.Unlock()
+	}
+}
+
+func TestPurgeChirpsErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	admin := db.addAdmin(t, "admin@example.com")
+	user := db.addUser(t, "user@example.com")
+	chirp := db.addChirp(t, user.ID, "Not going anywhere")
+	backdateChirps(db, map[uuid.UUID]time.Duration{chirp.ID: 10000 * time.Hour})
+	adminToken := makeTestToken(t, admin.ID)
+
+	tests := []struct {
+		name  string
+		token string
+		body  any
+		want  int
+	}{
+		{"not an admin", makeTestToken(t, user.ID), map[string]string{"older_than": "1h"}, http.StatusForbidden},
+		{"no body", adminToken, nil, http.StatusBadRequest},
+		{"days", adminToken, map[string]string{"older_than": "30d"}, http.StatusBadRequest},
+		{"negative", adminToken, map[string]string{"older_than": "-1h"}, http.StatusBadRequest},
+		{"zero", adminToken, map[string]string{"older_than": "0s"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if resp := call(t, srv, http.MethodPost, "/admin/chirps/purge", tt.token, tt.body, nil); resp.StatusCode != tt.want {
+			t.Errorf("%s: got status %v want %v", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+	if got := len(chirpIDs(db)); got != 1 {
+		t.Errorf("rejected purges left %d chirps, want 1", got)
+	}
+}
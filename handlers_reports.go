@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxReportReasonLength = 500
+
+// handlerReportChirp flags a chirp for moderation. The reason is optional;
+// each user may report a given chirp only once.
+func (cfg *apiConfig) handlerReportChirp(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Reason string `json:"reason"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, chirp, ok := cfg.chirpActionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	reqBody := requestBody{}
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+
+	if len(reqBody.Reason) > maxReportReasonLength {
+		respondWithError(w, r, http.StatusBadRequest, "Reason is too long")
+		return
+	}
+
+	if chirp.UserID == userID {
+		respondWithError(w, r, http.StatusBadRequest, "You cannot report your own chirp")
+		return
+	}
+
+	// The (chirp_id, reporter_id) key turns a second report into a no-op
+	created, err := cfg.dbQueries.CreateChirpReport(r.Context(), database.CreateChirpReportParams{
+		ChirpID:    chirp.ID,
+		ReporterID: userID,
+		Reason:     reqBody.Reason,
+	})
+	if err != nil {
+		respondWithReferenceError(w, r, err, "Chirp not found")
+		return
+	}
+	if created == 0 {
+		respondWithError(w, r, http.StatusConflict, "You have already reported this chirp")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerGetReports is the moderation queue: chirps with open reports,
+// most reported first.
+func (cfg *apiConfig) handlerGetReports(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireAdmin(w, r); !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetReportedChirps(r.Context(), database.GetReportedChirpsParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	reports := make([]ReportedChirp, len(rows))
+	for i, row := range rows {
+		reasons := row.Reasons
+		if reasons == nil {
+			reasons = []string{}
+		}
+		reports[i] = ReportedChirp{
+			Chirp:           chirpFromDB(row.Chirp),
+			ReportCount:     row.ReportCount,
+			Reasons:         reasons,
+			FirstReportedAt: row.FirstReportedAt,
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reports)
+}
+
+// handlerResolveReports closes the open reports on a chirp. The "dismiss"
+// action keeps the chirp; "delete" removes it as its author could.
+func (cfg *apiConfig) handlerResolveReports(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Action string `json:"action"`
+	}
+
+	adminID, ok := cfg.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+
+	reqBody := requestBody{}
+	err = json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+
+	if reqBody.Action != "dismiss" && reqBody.Action != "delete" {
+		respondWithError(w, r, http.StatusBadRequest, `Action must be "dismiss" or "delete"`)
+		return
+	}
+
+	resolved, err := cfg.dbQueries.ResolveChirpReports(r.Context(), chirpID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if resolved == 0 {
+		respondWithError(w, r, http.StatusNotFound, "No open reports for this chirp")
+		return
+	}
+
+	action := auditReportsDismiss
+	if reqBody.Action == "delete" {
+		err = cfg.dbQueries.DeleteChirp(r.Context(), chirpID)
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+		cfg.chirpCache.invalidate()
+		action = auditReportsDelete
+	}
+	cfg.audit(r, auditEntry{ActorID: adminID, Action: action, Target: chirpID.String()})
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// reportChirp reports chirpID as the holder of token. A nil payload sends
+// no body at all.
+func reportChirp(t *testing.T, cfg *apiConfig, chirpID, token string, payload any) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	if payload != nil {
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			t.Fatalf("Failed to marshal payload: %v", err)
+		}
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/chirps/"+chirpID+"/report", &body)
+	req.SetPathValue("chirpID", chirpID)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerReportChirp(rr, req)
+	return rr
+}
+
+// resolveReports applies action to the open reports on chirpID.
+func resolveReports(t *testing.T, cfg *apiConfig, chirpID, token, action string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"action": action})
+	req := httptest.NewRequest(http.MethodPost, "/admin/reports/"+chirpID+"/resolve", bytes.NewReader(body))
+	req.SetPathValue("chirpID", chirpID)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerResolveReports(rr, req)
+	return rr
+}
+
+// getReports returns the moderation queue.
+func getReports(t *testing.T, cfg *apiConfig, token string) []ReportedChirp {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/admin/reports", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerGetReports(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("reports returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var reports []ReportedChirp
+	if err := json.NewDecoder(rr.Body).Decode(&reports); err != nil {
+		t.Fatalf("Failed to decode reports: %v", err)
+	}
+	return reports
+}
+
+func TestChirpReportFlow(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	author := db.addUser(t, "author@example.com")
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	spam := db.addChirp(t, author.ID, "Buy now")
+	rude := db.addChirp(t, author.ID, "Something rude")
+	aliceToken := makeTestToken(t, alice.ID)
+	bobToken := makeTestToken(t, bob.ID)
+	adminToken := makeTestToken(t, db.addAdmin(t, "admin@example.com").ID)
+
+	for _, report := range []struct {
+		chirpID string
+		token   string
+		payload any
+	}{
+		{spam.ID.String(), aliceToken, map[string]any{"reason": "spam"}},
+		{spam.ID.String(), bobToken, nil},
+		{rude.ID.String(), aliceToken, map[string]any{"reason": "abuse"}},
+	} {
+		if rr := reportChirp(t, cfg, report.chirpID, report.token, report.payload); rr.Code != http.StatusNoContent {
+			t.Fatalf("report returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+		}
+	}
+
+	reports := getReports(t, cfg, adminToken)
+	if len(reports) != 2 {
+		t.Fatalf("expected two reported chirps, got %+v", reports)
+	}
+	if reports[0].Chirp.ID != spam.ID || reports[0].ReportCount != 2 {
+		t.Errorf("first entry = %+v, want the spam chirp with 2 reports", reports[0])
+	}
+	if len(reports[0].Reasons) != 1 || reports[0].Reasons[0] != "spam" {
+		t.Errorf("reasons = %v, want [spam]", reports[0].Reasons)
+	}
+	if reports[1].Chirp.ID != rude.ID || reports[1].ReportCount != 1 {
+		t.Errorf("second entry = %+v, want the rude chirp with 1 report", reports[1])
+	}
+
+	// Dismissing keeps the chirp but empties its reports
+	if rr := resolveReports(t, cfg, spam.ID.String(), adminToken, "dismiss"); rr.Code != http.StatusNoContent {
+		t.Fatalf("dismiss returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	if _, err := db.GetChirpByID(t.Context(), spam.ID); err != nil {
+		t.Errorf("dismissed chirp should still exist: %v", err)
+	}
+
+	// Deleting removes the chirp
+	if rr := resolveReports(t, cfg, rude.ID.String(), adminToken, "delete"); rr.Code != http.StatusNoContent {
+		t.Fatalf("delete returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	if _, err := db.GetChirpByID(t.Context(), rude.ID); err == nil {
+		t.Error("deleted chirp should no longer be readable")
+	}
+
+	if reports := getReports(t, cfg, adminToken); len(reports) != 0 {
+		t.Errorf("expected an empty queue after resolving, got %+v", reports)
+	}
+
+	// Resolved reports still count towards the one-report-per-user rule
+	if rr := resolveReports(t, cfg, spam.ID.String(), adminToken, "dismiss"); rr.Code != http.StatusNotFound {
+		t.Errorf("resolving twice: got status %v want %v", rr.Code, http.StatusNotFound)
+	}
+	if rr := reportChirp(t, cfg, spam.ID.String(), aliceToken, nil); rr.Code != http.StatusConflict {
+		t.Errorf("re-report after dismissal: got status %v want %v", rr.Code, http.StatusConflict)
+	}
+}
+
+func TestReportChirpErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	author := db.addUser(t, "author@example.com")
+	other := db.addUser(t, "other@example.com")
+	chirp := db.addChirp(t, author.ID, "Hello")
+	authorToken := makeTestToken(t, author.ID)
+	otherToken := makeTestToken(t, other.ID)
+	adminToken := makeTestToken(t, db.addAdmin(t, "admin@example.com").ID)
+
+	if rr := reportChirp(t, cfg, chirp.ID.String(), otherToken, nil); rr.Code != http.StatusNoContent {
+		t.Fatalf("first report: got status %v want %v", rr.Code, http.StatusNoContent)
+	}
+
+	tests := []struct {
+		name    string
+		chirpID string
+		token   string
+		payload any
+		want    int
+	}{
+		{"no token", chirp.ID.String(), "", nil, http.StatusUnauthorized},
+		{"invalid ID", "nope", otherToken, nil, http.StatusBadRequest},
+		{"unknown chirp", uuid.NewString(), otherToken, nil, http.StatusNotFound},
+		{"own chirp", chirp.ID.String(), authorToken, nil, http.StatusBadRequest},
+		{"reason too long", chirp.ID.String(), otherToken, map[string]any{"reason": string(bytes.Repeat([]byte("a"), 501))}, http.StatusBadRequest},
+		{"duplicate", chirp.ID.String(), otherToken, map[string]any{"reason": "again"}, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		if rr := reportChirp(t, cfg, tt.chirpID, tt.token, tt.payload); rr.Code != tt.want {
+			t.Errorf("%s: got status %v want %v", tt.name, rr.Code, tt.want)
+		}
+	}
+
+	if rr := resolveReports(t, cfg, chirp.ID.String(), adminToken, "ignore"); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid action: got status %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// The admin endpoints are closed to regular users
+	req := httptest.NewRequest(http.MethodGet, "/admin/reports", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	rr := httptest.NewRecorder()
+	cfg.handlerGetReports(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("reports for a regular user: got status %v want %v", rr.Code, http.StatusForbidden)
+	}
+	if rr := resolveReports(t, cfg, chirp.ID.String(), otherToken, "delete"); rr.Code != http.StatusForbidden {
+		t.Errorf("resolve by a regular user: got status %v want %v", rr.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// handlerGetUserStats summarises a user's activity. Every figure comes from
+// an aggregate query, so the cost does not grow with the number of chirps
+// returned.
+func (cfg *apiConfig) handlerGetUserStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	_, err = cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithLookupError(w, r, err, "User not found")
+		return
+	}
+	if !cfg.requireVisibleAccount(w, r, userID) {
+		return
+	}
+
+	chirpStats, err := cfg.dbQueries.GetUserChirpStats(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	likeStats, err := cfg.dbQueries.GetUserLikeStats(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	followerCount, err := cfg.dbQueries.CountFollowers(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	followingCount, err := cfg.dbQueries.CountFollowing(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(UserStats{
+		ChirpCount:     chirpStats.ChirpCount,
+		FirstChirpAt:   nullableTime(chirpStats.FirstChirpAt),
+		LastChirpAt:    nullableTime(chirpStats.LastChirpAt),
+		FollowerCount:  followerCount,
+		FollowingCount: followingCount,
+		LikesReceived:  likeStats.LikesReceived,
+		LikesGiven:     likeStats.LikesGiven,
+	})
+}
+
+// nullableTime returns a pointer to t's value, or nil when it is NULL.
+func nullableTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
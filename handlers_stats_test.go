@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func getUserStats(t *testing.T, cfg *apiConfig, userID string) (UserStats, int) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/users/"+userID+"/stats", nil)
+	req.SetPathValue("userID", userID)
+	rr := httptest.NewRecorder()
+	cfg.handlerGetUserStats(rr, req)
+	var stats UserStats
+	if rr.Code == http.StatusOK {
+		if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+			t.Fatalf("Failed to decode stats: %v", err)
+		}
+	}
+	return stats, rr.Code
+}
+
+func TestUserStatsEmpty(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "quiet@example.com")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/"+user.ID.String()+"/stats", nil)
+	req.SetPathValue("userID", user.ID.String())
+	rr := httptest.NewRecorder()
+	cfg.handlerGetUserStats(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %v want %v", rr.Code, http.StatusOK)
+	}
+
+	// Timestamps are null rather than missing or zero
+	var raw map[string]any
+	json.NewDecoder(rr.Body).Decode(&raw)
+	want := map[string]any{
+		"chirp_count":     0.0,
+		"first_chirp_at":  nil,
+		"last_chirp_at":   nil,
+		"follower_count":  0.0,
+		"following_count": 0.0,
+		"likes_received":  0.0,
+		"likes_given":     0.0,
+	}
+	for key, value := range want {
+		got, ok := raw[key]
+		if !ok || got != value {
+			t.Errorf("%s = %v (present %v), want %v", key, got, ok, value)
+		}
+	}
+}
+
+func TestUserStatsPopulated(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+	carol := db.addUser(t, "carol@example.com")
+
+	first := db.addChirp(t, alice.ID, "First")
+	second := db.addChirp(t, alice.ID, "Second")
+	deleted := db.addChirp(t, alice.ID, "Gone")
+	bobs := db.addChirp(t, bob.ID, "Bob's")
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	db.mu.Lock()
+	for i := range db.chirps {
+		switch db.chirps[i].ID {
+		case first.ID:
+			db.chirps[i].CreatedAt = base
+		case second.ID:
+			db.chirps[i].CreatedAt = base.Add(48 * time.Hour)
+		case deleted.ID:
+			db.chirps[i].CreatedAt = base.Add(96 * time.Hour)
+		}
+	}
+	db.mu.Unlock()
+
+	ctx := t.Context()
+	for _, like := range []database.LikeChirpParams{
+		{ChirpID: first.ID, UserID: bob.ID},
+		{ChirpID: first.ID, UserID: carol.ID},
+		{ChirpID: second.ID, UserID: bob.ID},
+		{ChirpID: deleted.ID, UserID: carol.ID},
+		{ChirpID: bobs.ID, UserID: alice.ID},
+	} {
+		if _, err := db.LikeChirp(ctx, like); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, follow := range []database.CreateFollowParams{
+		{FollowerID: bob.ID, FolloweeID: alice.ID},
+		{FollowerID: carol.ID, FolloweeID: alice.ID},
+		{FollowerID: alice.ID, FolloweeID: bob.ID},
+	} {
+		if _, err := db.CreateFollow(ctx, follow); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Deleted chirps and the likes on them no longer count
+	if err := db.DeleteChirp(ctx, deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, code := getUserStats(t, cfg, alice.ID.String())
+	if code != http.StatusOK {
+		t.Fatalf("got status %v want %v", code, http.StatusOK)
+	}
+	if stats.ChirpCount != 2 || stats.FollowerCount != 2 || stats.FollowingCount != 1 || stats.LikesReceived != 3 || stats.LikesGiven != 1 {
+		t.Errorf("stats = %+v, want 2 chirps, 2 followers, 1 following, 3 likes received, 1 given", stats)
+	}
+	if stats.FirstChirpAt == nil || !stats.FirstChirpAt.Equal(base) {
+		t.Errorf("first_chirp_at = %v, want %v", stats.FirstChirpAt, base)
+	}
+	if stats.LastChirpAt == nil || !stats.LastChirpAt.Equal(base.Add(48*time.Hour)) {
+		t.Errorf("last_chirp_at = %v, want %v", stats.LastChirpAt, base.Add(48*time.Hour))
+	}
+}
+
+func TestUserStatsErrors(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	if _, code := getUserStats(t, cfg, uuid.NewString()); code != http.StatusNotFound {
+		t.Errorf("unknown user: got status %v want %v", code, http.StatusNotFound)
+	}
+	if _, code := getUserStats(t, cfg, "nope"); code != http.StatusBadRequest {
+		t.Errorf("invalid ID: got status %v want %v", code, http.StatusBadRequest)
+	}
+}
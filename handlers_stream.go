@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsWriteWait is how long a single frame may take to write.
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is how long we wait for any message (including a pong)
+	// before treating the client as gone.
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod must be shorter than wsPongWait.
+	wsPingPeriod = wsPongWait * 9 / 10
+	// wsMaxMessageSize caps what clients may send; the stream is one-way so
+	// anything beyond control frames is discarded.
+	wsMaxMessageSize = 512
+
+	// sseKeepAlive is how often an idle event stream gets a comment line so
+	// intermediaries don't drop the connection.
+	sseKeepAlive = 30 * time.Second
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// parseStreamAuthor reads the optional author_id filter shared by the live
+// chirp endpoints.
+func parseStreamAuthor(r *http.Request) (uuid.NullUUID, error) {
+	authorIDStr := r.URL.Query().Get("author_id")
+	if authorIDStr == "" {
+		return uuid.NullUUID{}, nil
+	}
+	authorID, err := uuid.Parse(authorIDStr)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: authorID, Valid: true}, nil
+}
+
+func (cfg *apiConfig) handlerChirpsWebSocket(w http.ResponseWriter, r *http.Request) {
+	authorID, err := parseStreamAuthor(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid author ID")
+		return
+	}
+
+	sub, ok := cfg.chirpHub.subscribe(authorID)
+	if !ok {
+		respondWithError(w, r, http.StatusServiceUnavailable, "Server is shutting down")
+		return
+	}
+	defer cfg.chirpHub.unsubscribe(sub)
+
+	// Upgrade writes its own error response on failure
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	go wsReadPump(conn, func() { cfg.chirpHub.unsubscribe(sub) })
+	wsWritePump(conn, sub)
+}
+
+// wsReadPump discards incoming messages so control frames (pongs, close) are
+// processed, and calls done once the client goes away.
+func wsReadPump(conn *websocket.Conn, done func()) {
+	defer done()
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+// wsWritePump forwards chirps from sub to the client and keeps the
+// connection alive with pings. It returns when sub is closed (unsubscribed,
+// evicted as too slow, or hub shutdown) or a write fails.
+func wsWritePump(conn *websocket.Conn, sub *chirpSubscriber) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case chirp, ok := <-sub.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			if err := conn.WriteJSON(chirp); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (cfg *apiConfig) handlerChirpsStream(w http.ResponseWriter, r *http.Request) {
+	authorID, err := parseStreamAuthor(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid author ID")
+		return
+	}
+
+	var lastEventID uuid.UUID
+	if lastEventIDStr := r.Header.Get("Last-Event-ID"); lastEventIDStr != "" {
+		lastEventID, err = uuid.Parse(lastEventIDStr)
+		if err != nil {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+	}
+
+	// Subscribe before backfilling so chirps created in between are not
+	// lost; any that show up in both are skipped below
+	sub, ok := cfg.chirpHub.subscribe(authorID)
+	if !ok {
+		respondWithError(w, r, http.StatusServiceUnavailable, "Server is shutting down")
+		return
+	}
+	defer cfg.chirpHub.unsubscribe(sub)
+
+	backfill := []Chirp{}
+	if lastEventID != uuid.Nil {
+		dbChirps, err := cfg.dbQueries.GetChirpsSince(r.Context(), database.GetChirpsSinceParams{
+			SinceID:  lastEventID,
+			AuthorID: authorID,
+			Limit:    maxPageLimit,
+		})
+		if err == nil {
+			for _, dbChirp := range dbChirps {
+				backfill = append(backfill, chirpFromRow(database.GetChirpsRow(dbChirp)))
+			}
+			err = cfg.populateChirps(r, backfill)
+		}
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+	}
+
+	// The stream outlives the server-wide read and write timeouts. Not
+	// every ResponseWriter supports deadlines, and that is fine.
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	sent := make(map[uuid.UUID]bool, len(backfill))
+	for _, chirp := range backfill {
+		if err := writeChirpEvent(w, chirp); err != nil {
+			return
+		}
+		sent[chirp.ID] = true
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case chirp, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if sent[chirp.ID] {
+				continue
+			}
+			if err := writeChirpEvent(w, chirp); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeChirpEvent writes chirp as a single SSE event whose ID is the chirp
+// ID, so reconnecting clients can resume via Last-Event-ID.
+func writeChirpEvent(w io.Writer, chirp Chirp) error {
+	data, err := json.Marshal(chirp)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\ndata: %s\n\n", chirp.ID, data)
+	return err
+}
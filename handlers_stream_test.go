@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// dialChirpsWebSocket connects to a test server running the WebSocket
+// handler with the given query string.
+func dialChirpsWebSocket(t *testing.T, srv *httptest.Server, query string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/chirps/ws" + query
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial WebSocket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readChirpFrame(t *testing.T, conn *websocket.Conn) Chirp {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var chirp Chirp
+	if err := conn.ReadJSON(&chirp); err != nil {
+		t.Fatalf("Failed to read chirp frame: %v", err)
+	}
+	return chirp
+}
+
+func TestHandlerChirpsWebSocket(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	bob := db.addUser(t, "bob@example.com")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/chirps/ws", cfg.handlerChirpsWebSocket)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	all := dialChirpsWebSocket(t, srv, "")
+	bobOnly := dialChirpsWebSocket(t, srv, "?author_id="+bob.ID.String())
+
+	postChirp(t, cfg, makeTestToken(t, alice.ID), map[string]any{"body": "from alice"})
+	postChirp(t, cfg, makeTestToken(t, bob.ID), map[string]any{"body": "from bob"})
+
+	if got := readChirpFrame(t, all); got.Body != "from alice" || got.UserID != alice.ID {
+		t.Errorf("first frame = %+v, want alice's chirp", got)
+	}
+	if got := readChirpFrame(t, all); got.Body != "from bob" {
+		t.Errorf("second frame = %+v, want bob's chirp", got)
+	}
+	if got := readChirpFrame(t, bobOnly); got.Body != "from bob" {
+		t.Errorf("filtered stream got %+v, want only bob's chirp", got)
+	}
+
+	// Shutting the hub down closes every stream
+	cfg.chirpHub.close()
+	all.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := all.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("expected going-away close after shutdown, got %v", err)
+	}
+}
+
+func TestHandlerChirpsWebSocketInvalidAuthor(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	rr := httptest.NewRecorder()
+	cfg.handlerChirpsWebSocket(rr, httptest.NewRequest(http.MethodGet, "/api/chirps/ws?author_id=nope", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestChirpHubEvictsSlowSubscribers(t *testing.T) {
+	hub := newChirpHub()
+	slow, _ := hub.subscribe(uuid.NullUUID{})
+
+	for i := 0; i < chirpSubscriberBuffer+1; i++ {
+		hub.publish(Chirp{ID: uuid.New()})
+	}
+
+	for i := 0; i < chirpSubscriberBuffer; i++ {
+		<-slow.C
+	}
+	if _, ok := <-slow.C; ok {
+		t.Fatal("expected slow subscriber to be evicted once its buffer overflowed")
+	}
+
+	// Unsubscribing after eviction must not panic
+	hub.unsubscribe(slow)
+
+	hub.close()
+	if _, ok := hub.subscribe(uuid.NullUUID{}); ok {
+		t.Error("subscribe should fail after the hub is closed")
+	}
+}
+
+// sseEvent is a parsed Server-Sent Event.
+type sseEvent struct {
+	ID   string
+	Data string
+}
+
+// openChirpStream connects to a test server running the SSE handler and
+// returns a channel of parsed events. Comment lines are skipped.
+func openChirpStream(t *testing.T, srv *httptest.Server, lastEventID string) <-chan sseEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/chirps/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	events := make(chan sseEvent)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+		var event sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if event.Data != "" {
+					events <- event
+				}
+				event = sseEvent{}
+			case strings.HasPrefix(line, "id: "):
+				event.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				event.Data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return events
+}
+
+func nextChirpEvent(t *testing.T, events <-chan sseEvent) Chirp {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("stream closed before an event arrived")
+		}
+		var chirp Chirp
+		if err := json.Unmarshal([]byte(event.Data), &chirp); err != nil {
+			t.Fatalf("Failed to decode event data: %v", err)
+		}
+		if event.ID != chirp.ID.String() {
+			t.Errorf("event id = %q, want chirp ID %s", event.ID, chirp.ID)
+		}
+		return chirp
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return Chirp{}
+}
+
+func TestHandlerChirpsStream(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/chirps/stream", cfg.handlerChirpsStream)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	events := openChirpStream(t, srv, "")
+	postChirp(t, cfg, token, map[string]any{"body": "live one"})
+	postChirp(t, cfg, token, map[string]any{"body": "live two"})
+
+	first := nextChirpEvent(t, events)
+	if first.Body != "live one" {
+		t.Errorf("first event = %+v, want \"live one\"", first)
+	}
+	if got := nextChirpEvent(t, events); got.Body != "live two" {
+		t.Errorf("second event = %+v, want \"live two\"", got)
+	}
+
+	// A reconnecting client gets what it missed from the database
+	postChirp(t, cfg, token, map[string]any{"body": "missed"})
+	resumed := openChirpStream(t, srv, first.ID.String())
+	if got := nextChirpEvent(t, resumed); got.Body != "live two" {
+		t.Errorf("first backfilled event = %+v, want \"live two\"", got)
+	}
+	if got := nextChirpEvent(t, resumed); got.Body != "missed" {
+		t.Errorf("second backfilled event = %+v, want \"missed\"", got)
+	}
+
+	if got := nextChirpEvent(t, events); got.Body != "missed" {
+		t.Errorf("live stream event = %+v, want \"missed\"", got)
+	}
+
+	// Shutdown ends open streams
+	cfg.chirpHub.close()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected stream to end after hub shutdown")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("timed out waiting for stream to end")
+	}
+}
+
+func TestHandlerChirpsStreamInvalidLastEventID(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps/stream", nil)
+	req.Header.Set("Last-Event-ID", "nope")
+	rr := httptest.NewRecorder()
+	cfg.handlerChirpsStream(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/auth"
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// totpIssuer names Chirpy in authenticator apps.
+const totpIssuer = "Chirpy"
+
+// Error codes of a password login by a user with two-factor authentication:
+// errCodeTOTPRequired when the request has no code, and errCodeInvalidTOTP
+// when its code is neither current nor an unused backup code.
+const (
+	errCodeTOTPRequired = "totp_required"
+	errCodeInvalidTOTP  = "invalid_totp"
+)
+
+// handlerSetupTwoFactor starts setting up two-factor authentication for the
+// caller, who confirms it with their password. It returns a new TOTP secret
+// for their authenticator app; nothing changes at login until the secret is
+// activated with a code from the app. Starting again replaces the secret.
+func (cfg *apiConfig) handlerSetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Password string `json:"password"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	reqBody := requestBody{}
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+
+	dbUser, ok := cfg.requireCurrentPassword(w, r, userID, reqBody.Password)
+	if !ok {
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	started, err := cfg.dbQueries.SetPendingTOTPSecret(r.Context(), database.SetPendingTOTPSecretParams{
+		UserID: userID,
+		Secret: secret,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if started == 0 {
+		respondWithError(w, r, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(TwoFactorSetup{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(totpIssuer, dbUser.Email, secret),
+	})
+}
+
+// handlerActivateTwoFactor turns on two-factor authentication once the
+// caller shows a current code for the secret from handlerSetupTwoFactor. The
+// response carries their backup codes, which are never shown again.
+func (cfg *apiConfig) handlerActivateTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Code string `json:"code"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	reqBody := requestBody{}
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+
+	// The secret was stored moments ago, possibly not on the replica yet
+	pending, err := cfg.dbQueries.GetUserTOTP(withPrimary(r.Context()), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, http.StatusConflict, "Two-factor setup has not been started")
+		return
+	}
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if pending.EnabledAt.Valid {
+		respondWithError(w, r, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	if !auth.ValidateTOTP(pending.Secret, reqBody.Code, time.Now()) {
+		var invalid fieldErrors
+		invalid.add("code", "Code", "is not the current code from the authenticator app")
+		invalid.respond(w, r)
+		return
+	}
+
+	// Hashing is the slow part, so it is done before anything is stored
+	codes, hashes, err := cfg.newBackupCodes()
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	enabled, err := cfg.dbQueries.EnableUserTOTP(r.Context(), database.EnableUserTOTPParams{
+		UserID: userID,
+		Secret: pending.Secret,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if enabled == 0 {
+		respondWithError(w, r, http.StatusConflict, "Two-factor setup was restarted; activate the new secret")
+		return
+	}
+	err = cfg.dbQueries.ReplaceBackupCodes(r.Context(), database.ReplaceBackupCodesParams{
+		UserID:     userID,
+		CodeHashes: hashes,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(BackupCodesResponse{BackupCodes: codes})
+}
+
+// handlerRegenerateBackupCodes replaces the caller's backup codes with a new
+// set, confirmed with their password. The old codes, used or not, stop
+// working.
+func (cfg *apiConfig) handlerRegenerateBackupCodes(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Password string `json:"password"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := cfg.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	reqBody := requestBody{}
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+
+	if _, ok := cfg.requireCurrentPassword(w, r, userID, reqBody.Password); !ok {
+		return
+	}
+
+	totp, err := cfg.dbQueries.GetUserTOTP(withPrimary(r.Context()), userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if err != nil || !totp.EnabledAt.Valid {
+		respondWithError(w, r, http.StatusConflict, "Two-factor authentication is not enabled")
+		return
+	}
+
+	codes, hashes, err := cfg.newBackupCodes()
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	err = cfg.dbQueries.ReplaceBackupCodes(r.Context(), database.ReplaceBackupCodesParams{
+		UserID:     userID,
+		CodeHashes: hashes,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(BackupCodesResponse{BackupCodes: codes})
+}
+
+// requireCurrentPassword loads userID and checks password against theirs,
+// writing a 403 with errCodeInvalidCurrentPassword when it does not match.
+func (cfg *apiConfig) requireCurrentPassword(w http.ResponseWriter, r *http.Request, userID uuid.UUID, password string) (database.User, bool) {
+	dbUser, err := cfg.dbQueries.GetUserByID(withPrimary(r.Context()), userID)
+	if err != nil {
+		respondWithLookupError(w, r, err, "User not found")
+		return database.User{}, false
+	}
+	if cfg.checkUserPassword(dbUser, password) != nil {
+		respondWithErrorCode(w, r, http.StatusForbidden, errCodeInvalidCurrentPassword, "Current password is incorrect")
+		return database.User{}, false
+	}
+	return dbUser, true
+}
+
+// newBackupCodes generates a set of backup codes and their hashes. Only the
+// hashes are stored, always with bcrypt at the configured cost, whatever
+// algorithm passwords are hashed with.
+func (cfg *apiConfig) newBackupCodes() (codes, hashes []string, err error) {
+	codes, err = auth.GenerateBackupCodes(auth.BackupCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	params := auth.PasswordParams{Algorithm: auth.AlgorithmBcrypt, BcryptCost: cfg.passwordHashing.BcryptCost}
+	hashes = make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i], err = auth.HashPassword(auth.NormalizeBackupCode(code), params)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return codes, hashes, nil
+}
+
+// requireSecondFactor checks the second factor of a password login by
+// userID, writing a 401 when it is missing or wrong. Users without
+// two-factor authentication pass without one. code may be a TOTP code or a
+// backup code; a backup code that matches is burnt, so it works once.
+func (cfg *apiConfig) requireSecondFactor(w http.ResponseWriter, r *http.Request, userID uuid.UUID, code string) bool {
+	totp, err := cfg.dbQueries.GetUserTOTP(withPrimary(r.Context()), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true
+	}
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return false
+	}
+	if !totp.EnabledAt.Valid {
+		return true
+	}
+
+	if code == "" {
+		respondWithErrorCode(w, r, http.StatusUnauthorized, errCodeTOTPRequired, "A two-factor code is required")
+		return false
+	}
+	if auth.ValidateTOTP(totp.Secret, code, time.Now()) {
+		return true
+	}
+
+	backupCodes, err := cfg.dbQueries.GetUnusedBackupCodes(withPrimary(r.Context()), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return false
+	}
+	code = auth.NormalizeBackupCode(code)
+	for _, backupCode := range backupCodes {
+		if checkPasswordHash(backupCode.CodeHash, code) != nil {
+			continue
+		}
+		// Another login may have burnt it since it was read
+		used, err := cfg.dbQueries.UseBackupCode(r.Context(), backupCode.ID)
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return false
+		}
+		if used > 0 {
+			return true
+		}
+		break
+	}
+
+	respondWithErrorCode(w, r, http.StatusUnauthorized, errCodeInvalidTOTP, "Invalid two-factor code")
+	return false
+}
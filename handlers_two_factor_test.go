@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/auth"
+)
+
+// twoFactorLogin logs in as the user of newTwoFactorUser with code as the
+// second factor, returning the status and error code.
+func twoFactorLogin(t *testing.T, srv *httptest.Server, code string) (int, string) {
+	t.Helper()
+	var errResp ErrorResponse
+	resp := call(t, srv, http.MethodPost, "/api/login", "", map[string]string{
+		"email":     "alice@example.com",
+		"password":  "hunter22",
+		"totp_code": code,
+	}, &errResp)
+	return resp.StatusCode, errResp.Code
+}
+
+// newTwoFactorUser signs up alice@example.com and turns on two-factor
+// authentication through the API, returning her access token, TOTP secret
+// and backup codes.
+func newTwoFactorUser(t *testing.T, srv *httptest.Server) (token, secret string, backupCodes []string) {
+	t.Helper()
+	var user User
+	credentials := map[string]string{"email": "alice@example.com", "password": "hunter22"}
+	if resp := call(t, srv, http.MethodPost, "/api/users", "", credentials, &user); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("signup: got status %v want %v", resp.StatusCode, http.StatusCreated)
+	}
+	var login struct {
+		Token string `json:"token"`
+	}
+	if resp := call(t, srv, http.MethodPost, "/api/login", "", credentials, &login); resp.StatusCode != http.StatusOK {
+		t.Fatalf("login: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	token = login.Token
+
+	if resp := call(t, srv, http.MethodPost, "/api/users/me/2fa/setup", token, map[string]string{"password": "wrong"}, nil); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("setup with the wrong password: got status %v want %v", resp.StatusCode, http.StatusForbidden)
+	}
+	var setup TwoFactorSetup
+	if resp := call(t, srv, http.MethodPost, "/api/users/me/2fa/setup", token, map[string]string{"password": "hunter22"}, &setup); resp.StatusCode != http.StatusOK {
+		t.Fatalf("setup: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+
+	// Until activation, logins need no code
+	if status, _ := twoFactorLogin(t, srv, ""); status != http.StatusOK {
+		t.Fatalf("login before activation: got status %v want %v", status, http.StatusOK)
+	}
+
+	if resp := call(t, srv, http.MethodPost, "/api/users/me/2fa/activate", token, map[string]string{"code": "000000"}, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("activate with a wrong code: got status %v want %v", resp.StatusCode, http.StatusBadRequest)
+	}
+	code, err := auth.TOTPCode(setup.Secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var activated BackupCodesResponse
+	if resp := call(t, srv, http.MethodPost, "/api/users/me/2fa/activate", token, map[string]string{"code": code}, &activated); resp.StatusCode != http.StatusOK {
+		t.Fatalf("activate: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if len(activated.BackupCodes) != auth.BackupCodeCount {
+		t.Fatalf("activation returned %d backup codes, want %d", len(activated.BackupCodes), auth.BackupCodeCount)
+	}
+	return token, setup.Secret, activated.BackupCodes
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.passwordHashing.BcryptCost = auth.MinPasswordCost
+	srv := newTestServer(t, cfg)
+	_, secret, backupCodes := newTwoFactorUser(t, srv)
+
+	// Only hashes are stored
+	if len(db.backupCodes) != len(backupCodes) {
+		t.Fatalf("stored %d backup codes, want %d", len(db.backupCodes), len(backupCodes))
+	}
+	for i, stored := range db.backupCodes {
+		if strings.Contains(stored.CodeHash, auth.NormalizeBackupCode(backupCodes[i])) {
+			t.Errorf("backup code %q is stored in the clear", backupCodes[i])
+		}
+	}
+
+	if status, code := twoFactorLogin(t, srv, ""); status != http.StatusUnauthorized || code != errCodeTOTPRequired {
+		t.Errorf("login without a code: got %v %q, want %v %q", status, code, http.StatusUnauthorized, errCodeTOTPRequired)
+	}
+	if status, code := twoFactorLogin(t, srv, "abcde-fghij"); status != http.StatusUnauthorized || code != errCodeInvalidTOTP {
+		t.Errorf("login with a wrong code: got %v %q, want %v %q", status, code, http.StatusUnauthorized, errCodeInvalidTOTP)
+	}
+	totp, _ := auth.TOTPCode(secret, time.Now())
+	if status, _ := twoFactorLogin(t, srv, totp); status != http.StatusOK {
+		t.Errorf("login with the TOTP code: got status %v want %v", status, http.StatusOK)
+	}
+}
+
+func TestBackupCodes(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	cfg.passwordHashing.BcryptCost = auth.MinPasswordCost
+	srv := newTestServer(t, cfg)
+	token, _, backupCodes := newTwoFactorUser(t, srv)
+
+	// Typed in capitals and without the hyphen, it still matches
+	typed := strings.ToUpper(strings.Replace(backupCodes[0], "-", "", 1))
+	if status, _ := twoFactorLogin(t, srv, typed); status != http.StatusOK {
+		t.Fatalf("login with a backup code: got status %v want %v", status, http.StatusOK)
+	}
+	if status, code := twoFactorLogin(t, srv, backupCodes[0]); status != http.StatusUnauthorized || code != errCodeInvalidTOTP {
+		t.Errorf("second login with the same backup code: got %v %q, want %v %q", status, code, http.StatusUnauthorized, errCodeInvalidTOTP)
+	}
+
+	if resp := call(t, srv, http.MethodPost, "/api/users/me/2fa/backup_codes", token, map[string]string{"password": "wrong"}, nil); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("regenerate with the wrong password: got status %v want %v", resp.StatusCode, http.StatusForbidden)
+	}
+	var regenerated BackupCodesResponse
+	if resp := call(t, srv, http.MethodPost, "/api/users/me/2fa/backup_codes", token, map[string]string{"password": "hunter22"}, &regenerated); resp.StatusCode != http.StatusOK {
+		t.Fatalf("regenerate: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if len(regenerated.BackupCodes) != auth.BackupCodeCount {
+		t.Fatalf("regeneration returned %d backup codes, want %d", len(regenerated.BackupCodes), auth.BackupCodeCount)
+	}
+
+	if status, code := twoFactorLogin(t, srv, backupCodes[1]); status != http.StatusUnauthorized || code != errCodeInvalidTOTP {
+		t.Errorf("login with an unused code of the old batch: got %v %q, want %v %q", status, code, http.StatusUnauthorized, errCodeInvalidTOTP)
+	}
+	if status, _ := twoFactorLogin(t, srv, regenerated.BackupCodes[1]); status != http.StatusOK {
+		t.Errorf("login with a new backup code: got status %v want %v", status, http.StatusOK)
+	}
+}
+
+func TestRegenerateBackupCodesWithoutTwoFactor(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	var user User
+	credentials := map[string]string{"email": "bob@example.com", "password": "hunter22"}
+	if resp := call(t, srv, http.MethodPost, "/api/users", "", credentials, &user); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("signup: got status %v want %v", resp.StatusCode, http.StatusCreated)
+	}
+	resp := call(t, srv, http.MethodPost, "/api/users/me/2fa/backup_codes", makeTestToken(t, user.ID), map[string]string{"password": "hunter22"}, nil)
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("got status %v want %v", resp.StatusCode, http.StatusConflict)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// handlerUsernameAvailable tells a signup form whether a username can be
+// registered, checking it with the same rules as POST /api/users. It needs
+// no login, so it is rate limited per client address to keep it from being
+// used to list who is registered.
+func (cfg *apiConfig) handlerUsernameAvailable(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !cfg.allowUsernameCheck(w, r) {
+		return
+	}
+
+	var resp UsernameAvailability
+	username, err := normalizeUsername(r.PathValue("name"))
+	switch {
+	case errors.Is(err, errUsernameReserved):
+		resp.Reason = "reserved"
+	case err != nil:
+		resp.Reason = "invalid"
+	default:
+		taken, err := cfg.dbQueries.UsernameExists(r.Context(), username)
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+		if taken {
+			resp.Reason = "taken"
+		} else {
+			resp.Available = true
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsernameAvailable(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	db.addUser(t, "alice@example.com")
+
+	tests := []struct {
+		name string
+		want UsernameAvailability
+	}{
+		{"bob", UsernameAvailability{Available: true}},
+		{"alice", UsernameAvailability{Reason: "taken"}},
+		// Checked the way signup stores it
+		{"Alice", UsernameAvailability{Reason: "taken"}},
+		{"admin", UsernameAvailability{Reason: "reserved"}},
+		{"Support", UsernameAvailability{Reason: "reserved"}},
+		{"al", UsernameAvailability{Reason: "invalid"}},
+		{strings.Repeat("a", maxUsernameLength+1), UsernameAvailability{Reason: "invalid"}},
+		{"first.last", UsernameAvailability{Reason: "invalid"}},
+		{"with space", UsernameAvailability{Reason: "invalid"}},
+	}
+	for _, tt := range tests {
+		var got UsernameAvailability
+		resp := call(t, srv, http.MethodGet, "/api/usernames/"+url.PathEscape(tt.name)+"/available", "", nil, &got)
+		if resp.StatusCode != http.StatusOK || got != tt.want {
+			t.Errorf("%q: got status %v and %+v, want %v and %+v", tt.name, resp.StatusCode, got, http.StatusOK, tt.want)
+		}
+	}
+
+	// A name reported free can be signed up with, and is then taken
+	var got UsernameAvailability
+	call(t, srv, http.MethodPost, "/api/users", "", map[string]string{"email": "bob@example.com", "password": "password123", "username": "bob"}, nil)
+	call(t, srv, http.MethodGet, "/api/usernames/bob/available", "", nil, &got)
+	if got != (UsernameAvailability{Reason: "taken"}) {
+		t.Errorf("after signing up as bob: got %+v", got)
+	}
+}
+
+func TestUsernameAvailableRateLimit(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	cfg.usernameLimiter = newMemoryRateLimiter(time.Minute)
+	cfg.usernameRateLimit = 3
+	srv := newTestServer(t, cfg)
+
+	for i := range 3 {
+		if resp := call(t, srv, http.MethodGet, "/api/usernames/bob/available", "", nil, nil); resp.StatusCode != http.StatusOK {
+			t.Fatalf("check %d under the limit: got status %v want %v", i+1, resp.StatusCode, http.StatusOK)
+		}
+	}
+	resp := call(t, srv, http.MethodGet, "/api/usernames/carol/available", "", nil, nil)
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("over the limit: got status %v and Retry-After %q, want %v", resp.StatusCode, resp.Header.Get("Retry-After"), http.StatusTooManyRequests)
+	}
+}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/AlexTLDR/chirpy/internal/auth"
@@ -10,74 +16,229 @@ import (
 	"github.com/google/uuid"
 )
 
+// emailConstraint is the unique constraint on users.email.
+const emailConstraint = "users_email_key"
+
+// errCodeInvalidCurrentPassword is the error code of a PUT /api/users whose
+// current_password is wrong.
+const errCodeInvalidCurrentPassword = "invalid_current_password"
+
+// signupAcceptedMessage is the whole response to a signup in privacy mode.
+const signupAcceptedMessage = "Check your email to finish signing up"
+
 func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request) {
 	type requestBody struct {
-		Email           string `json:"email"`
-		Password        string `json:"password"`
-		ExpiresInSeconds *int  `json:"expires_in_seconds,omitempty"`
+		Email       string `json:"email"`
+		Password    string `json:"password"`
+		Username    string `json:"username"`
+		DisplayName string `json:"display_name"`
+		Bio         string `json:"bio"`
+		// Website is the honeypot: signup forms hide it, so only bots
+		// fill it in
+		Website          string `json:"website"`
+		ExpiresInSeconds *int   `json:"expires_in_seconds,omitempty"`
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 
+	if !cfg.allowSignup(w, r) {
+		return
+	}
+
 	decoder := json.NewDecoder(r.Body)
 	reqBody := requestBody{}
 	err := decoder.Decode(&reqBody)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
 		return
 	}
 
+	// Every invalid field is reported at once
+	var invalid fieldErrors
 	if reqBody.Email == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Email is required"})
-		return
+		invalid.add("email", "Email", "is required")
 	}
 
 	if reqBody.Password == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Password is required"})
+		invalid.add("password", "Password", "is required")
+	}
+
+	// Without a chosen username one is derived from the email address
+	username := defaultUsername(reqBody.Email)
+	if reqBody.Username != "" {
+		username, err = normalizeUsername(reqBody.Username)
+		if err != nil {
+			invalid.add("username", "Username", err.Error())
+		}
+	}
+
+	if err := validateProfileText(reqBody.DisplayName, maxDisplayNameLength); err != nil {
+		invalid.add("display_name", "display_name", err.Error())
+	}
+	if err := validateProfileText(reqBody.Bio, maxBioLength); err != nil {
+		invalid.add("bio", "bio", err.Error())
+	}
+	if invalid.respond(w, r) {
 		return
 	}
+	bio := cleanProfanity(reqBody.Bio)
 
-	hashedPassword, err := auth.HashPassword(reqBody.Password)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+	// A bot that filled in the honeypot gets the response a real signup
+	// would, so it has no reason to try again another way
+	if cfg.signupHoneypot && reqBody.Website != "" {
+		logf(r.Context(), "Ignoring signup for %s with the honeypot field filled in", cfg.clientIP(r))
+		if cfg.signupPrivacy {
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(SignupAcceptedResponse{Message: signupAcceptedMessage})
+			return
+		}
+		now := time.Now().UTC()
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(userFromDB(database.User{
+			ID:          uuid.New(),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			Email:       reqBody.Email,
+			Username:    username,
+			DisplayName: sql.NullString{String: reqBody.DisplayName, Valid: reqBody.DisplayName != ""},
+			Bio:         sql.NullString{String: bio, Valid: bio != ""},
+		}))
 		return
 	}
 
-	dbUser, err := cfg.dbQueries.CreateUser(r.Context(), database.CreateUserParams{
-		Email:          reqBody.Email,
-		HashedPassword: hashedPassword,
-	})
+	hashedPassword, err := auth.HashPassword(reqBody.Password, cfg.passwordHashing)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
 
-	user := User{
-		ID:          dbUser.ID,
-		CreatedAt:   dbUser.CreatedAt,
-		UpdatedAt:   dbUser.UpdatedAt,
-		Email:       dbUser.Email,
-		IsChirpyRed: dbUser.IsChirpyRed,
+	var dbUser database.User
+	for attempt := 0; attempt < usernameAttempts; attempt++ {
+		candidate := username
+		if reqBody.Username == "" {
+			candidate = usernameCandidate(username, attempt)
+		}
+		dbUser, err = cfg.dbQueries.CreateUser(r.Context(), database.CreateUserParams{
+			Email:          reqBody.Email,
+			HashedPassword: sql.NullString{String: hashedPassword, Valid: true},
+			Username:       candidate,
+			DisplayName:    sql.NullString{String: reqBody.DisplayName, Valid: reqBody.DisplayName != ""},
+			Bio:            sql.NullString{String: bio, Valid: bio != ""},
+		})
+		// A chosen username is never swapped for another one
+		if reqBody.Username != "" || !isUniqueViolation(err, usernameConstraint) {
+			break
+		}
+	}
+	if isUniqueViolation(err, usernameConstraint) {
+		respondWithError(w, r, http.StatusConflict, "Username is already taken")
+		return
+	}
+	emailTaken := isUniqueViolation(err, emailConstraint)
+	if emailTaken && !cfg.signupPrivacy {
+		respondWithError(w, r, http.StatusConflict, "Email is already registered")
+		return
+	}
+	if err != nil && !emailTaken {
+		respondWithDBError(w, r, err)
+		return
 	}
 
+	// In privacy mode a new and an existing email get the same response;
+	// only the email sent to the address tells them apart
+	if cfg.signupPrivacy {
+		if emailTaken {
+			cfg.sendAccountExistsEmail(reqBody.Email)
+		} else {
+			cfg.sendWelcomeEmail(dbUser.Email)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(SignupAcceptedResponse{Message: signupAcceptedMessage})
+		return
+	}
+
+	cfg.sendWelcomeEmail(dbUser.Email)
+
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(user)
+	json.NewEncoder(w).Encode(userFromDB(dbUser))
 }
 
-func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+// incorrectLogin is the only error a failed login gets, so the response does
+// not tell an unknown email from a wrong password.
+const incorrectLogin = "Incorrect email or password"
+
+// dummyPasswordHashes caches dummyPasswordHash's hash for each setting.
+var dummyPasswordHashes sync.Map
+
+// dummyPasswordHash is compared against when no account has the email, so
+// a failed login costs one password check either way and its timing does
+// not reveal which emails are registered. It is hashed on first use, with
+// params, which must be how real passwords are hashed.
+func dummyPasswordHash(params auth.PasswordParams) string {
+	if hash, ok := dummyPasswordHashes.Load(params); ok {
+		return hash.(string)
+	}
+	hash, err := auth.HashPassword("not the password of any account", params)
+	if err != nil {
+		panic(err)
+	}
+	actual, _ := dummyPasswordHashes.LoadOrStore(params, hash)
+	return actual.(string)
+}
+
+// checkPasswordHash is auth.CheckPasswordHash; tests replace it to see
+// which hash a login was checked against.
+var checkPasswordHash = auth.CheckPasswordHash
+
+// errNoPassword is what checkUserPassword returns for accounts that sign in
+// through an identity provider.
+var errNoPassword = errors.New("account has no password")
+
+// checkUserPassword checks password against user's hash. An account without
+// a password never matches, after the same bcrypt work as one with a
+// password.
+func (cfg *apiConfig) checkUserPassword(user database.User, password string) error {
+	if !user.HashedPassword.Valid {
+		checkPasswordHash(dummyPasswordHash(cfg.passwordHashing), password)
+		return errNoPassword
+	}
+	return checkPasswordHash(user.HashedPassword.String, password)
+}
+
+// upgradePasswordHash rehashes password, which has just been checked
+// against user's hash, if that hash was made with another algorithm or
+// settings than passwordHashing, so existing hashes follow a change of
+// PASSWORD_HASH_ALGORITHM or its settings as their owners log in. The login
+// has already succeeded, so a failure is only logged and the old hash
+// keeps working.
+func (cfg *apiConfig) upgradePasswordHash(r *http.Request, user database.User, password string) {
+	if !auth.NeedsRehash(user.HashedPassword.String, cfg.passwordHashing) {
 		return
 	}
+	hash, err := auth.HashPassword(password, cfg.passwordHashing)
+	if err == nil {
+		_, err = cfg.dbQueries.RehashUserPassword(r.Context(), database.RehashUserPasswordParams{
+			NewHash: hash,
+			ID:      user.ID,
+			OldHash: user.HashedPassword.String,
+		})
+	}
+	if err != nil {
+		logf(r.Context(), "Error rehashing the password of user %s: %v", user.ID, err)
+	}
+}
 
+func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 	type requestBody struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		// RememberMe asks for a long-lived refresh token
+		RememberMe bool `json:"remember_me"`
+		// Scopes limits the tokens issued; omitted, they have full access
+		Scopes []string `json:"scopes"`
+		// TOTPCode is the second factor of a user with two-factor
+		// authentication: a code from their app or a backup code
+		TOTPCode string `json:"totp_code"`
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -86,139 +247,221 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 	reqBody := requestBody{}
 	err := decoder.Decode(&reqBody)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
 		return
 	}
 
+	if reqBody.Scopes != nil {
+		var invalid fieldErrors
+		if len(reqBody.Scopes) == 0 {
+			invalid.add("scopes", "Scopes", "must not be empty")
+		}
+		for _, scope := range reqBody.Scopes {
+			if !slices.Contains(auth.AllScopes, scope) {
+				invalid.add("scopes", "Scopes", fmt.Sprintf("contains unknown scope %q", scope))
+				break
+			}
+		}
+		if invalid.respond(w, r) {
+			return
+		}
+	}
+
 	if reqBody.Email == "" || reqBody.Password == "" {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Incorrect email or password"})
+		respondWithError(w, r, http.StatusUnauthorized, incorrectLogin)
 		return
 	}
 
-	dbUser, err := cfg.dbQueries.GetUserByEmail(r.Context(), reqBody.Email)
+	// A replica may not have a fresh signup or password change yet
+	dbUser, err := cfg.dbQueries.GetUserByEmail(withPrimary(r.Context()), reqBody.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithDBError(w, r, err)
+		return
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Incorrect email or password"})
+		// Spend the same bcrypt work as for a real account
+		checkPasswordHash(dummyPasswordHash(cfg.passwordHashing), reqBody.Password)
+		respondWithError(w, r, http.StatusUnauthorized, incorrectLogin)
 		return
 	}
 
-	err = auth.CheckPasswordHash(dbUser.HashedPassword, reqBody.Password)
+	err = cfg.checkUserPassword(dbUser, reqBody.Password)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Incorrect email or password"})
+		respondWithError(w, r, http.StatusUnauthorized, incorrectLogin)
+		return
+	}
+	cfg.upgradePasswordHash(r, dbUser, reqBody.Password)
+
+	if !cfg.requireSecondFactor(w, r, dbUser.ID, reqBody.TOTPCode) {
+		return
+	}
+
+	cfg.respondWithLogin(w, r, dbUser, reqBody.RememberMe, reqBody.Scopes)
+}
+
+// respondWithLogin issues dbUser, who has just proved who they are, an
+// access token and a refresh token starting a new family, and writes them
+// with the user's profile, recording the login in their history. Both
+// tokens are limited to scopes unless it is nil. A banned user gets a 403
+// instead.
+func (cfg *apiConfig) respondWithLogin(w http.ResponseWriter, r *http.Request, dbUser database.User, rememberMe bool, scopes []string) {
+	if !cfg.requireActiveAccount(w, r, dbUser.ID) {
 		return
 	}
 
-	// Create JWT access token (1 hour expiration)
-	accessToken, err := auth.MakeJWT(dbUser.ID, cfg.jwtSecret, time.Hour)
+	accessToken, err := cfg.makeAccessToken(dbUser.ID, scopes)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
 
 	// Create refresh token
 	refreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
 
-	// Store refresh token in database (60 days expiration)
-	expiresAt := time.Now().UTC().Add(60 * 24 * time.Hour)
-	_, err = cfg.dbQueries.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:     refreshToken,
-		UserID:    dbUser.ID,
-		ExpiresAt: expiresAt,
+	// A login starts a new family of refresh tokens
+	storedToken, err := cfg.storeRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		Token:      refreshToken,
+		UserID:     dbUser.ID,
+		FamilyID:   uuid.New(),
+		RememberMe: rememberMe,
+		Scopes:     scopes,
 	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+		respondWithDBError(w, r, err)
 		return
 	}
+	cfg.recordLogin(r, dbUser.ID)
 
 	// Response structure with both tokens
 	response := struct {
 		User
-		Token        string `json:"token"`
-		RefreshToken string `json:"refresh_token"`
+		accessTokenResponse
+		RefreshToken          string    `json:"refresh_token"`
+		RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+		Scopes                []string  `json:"scopes,omitempty"`
 	}{
-		User: User{
-			ID:          dbUser.ID,
-			CreatedAt:   dbUser.CreatedAt,
-			UpdatedAt:   dbUser.UpdatedAt,
-			Email:       dbUser.Email,
-			IsChirpyRed: dbUser.IsChirpyRed,
-		},
-		Token:        accessToken,
-		RefreshToken: refreshToken,
+		User:                  userFromDB(dbUser),
+		accessTokenResponse:   accessToken,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: storedToken.ExpiresAt,
+		Scopes:                scopes,
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
+// handlerRefresh exchanges a refresh token for a new access token and a new
+// refresh token. The presented token is revoked, so each one works once;
+// presenting it again is treated as theft (see refreshTokenReused).
 func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract refresh token from Authorization header
+	refreshToken, ok := requireRefreshToken(w, r)
+	if !ok {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	oldToken, err := cfg.dbQueries.GetRefreshToken(r.Context(), refreshToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithErrorCode(w, r, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if oldToken.RevokedAt.Valid {
+		cfg.refreshTokenReused(r, oldToken)
+		respondWithErrorCode(w, r, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+	if !oldToken.ExpiresAt.After(time.Now().UTC()) {
+		respondWithErrorCode(w, r, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
 
-	// Extract refresh token from Authorization header
-	refreshToken, err := auth.GetBearerToken(r.Header)
+	// Revoking the old token claims it; if a concurrent request got there
+	// first, the same token was presented twice
+	revoked, err := cfg.dbQueries.RevokeRefreshToken(r.Context(), oldToken.Token)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		respondWithDBError(w, r, err)
+		return
+	}
+	if revoked == 0 {
+		cfg.refreshTokenReused(r, oldToken)
+		respondWithErrorCode(w, r, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
 		return
 	}
 
-	// Get user from refresh token (validates token exists, not expired, not revoked)
-	dbUser, err := cfg.dbQueries.GetUserFromRefreshToken(r.Context(), refreshToken)
+	accessToken, err := cfg.makeAccessToken(oldToken.UserID, oldToken.Scopes)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
 
-	// Create new JWT access token (1 hour expiration)
-	accessToken, err := auth.MakeJWT(dbUser.ID, cfg.jwtSecret, time.Hour)
+	newToken, err := auth.MakeRefreshToken()
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	// The new token keeps the family, lifetime and scopes chosen at login
+	storedToken, err := cfg.storeRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		Token:       newToken,
+		UserID:      oldToken.UserID,
+		FamilyID:    oldToken.FamilyID,
+		ParentToken: sql.NullString{String: oldToken.Token, Valid: true},
+		RememberMe:  oldToken.RememberMe,
+		Scopes:      oldToken.Scopes,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
 		return
 	}
 
-	// Response with new access token
+	// Response with both new tokens
 	response := struct {
-		Token string `json:"token"`
+		accessTokenResponse
+		RefreshToken          string    `json:"refresh_token"`
+		RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
 	}{
-		Token: accessToken,
+		accessTokenResponse:   accessToken,
+		RefreshToken:          newToken,
+		RefreshTokenExpiresAt: storedToken.ExpiresAt,
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
-func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		return
+// refreshTokenReused handles a refresh token presented after it was revoked
+// or rotated. Only the legitimate client or someone who stole the token can
+// hold an old one, and there is no telling which, so every token in its
+// family is revoked and both have to log in again.
+func (cfg *apiConfig) refreshTokenReused(r *http.Request, token database.RefreshToken) {
+	cfg.refreshTokenReuse.Add(1)
+	revoked, err := cfg.dbQueries.RevokeRefreshTokenFamily(r.Context(), token.FamilyID)
+	if err != nil {
+		logf(r.Context(), "Error revoking refresh token family %s: %v", token.FamilyID, err)
 	}
+	logf(r.Context(), "Security: revoked refresh token reused for user %s; revoked %d more tokens in family %s", token.UserID, revoked, token.FamilyID)
+	cfg.audit(r, auditEntry{ActorID: token.UserID, Action: auditRefreshTokenReuse, Target: token.FamilyID.String()})
+}
 
+func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
 	// Extract refresh token from Authorization header
-	refreshToken, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+	refreshToken, ok := requireRefreshToken(w, r)
+	if !ok {
 		return
 	}
 
 	// Revoke the refresh token
-	err = cfg.dbQueries.RevokeRefreshToken(r.Context(), refreshToken)
+	_, err := cfg.dbQueries.RevokeRefreshToken(r.Context(), refreshToken)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -228,15 +471,22 @@ func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// handlerUpdateUser changes the fields present in the request and leaves the
+// rest of the profile as it was. A new email only replaces the old one once
+// it is confirmed.
 func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		return
-	}
-
 	type requestBody struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email       *string `json:"email"`
+		Password    *string `json:"password"`
+		Username    *string `json:"username"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+		// CurrentPassword is required to change the email or password, so
+		// a stolen access token alone cannot take over the account
+		CurrentPassword *string `json:"current_password"`
+		// UpdatedAt, when sent, is the updated_at of the record the edit
+		// was based on; the update is refused if the account changed since
+		UpdatedAt *time.Time `json:"updated_at"`
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -244,80 +494,154 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request)
 	// Extract and validate access token
 	accessToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		respondWithErrorCode(w, r, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Validate the JWT token and get user ID
-	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	claims, err := auth.ValidateJWTWithSecrets(accessToken, cfg.jwtSecrets)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized"})
+		cfg.respondUnauthorized(w, r)
 		return
 	}
+	userID := claims.UserID
 
 	// Parse request body
 	decoder := json.NewDecoder(r.Body)
 	reqBody := requestBody{}
 	err = decoder.Decode(&reqBody)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+		respondWithError(w, r, http.StatusBadRequest, "Something went wrong")
 		return
 	}
 
-	if reqBody.Email == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Email is required"})
-		return
+	// Omitted fields are passed as NULL, which the query leaves unchanged
+	params := database.UpdateUserParams{ID: userID}
+	if reqBody.UpdatedAt != nil {
+		params.ExpectedUpdatedAt = sql.NullTime{Time: *reqBody.UpdatedAt, Valid: true}
 	}
 
-	if reqBody.Password == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Password is required"})
-		return
+	// Every invalid field is reported at once
+	var invalid fieldErrors
+	// A new email is not set here but waits to be confirmed, see
+	// startEmailChange
+	if reqBody.Email != nil && *reqBody.Email == "" {
+		invalid.add("email", "Email", "is required")
 	}
 
-	// Hash the new password
-	hashedPassword, err := auth.HashPassword(reqBody.Password)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+	if reqBody.Password != nil && *reqBody.Password == "" {
+		invalid.add("password", "Password", "is required")
+	}
+
+	if reqBody.Username != nil {
+		username, err := normalizeUsername(*reqBody.Username)
+		if err != nil {
+			invalid.add("username", "Username", err.Error())
+		}
+		params.Username = sql.NullString{String: username, Valid: true}
+	}
+
+	// An empty display name or bio clears it
+	if reqBody.DisplayName != nil {
+		if err := validateProfileText(*reqBody.DisplayName, maxDisplayNameLength); err != nil {
+			invalid.add("display_name", "display_name", err.Error())
+		}
+		params.DisplayName = sql.NullString{String: *reqBody.DisplayName, Valid: true}
+	}
+
+	if reqBody.Bio != nil {
+		if err := validateProfileText(*reqBody.Bio, maxBioLength); err != nil {
+			invalid.add("bio", "bio", err.Error())
+		}
+		params.Bio = sql.NullString{String: cleanProfanity(*reqBody.Bio), Valid: true}
+	}
+
+	changingCredentials := reqBody.Email != nil || reqBody.Password != nil
+	if changingCredentials && (reqBody.CurrentPassword == nil || *reqBody.CurrentPassword == "") {
+		invalid.add("current_password", "current_password", "is required to change the email or password")
+	}
+
+	if invalid.respond(w, r) {
 		return
 	}
 
+	if changingCredentials {
+		current, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+		err = cfg.checkUserPassword(current, *reqBody.CurrentPassword)
+		if err != nil {
+			respondWithErrorCode(w, r, http.StatusForbidden, errCodeInvalidCurrentPassword, "Current password is incorrect")
+			return
+		}
+	}
+
+	// Only hash the new password once the request is known to be valid
+	if reqBody.Password != nil {
+		hashedPassword, err := auth.HashPassword(*reqBody.Password, cfg.passwordHashing)
+		if err != nil {
+			respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		params.HashedPassword = sql.NullString{String: hashedPassword, Valid: true}
+	}
+
 	// Update the user in the database
-	dbUser, err := cfg.dbQueries.UpdateUser(r.Context(), database.UpdateUserParams{
-		ID:             userID,
-		Email:          reqBody.Email,
-		HashedPassword: hashedPassword,
-	})
+	dbUser, err := cfg.dbQueries.UpdateUser(r.Context(), params)
+	if isUniqueViolation(err, usernameConstraint) {
+		respondWithError(w, r, http.StatusConflict, "Username is already taken")
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) && params.ExpectedUpdatedAt.Valid {
+		cfg.respondWithUserConflict(w, r, userID)
+		return
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Something went wrong"})
+		respondWithDBError(w, r, err)
 		return
 	}
 
-	// Return updated user (without password)
-	user := User{
-		ID:          dbUser.ID,
-		CreatedAt:   dbUser.CreatedAt,
-		UpdatedAt:   dbUser.UpdatedAt,
-		Email:       dbUser.Email,
-		IsChirpyRed: dbUser.IsChirpyRed,
+	// A new password signs out every other session
+	if params.HashedPassword.Valid {
+		err = cfg.dbQueries.RevokeUserRefreshTokens(r.Context(), userID)
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+	}
+
+	if reqBody.Email != nil && *reqBody.Email != dbUser.Email {
+		dbUser, err = cfg.startEmailChange(r, userID, *reqBody.Email)
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
 	}
 
+	// Return updated user (without password)
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(user)
+	json.NewEncoder(w).Encode(userFromDB(dbUser))
 }
 
-func (cfg *apiConfig) handlerPolkaWebhook(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+// respondWithUserConflict answers a stale PUT /api/users with 409 and the
+// user's current record.
+func (cfg *apiConfig) respondWithUserConflict(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	current, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
 		return
 	}
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(UserConflictResponse{
+		Error:     "User was modified since updated_at; merge with current and retry",
+		RequestID: requestID(r.Context()),
+		Current:   userFromDB(current),
+	})
+}
 
+func (cfg *apiConfig) handlerPolkaWebhook(w http.ResponseWriter, r *http.Request) {
 	// Check API key authentication
 	apiKey, err := auth.GetAPIKey(r.Header)
 	if err != nil {
@@ -330,20 +654,15 @@ func (cfg *apiConfig) handlerPolkaWebhook(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	type webhookData struct {
-		UserID string `json:"user_id"`
-	}
-
-	type webhookRequest struct {
-		Event string      `json:"event"`
-		Data  webhookData `json:"data"`
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 
-	decoder := json.NewDecoder(r.Body)
-	reqBody := webhookRequest{}
-	err = decoder.Decode(&reqBody)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	reqBody := polkaWebhook{}
+	err = json.Unmarshal(body, &reqBody)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
@@ -355,21 +674,124 @@ func (cfg *apiConfig) handlerPolkaWebhook(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Parse the user ID
+	// Reject malformed deliveries now rather than retrying them later
 	userID, err := uuid.Parse(reqBody.Data.UserID)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	// Upgrade the user to Chirpy Red
-	err = cfg.dbQueries.UpgradeUserToChirpyRed(r.Context(), userID)
-	if err != nil {
-		// If user not found, return 404
+	// A 404 tells Polka the user does not exist and to stop; a 503 that
+	// the database is struggling and the delivery should be retried
+	_, err = cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		logf(r.Context(), "Error looking up user %s for a Polka upgrade: %v", userID, err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	// The upgrade itself happens in the webhook worker. Once the delivery
+	// is stored it will be retried until it succeeds, so Polka can stop
+	// resending it; a failure to store it asks Polka to try again.
+	_, err = cfg.dbQueries.CreateWebhookEvent(r.Context(), database.CreateWebhookEventParams{
+		Source:  webhookSourcePolka,
+		Event:   reqBody.Event,
+		Payload: body,
+	})
+	if err != nil {
+		logf(r.Context(), "Error storing the Polka upgrade of user %s: %v", userID, err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	cfg.webhooks.notify()
 
-	// Return 204 No Content on success
 	w.WriteHeader(http.StatusNoContent)
-}
\ No newline at end of file
+}
+
+func (cfg *apiConfig) handlerGetUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithLookupError(w, r, err, "User not found")
+		return
+	}
+	if dbUser.DeletedAt.Valid {
+		respondUserDeleted(w, r)
+		return
+	}
+
+	followerCount, err := cfg.dbQueries.CountFollowers(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	followingCount, err := cfg.dbQueries.CountFollowing(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	profile := UserProfile{
+		PublicUser: PublicUser{
+			ID:          dbUser.ID,
+			Email:       cfg.publicEmail(dbUser.Email),
+			Username:    dbUser.Username,
+			IsChirpyRed: dbUser.IsChirpyRed,
+		},
+		IsPrivate:      dbUser.IsPrivate,
+		FollowerCount:  followerCount,
+		FollowingCount: followingCount,
+	}
+
+	// Anyone may find a private account and ask to follow it, but its
+	// details are for approved followers only
+	visible, err := cfg.canView(r, userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+	if visible {
+		profile.DisplayName = nullableString(dbUser.DisplayName)
+		profile.Bio = nullableString(dbUser.Bio)
+		profile.AvatarURL = nullableString(dbUser.AvatarUrl)
+		profile.PinnedChirp, err = cfg.pinnedChirp(r, dbUser)
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profile)
+}
+
+// userFromDB converts a database user into its JSON representation, which
+// leaves out the password hash.
+func userFromDB(dbUser database.User) User {
+	return User{
+		ID:           dbUser.ID,
+		CreatedAt:    dbUser.CreatedAt,
+		UpdatedAt:    dbUser.UpdatedAt,
+		Email:        dbUser.Email,
+		Username:     dbUser.Username,
+		DisplayName:  nullableString(dbUser.DisplayName),
+		Bio:          nullableString(dbUser.Bio),
+		AvatarURL:    nullableString(dbUser.AvatarUrl),
+		IsChirpyRed:  dbUser.IsChirpyRed,
+		IsAdmin:      dbUser.IsAdmin,
+		IsPrivate:    dbUser.IsPrivate,
+		PendingEmail: nullableString(dbUser.PendingEmail),
+	}
+}
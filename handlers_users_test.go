@@ -0,0 +1,985 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/auth"
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/AlexTLDR/chirpy/internal/mail"
+	"github.com/google/uuid"
+)
+
+// fakeSender hands every message it is asked to send to sent.
+type fakeSender struct {
+	sent chan mail.Message
+	err  error
+}
+
+func newFakeSender() *fakeSender {
+	return &fakeSender{sent: make(chan mail.Message, 10)}
+}
+
+func (s *fakeSender) Send(ctx context.Context, msg mail.Message) error {
+	s.sent <- msg
+	return s.err
+}
+
+func (s *fakeSender) next(t *testing.T) mail.Message {
+	t.Helper()
+	select {
+	case msg := <-s.sent:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email was sent")
+		return mail.Message{}
+	}
+}
+
+// postUser sends a signup request with the given JSON payload.
+func postUser(t *testing.T, cfg *apiConfig, payload any) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/users", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	cfg.handlerCreateUser(rr, req)
+	return rr
+}
+
+// putUser sends an update request for the user behind token.
+func putUser(t *testing.T, cfg *apiConfig, token string, payload any) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPut, "/api/users", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	cfg.handlerUpdateUser(rr, req)
+	return rr
+}
+
+func createUser(t *testing.T, cfg *apiConfig, email string) *httptest.ResponseRecorder {
+	t.Helper()
+	return postUser(t, cfg, map[string]string{"email": email, "password": "password123"})
+}
+
+func decodeUser(t *testing.T, rr *httptest.ResponseRecorder) User {
+	t.Helper()
+	var user User
+	if err := json.NewDecoder(rr.Body).Decode(&user); err != nil {
+		t.Fatalf("Failed to decode user: %v", err)
+	}
+	return user
+}
+
+func TestCreateUserUsername(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+
+	rr := postUser(t, cfg, map[string]string{"email": "alice@example.com", "password": "password123", "username": "Alice_W"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	if user := decodeUser(t, rr); user.Username != "alice_w" {
+		t.Errorf("username = %q, want alice_w", user.Username)
+	}
+
+	// Without a username one is derived from the email, avoiding taken and
+	// reserved names
+	rr = createUser(t, cfg, "first.last@example.com")
+	if user := decodeUser(t, rr); rr.Code != http.StatusCreated || user.Username != "first_last" {
+		t.Errorf("derived username = %v %q, want first_last", rr.Code, user.Username)
+	}
+	for _, email := range []string{"alice_w@example.org", "admin@example.com"} {
+		rr = createUser(t, cfg, email)
+		base := defaultUsername(email)
+		user := decodeUser(t, rr)
+		if rr.Code != http.StatusCreated || !strings.HasPrefix(user.Username, base+"_") {
+			t.Errorf("username for %s = %v %q, want %s with a suffix", email, rr.Code, user.Username, base)
+		}
+	}
+
+	tests := []struct {
+		username string
+		code     int
+	}{
+		{"alice_w", http.StatusConflict},
+		{"ALICE_W", http.StatusConflict},
+		{"al", http.StatusBadRequest},
+		{"not-valid", http.StatusBadRequest},
+		{"admin", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rr := postUser(t, cfg, map[string]string{"email": "other@example.com", "password": "password123", "username": tt.username})
+		if rr.Code != tt.code {
+			t.Errorf("username %q: got status %v want %v", tt.username, rr.Code, tt.code)
+		}
+	}
+}
+
+func TestUpdateUsername(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	db.addUser(t, "bob@example.com")
+	token := makeTestToken(t, alice.ID)
+
+	tests := []struct {
+		name     string
+		username any
+		code     int
+		want     string
+	}{
+		{"omitted keeps the username", nil, http.StatusOK, "alice"},
+		{"changed", "Alice_2", http.StatusOK, "alice_2"},
+		{"unchanged is not a conflict", "alice_2", http.StatusOK, "alice_2"},
+		{"taken", "bob", http.StatusConflict, "alice_2"},
+		{"invalid", "a", http.StatusBadRequest, "alice_2"},
+		{"reserved", "root", http.StatusBadRequest, "alice_2"},
+	}
+	for _, tt := range tests {
+		payload := map[string]any{}
+		if tt.username != nil {
+			payload["username"] = tt.username
+		}
+		rr := putUser(t, cfg, token, payload)
+		if rr.Code != tt.code {
+			t.Errorf("%s: got status %v want %v", tt.name, rr.Code, tt.code)
+		}
+		user, err := db.GetUserByID(t.Context(), alice.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Username != tt.want {
+			t.Errorf("%s: username = %q, want %q", tt.name, user.Username, tt.want)
+		}
+	}
+}
+
+func TestUpdateUserStaleWrite(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	token := makeTestToken(t, alice.ID)
+
+	// Two clients load the same record
+	loaded := userFromDB(alice).UpdatedAt
+
+	rr := putUser(t, cfg, token, map[string]any{"bio": "first", "updated_at": loaded})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("first write: got status %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	first := decodeUser(t, rr)
+
+	// The second one is based on the record before the first write
+	rr = putUser(t, cfg, token, map[string]any{"bio": "second", "updated_at": loaded})
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("stale write: got status %v want %v", rr.Code, http.StatusConflict)
+	}
+	var conflict UserConflictResponse
+	if err := json.NewDecoder(rr.Body).Decode(&conflict); err != nil {
+		t.Fatal(err)
+	}
+	if conflict.Error == "" || conflict.Current.Bio == nil || *conflict.Current.Bio != "first" || !conflict.Current.UpdatedAt.Equal(first.UpdatedAt) {
+		t.Errorf("conflict = %+v, want the current record", conflict)
+	}
+	if user, _ := db.GetUserByID(t.Context(), alice.ID); user.Bio.String != "first" {
+		t.Errorf("bio = %q after a stale write, want %q", user.Bio.String, "first")
+	}
+
+	// Retrying on top of the current record succeeds
+	rr = putUser(t, cfg, token, map[string]any{"bio": "second", "updated_at": conflict.Current.UpdatedAt})
+	if rr.Code != http.StatusOK {
+		t.Errorf("merged write: got status %v want %v", rr.Code, http.StatusOK)
+	}
+
+	// Without updated_at the last write wins
+	rr = putUser(t, cfg, token, map[string]any{"bio": "third"})
+	if rr.Code != http.StatusOK {
+		t.Errorf("unguarded write: got status %v want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestUpdateCredentialsRequireCurrentPassword(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := decodeUser(t, postUser(t, cfg, map[string]string{"email": "alice@example.com", "password": "password123"}))
+	token := makeTestToken(t, user.ID)
+
+	login := func(email, password string) (refreshToken string, code int) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"email": email, "password": password})
+		rr := httptest.NewRecorder()
+		cfg.handlerLogin(rr, httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body)))
+		var resp struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp.RefreshToken, rr.Code
+	}
+	refreshToken, _ := login("alice@example.com", "password123")
+
+	// Missing
+	for _, payload := range []map[string]string{
+		{"password": "new-password"},
+		{"email": "mallory@example.com", "current_password": ""},
+	} {
+		checkFieldErrors(t, "missing current_password", putUser(t, cfg, token, payload), map[string]string{
+			"current_password": "is required to change the email or password",
+		})
+	}
+
+	// Wrong
+	rr := putUser(t, cfg, token, map[string]string{"email": "mallory@example.com", "current_password": "guess"})
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("wrong current_password: got status %v want %v", rr.Code, http.StatusForbidden)
+	}
+	var errResp ErrorResponse
+	json.NewDecoder(rr.Body).Decode(&errResp)
+	if errResp.Code != errCodeInvalidCurrentPassword {
+		t.Errorf("error code = %q, want %q", errResp.Code, errCodeInvalidCurrentPassword)
+	}
+	if stored, _ := db.GetUserByID(t.Context(), user.ID); stored.Email != "alice@example.com" {
+		t.Errorf("email = %q after a rejected change", stored.Email)
+	}
+
+	// Profile fields alone do not need it
+	if rr := putUser(t, cfg, token, map[string]string{"bio": "hello"}); rr.Code != http.StatusOK {
+		t.Errorf("bio change: got status %v want %v", rr.Code, http.StatusOK)
+	}
+
+	// Correct
+	rr = putUser(t, cfg, token, map[string]string{"password": "new-password", "current_password": "password123"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("password change: got status %v want %v", rr.Code, http.StatusOK)
+	}
+	if _, code := login("alice@example.com", "new-password"); code != http.StatusOK {
+		t.Errorf("login with the new password: got status %v want %v", code, http.StatusOK)
+	}
+	if _, err := db.GetUserFromRefreshToken(t.Context(), refreshToken); err == nil {
+		t.Error("refresh token issued before the password change still works")
+	}
+
+	// The new email waits to be confirmed
+	rr = putUser(t, cfg, token, map[string]string{"email": "alice@example.org", "current_password": "new-password"})
+	if updated := decodeUser(t, rr); rr.Code != http.StatusOK || updated.Email != "alice@example.com" || updated.PendingEmail == nil || *updated.PendingEmail != "alice@example.org" {
+		t.Errorf("email change: got status %v and %+v, want %v with the new email pending", rr.Code, updated, http.StatusOK)
+	}
+}
+
+func TestLoginStillUsesEmail(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	postUser(t, cfg, map[string]string{"email": "alice@example.com", "password": "password123", "username": "wonderland"})
+
+	login := func(email string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"email": email, "password": "password123"})
+		req := httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+		cfg.handlerLogin(rr, req)
+		return rr
+	}
+	rr := login("alice@example.com")
+	if user := decodeUser(t, rr); rr.Code != http.StatusOK || user.Username != "wonderland" {
+		t.Errorf("login by email = %v %q, want 200 with the username", rr.Code, user.Username)
+	}
+	if rr := login("wonderland"); rr.Code != http.StatusUnauthorized {
+		t.Errorf("login by username: got status %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestLoginUnknownEmailLooksLikeWrongPassword(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	postUser(t, cfg, map[string]string{"email": "alice@example.com", "password": "password123"})
+
+	var checked []string
+	checkPasswordHash = func(hash, password string) error {
+		checked = append(checked, hash)
+		return auth.CheckPasswordHash(hash, password)
+	}
+	t.Cleanup(func() { checkPasswordHash = auth.CheckPasswordHash })
+
+	login := func(email, password string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"email": email, "password": password})
+		rr := httptest.NewRecorder()
+		cfg.handlerLogin(rr, httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body)))
+		return rr
+	}
+
+	unknown := login("nobody@example.com", "password123")
+	if len(checked) != 1 || checked[0] != dummyPasswordHash(cfg.passwordHashing) {
+		t.Fatalf("unknown email checked hashes %q, want only the dummy hash", checked)
+	}
+	wrong := login("alice@example.com", "wrong-password")
+	if len(checked) != 2 || checked[1] == dummyPasswordHash(cfg.passwordHashing) {
+		t.Fatalf("wrong password checked hashes %q, want the account's hash", checked)
+	}
+
+	if unknown.Code != http.StatusUnauthorized || wrong.Code != http.StatusUnauthorized {
+		t.Errorf("got status %v and %v, want %v for both", unknown.Code, wrong.Code, http.StatusUnauthorized)
+	}
+	if !bytes.Equal(unknown.Body.Bytes(), wrong.Body.Bytes()) {
+		t.Errorf("responses differ: %s vs %s", unknown.Body, wrong.Body)
+	}
+}
+
+// rehashCountingStore counts the password hashes logins upgrade.
+type rehashCountingStore struct {
+	*fakeStore
+	rehashes int
+}
+
+func (s *rehashCountingStore) RehashUserPassword(ctx context.Context, arg database.RehashUserPasswordParams) (int64, error) {
+	s.rehashes++
+	return s.fakeStore.RehashUserPassword(ctx, arg)
+}
+
+func TestLoginRehashesPassword(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.passwordHashing.BcryptCost = auth.MinPasswordCost
+	alice := decodeUser(t, postUser(t, cfg, map[string]string{"email": "alice@example.com", "password": "password123"}))
+	counting := &rehashCountingStore{fakeStore: db}
+	cfg.dbQueries = counting
+
+	login := func(password string) int {
+		body, _ := json.Marshal(map[string]string{"email": "alice@example.com", "password": password})
+		rr := httptest.NewRecorder()
+		cfg.handlerLogin(rr, httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body)))
+		return rr.Code
+	}
+	storedCost := func() int {
+		user, _ := db.GetUserByID(t.Context(), alice.ID)
+		cost, err := auth.PasswordHashCost(user.HashedPassword.String)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cost
+	}
+
+	// Nothing to upgrade at the cost the hash was made with
+	if code := login("password123"); code != http.StatusOK || counting.rehashes != 0 {
+		t.Fatalf("login at the same cost: got status %v and %d rehashes", code, counting.rehashes)
+	}
+
+	cfg.passwordHashing.BcryptCost = auth.MinPasswordCost + 1
+	if code := login("wrong-password"); code != http.StatusUnauthorized || counting.rehashes != 0 {
+		t.Fatalf("failed login: got status %v and %d rehashes", code, counting.rehashes)
+	}
+	// The old hash still logs in, and is upgraded as it does
+	if code := login("password123"); code != http.StatusOK {
+		t.Fatalf("login with the old hash: got status %v want %v", code, http.StatusOK)
+	}
+	if counting.rehashes != 1 || storedCost() != cfg.passwordHashing.BcryptCost {
+		t.Fatalf("after the login: %d rehashes and a hash of cost %d, want 1 and %d", counting.rehashes, storedCost(), cfg.passwordHashing.BcryptCost)
+	}
+	// The new hash logs in without being redone
+	if code := login("password123"); code != http.StatusOK || counting.rehashes != 1 {
+		t.Errorf("login with the new hash: got status %v and %d rehashes, want %v and 1", code, counting.rehashes, http.StatusOK)
+	}
+}
+
+func TestLoginMigratesToArgon2id(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.passwordHashing.BcryptCost = auth.MinPasswordCost
+	cfg.passwordHashing.Argon2 = auth.Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1}
+	alice := decodeUser(t, postUser(t, cfg, map[string]string{"email": "alice@example.com", "password": "password123"}))
+	counting := &rehashCountingStore{fakeStore: db}
+	cfg.dbQueries = counting
+
+	login := func(password string) int {
+		body, _ := json.Marshal(map[string]string{"email": "alice@example.com", "password": password})
+		rr := httptest.NewRecorder()
+		cfg.handlerLogin(rr, httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body)))
+		return rr.Code
+	}
+	storedHash := func() string {
+		user, _ := db.GetUserByID(t.Context(), alice.ID)
+		return user.HashedPassword.String
+	}
+
+	cfg.passwordHashing.Algorithm = auth.AlgorithmArgon2id
+	if code := login("password123"); code != http.StatusOK {
+		t.Fatalf("login with the bcrypt hash: got status %v want %v", code, http.StatusOK)
+	}
+	argon2Hash := storedHash()
+	if counting.rehashes != 1 || !strings.HasPrefix(argon2Hash, "$argon2id$") {
+		t.Fatalf("after the login: %d rehashes and hash %q, want 1 and an argon2id hash", counting.rehashes, argon2Hash)
+	}
+	if code := login("password123"); code != http.StatusOK || counting.rehashes != 1 || storedHash() != argon2Hash {
+		t.Errorf("login with the argon2id hash: got status %v and %d rehashes, want %v and 1", code, counting.rehashes, http.StatusOK)
+	}
+	if code := login("wrong-password"); code != http.StatusUnauthorized {
+		t.Errorf("wrong password against the argon2id hash: got status %v want %v", code, http.StatusUnauthorized)
+	}
+
+	// New passwords are hashed with argon2id straight away
+	bob := decodeUser(t, postUser(t, cfg, map[string]string{"email": "bob@example.com", "password": "password123"}))
+	if user, _ := db.GetUserByID(t.Context(), bob.ID); !strings.HasPrefix(user.HashedPassword.String, "$argon2id$") {
+		t.Errorf("new user's hash = %q, want argon2id", user.HashedPassword.String)
+	}
+
+	// Going back to bcrypt keeps everyone able to log in
+	cfg.passwordHashing.Algorithm = auth.AlgorithmBcrypt
+	if code := login("password123"); code != http.StatusOK || counting.rehashes != 2 || strings.HasPrefix(storedHash(), "$argon2id$") {
+		t.Errorf("login after switching back: got status %v and %d rehashes, want %v and 2", code, counting.rehashes, http.StatusOK)
+	}
+}
+
+func TestRefreshTokenRotationAndReuse(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	postUser(t, cfg, map[string]string{"email": "alice@example.com", "password": "password123"})
+
+	body, _ := json.Marshal(map[string]string{"email": "alice@example.com", "password": "password123"})
+	rr := httptest.NewRecorder()
+	cfg.handlerLogin(rr, httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body)))
+	var loggedIn struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	json.NewDecoder(rr.Body).Decode(&loggedIn)
+
+	refresh := func(token string) (refreshToken string, code int) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/refresh", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		cfg.handlerRefresh(rr, req)
+		var resp struct {
+			Token        string `json:"token"`
+			RefreshToken string `json:"refresh_token"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		if rr.Code == http.StatusOK && (resp.Token == "" || resp.RefreshToken == "") {
+			t.Fatalf("refresh response is missing a token: %+v", resp)
+		}
+		return resp.RefreshToken, rr.Code
+	}
+
+	// Each refresh hands out a new token in the same family
+	first := loggedIn.RefreshToken
+	second, code := refresh(first)
+	if code != http.StatusOK || second == first {
+		t.Fatalf("refresh = %v %q, want 200 with a new refresh token", code, second)
+	}
+	third, code := refresh(second)
+	if code != http.StatusOK {
+		t.Fatalf("second refresh: got status %v want %v", code, http.StatusOK)
+	}
+	rt, _ := db.GetRefreshToken(t.Context(), third)
+	parent, _ := db.GetRefreshToken(t.Context(), second)
+	if !rt.ParentToken.Valid || rt.ParentToken.String != second || rt.FamilyID != parent.FamilyID {
+		t.Errorf("rotated token = %+v, want a child of %q in family %s", rt, second, parent.FamilyID)
+	}
+
+	// Replaying an old token revokes the whole family, latest token included
+	if _, code := refresh(first); code != http.StatusUnauthorized {
+		t.Fatalf("replayed token: got status %v want %v", code, http.StatusUnauthorized)
+	}
+	if _, code := refresh(third); code != http.StatusUnauthorized {
+		t.Errorf("latest token after a replay: got status %v want %v", code, http.StatusUnauthorized)
+	}
+	if got := cfg.refreshTokenReuse.Load(); got != 2 {
+		t.Errorf("reuse metric = %d, want 2", got)
+	}
+	entries, _ := db.GetAuditLog(t.Context(), database.GetAuditLogParams{Limit: 10})
+	if len(entries) == 0 || entries[0].Action != auditRefreshTokenReuse || entries[0].Target != rt.FamilyID.String() {
+		t.Errorf("audit log = %+v, want a reuse entry for the family", entries)
+	}
+
+	// Unknown tokens are rejected without counting as reuse
+	if _, code := refresh("never-issued"); code != http.StatusUnauthorized {
+		t.Errorf("unknown token: got status %v want %v", code, http.StatusUnauthorized)
+	}
+	if got := cfg.refreshTokenReuse.Load(); got != 2 {
+		t.Errorf("reuse metric after an unknown token = %d, want 2", got)
+	}
+}
+
+func TestLoginRememberMe(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.refreshTokenTTL = refreshTokenTTL{Session: time.Hour, RememberMe: 100 * time.Hour}
+	postUser(t, cfg, map[string]string{"email": "alice@example.com", "password": "password123"})
+
+	type tokens struct {
+		RefreshToken          string    `json:"refresh_token"`
+		RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+	}
+	login := func(rememberMe bool) tokens {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{"email": "alice@example.com", "password": "password123", "remember_me": rememberMe})
+		rr := httptest.NewRecorder()
+		cfg.handlerLogin(rr, httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body)))
+		var resp tokens
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp
+	}
+	refresh := func(token string) tokens {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/refresh", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		cfg.handlerRefresh(rr, req)
+		var resp tokens
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp
+	}
+	// checkExpiry checks the stored expiry is lifetime from now and matches
+	// what the response reported.
+	checkExpiry := func(name string, got tokens, lifetime time.Duration) {
+		t.Helper()
+		stored, err := db.GetRefreshToken(t.Context(), got.RefreshToken)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if d := time.Until(stored.ExpiresAt); d < lifetime-time.Minute || d > lifetime {
+			t.Errorf("%s: token expires in %v, want %v", name, d, lifetime)
+		}
+		if !got.RefreshTokenExpiresAt.Equal(stored.ExpiresAt) {
+			t.Errorf("%s: response says %v, stored %v", name, got.RefreshTokenExpiresAt, stored.ExpiresAt)
+		}
+	}
+
+	session := login(false)
+	checkExpiry("session login", session, time.Hour)
+	remembered := login(true)
+	checkExpiry("remembered login", remembered, 100*time.Hour)
+
+	// Rotation keeps the lifetime chosen at login
+	checkExpiry("session refresh", refresh(session.RefreshToken), time.Hour)
+	checkExpiry("remembered refresh", refresh(remembered.RefreshToken), 100*time.Hour)
+}
+
+func TestTokenResponseShape(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	cfg.accessTokenTTL = 15 * time.Minute
+	postUser(t, cfg, map[string]string{"email": "alice@example.com", "password": "password123"})
+
+	// check decodes a login or refresh response, checking the OAuth fields
+	// agree with the original ones and the configured lifetime.
+	check := func(name string, rr *httptest.ResponseRecorder) map[string]any {
+		t.Helper()
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: got status %v want %v", name, rr.Code, http.StatusOK)
+		}
+		var resp map[string]any
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, field := range []string{"token", "refresh_token", "refresh_token_expires_at"} {
+			if _, ok := resp[field].(string); !ok {
+				t.Errorf("%s: %q = %v, want the field kept", name, field, resp[field])
+			}
+		}
+		token, _ := resp["token"].(string)
+		if resp["access_token"] != token {
+			t.Errorf("%s: access_token = %v, want the token %q", name, resp["access_token"], token)
+		}
+		if resp["token_type"] != "Bearer" {
+			t.Errorf("%s: token_type = %v, want Bearer", name, resp["token_type"])
+		}
+		if resp["expires_in"] != float64(900) {
+			t.Errorf("%s: expires_in = %v, want 900", name, resp["expires_in"])
+		}
+		if _, err := auth.ValidateJWT(token, testJWTSecret); err != nil {
+			t.Errorf("%s: token does not validate: %v", name, err)
+		}
+		return resp
+	}
+
+	body, _ := json.Marshal(map[string]string{"email": "alice@example.com", "password": "password123"})
+	rr := httptest.NewRecorder()
+	cfg.handlerLogin(rr, httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body)))
+	login := check("login", rr)
+	if login["email"] != "alice@example.com" {
+		t.Errorf("login: email = %v, want the user kept alongside the tokens", login["email"])
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/refresh", nil)
+	req.Header.Set("Authorization", "Bearer "+login["refresh_token"].(string))
+	rr = httptest.NewRecorder()
+	cfg.handlerRefresh(rr, req)
+	check("refresh", rr)
+}
+
+func TestLoginScopes(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	mux := newTestMux(t, cfg, false)
+	postUser(t, cfg, map[string]string{"email": "dash@example.com", "password": "password123"})
+	user, _ := db.GetUserByEmail(t.Context(), "dash@example.com")
+
+	rr := apiKeyRequest(t, mux, http.MethodPost, "/api/v1/login", "", map[string]any{
+		"email": "dash@example.com", "password": "password123", "scopes": []string{auth.ScopeChirpsRead},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("login returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	var login struct {
+		Token        string   `json:"token"`
+		RefreshToken string   `json:"refresh_token"`
+		Scopes       []string `json:"scopes"`
+	}
+	json.NewDecoder(rr.Body).Decode(&login)
+	if len(login.Scopes) != 1 || login.Scopes[0] != auth.ScopeChirpsRead {
+		t.Errorf("login scopes = %v, want [%s]", login.Scopes, auth.ScopeChirpsRead)
+	}
+
+	// checkScoped checks a read-only token can read chirps but not write
+	checkScoped := func(name, token string) {
+		t.Helper()
+		bearer := "Bearer " + token
+		if rr := apiKeyRequest(t, mux, http.MethodGet, "/api/v1/chirps", bearer, nil); rr.Code != http.StatusOK {
+			t.Errorf("%s: reading chirps got status %v want %v", name, rr.Code, http.StatusOK)
+		}
+		for _, tt := range []struct{ method, path, scope string }{
+			{http.MethodPost, "/api/v1/chirps", auth.ScopeChirpsWrite},
+			{http.MethodPut, "/api/v1/users", auth.ScopeUsersWrite},
+			{http.MethodPost, "/api/v1/api_keys", auth.ScopeUsersWrite},
+		} {
+			rr := apiKeyRequest(t, mux, tt.method, tt.path, bearer, map[string]string{"body": "hi", "label": "x"})
+			var got ErrorResponse
+			json.NewDecoder(rr.Body).Decode(&got)
+			if rr.Code != http.StatusForbidden || got.Code != errCodeInsufficientScope || got.Scope != tt.scope {
+				t.Errorf("%s: %s %s = %v %+v, want 403 missing %s", name, tt.method, tt.path, rr.Code, got, tt.scope)
+			}
+		}
+	}
+	checkScoped("login", login.Token)
+
+	// Refreshing keeps the scopes
+	rr = apiKeyRequest(t, mux, http.MethodPost, "/api/v1/refresh", "Bearer "+login.RefreshToken, nil)
+	json.NewDecoder(rr.Body).Decode(&login)
+	checkScoped("refresh", login.Token)
+	if len(db.chirps) != 0 {
+		t.Errorf("read-only tokens created %d chirps", len(db.chirps))
+	}
+
+	// Tokens issued without scopes keep full access
+	rr = apiKeyRequest(t, mux, http.MethodPost, "/api/v1/chirps", "Bearer "+makeTestToken(t, user.ID), map[string]string{"body": "hi"})
+	if rr.Code != http.StatusCreated {
+		t.Errorf("unscoped token: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+
+	for name, scopes := range map[string][]string{
+		"no scopes":     {},
+		"unknown scope": {auth.ScopeChirpsRead, "chirps:everything"},
+	} {
+		rr := apiKeyRequest(t, mux, http.MethodPost, "/api/v1/login", "", map[string]any{
+			"email": "dash@example.com", "password": "password123", "scopes": scopes,
+		})
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %v want %v", name, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestCreateUserSendsWelcomeEmail(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	sender := newFakeSender()
+	cfg.mailer = sender
+
+	if rr := createUser(t, cfg, "new@example.com"); rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	msg := sender.next(t)
+	if msg.To != "new@example.com" {
+		t.Errorf("welcome email sent to %q, want new@example.com", msg.To)
+	}
+	if msg.Text == "" || msg.HTML == "" {
+		t.Error("welcome email should have both a plain-text and an HTML body")
+	}
+}
+
+func TestCreateUserSucceedsWhenEmailFails(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	sender := newFakeSender()
+	sender.err = errors.New("connection refused")
+	cfg.mailer = sender
+
+	if rr := createUser(t, cfg, "new@example.com"); rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	sender.next(t)
+}
+
+func TestCreateUserDuplicateEmail(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	createUser(t, cfg, "taken@example.com")
+
+	rr := postUser(t, cfg, map[string]string{"email": "taken@example.com", "password": "password123", "username": "someone_else"})
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("duplicate email: got status %v want %v", rr.Code, http.StatusConflict)
+	}
+	if !strings.Contains(rr.Body.String(), "Email is already registered") {
+		t.Errorf("duplicate email error = %s", rr.Body)
+	}
+}
+
+func TestSignupPrivacyMode(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.signupPrivacy = true
+	sender := newFakeSender()
+	cfg.mailer = sender
+
+	fresh := postUser(t, cfg, map[string]string{"email": "new@example.com", "password": "password123"})
+	if msg := sender.next(t); msg.To != "new@example.com" || msg.Subject != "Welcome to Chirpy" {
+		t.Errorf("new signup sent %q to %s, want the welcome email", msg.Subject, msg.To)
+	}
+	taken := postUser(t, cfg, map[string]string{"email": "new@example.com", "password": "another-password", "username": "impostor"})
+	if msg := sender.next(t); msg.To != "new@example.com" || msg.Subject == "Welcome to Chirpy" {
+		t.Errorf("signup with a taken email sent %q to %s, want the account exists email", msg.Subject, msg.To)
+	}
+
+	// Clients cannot tell the two apart
+	if fresh.Code != http.StatusAccepted || taken.Code != http.StatusAccepted {
+		t.Fatalf("got status %v and %v, want %v for both", fresh.Code, taken.Code, http.StatusAccepted)
+	}
+	if !bytes.Equal(fresh.Body.Bytes(), taken.Body.Bytes()) {
+		t.Errorf("responses differ: %s vs %s", fresh.Body, taken.Body)
+	}
+	var accepted SignupAcceptedResponse
+	if err := json.Unmarshal(fresh.Body.Bytes(), &accepted); err != nil || accepted.Message == "" {
+		t.Errorf("body = %s, want a generic message", fresh.Body)
+	}
+
+	// The existing account is untouched
+	user, err := db.GetUserByEmail(t.Context(), "new@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.CheckPasswordHash(user.HashedPassword.String, "password123"); err != nil || user.Username == "impostor" {
+		t.Errorf("existing account was changed: %+v", user)
+	}
+
+	// Invalid requests and taken usernames still say so; neither depends on
+	// whether the email is registered
+	if rr := postUser(t, cfg, map[string]string{"email": "other@example.com"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("missing password: got status %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	if rr := postUser(t, cfg, map[string]string{"email": "other@example.com", "password": "password123", "username": user.Username}); rr.Code != http.StatusConflict {
+		t.Errorf("taken username: got status %v want %v", rr.Code, http.StatusConflict)
+	}
+}
+
+func TestSignupHoneypot(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	sender := newFakeSender()
+	cfg.mailer = sender
+	bot := map[string]string{"email": "bot@example.com", "password": "password123", "website": "https://spam.example.com"}
+
+	// Without the honeypot the field is ignored
+	if rr := postUser(t, cfg, bot); rr.Code != http.StatusCreated {
+		t.Fatalf("honeypot off: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+	sender.next(t)
+
+	cfg.signupHoneypot = true
+	bot["email"] = "bot2@example.com"
+	rr := postUser(t, cfg, bot)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("filled honeypot: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+	if user := decodeUser(t, rr); user.Email != "bot2@example.com" || user.Username != "bot2" || user.ID == uuid.Nil {
+		t.Errorf("filled honeypot: got %+v, want a plausible new user", user)
+	}
+	if _, err := db.GetUserByEmail(t.Context(), "bot2@example.com"); err == nil {
+		t.Error("filled honeypot: a user was created")
+	}
+
+	// In privacy mode it gets the same 202 as everyone else
+	cfg.signupPrivacy = true
+	if rr := postUser(t, cfg, bot); rr.Code != http.StatusAccepted {
+		t.Errorf("filled honeypot in privacy mode: got status %v want %v", rr.Code, http.StatusAccepted)
+	}
+	cfg.signupPrivacy = false
+
+	// People leave it empty and sign up as usual
+	if rr := postUser(t, cfg, map[string]string{"email": "alice@example.com", "password": "password123", "website": ""}); rr.Code != http.StatusCreated {
+		t.Fatalf("empty honeypot: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+	if msg := sender.next(t); msg.To != "alice@example.com" {
+		t.Errorf("sent an email to %s, want only alice's welcome", msg.To)
+	}
+	if _, err := db.GetUserByEmail(t.Context(), "alice@example.com"); err != nil {
+		t.Errorf("empty honeypot: the user was not created: %v", err)
+	}
+}
+
+func TestProfileFields(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	rr := postUser(t, cfg, map[string]string{
+		"email":        "alice@example.com",
+		"password":     "password123",
+		"display_name": "Alice W.",
+		"bio":          "Down the rabbit hole, what a kerfuffle",
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	user := decodeUser(t, rr)
+	if user.DisplayName == nil || *user.DisplayName != "Alice W." {
+		t.Errorf("display_name = %v, want Alice W.", user.DisplayName)
+	}
+	if user.Bio == nil || *user.Bio != "Down the rabbit hole, what a ****" {
+		t.Errorf("bio = %v, want it cleaned", user.Bio)
+	}
+
+	// Unset fields are null rather than empty strings
+	rr = createUser(t, cfg, "bob@example.com")
+	var raw map[string]any
+	json.NewDecoder(rr.Body).Decode(&raw)
+	if v, ok := raw["display_name"]; !ok || v != nil {
+		t.Errorf("display_name = %v, want null", v)
+	}
+
+	profile, _ := getProfile(t, cfg, user.ID.String())
+	if profile.DisplayName == nil || *profile.DisplayName != "Alice W." || profile.Bio == nil {
+		t.Errorf("public profile = %+v, want the display name and bio", profile.PublicUser)
+	}
+
+	token := makeTestToken(t, user.ID)
+	stored := func() database.User {
+		t.Helper()
+		u, err := db.GetUserByID(t.Context(), user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	before := stored()
+
+	// Partial updates leave everything else alone
+	if rr := putUser(t, cfg, token, map[string]string{"bio": "New bio"}); rr.Code != http.StatusOK {
+		t.Fatalf("bio update: got status %v want %v", rr.Code, http.StatusOK)
+	}
+	after := stored()
+	if after.Bio.String != "New bio" || after.DisplayName.String != "Alice W." ||
+		after.Email != before.Email || after.HashedPassword != before.HashedPassword || after.Username != before.Username {
+		t.Errorf("after a bio-only update: %+v", after)
+	}
+
+	// An empty value clears the field
+	putUser(t, cfg, token, map[string]string{"display_name": ""})
+	if after := stored(); after.DisplayName.Valid || !after.Bio.Valid {
+		t.Errorf("after clearing the display name: %+v", after)
+	}
+}
+
+func TestProfileFieldValidation(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	alice := db.addUser(t, "alice@example.com")
+	token := makeTestToken(t, alice.ID)
+
+	tests := []struct {
+		name  string
+		field string
+		value string
+		ok    bool
+	}{
+		{"display name at the limit", "display_name", strings.Repeat("é", 50), true},
+		{"display name too long", "display_name", strings.Repeat("a", 51), false},
+		{"display name with a newline", "display_name", "Alice\nW.", false},
+		{"bio at the limit", "bio", strings.Repeat("b", 200), true},
+		{"bio too long", "bio", strings.Repeat("b", 201), false},
+		{"bio with a NUL", "bio", "hi\x00there", false},
+		{"bio with an escape sequence", "bio", "\x1b[31mred", false},
+	}
+	for _, tt := range tests {
+		for _, rr := range []*httptest.ResponseRecorder{
+			postUser(t, cfg, map[string]string{"email": "new@example.com", "password": "password123", tt.field: tt.value}),
+			putUser(t, cfg, token, map[string]string{tt.field: tt.value}),
+		} {
+			if tt.ok {
+				if rr.Code != http.StatusOK && rr.Code != http.StatusCreated {
+					t.Errorf("%s: got status %v, want success", tt.name, rr.Code)
+				}
+				continue
+			}
+			var errResp ErrorResponse
+			json.NewDecoder(rr.Body).Decode(&errResp)
+			if rr.Code != http.StatusBadRequest || !strings.HasPrefix(errResp.Error, tt.field+" ") {
+				t.Errorf("%s: got %v %q, want 400 naming %s", tt.name, rr.Code, errResp.Error, tt.field)
+			}
+		}
+		db.mu.Lock()
+		db.users = db.users[:1]
+		db.mu.Unlock()
+	}
+}
+
+func TestTokenTypeMisuse(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+
+	credentials := map[string]string{"email": "alice@example.com", "password": "hunter22"}
+	call(t, srv, http.MethodPost, "/api/v1/users", "", credentials, nil)
+	var login struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	call(t, srv, http.MethodPost, "/api/v1/login", "", credentials, &login)
+
+	tests := []struct {
+		name, method, path, token string
+		body                      any
+		wantCode                  string
+	}{
+		{"access token to refresh", http.MethodPost, "/api/v1/refresh", login.Token, nil, errCodeExpectedRefreshToken},
+		{"access token to revoke", http.MethodPost, "/api/v1/revoke", login.Token, nil, errCodeExpectedRefreshToken},
+		{"refresh token to create a chirp", http.MethodPost, "/api/v1/chirps", login.RefreshToken, map[string]string{"body": "Hello"}, errCodeExpectedAccessToken},
+		{"refresh token to update the user", http.MethodPut, "/api/v1/users", login.RefreshToken, map[string]string{"username": "alice2"}, errCodeExpectedAccessToken},
+		{"forged access token", http.MethodPost, "/api/v1/chirps", login.Token + "x", map[string]string{"body": "Hello"}, errCodeUnauthorized},
+	}
+	for _, tt := range tests {
+		var errResp ErrorResponse
+		resp := call(t, srv, tt.method, tt.path, tt.token, tt.body, &errResp)
+		if resp.StatusCode != http.StatusUnauthorized || errResp.Code != tt.wantCode {
+			t.Errorf("%s: got status %v and %+v, want a 401 with code %q", tt.name, resp.StatusCode, errResp, tt.wantCode)
+		}
+	}
+
+	// Neither mistake used the tokens up
+	var refreshed struct {
+		Token string `json:"token"`
+	}
+	if resp := call(t, srv, http.MethodPost, "/api/v1/refresh", login.RefreshToken, nil, &refreshed); resp.StatusCode != http.StatusOK {
+		t.Errorf("refresh: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if resp := call(t, srv, http.MethodPost, "/api/v1/chirps", refreshed.Token, map[string]string{"body": "Hello"}, nil); resp.StatusCode != http.StatusCreated {
+		t.Errorf("chirp with the new access token: got status %v want %v", resp.StatusCode, http.StatusCreated)
+	}
+}
+
+func TestJWTSecretRotation(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	user := db.addUser(t, "alice@example.com")
+	before := makeTestToken(t, user.ID)
+
+	// Rotate in a new secret, keeping the old one to validate with
+	cfg.jwtSecrets = []string{"rotated-secret", testJWTSecret}
+	after, err := cfg.makeAccessToken(user.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.ValidateJWT(after.Token, "rotated-secret"); err != nil {
+		t.Fatalf("new tokens are not signed with the first secret: %v", err)
+	}
+	for name, token := range map[string]string{"token from before": before, "token from after": after.Token} {
+		if resp := call(t, srv, http.MethodGet, "/api/users/me/logins", token, nil, nil); resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: got status %v want %v", name, resp.StatusCode, http.StatusOK)
+		}
+	}
+
+	// Once the old secret is dropped its tokens stop working
+	cfg.jwtSecrets = []string{"rotated-secret"}
+	if resp := call(t, srv, http.MethodGet, "/api/users/me/logins", before, nil, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("token signed with a dropped secret: got status %v want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// middlewareHEAD answers HEAD requests with the headers the same GET would
+// get and no body. ServeMux already routes HEAD to GET patterns and the
+// server drops the body, but it only reports Content-Length when the whole
+// body fits in its write buffer; this counts the body instead, and keeps it
+// from being written at all.
+func middlewareHEAD(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		hw := &headResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(hw, r)
+		hw.commit(true)
+	})
+}
+
+// headResponseWriter discards the body of a HEAD response and holds the
+// headers back until the handler returns, when the body's length is known.
+type headResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	committed   bool
+	length      int
+}
+
+func (h *headResponseWriter) WriteHeader(status int) {
+	if h.committed {
+		return
+	}
+	// Informational responses go straight out; only the final status waits
+	if status >= 100 && status < 200 {
+		h.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if !h.wroteHeader {
+		h.status = status
+		h.wroteHeader = true
+	}
+}
+
+func (h *headResponseWriter) Write(p []byte) (int, error) {
+	h.length += len(p)
+	return len(p), nil
+}
+
+// Flush sends the headers without a length, so streaming handlers are not
+// held back until they return.
+func (h *headResponseWriter) Flush() {
+	h.commit(false)
+	if f, ok := h.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (h *headResponseWriter) Unwrap() http.ResponseWriter {
+	return h.ResponseWriter
+}
+
+// commit writes the held status and headers once. When final, the body is
+// complete and its length is reported unless the handler set one itself.
+func (h *headResponseWriter) commit(final bool) {
+	if h.committed {
+		return
+	}
+	h.committed = true
+
+	bodyAllowed := h.status != http.StatusNoContent && h.status != http.StatusNotModified
+	if final && bodyAllowed && h.Header().Get("Content-Length") == "" {
+		h.Header().Set("Content-Length", strconv.Itoa(h.length))
+	}
+	h.ResponseWriter.WriteHeader(h.status)
+}
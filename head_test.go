@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/AlexTLDR/chirpy/internal/config"
+)
+
+func TestHEAD(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	admin := db.addAdmin(t, "admin@example.com")
+	chirp := db.addChirp(t, admin.ID, "Hello")
+	// Enough chirps that the list outgrows the server's write buffer
+	for range 40 {
+		db.addChirp(t, admin.ID, strings.Repeat("a", 140))
+	}
+	token := makeTestToken(t, admin.ID)
+	srv := newTestServer(t, cfg)
+
+	do := func(method, path string) (*http.Response, []byte) {
+		t.Helper()
+		req, err := http.NewRequestWithContext(t.Context(), method, srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+
+	for _, path := range []string{"/api/v1/healthz", "/api/v1/chirps", "/api/v1/chirps/" + chirp.ID.String(), "/admin/metrics"} {
+		get, getBody := do(http.MethodGet, path)
+		head, headBody := do(http.MethodHead, path)
+
+		if head.StatusCode != get.StatusCode || head.StatusCode != http.StatusOK {
+			t.Errorf("HEAD %s: got status %v, GET got %v", path, head.StatusCode, get.StatusCode)
+			continue
+		}
+		if len(headBody) != 0 {
+			t.Errorf("HEAD %s: got a %d byte body", path, len(headBody))
+		}
+		for _, name := range []string{"Content-Type", "ETag"} {
+			if head.Header.Get(name) != get.Header.Get(name) {
+				t.Errorf("HEAD %s: %s = %q, GET has %q", path, name, head.Header.Get(name), get.Header.Get(name))
+			}
+		}
+		// The metrics count requests, so only their size can change
+		if path == "/admin/metrics" {
+			if head.Header.Get("Content-Length") == "" {
+				t.Errorf("HEAD %s: no Content-Length", path)
+			}
+			continue
+		}
+		if want := strconv.Itoa(len(getBody)); head.Header.Get("Content-Length") != want {
+			t.Errorf("HEAD %s: Content-Length = %q, want the GET body's %s", path, head.Header.Get("Content-Length"), want)
+		}
+	}
+
+	// The body is dropped before it reaches the server, too
+	rr := httptest.NewRecorder()
+	newServer(cfg, config.Default()).ServeHTTP(rr, httptest.NewRequest(http.MethodHead, "/api/v1/chirps/"+chirp.ID.String(), nil))
+	if rr.Code != http.StatusOK || rr.Body.Len() != 0 || rr.Header().Get("ETag") == "" {
+		t.Errorf("HEAD through newServer: got status %v, %d byte body and ETag %q", rr.Code, rr.Body.Len(), rr.Header().Get("ETag"))
+	}
+
+	// HEAD is only answered where GET is
+	if resp, _ := do(http.MethodHead, "/api/v1/login"); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("HEAD /api/v1/login: got status %v want %v", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
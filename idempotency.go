@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+	idempotencyKeyTTL    = 24 * time.Hour
+)
+
+// idempotentRequest is a claimed Idempotency-Key. The handler either
+// completes it with the response to replay or, when it fails, lets
+// abandon release the key so the client's retry runs again.
+type idempotentRequest struct {
+	cfg       *apiConfig
+	userID    uuid.UUID
+	key       string
+	completed bool
+}
+
+// beginIdempotentRequest looks at the request's Idempotency-Key header.
+// Without one it returns nil and true and the handler proceeds as usual. A
+// new key is claimed for userID, fingerprinted by params, the decoded
+// request body. A key seen before is answered here: its stored response is
+// replayed, or 409 returned if it came with different params or is still
+// being processed. It returns false once it has written a response.
+func (cfg *apiConfig) beginIdempotentRequest(w http.ResponseWriter, r *http.Request, userID uuid.UUID, params any) (*idempotentRequest, bool) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" {
+		return nil, true
+	}
+	if len(key) > maxIdempotencyKeyLen {
+		respondWithError(w, r, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+		return nil, false
+	}
+
+	fingerprint, err := json.Marshal(params)
+	if err != nil {
+		logf(r.Context(), "Fingerprinting the request for its Idempotency-Key: %v", err)
+		respondWithError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return nil, false
+	}
+	sum := sha256.Sum256(fingerprint)
+	requestHash := hex.EncodeToString(sum[:])
+
+	_, err = cfg.dbQueries.ClaimIdempotencyKey(r.Context(), database.ClaimIdempotencyKeyParams{
+		UserID:      userID,
+		Key:         key,
+		ExpiresAt:   time.Now().UTC().Add(idempotencyKeyTTL),
+		RequestHash: requestHash,
+	})
+	if err == nil {
+		return &idempotentRequest{cfg: cfg, userID: userID, key: key}, true
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		respondWithDBError(w, r, err)
+		return nil, false
+	}
+
+	previous, err := cfg.dbQueries.GetIdempotencyKey(r.Context(), database.GetIdempotencyKeyParams{
+		UserID: userID,
+		Key:    key,
+	})
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return nil, false
+	}
+	if previous.RequestHash != requestHash {
+		respondWithError(w, r, http.StatusConflict, "Idempotency-Key was already used for a different request")
+		return nil, false
+	}
+	if !previous.Response.Valid {
+		respondWithError(w, r, http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+		return nil, false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(previous.Response.String))
+	return nil, false
+}
+
+// complete stores body as the response replayed for the key. A nil
+// request, from a call without a key, ignores it.
+func (req *idempotentRequest) complete(r *http.Request, body []byte) {
+	if req == nil {
+		return
+	}
+	req.completed = true
+	err := req.cfg.dbQueries.SetIdempotencyKeyResponse(r.Context(), database.SetIdempotencyKeyResponseParams{
+		UserID:   req.userID,
+		Key:      req.key,
+		Response: sql.NullString{String: string(body), Valid: true},
+	})
+	if err != nil {
+		logf(r.Context(), "Error storing response for idempotency key %q: %v", req.key, err)
+	}
+}
+
+// abandon releases the key unless the request completed. Handlers defer
+// it right after claiming the key.
+func (req *idempotentRequest) abandon(r *http.Request) {
+	if req == nil || req.completed {
+		return
+	}
+	err := req.cfg.dbQueries.DeleteIdempotencyKey(r.Context(), database.DeleteIdempotencyKeyParams{
+		UserID: req.userID,
+		Key:    req.key,
+	})
+	if err != nil {
+		logf(r.Context(), "Error releasing idempotency key %q: %v", req.key, err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// postChirpWithKey posts a chirp with the given Idempotency-Key.
+func postChirpWithKey(t *testing.T, cfg *apiConfig, token, key, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, _ := json.Marshal(map[string]string{"body": body})
+	req := httptest.NewRequest(http.MethodPost, "/api/chirps", bytes.NewReader(payload))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(idempotencyKeyHeader, key)
+	rr := httptest.NewRecorder()
+	cfg.handlerCreateChirp(rr, req)
+	return rr
+}
+
+func countChirps(db *fakeStore) int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return len(db.chirps)
+}
+
+func TestIdempotencyKeyReplaysResponse(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	first := postChirpWithKey(t, cfg, token, "key-1", "hello")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request: got status %v want %v: %s", first.Code, http.StatusCreated, first.Body)
+	}
+	second := postChirpWithKey(t, cfg, token, "key-1", "hello")
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %v %s, want %v %s", second.Code, second.Body, http.StatusCreated, first.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("replay should set Idempotent-Replayed")
+	}
+	if n := countChirps(db); n != 1 {
+		t.Errorf("got %d chirps, want 1", n)
+	}
+
+	// Keys are scoped to the user
+	other := db.addUser(t, "other@example.com")
+	if rr := postChirpWithKey(t, cfg, makeTestToken(t, other.ID), "key-1", "hello"); rr.Code != http.StatusCreated || rr.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("another user's key: got %v, want a new chirp", rr.Code)
+	}
+}
+
+func TestIdempotencyKeyDifferentKeys(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	postChirpWithKey(t, cfg, token, "key-1", "hello")
+	postChirpWithKey(t, cfg, token, "key-2", "hello")
+	if n := countChirps(db); n != 2 {
+		t.Errorf("got %d chirps, want 2", n)
+	}
+}
+
+func TestIdempotencyKeyConflict(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	postChirpWithKey(t, cfg, token, "key-1", "hello")
+	if rr := postChirpWithKey(t, cfg, token, "key-1", "goodbye"); rr.Code != http.StatusConflict {
+		t.Errorf("different body: got status %v want %v", rr.Code, http.StatusConflict)
+	}
+	if n := countChirps(db); n != 1 {
+		t.Errorf("got %d chirps, want 1", n)
+	}
+}
+
+func TestIdempotencyKeyReleasedOnFailure(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	if rr := postChirpWithKey(t, cfg, token, "key-1", ""); rr.Code != http.StatusBadRequest {
+		t.Fatalf("empty chirp: got status %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	// The failed attempt does not hold on to the key
+	if rr := postChirpWithKey(t, cfg, token, "key-1", "fixed"); rr.Code != http.StatusCreated {
+		t.Errorf("retry after a failure: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+}
+
+func TestCleanupDeletesExpiredIdempotencyKeys(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+	postChirpWithKey(t, cfg, token, "old", "hello")
+	postChirpWithKey(t, cfg, token, "new", "hello")
+
+	db.mu.Lock()
+	db.idempotency[0].ExpiresAt = time.Now().Add(-time.Second)
+	db.mu.Unlock()
+
+	newCleanupWorker(db).runOnce(t.Context())
+	db.mu.Lock()
+	keys := db.idempotency
+	db.mu.Unlock()
+	if len(keys) != 1 || keys[0].Key != "new" {
+		t.Errorf("keys after cleanup = %+v, want only the live one", keys)
+	}
+
+	// An expired key starts over
+	if rr := postChirpWithKey(t, cfg, token, "old", "goodbye"); rr.Code != http.StatusCreated {
+		t.Errorf("reusing an expired key: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2Params are the argon2id settings new hashes are made with. Memory
+// is in KiB and must be at least 8 per thread of Parallelism.
+type Argon2Params struct {
+	Memory      int
+	Iterations  int
+	Parallelism int
+}
+
+// DefaultArgon2Params are the second recommended option of RFC 9106, for
+// servers that cannot spare 2 GiB per hash.
+var DefaultArgon2Params = Argon2Params{Memory: 64 * 1024, Iterations: 3, Parallelism: 4}
+
+// Valid reports why p cannot be hashed with, or nil if it can
+func (p Argon2Params) Valid() error {
+	switch {
+	case p.Iterations < 1:
+		return errors.New("iterations must be at least 1")
+	case p.Parallelism < 1 || p.Parallelism > 255:
+		return errors.New("parallelism must be between 1 and 255")
+	case p.Memory < 8*p.Parallelism || p.Memory > 1<<32-1:
+		return errors.New("memory must be at least 8 KiB per thread of parallelism")
+	}
+	return nil
+}
+
+const (
+	argon2Prefix  = "$argon2id$"
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// ErrMalformedHash is returned for a stored hash that cannot be parsed
+var ErrMalformedHash = errors.New("malformed password hash")
+
+// HashArgon2id hashes a password with argon2id and a random salt. The
+// result is in the PHC string format, which carries the parameters, so it
+// can be checked after they change:
+//
+//	$argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+func HashArgon2id(password string, p Argon2Params) (string, error) {
+	if err := p.Valid(); err != nil {
+		return "", err
+	}
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, uint32(p.Iterations), uint32(p.Memory), uint8(p.Parallelism), argon2KeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckArgon2id compares a password with a hash made by HashArgon2id
+func CheckArgon2id(hash, password string) error {
+	p, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return err
+	}
+	got := argon2.IDKey([]byte(password), salt, uint32(p.Iterations), uint32(p.Memory), uint8(p.Parallelism), uint32(len(key)))
+	if subtle.ConstantTimeCompare(got, key) != 1 {
+		return ErrMismatchedPassword
+	}
+	return nil
+}
+
+// parseArgon2id splits a PHC string into its parameters, salt and key
+func parseArgon2id(hash string) (p Argon2Params, salt, key []byte, err error) {
+	fields := strings.Split(strings.TrimPrefix(hash, argon2Prefix), "$")
+	if !strings.HasPrefix(hash, argon2Prefix) || len(fields) != 4 || fields[0] != fmt.Sprintf("v=%d", argon2.Version) {
+		return p, nil, nil, ErrMalformedHash
+	}
+	if _, err := fmt.Sscanf(fields[1], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil || p.Valid() != nil {
+		return p, nil, nil, ErrMalformedHash
+	}
+	salt, err = base64.RawStdEncoding.DecodeString(fields[2])
+	if err != nil {
+		return p, nil, nil, ErrMalformedHash
+	}
+	key, err = base64.RawStdEncoding.DecodeString(fields[3])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrMalformedHash
+	}
+	return p, salt, key, nil
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testArgon2Params are cheap enough to hash with in every test
+var testArgon2Params = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 2}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	hash, err := HashArgon2id("testpassword123", testArgon2Params)
+	if err != nil {
+		t.Fatalf("HashArgon2id failed: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=2$") {
+		t.Errorf("hash %q does not carry its parameters", hash)
+	}
+	if err := CheckArgon2id(hash, "testpassword123"); err != nil {
+		t.Errorf("CheckArgon2id failed for the correct password: %v", err)
+	}
+	if err := CheckArgon2id(hash, "wrongpassword"); !errors.Is(err, ErrMismatchedPassword) {
+		t.Errorf("CheckArgon2id for a wrong password = %v, want ErrMismatchedPassword", err)
+	}
+
+	again, _ := HashArgon2id("testpassword123", testArgon2Params)
+	if again == hash {
+		t.Error("two hashes of the same password share a salt")
+	}
+}
+
+func TestArgon2idMalformed(t *testing.T) {
+	hash, err := HashArgon2id("testpassword123", testArgon2Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Split(hash, "$")
+	for _, bad := range []string{
+		"$argon2id$",
+		strings.Replace(hash, "v=19", "v=16", 1),
+		strings.Replace(hash, "m=1024", "m=lots", 1),
+		strings.Replace(hash, "p=2", "p=0", 1),
+		strings.Join(fields[:5], "$"),
+		strings.Replace(hash, fields[4], "!!!", 1),
+		hash + "$extra",
+	} {
+		if err := CheckArgon2id(bad, "testpassword123"); !errors.Is(err, ErrMalformedHash) {
+			t.Errorf("CheckArgon2id(%q) = %v, want ErrMalformedHash", bad, err)
+		}
+	}
+
+	if _, err := HashArgon2id("testpassword123", Argon2Params{Memory: 8, Iterations: 1, Parallelism: 2}); err == nil {
+		t.Error("HashArgon2id accepted less than 8 KiB per thread")
+	}
+}
+
+func TestCheckPasswordHashEitherAlgorithm(t *testing.T) {
+	bcryptParams := PasswordParams{Algorithm: AlgorithmBcrypt, BcryptCost: MinPasswordCost, Argon2: testArgon2Params}
+	argon2Params := PasswordParams{Algorithm: AlgorithmArgon2id, BcryptCost: MinPasswordCost, Argon2: testArgon2Params}
+
+	for _, params := range []PasswordParams{bcryptParams, argon2Params} {
+		hash, err := HashPassword("testpassword123", params)
+		if err != nil {
+			t.Fatalf("%s: HashPassword failed: %v", params.Algorithm, err)
+		}
+		if err := CheckPasswordHash(hash, "testpassword123"); err != nil {
+			t.Errorf("%s: CheckPasswordHash failed for the correct password: %v", params.Algorithm, err)
+		}
+		if err := CheckPasswordHash(hash, "wrongpassword"); !errors.Is(err, ErrMismatchedPassword) {
+			t.Errorf("%s: CheckPasswordHash for a wrong password = %v, want ErrMismatchedPassword", params.Algorithm, err)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcryptParams := PasswordParams{Algorithm: AlgorithmBcrypt, BcryptCost: MinPasswordCost, Argon2: testArgon2Params}
+	argon2Params := PasswordParams{Algorithm: AlgorithmArgon2id, BcryptCost: MinPasswordCost, Argon2: testArgon2Params}
+	bcryptHash, _ := HashPassword("testpassword123", bcryptParams)
+	argon2Hash, _ := HashPassword("testpassword123", argon2Params)
+
+	costlier := bcryptParams
+	costlier.BcryptCost++
+	moreMemory := argon2Params
+	moreMemory.Argon2.Memory *= 2
+
+	tests := []struct {
+		name   string
+		hash   string
+		params PasswordParams
+		want   bool
+	}{
+		{"bcrypt as configured", bcryptHash, bcryptParams, false},
+		{"bcrypt at another cost", bcryptHash, costlier, true},
+		{"bcrypt with argon2id configured", bcryptHash, argon2Params, true},
+		{"argon2id as configured", argon2Hash, argon2Params, false},
+		{"argon2id with other parameters", argon2Hash, moreMemory, true},
+		{"argon2id with bcrypt configured", argon2Hash, bcryptParams, true},
+		{"malformed", "not a hash", argon2Params, false},
+	}
+	for _, tt := range tests {
+		if got := NeedsRehash(tt.hash, tt.params); got != tt.want {
+			t.Errorf("%s: NeedsRehash = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
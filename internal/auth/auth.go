@@ -2,9 +2,11 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,56 +15,187 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword hashes a password using bcrypt
-func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// The bcrypt costs HashPassword accepts, and the one it is used with
+// unless configured otherwise
+const (
+	MinPasswordCost     = bcrypt.MinCost
+	MaxPasswordCost     = bcrypt.MaxCost
+	DefaultPasswordCost = bcrypt.DefaultCost
+)
+
+// The algorithms HashPassword can hash with
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// PasswordParams say how HashPassword hashes: with Algorithm, using
+// BcryptCost or Argon2 as it needs
+type PasswordParams struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+// ErrMismatchedPassword is returned by CheckPasswordHash for a wrong
+// password, whichever algorithm the hash was made with
+var ErrMismatchedPassword = bcrypt.ErrMismatchedHashAndPassword
+
+// HashPassword hashes a password with the algorithm params name
+func HashPassword(password string, params PasswordParams) (string, error) {
+	if params.Algorithm == AlgorithmArgon2id {
+		return HashArgon2id(password, params.Argon2)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), params.BcryptCost)
 	if err != nil {
 		return "", err
 	}
 	return string(hash), nil
 }
 
-// CheckPasswordHash compares a password with its hash
+// CheckPasswordHash compares a password with its hash. Hashes of either
+// algorithm are checked, so changing algorithm does not lock anyone out.
 func CheckPasswordHash(hash, password string) error {
+	if strings.HasPrefix(hash, argon2Prefix) {
+		return CheckArgon2id(hash, password)
+	}
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
-// MakeJWT creates a new JWT token for a user
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+// PasswordHashCost returns the bcrypt cost hash was made with
+func PasswordHashCost(hash string) (int, error) {
+	return bcrypt.Cost([]byte(hash))
+}
+
+// NeedsRehash reports whether hash was made with another algorithm or
+// settings than params, so the password should be hashed again the next
+// time it is known. Hashes that cannot be parsed are left alone.
+func NeedsRehash(hash string, params PasswordParams) bool {
+	if strings.HasPrefix(hash, argon2Prefix) {
+		p, _, _, err := parseArgon2id(hash)
+		return err == nil && (params.Algorithm != AlgorithmArgon2id || p != params.Argon2)
+	}
+	cost, err := PasswordHashCost(hash)
+	return err == nil && (params.Algorithm == AlgorithmArgon2id || cost != params.BcryptCost)
+}
+
+// Scopes an access token can be limited to. A token without a scopes claim,
+// which includes every token issued before scopes existed, has all of them.
+const (
+	ScopeChirpsRead  = "chirps:read"
+	ScopeChirpsWrite = "chirps:write"
+	ScopeUsersRead   = "users:read"
+	ScopeUsersWrite  = "users:write"
+	ScopeAdmin       = "admin"
+)
+
+// AllScopes lists every scope a token can be issued with
+var AllScopes = []string{ScopeChirpsRead, ScopeChirpsWrite, ScopeUsersRead, ScopeUsersWrite, ScopeAdmin}
+
+// Claims is what a validated access token says about its bearer
+type Claims struct {
+	UserID uuid.UUID
+	// Scopes is nil for a token with full access
+	Scopes []string
+}
+
+// HasScope reports whether the token grants scope
+func (c Claims) HasScope(scope string) bool {
+	return c.Scopes == nil || slices.Contains(c.Scopes, scope)
+}
+
+// tokenClaims is the JWT payload of an access token
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	// TokenUse is always tokenUseAccess; ValidateJWT rejects anything else
+	TokenUse string   `json:"token_use"`
+	Scopes   []string `json:"scopes,omitempty"`
+}
+
+// tokenUseAccess is the token_use claim of an access token
+const tokenUseAccess = "access"
+
+// ErrNotAccessToken is returned by ValidateJWT for a token that is not an
+// access token: an opaque refresh token sent in its place, or a JWT issued
+// for another purpose
+var ErrNotAccessToken = errors.New("not an access token")
+
+// IsJWT reports whether token is shaped like a JWT. Refresh tokens and API
+// keys are opaque and never contain a dot.
+func IsJWT(token string) bool {
+	return strings.Contains(token, ".")
+}
+
+// MakeJWT creates a new JWT token for a user. Without scopes the token has
+// full access.
+func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration, scopes ...string) (string, error) {
+	// An empty scopes claim would be omitted and read back as full access
+	if scopes != nil && len(scopes) == 0 {
+		return "", errors.New("a scoped token needs at least one scope")
+	}
+
 	now := time.Now().UTC()
-	
-	claims := jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(now),
-		ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
-		Subject:   userID.String(),
-	}
-	
+
+	claims := tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		TokenUse: tokenUseAccess,
+		Scopes:   scopes,
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(tokenSecret))
 }
 
-// ValidateJWT validates a JWT token and returns the user ID
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
+// ValidateJWT validates a JWT token and returns its claims. A token that is
+// not an access token fails with ErrNotAccessToken.
+func ValidateJWT(tokenString, tokenSecret string) (Claims, error) {
+	if tokenString != "" && !IsJWT(tokenString) {
+		return Claims{}, ErrNotAccessToken
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &tokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(tokenSecret), nil
 	})
-	
+
 	if err != nil {
-		return uuid.Nil, err
+		return Claims{}, err
 	}
-	
-	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+
+	claims, ok := token.Claims.(*tokenClaims)
 	if !ok || !token.Valid {
-		return uuid.Nil, jwt.ErrInvalidKey
+		return Claims{}, jwt.ErrInvalidKey
+	}
+	if claims.TokenUse != tokenUseAccess {
+		return Claims{}, ErrNotAccessToken
 	}
-	
+
 	userID, err := uuid.Parse(claims.Subject)
 	if err != nil {
-		return uuid.Nil, err
+		return Claims{}, err
 	}
-	
-	return userID, nil
+
+	return Claims{UserID: userID, Scopes: claims.Scopes}, nil
+}
+
+// ValidateJWTWithSecrets validates a JWT as ValidateJWT does, trying each
+// of secrets in order until one matches its signature. During a rotation
+// the new secret comes first and the old ones after it, so tokens signed
+// before the rotation stay valid until they expire.
+func ValidateJWTWithSecrets(tokenString string, secrets []string) (Claims, error) {
+	err := errors.New("no secrets to validate the token with")
+	for _, secret := range secrets {
+		var claims Claims
+		claims, err = ValidateJWT(tokenString, secret)
+		if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			return claims, err
+		}
+	}
+	return Claims{}, err
 }
 
 // GetBearerToken extracts the JWT token from the Authorization header
@@ -71,20 +204,20 @@ func GetBearerToken(headers http.Header) (string, error) {
 	if authHeader == "" {
 		return "", errors.New("authorization header not found")
 	}
-	
+
 	// Split the header value to separate "Bearer" from the token
 	parts := strings.Fields(authHeader) // Use Fields to handle multiple spaces
 	if len(parts) < 2 || strings.ToLower(parts[0]) != "bearer" {
 		return "", errors.New("authorization header must be in format 'Bearer TOKEN'")
 	}
-	
+
 	// Join all parts after "Bearer" in case the token itself contains spaces
 	token := strings.Join(parts[1:], " ")
 	token = strings.TrimSpace(token)
 	if token == "" {
 		return "", errors.New("token cannot be empty")
 	}
-	
+
 	return token, nil
 }
 
@@ -96,7 +229,7 @@ func MakeRefreshToken() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	
+
 	// Convert to hex string
 	token := hex.EncodeToString(bytes)
 	return token, nil
@@ -108,19 +241,40 @@ func GetAPIKey(headers http.Header) (string, error) {
 	if authHeader == "" {
 		return "", errors.New("authorization header not found")
 	}
-	
+
 	// Split the header value to separate "ApiKey" from the key
 	parts := strings.Fields(authHeader) // Use Fields to handle multiple spaces
 	if len(parts) < 2 || strings.ToLower(parts[0]) != "apikey" {
 		return "", errors.New("authorization header must be in format 'ApiKey KEY'")
 	}
-	
+
 	// Join all parts after "ApiKey" in case the key itself contains spaces
 	key := strings.Join(parts[1:], " ")
 	key = strings.TrimSpace(key)
 	if key == "" {
 		return "", errors.New("api key cannot be empty")
 	}
-	
+
 	return key, nil
-}
\ No newline at end of file
+}
+
+// APIKeyPrefix starts every key MakeAPIKey generates, so a leaked key is
+// recognisable as a Chirpy key.
+const APIKeyPrefix = "chirpy_"
+
+// MakeAPIKey generates a random API key: APIKeyPrefix followed by 256
+// hex-encoded random bits
+func MakeAPIKey() (string, error) {
+	token, err := MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + token, nil
+}
+
+// HashAPIKey returns the hex-encoded SHA-256 of key. API keys are random,
+// so unlike passwords they need no salt or slow hash.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
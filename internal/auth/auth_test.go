@@ -1,25 +1,28 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
 func TestHashPassword(t *testing.T) {
 	password := "testpassword123"
-	
-	hash, err := HashPassword(password)
+
+	hash, err := HashPassword(password, PasswordParams{Algorithm: AlgorithmBcrypt, BcryptCost: DefaultPasswordCost})
 	if err != nil {
 		t.Fatalf("HashPassword failed: %v", err)
 	}
-	
+
 	if hash == "" {
 		t.Fatal("HashPassword returned empty hash")
 	}
-	
+
 	if hash == password {
 		t.Fatal("HashPassword returned the original password instead of a hash")
 	}
@@ -196,18 +199,18 @@ func TestMakeRefreshTokenMultipleCalls(t *testing.T) {
 
 func TestCheckPasswordHash(t *testing.T) {
 	password := "testpassword123"
-	
-	hash, err := HashPassword(password)
+
+	hash, err := HashPassword(password, PasswordParams{Algorithm: AlgorithmBcrypt, BcryptCost: DefaultPasswordCost})
 	if err != nil {
 		t.Fatalf("HashPassword failed: %v", err)
 	}
-	
+
 	// Test correct password
 	err = CheckPasswordHash(hash, password)
 	if err != nil {
 		t.Fatalf("CheckPasswordHash failed for correct password: %v", err)
 	}
-	
+
 	// Test incorrect password
 	err = CheckPasswordHash(hash, "wrongpassword")
 	if err == nil {
@@ -215,27 +218,42 @@ func TestCheckPasswordHash(t *testing.T) {
 	}
 }
 
+func TestPasswordHashCost(t *testing.T) {
+	for _, cost := range []int{MinPasswordCost, DefaultPasswordCost} {
+		hash, err := HashPassword("testpassword123", PasswordParams{Algorithm: AlgorithmBcrypt, BcryptCost: cost})
+		if err != nil {
+			t.Fatalf("HashPassword failed: %v", err)
+		}
+		if got, err := PasswordHashCost(hash); err != nil || got != cost {
+			t.Errorf("PasswordHashCost = %d, %v; want %d", got, err, cost)
+		}
+	}
+	if _, err := PasswordHashCost("not a hash"); err == nil {
+		t.Error("PasswordHashCost accepted a malformed hash")
+	}
+}
+
 func TestMakeJWT(t *testing.T) {
 	userID := uuid.New()
 	secret := "test-secret"
 	expiresIn := time.Hour
-	
+
 	token, err := MakeJWT(userID, secret, expiresIn)
 	if err != nil {
 		t.Fatalf("MakeJWT failed: %v", err)
 	}
-	
+
 	if token == "" {
 		t.Fatal("MakeJWT returned empty token")
 	}
-	
+
 	// Test that different users get different tokens
 	userID2 := uuid.New()
 	token2, err := MakeJWT(userID2, secret, expiresIn)
 	if err != nil {
 		t.Fatalf("MakeJWT failed for second user: %v", err)
 	}
-	
+
 	if token == token2 {
 		t.Fatal("MakeJWT returned the same token for different users")
 	}
@@ -245,21 +263,140 @@ func TestValidateJWT(t *testing.T) {
 	userID := uuid.New()
 	secret := "test-secret"
 	expiresIn := time.Hour
-	
+
 	// Create a valid token
 	token, err := MakeJWT(userID, secret, expiresIn)
 	if err != nil {
 		t.Fatalf("MakeJWT failed: %v", err)
 	}
-	
+
 	// Validate the token
-	validatedUserID, err := ValidateJWT(token, secret)
+	claims, err := ValidateJWT(token, secret)
 	if err != nil {
 		t.Fatalf("ValidateJWT failed: %v", err)
 	}
-	
-	if validatedUserID != userID {
-		t.Fatalf("ValidateJWT returned wrong user ID. Expected %v, got %v", userID, validatedUserID)
+
+	if claims.UserID != userID {
+		t.Fatalf("ValidateJWT returned wrong user ID. Expected %v, got %v", userID, claims.UserID)
+	}
+}
+
+func TestValidateJWTWithSecrets(t *testing.T) {
+	userID := uuid.New()
+	secretA, secretB := "secret-a", "secret-b"
+
+	fromB, err := MakeJWT(userID, secretB, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	for _, secrets := range [][]string{{secretA, secretB}, {secretB, secretA}, {secretB}} {
+		claims, err := ValidateJWTWithSecrets(fromB, secrets)
+		if err != nil || claims.UserID != userID {
+			t.Errorf("secrets %q: got %v, %v; want the token's claims", secrets, claims.UserID, err)
+		}
+	}
+
+	fromC, err := MakeJWT(userID, "secret-c", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := ValidateJWTWithSecrets(fromC, []string{secretA, secretB}); !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		t.Errorf("token signed with an absent secret: got %v, want ErrTokenSignatureInvalid", err)
+	}
+	if _, err := ValidateJWTWithSecrets(fromB, nil); err == nil {
+		t.Error("validated a token without any secrets")
+	}
+
+	// A matching secret settles it: an expired token is not retried
+	expired, err := MakeJWT(userID, secretA, -time.Minute)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := ValidateJWTWithSecrets(expired, []string{secretA, secretB}); !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("expired token: got %v, want ErrTokenExpired", err)
+	}
+}
+
+func TestValidateJWTScopes(t *testing.T) {
+	userID := uuid.New()
+	secret := "test-secret"
+
+	// Tokens issued before scopes existed carry no scopes claim
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":       "chirpy",
+		"sub":       userID.String(),
+		"exp":       time.Now().Add(time.Hour).Unix(),
+		"token_use": "access",
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("signing legacy token failed: %v", err)
+	}
+	scoped, err := MakeJWT(userID, secret, time.Hour, ScopeChirpsRead)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  map[string]bool
+	}{
+		{"legacy token", legacy, map[string]bool{ScopeChirpsRead: true, ScopeChirpsWrite: true, ScopeUsersWrite: true, ScopeAdmin: true}},
+		{"scoped token", scoped, map[string]bool{ScopeChirpsRead: true, ScopeChirpsWrite: false, ScopeUsersWrite: false, ScopeAdmin: false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ValidateJWT(tt.token, secret)
+			if err != nil {
+				t.Fatalf("ValidateJWT failed: %v", err)
+			}
+			if claims.UserID != userID {
+				t.Fatalf("User ID mismatch. Expected %v, got %v", userID, claims.UserID)
+			}
+			for scope, want := range tt.want {
+				if got := claims.HasScope(scope); got != want {
+					t.Errorf("HasScope(%q) = %v, want %v", scope, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateJWTTokenUse(t *testing.T) {
+	userID := uuid.New()
+	secret := "test-secret"
+
+	refreshToken, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("MakeRefreshToken failed: %v", err)
+	}
+	sign := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatalf("signing token failed: %v", err)
+		}
+		return token
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tests := map[string]string{
+		"refresh token":     refreshToken,
+		"API key":           APIKeyPrefix + refreshToken,
+		"no token_use":      sign(jwt.MapClaims{"iss": "chirpy", "sub": userID.String(), "exp": exp}),
+		"another token_use": sign(jwt.MapClaims{"iss": "chirpy", "sub": userID.String(), "exp": exp, "token_use": "refresh"}),
+	}
+	for name, token := range tests {
+		if _, err := ValidateJWT(token, secret); !errors.Is(err, ErrNotAccessToken) {
+			t.Errorf("%s: got error %v, want ErrNotAccessToken", name, err)
+		}
+	}
+
+	accessToken, err := MakeJWT(userID, secret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if !IsJWT(accessToken) || IsJWT(refreshToken) {
+		t.Errorf("IsJWT is %v for an access token and %v for a refresh token", IsJWT(accessToken), IsJWT(refreshToken))
 	}
 }
 
@@ -268,13 +405,13 @@ func TestValidateJWTWithWrongSecret(t *testing.T) {
 	secret := "test-secret"
 	wrongSecret := "wrong-secret"
 	expiresIn := time.Hour
-	
+
 	// Create a token with the correct secret
 	token, err := MakeJWT(userID, secret, expiresIn)
 	if err != nil {
 		t.Fatalf("MakeJWT failed: %v", err)
 	}
-	
+
 	// Try to validate with wrong secret
 	_, err = ValidateJWT(token, wrongSecret)
 	if err == nil {
@@ -286,16 +423,16 @@ func TestValidateJWTWithExpiredToken(t *testing.T) {
 	userID := uuid.New()
 	secret := "test-secret"
 	expiresIn := time.Millisecond * 1 // Very short expiration
-	
+
 	// Create a token that expires quickly
 	token, err := MakeJWT(userID, secret, expiresIn)
 	if err != nil {
 		t.Fatalf("MakeJWT failed: %v", err)
 	}
-	
+
 	// Wait for the token to expire
 	time.Sleep(time.Millisecond * 10)
-	
+
 	// Try to validate expired token
 	_, err = ValidateJWT(token, secret)
 	if err == nil {
@@ -306,7 +443,7 @@ func TestValidateJWTWithExpiredToken(t *testing.T) {
 func TestValidateJWTWithInvalidToken(t *testing.T) {
 	secret := "test-secret"
 	invalidToken := "invalid.token.here"
-	
+
 	_, err := ValidateJWT(invalidToken, secret)
 	if err == nil {
 		t.Fatal("ValidateJWT should have failed with invalid token")
@@ -315,7 +452,7 @@ func TestValidateJWTWithInvalidToken(t *testing.T) {
 
 func TestValidateJWTWithEmptyToken(t *testing.T) {
 	secret := "test-secret"
-	
+
 	_, err := ValidateJWT("", secret)
 	if err == nil {
 		t.Fatal("ValidateJWT should have failed with empty token")
@@ -348,7 +485,7 @@ func TestJWTRoundTrip(t *testing.T) {
 			expiresIn: time.Minute,
 		},
 	}
-	
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create token
@@ -356,15 +493,15 @@ func TestJWTRoundTrip(t *testing.T) {
 			if err != nil {
 				t.Fatalf("MakeJWT failed: %v", err)
 			}
-			
+
 			// Validate token
-			validatedUserID, err := ValidateJWT(token, tt.secret)
+			claims, err := ValidateJWT(token, tt.secret)
 			if err != nil {
 				t.Fatalf("ValidateJWT failed: %v", err)
 			}
-			
-			if validatedUserID != tt.userID {
-				t.Fatalf("User ID mismatch. Expected %v, got %v", tt.userID, validatedUserID)
+
+			if claims.UserID != tt.userID {
+				t.Fatalf("User ID mismatch. Expected %v, got %v", tt.userID, claims.UserID)
 			}
 		})
 	}
@@ -477,4 +614,32 @@ func TestGetAPIKeyWithMissingHeader(t *testing.T) {
 	if err.Error() != expectedError {
 		t.Errorf("Expected error %q but got %q", expectedError, err.Error())
 	}
-}
\ No newline at end of file
+}
+
+func TestMakeAPIKey(t *testing.T) {
+	key, err := MakeAPIKey()
+	if err != nil {
+		t.Fatalf("MakeAPIKey failed: %v", err)
+	}
+	if !strings.HasPrefix(key, APIKeyPrefix) || len(key) != len(APIKeyPrefix)+64 {
+		t.Errorf("Expected %s followed by 64 hex characters, got %q", APIKeyPrefix, key)
+	}
+
+	other, _ := MakeAPIKey()
+	if key == other {
+		t.Fatal("MakeAPIKey returned the same key twice")
+	}
+}
+
+func TestHashAPIKey(t *testing.T) {
+	hash := HashAPIKey("chirpy_key")
+	if len(hash) != 64 {
+		t.Errorf("Expected a 64 character hex hash, got %q", hash)
+	}
+	if hash != HashAPIKey("chirpy_key") {
+		t.Error("HashAPIKey is not deterministic")
+	}
+	if hash == HashAPIKey("chirpy_other") {
+		t.Error("Different keys hashed the same")
+	}
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP settings, the defaults of RFC 6238 that every authenticator app
+// assumes when an otpauth URI leaves them out
+const (
+	totpStep      = 30 * time.Second
+	totpDigits    = 6
+	totpSecretLen = 20
+)
+
+// BackupCodeCount is how many backup codes GenerateBackupCodes is asked for
+// at a time
+const BackupCodeCount = 10
+
+// backupCodeAlphabet has 32 characters, so each random byte picks one
+// without bias
+const backupCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a random 160-bit TOTP secret, base32-encoded
+// without padding as authenticator apps expect it
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretLen)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPURI returns the otpauth URI that sets up secret in an authenticator
+// app, which shows it as account at issuer
+func TOTPURI(issuer, account, secret string) string {
+	query := url.Values{"secret": {secret}, "issuer": {issuer}}
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + query.Encode()
+}
+
+// TOTPCode returns the RFC 6238 code for secret at t: six digits from
+// HMAC-SHA1 over the number of 30-second steps since the Unix epoch
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(t.Unix()/int64(totpStep/time.Second)))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000), nil
+}
+
+// ValidateTOTP reports whether code is secret's code at t or one step
+// either side of it, which allows for a phone clock that is a little off
+func ValidateTOTP(secret, code string, t time.Time) bool {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return false
+	}
+	valid := false
+	for _, skew := range []time.Duration{-totpStep, 0, totpStep} {
+		want, err := TOTPCode(secret, t.Add(skew))
+		if err != nil {
+			return false
+		}
+		if subtle.ConstantTimeCompare([]byte(code), []byte(want)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// GenerateBackupCodes returns n random single-use codes of 50 bits each,
+// written as two groups of five characters, e.g. "k3v7q-mz2ad"
+func GenerateBackupCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		random := make([]byte, 10)
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+		for j, b := range random {
+			random[j] = backupCodeAlphabet[b%32]
+		}
+		codes[i] = string(random[:5]) + "-" + string(random[5:])
+	}
+	return codes, nil
+}
+
+// NormalizeBackupCode puts a backup code the way a user typed it into the
+// form it is hashed in: lowercase, without the hyphen or spaces
+func NormalizeBackupCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// The SHA-1 test vectors of RFC 6238 appendix B, cut to six digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(secret, time.Unix(tt.unix, 0))
+		if err != nil || got != tt.want {
+			t.Errorf("TOTPCode at %d = %q, %v, want %q", tt.unix, got, err, tt.want)
+		}
+	}
+
+	if _, err := TOTPCode("not base32!", time.Now()); err == nil {
+		t.Error("TOTPCode with an invalid secret returned no error")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, tt := range []struct {
+		at   time.Duration
+		want bool
+	}{
+		{-2 * totpStep, false},
+		{-totpStep, true},
+		{0, true},
+		{totpStep, true},
+		{2 * totpStep, false},
+	} {
+		code, _ := TOTPCode(secret, now.Add(tt.at))
+		if got := ValidateTOTP(secret, code, now); got != tt.want {
+			t.Errorf("code from %v away: ValidateTOTP = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+	if ValidateTOTP(secret, "12345", now) || ValidateTOTP(secret, "", now) {
+		t.Error("ValidateTOTP accepted a code of the wrong length")
+	}
+}
+
+func TestGenerateBackupCodes(t *testing.T) {
+	codes, err := GenerateBackupCodes(BackupCodeCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != BackupCodeCount {
+		t.Fatalf("got %d codes, want %d", len(codes), BackupCodeCount)
+	}
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' || strings.Trim(NormalizeBackupCode(code), backupCodeAlphabet) != "" {
+			t.Errorf("code %q is not two groups of five", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q generated twice", code)
+		}
+		seen[code] = true
+	}
+
+	if got := NormalizeBackupCode(" K3V7Q-MZ2AD "); got != "k3v7qmz2ad" {
+		t.Errorf("NormalizeBackupCode = %q, want k3v7qmz2ad", got)
+	}
+}
@@ -0,0 +1,672 @@
+// Package config reads the server's settings from the environment and
+// validates them in one place, so a misconfigured server refuses to start
+// with a list of everything that is wrong rather than failing on first use.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/auth"
+	"github.com/AlexTLDR/chirpy/internal/mail"
+	"github.com/joho/godotenv"
+)
+
+// Config is every setting the server reads from the environment.
+type Config struct {
+	DBURL    string
+	Platform string
+	// JWTSecrets are the secrets access tokens are checked against, in
+	// order. The first one signs new tokens.
+	JWTSecrets []string
+	PolkaKey   string
+	Port       string
+
+	// PublicURL is the address users reach the server at, for links in
+	// emails; when unset links use the host each request was sent to
+	PublicURL string
+
+	// DBReplicaURL, when set, is a read replica for the read-heavy queries
+	DBReplicaURL string
+
+	// LegacyAPI keeps the unversioned /api/ routes; DISABLE_LEGACY_API
+	// turns them off
+	LegacyAPI bool
+	// ChirpCacheTTL is how long GET /api/chirps results are cached; 0
+	// disables the cache
+	ChirpCacheTTL time.Duration
+	// DuplicateChirpWindow is how long an identical chirp by the same user
+	// is rejected for; 0 allows duplicates
+	DuplicateChirpWindow time.Duration
+	SignupPrivacy        bool
+	// HideEmails leaves email addresses out of public user profiles
+	HideEmails bool
+	// UserDirectoryRequiresAuth only lists users to signed-in callers
+	UserDirectoryRequiresAuth bool
+	// ViewFlushInterval is how often counted chirp views are written to
+	// the database, and so about how many a crash can lose
+	ViewFlushInterval time.Duration
+	// CountListViews counts a view of every chirp in a list response, not
+	// only of chirps fetched by ID
+	CountListViews bool
+
+	// TLSCertFile and TLSKeyFile are set together, and HTTPRedirectPort
+	// only with them
+	TLSCertFile      string
+	TLSKeyFile       string
+	HTTPRedirectPort string
+	// TrustProxy takes client addresses from the X-Forwarded-For header a
+	// reverse proxy in front of the server adds
+	TrustProxy bool
+
+	FilepathRoot string
+	SPAMode      bool
+	MediaDir     string
+	// ChirpMediaLocalOnly only lets chirps link to images uploaded to
+	// MediaDir
+	ChirpMediaLocalOnly bool
+
+	Timeouts       Timeouts
+	ChirpRateLimit ChirpRateLimit
+	// SignupRateLimit caps signups per client address; off by default
+	SignupRateLimit SignupRateLimit
+	// SignupHoneypot quietly drops signups that fill in the hidden
+	// website field, which only bots see
+	SignupHoneypot bool
+	// UsernameCheckRateLimit caps username availability checks per client
+	// address, so they cannot be used to list the registered usernames
+	UsernameCheckRateLimit UsernameCheckRateLimit
+	// DailyChirpQuota caps the chirps a user without Chirpy Red may post
+	// in any 24 hours; 0 turns the cap off
+	DailyChirpQuota int
+	// PurgeBatchSize is how many chirps POST /admin/chirps/purge deletes
+	// per statement
+	PurgeBatchSize int
+	// PasswordHashing is how new password hashes are made. Hashes made
+	// otherwise are redone this way on the next successful login.
+	PasswordHashing auth.PasswordParams
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL RefreshTokenTTL
+	Google          Google
+	// SMTP is only used outside dev, when SMTP.Host is set
+	SMTP  mail.SMTPConfig
+	Pprof Pprof
+
+	// Seed is only set by the -seed flags, not the environment
+	Seed Seed
+	// EnvFile is the .env file Parse read settings from, or "" if there
+	// was none
+	EnvFile string
+}
+
+// Timeouts are the HTTP server timeouts; 0 disables one.
+type Timeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+}
+
+// ChirpRateLimit caps how many chirps each user may post per window, with
+// RedLimit for Chirpy Red members. A limit of 0 turns the check off.
+type ChirpRateLimit struct {
+	Limit    int
+	RedLimit int
+	Window   time.Duration
+}
+
+// SignupRateLimit caps how many accounts each client address may create
+// per window. A limit of 0 turns the check off.
+type SignupRateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// UsernameCheckRateLimit caps how many username availability checks each
+// client address may make per window. A limit of 0 turns the check off.
+type UsernameCheckRateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// RefreshTokenTTL is the refresh token lifetime for ordinary logins and
+// for logins that ask to be remembered.
+type RefreshTokenTTL struct {
+	Session    time.Duration
+	RememberMe time.Duration
+}
+
+// Google is the OAuth client for signing in with Google. It is either
+// fully set or, with Google login disabled, empty.
+type Google struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// Enabled reports whether Google login is configured.
+func (g Google) Enabled() bool {
+	return g.ClientID != ""
+}
+
+// Pprof controls the /debug/pprof/ endpoints and the mutex and block
+// profile rates.
+type Pprof struct {
+	Enabled       bool
+	MutexFraction int
+	BlockRate     int
+}
+
+// Seed asks for sample data to be added to a dev database instead of
+// starting the server.
+type Seed struct {
+	Enabled bool
+	Users   int
+	Chirps  int
+}
+
+// Default returns the settings used for everything left unset. The
+// required settings have no default and are empty.
+func Default() Config {
+	return Config{
+		Port:                 "8080",
+		LegacyAPI:            true,
+		ChirpCacheTTL:        5 * time.Second,
+		DuplicateChirpWindow: 5 * time.Minute,
+		ViewFlushInterval:    10 * time.Second,
+		FilepathRoot:         ".",
+		MediaDir:             "media",
+		Timeouts: Timeouts{
+			ReadHeader: 10 * time.Second,
+			Read:       30 * time.Second,
+			Write:      30 * time.Second,
+			Idle:       120 * time.Second,
+		},
+		ChirpRateLimit: ChirpRateLimit{
+			Limit:    30,
+			RedLimit: 100,
+			Window:   5 * time.Minute,
+		},
+		SignupRateLimit: SignupRateLimit{Window: time.Hour},
+		UsernameCheckRateLimit: UsernameCheckRateLimit{
+			Limit:  30,
+			Window: time.Minute,
+		},
+		DailyChirpQuota: 100,
+		PurgeBatchSize:  1000,
+		PasswordHashing: auth.PasswordParams{
+			Algorithm:  auth.AlgorithmBcrypt,
+			BcryptCost: auth.DefaultPasswordCost,
+			Argon2:     auth.DefaultArgon2Params,
+		},
+		AccessTokenTTL: time.Hour,
+		RefreshTokenTTL: RefreshTokenTTL{
+			Session:    7 * 24 * time.Hour,
+			RememberMe: 180 * 24 * time.Hour,
+		},
+	}
+}
+
+// Error lists every problem found in the environment.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// ErrVersion is returned by Parse when -version is given, so the caller
+// can print its version and exit.
+var ErrVersion = errors.New("version requested")
+
+// variable is an environment variable that can also be set by a flag.
+type variable struct {
+	env   string
+	usage string
+	bool  bool
+}
+
+// variables lists every setting that can be given as a flag. Each one's
+// flag is its name in lower case with dashes, e.g. -db-url for DB_URL.
+var variables = []variable{
+	{env: "ENV_FILE", usage: "file of KEY=value settings to read instead of .env; the environment and flags win over it"},
+	{env: "DB_URL", usage: "Postgres connection string (required)"},
+	{env: "DB_URL_FILE", usage: "file to read DB_URL from instead, such as a mounted secret"},
+	{env: "DB_REPLICA_URL", usage: "Postgres read replica for chirp reads and metrics counts"},
+	{env: "PLATFORM", usage: "platform, e.g. dev or prod (required)"},
+	{env: "JWT_SECRET", usage: "secret access tokens are signed with (required)"},
+	{env: "JWT_SECRET_FILE", usage: "file to read JWT_SECRET from instead, such as a mounted secret"},
+	{env: "JWT_SECRETS", usage: "comma-separated secrets to use instead of JWT_SECRET while rotating it: the first signs new tokens, and tokens signed with any are accepted"},
+	{env: "JWT_SECRETS_FILE", usage: "file to read JWT_SECRETS from instead, such as a mounted secret"},
+	{env: "POLKA_KEY", usage: "API key Polka signs its webhooks with (required)"},
+	{env: "POLKA_KEY_FILE", usage: "file to read POLKA_KEY from instead, such as a mounted secret"},
+	{env: "PORT", usage: "port to listen on (default 8080)"},
+	{env: "PUBLIC_URL", usage: "address users reach the server at, for links in emails, e.g. https://chirpy.example.com"},
+	{env: "DISABLE_LEGACY_API", usage: "only serve /api/v1/, not the deprecated /api/ aliases", bool: true},
+	{env: "CHIRP_CACHE_TTL", usage: "how long chirp lists are cached; 0 disables (default 5s)"},
+	{env: "DUPLICATE_CHIRP_WINDOW", usage: "reject a chirp identical to one posted this recently; 0 disables (default 5m)"},
+	{env: "SIGNUP_PRIVACY", usage: "do not reveal at signup whether an email is registered", bool: true},
+	{env: "PRIVACY_HIDE_EMAILS", usage: "leave email addresses out of public user profiles and the user directory", bool: true},
+	{env: "USER_DIRECTORY_REQUIRE_AUTH", usage: "only list users at GET /api/users to signed-in callers", bool: true},
+	{env: "VIEW_FLUSH_INTERVAL", usage: "how often chirp view counts are written to the database (default 10s)"},
+	{env: "COUNT_LIST_VIEWS", usage: "count a view of every chirp in a list, not just chirps fetched by ID", bool: true},
+	{env: "TLS_CERT_FILE", usage: "certificate to serve HTTPS with; set with -tls-key-file"},
+	{env: "TLS_KEY_FILE", usage: "key for -tls-cert-file"},
+	{env: "HTTP_REDIRECT_PORT", usage: "with TLS, redirect plain HTTP on this port to HTTPS"},
+	{env: "TRUST_PROXY", usage: "take client addresses from the X-Forwarded-For header set by a reverse proxy", bool: true},
+	{env: "FILEPATH_ROOT", usage: "directory served under /app/ (default .)"},
+	{env: "SPA_MODE", usage: "serve index.html for /app/ paths that match no file", bool: true},
+	{env: "MEDIA_DIR", usage: "where uploaded avatars are stored (default media)"},
+	{env: "CHIRP_MEDIA_LOCAL_ONLY", usage: "only let chirps link to images uploaded to this server under /media/", bool: true},
+	{env: "READ_HEADER_TIMEOUT", usage: "server read header timeout; 0 disables (default 10s)"},
+	{env: "READ_TIMEOUT", usage: "server read timeout; 0 disables (default 30s)"},
+	{env: "WRITE_TIMEOUT", usage: "server write timeout; 0 disables (default 30s)"},
+	{env: "IDLE_TIMEOUT", usage: "server idle timeout; 0 disables (default 120s)"},
+	{env: "CHIRP_RATE_LIMIT", usage: "chirps each user may post per window; 0 disables (default 30)"},
+	{env: "CHIRP_RATE_LIMIT_RED", usage: "the same for Chirpy Red members (default 100)"},
+	{env: "CHIRP_RATE_WINDOW", usage: "chirp rate limit window (default 5m)"},
+	{env: "SIGNUP_RATE_LIMIT", usage: "signups each client address may make per window; 0 disables (default 0)"},
+	{env: "SIGNUP_RATE_WINDOW", usage: "signup rate limit window (default 1h)"},
+	{env: "SIGNUP_HONEYPOT", usage: "pretend to accept signups that fill in the hidden website field, without creating them", bool: true},
+	{env: "USERNAME_CHECK_RATE_LIMIT", usage: "username availability checks each client address may make per window; 0 disables (default 30)"},
+	{env: "USERNAME_CHECK_RATE_WINDOW", usage: "username availability check rate limit window (default 1m)"},
+	{env: "DAILY_CHIRP_QUOTA", usage: "chirps a user without Chirpy Red may post per 24 hours; 0 disables (default 100)"},
+	{env: "PURGE_BATCH_SIZE", usage: "chirps the admin purge deletes per statement (default 1000)"},
+	{env: "PASSWORD_HASH_ALGORITHM", usage: "bcrypt or argon2id; hashes of the other are upgraded at login (default bcrypt)"},
+	{env: "BCRYPT_COST", usage: "bcrypt cost of password hashes, 4 to 31; older hashes are upgraded at login (default 10)"},
+	{env: "ARGON2_MEMORY", usage: "argon2id memory per hash in KiB (default 65536)"},
+	{env: "ARGON2_ITERATIONS", usage: "argon2id passes over the memory (default 3)"},
+	{env: "ARGON2_PARALLELISM", usage: "argon2id threads per hash (default 4)"},
+	{env: "ACCESS_TOKEN_TTL", usage: "access token lifetime (default 1h)"},
+	{env: "REFRESH_TOKEN_TTL", usage: "refresh token lifetime (default 168h)"},
+	{env: "REFRESH_TOKEN_TTL_REMEMBER_ME", usage: "refresh token lifetime for remembered logins (default 4320h)"},
+	{env: "GOOGLE_CLIENT_ID", usage: "Google OAuth client ID; enables Google login"},
+	{env: "GOOGLE_CLIENT_SECRET", usage: "Google OAuth client secret"},
+	{env: "GOOGLE_REDIRECT_URL", usage: "Google OAuth redirect URL"},
+	{env: "SMTP_HOST", usage: "SMTP relay for outgoing email; ignored in dev"},
+	{env: "SMTP_PORT", usage: "SMTP relay port"},
+	{env: "SMTP_USER", usage: "SMTP user name"},
+	{env: "SMTP_PASS", usage: "SMTP password"},
+	{env: "SMTP_FROM", usage: "sender address of outgoing email"},
+	{env: "ENABLE_PPROF", usage: "serve /debug/pprof/ (default on in dev only)", bool: true},
+	{env: "PPROF_MUTEX_FRACTION", usage: "mutex profile fraction; 0 disables"},
+	{env: "PPROF_BLOCK_RATE", usage: "block profile rate; 0 disables"},
+}
+
+func flagName(env string) string {
+	return strings.ReplaceAll(strings.ToLower(env), "_", "-")
+}
+
+// flagValue holds a flag's raw value, to be parsed by Load like the
+// environment variable it overrides.
+type flagValue struct {
+	value  string
+	set    bool
+	isBool bool
+}
+
+func (f *flagValue) String() string { return f.value }
+
+func (f *flagValue) Set(value string) error {
+	f.value = value
+	f.set = true
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be given as a bare -flag.
+func (f *flagValue) IsBoolFlag() bool { return f.isBool }
+
+// Parse reads the command line args (without the program name) and then
+// the configuration as Load does, looking variables up with lookupEnv. Each
+// setting comes from its flag, else its environment variable, else the .env
+// file, else its default. A variable set to an empty value still hides the
+// .env file's, so it can be cleared for one run. The
+// .env file is the one ENV_FILE names, or .env in the working directory if
+// there is one. Usage and flag errors are printed to output. It returns
+// flag.ErrHelp for -help and ErrVersion for -version.
+func Parse(args []string, lookupEnv func(string) (string, bool), output io.Writer) (Config, error) {
+	fs := flag.NewFlagSet("chirpy", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintf(output, "Usage: chirpy [flags]\n\nEvery flag overrides the environment variable in brackets.\n\n")
+		fs.PrintDefaults()
+	}
+	version := fs.Bool("version", false, "print the version and exit")
+	var seed Seed
+	fs.BoolVar(&seed.Enabled, "seed", false, "add sample users and chirps to the dev database and exit")
+	fs.IntVar(&seed.Users, "seed-users", 10, "users -seed creates; existing ones are reused")
+	fs.IntVar(&seed.Chirps, "seed-chirps", 300, "chirps -seed adds")
+	values := make(map[string]*flagValue, len(variables))
+	for _, v := range variables {
+		values[v.env] = &flagValue{isBool: v.bool}
+		fs.Var(values[v.env], flagName(v.env), v.usage+" ["+v.env+"]")
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return Config{}, err
+	}
+	if fs.NArg() > 0 {
+		// Reported like the flag package reports its own errors
+		err := fmt.Errorf("unexpected argument %q", fs.Arg(0))
+		fmt.Fprintln(output, err)
+		fs.Usage()
+		return Config{}, err
+	}
+	if *version {
+		return Config{}, ErrVersion
+	}
+
+	lookup := func(name string) (string, bool) {
+		if f, ok := values[name]; ok && f.set {
+			return f.value, true
+		}
+		return lookupEnv(name)
+	}
+	envFilePath, _ := lookup("ENV_FILE")
+	envFile, fileValues, err := readEnvFile(envFilePath)
+	if err != nil {
+		return Config{}, &Error{Problems: []string{err.Error()}}
+	}
+
+	cfg, err := Load(func(name string) string {
+		if value, ok := lookup(name); ok {
+			return value
+		}
+		return fileValues[name]
+	})
+	cfg.EnvFile = envFile
+	if err != nil || !seed.Enabled {
+		return cfg, err
+	}
+
+	var problems []string
+	if cfg.Platform != "dev" {
+		problems = append(problems, "-seed requires PLATFORM=dev")
+	}
+	if seed.Users < 1 {
+		problems = append(problems, "-seed-users must be at least 1")
+	}
+	if seed.Chirps < 0 {
+		problems = append(problems, "-seed-chirps must not be negative")
+	}
+	if problems != nil {
+		return Config{}, &Error{Problems: problems}
+	}
+	cfg.Seed = seed
+	return cfg, nil
+}
+
+// readEnvFile reads the variables in path, or in .env if path is "". A
+// missing .env is no error, as deployments that set everything in the
+// environment have none; the returned path is then "".
+func readEnvFile(path string) (string, map[string]string, error) {
+	name := "ENV_FILE"
+	if path == "" {
+		name, path = ".env", ".env"
+	}
+	values, err := godotenv.Read(path)
+	if errors.Is(err, fs.ErrNotExist) && name == ".env" {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("%s cannot be read: %v", name, err)
+	}
+	return path, values, nil
+}
+
+// Load reads the configuration through getenv, normally os.Getenv, and the
+// secret files it names. Unset variables keep their defaults. If anything
+// is missing or invalid it returns an *Error naming all of it.
+func Load(getenv func(string) string) (Config, error) {
+	l := &loader{getenv: getenv}
+	cfg := Default()
+
+	cfg.DBURL = l.secret("DB_URL")
+	l.string("DB_REPLICA_URL", &cfg.DBReplicaURL)
+	cfg.Platform = l.required("PLATFORM")
+	// JWT_SECRETS takes JWT_SECRET's place while rotating it
+	if secrets, set := l.optionalSecret("JWT_SECRETS"); !set {
+		cfg.JWTSecrets = []string{l.secret("JWT_SECRET")}
+	} else if secrets != "" {
+		for _, secret := range strings.Split(secrets, ",") {
+			secret = strings.TrimSpace(secret)
+			if secret == "" {
+				l.problem("JWT_SECRETS must not have empty entries")
+				break
+			}
+			cfg.JWTSecrets = append(cfg.JWTSecrets, secret)
+		}
+	}
+	cfg.PolkaKey = l.secret("POLKA_KEY")
+	l.port("PORT", &cfg.Port)
+	l.string("PUBLIC_URL", &cfg.PublicURL)
+	if cfg.PublicURL != "" {
+		u, err := url.Parse(cfg.PublicURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			l.problem("PUBLIC_URL must be an absolute http or https URL, got " + strconv.Quote(cfg.PublicURL))
+		}
+		cfg.PublicURL = strings.TrimSuffix(cfg.PublicURL, "/")
+	}
+
+	var disableLegacyAPI bool
+	l.bool("DISABLE_LEGACY_API", &disableLegacyAPI)
+	cfg.LegacyAPI = !disableLegacyAPI
+	l.duration("CHIRP_CACHE_TTL", &cfg.ChirpCacheTTL, true)
+	l.duration("DUPLICATE_CHIRP_WINDOW", &cfg.DuplicateChirpWindow, true)
+	l.bool("SIGNUP_PRIVACY", &cfg.SignupPrivacy)
+	l.bool("PRIVACY_HIDE_EMAILS", &cfg.HideEmails)
+	l.bool("USER_DIRECTORY_REQUIRE_AUTH", &cfg.UserDirectoryRequiresAuth)
+	l.duration("VIEW_FLUSH_INTERVAL", &cfg.ViewFlushInterval, false)
+	l.bool("COUNT_LIST_VIEWS", &cfg.CountListViews)
+
+	l.string("TLS_CERT_FILE", &cfg.TLSCertFile)
+	l.string("TLS_KEY_FILE", &cfg.TLSKeyFile)
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		l.problem("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	l.port("HTTP_REDIRECT_PORT", &cfg.HTTPRedirectPort)
+	if cfg.HTTPRedirectPort != "" && cfg.TLSCertFile == "" && cfg.TLSKeyFile == "" {
+		l.problem("HTTP_REDIRECT_PORT requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	l.bool("TRUST_PROXY", &cfg.TrustProxy)
+
+	l.string("FILEPATH_ROOT", &cfg.FilepathRoot)
+	l.bool("SPA_MODE", &cfg.SPAMode)
+	l.string("MEDIA_DIR", &cfg.MediaDir)
+	l.bool("CHIRP_MEDIA_LOCAL_ONLY", &cfg.ChirpMediaLocalOnly)
+
+	l.duration("READ_HEADER_TIMEOUT", &cfg.Timeouts.ReadHeader, true)
+	l.duration("READ_TIMEOUT", &cfg.Timeouts.Read, true)
+	l.duration("WRITE_TIMEOUT", &cfg.Timeouts.Write, true)
+	l.duration("IDLE_TIMEOUT", &cfg.Timeouts.Idle, true)
+
+	l.int("CHIRP_RATE_LIMIT", &cfg.ChirpRateLimit.Limit)
+	l.int("CHIRP_RATE_LIMIT_RED", &cfg.ChirpRateLimit.RedLimit)
+	l.duration("CHIRP_RATE_WINDOW", &cfg.ChirpRateLimit.Window, false)
+	l.int("SIGNUP_RATE_LIMIT", &cfg.SignupRateLimit.Limit)
+	l.duration("SIGNUP_RATE_WINDOW", &cfg.SignupRateLimit.Window, false)
+	l.bool("SIGNUP_HONEYPOT", &cfg.SignupHoneypot)
+	l.int("USERNAME_CHECK_RATE_LIMIT", &cfg.UsernameCheckRateLimit.Limit)
+	l.duration("USERNAME_CHECK_RATE_WINDOW", &cfg.UsernameCheckRateLimit.Window, false)
+	l.int("DAILY_CHIRP_QUOTA", &cfg.DailyChirpQuota)
+	l.int("PURGE_BATCH_SIZE", &cfg.PurgeBatchSize)
+	if cfg.PurgeBatchSize == 0 {
+		l.problem("PURGE_BATCH_SIZE must be positive")
+	}
+	passwords := &cfg.PasswordHashing
+	l.string("PASSWORD_HASH_ALGORITHM", &passwords.Algorithm)
+	if passwords.Algorithm != auth.AlgorithmBcrypt && passwords.Algorithm != auth.AlgorithmArgon2id {
+		l.problem("PASSWORD_HASH_ALGORITHM must be bcrypt or argon2id, got " + strconv.Quote(passwords.Algorithm))
+	}
+	l.int("BCRYPT_COST", &passwords.BcryptCost)
+	if passwords.BcryptCost < auth.MinPasswordCost || passwords.BcryptCost > auth.MaxPasswordCost {
+		l.problem(fmt.Sprintf("BCRYPT_COST must be between %d and %d, got %d", auth.MinPasswordCost, auth.MaxPasswordCost, passwords.BcryptCost))
+	}
+	l.int("ARGON2_MEMORY", &passwords.Argon2.Memory)
+	l.int("ARGON2_ITERATIONS", &passwords.Argon2.Iterations)
+	l.int("ARGON2_PARALLELISM", &passwords.Argon2.Parallelism)
+	if err := passwords.Argon2.Valid(); err != nil {
+		l.problem("ARGON2_MEMORY, ARGON2_ITERATIONS and ARGON2_PARALLELISM: " + err.Error())
+	}
+
+	l.duration("ACCESS_TOKEN_TTL", &cfg.AccessTokenTTL, false)
+	l.duration("REFRESH_TOKEN_TTL", &cfg.RefreshTokenTTL.Session, false)
+	l.duration("REFRESH_TOKEN_TTL_REMEMBER_ME", &cfg.RefreshTokenTTL.RememberMe, false)
+
+	l.string("GOOGLE_CLIENT_ID", &cfg.Google.ClientID)
+	l.string("GOOGLE_CLIENT_SECRET", &cfg.Google.ClientSecret)
+	l.string("GOOGLE_REDIRECT_URL", &cfg.Google.RedirectURL)
+	if g := cfg.Google; g != (Google{}) && (g.ClientID == "" || g.ClientSecret == "" || g.RedirectURL == "") {
+		l.problem("GOOGLE_CLIENT_ID, GOOGLE_CLIENT_SECRET and GOOGLE_REDIRECT_URL must be set together")
+	}
+
+	l.string("SMTP_HOST", &cfg.SMTP.Host)
+	l.string("SMTP_PORT", &cfg.SMTP.Port)
+	l.string("SMTP_USER", &cfg.SMTP.Username)
+	l.string("SMTP_PASS", &cfg.SMTP.Password)
+	l.string("SMTP_FROM", &cfg.SMTP.From)
+	if s := cfg.SMTP; s.Host != "" && cfg.Platform != "dev" {
+		if s.Port == "" || s.From == "" {
+			l.problem("SMTP_HOST requires SMTP_PORT and SMTP_FROM")
+		}
+		if (s.Username == "") != (s.Password == "") {
+			l.problem("SMTP_USER and SMTP_PASS must be set together")
+		}
+	}
+
+	// Profiling is on in dev unless ENABLE_PPROF says otherwise
+	cfg.Pprof.Enabled = cfg.Platform == "dev"
+	l.bool("ENABLE_PPROF", &cfg.Pprof.Enabled)
+	l.int("PPROF_MUTEX_FRACTION", &cfg.Pprof.MutexFraction)
+	l.int("PPROF_BLOCK_RATE", &cfg.Pprof.BlockRate)
+
+	if len(l.problems) > 0 {
+		return Config{}, &Error{Problems: l.problems}
+	}
+	return cfg, nil
+}
+
+// loader reads variables, leaving the value untouched when a variable is
+// unset and collecting a problem instead of stopping when one is invalid.
+type loader struct {
+	getenv   func(string) string
+	problems []string
+}
+
+func (l *loader) problem(msg string) {
+	l.problems = append(l.problems, msg)
+}
+
+func (l *loader) required(name string) string {
+	value := l.getenv(name)
+	if value == "" {
+		l.problem(name + " is not set")
+	}
+	return value
+}
+
+// secret reads a required setting from the file named by name+"_FILE",
+// the way Docker and Kubernetes mount secrets, or without that from name
+// itself. The file's trailing newline is not part of the value.
+func (l *loader) secret(name string) string {
+	value, set := l.optionalSecret(name)
+	if !set {
+		l.problem(name + " is not set")
+	}
+	return value
+}
+
+// optionalSecret reads a setting the way secret does, reporting whether it
+// was given at all rather than requiring it.
+func (l *loader) optionalSecret(name string) (value string, set bool) {
+	path := l.getenv(name + "_FILE")
+	if path == "" {
+		value = l.getenv(name)
+		return value, value != ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		l.problem(name + "_FILE cannot be read: " + err.Error())
+		return "", true
+	}
+	value = strings.TrimRight(string(data), "\r\n")
+	if value == "" {
+		l.problem(name + "_FILE " + strconv.Quote(path) + " is empty")
+	}
+	return value, true
+}
+
+func (l *loader) string(name string, value *string) {
+	if raw := l.getenv(name); raw != "" {
+		*value = raw
+	}
+}
+
+func (l *loader) bool(name string, value *bool) {
+	raw := l.getenv(name)
+	if raw == "" {
+		return
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		l.problem(name + " must be a boolean, got " + strconv.Quote(raw))
+		return
+	}
+	*value = b
+}
+
+// duration reads a time.Duration. Negative durations are always rejected,
+// and zero only allowed with allowZero.
+func (l *loader) duration(name string, value *time.Duration, allowZero bool) {
+	raw := l.getenv(name)
+	if raw == "" {
+		return
+	}
+	d, err := time.ParseDuration(raw)
+	switch {
+	case err != nil:
+		l.problem(name + " must be a duration such as 30s or 5m, got " + strconv.Quote(raw))
+	case d < 0:
+		l.problem(name + " must not be negative")
+	case d == 0 && !allowZero:
+		l.problem(name + " must be positive")
+	default:
+		*value = d
+	}
+}
+
+// int reads a non-negative integer.
+func (l *loader) int(name string, value *int) {
+	raw := l.getenv(name)
+	if raw == "" {
+		return
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		l.problem(name + " must be a non-negative integer, got " + strconv.Quote(raw))
+		return
+	}
+	*value = n
+}
+
+func (l *loader) port(name string, value *string) {
+	raw := l.getenv(name)
+	if raw == "" {
+		return
+	}
+	if n, err := strconv.Atoi(raw); err != nil || n < 1 || n > 65535 {
+		l.problem(name + " must be a port number, got " + strconv.Quote(raw))
+		return
+	}
+	*value = raw
+}
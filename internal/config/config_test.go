@@ -0,0 +1,469 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/auth"
+)
+
+// required is the smallest environment that loads.
+var required = map[string]string{
+	"DB_URL":     "postgres://localhost/chirpy",
+	"PLATFORM":   "prod",
+	"JWT_SECRET": "secret",
+	"POLKA_KEY":  "key",
+}
+
+// load loads required overlaid with env; an empty value unsets a variable.
+func load(env map[string]string) (Config, error) {
+	merged := make(map[string]string)
+	for k, v := range required {
+		merged[k] = v
+	}
+	for k, v := range env {
+		merged[k] = v
+	}
+	return Load(func(name string) string { return merged[name] })
+}
+
+// lookupIn looks variables up in env, as os.LookupEnv does in the process
+// environment.
+func lookupIn(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Default()
+	want.DBURL = "postgres://localhost/chirpy"
+	want.Platform = "prod"
+	want.JWTSecrets = []string{"secret"}
+	want.PolkaKey = "key"
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("got %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	cfg, err := load(map[string]string{
+		"PORT":                        "9000",
+		"DB_REPLICA_URL":              "postgres://replica/chirpy",
+		"DISABLE_LEGACY_API":          "true",
+		"CHIRP_CACHE_TTL":             "0",
+		"READ_HEADER_TIMEOUT":         "2s",
+		"IDLE_TIMEOUT":                "0",
+		"CHIRP_RATE_LIMIT":            "0",
+		"CHIRP_RATE_WINDOW":           "1h",
+		"SIGNUP_RATE_LIMIT":           "5",
+		"SIGNUP_HONEYPOT":             "true",
+		"USERNAME_CHECK_RATE_LIMIT":   "0",
+		"USERNAME_CHECK_RATE_WINDOW":  "10m",
+		"DAILY_CHIRP_QUOTA":           "0",
+		"PURGE_BATCH_SIZE":            "50",
+		"PASSWORD_HASH_ALGORITHM":     "argon2id",
+		"BCRYPT_COST":                 "12",
+		"ARGON2_MEMORY":               "19456",
+		"ARGON2_ITERATIONS":           "2",
+		"VIEW_FLUSH_INTERVAL":         "1m",
+		"COUNT_LIST_VIEWS":            "true",
+		"TRUST_PROXY":                 "true",
+		"CHIRP_MEDIA_LOCAL_ONLY":      "true",
+		"PRIVACY_HIDE_EMAILS":         "true",
+		"USER_DIRECTORY_REQUIRE_AUTH": "true",
+		"PUBLIC_URL":                  "https://chirpy.example.com/",
+		"ACCESS_TOKEN_TTL":            "15m",
+		"REFRESH_TOKEN_TTL":           "12h",
+		"GOOGLE_CLIENT_ID":            "id",
+		"GOOGLE_CLIENT_SECRET":        "secret",
+		"GOOGLE_REDIRECT_URL":         "https://chirpy.example.com/callback",
+		"PPROF_BLOCK_RATE":            "1000",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Default().Timeouts
+	want.ReadHeader = 2 * time.Second
+	want.Idle = 0
+	if cfg.Timeouts != want {
+		t.Errorf("timeouts = %+v, want %+v", cfg.Timeouts, want)
+	}
+	if cfg.ChirpRateLimit != (ChirpRateLimit{Limit: 0, RedLimit: 100, Window: time.Hour}) {
+		t.Errorf("chirp rate limit = %+v", cfg.ChirpRateLimit)
+	}
+	if cfg.SignupRateLimit != (SignupRateLimit{Limit: 5, Window: time.Hour}) || !cfg.SignupHoneypot || Default().SignupRateLimit.Limit != 0 || Default().SignupHoneypot {
+		t.Errorf("signup rate limit = %+v, honeypot = %v", cfg.SignupRateLimit, cfg.SignupHoneypot)
+	}
+	if cfg.UsernameCheckRateLimit != (UsernameCheckRateLimit{Limit: 0, Window: 10 * time.Minute}) || Default().UsernameCheckRateLimit.Limit != 30 {
+		t.Errorf("username check rate limit = %+v", cfg.UsernameCheckRateLimit)
+	}
+	if cfg.DailyChirpQuota != 0 || Default().DailyChirpQuota != 100 {
+		t.Errorf("daily chirp quota = %d, default %d", cfg.DailyChirpQuota, Default().DailyChirpQuota)
+	}
+	if cfg.ViewFlushInterval != time.Minute || !cfg.CountListViews || Default().ViewFlushInterval != 10*time.Second {
+		t.Errorf("view flush interval = %v, count list views = %v", cfg.ViewFlushInterval, cfg.CountListViews)
+	}
+	if cfg.PurgeBatchSize != 50 || Default().PurgeBatchSize != 1000 {
+		t.Errorf("purge batch size = %d, default %d", cfg.PurgeBatchSize, Default().PurgeBatchSize)
+	}
+	wantPasswords := auth.PasswordParams{
+		Algorithm:  auth.AlgorithmArgon2id,
+		BcryptCost: 12,
+		Argon2:     auth.Argon2Params{Memory: 19456, Iterations: 2, Parallelism: 4},
+	}
+	if cfg.PasswordHashing != wantPasswords || Default().PasswordHashing.Algorithm != auth.AlgorithmBcrypt || Default().PasswordHashing.BcryptCost != 10 {
+		t.Errorf("password hashing = %+v, want %+v", cfg.PasswordHashing, wantPasswords)
+	}
+	if cfg.AccessTokenTTL != 15*time.Minute || Default().AccessTokenTTL != time.Hour {
+		t.Errorf("access token TTL = %v, default %v", cfg.AccessTokenTTL, Default().AccessTokenTTL)
+	}
+	if cfg.RefreshTokenTTL != (RefreshTokenTTL{Session: 12 * time.Hour, RememberMe: Default().RefreshTokenTTL.RememberMe}) {
+		t.Errorf("refresh token TTL = %+v", cfg.RefreshTokenTTL)
+	}
+	if cfg.PublicURL != "https://chirpy.example.com" {
+		t.Errorf("public URL = %q, want it without the trailing slash", cfg.PublicURL)
+	}
+	if !cfg.TrustProxy || Default().TrustProxy {
+		t.Errorf("trust proxy = %v", cfg.TrustProxy)
+	}
+	if !cfg.ChirpMediaLocalOnly || Default().ChirpMediaLocalOnly {
+		t.Errorf("chirp media local only = %v", cfg.ChirpMediaLocalOnly)
+	}
+	if !cfg.HideEmails || !cfg.UserDirectoryRequiresAuth || Default().HideEmails || Default().UserDirectoryRequiresAuth {
+		t.Errorf("hide emails = %v, user directory requires auth = %v", cfg.HideEmails, cfg.UserDirectoryRequiresAuth)
+	}
+	if cfg.DBReplicaURL != "postgres://replica/chirpy" || Default().DBReplicaURL != "" {
+		t.Errorf("replica URL = %q", cfg.DBReplicaURL)
+	}
+	if cfg.Port != "9000" || cfg.LegacyAPI || cfg.ChirpCacheTTL != 0 || !cfg.Google.Enabled() {
+		t.Errorf("got %+v", cfg)
+	}
+	if cfg.Pprof != (Pprof{BlockRate: 1000}) {
+		t.Errorf("pprof = %+v, want off outside dev with the block rate set", cfg.Pprof)
+	}
+}
+
+func TestLoadPprof(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want bool
+	}{
+		{"dev", map[string]string{"PLATFORM": "dev"}, true},
+		{"prod", nil, false},
+		{"enabled in prod", map[string]string{"ENABLE_PPROF": "true"}, true},
+		{"disabled in dev", map[string]string{"PLATFORM": "dev", "ENABLE_PPROF": "false"}, false},
+	}
+	for _, tt := range tests {
+		cfg, err := load(tt.env)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if cfg.Pprof.Enabled != tt.want {
+			t.Errorf("%s: pprof enabled = %v, want %v", tt.name, cfg.Pprof.Enabled, tt.want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "empty environment",
+			env:  map[string]string{"DB_URL": "", "PLATFORM": "", "JWT_SECRET": "", "POLKA_KEY": ""},
+			want: "invalid configuration:\n" +
+				"  DB_URL is not set\n" +
+				"  PLATFORM is not set\n" +
+				"  JWT_SECRET is not set\n" +
+				"  POLKA_KEY is not set",
+		},
+		{
+			name: "missing secret and bad values",
+			env: map[string]string{
+				"JWT_SECRET":              "",
+				"PORT":                    "http",
+				"PUBLIC_URL":              "chirpy.example.com",
+				"SIGNUP_PRIVACY":          "sometimes",
+				"WRITE_TIMEOUT":           "-1s",
+				"CHIRP_RATE_LIMIT_RED":    "-1",
+				"CHIRP_RATE_WINDOW":       "0s",
+				"SIGNUP_RATE_LIMIT":       "many",
+				"PURGE_BATCH_SIZE":        "0",
+				"PASSWORD_HASH_ALGORITHM": "scrypt",
+				"BCRYPT_COST":             "32",
+				"ARGON2_PARALLELISM":      "256",
+				"REFRESH_TOKEN_TTL":       "180d",
+			},
+			want: "invalid configuration:\n" +
+				"  JWT_SECRET is not set\n" +
+				`  PORT must be a port number, got "http"` + "\n" +
+				`  PUBLIC_URL must be an absolute http or https URL, got "chirpy.example.com"` + "\n" +
+				`  SIGNUP_PRIVACY must be a boolean, got "sometimes"` + "\n" +
+				"  WRITE_TIMEOUT must not be negative\n" +
+				`  CHIRP_RATE_LIMIT_RED must be a non-negative integer, got "-1"` + "\n" +
+				"  CHIRP_RATE_WINDOW must be positive\n" +
+				`  SIGNUP_RATE_LIMIT must be a non-negative integer, got "many"` + "\n" +
+				"  PURGE_BATCH_SIZE must be positive\n" +
+				`  PASSWORD_HASH_ALGORITHM must be bcrypt or argon2id, got "scrypt"` + "\n" +
+				"  BCRYPT_COST must be between 4 and 31, got 32\n" +
+				"  ARGON2_MEMORY, ARGON2_ITERATIONS and ARGON2_PARALLELISM: parallelism must be between 1 and 255\n" +
+				`  REFRESH_TOKEN_TTL must be a duration such as 30s or 5m, got "180d"`,
+		},
+		{
+			name: "incomplete groups",
+			env: map[string]string{
+				"TLS_CERT_FILE":    "cert.pem",
+				"GOOGLE_CLIENT_ID": "id",
+				"SMTP_HOST":        "smtp.example.com",
+				"SMTP_USER":        "chirpy",
+			},
+			want: "invalid configuration:\n" +
+				"  TLS_CERT_FILE and TLS_KEY_FILE must be set together\n" +
+				"  GOOGLE_CLIENT_ID, GOOGLE_CLIENT_SECRET and GOOGLE_REDIRECT_URL must be set together\n" +
+				"  SMTP_HOST requires SMTP_PORT and SMTP_FROM\n" +
+				"  SMTP_USER and SMTP_PASS must be set together",
+		},
+		{
+			name: "redirect without TLS",
+			env:  map[string]string{"HTTP_REDIRECT_PORT": "80"},
+			want: "invalid configuration:\n  HTTP_REDIRECT_PORT requires TLS_CERT_FILE and TLS_KEY_FILE",
+		},
+	}
+	for _, tt := range tests {
+		_, err := load(tt.env)
+		var cfgErr *Error
+		if !errors.As(err, &cfgErr) {
+			t.Fatalf("%s: got %v, want a *config.Error", tt.name, err)
+		}
+		if err.Error() != tt.want {
+			t.Errorf("%s: error is\n%s\nwant\n%s", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	file := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg, err := load(map[string]string{
+		"DB_URL_FILE":     file("db_url", "postgres://db/chirpy\n"),
+		"JWT_SECRET_FILE": file("jwt_secret", " s3cret with spaces \r\n"),
+		"POLKA_KEY_FILE":  file("polka_key", "from-file"),
+		// The files win over the plain variables
+		"POLKA_KEY": "from-env",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DBURL != "postgres://db/chirpy" {
+		t.Errorf("DB URL = %q, want it without the trailing newline", cfg.DBURL)
+	}
+	if !slices.Equal(cfg.JWTSecrets, []string{" s3cret with spaces "}) {
+		t.Errorf("JWT secrets = %q, want only the line ending trimmed", cfg.JWTSecrets)
+	}
+	if cfg.PolkaKey != "from-file" {
+		t.Errorf("Polka key = %q, want the file's", cfg.PolkaKey)
+	}
+
+	missing := filepath.Join(dir, "missing")
+	empty := file("empty", "\n\n")
+	_, err = load(map[string]string{"JWT_SECRET_FILE": missing, "POLKA_KEY_FILE": empty})
+	want := "invalid configuration:\n" +
+		"  JWT_SECRET_FILE cannot be read: open " + missing + ": no such file or directory\n" +
+		`  POLKA_KEY_FILE "` + empty + `" is empty`
+	if err == nil || err.Error() != want {
+		t.Errorf("error is\n%v\nwant\n%s", err, want)
+	}
+}
+
+func TestLoadJWTSecrets(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{"single secret", map[string]string{"JWT_SECRET": "a"}, []string{"a"}},
+		{"rotation", map[string]string{"JWT_SECRET": "", "JWT_SECRETS": "new, old,older"}, []string{"new", "old", "older"}},
+		{"the list wins", map[string]string{"JWT_SECRET": "a", "JWT_SECRETS": "b,a"}, []string{"b", "a"}},
+	}
+	for _, tt := range tests {
+		cfg, err := load(tt.env)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !slices.Equal(cfg.JWTSecrets, tt.want) {
+			t.Errorf("%s: JWT secrets = %q, want %q", tt.name, cfg.JWTSecrets, tt.want)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "jwt_secrets")
+	if err := os.WriteFile(path, []byte("new,old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := load(map[string]string{"JWT_SECRET": "", "JWT_SECRETS_FILE": path})
+	if err != nil || !slices.Equal(cfg.JWTSecrets, []string{"new", "old"}) {
+		t.Errorf("from a file: got %q, %v", cfg.JWTSecrets, err)
+	}
+
+	_, err = load(map[string]string{"JWT_SECRETS": "new,,old"})
+	if want := "invalid configuration:\n  JWT_SECRETS must not have empty entries"; err == nil || err.Error() != want {
+		t.Errorf("empty entry: got %v, want %s", err, want)
+	}
+}
+
+func TestLoadSMTPIgnoredInDev(t *testing.T) {
+	if _, err := load(map[string]string{"PLATFORM": "dev", "SMTP_HOST": "smtp.example.com"}); err != nil {
+		t.Errorf("incomplete SMTP settings in dev: %v", err)
+	}
+}
+
+func TestParseFlagOverridesEnv(t *testing.T) {
+	env := maps.Clone(required)
+	env["PORT"] = "8000"
+	env["SPA_MODE"] = "false"
+	cfg, err := Parse([]string{"-port", "9090", "-platform=dev", "-spa-mode"}, lookupIn(env), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != "9090" || cfg.Platform != "dev" || !cfg.SPAMode {
+		t.Errorf("got port %q, platform %q, SPA mode %v; want the flags to win", cfg.Port, cfg.Platform, cfg.SPAMode)
+	}
+	if cfg.DBURL != required["DB_URL"] {
+		t.Errorf("DB URL = %q, want it from the environment", cfg.DBURL)
+	}
+	if !cfg.Pprof.Enabled {
+		t.Error("pprof is off, want it on by default with -platform=dev")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	lookupEnv := lookupIn(required)
+	tests := []struct {
+		args []string
+		want error
+	}{
+		{[]string{"-help"}, flag.ErrHelp},
+		{[]string{"-version"}, ErrVersion},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		if _, err := Parse(tt.args, lookupEnv, &out); !errors.Is(err, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.args, err, tt.want)
+		}
+	}
+
+	var out strings.Builder
+	if _, err := Parse([]string{"-help"}, lookupEnv, &out); !strings.Contains(out.String(), "-db-url") || !strings.Contains(out.String(), "[DB_URL]") {
+		t.Errorf("usage is\n%s\nwant -db-url with its variable", out.String())
+	} else if err == nil {
+		t.Error("-help returned no error")
+	}
+
+	for _, args := range [][]string{{"-no-such-flag"}, {"serve"}} {
+		if _, err := Parse(args, lookupEnv, io.Discard); err == nil {
+			t.Errorf("%v: got no error", args)
+		}
+	}
+	_, err := Parse([]string{"-port", "http"}, lookupEnv, io.Discard)
+	var cfgErr *Error
+	if !errors.As(err, &cfgErr) || !strings.Contains(err.Error(), "PORT must be a port number") {
+		t.Errorf("-port http: got %v, want a PORT problem", err)
+	}
+}
+
+func TestParseSeed(t *testing.T) {
+	lookupEnv := lookupIn(required)
+	cfg, err := Parse([]string{"-platform", "dev", "-seed", "-seed-users", "3"}, lookupEnv, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Seed != (Seed{Enabled: true, Users: 3, Chirps: 300}) {
+		t.Errorf("seed = %+v", cfg.Seed)
+	}
+
+	_, err = Parse([]string{"-seed", "-seed-users", "0"}, lookupEnv, io.Discard)
+	want := "invalid configuration:\n  -seed requires PLATFORM=dev\n  -seed-users must be at least 1"
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v, want\n%s", err, want)
+	}
+}
+
+func TestParseEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	lookupEnv := lookupIn(required)
+
+	// Without a .env file the environment is all there is
+	cfg, err := Parse(nil, lookupEnv, io.Discard)
+	if err != nil || cfg.EnvFile != "" {
+		t.Fatalf("no .env: got %v, EnvFile %q", err, cfg.EnvFile)
+	}
+
+	// Flags win over the environment, which wins over .env, which wins
+	// over the defaults
+	os.WriteFile(".env", []byte("PORT=7000\nPLATFORM=dev\nFILEPATH_ROOT=/srv/www\n"), 0o600)
+	env := maps.Clone(required)
+	env["PORT"] = "8000"
+	cfg, err = Parse([]string{"-platform", "staging"}, lookupIn(env), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Platform != "staging" || cfg.Port != "8000" || cfg.FilepathRoot != "/srv/www" || cfg.MediaDir != Default().MediaDir {
+		t.Errorf("got platform %q, port %q, root %q, media dir %q; want the flag, environment, .env and default", cfg.Platform, cfg.Port, cfg.FilepathRoot, cfg.MediaDir)
+	}
+	if cfg.EnvFile != ".env" {
+		t.Errorf("EnvFile = %q, want .env", cfg.EnvFile)
+	}
+
+	// A variable set to nothing still hides the .env file's value
+	env["FILEPATH_ROOT"] = ""
+	cfg, err = Parse(nil, lookupIn(env), io.Discard)
+	if err != nil || cfg.FilepathRoot != Default().FilepathRoot {
+		t.Errorf("empty FILEPATH_ROOT: got %v, root %q, want the default %q", err, cfg.FilepathRoot, Default().FilepathRoot)
+	}
+
+	// ENV_FILE, or its flag, replaces .env
+	other := filepath.Join(dir, "chirpy.env")
+	os.WriteFile(other, []byte("PORT=7001\n"), 0o600)
+	for _, args := range [][]string{{"-env-file", other}, nil} {
+		env := maps.Clone(required)
+		env["ENV_FILE"] = other
+		cfg, err := Parse(args, lookupIn(env), io.Discard)
+		if err != nil || cfg.Port != "7001" || cfg.EnvFile != other || cfg.FilepathRoot != Default().FilepathRoot {
+			t.Errorf("%v: got %v, port %q, EnvFile %q, root %q; want only %s read", args, err, cfg.Port, cfg.EnvFile, cfg.FilepathRoot, other)
+		}
+	}
+
+	// A file that was asked for must be there
+	_, err = Parse([]string{"-env-file", filepath.Join(dir, "missing.env")}, lookupEnv, io.Discard)
+	var cfgErr *Error
+	if !errors.As(err, &cfgErr) || !strings.Contains(err.Error(), "ENV_FILE cannot be read") {
+		t.Errorf("missing ENV_FILE: got %v, want a problem naming it", err)
+	}
+}
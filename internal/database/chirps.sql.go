@@ -7,29 +7,146 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const addChirpViews = `-- name: AddChirpViews :exec
+UPDATE chirps SET view_count = chirps.view_count + views.n
+FROM unnest($1::uuid[], $2::bigint[]) AS views(chirp_id, n)
+WHERE chirps.id = views.chirp_id
+`
+
+type AddChirpViewsParams struct {
+	ChirpIds []uuid.UUID
+	Views    []int64
+}
+
+// Adds views[i] to the view count of chirp_ids[i], for a whole batch in
+// one statement. updated_at is left alone: a view is not an edit.
+func (q *Queries) AddChirpViews(ctx context.Context, arg AddChirpViewsParams) error {
+	_, err := q.exec(ctx, q.addChirpViewsStmt, addChirpViews, pq.Array(arg.ChirpIds), pq.Array(arg.Views))
+	return err
+}
+
+const countChirps = `-- name: CountChirps :one
+SELECT COUNT(*) FROM chirps
+WHERE deleted_at IS NULL
+`
+
+// Chirps that are not deleted, for the admin metrics page.
+func (q *Queries) CountChirps(ctx context.Context) (int64, error) {
+	row := q.queryRow(ctx, q.countChirpsStmt, countChirps)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countChirpsBefore = `-- name: CountChirpsBefore :one
+SELECT COUNT(*) FROM chirps
+WHERE created_at < $1
+`
+
+// Chirps, deleted or not, that a purge with this cutoff would remove.
+func (q *Queries) CountChirpsBefore(ctx context.Context, before time.Time) (int64, error) {
+	row := q.queryRow(ctx, q.countChirpsBeforeStmt, countChirpsBefore, before)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countChirpsWithMedia = `-- name: CountChirpsWithMedia :one
+SELECT COUNT(*) FROM chirps
+WHERE media_url = $1
+`
+
+// Chirps, deleted or not, whose image is media_url, so an uploaded file
+// still shown by one is not removed.
+func (q *Queries) CountChirpsWithMedia(ctx context.Context, mediaUrl sql.NullString) (int64, error) {
+	row := q.queryRow(ctx, q.countChirpsWithMediaStmt, countChirpsWithMedia, mediaUrl)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_chirp_id, media_url, media_alt)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, view_count, media_url, media_alt
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
+	MediaUrl      sql.NullString
+	MediaAlt      sql.NullString
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.queryRow(ctx, q.createChirpStmt, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ParentChirpID,
+		arg.MediaUrl,
+		arg.MediaAlt,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.ViewCount,
+		&i.MediaUrl,
+		&i.MediaAlt,
+	)
+	return i, err
+}
+
+const createChirpAt = `-- name: CreateChirpAt :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_chirp_id)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, view_count, media_url, media_alt
+`
+
+type CreateChirpAtParams struct {
+	CreatedAt     time.Time
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
+}
+
+// CreateChirp with a given creation time, for backdated sample data.
+func (q *Queries) CreateChirpAt(ctx context.Context, arg CreateChirpAtParams) (Chirp, error) {
+	row := q.queryRow(ctx, q.createChirpAtStmt, createChirpAt,
+		arg.CreatedAt,
+		arg.Body,
+		arg.UserID,
+		arg.ParentChirpID,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,67 +154,392 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.ViewCount,
+		&i.MediaUrl,
+		&i.MediaAlt,
 	)
 	return i, err
 }
 
+const createChirps = `-- name: CreateChirps :many
+INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+SELECT gen_random_uuid(),
+    NOW() + bodies.n * INTERVAL '1 microsecond',
+    NOW() + bodies.n * INTERVAL '1 microsecond',
+    bodies.body,
+    $1
+FROM unnest($2::text[]) WITH ORDINALITY AS bodies(body, n)
+RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, view_count, media_url, media_alt
+`
+
+type CreateChirpsParams struct {
+	UserID uuid.UUID
+	Bodies []string
+}
+
+// A single statement, so either every body is stored or none is. Each chirp
+// is a microsecond later than the one before it to keep the given order.
+func (q *Queries) CreateChirps(ctx context.Context, arg CreateChirpsParams) ([]Chirp, error) {
+	rows, err := q.query(ctx, q.createChirpsStmt, createChirps, arg.UserID, pq.Array(arg.Bodies))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.ViewCount,
+			&i.MediaUrl,
+			&i.MediaAlt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const deleteAllChirps = `-- name: DeleteAllChirps :exec
 DELETE FROM chirps
 `
 
 func (q *Queries) DeleteAllChirps(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteAllChirps)
+	_, err := q.exec(ctx, q.deleteAllChirpsStmt, deleteAllChirps)
 	return err
 }
 
 const deleteChirp = `-- name: DeleteChirp :exec
-DELETE FROM chirps
-WHERE id = $1
+WITH deleted AS (
+    UPDATE chirps SET deleted_at = NOW(), updated_at = NOW()
+    WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+    RETURNING chirps.id
+), unpinned AS (
+    UPDATE users SET pinned_chirp_id = NULL
+    WHERE pinned_chirp_id IN (SELECT id FROM deleted)
+)
+UPDATE chirps SET parent_chirp_id = NULL
+WHERE parent_chirp_id IN (SELECT id FROM deleted)
 `
 
+// Soft delete: the row stays for moderation but every read query skips it.
+// Replies are detached and the author's pin cleared as a hard delete's
+// ON DELETE SET NULL would.
 func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteChirp, id)
+	_, err := q.exec(ctx, q.deleteChirpStmt, deleteChirp, id)
 	return err
 }
 
+const deleteUserChirpsBefore = `-- name: DeleteUserChirpsBefore :many
+WITH deleted AS (
+    UPDATE chirps SET deleted_at = NOW(), updated_at = NOW()
+    WHERE chirps.user_id = $1
+      AND chirps.created_at < $2
+      AND chirps.deleted_at IS NULL
+    RETURNING chirps.id
+), unpinned AS (
+    UPDATE users SET pinned_chirp_id = NULL
+    WHERE pinned_chirp_id IN (SELECT id FROM deleted)
+), detached AS (
+    UPDATE chirps SET parent_chirp_id = NULL
+    WHERE parent_chirp_id IN (SELECT id FROM deleted)
+      AND id NOT IN (SELECT id FROM deleted)
+)
+SELECT id FROM deleted
+`
+
+type DeleteUserChirpsBeforeParams struct {
+	UserID uuid.UUID
+	Before time.Time
+}
+
+// DeleteChirp for each of the user's chirps created before the given time,
+// in one statement. A reply deleted with its parent is left attached, so no
+// row is updated twice. Returns the IDs of the deleted chirps.
+func (q *Queries) DeleteUserChirpsBefore(ctx context.Context, arg DeleteUserChirpsBeforeParams) ([]uuid.UUID, error) {
+	rows, err := q.query(ctx, q.deleteUserChirpsBeforeStmt, deleteUserChirpsBefore, arg.UserID, arg.Before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteUserChirpsByIDs = `-- name: DeleteUserChirpsByIDs :many
+WITH deleted AS (
+    UPDATE chirps SET deleted_at = NOW(), updated_at = NOW()
+    WHERE chirps.id = ANY($1::uuid[])
+      AND chirps.user_id = $2
+      AND chirps.deleted_at IS NULL
+    RETURNING chirps.id
+), unpinned AS (
+    UPDATE users SET pinned_chirp_id = NULL
+    WHERE pinned_chirp_id IN (SELECT id FROM deleted)
+), detached AS (
+    UPDATE chirps SET parent_chirp_id = NULL
+    WHERE parent_chirp_id IN (SELECT id FROM deleted)
+      AND id NOT IN (SELECT id FROM deleted)
+)
+SELECT id FROM deleted
+`
+
+type DeleteUserChirpsByIDsParams struct {
+	ChirpIds []uuid.UUID
+	UserID   uuid.UUID
+}
+
+// DeleteChirp for each of chirp_ids that is one of the user's chirps, in
+// one statement. A reply deleted with its parent is left attached, so no
+// row is updated twice. Returns the IDs of the deleted chirps.
+func (q *Queries) DeleteUserChirpsByIDs(ctx context.Context, arg DeleteUserChirpsByIDsParams) ([]uuid.UUID, error) {
+	rows, err := q.query(ctx, q.deleteUserChirpsByIDsStmt, deleteUserChirpsByIDs, pq.Array(arg.ChirpIds), arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE id = $1
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.view_count, chirps.media_url, chirps.media_alt, COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count
+FROM chirps
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+GROUP BY chirps.id
 `
 
-func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirpByID, id)
-	var i Chirp
+type GetChirpByIDRow struct {
+	Chirp      Chirp
+	LikeCount  int64
+	ReplyCount int64
+}
+
+func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (GetChirpByIDRow, error) {
+	row := q.queryRow(ctx, q.getChirpByIDStmt, getChirpByID, id)
+	var i GetChirpByIDRow
 	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Body,
-		&i.UserID,
+		&i.Chirp.ID,
+		&i.Chirp.CreatedAt,
+		&i.Chirp.UpdatedAt,
+		&i.Chirp.Body,
+		&i.Chirp.UserID,
+		&i.Chirp.ParentChirpID,
+		&i.Chirp.DeletedAt,
+		&i.Chirp.ViewCount,
+		&i.Chirp.MediaUrl,
+		&i.Chirp.MediaAlt,
+		&i.LikeCount,
+		&i.ReplyCount,
 	)
 	return i, err
 }
 
+const getChirpByIDWithAuthor = `-- name: GetChirpByIDWithAuthor :one
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.view_count, chirps.media_url, chirps.media_alt, COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count,
+    users.email AS author_email, users.username AS author_username, users.display_name AS author_display_name, users.bio AS author_bio, users.avatar_url AS author_avatar_url, users.is_chirpy_red AS author_is_chirpy_red,
+    users.deleted_at IS NOT NULL AS author_deleted
+FROM chirps
+INNER JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+GROUP BY chirps.id, users.id
+`
+
+type GetChirpByIDWithAuthorRow struct {
+	Chirp             Chirp
+	LikeCount         int64
+	ReplyCount        int64
+	AuthorEmail       string
+	AuthorUsername    string
+	AuthorDisplayName sql.NullString
+	AuthorBio         sql.NullString
+	AuthorAvatarUrl   sql.NullString
+	AuthorIsChirpyRed bool
+	AuthorDeleted     bool
+}
+
+// GetChirpByID plus the author's public profile.
+func (q *Queries) GetChirpByIDWithAuthor(ctx context.Context, id uuid.UUID) (GetChirpByIDWithAuthorRow, error) {
+	row := q.queryRow(ctx, q.getChirpByIDWithAuthorStmt, getChirpByIDWithAuthor, id)
+	var i GetChirpByIDWithAuthorRow
+	err := row.Scan(
+		&i.Chirp.ID,
+		&i.Chirp.CreatedAt,
+		&i.Chirp.UpdatedAt,
+		&i.Chirp.Body,
+		&i.Chirp.UserID,
+		&i.Chirp.ParentChirpID,
+		&i.Chirp.DeletedAt,
+		&i.Chirp.ViewCount,
+		&i.Chirp.MediaUrl,
+		&i.Chirp.MediaAlt,
+		&i.LikeCount,
+		&i.ReplyCount,
+		&i.AuthorEmail,
+		&i.AuthorUsername,
+		&i.AuthorDisplayName,
+		&i.AuthorBio,
+		&i.AuthorAvatarUrl,
+		&i.AuthorIsChirpyRed,
+		&i.AuthorDeleted,
+	)
+	return i, err
+}
+
+const getChirpReplies = `-- name: GetChirpReplies :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.view_count, chirps.media_url, chirps.media_alt, COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count
+FROM chirps
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirps.parent_chirp_id = $1::uuid AND chirps.deleted_at IS NULL
+  AND can_view_user(chirps.user_id, $2::uuid)
+GROUP BY chirps.id
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $3 OFFSET $4
+`
+
+type GetChirpRepliesParams struct {
+	ParentID uuid.UUID
+	ViewerID uuid.NullUUID
+	Limit    int32
+	Offset   int32
+}
+
+type GetChirpRepliesRow struct {
+	Chirp      Chirp
+	LikeCount  int64
+	ReplyCount int64
+}
+
+// Only replies viewer_id may see, which is NULL for an anonymous request.
+func (q *Queries) GetChirpReplies(ctx context.Context, arg GetChirpRepliesParams) ([]GetChirpRepliesRow, error) {
+	rows, err := q.query(ctx, q.getChirpRepliesStmt, getChirpReplies, arg.ParentID, arg.ViewerID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpRepliesRow
+	for rows.Next() {
+		var i GetChirpRepliesRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.ParentChirpID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.ViewCount,
+			&i.Chirp.MediaUrl,
+			&i.Chirp.MediaAlt,
+			&i.LikeCount,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-ORDER BY created_at ASC
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.view_count, chirps.media_url, chirps.media_alt, COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count
+FROM chirps
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirps.deleted_at IS NULL
+  AND ($1::timestamp IS NULL OR chirps.created_at >= $1)
+  AND ($2::timestamp IS NULL OR chirps.created_at < $2)
+GROUP BY chirps.id
+ORDER BY chirps.created_at DESC, chirps.id DESC
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type GetChirpsParams struct {
+	CreatedFrom   sql.NullTime
+	CreatedBefore sql.NullTime
+}
+
+type GetChirpsRow struct {
+	Chirp      Chirp
+	LikeCount  int64
+	ReplyCount int64
+}
+
+// Newest first. The ID breaks ties between chirps created in the same
+// microsecond, such as a bulk insert, so the order is always the same.
+func (q *Queries) GetChirps(ctx context.Context, arg GetChirpsParams) ([]GetChirpsRow, error) {
+	rows, err := q.query(ctx, q.getChirpsStmt, getChirps, arg.CreatedFrom, arg.CreatedBefore)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Chirp
+	var items []GetChirpsRow
 	for rows.Next() {
-		var i Chirp
+		var i GetChirpsRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.Body,
-			&i.UserID,
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.ParentChirpID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.ViewCount,
+			&i.Chirp.MediaUrl,
+			&i.Chirp.MediaAlt,
+			&i.LikeCount,
+			&i.ReplyCount,
 		); err != nil {
 			return nil, err
 		}
@@ -113,13 +555,348 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpsByUserID = `-- name: GetChirpsByUserID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.view_count, chirps.media_url, chirps.media_alt, COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count
+FROM chirps
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirps.user_id = $1 AND chirps.deleted_at IS NULL
+  AND ($2::timestamp IS NULL OR chirps.created_at >= $2)
+  AND ($3::timestamp IS NULL OR chirps.created_at < $3)
+GROUP BY chirps.id
+ORDER BY chirps.created_at DESC, chirps.id DESC
+`
+
+type GetChirpsByUserIDParams struct {
+	UserID        uuid.UUID
+	CreatedFrom   sql.NullTime
+	CreatedBefore sql.NullTime
+}
+
+type GetChirpsByUserIDRow struct {
+	Chirp      Chirp
+	LikeCount  int64
+	ReplyCount int64
+}
+
+func (q *Queries) GetChirpsByUserID(ctx context.Context, arg GetChirpsByUserIDParams) ([]GetChirpsByUserIDRow, error) {
+	rows, err := q.query(ctx, q.getChirpsByUserIDStmt, getChirpsByUserID, arg.UserID, arg.CreatedFrom, arg.CreatedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpsByUserIDRow
+	for rows.Next() {
+		var i GetChirpsByUserIDRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.ParentChirpID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.ViewCount,
+			&i.Chirp.MediaUrl,
+			&i.Chirp.MediaAlt,
+			&i.LikeCount,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsSince = `-- name: GetChirpsSince :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.view_count, chirps.media_url, chirps.media_alt, COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count
+FROM chirps
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE (chirps.created_at, chirps.id) > (
+        SELECT since.created_at, since.id FROM chirps AS since WHERE since.id = $1
+    )
+  AND ($2::uuid IS NULL OR chirps.user_id = $2)
+  AND chirps.deleted_at IS NULL
+  AND can_view_user(chirps.user_id, NULL)
+GROUP BY chirps.id
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $3
+`
+
+type GetChirpsSinceParams struct {
+	SinceID  uuid.UUID
+	AuthorID uuid.NullUUID
+	Limit    int32
+}
+
+type GetChirpsSinceRow struct {
+	Chirp      Chirp
+	LikeCount  int64
+	ReplyCount int64
+}
+
+// Live streams are anonymous, so only public accounts' chirps are included.
+func (q *Queries) GetChirpsSince(ctx context.Context, arg GetChirpsSinceParams) ([]GetChirpsSinceRow, error) {
+	rows, err := q.query(ctx, q.getChirpsSinceStmt, getChirpsSince, arg.SinceID, arg.AuthorID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpsSinceRow
+	for rows.Next() {
+		var i GetChirpsSinceRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.ParentChirpID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.ViewCount,
+			&i.Chirp.MediaUrl,
+			&i.Chirp.MediaAlt,
+			&i.LikeCount,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsWithAuthors = `-- name: GetChirpsWithAuthors :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.view_count, chirps.media_url, chirps.media_alt, COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count,
+    users.email AS author_email, users.username AS author_username, users.display_name AS author_display_name, users.bio AS author_bio, users.avatar_url AS author_avatar_url, users.is_chirpy_red AS author_is_chirpy_red,
+    users.deleted_at IS NOT NULL AS author_deleted
+FROM chirps
+INNER JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirps.deleted_at IS NULL
+  AND ($1::uuid IS NULL OR chirps.user_id = $1)
+  AND ($2::timestamp IS NULL OR chirps.created_at >= $2)
+  AND ($3::timestamp IS NULL OR chirps.created_at < $3)
+GROUP BY chirps.id, users.id
+ORDER BY chirps.created_at DESC, chirps.id DESC
+`
+
+type GetChirpsWithAuthorsParams struct {
+	AuthorID      uuid.NullUUID
+	CreatedFrom   sql.NullTime
+	CreatedBefore sql.NullTime
+}
+
+type GetChirpsWithAuthorsRow struct {
+	Chirp             Chirp
+	LikeCount         int64
+	ReplyCount        int64
+	AuthorEmail       string
+	AuthorUsername    string
+	AuthorDisplayName sql.NullString
+	AuthorBio         sql.NullString
+	AuthorAvatarUrl   sql.NullString
+	AuthorIsChirpyRed bool
+	AuthorDeleted     bool
+}
+
+// GetChirps, optionally limited to one author, plus each author's public
+// profile.
+func (q *Queries) GetChirpsWithAuthors(ctx context.Context, arg GetChirpsWithAuthorsParams) ([]GetChirpsWithAuthorsRow, error) {
+	rows, err := q.query(ctx, q.getChirpsWithAuthorsStmt, getChirpsWithAuthors, arg.AuthorID, arg.CreatedFrom, arg.CreatedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpsWithAuthorsRow
+	for rows.Next() {
+		var i GetChirpsWithAuthorsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.ParentChirpID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.ViewCount,
+			&i.Chirp.MediaUrl,
+			&i.Chirp.MediaAlt,
+			&i.LikeCount,
+			&i.ReplyCount,
+			&i.AuthorEmail,
+			&i.AuthorUsername,
+			&i.AuthorDisplayName,
+			&i.AuthorBio,
+			&i.AuthorAvatarUrl,
+			&i.AuthorIsChirpyRed,
+			&i.AuthorDeleted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeed = `-- name: GetFeed :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.deleted_at, chirps.view_count, chirps.media_url, chirps.media_alt, COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count
+FROM chirps
+LEFT JOIN follows ON follows.followee_id = chirps.user_id
+    AND follows.follower_id = $1
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE (follows.follower_id IS NOT NULL
+       OR ($2::boolean AND chirps.user_id = $1))
+  AND NOT EXISTS (
+      SELECT 1 FROM mutes
+      WHERE mutes.muter_id = $1 AND mutes.muted_id = chirps.user_id
+  )
+  AND can_view_user(chirps.user_id, $1)
+  AND chirps.deleted_at IS NULL
+GROUP BY chirps.id
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $3 OFFSET $4
+`
+
+type GetFeedParams struct {
+	UserID      uuid.UUID
+	IncludeSelf bool
+	Limit       int32
+	Offset      int32
+}
+
+type GetFeedRow struct {
+	Chirp      Chirp
+	LikeCount  int64
+	ReplyCount int64
+}
+
+func (q *Queries) GetFeed(ctx context.Context, arg GetFeedParams) ([]GetFeedRow, error) {
+	rows, err := q.query(ctx, q.getFeedStmt, getFeed,
+		arg.UserID,
+		arg.IncludeSelf,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedRow
+	for rows.Next() {
+		var i GetFeedRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.ParentChirpID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.ViewCount,
+			&i.Chirp.MediaUrl,
+			&i.Chirp.MediaAlt,
+			&i.LikeCount,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNthNewestChirpTime = `-- name: GetNthNewestChirpTime :one
+SELECT created_at FROM chirps
 WHERE user_id = $1
-ORDER BY created_at ASC
+ORDER BY created_at DESC
+OFFSET $2
+LIMIT 1
 `
 
-func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByUserID, userID)
+type GetNthNewestChirpTimeParams struct {
+	UserID uuid.UUID
+	Offset int32
+}
+
+// When the user's chirp with Offset newer ones was posted, deleted or not,
+// so the daily quota can tell when a slot frees up.
+func (q *Queries) GetNthNewestChirpTime(ctx context.Context, arg GetNthNewestChirpTimeParams) (time.Time, error) {
+	row := q.queryRow(ctx, q.getNthNewestChirpTimeStmt, getNthNewestChirpTime, arg.UserID, arg.Offset)
+	var created_at time.Time
+	err := row.Scan(&created_at)
+	return created_at, err
+}
+
+const getUserChirpStats = `-- name: GetUserChirpStats :one
+SELECT COUNT(*) AS chirp_count, MIN(created_at) AS first_chirp_at, MAX(created_at) AS last_chirp_at
+FROM chirps
+WHERE user_id = $1 AND deleted_at IS NULL
+`
+
+type GetUserChirpStatsRow struct {
+	ChirpCount   int64
+	FirstChirpAt sql.NullTime
+	LastChirpAt  sql.NullTime
+}
+
+func (q *Queries) GetUserChirpStats(ctx context.Context, userID uuid.UUID) (GetUserChirpStatsRow, error) {
+	row := q.queryRow(ctx, q.getUserChirpStatsStmt, getUserChirpStats, userID)
+	var i GetUserChirpStatsRow
+	err := row.Scan(&i.ChirpCount, &i.FirstChirpAt, &i.LastChirpAt)
+	return i, err
+}
+
+const getUserChirpsAfter = `-- name: GetUserChirpsAfter :many
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, view_count, media_url, media_alt FROM chirps
+WHERE user_id = $1 AND deleted_at IS NULL
+  AND (created_at, id) > ($2::timestamp, $3::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT $4
+`
+
+type GetUserChirpsAfterParams struct {
+	UserID         uuid.UUID
+	AfterCreatedAt time.Time
+	AfterID        uuid.UUID
+	Limit          int32
+}
+
+// Keyset pagination over a user's chirps in creation order, for exports.
+func (q *Queries) GetUserChirpsAfter(ctx context.Context, arg GetUserChirpsAfterParams) ([]Chirp, error) {
+	rows, err := q.query(ctx, q.getUserChirpsAfterStmt, getUserChirpsAfter,
+		arg.UserID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -133,6 +910,11 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.ViewCount,
+			&i.MediaUrl,
+			&i.MediaAlt,
 		); err != nil {
 			return nil, err
 		}
@@ -146,3 +928,100 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const hasRecentDuplicateChirp = `-- name: HasRecentDuplicateChirp :one
+SELECT EXISTS (
+    SELECT 1 FROM chirps
+    WHERE user_id = $1
+      AND md5(body) = md5($2)
+      AND body = $2
+      AND parent_chirp_id IS NOT DISTINCT FROM $3
+      AND created_at >= $4
+      AND deleted_at IS NULL
+)
+`
+
+type HasRecentDuplicateChirpParams struct {
+	UserID        uuid.UUID
+	Body          string
+	ParentChirpID uuid.NullUUID
+	Since         time.Time
+}
+
+// Whether the user posted the same body, in reply to the same chirp or to
+// none, since the given time. chirps_user_id_body_hash_idx serves it.
+func (q *Queries) HasRecentDuplicateChirp(ctx context.Context, arg HasRecentDuplicateChirpParams) (bool, error) {
+	row := q.queryRow(ctx, q.hasRecentDuplicateChirpStmt, hasRecentDuplicateChirp,
+		arg.UserID,
+		arg.Body,
+		arg.ParentChirpID,
+		arg.Since,
+	)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const purgeChirpsBefore = `-- name: PurgeChirpsBefore :execrows
+DELETE FROM chirps
+WHERE id IN (
+    SELECT id FROM chirps
+    WHERE created_at < $1
+    ORDER BY created_at
+    LIMIT $2
+)
+`
+
+type PurgeChirpsBeforeParams struct {
+	Before time.Time
+	Limit  int32
+}
+
+// Hard-deletes up to limit of the oldest chirps created before the cutoff,
+// including soft-deleted ones. Their likes, bookmarks and other rows go
+// with them by cascade, and replies and pins are cleared by the foreign
+// keys.
+func (q *Queries) PurgeChirpsBefore(ctx context.Context, arg PurgeChirpsBeforeParams) (int64, error) {
+	result, err := q.exec(ctx, q.purgeChirpsBeforeStmt, purgeChirpsBefore, arg.Before, arg.Limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateChirp = `-- name: UpdateChirp :one
+WITH previous AS (
+    INSERT INTO chirp_revisions (id, chirp_id, body, created_at)
+    SELECT gen_random_uuid(), chirps.id, chirps.body, chirps.updated_at
+    FROM chirps
+    WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+)
+UPDATE chirps SET body = $2, updated_at = NOW()
+WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, view_count, media_url, media_alt
+`
+
+type UpdateChirpParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+// Records the current body as a revision in the same statement, so an edit
+// never lands without its history.
+func (q *Queries) UpdateChirp(ctx context.Context, arg UpdateChirpParams) (Chirp, error) {
+	row := q.queryRow(ctx, q.updateChirpStmt, updateChirp, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.ViewCount,
+		&i.MediaUrl,
+		&i.MediaAlt,
+	)
+	return i, err
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"database/sql"
 	"encoding/json"
 	"testing"
 	"time"
@@ -11,26 +12,26 @@ import (
 func TestUserModel(t *testing.T) {
 	userID := uuid.New()
 	now := time.Now().UTC()
-	
+
 	user := User{
 		ID:             userID,
 		CreatedAt:      now,
 		UpdatedAt:      now,
 		Email:          "test@example.com",
-		HashedPassword: "hashed_password_123",
+		HashedPassword: sql.NullString{String: "hashed_password_123", Valid: true},
 	}
 
 	// Test that all fields are set correctly
 	if user.ID != userID {
 		t.Errorf("Expected ID %v, got %v", userID, user.ID)
 	}
-	
+
 	if user.Email != "test@example.com" {
 		t.Errorf("Expected email test@example.com, got %v", user.Email)
 	}
-	
-	if user.HashedPassword != "hashed_password_123" {
-		t.Errorf("Expected hashed password hashed_password_123, got %v", user.HashedPassword)
+
+	if user.HashedPassword.String != "hashed_password_123" {
+		t.Errorf("Expected hashed password hashed_password_123, got %v", user.HashedPassword.String)
 	}
 }
 
@@ -38,7 +39,7 @@ func TestChirpModel(t *testing.T) {
 	chirpID := uuid.New()
 	userID := uuid.New()
 	now := time.Now().UTC()
-	
+
 	chirp := Chirp{
 		ID:        chirpID,
 		CreatedAt: now,
@@ -51,11 +52,11 @@ func TestChirpModel(t *testing.T) {
 	if chirp.ID != chirpID {
 		t.Errorf("Expected ID %v, got %v", chirpID, chirp.ID)
 	}
-	
+
 	if chirp.Body != "This is a test chirp message" {
 		t.Errorf("Expected body 'This is a test chirp message', got %v", chirp.Body)
 	}
-	
+
 	if chirp.UserID != userID {
 		t.Errorf("Expected UserID %v, got %v", userID, chirp.UserID)
 	}
@@ -64,13 +65,13 @@ func TestChirpModel(t *testing.T) {
 func TestUserModelJSONSerialization(t *testing.T) {
 	userID := uuid.New()
 	now := time.Now().UTC()
-	
+
 	user := User{
 		ID:             userID,
 		CreatedAt:      now,
 		UpdatedAt:      now,
 		Email:          "test@example.com",
-		HashedPassword: "hashed_password_123",
+		HashedPassword: sql.NullString{String: "hashed_password_123", Valid: true},
 	}
 
 	// Marshal to JSON
@@ -90,11 +91,11 @@ func TestUserModelJSONSerialization(t *testing.T) {
 	if unmarshaled.ID != user.ID {
 		t.Errorf("ID mismatch after JSON round trip: got %v want %v", unmarshaled.ID, user.ID)
 	}
-	
+
 	if unmarshaled.Email != user.Email {
 		t.Errorf("Email mismatch after JSON round trip: got %v want %v", unmarshaled.Email, user.Email)
 	}
-	
+
 	if unmarshaled.HashedPassword != user.HashedPassword {
 		t.Errorf("HashedPassword mismatch after JSON round trip: got %v want %v", unmarshaled.HashedPassword, user.HashedPassword)
 	}
@@ -104,7 +105,7 @@ func TestChirpModelJSONSerialization(t *testing.T) {
 	chirpID := uuid.New()
 	userID := uuid.New()
 	now := time.Now().UTC()
-	
+
 	chirp := Chirp{
 		ID:        chirpID,
 		CreatedAt: now,
@@ -130,11 +131,11 @@ func TestChirpModelJSONSerialization(t *testing.T) {
 	if unmarshaled.ID != chirp.ID {
 		t.Errorf("ID mismatch after JSON round trip: got %v want %v", unmarshaled.ID, chirp.ID)
 	}
-	
+
 	if unmarshaled.Body != chirp.Body {
 		t.Errorf("Body mismatch after JSON round trip: got %v want %v", unmarshaled.Body, chirp.Body)
 	}
-	
+
 	if unmarshaled.UserID != chirp.UserID {
 		t.Errorf("UserID mismatch after JSON round trip: got %v want %v", unmarshaled.UserID, chirp.UserID)
 	}
@@ -143,15 +144,15 @@ func TestChirpModelJSONSerialization(t *testing.T) {
 func TestCreateUserParams(t *testing.T) {
 	params := CreateUserParams{
 		Email:          "test@example.com",
-		HashedPassword: "hashed_password_123",
+		HashedPassword: sql.NullString{String: "hashed_password_123", Valid: true},
 	}
 
 	if params.Email != "test@example.com" {
 		t.Errorf("Expected email test@example.com, got %v", params.Email)
 	}
-	
-	if params.HashedPassword != "hashed_password_123" {
-		t.Errorf("Expected hashed password hashed_password_123, got %v", params.HashedPassword)
+
+	if params.HashedPassword.String != "hashed_password_123" {
+		t.Errorf("Expected hashed password hashed_password_123, got %v", params.HashedPassword.String)
 	}
 }
 
@@ -165,10 +166,15 @@ func TestCreateChirpParams(t *testing.T) {
 	if params.Body != "Test chirp body" {
 		t.Errorf("Expected body 'Test chirp body', got %v", params.Body)
 	}
-	
+
 	if params.UserID != userID {
 		t.Errorf("Expected UserID %v, got %v", userID, params.UserID)
 	}
+
+	// Media is optional and NULL unless given
+	if params.MediaUrl.Valid || params.MediaAlt.Valid {
+		t.Errorf("Expected no media, got %v and %v", params.MediaUrl, params.MediaAlt)
+	}
 }
 
 func TestModelFieldTypes(t *testing.T) {
@@ -181,14 +187,16 @@ func TestModelFieldTypes(t *testing.T) {
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 	user.Email = "string"
-	user.HashedPassword = "string"
+	user.HashedPassword = sql.NullString{String: "string", Valid: true}
 
 	chirp.ID = uuid.New()
 	chirp.CreatedAt = time.Now()
 	chirp.UpdatedAt = time.Now()
 	chirp.Body = "string"
 	chirp.UserID = uuid.New()
+	chirp.MediaUrl = sql.NullString{String: "https://example.com/cat.jpg", Valid: true}
+	chirp.MediaAlt = sql.NullString{String: "A cat", Valid: true}
 
 	// If we get here, all type assignments worked
 	t.Log("All model field types are correct")
-}
\ No newline at end of file
+}
@@ -7,6 +7,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"fmt"
 )
 
 type DBTX interface {
@@ -20,12 +21,1328 @@ func New(db DBTX) *Queries {
 	return &Queries{db: db}
 }
 
+func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
+	q := Queries{db: db}
+	var err error
+	if q.addChirpEntityStmt, err = db.PrepareContext(ctx, addChirpEntity); err != nil {
+		return nil, fmt.Errorf("error preparing query AddChirpEntity: %w", err)
+	}
+	if q.addChirpHashtagStmt, err = db.PrepareContext(ctx, addChirpHashtag); err != nil {
+		return nil, fmt.Errorf("error preparing query AddChirpHashtag: %w", err)
+	}
+	if q.addChirpMentionStmt, err = db.PrepareContext(ctx, addChirpMention); err != nil {
+		return nil, fmt.Errorf("error preparing query AddChirpMention: %w", err)
+	}
+	if q.addChirpViewsStmt, err = db.PrepareContext(ctx, addChirpViews); err != nil {
+		return nil, fmt.Errorf("error preparing query AddChirpViews: %w", err)
+	}
+	if q.approveAllFollowRequestsStmt, err = db.PrepareContext(ctx, approveAllFollowRequests); err != nil {
+		return nil, fmt.Errorf("error preparing query ApproveAllFollowRequests: %w", err)
+	}
+	if q.approveFollowRequestStmt, err = db.PrepareContext(ctx, approveFollowRequest); err != nil {
+		return nil, fmt.Errorf("error preparing query ApproveFollowRequest: %w", err)
+	}
+	if q.banUserStmt, err = db.PrepareContext(ctx, banUser); err != nil {
+		return nil, fmt.Errorf("error preparing query BanUser: %w", err)
+	}
+	if q.canViewUserStmt, err = db.PrepareContext(ctx, canViewUser); err != nil {
+		return nil, fmt.Errorf("error preparing query CanViewUser: %w", err)
+	}
+	if q.claimIdempotencyKeyStmt, err = db.PrepareContext(ctx, claimIdempotencyKey); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimIdempotencyKey: %w", err)
+	}
+	if q.confirmPendingEmailStmt, err = db.PrepareContext(ctx, confirmPendingEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query ConfirmPendingEmail: %w", err)
+	}
+	if q.consumeMagicLinkTokenStmt, err = db.PrepareContext(ctx, consumeMagicLinkToken); err != nil {
+		return nil, fmt.Errorf("error preparing query ConsumeMagicLinkToken: %w", err)
+	}
+	if q.countActiveRefreshTokensStmt, err = db.PrepareContext(ctx, countActiveRefreshTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query CountActiveRefreshTokens: %w", err)
+	}
+	if q.countChirpsStmt, err = db.PrepareContext(ctx, countChirps); err != nil {
+		return nil, fmt.Errorf("error preparing query CountChirps: %w", err)
+	}
+	if q.countChirpsBeforeStmt, err = db.PrepareContext(ctx, countChirpsBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query CountChirpsBefore: %w", err)
+	}
+	if q.countChirpsWithMediaStmt, err = db.PrepareContext(ctx, countChirpsWithMedia); err != nil {
+		return nil, fmt.Errorf("error preparing query CountChirpsWithMedia: %w", err)
+	}
+	if q.countFollowersStmt, err = db.PrepareContext(ctx, countFollowers); err != nil {
+		return nil, fmt.Errorf("error preparing query CountFollowers: %w", err)
+	}
+	if q.countFollowingStmt, err = db.PrepareContext(ctx, countFollowing); err != nil {
+		return nil, fmt.Errorf("error preparing query CountFollowing: %w", err)
+	}
+	if q.countUnreadNotificationsStmt, err = db.PrepareContext(ctx, countUnreadNotifications); err != nil {
+		return nil, fmt.Errorf("error preparing query CountUnreadNotifications: %w", err)
+	}
+	if q.countUsersStmt, err = db.PrepareContext(ctx, countUsers); err != nil {
+		return nil, fmt.Errorf("error preparing query CountUsers: %w", err)
+	}
+	if q.countUsersWithAvatarStmt, err = db.PrepareContext(ctx, countUsersWithAvatar); err != nil {
+		return nil, fmt.Errorf("error preparing query CountUsersWithAvatar: %w", err)
+	}
+	if q.createAPIKeyStmt, err = db.PrepareContext(ctx, createAPIKey); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAPIKey: %w", err)
+	}
+	if q.createAuditLogEntryStmt, err = db.PrepareContext(ctx, createAuditLogEntry); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAuditLogEntry: %w", err)
+	}
+	if q.createBookmarkStmt, err = db.PrepareContext(ctx, createBookmark); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateBookmark: %w", err)
+	}
+	if q.createChirpStmt, err = db.PrepareContext(ctx, createChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateChirp: %w", err)
+	}
+	if q.createChirpAtStmt, err = db.PrepareContext(ctx, createChirpAt); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateChirpAt: %w", err)
+	}
+	if q.createChirpReportStmt, err = db.PrepareContext(ctx, createChirpReport); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateChirpReport: %w", err)
+	}
+	if q.createChirpsStmt, err = db.PrepareContext(ctx, createChirps); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateChirps: %w", err)
+	}
+	if q.createFollowStmt, err = db.PrepareContext(ctx, createFollow); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateFollow: %w", err)
+	}
+	if q.createFollowRequestStmt, err = db.PrepareContext(ctx, createFollowRequest); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateFollowRequest: %w", err)
+	}
+	if q.createLoginHistoryEntryStmt, err = db.PrepareContext(ctx, createLoginHistoryEntry); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateLoginHistoryEntry: %w", err)
+	}
+	if q.createMagicLinkTokenStmt, err = db.PrepareContext(ctx, createMagicLinkToken); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateMagicLinkToken: %w", err)
+	}
+	if q.createMuteStmt, err = db.PrepareContext(ctx, createMute); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateMute: %w", err)
+	}
+	if q.createNotificationStmt, err = db.PrepareContext(ctx, createNotification); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateNotification: %w", err)
+	}
+	if q.createOAuthUserStmt, err = db.PrepareContext(ctx, createOAuthUser); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateOAuthUser: %w", err)
+	}
+	if q.createRefreshTokenStmt, err = db.PrepareContext(ctx, createRefreshToken); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateRefreshToken: %w", err)
+	}
+	if q.createUserStmt, err = db.PrepareContext(ctx, createUser); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateUser: %w", err)
+	}
+	if q.createWebhookEventStmt, err = db.PrepareContext(ctx, createWebhookEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateWebhookEvent: %w", err)
+	}
+	if q.deleteAPIKeyStmt, err = db.PrepareContext(ctx, deleteAPIKey); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteAPIKey: %w", err)
+	}
+	if q.deleteAllChirpsStmt, err = db.PrepareContext(ctx, deleteAllChirps); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteAllChirps: %w", err)
+	}
+	if q.deleteAllRefreshTokensStmt, err = db.PrepareContext(ctx, deleteAllRefreshTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteAllRefreshTokens: %w", err)
+	}
+	if q.deleteAllUsersStmt, err = db.PrepareContext(ctx, deleteAllUsers); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteAllUsers: %w", err)
+	}
+	if q.deleteBookmarkStmt, err = db.PrepareContext(ctx, deleteBookmark); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteBookmark: %w", err)
+	}
+	if q.deleteChirpStmt, err = db.PrepareContext(ctx, deleteChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteChirp: %w", err)
+	}
+	if q.deleteChirpEntitiesStmt, err = db.PrepareContext(ctx, deleteChirpEntities); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteChirpEntities: %w", err)
+	}
+	if q.deleteChirpHashtagsStmt, err = db.PrepareContext(ctx, deleteChirpHashtags); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteChirpHashtags: %w", err)
+	}
+	if q.deleteChirpMentionsStmt, err = db.PrepareContext(ctx, deleteChirpMentions); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteChirpMentions: %w", err)
+	}
+	if q.deleteExpiredIdempotencyKeysStmt, err = db.PrepareContext(ctx, deleteExpiredIdempotencyKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredIdempotencyKeys: %w", err)
+	}
+	if q.deleteExpiredMagicLinkTokensStmt, err = db.PrepareContext(ctx, deleteExpiredMagicLinkTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredMagicLinkTokens: %w", err)
+	}
+	if q.deleteFollowStmt, err = db.PrepareContext(ctx, deleteFollow); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteFollow: %w", err)
+	}
+	if q.deleteFollowRequestStmt, err = db.PrepareContext(ctx, deleteFollowRequest); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteFollowRequest: %w", err)
+	}
+	if q.deleteIdempotencyKeyStmt, err = db.PrepareContext(ctx, deleteIdempotencyKey); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteIdempotencyKey: %w", err)
+	}
+	if q.deleteMuteStmt, err = db.PrepareContext(ctx, deleteMute); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMute: %w", err)
+	}
+	if q.deleteUserChirpsBeforeStmt, err = db.PrepareContext(ctx, deleteUserChirpsBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUserChirpsBefore: %w", err)
+	}
+	if q.deleteUserChirpsByIDsStmt, err = db.PrepareContext(ctx, deleteUserChirpsByIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUserChirpsByIDs: %w", err)
+	}
+	if q.deleteUserKeepChirpsStmt, err = db.PrepareContext(ctx, deleteUserKeepChirps); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUserKeepChirps: %w", err)
+	}
+	if q.enableUserTOTPStmt, err = db.PrepareContext(ctx, enableUserTOTP); err != nil {
+		return nil, fmt.Errorf("error preparing query EnableUserTOTP: %w", err)
+	}
+	if q.getAPIKeysStmt, err = db.PrepareContext(ctx, getAPIKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAPIKeys: %w", err)
+	}
+	if q.getAccountStatusStmt, err = db.PrepareContext(ctx, getAccountStatus); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccountStatus: %w", err)
+	}
+	if q.getAuditLogStmt, err = db.PrepareContext(ctx, getAuditLog); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAuditLog: %w", err)
+	}
+	if q.getBookmarkedChirpsStmt, err = db.PrepareContext(ctx, getBookmarkedChirps); err != nil {
+		return nil, fmt.Errorf("error preparing query GetBookmarkedChirps: %w", err)
+	}
+	if q.getChirpByIDStmt, err = db.PrepareContext(ctx, getChirpByID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpByID: %w", err)
+	}
+	if q.getChirpByIDWithAuthorStmt, err = db.PrepareContext(ctx, getChirpByIDWithAuthor); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpByIDWithAuthor: %w", err)
+	}
+	if q.getChirpEntitiesStmt, err = db.PrepareContext(ctx, getChirpEntities); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpEntities: %w", err)
+	}
+	if q.getChirpLikersStmt, err = db.PrepareContext(ctx, getChirpLikers); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpLikers: %w", err)
+	}
+	if q.getChirpMentionsStmt, err = db.PrepareContext(ctx, getChirpMentions); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpMentions: %w", err)
+	}
+	if q.getChirpRepliesStmt, err = db.PrepareContext(ctx, getChirpReplies); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpReplies: %w", err)
+	}
+	if q.getChirpRevisionsStmt, err = db.PrepareContext(ctx, getChirpRevisions); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpRevisions: %w", err)
+	}
+	if q.getChirpsStmt, err = db.PrepareContext(ctx, getChirps); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirps: %w", err)
+	}
+	if q.getChirpsByHashtagStmt, err = db.PrepareContext(ctx, getChirpsByHashtag); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpsByHashtag: %w", err)
+	}
+	if q.getChirpsByUserIDStmt, err = db.PrepareContext(ctx, getChirpsByUserID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpsByUserID: %w", err)
+	}
+	if q.getChirpsSinceStmt, err = db.PrepareContext(ctx, getChirpsSince); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpsSince: %w", err)
+	}
+	if q.getChirpsWithAuthorsStmt, err = db.PrepareContext(ctx, getChirpsWithAuthors); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpsWithAuthors: %w", err)
+	}
+	if q.getDueWebhookEventsStmt, err = db.PrepareContext(ctx, getDueWebhookEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDueWebhookEvents: %w", err)
+	}
+	if q.getFeedStmt, err = db.PrepareContext(ctx, getFeed); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFeed: %w", err)
+	}
+	if q.getFollowRequestsStmt, err = db.PrepareContext(ctx, getFollowRequests); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFollowRequests: %w", err)
+	}
+	if q.getFollowersStmt, err = db.PrepareContext(ctx, getFollowers); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFollowers: %w", err)
+	}
+	if q.getFollowingStmt, err = db.PrepareContext(ctx, getFollowing); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFollowing: %w", err)
+	}
+	if q.getHiddenAuthorIDsStmt, err = db.PrepareContext(ctx, getHiddenAuthorIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query GetHiddenAuthorIDs: %w", err)
+	}
+	if q.getIdempotencyKeyStmt, err = db.PrepareContext(ctx, getIdempotencyKey); err != nil {
+		return nil, fmt.Errorf("error preparing query GetIdempotencyKey: %w", err)
+	}
+	if q.getLikedChirpIDsStmt, err = db.PrepareContext(ctx, getLikedChirpIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLikedChirpIDs: %w", err)
+	}
+	if q.getLoginHistoryStmt, err = db.PrepareContext(ctx, getLoginHistory); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLoginHistory: %w", err)
+	}
+	if q.getNotificationsStmt, err = db.PrepareContext(ctx, getNotifications); err != nil {
+		return nil, fmt.Errorf("error preparing query GetNotifications: %w", err)
+	}
+	if q.getNthNewestChirpTimeStmt, err = db.PrepareContext(ctx, getNthNewestChirpTime); err != nil {
+		return nil, fmt.Errorf("error preparing query GetNthNewestChirpTime: %w", err)
+	}
+	if q.getRefreshTokenStmt, err = db.PrepareContext(ctx, getRefreshToken); err != nil {
+		return nil, fmt.Errorf("error preparing query GetRefreshToken: %w", err)
+	}
+	if q.getReportedChirpsStmt, err = db.PrepareContext(ctx, getReportedChirps); err != nil {
+		return nil, fmt.Errorf("error preparing query GetReportedChirps: %w", err)
+	}
+	if q.getUnusedBackupCodesStmt, err = db.PrepareContext(ctx, getUnusedBackupCodes); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUnusedBackupCodes: %w", err)
+	}
+	if q.getUserByEmailStmt, err = db.PrepareContext(ctx, getUserByEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserByEmail: %w", err)
+	}
+	if q.getUserByIDStmt, err = db.PrepareContext(ctx, getUserByID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserByID: %w", err)
+	}
+	if q.getUserChirpStatsStmt, err = db.PrepareContext(ctx, getUserChirpStats); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserChirpStats: %w", err)
+	}
+	if q.getUserChirpsAfterStmt, err = db.PrepareContext(ctx, getUserChirpsAfter); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserChirpsAfter: %w", err)
+	}
+	if q.getUserFromRefreshTokenStmt, err = db.PrepareContext(ctx, getUserFromRefreshToken); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserFromRefreshToken: %w", err)
+	}
+	if q.getUserIDByAPIKeyStmt, err = db.PrepareContext(ctx, getUserIDByAPIKey); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserIDByAPIKey: %w", err)
+	}
+	if q.getUserLikeStatsStmt, err = db.PrepareContext(ctx, getUserLikeStats); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserLikeStats: %w", err)
+	}
+	if q.getUserSessionsStmt, err = db.PrepareContext(ctx, getUserSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserSessions: %w", err)
+	}
+	if q.getUserTOTPStmt, err = db.PrepareContext(ctx, getUserTOTP); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserTOTP: %w", err)
+	}
+	if q.getWebhookEventsStmt, err = db.PrepareContext(ctx, getWebhookEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebhookEvents: %w", err)
+	}
+	if q.hasRecentDuplicateChirpStmt, err = db.PrepareContext(ctx, hasRecentDuplicateChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query HasRecentDuplicateChirp: %w", err)
+	}
+	if q.likeChirpStmt, err = db.PrepareContext(ctx, likeChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query LikeChirp: %w", err)
+	}
+	if q.listUsersStmt, err = db.PrepareContext(ctx, listUsers); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUsers: %w", err)
+	}
+	if q.markAllNotificationsReadStmt, err = db.PrepareContext(ctx, markAllNotificationsRead); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkAllNotificationsRead: %w", err)
+	}
+	if q.markNotificationsReadStmt, err = db.PrepareContext(ctx, markNotificationsRead); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkNotificationsRead: %w", err)
+	}
+	if q.markWebhookEventFailedStmt, err = db.PrepareContext(ctx, markWebhookEventFailed); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkWebhookEventFailed: %w", err)
+	}
+	if q.markWebhookEventProcessedStmt, err = db.PrepareContext(ctx, markWebhookEventProcessed); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkWebhookEventProcessed: %w", err)
+	}
+	if q.pruneLoginHistoryStmt, err = db.PrepareContext(ctx, pruneLoginHistory); err != nil {
+		return nil, fmt.Errorf("error preparing query PruneLoginHistory: %w", err)
+	}
+	if q.purgeChirpsBeforeStmt, err = db.PrepareContext(ctx, purgeChirpsBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query PurgeChirpsBefore: %w", err)
+	}
+	if q.rehashUserPasswordStmt, err = db.PrepareContext(ctx, rehashUserPassword); err != nil {
+		return nil, fmt.Errorf("error preparing query RehashUserPassword: %w", err)
+	}
+	if q.replaceBackupCodesStmt, err = db.PrepareContext(ctx, replaceBackupCodes); err != nil {
+		return nil, fmt.Errorf("error preparing query ReplaceBackupCodes: %w", err)
+	}
+	if q.resolveChirpReportsStmt, err = db.PrepareContext(ctx, resolveChirpReports); err != nil {
+		return nil, fmt.Errorf("error preparing query ResolveChirpReports: %w", err)
+	}
+	if q.resolveMentionHandlesStmt, err = db.PrepareContext(ctx, resolveMentionHandles); err != nil {
+		return nil, fmt.Errorf("error preparing query ResolveMentionHandles: %w", err)
+	}
+	if q.revokeRefreshTokenStmt, err = db.PrepareContext(ctx, revokeRefreshToken); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeRefreshToken: %w", err)
+	}
+	if q.revokeRefreshTokenFamilyStmt, err = db.PrepareContext(ctx, revokeRefreshTokenFamily); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeRefreshTokenFamily: %w", err)
+	}
+	if q.revokeUserRefreshTokensStmt, err = db.PrepareContext(ctx, revokeUserRefreshTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeUserRefreshTokens: %w", err)
+	}
+	if q.setIdempotencyKeyResponseStmt, err = db.PrepareContext(ctx, setIdempotencyKeyResponse); err != nil {
+		return nil, fmt.Errorf("error preparing query SetIdempotencyKeyResponse: %w", err)
+	}
+	if q.setPendingTOTPSecretStmt, err = db.PrepareContext(ctx, setPendingTOTPSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query SetPendingTOTPSecret: %w", err)
+	}
+	if q.setUserAdminStmt, err = db.PrepareContext(ctx, setUserAdmin); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserAdmin: %w", err)
+	}
+	if q.setUserAvatarStmt, err = db.PrepareContext(ctx, setUserAvatar); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserAvatar: %w", err)
+	}
+	if q.setUserPendingEmailStmt, err = db.PrepareContext(ctx, setUserPendingEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserPendingEmail: %w", err)
+	}
+	if q.setUserPinnedChirpStmt, err = db.PrepareContext(ctx, setUserPinnedChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserPinnedChirp: %w", err)
+	}
+	if q.setUserPrivateStmt, err = db.PrepareContext(ctx, setUserPrivate); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserPrivate: %w", err)
+	}
+	if q.unbanUserStmt, err = db.PrepareContext(ctx, unbanUser); err != nil {
+		return nil, fmt.Errorf("error preparing query UnbanUser: %w", err)
+	}
+	if q.unlikeChirpStmt, err = db.PrepareContext(ctx, unlikeChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query UnlikeChirp: %w", err)
+	}
+	if q.unpinChirpStmt, err = db.PrepareContext(ctx, unpinChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query UnpinChirp: %w", err)
+	}
+	if q.updateChirpStmt, err = db.PrepareContext(ctx, updateChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateChirp: %w", err)
+	}
+	if q.updateUserStmt, err = db.PrepareContext(ctx, updateUser); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateUser: %w", err)
+	}
+	if q.upgradeUserToChirpyRedStmt, err = db.PrepareContext(ctx, upgradeUserToChirpyRed); err != nil {
+		return nil, fmt.Errorf("error preparing query UpgradeUserToChirpyRed: %w", err)
+	}
+	if q.useBackupCodeStmt, err = db.PrepareContext(ctx, useBackupCode); err != nil {
+		return nil, fmt.Errorf("error preparing query UseBackupCode: %w", err)
+	}
+	if q.usernameExistsStmt, err = db.PrepareContext(ctx, usernameExists); err != nil {
+		return nil, fmt.Errorf("error preparing query UsernameExists: %w", err)
+	}
+	return &q, nil
+}
+
+func (q *Queries) Close() error {
+	var err error
+	if q.addChirpEntityStmt != nil {
+		if cerr := q.addChirpEntityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addChirpEntityStmt: %w", cerr)
+		}
+	}
+	if q.addChirpHashtagStmt != nil {
+		if cerr := q.addChirpHashtagStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addChirpHashtagStmt: %w", cerr)
+		}
+	}
+	if q.addChirpMentionStmt != nil {
+		if cerr := q.addChirpMentionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addChirpMentionStmt: %w", cerr)
+		}
+	}
+	if q.addChirpViewsStmt != nil {
+		if cerr := q.addChirpViewsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addChirpViewsStmt: %w", cerr)
+		}
+	}
+	if q.approveAllFollowRequestsStmt != nil {
+		if cerr := q.approveAllFollowRequestsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing approveAllFollowRequestsStmt: %w", cerr)
+		}
+	}
+	if q.approveFollowRequestStmt != nil {
+		if cerr := q.approveFollowRequestStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing approveFollowRequestStmt: %w", cerr)
+		}
+	}
+	if q.banUserStmt != nil {
+		if cerr := q.banUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing banUserStmt: %w", cerr)
+		}
+	}
+	if q.canViewUserStmt != nil {
+		if cerr := q.canViewUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing canViewUserStmt: %w", cerr)
+		}
+	}
+	if q.claimIdempotencyKeyStmt != nil {
+		if cerr := q.claimIdempotencyKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimIdempotencyKeyStmt: %w", cerr)
+		}
+	}
+	if q.confirmPendingEmailStmt != nil {
+		if cerr := q.confirmPendingEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing confirmPendingEmailStmt: %w", cerr)
+		}
+	}
+	if q.consumeMagicLinkTokenStmt != nil {
+		if cerr := q.consumeMagicLinkTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing consumeMagicLinkTokenStmt: %w", cerr)
+		}
+	}
+	if q.countActiveRefreshTokensStmt != nil {
+		if cerr := q.countActiveRefreshTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countActiveRefreshTokensStmt: %w", cerr)
+		}
+	}
+	if q.countChirpsStmt != nil {
+		if cerr := q.countChirpsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countChirpsStmt: %w", cerr)
+		}
+	}
+	if q.countChirpsBeforeStmt != nil {
+		if cerr := q.countChirpsBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countChirpsBeforeStmt: %w", cerr)
+		}
+	}
+	if q.countChirpsWithMediaStmt != nil {
+		if cerr := q.countChirpsWithMediaStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countChirpsWithMediaStmt: %w", cerr)
+		}
+	}
+	if q.countFollowersStmt != nil {
+		if cerr := q.countFollowersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countFollowersStmt: %w", cerr)
+		}
+	}
+	if q.countFollowingStmt != nil {
+		if cerr := q.countFollowingStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countFollowingStmt: %w", cerr)
+		}
+	}
+	if q.countUnreadNotificationsStmt != nil {
+		if cerr := q.countUnreadNotificationsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countUnreadNotificationsStmt: %w", cerr)
+		}
+	}
+	if q.countUsersStmt != nil {
+		if cerr := q.countUsersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countUsersStmt: %w", cerr)
+		}
+	}
+	if q.countUsersWithAvatarStmt != nil {
+		if cerr := q.countUsersWithAvatarStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countUsersWithAvatarStmt: %w", cerr)
+		}
+	}
+	if q.createAPIKeyStmt != nil {
+		if cerr := q.createAPIKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAPIKeyStmt: %w", cerr)
+		}
+	}
+	if q.createAuditLogEntryStmt != nil {
+		if cerr := q.createAuditLogEntryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAuditLogEntryStmt: %w", cerr)
+		}
+	}
+	if q.createBookmarkStmt != nil {
+		if cerr := q.createBookmarkStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createBookmarkStmt: %w", cerr)
+		}
+	}
+	if q.createChirpStmt != nil {
+		if cerr := q.createChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createChirpStmt: %w", cerr)
+		}
+	}
+	if q.createChirpAtStmt != nil {
+		if cerr := q.createChirpAtStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createChirpAtStmt: %w", cerr)
+		}
+	}
+	if q.createChirpReportStmt != nil {
+		if cerr := q.createChirpReportStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createChirpReportStmt: %w", cerr)
+		}
+	}
+	if q.createChirpsStmt != nil {
+		if cerr := q.createChirpsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createChirpsStmt: %w", cerr)
+		}
+	}
+	if q.createFollowStmt != nil {
+		if cerr := q.createFollowStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createFollowStmt: %w", cerr)
+		}
+	}
+	if q.createFollowRequestStmt != nil {
+		if cerr := q.createFollowRequestStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createFollowRequestStmt: %w", cerr)
+		}
+	}
+	if q.createLoginHistoryEntryStmt != nil {
+		if cerr := q.createLoginHistoryEntryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createLoginHistoryEntryStmt: %w", cerr)
+		}
+	}
+	if q.createMagicLinkTokenStmt != nil {
+		if cerr := q.createMagicLinkTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createMagicLinkTokenStmt: %w", cerr)
+		}
+	}
+	if q.createMuteStmt != nil {
+		if cerr := q.createMuteStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createMuteStmt: %w", cerr)
+		}
+	}
+	if q.createNotificationStmt != nil {
+		if cerr := q.createNotificationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createNotificationStmt: %w", cerr)
+		}
+	}
+	if q.createOAuthUserStmt != nil {
+		if cerr := q.createOAuthUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createOAuthUserStmt: %w", cerr)
+		}
+	}
+	if q.createRefreshTokenStmt != nil {
+		if cerr := q.createRefreshTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createRefreshTokenStmt: %w", cerr)
+		}
+	}
+	if q.createUserStmt != nil {
+		if cerr := q.createUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createUserStmt: %w", cerr)
+		}
+	}
+	if q.createWebhookEventStmt != nil {
+		if cerr := q.createWebhookEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createWebhookEventStmt: %w", cerr)
+		}
+	}
+	if q.deleteAPIKeyStmt != nil {
+		if cerr := q.deleteAPIKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteAPIKeyStmt: %w", cerr)
+		}
+	}
+	if q.deleteAllChirpsStmt != nil {
+		if cerr := q.deleteAllChirpsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteAllChirpsStmt: %w", cerr)
+		}
+	}
+	if q.deleteAllRefreshTokensStmt != nil {
+		if cerr := q.deleteAllRefreshTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteAllRefreshTokensStmt: %w", cerr)
+		}
+	}
+	if q.deleteAllUsersStmt != nil {
+		if cerr := q.deleteAllUsersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteAllUsersStmt: %w", cerr)
+		}
+	}
+	if q.deleteBookmarkStmt != nil {
+		if cerr := q.deleteBookmarkStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteBookmarkStmt: %w", cerr)
+		}
+	}
+	if q.deleteChirpStmt != nil {
+		if cerr := q.deleteChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteChirpStmt: %w", cerr)
+		}
+	}
+	if q.deleteChirpEntitiesStmt != nil {
+		if cerr := q.deleteChirpEntitiesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteChirpEntitiesStmt: %w", cerr)
+		}
+	}
+	if q.deleteChirpHashtagsStmt != nil {
+		if cerr := q.deleteChirpHashtagsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteChirpHashtagsStmt: %w", cerr)
+		}
+	}
+	if q.deleteChirpMentionsStmt != nil {
+		if cerr := q.deleteChirpMentionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteChirpMentionsStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredIdempotencyKeysStmt != nil {
+		if cerr := q.deleteExpiredIdempotencyKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredIdempotencyKeysStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredMagicLinkTokensStmt != nil {
+		if cerr := q.deleteExpiredMagicLinkTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredMagicLinkTokensStmt: %w", cerr)
+		}
+	}
+	if q.deleteFollowStmt != nil {
+		if cerr := q.deleteFollowStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteFollowStmt: %w", cerr)
+		}
+	}
+	if q.deleteFollowRequestStmt != nil {
+		if cerr := q.deleteFollowRequestStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteFollowRequestStmt: %w", cerr)
+		}
+	}
+	if q.deleteIdempotencyKeyStmt != nil {
+		if cerr := q.deleteIdempotencyKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteIdempotencyKeyStmt: %w", cerr)
+		}
+	}
+	if q.deleteMuteStmt != nil {
+		if cerr := q.deleteMuteStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMuteStmt: %w", cerr)
+		}
+	}
+	if q.deleteUserChirpsBeforeStmt != nil {
+		if cerr := q.deleteUserChirpsBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUserChirpsBeforeStmt: %w", cerr)
+		}
+	}
+	if q.deleteUserChirpsByIDsStmt != nil {
+		if cerr := q.deleteUserChirpsByIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUserChirpsByIDsStmt: %w", cerr)
+		}
+	}
+	if q.deleteUserKeepChirpsStmt != nil {
+		if cerr := q.deleteUserKeepChirpsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUserKeepChirpsStmt: %w", cerr)
+		}
+	}
+	if q.enableUserTOTPStmt != nil {
+		if cerr := q.enableUserTOTPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing enableUserTOTPStmt: %w", cerr)
+		}
+	}
+	if q.getAPIKeysStmt != nil {
+		if cerr := q.getAPIKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAPIKeysStmt: %w", cerr)
+		}
+	}
+	if q.getAccountStatusStmt != nil {
+		if cerr := q.getAccountStatusStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccountStatusStmt: %w", cerr)
+		}
+	}
+	if q.getAuditLogStmt != nil {
+		if cerr := q.getAuditLogStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAuditLogStmt: %w", cerr)
+		}
+	}
+	if q.getBookmarkedChirpsStmt != nil {
+		if cerr := q.getBookmarkedChirpsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getBookmarkedChirpsStmt: %w", cerr)
+		}
+	}
+	if q.getChirpByIDStmt != nil {
+		if cerr := q.getChirpByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpByIDStmt: %w", cerr)
+		}
+	}
+	if q.getChirpByIDWithAuthorStmt != nil {
+		if cerr := q.getChirpByIDWithAuthorStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpByIDWithAuthorStmt: %w", cerr)
+		}
+	}
+	if q.getChirpEntitiesStmt != nil {
+		if cerr := q.getChirpEntitiesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpEntitiesStmt: %w", cerr)
+		}
+	}
+	if q.getChirpLikersStmt != nil {
+		if cerr := q.getChirpLikersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpLikersStmt: %w", cerr)
+		}
+	}
+	if q.getChirpMentionsStmt != nil {
+		if cerr := q.getChirpMentionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpMentionsStmt: %w", cerr)
+		}
+	}
+	if q.getChirpRepliesStmt != nil {
+		if cerr := q.getChirpRepliesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpRepliesStmt: %w", cerr)
+		}
+	}
+	if q.getChirpRevisionsStmt != nil {
+		if cerr := q.getChirpRevisionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpRevisionsStmt: %w", cerr)
+		}
+	}
+	if q.getChirpsStmt != nil {
+		if cerr := q.getChirpsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpsStmt: %w", cerr)
+		}
+	}
+	if q.getChirpsByHashtagStmt != nil {
+		if cerr := q.getChirpsByHashtagStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpsByHashtagStmt: %w", cerr)
+		}
+	}
+	if q.getChirpsByUserIDStmt != nil {
+		if cerr := q.getChirpsByUserIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpsByUserIDStmt: %w", cerr)
+		}
+	}
+	if q.getChirpsSinceStmt != nil {
+		if cerr := q.getChirpsSinceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpsSinceStmt: %w", cerr)
+		}
+	}
+	if q.getChirpsWithAuthorsStmt != nil {
+		if cerr := q.getChirpsWithAuthorsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpsWithAuthorsStmt: %w", cerr)
+		}
+	}
+	if q.getDueWebhookEventsStmt != nil {
+		if cerr := q.getDueWebhookEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDueWebhookEventsStmt: %w", cerr)
+		}
+	}
+	if q.getFeedStmt != nil {
+		if cerr := q.getFeedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFeedStmt: %w", cerr)
+		}
+	}
+	if q.getFollowRequestsStmt != nil {
+		if cerr := q.getFollowRequestsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFollowRequestsStmt: %w", cerr)
+		}
+	}
+	if q.getFollowersStmt != nil {
+		if cerr := q.getFollowersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFollowersStmt: %w", cerr)
+		}
+	}
+	if q.getFollowingStmt != nil {
+		if cerr := q.getFollowingStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFollowingStmt: %w", cerr)
+		}
+	}
+	if q.getHiddenAuthorIDsStmt != nil {
+		if cerr := q.getHiddenAuthorIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getHiddenAuthorIDsStmt: %w", cerr)
+		}
+	}
+	if q.getIdempotencyKeyStmt != nil {
+		if cerr := q.getIdempotencyKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getIdempotencyKeyStmt: %w", cerr)
+		}
+	}
+	if q.getLikedChirpIDsStmt != nil {
+		if cerr := q.getLikedChirpIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLikedChirpIDsStmt: %w", cerr)
+		}
+	}
+	if q.getLoginHistoryStmt != nil {
+		if cerr := q.getLoginHistoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLoginHistoryStmt: %w", cerr)
+		}
+	}
+	if q.getNotificationsStmt != nil {
+		if cerr := q.getNotificationsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getNotificationsStmt: %w", cerr)
+		}
+	}
+	if q.getNthNewestChirpTimeStmt != nil {
+		if cerr := q.getNthNewestChirpTimeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getNthNewestChirpTimeStmt: %w", cerr)
+		}
+	}
+	if q.getRefreshTokenStmt != nil {
+		if cerr := q.getRefreshTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getRefreshTokenStmt: %w", cerr)
+		}
+	}
+	if q.getReportedChirpsStmt != nil {
+		if cerr := q.getReportedChirpsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getReportedChirpsStmt: %w", cerr)
+		}
+	}
+	if q.getUnusedBackupCodesStmt != nil {
+		if cerr := q.getUnusedBackupCodesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUnusedBackupCodesStmt: %w", cerr)
+		}
+	}
+	if q.getUserByEmailStmt != nil {
+		if cerr := q.getUserByEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserByEmailStmt: %w", cerr)
+		}
+	}
+	if q.getUserByIDStmt != nil {
+		if cerr := q.getUserByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserByIDStmt: %w", cerr)
+		}
+	}
+	if q.getUserChirpStatsStmt != nil {
+		if cerr := q.getUserChirpStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserChirpStatsStmt: %w", cerr)
+		}
+	}
+	if q.getUserChirpsAfterStmt != nil {
+		if cerr := q.getUserChirpsAfterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserChirpsAfterStmt: %w", cerr)
+		}
+	}
+	if q.getUserFromRefreshTokenStmt != nil {
+		if cerr := q.getUserFromRefreshTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserFromRefreshTokenStmt: %w", cerr)
+		}
+	}
+	if q.getUserIDByAPIKeyStmt != nil {
+		if cerr := q.getUserIDByAPIKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserIDByAPIKeyStmt: %w", cerr)
+		}
+	}
+	if q.getUserLikeStatsStmt != nil {
+		if cerr := q.getUserLikeStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserLikeStatsStmt: %w", cerr)
+		}
+	}
+	if q.getUserSessionsStmt != nil {
+		if cerr := q.getUserSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserSessionsStmt: %w", cerr)
+		}
+	}
+	if q.getUserTOTPStmt != nil {
+		if cerr := q.getUserTOTPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserTOTPStmt: %w", cerr)
+		}
+	}
+	if q.getWebhookEventsStmt != nil {
+		if cerr := q.getWebhookEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebhookEventsStmt: %w", cerr)
+		}
+	}
+	if q.hasRecentDuplicateChirpStmt != nil {
+		if cerr := q.hasRecentDuplicateChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing hasRecentDuplicateChirpStmt: %w", cerr)
+		}
+	}
+	if q.likeChirpStmt != nil {
+		if cerr := q.likeChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing likeChirpStmt: %w", cerr)
+		}
+	}
+	if q.listUsersStmt != nil {
+		if cerr := q.listUsersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUsersStmt: %w", cerr)
+		}
+	}
+	if q.markAllNotificationsReadStmt != nil {
+		if cerr := q.markAllNotificationsReadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markAllNotificationsReadStmt: %w", cerr)
+		}
+	}
+	if q.markNotificationsReadStmt != nil {
+		if cerr := q.markNotificationsReadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markNotificationsReadStmt: %w", cerr)
+		}
+	}
+	if q.markWebhookEventFailedStmt != nil {
+		if cerr := q.markWebhookEventFailedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markWebhookEventFailedStmt: %w", cerr)
+		}
+	}
+	if q.markWebhookEventProcessedStmt != nil {
+		if cerr := q.markWebhookEventProcessedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markWebhookEventProcessedStmt: %w", cerr)
+		}
+	}
+	if q.pruneLoginHistoryStmt != nil {
+		if cerr := q.pruneLoginHistoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing pruneLoginHistoryStmt: %w", cerr)
+		}
+	}
+	if q.purgeChirpsBeforeStmt != nil {
+		if cerr := q.purgeChirpsBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing purgeChirpsBeforeStmt: %w", cerr)
+		}
+	}
+	if q.rehashUserPasswordStmt != nil {
+		if cerr := q.rehashUserPasswordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing rehashUserPasswordStmt: %w", cerr)
+		}
+	}
+	if q.replaceBackupCodesStmt != nil {
+		if cerr := q.replaceBackupCodesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing replaceBackupCodesStmt: %w", cerr)
+		}
+	}
+	if q.resolveChirpReportsStmt != nil {
+		if cerr := q.resolveChirpReportsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing resolveChirpReportsStmt: %w", cerr)
+		}
+	}
+	if q.resolveMentionHandlesStmt != nil {
+		if cerr := q.resolveMentionHandlesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing resolveMentionHandlesStmt: %w", cerr)
+		}
+	}
+	if q.revokeRefreshTokenStmt != nil {
+		if cerr := q.revokeRefreshTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeRefreshTokenStmt: %w", cerr)
+		}
+	}
+	if q.revokeRefreshTokenFamilyStmt != nil {
+		if cerr := q.revokeRefreshTokenFamilyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeRefreshTokenFamilyStmt: %w", cerr)
+		}
+	}
+	if q.revokeUserRefreshTokensStmt != nil {
+		if cerr := q.revokeUserRefreshTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeUserRefreshTokensStmt: %w", cerr)
+		}
+	}
+	if q.setIdempotencyKeyResponseStmt != nil {
+		if cerr := q.setIdempotencyKeyResponseStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setIdempotencyKeyResponseStmt: %w", cerr)
+		}
+	}
+	if q.setPendingTOTPSecretStmt != nil {
+		if cerr := q.setPendingTOTPSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setPendingTOTPSecretStmt: %w", cerr)
+		}
+	}
+	if q.setUserAdminStmt != nil {
+		if cerr := q.setUserAdminStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserAdminStmt: %w", cerr)
+		}
+	}
+	if q.setUserAvatarStmt != nil {
+		if cerr := q.setUserAvatarStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserAvatarStmt: %w", cerr)
+		}
+	}
+	if q.setUserPendingEmailStmt != nil {
+		if cerr := q.setUserPendingEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserPendingEmailStmt: %w", cerr)
+		}
+	}
+	if q.setUserPinnedChirpStmt != nil {
+		if cerr := q.setUserPinnedChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserPinnedChirpStmt: %w", cerr)
+		}
+	}
+	if q.setUserPrivateStmt != nil {
+		if cerr := q.setUserPrivateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserPrivateStmt: %w", cerr)
+		}
+	}
+	if q.unbanUserStmt != nil {
+		if cerr := q.unbanUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing unbanUserStmt: %w", cerr)
+		}
+	}
+	if q.unlikeChirpStmt != nil {
+		if cerr := q.unlikeChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing unlikeChirpStmt: %w", cerr)
+		}
+	}
+	if q.unpinChirpStmt != nil {
+		if cerr := q.unpinChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing unpinChirpStmt: %w", cerr)
+		}
+	}
+	if q.updateChirpStmt != nil {
+		if cerr := q.updateChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateChirpStmt: %w", cerr)
+		}
+	}
+	if q.updateUserStmt != nil {
+		if cerr := q.updateUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateUserStmt: %w", cerr)
+		}
+	}
+	if q.upgradeUserToChirpyRedStmt != nil {
+		if cerr := q.upgradeUserToChirpyRedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upgradeUserToChirpyRedStmt: %w", cerr)
+		}
+	}
+	if q.useBackupCodeStmt != nil {
+		if cerr := q.useBackupCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing useBackupCodeStmt: %w", cerr)
+		}
+	}
+	if q.usernameExistsStmt != nil {
+		if cerr := q.usernameExistsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing usernameExistsStmt: %w", cerr)
+		}
+	}
+	return err
+}
+
+func (q *Queries) exec(ctx context.Context, stmt *sql.Stmt, query string, args ...interface{}) (sql.Result, error) {
+	switch {
+	case stmt != nil && q.tx != nil:
+		return q.tx.StmtContext(ctx, stmt).ExecContext(ctx, args...)
+	case stmt != nil:
+		return stmt.ExecContext(ctx, args...)
+	default:
+		return q.db.ExecContext(ctx, query, args...)
+	}
+}
+
+func (q *Queries) query(ctx context.Context, stmt *sql.Stmt, query string, args ...interface{}) (*sql.Rows, error) {
+	switch {
+	case stmt != nil && q.tx != nil:
+		return q.tx.StmtContext(ctx, stmt).QueryContext(ctx, args...)
+	case stmt != nil:
+		return stmt.QueryContext(ctx, args...)
+	default:
+		return q.db.QueryContext(ctx, query, args...)
+	}
+}
+
+func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, args ...interface{}) *sql.Row {
+	switch {
+	case stmt != nil && q.tx != nil:
+		return q.tx.StmtContext(ctx, stmt).QueryRowContext(ctx, args...)
+	case stmt != nil:
+		return stmt.QueryRowContext(ctx, args...)
+	default:
+		return q.db.QueryRowContext(ctx, query, args...)
+	}
+}
+
 type Queries struct {
-	db DBTX
+	db                               DBTX
+	tx                               *sql.Tx
+	addChirpEntityStmt               *sql.Stmt
+	addChirpHashtagStmt              *sql.Stmt
+	addChirpMentionStmt              *sql.Stmt
+	addChirpViewsStmt                *sql.Stmt
+	approveAllFollowRequestsStmt     *sql.Stmt
+	approveFollowRequestStmt         *sql.Stmt
+	banUserStmt                      *sql.Stmt
+	canViewUserStmt                  *sql.Stmt
+	claimIdempotencyKeyStmt          *sql.Stmt
+	confirmPendingEmailStmt          *sql.Stmt
+	consumeMagicLinkTokenStmt        *sql.Stmt
+	countActiveRefreshTokensStmt     *sql.Stmt
+	countChirpsStmt                  *sql.Stmt
+	countChirpsBeforeStmt            *sql.Stmt
+	countChirpsWithMediaStmt         *sql.Stmt
+	countFollowersStmt               *sql.Stmt
+	countFollowingStmt               *sql.Stmt
+	countUnreadNotificationsStmt     *sql.Stmt
+	countUsersStmt                   *sql.Stmt
+	countUsersWithAvatarStmt         *sql.Stmt
+	createAPIKeyStmt                 *sql.Stmt
+	createAuditLogEntryStmt          *sql.Stmt
+	createBookmarkStmt               *sql.Stmt
+	createChirpStmt                  *sql.Stmt
+	createChirpAtStmt                *sql.Stmt
+	createChirpReportStmt            *sql.Stmt
+	createChirpsStmt                 *sql.Stmt
+	createFollowStmt                 *sql.Stmt
+	createFollowRequestStmt          *sql.Stmt
+	createLoginHistoryEntryStmt      *sql.Stmt
+	createMagicLinkTokenStmt         *sql.Stmt
+	createMuteStmt                   *sql.Stmt
+	createNotificationStmt           *sql.Stmt
+	createOAuthUserStmt              *sql.Stmt
+	createRefreshTokenStmt           *sql.Stmt
+	createUserStmt                   *sql.Stmt
+	createWebhookEventStmt           *sql.Stmt
+	deleteAPIKeyStmt                 *sql.Stmt
+	deleteAllChirpsStmt              *sql.Stmt
+	deleteAllRefreshTokensStmt       *sql.Stmt
+	deleteAllUsersStmt               *sql.Stmt
+	deleteBookmarkStmt               *sql.Stmt
+	deleteChirpStmt                  *sql.Stmt
+	deleteChirpEntitiesStmt          *sql.Stmt
+	deleteChirpHashtagsStmt          *sql.Stmt
+	deleteChirpMentionsStmt          *sql.Stmt
+	deleteExpiredIdempotencyKeysStmt *sql.Stmt
+	deleteExpiredMagicLinkTokensStmt *sql.Stmt
+	deleteFollowStmt                 *sql.Stmt
+	deleteFollowRequestStmt          *sql.Stmt
+	deleteIdempotencyKeyStmt         *sql.Stmt
+	deleteMuteStmt                   *sql.Stmt
+	deleteUserChirpsBeforeStmt       *sql.Stmt
+	deleteUserChirpsByIDsStmt        *sql.Stmt
+	deleteUserKeepChirpsStmt         *sql.Stmt
+	enableUserTOTPStmt               *sql.Stmt
+	getAPIKeysStmt                   *sql.Stmt
+	getAccountStatusStmt             *sql.Stmt
+	getAuditLogStmt                  *sql.Stmt
+	getBookmarkedChirpsStmt          *sql.Stmt
+	getChirpByIDStmt                 *sql.Stmt
+	getChirpByIDWithAuthorStmt       *sql.Stmt
+	getChirpEntitiesStmt             *sql.Stmt
+	getChirpLikersStmt               *sql.Stmt
+	getChirpMentionsStmt             *sql.Stmt
+	getChirpRepliesStmt              *sql.Stmt
+	getChirpRevisionsStmt            *sql.Stmt
+	getChirpsStmt                    *sql.Stmt
+	getChirpsByHashtagStmt           *sql.Stmt
+	getChirpsByUserIDStmt            *sql.Stmt
+	getChirpsSinceStmt               *sql.Stmt
+	getChirpsWithAuthorsStmt         *sql.Stmt
+	getDueWebhookEventsStmt          *sql.Stmt
+	getFeedStmt                      *sql.Stmt
+	getFollowRequestsStmt            *sql.Stmt
+	getFollowersStmt                 *sql.Stmt
+	getFollowingStmt                 *sql.Stmt
+	getHiddenAuthorIDsStmt           *sql.Stmt
+	getIdempotencyKeyStmt            *sql.Stmt
+	getLikedChirpIDsStmt             *sql.Stmt
+	getLoginHistoryStmt              *sql.Stmt
+	getNotificationsStmt             *sql.Stmt
+	getNthNewestChirpTimeStmt        *sql.Stmt
+	getRefreshTokenStmt              *sql.Stmt
+	getReportedChirpsStmt            *sql.Stmt
+	getUnusedBackupCodesStmt         *sql.Stmt
+	getUserByEmailStmt               *sql.Stmt
+	getUserByIDStmt                  *sql.Stmt
+	getUserChirpStatsStmt            *sql.Stmt
+	getUserChirpsAfterStmt           *sql.Stmt
+	getUserFromRefreshTokenStmt      *sql.Stmt
+	getUserIDByAPIKeyStmt            *sql.Stmt
+	getUserLikeStatsStmt             *sql.Stmt
+	getUserSessionsStmt              *sql.Stmt
+	getUserTOTPStmt                  *sql.Stmt
+	getWebhookEventsStmt             *sql.Stmt
+	hasRecentDuplicateChirpStmt      *sql.Stmt
+	likeChirpStmt                    *sql.Stmt
+	listUsersStmt                    *sql.Stmt
+	markAllNotificationsReadStmt     *sql.Stmt
+	markNotificationsReadStmt        *sql.Stmt
+	markWebhookEventFailedStmt       *sql.Stmt
+	markWebhookEventProcessedStmt    *sql.Stmt
+	pruneLoginHistoryStmt            *sql.Stmt
+	purgeChirpsBeforeStmt            *sql.Stmt
+	rehashUserPasswordStmt           *sql.Stmt
+	replaceBackupCodesStmt           *sql.Stmt
+	resolveChirpReportsStmt          *sql.Stmt
+	resolveMentionHandlesStmt        *sql.Stmt
+	revokeRefreshTokenStmt           *sql.Stmt
+	revokeRefreshTokenFamilyStmt     *sql.Stmt
+	revokeUserRefreshTokensStmt      *sql.Stmt
+	setIdempotencyKeyResponseStmt    *sql.Stmt
+	setPendingTOTPSecretStmt         *sql.Stmt
+	setUserAdminStmt                 *sql.Stmt
+	setUserAvatarStmt                *sql.Stmt
+	setUserPendingEmailStmt          *sql.Stmt
+	setUserPinnedChirpStmt           *sql.Stmt
+	setUserPrivateStmt               *sql.Stmt
+	unbanUserStmt                    *sql.Stmt
+	unlikeChirpStmt                  *sql.Stmt
+	unpinChirpStmt                   *sql.Stmt
+	updateChirpStmt                  *sql.Stmt
+	updateUserStmt                   *sql.Stmt
+	upgradeUserToChirpyRedStmt       *sql.Stmt
+	useBackupCodeStmt                *sql.Stmt
+	usernameExistsStmt               *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db: tx,
+		db:                               tx,
+		tx:                               tx,
+		addChirpEntityStmt:               q.addChirpEntityStmt,
+		addChirpHashtagStmt:              q.addChirpHashtagStmt,
+		addChirpMentionStmt:              q.addChirpMentionStmt,
+		addChirpViewsStmt:                q.addChirpViewsStmt,
+		approveAllFollowRequestsStmt:     q.approveAllFollowRequestsStmt,
+		approveFollowRequestStmt:         q.approveFollowRequestStmt,
+		banUserStmt:                      q.banUserStmt,
+		canViewUserStmt:                  q.canViewUserStmt,
+		claimIdempotencyKeyStmt:          q.claimIdempotencyKeyStmt,
+		confirmPendingEmailStmt:          q.confirmPendingEmailStmt,
+		consumeMagicLinkTokenStmt:        q.consumeMagicLinkTokenStmt,
+		countActiveRefreshTokensStmt:     q.countActiveRefreshTokensStmt,
+		countChirpsStmt:                  q.countChirpsStmt,
+		countChirpsBeforeStmt:            q.countChirpsBeforeStmt,
+		countChirpsWithMediaStmt:         q.countChirpsWithMediaStmt,
+		countFollowersStmt:               q.countFollowersStmt,
+		countFollowingStmt:               q.countFollowingStmt,
+		countUnreadNotificationsStmt:     q.countUnreadNotificationsStmt,
+		countUsersStmt:                   q.countUsersStmt,
+		countUsersWithAvatarStmt:         q.countUsersWithAvatarStmt,
+		createAPIKeyStmt:                 q.createAPIKeyStmt,
+		createAuditLogEntryStmt:          q.createAuditLogEntryStmt,
+		createBookmarkStmt:               q.createBookmarkStmt,
+		createChirpStmt:                  q.createChirpStmt,
+		createChirpAtStmt:                q.createChirpAtStmt,
+		createChirpReportStmt:            q.createChirpReportStmt,
+		createChirpsStmt:                 q.createChirpsStmt,
+		createFollowStmt:                 q.createFollowStmt,
+		createFollowRequestStmt:          q.createFollowRequestStmt,
+		createLoginHistoryEntryStmt:      q.createLoginHistoryEntryStmt,
+		createMagicLinkTokenStmt:         q.createMagicLinkTokenStmt,
+		createMuteStmt:                   q.createMuteStmt,
+		createNotificationStmt:           q.createNotificationStmt,
+		createOAuthUserStmt:              q.createOAuthUserStmt,
+		createRefreshTokenStmt:           q.createRefreshTokenStmt,
+		createUserStmt:                   q.createUserStmt,
+		createWebhookEventStmt:           q.createWebhookEventStmt,
+		deleteAPIKeyStmt:                 q.deleteAPIKeyStmt,
+		deleteAllChirpsStmt:              q.deleteAllChirpsStmt,
+		deleteAllRefreshTokensStmt:       q.deleteAllRefreshTokensStmt,
+		deleteAllUsersStmt:               q.deleteAllUsersStmt,
+		deleteBookmarkStmt:               q.deleteBookmarkStmt,
+		deleteChirpStmt:                  q.deleteChirpStmt,
+		deleteChirpEntitiesStmt:          q.deleteChirpEntitiesStmt,
+		deleteChirpHashtagsStmt:          q.deleteChirpHashtagsStmt,
+		deleteChirpMentionsStmt:          q.deleteChirpMentionsStmt,
+		deleteExpiredIdempotencyKeysStmt: q.deleteExpiredIdempotencyKeysStmt,
+		deleteExpiredMagicLinkTokensStmt: q.deleteExpiredMagicLinkTokensStmt,
+		deleteFollowStmt:                 q.deleteFollowStmt,
+		deleteFollowRequestStmt:          q.deleteFollowRequestStmt,
+		deleteIdempotencyKeyStmt:         q.deleteIdempotencyKeyStmt,
+		deleteMuteStmt:                   q.deleteMuteStmt,
+		deleteUserChirpsBeforeStmt:       q.deleteUserChirpsBeforeStmt,
+		deleteUserChirpsByIDsStmt:        q.deleteUserChirpsByIDsStmt,
+		deleteUserKeepChirpsStmt:         q.deleteUserKeepChirpsStmt,
+		enableUserTOTPStmt:               q.enableUserTOTPStmt,
+		getAPIKeysStmt:                   q.getAPIKeysStmt,
+		getAccountStatusStmt:             q.getAccountStatusStmt,
+		getAuditLogStmt:                  q.getAuditLogStmt,
+		getBookmarkedChirpsStmt:          q.getBookmarkedChirpsStmt,
+		getChirpByIDStmt:                 q.getChirpByIDStmt,
+		getChirpByIDWithAuthorStmt:       q.getChirpByIDWithAuthorStmt,
+		getChirpEntitiesStmt:             q.getChirpEntitiesStmt,
+		getChirpLikersStmt:               q.getChirpLikersStmt,
+		getChirpMentionsStmt:             q.getChirpMentionsStmt,
+		getChirpRepliesStmt:              q.getChirpRepliesStmt,
+		getChirpRevisionsStmt:            q.getChirpRevisionsStmt,
+		getChirpsStmt:                    q.getChirpsStmt,
+		getChirpsByHashtagStmt:           q.getChirpsByHashtagStmt,
+		getChirpsByUserIDStmt:            q.getChirpsByUserIDStmt,
+		getChirpsSinceStmt:               q.getChirpsSinceStmt,
+		getChirpsWithAuthorsStmt:         q.getChirpsWithAuthorsStmt,
+		getDueWebhookEventsStmt:          q.getDueWebhookEventsStmt,
+		getFeedStmt:                      q.getFeedStmt,
+		getFollowRequestsStmt:            q.getFollowRequestsStmt,
+		getFollowersStmt:                 q.getFollowersStmt,
+		getFollowingStmt:                 q.getFollowingStmt,
+		getHiddenAuthorIDsStmt:           q.getHiddenAuthorIDsStmt,
+		getIdempotencyKeyStmt:            q.getIdempotencyKeyStmt,
+		getLikedChirpIDsStmt:             q.getLikedChirpIDsStmt,
+		getLoginHistoryStmt:              q.getLoginHistoryStmt,
+		getNotificationsStmt:             q.getNotificationsStmt,
+		getNthNewestChirpTimeStmt:        q.getNthNewestChirpTimeStmt,
+		getRefreshTokenStmt:              q.getRefreshTokenStmt,
+		getReportedChirpsStmt:            q.getReportedChirpsStmt,
+		getUnusedBackupCodesStmt:         q.getUnusedBackupCodesStmt,
+		getUserByEmailStmt:               q.getUserByEmailStmt,
+		getUserByIDStmt:                  q.getUserByIDStmt,
+		getUserChirpStatsStmt:            q.getUserChirpStatsStmt,
+		getUserChirpsAfterStmt:           q.getUserChirpsAfterStmt,
+		getUserFromRefreshTokenStmt:      q.getUserFromRefreshTokenStmt,
+		getUserIDByAPIKeyStmt:            q.getUserIDByAPIKeyStmt,
+		getUserLikeStatsStmt:             q.getUserLikeStatsStmt,
+		getUserSessionsStmt:              q.getUserSessionsStmt,
+		getUserTOTPStmt:                  q.getUserTOTPStmt,
+		getWebhookEventsStmt:             q.getWebhookEventsStmt,
+		hasRecentDuplicateChirpStmt:      q.hasRecentDuplicateChirpStmt,
+		likeChirpStmt:                    q.likeChirpStmt,
+		listUsersStmt:                    q.listUsersStmt,
+		markAllNotificationsReadStmt:     q.markAllNotificationsReadStmt,
+		markNotificationsReadStmt:        q.markNotificationsReadStmt,
+		markWebhookEventFailedStmt:       q.markWebhookEventFailedStmt,
+		markWebhookEventProcessedStmt:    q.markWebhookEventProcessedStmt,
+		pruneLoginHistoryStmt:            q.pruneLoginHistoryStmt,
+		purgeChirpsBeforeStmt:            q.purgeChirpsBeforeStmt,
+		rehashUserPasswordStmt:           q.rehashUserPasswordStmt,
+		replaceBackupCodesStmt:           q.replaceBackupCodesStmt,
+		resolveChirpReportsStmt:          q.resolveChirpReportsStmt,
+		resolveMentionHandlesStmt:        q.resolveMentionHandlesStmt,
+		revokeRefreshTokenStmt:           q.revokeRefreshTokenStmt,
+		revokeRefreshTokenFamilyStmt:     q.revokeRefreshTokenFamilyStmt,
+		revokeUserRefreshTokensStmt:      q.revokeUserRefreshTokensStmt,
+		setIdempotencyKeyResponseStmt:    q.setIdempotencyKeyResponseStmt,
+		setPendingTOTPSecretStmt:         q.setPendingTOTPSecretStmt,
+		setUserAdminStmt:                 q.setUserAdminStmt,
+		setUserAvatarStmt:                q.setUserAvatarStmt,
+		setUserPendingEmailStmt:          q.setUserPendingEmailStmt,
+		setUserPinnedChirpStmt:           q.setUserPinnedChirpStmt,
+		setUserPrivateStmt:               q.setUserPrivateStmt,
+		unbanUserStmt:                    q.unbanUserStmt,
+		unlikeChirpStmt:                  q.unlikeChirpStmt,
+		unpinChirpStmt:                   q.unpinChirpStmt,
+		updateChirpStmt:                  q.updateChirpStmt,
+		updateUserStmt:                   q.updateUserStmt,
+		upgradeUserToChirpyRedStmt:       q.upgradeUserToChirpyRedStmt,
+		useBackupCodeStmt:                q.useBackupCodeStmt,
+		usernameExistsStmt:               q.usernameExistsStmt,
 	}
 }
@@ -6,33 +6,202 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-type Chirp struct {
+type ApiKey struct {
 	ID        uuid.UUID
+	UserID    uuid.UUID
+	Label     string
+	Prefix    string
+	KeyHash   string
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
+}
+
+type AuditLog struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ActorID    uuid.NullUUID
+	ActorToken sql.NullString
+	Action     string
+	Target     string
+	RequestID  string
+}
+
+type BackupCode struct {
+	ID        uuid.UUID
 	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
 }
 
-type RefreshToken struct {
-	Token     string
+type Bookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Chirp struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
+	DeletedAt     sql.NullTime
+	ViewCount     int64
+	MediaUrl      sql.NullString
+	MediaAlt      sql.NullString
+}
+
+type ChirpEntity struct {
+	ChirpID    uuid.UUID
+	Type       string
+	StartIndex int32
+	EndIndex   int32
+	Value      string
+}
+
+type ChirpHashtag struct {
+	ChirpID uuid.UUID
+	Tag     string
+}
+
+type ChirpLike struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+type ChirpReport struct {
+	ChirpID    uuid.UUID
+	ReporterID uuid.UUID
+	Reason     string
+	CreatedAt  time.Time
+	ResolvedAt sql.NullTime
+}
+
+type ChirpRevision struct {
+	ID        uuid.UUID
+	ChirpID   uuid.UUID
+	Body      string
 	CreatedAt time.Time
-	UpdatedAt time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type FollowRequest struct {
+	RequesterID uuid.UUID
+	TargetID    uuid.UUID
+	CreatedAt   time.Time
+}
+
+type IdempotencyKey struct {
+	UserID      uuid.UUID
+	Key         string
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	RequestHash string
+	Response    sql.NullString
+}
+
+type LoginHistory struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	Ip        string
+	UserAgent string
+}
+
+type MagicLinkToken struct {
+	TokenHash string
 	UserID    uuid.UUID
+	CreatedAt time.Time
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	ActorID   uuid.UUID
+	Type      string
+	ChirpID   uuid.NullUUID
+	ReadAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	Token       string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	ExpiresAt   time.Time
+	RevokedAt   sql.NullTime
+	FamilyID    uuid.UUID
+	ParentToken sql.NullString
+	RememberMe  bool
+	Scopes      []string
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ID                    uuid.UUID
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	Email                 string
+	HashedPassword        sql.NullString
+	IsChirpyRed           bool
+	IsAdmin               bool
+	Username              string
+	DisplayName           sql.NullString
+	Bio                   sql.NullString
+	AvatarUrl             sql.NullString
+	PinnedChirpID         uuid.NullUUID
+	AuthProvider          string
+	IsPrivate             bool
+	BannedAt              sql.NullTime
+	BannedUntil           sql.NullTime
+	BanReason             sql.NullString
+	DeletedAt             sql.NullTime
+	PendingEmail          sql.NullString
+	PendingEmailTokenHash sql.NullString
+	PendingEmailExpiresAt sql.NullTime
+}
+
+type UserTotp struct {
+	UserID    uuid.UUID
+	Secret    string
+	EnabledAt sql.NullTime
+	CreatedAt time.Time
+}
+
+type WebhookEvent struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Source        string
+	Event         string
+	Payload       json.RawMessage
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     string
 }
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// BenchmarkChirpQueries compares chirp inserts and reads with and without
+// prepared statements. It needs a migrated database, which it adds a user
+// and chirps to and removes them again:
+//
+//	CHIRPY_BENCH_DB_URL=postgres://... go test -run '^$' -bench ChirpQueries ./internal/database
+func BenchmarkChirpQueries(b *testing.B) {
+	dbURL := os.Getenv("CHIRPY_BENCH_DB_URL")
+	if dbURL == "" {
+		b.Skip("CHIRPY_BENCH_DB_URL is not set")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	prepared, err := Prepare(ctx, db)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { prepared.Close() })
+
+	name := "bench_" + uuid.NewString()[:8]
+	user, err := New(db).CreateUser(ctx, CreateUserParams{Email: name + "@example.com", Username: name})
+	if err != nil {
+		b.Fatal(err)
+	}
+	// Their chirps go with them
+	b.Cleanup(func() { db.Exec("DELETE FROM users WHERE id = $1", user.ID) })
+
+	for _, q := range []struct {
+		name    string
+		queries *Queries
+	}{
+		{"unprepared", New(db)},
+		{"prepared", prepared},
+	} {
+		b.Run(q.name+"/insert", func(b *testing.B) {
+			for b.Loop() {
+				if _, err := q.queries.CreateChirp(ctx, CreateChirpParams{Body: "Benchmarking", UserID: user.ID}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(q.name+"/read", func(b *testing.B) {
+			chirp, err := q.queries.CreateChirp(ctx, CreateChirpParams{Body: "Benchmarking", UserID: user.ID})
+			if err != nil {
+				b.Fatal(err)
+			}
+			for b.Loop() {
+				if _, err := q.queries.GetChirpByID(ctx, chirp.ID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -7,32 +7,63 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countActiveRefreshTokens = `-- name: CountActiveRefreshTokens :one
+SELECT COUNT(*) FROM refresh_tokens
+WHERE expires_at > NOW() AND revoked_at IS NULL
+`
+
+// Tokens that could still be exchanged for an access token.
+func (q *Queries) CountActiveRefreshTokens(ctx context.Context) (int64, error) {
+	row := q.queryRow(ctx, q.countActiveRefreshTokensStmt, countActiveRefreshTokens)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, remember_me, scopes)
 VALUES (
     $1,
     NOW(),
     NOW(),
     $2,
     $3,
-    NULL
+    NULL,
+    $4,
+    $5,
+    $6,
+    $7
 )
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, remember_me, scopes
 `
 
 type CreateRefreshTokenParams struct {
-	Token     string
-	UserID    uuid.UUID
-	ExpiresAt time.Time
+	Token       string
+	UserID      uuid.UUID
+	ExpiresAt   time.Time
+	FamilyID    uuid.UUID
+	ParentToken sql.NullString
+	RememberMe  bool
+	Scopes      []string
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, createRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt)
+	row := q.queryRow(ctx, q.createRefreshTokenStmt, createRefreshToken,
+		arg.Token,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.FamilyID,
+		arg.ParentToken,
+		arg.RememberMe,
+		pq.Array(arg.Scopes),
+	)
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -41,6 +72,10 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.RememberMe,
+		pq.Array(&i.Scopes),
 	)
 	return i, err
 }
@@ -50,12 +85,37 @@ DELETE FROM refresh_tokens
 `
 
 func (q *Queries) DeleteAllRefreshTokens(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteAllRefreshTokens)
+	_, err := q.exec(ctx, q.deleteAllRefreshTokensStmt, deleteAllRefreshTokens)
 	return err
 }
 
+const getRefreshToken = `-- name: GetRefreshToken :one
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, remember_me, scopes FROM refresh_tokens
+WHERE token = $1
+`
+
+// Returns the token whether or not it is still usable, so a revoked one
+// can be told apart from one that never existed.
+func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
+	row := q.queryRow(ctx, q.getRefreshTokenStmt, getRefreshToken, token)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.RememberMe,
+		pq.Array(&i.Scopes),
+	)
+	return i, err
+}
+
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red FROM users
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red, users.is_admin, users.username, users.display_name, users.bio, users.avatar_url, users.pinned_chirp_id, users.auth_provider, users.is_private, users.banned_at, users.banned_until, users.ban_reason, users.deleted_at, users.pending_email, users.pending_email_token_hash, users.pending_email_expires_at FROM users
 INNER JOIN refresh_tokens ON users.id = refresh_tokens.user_id
 WHERE refresh_tokens.token = $1
   AND refresh_tokens.expires_at > NOW()
@@ -63,7 +123,7 @@ WHERE refresh_tokens.token = $1
 `
 
 func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserFromRefreshToken, token)
+	row := q.queryRow(ctx, q.getUserFromRefreshTokenStmt, getUserFromRefreshToken, token)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -72,17 +132,99 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Us
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.PinnedChirpID,
+		&i.AuthProvider,
+		&i.IsPrivate,
+		&i.BannedAt,
+		&i.BannedUntil,
+		&i.BanReason,
+		&i.DeletedAt,
+		&i.PendingEmail,
+		&i.PendingEmailTokenHash,
+		&i.PendingEmailExpiresAt,
 	)
 	return i, err
 }
 
-const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
+const getUserSessions = `-- name: GetUserSessions :many
+SELECT created_at, expires_at FROM refresh_tokens
+WHERE user_id = $1 AND expires_at > NOW() AND revoked_at IS NULL
+ORDER BY created_at ASC
+`
+
+type GetUserSessionsRow struct {
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Session metadata only; the token itself is a credential and stays here.
+func (q *Queries) GetUserSessions(ctx context.Context, userID uuid.UUID) ([]GetUserSessionsRow, error) {
+	rows, err := q.query(ctx, q.getUserSessionsStmt, getUserSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserSessionsRow
+	for rows.Next() {
+		var i GetUserSessionsRow
+		if err := rows.Scan(&i.CreatedAt, &i.ExpiresAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeRefreshToken = `-- name: RevokeRefreshToken :execrows
 UPDATE refresh_tokens 
 SET revoked_at = NOW(), updated_at = NOW()
-WHERE token = $1
+WHERE token = $1 AND revoked_at IS NULL
+`
+
+// Affects no rows if the token was already revoked, so only one of two
+// concurrent rotations of the same token can succeed.
+func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) (int64, error) {
+	result, err := q.exec(ctx, q.revokeRefreshTokenStmt, revokeRefreshToken, token)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL
+`
+
+// Revokes every token rotated from the same login.
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) (int64, error) {
+	result, err := q.exec(ctx, q.revokeRefreshTokenFamilyStmt, revokeRefreshTokenFamily, familyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
 `
 
-func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
-	_, err := q.db.ExecContext(ctx, revokeRefreshToken, token)
+// Signs the user out everywhere, e.g. after a password change.
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.exec(ctx, q.revokeUserRefreshTokensStmt, revokeUserRefreshTokens, userID)
 	return err
 }
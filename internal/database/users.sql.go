@@ -7,29 +7,194 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const banUser = `-- name: BanUser :execrows
+UPDATE users
+SET banned_at = NOW(),
+    banned_until = $1,
+    ban_reason = $2,
+    updated_at = NOW()
+WHERE id = $3
+`
+
+type BanUserParams struct {
+	BannedUntil sql.NullTime
+	BanReason   sql.NullString
+	ID          uuid.UUID
+}
+
+// A NULL banned_until bans the user until they are unbanned. Banning a
+// banned user replaces their ban.
+func (q *Queries) BanUser(ctx context.Context, arg BanUserParams) (int64, error) {
+	result, err := q.exec(ctx, q.banUserStmt, banUser, arg.BannedUntil, arg.BanReason, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const canViewUser = `-- name: CanViewUser :one
+SELECT COALESCE(can_view_user($1::uuid, $2::uuid), FALSE)::boolean AS visible
+`
+
+type CanViewUserParams struct {
+	AuthorID uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+// Whether viewer_id, NULL for an anonymous request, may see author_id's
+// chirps and profile. An unknown author is never visible.
+func (q *Queries) CanViewUser(ctx context.Context, arg CanViewUserParams) (bool, error) {
+	row := q.queryRow(ctx, q.canViewUserStmt, canViewUser, arg.AuthorID, arg.ViewerID)
+	var visible bool
+	err := row.Scan(&visible)
+	return visible, err
+}
+
+const confirmPendingEmail = `-- name: ConfirmPendingEmail :one
+WITH old AS (
+    SELECT id, email FROM users
+    WHERE pending_email_token_hash = $1::text
+      AND pending_email_expires_at > NOW()
+    FOR UPDATE
+)
+UPDATE users
+SET email = users.pending_email,
+    pending_email = NULL,
+    pending_email_token_hash = NULL,
+    pending_email_expires_at = NULL,
+    updated_at = NOW()
+FROM old
+WHERE users.id = old.id
+RETURNING users.id, users.email, old.email AS old_email
+`
+
+type ConfirmPendingEmailRow struct {
+	ID       uuid.UUID
+	Email    string
+	OldEmail string
+}
+
+// Makes the pending email of the user whose unexpired token hashes to
+// token_hash their email, returning the address it replaced. The token is
+// cleared, so it only works once.
+func (q *Queries) ConfirmPendingEmail(ctx context.Context, tokenHash string) (ConfirmPendingEmailRow, error) {
+	row := q.queryRow(ctx, q.confirmPendingEmailStmt, confirmPendingEmail, tokenHash)
+	var i ConfirmPendingEmailRow
+	err := row.Scan(&i.ID, &i.Email, &i.OldEmail)
+	return i, err
+}
+
+const countUsers = `-- name: CountUsers :one
+SELECT COUNT(*) FROM users
+`
+
+func (q *Queries) CountUsers(ctx context.Context) (int64, error) {
+	row := q.queryRow(ctx, q.countUsersStmt, countUsers)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUsersWithAvatar = `-- name: CountUsersWithAvatar :one
+SELECT COUNT(*) FROM users
+WHERE avatar_url = $1
+`
+
+func (q *Queries) CountUsersWithAvatar(ctx context.Context, avatarUrl sql.NullString) (int64, error) {
+	row := q.queryRow(ctx, q.countUsersWithAvatarStmt, countUsersWithAvatar, avatarUrl)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createOAuthUser = `-- name: CreateOAuthUser :one
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username, auth_provider)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    NULL,
+    $2,
+    $3
+)
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, username, display_name, bio, avatar_url, pinned_chirp_id, auth_provider, is_private, banned_at, banned_until, ban_reason, deleted_at, pending_email, pending_email_token_hash, pending_email_expires_at
+`
+
+type CreateOAuthUserParams struct {
+	Email        string
+	Username     string
+	AuthProvider string
+}
+
+// Users signing in through an identity provider get no password.
+func (q *Queries) CreateOAuthUser(ctx context.Context, arg CreateOAuthUserParams) (User, error) {
+	row := q.queryRow(ctx, q.createOAuthUserStmt, createOAuthUser, arg.Email, arg.Username, arg.AuthProvider)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.PinnedChirpID,
+		&i.AuthProvider,
+		&i.IsPrivate,
+		&i.BannedAt,
+		&i.BannedUntil,
+		&i.BanReason,
+		&i.DeletedAt,
+		&i.PendingEmail,
+		&i.PendingEmailTokenHash,
+		&i.PendingEmailExpiresAt,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username, display_name, bio)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, username, display_name, bio, avatar_url, pinned_chirp_id, auth_provider, is_private, banned_at, banned_until, ban_reason, deleted_at, pending_email, pending_email_token_hash, pending_email_expires_at
 `
 
 type CreateUserParams struct {
 	Email          string
-	HashedPassword string
+	HashedPassword sql.NullString
+	Username       string
+	DisplayName    sql.NullString
+	Bio            sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.queryRow(ctx, q.createUserStmt, createUser,
+		arg.Email,
+		arg.HashedPassword,
+		arg.Username,
+		arg.DisplayName,
+		arg.Bio,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -38,6 +203,21 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.PinnedChirpID,
+		&i.AuthProvider,
+		&i.IsPrivate,
+		&i.BannedAt,
+		&i.BannedUntil,
+		&i.BanReason,
+		&i.DeletedAt,
+		&i.PendingEmail,
+		&i.PendingEmailTokenHash,
+		&i.PendingEmailExpiresAt,
 	)
 	return i, err
 }
@@ -47,17 +227,323 @@ DELETE FROM users
 `
 
 func (q *Queries) DeleteAllUsers(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteAllUsers)
+	_, err := q.exec(ctx, q.deleteAllUsersStmt, deleteAllUsers)
 	return err
 }
 
+const deleteUserKeepChirps = `-- name: DeleteUserKeepChirps :execrows
+WITH deleted_refresh_tokens AS (
+    DELETE FROM refresh_tokens WHERE user_id = $1
+), deleted_api_keys AS (
+    DELETE FROM api_keys WHERE user_id = $1
+), deleted_login_history AS (
+    DELETE FROM login_history WHERE user_id = $1
+), deleted_magic_link_tokens AS (
+    DELETE FROM magic_link_tokens WHERE user_id = $1
+), deleted_user_totp AS (
+    DELETE FROM user_totp WHERE user_id = $1
+), deleted_backup_codes AS (
+    DELETE FROM backup_codes WHERE user_id = $1
+), deleted_idempotency_keys AS (
+    DELETE FROM idempotency_keys WHERE user_id = $1
+), deleted_likes AS (
+    DELETE FROM chirp_likes WHERE user_id = $1
+), deleted_bookmarks AS (
+    DELETE FROM bookmarks WHERE user_id = $1
+), deleted_reports AS (
+    DELETE FROM chirp_reports WHERE reporter_id = $1
+), deleted_follows AS (
+    DELETE FROM follows WHERE follower_id = $1 OR followee_id = $1
+), deleted_follow_requests AS (
+    DELETE FROM follow_requests WHERE requester_id = $1 OR target_id = $1
+), deleted_mutes AS (
+    DELETE FROM mutes WHERE muter_id = $1 OR muted_id = $1
+), deleted_notifications AS (
+    DELETE FROM notifications WHERE user_id = $1 OR actor_id = $1
+)
+UPDATE users
+SET email = id::text || '@deleted.invalid',
+    username = 'deleted_' || LEFT(REPLACE(id::text, '-', ''), 22),
+    hashed_password = NULL,
+    display_name = NULL,
+    bio = NULL,
+    avatar_url = NULL,
+    pinned_chirp_id = NULL,
+    is_chirpy_red = FALSE,
+    is_admin = FALSE,
+    is_private = FALSE,
+    banned_at = NULL,
+    banned_until = NULL,
+    ban_reason = NULL,
+    pending_email = NULL,
+    pending_email_token_hash = NULL,
+    pending_email_expires_at = NULL,
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+  AND deleted_at IS NULL
+`
+
+// Replaces the user with a tombstone that their chirps stay attached to.
+// Everything identifying them is scrubbed, with the unique email and
+// username rewritten from the ID (the username cut to 30 characters like
+// any other), and the rest of their data goes as if the row had been
+// deleted.
+func (q *Queries) DeleteUserKeepChirps(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.exec(ctx, q.deleteUserKeepChirpsStmt, deleteUserKeepChirps, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAccountStatus = `-- name: GetAccountStatus :one
+SELECT deleted_at IS NOT NULL AS deleted,
+    (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))::boolean AS banned
+FROM users
+WHERE id = $1
+`
+
+type GetAccountStatusRow struct {
+	Deleted bool
+	Banned  bool
+}
+
+// Whether the user's account was deleted, and whether they are banned
+// right now.
+func (q *Queries) GetAccountStatus(ctx context.Context, id uuid.UUID) (GetAccountStatusRow, error) {
+	row := q.queryRow(ctx, q.getAccountStatusStmt, getAccountStatus, id)
+	var i GetAccountStatusRow
+	err := row.Scan(&i.Deleted, &i.Banned)
+	return i, err
+}
+
+const getHiddenAuthorIDs = `-- name: GetHiddenAuthorIDs :many
+SELECT author_id::uuid
+FROM unnest($1::uuid[]) AS author_id
+WHERE NOT can_view_user(author_id, $2::uuid)
+`
+
+type GetHiddenAuthorIDsParams struct {
+	AuthorIds []uuid.UUID
+	ViewerID  uuid.NullUUID
+}
+
+// The authors among author_ids whose chirps viewer_id may not see.
+func (q *Queries) GetHiddenAuthorIDs(ctx context.Context, arg GetHiddenAuthorIDsParams) ([]uuid.UUID, error) {
+	rows, err := q.query(ctx, q.getHiddenAuthorIDsStmt, getHiddenAuthorIDs, pq.Array(arg.AuthorIds), arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var author_id uuid.UUID
+		if err := rows.Scan(&author_id); err != nil {
+			return nil, err
+		}
+		items = append(items, author_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, username, display_name, bio, avatar_url, pinned_chirp_id, auth_provider, is_private, banned_at, banned_until, ban_reason, deleted_at, pending_email, pending_email_token_hash, pending_email_expires_at FROM users
 WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	row := q.queryRow(ctx, q.getUserByEmailStmt, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.PinnedChirpID,
+		&i.AuthProvider,
+		&i.IsPrivate,
+		&i.BannedAt,
+		&i.BannedUntil,
+		&i.BanReason,
+		&i.DeletedAt,
+		&i.PendingEmail,
+		&i.PendingEmailTokenHash,
+		&i.PendingEmailExpiresAt,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, username, display_name, bio, avatar_url, pinned_chirp_id, auth_provider, is_private, banned_at, banned_until, ban_reason, deleted_at, pending_email, pending_email_token_hash, pending_email_expires_at FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.queryRow(ctx, q.getUserByIDStmt, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.PinnedChirpID,
+		&i.AuthProvider,
+		&i.IsPrivate,
+		&i.BannedAt,
+		&i.BannedUntil,
+		&i.BanReason,
+		&i.DeletedAt,
+		&i.PendingEmail,
+		&i.PendingEmailTokenHash,
+		&i.PendingEmailExpiresAt,
+	)
+	return i, err
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT id, created_at, email, username, display_name, bio, avatar_url, is_chirpy_red, is_private
+FROM users
+WHERE deleted_at IS NULL
+  AND NOT (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))
+  AND ($1::boolean IS NULL OR is_chirpy_red = $1)
+ORDER BY created_at ASC, id ASC
+LIMIT $2 OFFSET $3
+`
+
+type ListUsersParams struct {
+	IsChirpyRed sql.NullBool
+	Limit       int32
+	Offset      int32
+}
+
+type ListUsersRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	Email       string
+	Username    string
+	DisplayName sql.NullString
+	Bio         sql.NullString
+	AvatarUrl   sql.NullString
+	IsChirpyRed bool
+	IsPrivate   bool
+}
+
+// The user directory: accounts that are neither deleted nor banned right
+// now, oldest first, optionally only those with or without Chirpy Red.
+// Only profile columns are read, never the password hash.
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
+	rows, err := q.query(ctx, q.listUsersStmt, listUsers, arg.IsChirpyRed, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersRow
+	for rows.Next() {
+		var i ListUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Email,
+			&i.Username,
+			&i.DisplayName,
+			&i.Bio,
+			&i.AvatarUrl,
+			&i.IsChirpyRed,
+			&i.IsPrivate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const rehashUserPassword = `-- name: RehashUserPassword :execrows
+UPDATE users
+SET hashed_password = $1::text
+WHERE id = $2
+  AND hashed_password = $3::text
+`
+
+type RehashUserPasswordParams struct {
+	NewHash string
+	ID      uuid.UUID
+	OldHash string
+}
+
+// Replaces the user's password hash with new_hash, a hash of the same
+// password at another cost, unless the password changed since old_hash was
+// read. updated_at is left alone since nothing the user sees changed.
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error) {
+	result, err := q.exec(ctx, q.rehashUserPasswordStmt, rehashUserPassword, arg.NewHash, arg.ID, arg.OldHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserAdmin = `-- name: SetUserAdmin :execrows
+UPDATE users
+SET is_admin = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserAdminParams struct {
+	ID      uuid.UUID
+	IsAdmin bool
+}
+
+func (q *Queries) SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (int64, error) {
+	result, err := q.exec(ctx, q.setUserAdminStmt, setUserAdmin, arg.ID, arg.IsAdmin)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserAvatar = `-- name: SetUserAvatar :one
+UPDATE users
+SET avatar_url = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, username, display_name, bio, avatar_url, pinned_chirp_id, auth_provider, is_private, banned_at, banned_until, ban_reason, deleted_at, pending_email, pending_email_token_hash, pending_email_expires_at
+`
+
+type SetUserAvatarParams struct {
+	ID        uuid.UUID
+	AvatarUrl sql.NullString
+}
+
+func (q *Queries) SetUserAvatar(ctx context.Context, arg SetUserAvatarParams) (User, error) {
+	row := q.queryRow(ctx, q.setUserAvatarStmt, setUserAvatar, arg.ID, arg.AvatarUrl)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -66,27 +552,212 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.PinnedChirpID,
+		&i.AuthProvider,
+		&i.IsPrivate,
+		&i.BannedAt,
+		&i.BannedUntil,
+		&i.BanReason,
+		&i.DeletedAt,
+		&i.PendingEmail,
+		&i.PendingEmailTokenHash,
+		&i.PendingEmailExpiresAt,
 	)
 	return i, err
 }
 
+const setUserPendingEmail = `-- name: SetUserPendingEmail :one
+UPDATE users
+SET pending_email = $1::text,
+    pending_email_token_hash = $2::text,
+    pending_email_expires_at = $3::timestamp,
+    updated_at = NOW()
+WHERE id = $4
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, username, display_name, bio, avatar_url, pinned_chirp_id, auth_provider, is_private, banned_at, banned_until, ban_reason, deleted_at, pending_email, pending_email_token_hash, pending_email_expires_at
+`
+
+type SetUserPendingEmailParams struct {
+	PendingEmail string
+	TokenHash    string
+	ExpiresAt    time.Time
+	ID           uuid.UUID
+}
+
+// Starts changing the user's email to pending_email, replacing any change
+// already waiting. Nothing changes for logins until the token sent to the
+// new address is confirmed.
+func (q *Queries) SetUserPendingEmail(ctx context.Context, arg SetUserPendingEmailParams) (User, error) {
+	row := q.queryRow(ctx, q.setUserPendingEmailStmt, setUserPendingEmail,
+		arg.PendingEmail,
+		arg.TokenHash,
+		arg.ExpiresAt,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.PinnedChirpID,
+		&i.AuthProvider,
+		&i.IsPrivate,
+		&i.BannedAt,
+		&i.BannedUntil,
+		&i.BanReason,
+		&i.DeletedAt,
+		&i.PendingEmail,
+		&i.PendingEmailTokenHash,
+		&i.PendingEmailExpiresAt,
+	)
+	return i, err
+}
+
+const setUserPinnedChirp = `-- name: SetUserPinnedChirp :execrows
+UPDATE users
+SET pinned_chirp_id = chirps.id,
+    updated_at = NOW()
+FROM chirps
+WHERE users.id = $1
+  AND chirps.id = $2
+  AND chirps.user_id = users.id
+  AND chirps.deleted_at IS NULL
+`
+
+type SetUserPinnedChirpParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+// Only a live chirp of the user's own can be pinned; otherwise no row is
+// updated.
+func (q *Queries) SetUserPinnedChirp(ctx context.Context, arg SetUserPinnedChirpParams) (int64, error) {
+	result, err := q.exec(ctx, q.setUserPinnedChirpStmt, setUserPinnedChirp, arg.UserID, arg.ChirpID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserPrivate = `-- name: SetUserPrivate :one
+UPDATE users
+SET is_private = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, username, display_name, bio, avatar_url, pinned_chirp_id, auth_provider, is_private, banned_at, banned_until, ban_reason, deleted_at, pending_email, pending_email_token_hash, pending_email_expires_at
+`
+
+type SetUserPrivateParams struct {
+	ID        uuid.UUID
+	IsPrivate bool
+}
+
+func (q *Queries) SetUserPrivate(ctx context.Context, arg SetUserPrivateParams) (User, error) {
+	row := q.queryRow(ctx, q.setUserPrivateStmt, setUserPrivate, arg.ID, arg.IsPrivate)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.PinnedChirpID,
+		&i.AuthProvider,
+		&i.IsPrivate,
+		&i.BannedAt,
+		&i.BannedUntil,
+		&i.BanReason,
+		&i.DeletedAt,
+		&i.PendingEmail,
+		&i.PendingEmailTokenHash,
+		&i.PendingEmailExpiresAt,
+	)
+	return i, err
+}
+
+const unbanUser = `-- name: UnbanUser :execrows
+UPDATE users
+SET banned_at = NULL,
+    banned_until = NULL,
+    ban_reason = NULL,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) UnbanUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.exec(ctx, q.unbanUserStmt, unbanUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unpinChirp = `-- name: UnpinChirp :exec
+UPDATE users
+SET pinned_chirp_id = NULL,
+    updated_at = NOW()
+WHERE pinned_chirp_id = $1
+`
+
+func (q *Queries) UnpinChirp(ctx context.Context, pinnedChirpID uuid.NullUUID) error {
+	_, err := q.exec(ctx, q.unpinChirpStmt, unpinChirp, pinnedChirpID)
+	return err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users 
-SET email = $2, 
-    hashed_password = $3, 
+SET email = COALESCE($2, email), 
+    hashed_password = COALESCE($3, hashed_password), 
+    username = COALESCE($4, username),
+    display_name = NULLIF(COALESCE($5, display_name), ''),
+    bio = NULLIF(COALESCE($6, bio), ''),
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+  AND ($7::timestamp IS NULL OR updated_at = $7)
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, username, display_name, bio, avatar_url, pinned_chirp_id, auth_provider, is_private, banned_at, banned_until, ban_reason, deleted_at, pending_email, pending_email_token_hash, pending_email_expires_at
 `
 
 type UpdateUserParams struct {
-	ID             uuid.UUID
-	Email          string
-	HashedPassword string
+	ID                uuid.UUID
+	Email             sql.NullString
+	HashedPassword    sql.NullString
+	Username          sql.NullString
+	DisplayName       sql.NullString
+	Bio               sql.NullString
+	ExpectedUpdatedAt sql.NullTime
 }
 
+// Fields passed as NULL are left unchanged. An empty display_name or bio
+// clears it. When expected_updated_at is set, the row is only updated if it
+// has not changed since then; otherwise no row is returned.
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.ID, arg.Email, arg.HashedPassword)
+	row := q.queryRow(ctx, q.updateUserStmt, updateUser,
+		arg.ID,
+		arg.Email,
+		arg.HashedPassword,
+		arg.Username,
+		arg.DisplayName,
+		arg.Bio,
+		arg.ExpectedUpdatedAt,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -95,6 +766,21 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.PinnedChirpID,
+		&i.AuthProvider,
+		&i.IsPrivate,
+		&i.BannedAt,
+		&i.BannedUntil,
+		&i.BanReason,
+		&i.DeletedAt,
+		&i.PendingEmail,
+		&i.PendingEmailTokenHash,
+		&i.PendingEmailExpiresAt,
 	)
 	return i, err
 }
@@ -107,6 +793,19 @@ WHERE id = $1
 `
 
 func (q *Queries) UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, upgradeUserToChirpyRed, id)
+	_, err := q.exec(ctx, q.upgradeUserToChirpyRedStmt, upgradeUserToChirpyRed, id)
 	return err
 }
+
+const usernameExists = `-- name: UsernameExists :one
+SELECT EXISTS (SELECT 1 FROM users WHERE username = $1)
+`
+
+// Whether any account, banned or not, holds username. Deleted accounts'
+// usernames are rewritten from their ID, so they never match a valid one.
+func (q *Queries) UsernameExists(ctx context.Context, username string) (bool, error) {
+	row := q.queryRow(ctx, q.usernameExistsStmt, usernameExists, username)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,188 @@
+// Package i18n translates API error messages. Messages are looked up by
+// error code in a catalog of embedded JSON files, one per language, and the
+// language is picked from the request's Accept-Language header. English is
+// the language the messages are written in, so it needs no file.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// English is the language of the untranslated messages.
+const English = "en"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Catalog holds the translations of each error code by language.
+type Catalog struct {
+	// messages maps a lowercase language tag to error codes to fmt
+	// formats
+	messages map[string]map[string]string
+}
+
+// Load reads a catalog from the *.json files of fsys. Each file is named
+// after its language tag, e.g. de.json, and maps error codes to messages,
+// which may use fmt verbs for the arguments given with the code.
+func Load(fsys fs.FS) (*Catalog, error) {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{messages: make(map[string]map[string]string)}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		c.messages[strings.ToLower(strings.TrimSuffix(path.Base(file), ".json"))] = messages
+	}
+	return c, nil
+}
+
+// Embedded returns the catalog of the translations shipped with Chirpy.
+func Embedded() *Catalog {
+	sub, err := fs.Sub(locales, "locales")
+	if err != nil {
+		panic(err)
+	}
+	c, err := Load(sub)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Languages lists the catalog's languages, English included, sorted.
+func (c *Catalog) Languages() []string {
+	langs := []string{English}
+	for lang := range c.messages {
+		if lang != English {
+			langs = append(langs, lang)
+		}
+	}
+	slices.Sort(langs)
+	return langs
+}
+
+// Match picks the catalog language that best satisfies an Accept-Language
+// header, falling back to English. A region the catalog lacks falls back to
+// its language, so de-AT gets de; a wildcard accepts English unless the
+// header rules it out with q=0.
+func (c *Catalog) Match(acceptLanguage string) string {
+	prefs := ParseAcceptLanguage(acceptLanguage)
+	excluded := make(map[string]bool)
+	for _, p := range prefs {
+		if p.Q == 0 {
+			excluded[p.Tag] = true
+		}
+	}
+	for _, p := range prefs {
+		if p.Q == 0 {
+			continue
+		}
+		if p.Tag == "*" {
+			if !excluded[English] {
+				return English
+			}
+			for _, lang := range c.Languages() {
+				if !excluded[lang] {
+					return lang
+				}
+			}
+			continue
+		}
+		if lang, ok := c.supports(p.Tag); ok && !excluded[lang] {
+			return lang
+		}
+	}
+	return English
+}
+
+// supports returns the catalog language serving tag, trying the tag itself
+// and then its primary language.
+func (c *Catalog) supports(tag string) (string, bool) {
+	for {
+		if tag == English {
+			return English, true
+		}
+		if _, ok := c.messages[tag]; ok {
+			return tag, true
+		}
+		i := strings.LastIndexByte(tag, '-')
+		if i < 0 {
+			return "", false
+		}
+		tag = tag[:i]
+	}
+}
+
+// Message returns the message for code in lang, formatted with args. It
+// reports false when lang has no translation of code, in which case the
+// caller's English message stands.
+func (c *Catalog) Message(lang, code string, args ...any) (string, bool) {
+	format, ok := c.messages[lang][code]
+	if !ok {
+		return "", false
+	}
+	if len(args) == 0 {
+		return format, true
+	}
+	return fmt.Sprintf(format, args...), true
+}
+
+// Preference is one language range of an Accept-Language header with its
+// quality.
+type Preference struct {
+	// Tag is the lowercase language range, e.g. "de-at" or "*"
+	Tag string
+	Q   float64
+}
+
+// ParseAcceptLanguage parses an Accept-Language header into its language
+// ranges, most preferred first; ranges of equal quality keep their order.
+// Ranges with a malformed quality are dropped, and those with q=0, which
+// rule a language out, are kept at the end.
+func ParseAcceptLanguage(header string) []Preference {
+	var prefs []Preference
+	for part := range strings.SplitSeq(header, ",") {
+		params := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(params[0]))
+		if tag == "" {
+			continue
+		}
+		q, ok := 1.0, true
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(strings.TrimSpace(name), "q") {
+				var err error
+				q, err = strconv.ParseFloat(strings.TrimSpace(value), 64)
+				ok = err == nil && q >= 0 && q <= 1
+			}
+		}
+		if ok {
+			prefs = append(prefs, Preference{Tag: tag, Q: q})
+		}
+	}
+	slices.SortStableFunc(prefs, func(a, b Preference) int {
+		switch {
+		case a.Q > b.Q:
+			return -1
+		case a.Q < b.Q:
+			return 1
+		}
+		return 0
+	})
+	return prefs
+}
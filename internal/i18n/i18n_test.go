@@ -0,0 +1,103 @@
+package i18n
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []Preference
+	}{
+		{"", nil},
+		{"de", []Preference{{"de", 1}}},
+		{"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5", []Preference{
+			{"fr-ch", 1}, {"fr", 0.9}, {"en", 0.8}, {"de", 0.7}, {"*", 0.5},
+		}},
+		// Out of order, with equal qualities kept in order
+		{"en;q=0.5, de , es;q=0.5,fr", []Preference{{"de", 1}, {"fr", 1}, {"en", 0.5}, {"es", 0.5}}},
+		// Malformed qualities drop the range; q=0 sorts last
+		{"de;q=0, fr;q=abc, es;q=2, en; Q=0.3", []Preference{{"en", 0.3}, {"de", 0}}},
+		{" , ;q=1", nil},
+	}
+	for _, tt := range tests {
+		if got := ParseAcceptLanguage(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAcceptLanguage(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	c, err := Load(fstest.MapFS{
+		"de.json":    {Data: []byte(`{"unauthorized": "Nicht autorisiert"}`)},
+		"pt-BR.json": {Data: []byte(`{"unauthorized": "Não autorizado"}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.Languages(), []string{"de", "en", "pt-br"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Languages() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		header, want string
+	}{
+		{"", "en"},
+		{"de", "de"},
+		{"DE-at", "de"},
+		{"pt-BR", "pt-br"},
+		// There is no plain pt to fall back to
+		{"pt-PT", "en"},
+		{"ja, de;q=0.5", "de"},
+		{"ja, ko", "en"},
+		{"en-GB, de;q=0.9", "en"},
+		{"en;q=0.5, de", "de"},
+		{"*", "en"},
+		{"ja, *;q=0.1", "en"},
+		{"*, en;q=0", "de"},
+		{"de;q=0, *", "en"},
+		{"de;q=0", "en"},
+		{"de;q=nonsense, pt-br;q=0.1", "pt-br"},
+	}
+	for _, tt := range tests {
+		if got := c.Match(tt.header); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	c, err := Load(fstest.MapFS{
+		"de.json": {Data: []byte(`{"unauthorized": "Nicht autorisiert", "quota": "Höchstens %d"}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg, ok := c.Message("de", "quota", 3); !ok || msg != "Höchstens 3" {
+		t.Errorf("formatted message = %q, %v", msg, ok)
+	}
+	if msg, ok := c.Message("de", "unauthorized"); !ok || msg != "Nicht autorisiert" {
+		t.Errorf("message = %q, %v", msg, ok)
+	}
+	for _, lang := range []string{"en", "fr"} {
+		if msg, ok := c.Message(lang, "unauthorized"); ok {
+			t.Errorf("Message(%q) = %q, want none", lang, msg)
+		}
+	}
+	if msg, ok := c.Message("de", "missing"); ok {
+		t.Errorf("untranslated code = %q, want none", msg)
+	}
+
+	if _, err := Load(fstest.MapFS{"de.json": {Data: []byte(`{"unauthorized": 1}`)}}); err == nil {
+		t.Error("malformed file loaded")
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	c := Embedded()
+	if msg, ok := c.Message("de", "unauthorized"); !ok || msg == "" {
+		t.Errorf("embedded German catalog has no unauthorized message")
+	}
+}
@@ -0,0 +1,30 @@
+package mail
+
+import (
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+var accountExistsHTML = htmltemplate.Must(htmltemplate.New("account_exists").Parse(`<!DOCTYPE html>
+<html>
+  <body>
+    <h1>You already have a Chirpy account</h1>
+    <p>Someone just tried to sign up to Chirpy with <strong>{{.Email}}</strong>, which already has an account.</p>
+    <p>If that was you, log in with your existing password instead. If it was not, you can ignore this email.</p>
+  </body>
+</html>
+`))
+
+var accountExistsText = texttemplate.Must(texttemplate.New("account_exists").Parse(`You already have a Chirpy account
+
+Someone just tried to sign up to Chirpy with {{.Email}}, which already has an account.
+
+If that was you, log in with your existing password instead. If it was not, you can ignore this email.
+`))
+
+// AccountExists builds the message sent when someone signs up with an email
+// that is already registered. It is how the owner learns the outcome when
+// the API does not reveal it.
+func AccountExists(email string) (Message, error) {
+	return render(email, "You already have a Chirpy account", accountExistsHTML, accountExistsText)
+}
@@ -0,0 +1,60 @@
+package mail
+
+import (
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+var confirmEmailChangeHTML = htmltemplate.Must(htmltemplate.New("confirm_email_change").Parse(`<!DOCTYPE html>
+<html>
+  <body>
+    <h1>Confirm your new email address</h1>
+    <p>Someone asked to change the email address of a Chirpy account to <strong>{{.Email}}</strong>.</p>
+    <p>If that was you, <a href="{{.Link}}">confirm the change</a> within 24 hours. Until then you keep logging in with your old address.</p>
+    <p>If it was not, you can ignore this email and nothing will change.</p>
+  </body>
+</html>
+`))
+
+var confirmEmailChangeText = texttemplate.Must(texttemplate.New("confirm_email_change").Parse(`Confirm your new email address
+
+Someone asked to change the email address of a Chirpy account to {{.Email}}.
+
+If that was you, confirm the change within 24 hours by opening this link:
+
+{{.Link}}
+
+Until then you keep logging in with your old address. If it was not you, you can ignore this email and nothing will change.
+`))
+
+// ConfirmEmailChange builds the message sent to the new address of an email
+// change, with the link that makes the change.
+func ConfirmEmailChange(email, link string) (Message, error) {
+	data := struct{ Email, Link string }{email, link}
+	return renderData(email, "Confirm your new Chirpy email address", data, confirmEmailChangeHTML, confirmEmailChangeText)
+}
+
+var emailChangedHTML = htmltemplate.Must(htmltemplate.New("email_changed").Parse(`<!DOCTYPE html>
+<html>
+  <body>
+    <h1>Your email address was changed</h1>
+    <p>The Chirpy account that used <strong>{{.Email}}</strong> now uses <strong>{{.NewEmail}}</strong>, and logs in with that instead.</p>
+    <p>If you did not make this change, someone else may have access to your account. Contact support straight away.</p>
+  </body>
+</html>
+`))
+
+var emailChangedText = texttemplate.Must(texttemplate.New("email_changed").Parse(`Your email address was changed
+
+The Chirpy account that used {{.Email}} now uses {{.NewEmail}}, and logs in with that instead.
+
+If you did not make this change, someone else may have access to your account. Contact support straight away.
+`))
+
+// EmailChanged builds the message sent to a user's old address once a
+// change to newEmail has been confirmed, so an unexpected change does not
+// go unnoticed.
+func EmailChanged(oldEmail, newEmail string) (Message, error) {
+	data := struct{ Email, NewEmail string }{oldEmail, newEmail}
+	return renderData(oldEmail, "Your Chirpy email address was changed", data, emailChangedHTML, emailChangedText)
+}
@@ -0,0 +1,34 @@
+package mail
+
+import (
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+var magicLinkHTML = htmltemplate.Must(htmltemplate.New("magic_link").Parse(`<!DOCTYPE html>
+<html>
+  <body>
+    <h1>Log in to Chirpy</h1>
+    <p>Someone asked to log in to the Chirpy account for <strong>{{.Email}}</strong> without a password.</p>
+    <p>If that was you, <a href="{{.Link}}">log in</a> within 15 minutes. The link works once.</p>
+    <p>If it was not, you can ignore this email.</p>
+  </body>
+</html>
+`))
+
+var magicLinkText = texttemplate.Must(texttemplate.New("magic_link").Parse(`Log in to Chirpy
+
+Someone asked to log in to the Chirpy account for {{.Email}} without a password.
+
+If that was you, log in within 15 minutes by opening this link, which works once:
+
+{{.Link}}
+
+If it was not, you can ignore this email.
+`))
+
+// MagicLink builds the message with a one-time link that logs email in.
+func MagicLink(email, link string) (Message, error) {
+	data := struct{ Email, Link string }{email, link}
+	return renderData(email, "Your Chirpy login link", data, magicLinkHTML, magicLinkText)
+}
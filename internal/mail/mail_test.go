@@ -0,0 +1,166 @@
+package mail
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWelcome(t *testing.T) {
+	msg, err := Welcome("new@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.To != "new@example.com" || msg.Subject == "" {
+		t.Errorf("unexpected headers: %+v", msg)
+	}
+	for name, body := range map[string]string{"text": msg.Text, "html": msg.HTML} {
+		if !strings.Contains(body, "new@example.com") {
+			t.Errorf("%s body does not mention the address: %q", name, body)
+		}
+	}
+	if !strings.Contains(msg.HTML, "<h1>") || strings.Contains(msg.Text, "<") {
+		t.Error("expected an HTML body and a plain-text alternative")
+	}
+
+	// The address is escaped in the HTML version
+	msg, err = Welcome(`"<b>"@example.com`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(msg.HTML, "<b>") {
+		t.Errorf("HTML body was not escaped: %q", msg.HTML)
+	}
+}
+
+func TestAccountExists(t *testing.T) {
+	msg, err := AccountExists("taken@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.To != "taken@example.com" || msg.Subject == "" || msg.Subject == "Welcome to Chirpy" {
+		t.Errorf("unexpected headers: %+v", msg)
+	}
+	for name, body := range map[string]string{"text": msg.Text, "html": msg.HTML} {
+		if !strings.Contains(body, "taken@example.com") || !strings.Contains(body, "already has an account") {
+			t.Errorf("%s body does not explain the attempt: %q", name, body)
+		}
+	}
+}
+
+func TestEmailChange(t *testing.T) {
+	link := "https://chirpy.example.com/api/users/me/confirm_email?token=abc&x=1"
+	msg, err := ConfirmEmailChange("new@example.com", link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.To != "new@example.com" || msg.Subject == "" {
+		t.Errorf("unexpected headers: %+v", msg)
+	}
+	if !strings.Contains(msg.Text, link) || !strings.Contains(msg.HTML, `href="https://chirpy.example.com/api/users/me/confirm_email?token=abc&amp;x=1"`) {
+		t.Errorf("confirmation does not carry the link:\n%s\n%s", msg.Text, msg.HTML)
+	}
+
+	msg, err = EmailChanged("old@example.com", "new@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.To != "old@example.com" {
+		t.Errorf("notification sent to %q, want the old address", msg.To)
+	}
+	for name, body := range map[string]string{"text": msg.Text, "html": msg.HTML} {
+		if !strings.Contains(body, "old@example.com") || !strings.Contains(body, "new@example.com") {
+			t.Errorf("%s body does not name both addresses: %q", name, body)
+		}
+	}
+}
+
+func TestMagicLink(t *testing.T) {
+	link := "https://chirpy.example.com/api/login/magic/verify?token=abc"
+	msg, err := MagicLink("alice@example.com", link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.To != "alice@example.com" || msg.Subject == "" {
+		t.Errorf("unexpected headers: %+v", msg)
+	}
+	for name, body := range map[string]string{"text": msg.Text, "html": msg.HTML} {
+		if !strings.Contains(body, link) {
+			t.Errorf("%s body does not carry the link: %q", name, body)
+		}
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	raw, err := buildMessage("chirpy@example.com", Message{
+		To:      "new@example.com",
+		Subject: "Welcome to Chirpy",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	}, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := netmail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatalf("message does not parse: %v", err)
+	}
+	if got := parsed.Header.Get("To"); got != "new@example.com" {
+		t.Errorf("To = %q", got)
+	}
+	if got := parsed.Header.Get("From"); got != "chirpy@example.com" {
+		t.Errorf("From = %q", got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", parsed.Header.Get("Content-Type"), err)
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var parts []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(quotedprintable.NewReader(part))
+		parts = append(parts, part.Header.Get("Content-Type")+": "+string(body))
+	}
+	want := []string{
+		"text/plain; charset=utf-8: plain body",
+		"text/html; charset=utf-8: <p>html body</p>",
+	}
+	if strings.Join(parts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("parts = %q, want %q", parts, want)
+	}
+
+	if _, err := buildMessage("chirpy@example.com", Message{To: "a@example.com\r\nBcc: b@example.com"}, time.Now()); err == nil {
+		t.Error("expected header injection to be rejected")
+	}
+}
+
+func TestNewSMTPSender(t *testing.T) {
+	valid := SMTPConfig{Host: "smtp.example.com", Port: "587", From: "chirpy@example.com"}
+	if _, err := NewSMTPSender(valid); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+
+	missingFrom := valid
+	missingFrom.From = ""
+	userOnly := valid
+	userOnly.Username = "chirpy"
+	for name, cfg := range map[string]SMTPConfig{"missing from": missingFrom, "user without password": userOnly} {
+		if _, err := NewSMTPSender(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package mail
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+var welcomeHTML = htmltemplate.Must(htmltemplate.New("welcome").Parse(`<!DOCTYPE html>
+<html>
+  <body>
+    <h1>Welcome to Chirpy!</h1>
+    <p>Your account for <strong>{{.Email}}</strong> is ready.</p>
+    <p>Log in and send your first chirp &mdash; 140 characters, or 280 with Chirpy Red.</p>
+  </body>
+</html>
+`))
+
+var welcomeText = texttemplate.Must(texttemplate.New("welcome").Parse(`Welcome to Chirpy!
+
+Your account for {{.Email}} is ready.
+
+Log in and send your first chirp - 140 characters, or 280 with Chirpy Red.
+`))
+
+// Welcome builds the message sent to a newly registered user.
+func Welcome(email string) (Message, error) {
+	return render(email, "Welcome to Chirpy", welcomeHTML, welcomeText)
+}
+
+// render builds a message to email from a pair of templates, each executed
+// with the address as .Email.
+func render(email, subject string, html *htmltemplate.Template, text *texttemplate.Template) (Message, error) {
+	return renderData(email, subject, struct{ Email string }{email}, html, text)
+}
+
+// renderData builds a message to email from a pair of templates, each
+// executed with data.
+func renderData(email, subject string, data any, html *htmltemplate.Template, text *texttemplate.Template) (Message, error) {
+	var htmlBody, textBody bytes.Buffer
+	if err := html.Execute(&htmlBody, data); err != nil {
+		return Message{}, err
+	}
+	if err := text.Execute(&textBody, data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      email,
+		Subject: subject,
+		Text:    textBody.String(),
+		HTML:    htmlBody.String(),
+	}, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// failingLookups is a fakeStore whose named lookup fails with a database
+// error instead of finding or missing the row.
+type failingLookups struct {
+	*fakeStore
+	failing string
+}
+
+var errConnectionReset = errors.New("connection reset by peer")
+
+func (s failingLookups) GetChirpByID(ctx context.Context, id uuid.UUID) (database.GetChirpByIDRow, error) {
+	if s.failing == "GetChirpByID" {
+		return database.GetChirpByIDRow{}, errConnectionReset
+	}
+	return s.fakeStore.GetChirpByID(ctx, id)
+}
+
+func (s failingLookups) GetChirpByIDWithAuthor(ctx context.Context, id uuid.UUID) (database.GetChirpByIDWithAuthorRow, error) {
+	if s.failing == "GetChirpByIDWithAuthor" {
+		return database.GetChirpByIDWithAuthorRow{}, errConnectionReset
+	}
+	return s.fakeStore.GetChirpByIDWithAuthor(ctx, id)
+}
+
+func (s failingLookups) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	if s.failing == "GetUserByID" {
+		return database.User{}, errConnectionReset
+	}
+	return s.fakeStore.GetUserByID(ctx, id)
+}
+
+func (s failingLookups) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	if s.failing == "GetUserByEmail" {
+		return database.User{}, errConnectionReset
+	}
+	return s.fakeStore.GetUserByEmail(ctx, email)
+}
+
+func TestLookupErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	other := db.addUser(t, "other@example.com")
+	chirp := db.addChirp(t, other.ID, "Hello")
+	token := makeTestToken(t, user.ID)
+	mux := newTestMux(t, cfg, false)
+	missing := uuid.NewString()
+
+	jsonBody := func(v any) []byte {
+		body, _ := json.Marshal(v)
+		return body
+	}
+	tests := []struct {
+		name    string
+		failing string
+		method  string
+		// missingPath finds no row; path finds one unless the lookup fails
+		missingPath, path string
+		body, missingBody []byte
+		missingStatus     int
+	}{
+		{"chirp", "GetChirpByID", http.MethodGet, "/api/v1/chirps/" + missing, "/api/v1/chirps/" + chirp.ID.String(), nil, nil, http.StatusNotFound},
+		{"chirp with author", "GetChirpByIDWithAuthor", http.MethodGet, "/api/v1/chirps/" + missing + "?include=author", "/api/v1/chirps/" + chirp.ID.String() + "?include=author", nil, nil, http.StatusNotFound},
+		{"replies", "GetChirpByID", http.MethodGet, "/api/v1/chirps/" + missing + "/replies", "/api/v1/chirps/" + chirp.ID.String() + "/replies", nil, nil, http.StatusNotFound},
+		{"likers", "GetChirpByID", http.MethodGet, "/api/v1/chirps/" + missing + "/likes", "/api/v1/chirps/" + chirp.ID.String() + "/likes", nil, nil, http.StatusNotFound},
+		{"like", "GetChirpByID", http.MethodPost, "/api/v1/chirps/" + missing + "/like", "/api/v1/chirps/" + chirp.ID.String() + "/like", nil, nil, http.StatusNotFound},
+		{"edit", "GetChirpByID", http.MethodPut, "/api/v1/chirps/" + missing, "/api/v1/chirps/" + chirp.ID.String(), jsonBody(map[string]string{"body": "Edited"}), jsonBody(map[string]string{"body": "Edited"}), http.StatusNotFound},
+		{"delete", "GetChirpByID", http.MethodDelete, "/api/v1/chirps/" + missing, "/api/v1/chirps/" + chirp.ID.String(), nil, nil, http.StatusNotFound},
+		{"reply", "GetChirpByID", http.MethodPost, "/api/v1/chirps", "/api/v1/chirps", jsonBody(map[string]string{"body": "Reply", "parent_chirp_id": chirp.ID.String()}), jsonBody(map[string]string{"body": "Reply", "parent_chirp_id": missing}), http.StatusBadRequest},
+		{"user", "GetUserByID", http.MethodGet, "/api/v1/users/" + missing, "/api/v1/users/" + other.ID.String(), nil, nil, http.StatusNotFound},
+		{"stats", "GetUserByID", http.MethodGet, "/api/v1/users/" + missing + "/stats", "/api/v1/users/" + other.ID.String() + "/stats", nil, nil, http.StatusNotFound},
+		{"follow", "GetUserByID", http.MethodPost, "/api/v1/users/" + missing + "/follow", "/api/v1/users/" + other.ID.String() + "/follow", nil, nil, http.StatusNotFound},
+		{"login", "GetUserByEmail", http.MethodPost, "/api/v1/login", "/api/v1/login", jsonBody(map[string]string{"email": "user@example.com", "password": "secret"}), jsonBody(map[string]string{"email": "nobody@example.com", "password": "secret"}), http.StatusUnauthorized},
+		{"admin", "GetUserByID", http.MethodGet, "/admin/audit", "/admin/audit", nil, nil, http.StatusForbidden},
+	}
+	serve := func(method, path string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	for _, tt := range tests {
+		cfg.dbQueries = db
+		if rr := serve(tt.method, tt.missingPath, tt.missingBody); rr.Code != tt.missingStatus {
+			t.Errorf("%s, no such row: got status %v want %v", tt.name, rr.Code, tt.missingStatus)
+		}
+
+		cfg.dbQueries = failingLookups{fakeStore: db, failing: tt.failing}
+		rr := serve(tt.method, tt.path, tt.body)
+		var errResp ErrorResponse
+		json.Unmarshal(rr.Body.Bytes(), &errResp)
+		if rr.Code != http.StatusInternalServerError || errResp.Error != "Something went wrong" {
+			t.Errorf("%s, %s failing: got status %v %+v, want a 500", tt.name, tt.failing, rr.Code, errResp)
+		}
+	}
+}
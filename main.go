@@ -1,81 +1,218 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"sync/atomic"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/AlexTLDR/chirpy/internal/database"
-	"github.com/joho/godotenv"
+	"github.com/AlexTLDR/chirpy/internal/config"
+	"github.com/AlexTLDR/chirpy/internal/mail"
 	_ "github.com/lib/pq"
 )
 
-func main() {
-	const filepathRoot = "."
-	const port = "8080"
+// version is set at build time with
+// -ldflags "-X main.version=v1.2.3".
+var version = "dev"
 
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
+func main() {
+	// Every setting is read and checked up front, so a bad environment
+	// stops the server here with the full list of problems. .env only
+	// fills in variables the flags and environment leave unset
+	conf, err := config.Parse(os.Args[1:], os.LookupEnv, os.Stderr)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return
+	case errors.Is(err, config.ErrVersion):
+		fmt.Println("chirpy", version)
+		return
+	case errors.As(err, new(*config.Error)):
+		log.Fatal(err)
+	case err != nil:
+		// The flag package has already printed the error and usage
+		os.Exit(2)
 	}
-
-	dbURL := os.Getenv("DB_URL")
-	if dbURL == "" {
-		log.Fatal("DB_URL environment variable is not set")
+	if conf.EnvFile == "" {
+		log.Print("No .env file; using the environment and flags only")
 	}
 
-	db, err := sql.Open("postgres", dbURL)
+	db, err := sql.Open("postgres", conf.DBURL)
 	if err != nil {
 		log.Fatal("Error opening database:", err)
 	}
 	defer db.Close()
 
-	dbQueries := database.New(db)
+	// Queries are prepared once here rather than parsed on every request
+	primaryQueries := prepareQueries(context.Background(), db, "database")
+	defer primaryQueries.Close()
 
-	platform := os.Getenv("PLATFORM")
-	if platform == "" {
-		log.Fatal("PLATFORM environment variable is not set")
+	// Chirp reads and metrics counts go to the read replica if there is one
+	var dbStore store = primaryQueries
+	if conf.DBReplicaURL != "" {
+		replicaDB, err := sql.Open("postgres", conf.DBReplicaURL)
+		if err != nil {
+			log.Fatal("Error opening read replica:", err)
+		}
+		defer replicaDB.Close()
+		replicaQueries := prepareQueries(context.Background(), replicaDB, "read replica")
+		defer replicaQueries.Close()
+		dbStore = newReplicaStore(dbStore, replicaQueries)
 	}
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("JWT_SECRET environment variable is not set")
+	// Every query is timed for the admin metrics
+	queryMetrics := &queryMetrics{}
+	dbQueries := instrumentStore(dbStore, queryMetrics)
+
+	// Serve HTTPS directly when a certificate is configured
+	tlsConfig, err := loadTLSConfig(conf.TLSCertFile, conf.TLSKeyFile)
+	if err != nil {
+		log.Fatal(err)
 	}
+	timeouts := serverTimeouts(conf.Timeouts)
 
-	polkaKey := os.Getenv("POLKA_KEY")
-	if polkaKey == "" {
-		log.Fatal("POLKA_KEY environment variable is not set")
+	// Email goes through SMTP when it is configured, except in dev
+	var mailer mail.Sender = mail.NoopSender{}
+	if conf.SMTP.Host != "" && conf.Platform != "dev" {
+		mailer, err = mail.NewSMTPSender(conf.SMTP)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	apiCfg := apiConfig{
-		fileserverHits: atomic.Int32{},
-		dbQueries:      dbQueries,
-		platform:       platform,
-		jwtSecret:      jwtSecret,
-		polkaKey:       polkaKey,
+	apiCfg := newAPIConfig(conf, dbQueries, mailer)
+	apiCfg.queryMetrics = queryMetrics
+
+	// -seed fills the dev database and exits without serving
+	if conf.Seed.Enabled {
+		summary, err := apiCfg.seed(context.Background(), conf.Seed)
+		if err != nil {
+			log.Fatal("Error seeding the database: ", err)
+		}
+		fmt.Println("Seeded the database:", summary)
+		return
 	}
 
-	mux := http.NewServeMux()
-	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot)))))
-	mux.HandleFunc("/api/healthz", handlerReadiness)
-	mux.HandleFunc("/admin/metrics", apiCfg.handlerMetrics)
-	mux.HandleFunc("/admin/reset", apiCfg.handlerReset)
-	mux.HandleFunc("/api/chirps/", apiCfg.handlerChirps)
-	mux.HandleFunc("/api/chirps", apiCfg.handlerChirps)
-	mux.HandleFunc("POST /api/users", apiCfg.handlerCreateUser)
-	mux.HandleFunc("PUT /api/users", apiCfg.handlerUpdateUser)
-	mux.HandleFunc("/api/login", apiCfg.handlerLogin)
-	mux.HandleFunc("/api/refresh", apiCfg.handlerRefresh)
-	mux.HandleFunc("/api/revoke", apiCfg.handlerRevoke)
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerPolkaWebhook)
+	// Stored webhook deliveries are applied in the background
+	apiCfg.webhooks = newWebhookWorker(dbQueries, apiCfg.processWebhookEvent)
+	apiCfg.webhooks.start()
+
+	// Requests are refused quickly while the database is unreachable
+	apiCfg.dbHealth = newDBHealth(db)
+	apiCfg.dbHealth.start()
+
+	// Chirp views are counted in memory and written in batches
+	apiCfg.chirpViews = newChirpViewCounter(dbQueries, conf.ViewFlushInterval)
+	apiCfg.chirpViews.start()
+
+	// Expired rows such as idempotency keys are deleted periodically
+	cleanup := newCleanupWorker(dbQueries)
+	cleanup.start()
 
 	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: mux,
+		Addr:      ":" + conf.Port,
+		Handler:   newServer(apiCfg, conf),
+		TLSConfig: tlsConfig,
 	}
+	timeouts.apply(srv)
 
-	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
-	log.Fatal(srv.ListenAndServe())
-}
\ No newline at end of file
+	// Optional plain-HTTP listener that only points clients at HTTPS
+	var redirectSrv *http.Server
+	if conf.HTTPRedirectPort != "" {
+		redirectSrv = &http.Server{
+			Addr:    ":" + conf.HTTPRedirectPort,
+			Handler: redirectToHTTPS(conf.Port),
+		}
+		timeouts.apply(redirectSrv)
+		go func() {
+			log.Printf("Redirecting HTTP on port %s to HTTPS\n", conf.HTTPRedirectPort)
+			if err := redirectSrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	// Live chirp streams are hijacked or long-lived, so Shutdown will not
+	// close them on its own
+	srv.RegisterOnShutdown(apiCfg.chirpHub.close)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down server: %v", err)
+		}
+		apiCfg.webhooks.stop()
+		apiCfg.chirpViews.stop()
+		cleanup.stop()
+		apiCfg.dbHealth.stop()
+		if redirectSrv != nil {
+			if err := redirectSrv.Shutdown(shutdownCtx); err != nil {
+				log.Printf("Error shutting down redirect server: %v", err)
+			}
+		}
+	}()
+
+	log.Printf("Serving files from %s on port: %s\n", conf.FilepathRoot, conf.Port)
+	if tlsConfig != nil {
+		// The certificate is already in TLSConfig
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	<-shutdownDone
+}
+
+// newAPIConfig builds the handlers' configuration from conf. The background
+// workers are left for the caller to start.
+func newAPIConfig(conf config.Config, dbQueries store, mailer mail.Sender) *apiConfig {
+	chirpRateLimit := chirpRateLimit(conf.ChirpRateLimit)
+	return &apiConfig{
+		dbQueries:            dbQueries,
+		platform:             conf.Platform,
+		jwtSecrets:           conf.JWTSecrets,
+		polkaKey:             conf.PolkaKey,
+		notifier:             storeNotifier{db: dbQueries},
+		chirpHub:             newChirpHub(),
+		chirpCache:           newChirpListCache(conf.ChirpCacheTTL),
+		mailer:               mailer,
+		mediaDir:             conf.MediaDir,
+		chirpMediaLocalOnly:  conf.ChirpMediaLocalOnly,
+		chirpLimiter:         newMemoryRateLimiter(chirpRateLimit.Window),
+		chirpRateLimit:       chirpRateLimit,
+		signupLimiter:        newMemoryRateLimiter(conf.SignupRateLimit.Window),
+		signupRateLimit:      conf.SignupRateLimit.Limit,
+		signupHoneypot:       conf.SignupHoneypot,
+		usernameLimiter:      newMemoryRateLimiter(conf.UsernameCheckRateLimit.Window),
+		usernameRateLimit:    conf.UsernameCheckRateLimit.Limit,
+		dailyChirpQuota:      conf.DailyChirpQuota,
+		duplicateChirpWindow: conf.DuplicateChirpWindow,
+		countListViews:       conf.CountListViews,
+		purgeBatchSize:       conf.PurgeBatchSize,
+		passwordHashing:      conf.PasswordHashing,
+		signupPrivacy:        conf.SignupPrivacy,
+		hideEmails:           conf.HideEmails,
+		directoryNeedsAuth:   conf.UserDirectoryRequiresAuth,
+		trustProxy:           conf.TrustProxy,
+		publicURL:            conf.PublicURL,
+		refreshTokenTTL:      refreshTokenTTL(conf.RefreshTokenTTL),
+		accessTokenTTL:       conf.AccessTokenTTL,
+		googleOAuth:          newGoogleOAuth(conf.Google),
+		startedAt:            time.Now(),
+	}
+}
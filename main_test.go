@@ -34,15 +34,15 @@ func TestHandlerReadiness(t *testing.T) {
 }
 
 func TestHandlerMetrics(t *testing.T) {
-	cfg := &apiConfig{
-		fileserverHits: atomic.Int32{},
-	}
+	cfg, db := newTestConfig(t)
+	admin := db.addAdmin(t, "admin@example.com")
 	cfg.fileserverHits.Store(5)
 
 	req, err := http.NewRequest("GET", "/admin/metrics", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
+	req.Header.Set("Authorization", "Bearer "+makeTestToken(t, admin.ID))
 
 	rr := httptest.NewRecorder()
 	handler := http.HandlerFunc(cfg.handlerMetrics)
@@ -69,7 +69,7 @@ func TestHandlerMetricsWrongMethod(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(cfg.handlerMetrics)
+	handler := newTestMux(t, cfg, false)
 
 	handler.ServeHTTP(rr, req)
 
@@ -127,7 +127,7 @@ func TestCleanProfanity(t *testing.T) {
 func TestUserStructJSON(t *testing.T) {
 	userID := uuid.New()
 	now := time.Now()
-	
+
 	user := User{
 		ID:        userID,
 		CreatedAt: now,
@@ -159,7 +159,7 @@ func TestChirpStructJSON(t *testing.T) {
 	chirpID := uuid.New()
 	userID := uuid.New()
 	now := time.Now()
-	
+
 	chirp := Chirp{
 		ID:        chirpID,
 		CreatedAt: now,
@@ -211,4 +211,4 @@ func TestErrorResponseJSON(t *testing.T) {
 	if unmarshaled.Error != errResp.Error {
 		t.Errorf("Error mismatch: got %v want %v", unmarshaled.Error, errResp.Error)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/AlexTLDR/chirpy/internal/i18n"
+)
+
+// Error codes shared by many endpoints. The rest are declared next to the
+// code that uses them.
+const (
+	errCodeInternal           = "internal_error"
+	errCodeUnauthorized       = "unauthorized"
+	errCodeRateLimited        = "rate_limited"
+	errCodeDailyQuotaExceeded = "daily_quota_exceeded"
+)
+
+// errorMessages translates the messages of errors with a code.
+var errorMessages = i18n.Embedded()
+
+// localizeError returns msg, the English message of an error with
+// errorCode, in the language the request's Accept-Language header asks for
+// if the catalog has it, formatting the translation with args. The response
+// is marked as varying by language, and Content-Language is set when the
+// message was translated. Errors without a code are always in English.
+func localizeError(w http.ResponseWriter, r *http.Request, errorCode, msg string, args ...any) string {
+	if errorCode == "" {
+		return msg
+	}
+	w.Header().Add("Vary", "Accept-Language")
+	lang := errorMessages.Match(r.Header.Get("Accept-Language"))
+	translated, ok := errorMessages.Message(lang, errorCode, args...)
+	if !ok {
+		return msg
+	}
+	w.Header().Set("Content-Language", lang)
+	return translated
+}
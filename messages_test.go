@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/auth"
+	"github.com/AlexTLDR/chirpy/internal/i18n"
+)
+
+func TestLocalizedErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.dailyChirpQuota = 1
+	user := db.addUser(t, "user@example.com")
+	db.addChirp(t, user.ID, "Earlier")
+	token := makeTestToken(t, user.ID)
+	readOnly, err := auth.MakeJWT(user.ID, testJWTSecret, time.Hour, auth.ScopeChirpsRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := newTestMux(t, cfg, false)
+
+	tests := []struct {
+		name, token, acceptLanguage string
+		wantCode, wantError         string
+		wantLanguage                string
+	}{
+		{"no header", "", "", errCodeUnauthorized, "Unauthorized", ""},
+		{"German", "", "de-DE,de;q=0.9,en;q=0.8", errCodeUnauthorized, "Nicht autorisiert", "de"},
+		{"English preferred", "", "en-US, de;q=0.5", errCodeUnauthorized, "Unauthorized", ""},
+		{"unsupported", "", "ja, ko;q=0.8", errCodeUnauthorized, "Unauthorized", ""},
+		{"wildcard", "", "*", errCodeUnauthorized, "Unauthorized", ""},
+		{"German over the wildcard", "", "*;q=0.1, de", errCodeUnauthorized, "Nicht autorisiert", "de"},
+		{"translation with arguments", token, "de", errCodeDailyQuotaExceeded, "Pro Tag können höchstens 1 Chirps gepostet werden", "de"},
+		{"scope", readOnly, "de", errCodeInsufficientScope, "Dem Zugriffstoken fehlt der Bereich chirps:write", "de"},
+		{"scope in English", readOnly, "fr", errCodeInsufficientScope, "Access token lacks the chirps:write scope", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/chirps", strings.NewReader(`{"body": "Hello"}`))
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		if tt.acceptLanguage != "" {
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		var errResp ErrorResponse
+		json.Unmarshal(rr.Body.Bytes(), &errResp)
+		if errResp.Code != tt.wantCode || errResp.Error != tt.wantError {
+			t.Errorf("%s: got %+v, want code %q and error %q", tt.name, errResp, tt.wantCode, tt.wantError)
+		}
+		if got := rr.Header().Get("Content-Language"); got != tt.wantLanguage {
+			t.Errorf("%s: Content-Language = %q, want %q", tt.name, got, tt.wantLanguage)
+		}
+		if !slices.Contains(rr.Header().Values("Vary"), "Accept-Language") {
+			t.Errorf("%s: Vary = %q, want Accept-Language", tt.name, rr.Header().Values("Vary"))
+		}
+	}
+
+	// Errors without a code stay in English
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/chirps/not-a-uuid", nil)
+	req.Header.Set("Accept-Language", "de")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	var errResp ErrorResponse
+	json.Unmarshal(rr.Body.Bytes(), &errResp)
+	if errResp.Code != "" || errResp.Error != "Invalid chirp ID" || rr.Header().Get("Content-Language") != "" {
+		t.Errorf("error without a code: got %+v and Content-Language %q", errResp, rr.Header().Get("Content-Language"))
+	}
+}
+
+func TestErrorCatalogCodes(t *testing.T) {
+	// Each language translates every error code
+	known := []string{
+		errCodeInternal, errCodeUnauthorized, errCodeRateLimited, errCodeDailyQuotaExceeded,
+		errCodeExpectedAccessToken, errCodeExpectedRefreshToken, errCodeInsufficientScope,
+		errCodeInvalidCurrentPassword, errCodeDatabaseUnavailable, errCodePrivateAccount,
+		errCodeAccountSuspended, errCodeInvalidEmailToken, errCodeInvalidLoginLink, errCodeDatabaseBusy,
+		errCodeTOTPRequired, errCodeInvalidTOTP,
+	}
+	for _, lang := range errorMessages.Languages() {
+		if lang == i18n.English {
+			continue
+		}
+		for _, code := range known {
+			if _, ok := errorMessages.Message(lang, code); !ok {
+				t.Errorf("%s has no translation of %s", lang, code)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// maxTrackedPaths caps how many distinct paths pathHitCounter keeps, so
+// requests for junk URLs cannot grow it without bound.
+const maxTrackedPaths = 500
+
+// otherPaths is the row that hits on untracked paths are counted under.
+const otherPaths = "(other)"
+
+// pathHitCounter counts file server hits per request path. The zero value
+// is ready to use. Once maxTrackedPaths paths are tracked, hits on new
+// paths go to otherPaths; paths that are already tracked keep counting.
+type pathHitCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+	other  int64
+}
+
+func (c *pathHitCounter) add(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+	if _, ok := c.counts[path]; !ok && len(c.counts) >= maxTrackedPaths {
+		c.other++
+		return
+	}
+	c.counts[path]++
+}
+
+// snapshot returns the counts, most hit first, with otherPaths last.
+func (c *pathHitCounter) snapshot() []PathHits {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rows := make([]PathHits, 0, len(c.counts)+1)
+	for path, hits := range c.counts {
+		rows = append(rows, PathHits{Path: path, Hits: hits})
+	}
+	slices.SortFunc(rows, func(a, b PathHits) int {
+		return cmp.Or(cmp.Compare(b.Hits, a.Hits), cmp.Compare(a.Path, b.Path))
+	})
+	if c.other > 0 {
+		rows = append(rows, PathHits{Path: otherPaths, Hits: c.other})
+	}
+	return rows
+}
+
+func (c *pathHitCounter) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts = nil
+	c.other = 0
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestPathHitsParallel(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	handler := cfg.middlewareMetricsInc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	const workers, perWorker = 8, 250
+	var wg sync.WaitGroup
+	for i := range workers {
+		path := []string{"/app/index.html", "/app/logo.png"}[i%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWorker {
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+			}
+		}()
+	}
+	wg.Wait()
+
+	want := []PathHits{
+		{Path: "/app/index.html", Hits: workers / 2 * perWorker},
+		{Path: "/app/logo.png", Hits: workers / 2 * perWorker},
+	}
+	if got := cfg.pathHits.snapshot(); !slices.Equal(got, want) {
+		t.Errorf("path hits = %v, want %v", got, want)
+	}
+	if got := cfg.fileserverHits.Load(); got != workers*perWorker {
+		t.Errorf("total hits = %d, want %d", got, workers*perWorker)
+	}
+}
+
+func TestPathHitsBounded(t *testing.T) {
+	var c pathHitCounter
+	c.add("/app/popular")
+	c.add("/app/popular")
+	for i := range maxTrackedPaths + 10 {
+		c.add(fmt.Sprintf("/app/junk/%d", i))
+	}
+	// Tracked paths keep counting once the table is full
+	c.add("/app/popular")
+
+	rows := c.snapshot()
+	if len(rows) != maxTrackedPaths+1 {
+		t.Fatalf("got %d rows, want %d tracked paths plus %q", len(rows), maxTrackedPaths, otherPaths)
+	}
+	if rows[0] != (PathHits{Path: "/app/popular", Hits: 3}) {
+		t.Errorf("first row = %v, want the most hit path", rows[0])
+	}
+	if last := rows[len(rows)-1]; last != (PathHits{Path: otherPaths, Hits: 11}) {
+		t.Errorf("last row = %v, want the untracked hits", last)
+	}
+
+	c.reset()
+	if rows := c.snapshot(); len(rows) != 0 {
+		t.Errorf("after reset got %v", rows)
+	}
+}
+
+func TestHandlerMetricsPathTable(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	admin := db.addAdmin(t, "admin@example.com")
+	token := makeTestToken(t, admin.ID)
+	for _, path := range []string{"/app/", "/app/", "/app/<script>"} {
+		cfg.pathHits.add(path)
+	}
+
+	get := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/metrics", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		cfg.handlerMetrics(rr, req)
+		return rr
+	}
+
+	rr := get("application/json")
+	var metrics Metrics
+	if err := json.NewDecoder(rr.Body).Decode(&metrics); err != nil {
+		t.Fatalf("Failed to decode metrics: %v", err)
+	}
+	want := []PathHits{{Path: "/app/", Hits: 2}, {Path: "/app/<script>", Hits: 1}}
+	if !slices.Equal(metrics.Paths, want) {
+		t.Errorf("paths = %v, want %v", metrics.Paths, want)
+	}
+
+	body := get("text/html").Body.String()
+	if !strings.Contains(body, "<td>/app/</td><td>2</td>") || strings.Contains(body, "<script>") {
+		t.Errorf("HTML table is missing or unescaped:\n%s", body)
+	}
+
+	// Reset clears the table
+	req := httptest.NewRequest(http.MethodPost, "/admin/reset", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	cfg.handlerReset(httptest.NewRecorder(), req)
+	if rows := cfg.pathHits.snapshot(); len(rows) != 0 {
+		t.Errorf("after reset got %v", rows)
+	}
+}
+
+// failingCounts is a fakeStore whose aggregate queries fail.
+type failingCounts struct {
+	*fakeStore
+}
+
+func (failingCounts) CountUsers(ctx context.Context) (int64, error) {
+	return 0, errConnectionReset
+}
+
+func (failingCounts) CountChirps(ctx context.Context) (int64, error) {
+	return 0, errConnectionReset
+}
+
+func (failingCounts) CountActiveRefreshTokens(ctx context.Context) (int64, error) {
+	return 0, errConnectionReset
+}
+
+func TestHandlerMetricsTotals(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	admin := db.addAdmin(t, "admin@example.com")
+	user := db.addUser(t, "user@example.com")
+	db.addChirp(t, user.ID, "Kept")
+	deleted := db.addChirp(t, user.ID, "Deleted")
+	db.DeleteChirp(t.Context(), deleted.ID)
+	for i, expiresAt := range []time.Time{time.Now().Add(time.Hour), time.Now().Add(time.Hour), time.Now().Add(-time.Hour)} {
+		_, err := db.CreateRefreshToken(t.Context(), database.CreateRefreshTokenParams{
+			Token:     fmt.Sprint("token", i),
+			UserID:    user.ID,
+			ExpiresAt: expiresAt,
+			FamilyID:  uuid.New(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.RevokeRefreshToken(t.Context(), "token1")
+	token := makeTestToken(t, admin.ID)
+
+	get := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/metrics", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		cfg.handlerMetrics(rr, req)
+		return rr
+	}
+
+	var metrics Metrics
+	if err := json.NewDecoder(get("application/json").Body).Decode(&metrics); err != nil {
+		t.Fatalf("Failed to decode metrics: %v", err)
+	}
+	total := func(n *int64) string {
+		if n == nil {
+			return "null"
+		}
+		return fmt.Sprint(*n)
+	}
+	// Only the unexpired, unrevoked token is active
+	if got := total(metrics.Users) + " " + total(metrics.Chirps) + " " + total(metrics.ActiveRefreshTokens); got != "2 1 1" {
+		t.Errorf("users, chirps and active refresh tokens = %s, want 2 1 1", got)
+	}
+	if metrics.GoVersion != runtime.Version() {
+		t.Errorf("go_version = %q, want %q", metrics.GoVersion, runtime.Version())
+	}
+
+	body := get("text/html").Body.String()
+	for _, want := range []string{"<tr><th>Users</th><td>2</td></tr>", "<tr><th>Chirps</th><td>1</td></tr>", "<tr><th>Active refresh tokens</th><td>1</td></tr>", runtime.Version()} {
+		if !strings.Contains(body, want) {
+			t.Errorf("HTML page is missing %q:\n%s", want, body)
+		}
+	}
+
+	// A database that cannot count still gets a page, without the totals
+	cfg.dbQueries = failingCounts{db}
+	rr := get("text/html")
+	if rr.Code != http.StatusOK || strings.Count(rr.Body.String(), "<td>n/a</td>") != 3 {
+		t.Errorf("with failing counts: got %v and\n%s\nwant 200 with three n/a totals", rr.Code, rr.Body.String())
+	}
+	metrics = Metrics{}
+	json.NewDecoder(get("application/json").Body).Decode(&metrics)
+	if metrics.Users != nil || metrics.Chirps != nil || metrics.ActiveRefreshTokens != nil {
+		t.Errorf("with failing counts: got totals %s %s %s, want null", total(metrics.Users), total(metrics.Chirps), total(metrics.ActiveRefreshTokens))
+	}
+}
+
+func TestMetricsTemplate(t *testing.T) {
+	users := int64(42)
+	metrics := Metrics{
+		UptimeSeconds:  3723,
+		GoVersion:      "go1.99",
+		Users:          &users,
+		FileserverHits: 7,
+		Paths:          []PathHits{{Path: `/app/"><img src=x>`, Hits: 3}},
+		Queries:        []QueryMetrics{{Query: "GetChirps", Calls: 4, Errors: 1, TotalSeconds: 0.002}},
+	}
+
+	var page strings.Builder
+	if err := metricsTemplate.Execute(&page, metrics); err != nil {
+		t.Fatal(err)
+	}
+	body := page.String()
+	for _, want := range []string{
+		"visited 7 times",
+		"<td>1h2m3s</td>",
+		"<td>go1.99</td>",
+		"<tr><th>Users</th><td>42</td></tr>",
+		"<tr><th>Chirps</th><td>n/a</td></tr>",
+		"<td>/app/&#34;&gt;&lt;img src=x&gt;</td><td>3</td>",
+		"<tr><td>GetChirps</td><td>4</td><td>1</td><td>500µs</td></tr>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page is missing %q:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	notificationLike          = "like"
+	notificationReply         = "reply"
+	notificationMention       = "mention"
+	notificationFollow        = "follow"
+	notificationFollowRequest = "follow_request"
+)
+
+// notificationEvent describes something that happened to UserID because of
+// ActorID. ChirpID is the chirp the event is about, if any.
+type notificationEvent struct {
+	Type    string
+	UserID  uuid.UUID
+	ActorID uuid.UUID
+	ChirpID uuid.NullUUID
+}
+
+// notifier delivers notification events. Handlers only depend on this
+// interface so delivery can move off the request path without touching them.
+type notifier interface {
+	Notify(ctx context.Context, event notificationEvent) error
+}
+
+// storeNotifier writes notifications to the database synchronously.
+type storeNotifier struct {
+	db store
+}
+
+func (n storeNotifier) Notify(ctx context.Context, event notificationEvent) error {
+	return n.db.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID:  event.UserID,
+		ActorID: event.ActorID,
+		Type:    event.Type,
+		ChirpID: event.ChirpID,
+	})
+}
+
+// notify sends event unless the actor is notifying themselves. The action
+// that triggered it has already succeeded, so failures are only logged.
+func (cfg *apiConfig) notify(ctx context.Context, event notificationEvent) {
+	if cfg.notifier == nil || event.UserID == event.ActorID {
+		return
+	}
+
+	err := cfg.notifier.Notify(ctx, event)
+	if err != nil {
+		logf(ctx, "Error sending %s notification to user %s: %v", event.Type, event.UserID, err)
+	}
+}
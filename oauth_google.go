@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/config"
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	googleAuthURL  = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+
+	authProviderGoogle = "google"
+
+	// oauthStateCookie holds the state parameter of a login in progress, so
+	// the callback can check it was started by the same browser
+	oauthStateCookie = "oauth_state"
+	oauthStateTTL    = 10 * time.Minute
+)
+
+// googleIssuers are the iss values Google puts in its ID tokens.
+var googleIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
+
+// errInvalidIDToken is returned for an ID token that does not establish a
+// verified Google identity for this client.
+var errInvalidIDToken = errors.New("invalid ID token")
+
+// googleOAuth is the client configuration for signing in with Google.
+type googleOAuth struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// AuthURL and TokenURL are Google's endpoints; tests point them at a
+	// fake
+	AuthURL  string
+	TokenURL string
+	Client   *http.Client
+}
+
+// googleClaims are the ID token claims the callback relies on.
+type googleClaims struct {
+	jwt.RegisteredClaims
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// newGoogleOAuth returns the client for signing in with Google, or nil when
+// it is not configured.
+func newGoogleOAuth(conf config.Google) *googleOAuth {
+	if !conf.Enabled() {
+		return nil
+	}
+	return &googleOAuth{
+		ClientID:     conf.ClientID,
+		ClientSecret: conf.ClientSecret,
+		RedirectURL:  conf.RedirectURL,
+		AuthURL:      googleAuthURL,
+		TokenURL:     googleTokenURL,
+		Client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// consentURL is where the browser is sent to sign in to Google.
+func (g *googleOAuth) consentURL(state string) string {
+	q := url.Values{
+		"client_id":     {g.ClientID},
+		"redirect_uri":  {g.RedirectURL},
+		"response_type": {"code"},
+		"scope":         {"openid email"},
+		"state":         {state},
+	}
+	return g.AuthURL + "?" + q.Encode()
+}
+
+// exchange trades an authorization code for the signed-in user's claims.
+//
+// The ID token's signature is not checked: it comes straight from Google's
+// token endpoint over TLS, which OpenID Connect Core 3.1.3.7 allows in place
+// of the signature. Its issuer, audience, expiry and email are checked.
+func (g *googleOAuth) exchange(ctx context.Context, code string) (googleClaims, error) {
+	form := url.Values{
+		"code":          {code},
+		"client_id":     {g.ClientID},
+		"client_secret": {g.ClientSecret},
+		"redirect_uri":  {g.RedirectURL},
+		"grant_type":    {"authorization_code"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return googleClaims{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return googleClaims{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return googleClaims{}, fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&tokens)
+	if err != nil {
+		return googleClaims{}, fmt.Errorf("decoding token response: %w", err)
+	}
+
+	var claims googleClaims
+	_, _, err = jwt.NewParser().ParseUnverified(tokens.IDToken, &claims)
+	if err != nil {
+		return googleClaims{}, fmt.Errorf("%w: %v", errInvalidIDToken, err)
+	}
+	switch {
+	case !slices.Contains(googleIssuers, claims.Issuer):
+		return googleClaims{}, fmt.Errorf("%w: issuer %q", errInvalidIDToken, claims.Issuer)
+	case !slices.Contains(claims.Audience, g.ClientID):
+		return googleClaims{}, fmt.Errorf("%w: audience %v", errInvalidIDToken, claims.Audience)
+	case claims.ExpiresAt == nil || !claims.ExpiresAt.After(time.Now()):
+		return googleClaims{}, fmt.Errorf("%w: expired", errInvalidIDToken)
+	case claims.Email == "" || !claims.EmailVerified:
+		return googleClaims{}, fmt.Errorf("%w: no verified email", errInvalidIDToken)
+	}
+	return claims, nil
+}
+
+// handlerGoogleLogin starts a Google sign-in by redirecting to Google's
+// consent screen. The state sent along is also set in a cookie, and the
+// callback only accepts a response carrying the same state.
+func (cfg *apiConfig) handlerGoogleLogin(w http.ResponseWriter, r *http.Request) {
+	if cfg.googleOAuth == nil {
+		respondWithError(w, r, http.StatusNotFound, "Google login is not configured")
+		return
+	}
+
+	b := make([]byte, 32)
+	rand.Read(b)
+	state := base64.RawURLEncoding.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int(oauthStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, cfg.googleOAuth.consentURL(state), http.StatusFound)
+}
+
+// handlerGoogleCallback completes a Google sign-in. The user with the
+// verified Google email is logged in, or created without a password if
+// there is none, and gets the same tokens as from POST /api/login.
+func (cfg *apiConfig) handlerGoogleCallback(w http.ResponseWriter, r *http.Request) {
+	if cfg.googleOAuth == nil {
+		respondWithError(w, r, http.StatusNotFound, "Google login is not configured")
+		return
+	}
+
+	// The state is single use whatever the outcome
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/", MaxAge: -1, HttpOnly: true, Secure: true})
+
+	query := r.URL.Query()
+	cookie, err := r.Cookie(oauthStateCookie)
+	state := query.Get("state")
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		respondWithError(w, r, http.StatusForbidden, "Invalid OAuth state")
+		return
+	}
+	if query.Get("error") != "" {
+		respondWithError(w, r, http.StatusUnauthorized, "Google login was cancelled or failed")
+		return
+	}
+	code := query.Get("code")
+	if code == "" {
+		respondWithError(w, r, http.StatusBadRequest, "Missing authorization code")
+		return
+	}
+
+	claims, err := cfg.googleOAuth.exchange(r.Context(), code)
+	if errors.Is(err, errInvalidIDToken) {
+		logf(r.Context(), "Rejected Google ID token: %v", err)
+		respondWithError(w, r, http.StatusUnauthorized, "Could not verify the Google account")
+		return
+	}
+	if err != nil {
+		logf(r.Context(), "Error exchanging Google authorization code: %v", err)
+		respondWithError(w, r, http.StatusBadGateway, "Could not reach Google")
+		return
+	}
+
+	dbUser, err := cfg.dbQueries.GetUserByEmail(withPrimary(r.Context()), claims.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		dbUser, err = cfg.createGoogleUser(r.Context(), claims.Email)
+		if err == nil {
+			cfg.sendWelcomeEmail(dbUser.Email)
+		}
+	}
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return
+	}
+
+	// Signups do not verify email addresses, so a password account with
+	// this email may not belong to the owner of the Google account
+	if dbUser.AuthProvider != authProviderGoogle {
+		respondWithError(w, r, http.StatusConflict, "This email is registered with a password; log in with it instead")
+		return
+	}
+
+	cfg.respondWithLogin(w, r, dbUser, false, nil)
+}
+
+// createGoogleUser creates a password-less user for email, with a username
+// derived from it as at signup.
+func (cfg *apiConfig) createGoogleUser(ctx context.Context, email string) (database.User, error) {
+	base := defaultUsername(email)
+	var dbUser database.User
+	var err error
+	for attempt := 0; attempt < usernameAttempts; attempt++ {
+		dbUser, err = cfg.dbQueries.CreateOAuthUser(ctx, database.CreateOAuthUserParams{
+			Email:        email,
+			Username:     usernameCandidate(base, attempt),
+			AuthProvider: authProviderGoogle,
+		})
+		if !isUniqueViolation(err, usernameConstraint) {
+			break
+		}
+	}
+	return dbUser, err
+}
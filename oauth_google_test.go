@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testGoogleClientID = "chirpy-test.apps.googleusercontent.com"
+
+// fakeGoogle is a token endpoint that answers every valid code exchange
+// with an ID token carrying claims.
+type fakeGoogle struct {
+	claims googleClaims
+	calls  atomic.Int32
+}
+
+func googleIDClaims(email string) googleClaims {
+	return googleClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "https://accounts.google.com",
+			Subject:   "1234567890",
+			Audience:  jwt.ClaimStrings{testGoogleClientID},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Email:         email,
+		EmailVerified: true,
+	}
+}
+
+// withFakeGoogle configures cfg for Google login against a fake token
+// endpoint.
+func withFakeGoogle(t *testing.T, cfg *apiConfig, claims googleClaims) *fakeGoogle {
+	t.Helper()
+	fake := &fakeGoogle{claims: claims}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.calls.Add(1)
+		r.ParseForm()
+		if r.Form.Get("code") != "good-code" || r.Form.Get("client_secret") != "test-secret" ||
+			r.Form.Get("grant_type") != "authorization_code" {
+			http.Error(w, `{"error": "invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		// The signature is not checked, so any key will do
+		idToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, fake.claims).SignedString([]byte("google"))
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "id_token": idToken})
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg.googleOAuth = &googleOAuth{
+		ClientID:     testGoogleClientID,
+		ClientSecret: "test-secret",
+		RedirectURL:  "http://localhost:8080/api/oauth/google/callback",
+		AuthURL:      googleAuthURL,
+		TokenURL:     srv.URL,
+		Client:       srv.Client(),
+	}
+	return fake
+}
+
+// startGoogleLogin begins a login and returns the state cookie it set.
+func startGoogleLogin(t *testing.T, cfg *apiConfig) *http.Cookie {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	cfg.handlerGoogleLogin(rr, httptest.NewRequest(http.MethodGet, "/api/oauth/google/login", nil))
+	if rr.Code != http.StatusFound {
+		t.Fatalf("login returned wrong status code: got %v want %v", rr.Code, http.StatusFound)
+	}
+	location, _ := url.Parse(rr.Header().Get("Location"))
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oauthStateCookie || !cookies[0].HttpOnly {
+		t.Fatalf("cookies = %v, want an HttpOnly state cookie", cookies)
+	}
+	if got := location.Query().Get("state"); got != cookies[0].Value {
+		t.Fatalf("redirect state %q does not match the cookie %q", got, cookies[0].Value)
+	}
+	if got := location.Query().Get("client_id"); got != testGoogleClientID {
+		t.Errorf("redirect client_id = %q, want %q", got, testGoogleClientID)
+	}
+	return cookies[0]
+}
+
+func googleCallback(t *testing.T, cfg *apiConfig, cookie *http.Cookie, state, code string) *httptest.ResponseRecorder {
+	t.Helper()
+	q := url.Values{"state": {state}, "code": {code}}
+	req := httptest.NewRequest(http.MethodGet, "/api/oauth/google/callback?"+q.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rr := httptest.NewRecorder()
+	cfg.handlerGoogleCallback(rr, req)
+	return rr
+}
+
+func TestGoogleLogin(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	withFakeGoogle(t, cfg, googleIDClaims("alice@gmail.com"))
+
+	cookie := startGoogleLogin(t, cfg)
+	rr := googleCallback(t, cfg, cookie, cookie.Value, "good-code")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("callback returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	var resp struct {
+		User
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Email != "alice@gmail.com" || resp.Username != "alice" || resp.Token == "" || resp.RefreshToken == "" {
+		t.Fatalf("callback response = %+v, want alice with both tokens", resp)
+	}
+	if _, err := db.GetRefreshToken(t.Context(), resp.RefreshToken); err != nil {
+		t.Errorf("refresh token was not stored: %v", err)
+	}
+
+	user, _ := db.GetUserByEmail(t.Context(), "alice@gmail.com")
+	if user.HashedPassword.Valid || user.AuthProvider != authProviderGoogle {
+		t.Errorf("created user has password %v and provider %q, want none and google", user.HashedPassword.Valid, user.AuthProvider)
+	}
+
+	// Signing in again finds the same user
+	cookie = startGoogleLogin(t, cfg)
+	rr = googleCallback(t, cfg, cookie, cookie.Value, "good-code")
+	if again := decodeUser(t, rr); rr.Code != http.StatusOK || again.ID != user.ID {
+		t.Errorf("second login = %v for %v, want 200 for %v", rr.Code, again.ID, user.ID)
+	}
+
+	// The account has no password to log in with
+	for _, password := range []string{"", "unset", "not the password of any account"} {
+		body, _ := json.Marshal(map[string]string{"email": "alice@gmail.com", "password": password})
+		rr := httptest.NewRecorder()
+		cfg.handlerLogin(rr, httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body)))
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("password login with %q: got status %v want %v", password, rr.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestGoogleLoginState(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	fake := withFakeGoogle(t, cfg, googleIDClaims("alice@gmail.com"))
+	cookie := startGoogleLogin(t, cfg)
+
+	for _, tt := range []struct {
+		name   string
+		cookie *http.Cookie
+		state  string
+	}{
+		{"no cookie", nil, cookie.Value},
+		{"no state", cookie, ""},
+		{"other state", cookie, "forged"},
+	} {
+		if rr := googleCallback(t, cfg, tt.cookie, tt.state, "good-code"); rr.Code != http.StatusForbidden {
+			t.Errorf("%s: got status %v want %v", tt.name, rr.Code, http.StatusForbidden)
+		}
+	}
+	if fake.calls.Load() != 0 {
+		t.Error("code was exchanged despite a bad state")
+	}
+
+	// The callback clears the state cookie
+	rr := googleCallback(t, cfg, cookie, cookie.Value, "good-code")
+	if cleared := rr.Result().Cookies(); len(cleared) != 1 || cleared[0].Name != oauthStateCookie || cleared[0].MaxAge >= 0 {
+		t.Errorf("callback cookies = %v, want the state cookie cleared", cleared)
+	}
+}
+
+func TestGoogleLoginRejected(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	db.addUser(t, "bob@gmail.com")
+
+	unverified := googleIDClaims("carol@gmail.com")
+	unverified.EmailVerified = false
+	otherClient := googleIDClaims("carol@gmail.com")
+	otherClient.Audience = jwt.ClaimStrings{"someone-else.apps.googleusercontent.com"}
+	expired := googleIDClaims("carol@gmail.com")
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	otherIssuer := googleIDClaims("carol@gmail.com")
+	otherIssuer.Issuer = "https://evil.example.com"
+
+	tests := []struct {
+		name   string
+		claims googleClaims
+		code   string
+		want   int
+	}{
+		{"unverified email", unverified, "good-code", http.StatusUnauthorized},
+		{"other audience", otherClient, "good-code", http.StatusUnauthorized},
+		{"expired", expired, "good-code", http.StatusUnauthorized},
+		{"other issuer", otherIssuer, "good-code", http.StatusUnauthorized},
+		{"bad code", googleIDClaims("carol@gmail.com"), "bad-code", http.StatusBadGateway},
+		{"password account", googleIDClaims("bob@gmail.com"), "good-code", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		withFakeGoogle(t, cfg, tt.claims)
+		cookie := startGoogleLogin(t, cfg)
+		if rr := googleCallback(t, cfg, cookie, cookie.Value, tt.code); rr.Code != tt.want {
+			t.Errorf("%s: got status %v want %v", tt.name, rr.Code, tt.want)
+		}
+	}
+	if _, err := db.GetUserByEmail(t.Context(), "carol@gmail.com"); err == nil {
+		t.Error("a rejected login created a user")
+	}
+}
+
+func TestGoogleLoginNotConfigured(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	rr := httptest.NewRecorder()
+	cfg.handlerGoogleLogin(rr, httptest.NewRequest(http.MethodGet, "/api/oauth/google/login", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got status %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
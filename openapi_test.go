@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	rr := httptest.NewRecorder()
+	handlerOpenAPI(rr, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(rr.Body.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to load spec: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("Spec is not valid OpenAPI: %v", err)
+	}
+
+	cfg, _ := newTestConfig(t)
+	for _, rt := range cfg.routes(".", false) {
+		method, path, _ := strings.Cut(rt.pattern, " ")
+
+		if path == "/app/" {
+			// Static files, not part of the API
+			continue
+		}
+
+		item := doc.Paths.Find(path)
+		if item == nil {
+			t.Errorf("route %q is missing from the spec", rt.pattern)
+			continue
+		}
+		if item.GetOperation(method) == nil {
+			t.Errorf("route %q has no %s operation in the spec", rt.pattern, method)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parsePagination reads the limit and offset query parameters, applying the
+// default page size when limit is absent.
+func parsePagination(r *http.Request) (limit, offset int32, err error) {
+	limit = defaultPageLimit
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		n, err := strconv.ParseInt(limitStr, 10, 32)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return 0, 0, errors.New("Invalid limit")
+		}
+		limit = int32(n)
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		n, err := strconv.ParseInt(offsetStr, 10, 32)
+		if err != nil || n < 0 {
+			return 0, 0, errors.New("Invalid offset")
+		}
+		offset = int32(n)
+	}
+
+	return limit, offset, nil
+}
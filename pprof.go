@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+)
+
+// pprofConfig controls the net/http/pprof endpoints under /debug/pprof/.
+// They are unauthenticated, so they are only registered at all when
+// Enabled.
+//
+// CPU profiles and traces run for ?seconds= before responding, which has
+// to stay under WRITE_TIMEOUT.
+type pprofConfig struct {
+	Enabled bool
+	// MutexFraction and BlockRate are passed to
+	// runtime.SetMutexProfileFraction and runtime.SetBlockProfileRate;
+	// 0 leaves those profiles empty
+	MutexFraction int
+	BlockRate     int
+}
+
+// register adds the profiling endpoints to mux and applies the profile
+// rates. It does nothing unless profiling is enabled.
+func (p pprofConfig) register(mux *http.ServeMux) {
+	if !p.Enabled {
+		return
+	}
+	runtime.SetMutexProfileFraction(p.MutexFraction)
+	runtime.SetBlockProfileRate(p.BlockRate)
+
+	// Index also serves the named profiles, e.g. /debug/pprof/heap
+	mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("POST /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlexTLDR/chirpy/internal/config"
+)
+
+func TestPprofRoutes(t *testing.T) {
+	for _, platform := range []string{"dev", "prod"} {
+		t.Run(platform, func(t *testing.T) {
+			env := map[string]string{"DB_URL": "postgres://localhost/chirpy", "PLATFORM": platform, "JWT_SECRET": "secret", "POLKA_KEY": "key"}
+			conf, err := config.Load(func(name string) string { return env[name] })
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg, _ := newTestConfig(t)
+			mux := newServer(cfg, conf)
+
+			want := http.StatusNotFound
+			if platform == "dev" {
+				want = http.StatusOK
+			}
+			for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/cmdline"} {
+				rr := httptest.NewRecorder()
+				mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+				if rr.Code != want {
+					t.Errorf("GET %s: got status %v want %v", path, rr.Code, want)
+				}
+			}
+		})
+	}
+}
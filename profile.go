@@ -0,0 +1,58 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 200
+)
+
+// validateProfileText checks an optional single-line profile field. The
+// error reads as a reason for fieldErrors.add.
+func validateProfileText(value string, maxLength int) error {
+	if utf8.RuneCountInString(value) > maxLength {
+		return fmt.Errorf("must be at most %d characters", maxLength)
+	}
+	if hasControlChars(value, "") {
+		return errors.New("must not contain control characters")
+	}
+	return nil
+}
+
+// hasControlChars reports whether s contains a C0 or C1 control character,
+// such as NUL or the ESC that starts a terminal escape sequence, other than
+// those listed in allowed. User text is rejected rather than silently
+// stripped of them.
+func hasControlChars(s, allowed string) bool {
+	for _, r := range s {
+		if unicode.IsControl(r) && !strings.ContainsRune(allowed, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// publicEmail is email as shown in a PublicUser: empty, and so left out,
+// when the server hides emails.
+func (cfg *apiConfig) publicEmail(email string) string {
+	if cfg.hideEmails {
+		return ""
+	}
+	return email
+}
+
+// nullableString returns a pointer to s's value, or nil when it is NULL, for
+// JSON fields that are null rather than omitted.
+func nullableString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// queryLatencyBuckets are the upper bounds of the query latency histogram.
+// Slower queries land in a final, unbounded bucket.
+var queryLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// queryMetrics counts calls, errors and latencies per store method. The
+// zero value is ready to use, and a nil *queryMetrics records nothing.
+type queryMetrics struct {
+	mu      sync.RWMutex
+	queries map[string]*queryStats
+}
+
+// queryStats is updated without holding queryMetrics.mu, so the counters
+// are atomic.
+type queryStats struct {
+	calls   atomic.Int64
+	errors  atomic.Int64
+	totalNs atomic.Int64
+	buckets []atomic.Int64
+}
+
+// observe records a call to query that started at start and failed with
+// *err. It is meant to be deferred. sql.ErrNoRows is how lookups report
+// "not found", so it is not counted as an error.
+func (m *queryMetrics) observe(query string, start time.Time, err *error) {
+	if m == nil {
+		return
+	}
+	elapsed := time.Since(start)
+	stats := m.stats(query)
+	stats.calls.Add(1)
+	if *err != nil && !errors.Is(*err, sql.ErrNoRows) {
+		stats.errors.Add(1)
+	}
+	stats.totalNs.Add(int64(elapsed))
+	bucket, _ := slices.BinarySearch(queryLatencyBuckets, elapsed)
+	stats.buckets[bucket].Add(1)
+}
+
+// stats returns the counters for query, creating them on its first call.
+func (m *queryMetrics) stats(query string) *queryStats {
+	m.mu.RLock()
+	stats, ok := m.queries[query]
+	m.mu.RUnlock()
+	if ok {
+		return stats
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if stats, ok := m.queries[query]; ok {
+		return stats
+	}
+	if m.queries == nil {
+		m.queries = make(map[string]*queryStats)
+	}
+	stats = &queryStats{buckets: make([]atomic.Int64, len(queryLatencyBuckets)+1)}
+	m.queries[query] = stats
+	return stats
+}
+
+// snapshot returns the metrics of every query called so far, by name.
+func (m *queryMetrics) snapshot() []QueryMetrics {
+	if m == nil {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := make([]QueryMetrics, 0, len(m.queries))
+	for query, stats := range m.queries {
+		row := QueryMetrics{
+			Query:        query,
+			Calls:        stats.calls.Load(),
+			Errors:       stats.errors.Load(),
+			TotalSeconds: time.Duration(stats.totalNs.Load()).Seconds(),
+			Latency:      make([]LatencyBucket, len(stats.buckets)),
+		}
+		for i := range stats.buckets {
+			row.Latency[i].Count = stats.buckets[i].Load()
+			if i < len(queryLatencyBuckets) {
+				row.Latency[i].LE = queryLatencyBuckets[i].Seconds()
+			}
+		}
+		rows = append(rows, row)
+	}
+	slices.SortFunc(rows, func(a, b QueryMetrics) int {
+		return strings.Compare(a.Query, b.Query)
+	})
+	return rows
+}
+
+func (m *queryMetrics) reset() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queries = nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// brokenAPIKeyStore fails every API key listing, as a lost connection would.
+type brokenAPIKeyStore struct {
+	store
+}
+
+func (brokenAPIKeyStore) GetAPIKeys(ctx context.Context, userID uuid.UUID) ([]database.ApiKey, error) {
+	return nil, errors.New("connection reset by peer")
+}
+
+func TestInstrumentedStore(t *testing.T) {
+	db := newFakeStore()
+	metrics := &queryMetrics{}
+	s := instrumentStore(brokenAPIKeyStore{db}, metrics)
+	ctx := t.Context()
+
+	user := db.addUser(t, "alice@example.com")
+	for range 3 {
+		if _, err := s.GetUserByID(ctx, user.ID); err != nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+	}
+	// Not found is an answer, not a failure
+	if _, err := s.GetUserByID(ctx, uuid.New()); err == nil {
+		t.Fatal("GetUserByID found a user that does not exist")
+	}
+	s.GetAPIKeys(ctx, user.ID)
+	s.GetAPIKeys(ctx, user.ID)
+
+	want := map[string][2]int64{
+		"GetAPIKeys":  {2, 2},
+		"GetUserByID": {4, 0},
+	}
+	got := metrics.snapshot()
+	if len(got) != len(want) {
+		t.Fatalf("got metrics for %d queries, want %d: %+v", len(got), len(want), got)
+	}
+	for _, q := range got {
+		if w := want[q.Query]; q.Calls != w[0] || q.Errors != w[1] {
+			t.Errorf("%s: %d calls and %d errors, want %d and %d", q.Query, q.Calls, q.Errors, w[0], w[1])
+		}
+		var inBuckets int64
+		for _, b := range q.Latency {
+			inBuckets += b.Count
+		}
+		if len(q.Latency) != len(queryLatencyBuckets)+1 || inBuckets != q.Calls {
+			t.Errorf("%s: latency histogram %+v does not hold every call", q.Query, q.Latency)
+		}
+	}
+	if got[0].Query != "GetAPIKeys" {
+		t.Errorf("queries are not sorted by name: %+v", got)
+	}
+
+	metrics.reset()
+	if rows := metrics.snapshot(); len(rows) != 0 {
+		t.Errorf("after reset got %v", rows)
+	}
+}
+
+func TestHandlerMetricsQueries(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.queryMetrics = &queryMetrics{}
+	cfg.dbQueries = instrumentStore(db, cfg.queryMetrics)
+	admin := db.addAdmin(t, "admin@example.com")
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/metrics", nil)
+	req.Header.Set("Authorization", "Bearer "+makeTestToken(t, admin.ID))
+	req.Header.Set("Accept", "application/json")
+	rr := httptest.NewRecorder()
+	cfg.handlerMetrics(rr, req)
+
+	var metrics Metrics
+	if err := json.NewDecoder(rr.Body).Decode(&metrics); err != nil {
+		t.Fatalf("Failed to decode metrics: %v", err)
+	}
+	// The admin check looked the caller up and the page counted its totals
+	var got []string
+	for _, q := range metrics.Queries {
+		if q.Calls != 1 {
+			t.Errorf("%s was called %d times, want once", q.Query, q.Calls)
+		}
+		got = append(got, q.Query)
+	}
+	want := []string{"CountActiveRefreshTokens", "CountChirps", "CountUsers", "GetUserByID"}
+	if !slices.Equal(got, want) {
+		t.Errorf("queries = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// rateLimiter counts events per key in fixed windows. The in-memory
+// implementation only sees one instance's traffic; a shared store such as
+// Redis can implement the same interface with INCR and EXPIRE.
+type rateLimiter interface {
+	// allow records an event for key unless limit events were already
+	// recorded in the current window. Either way it reports where key
+	// stands in that window.
+	allow(ctx context.Context, key string, limit int) (rateLimitStatus, error)
+	// allowN is allow for n events at once: all n are recorded if they
+	// fit under limit, and none otherwise.
+	allowN(ctx context.Context, key string, n, limit int) (rateLimitStatus, error)
+}
+
+// rateLimitStatus is where a key stands in its current window.
+type rateLimitStatus struct {
+	allowed   bool
+	limit     int
+	remaining int
+	// resetIn is the time until the window ends and the count starts over
+	resetIn time.Duration
+}
+
+type memoryRateLimiter struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	windows   map[string]rateWindow
+	lastSweep time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func newMemoryRateLimiter(window time.Duration) *memoryRateLimiter {
+	return &memoryRateLimiter{
+		window:  window,
+		now:     time.Now,
+		windows: make(map[string]rateWindow),
+	}
+}
+
+func (l *memoryRateLimiter) allow(ctx context.Context, key string, limit int) (rateLimitStatus, error) {
+	return l.allowN(ctx, key, 1, limit)
+}
+
+func (l *memoryRateLimiter) allowN(ctx context.Context, key string, n, limit int) (rateLimitStatus, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = rateWindow{start: now}
+	}
+	status := rateLimitStatus{limit: limit, resetIn: w.start.Add(l.window).Sub(now)}
+	if w.count+n <= limit {
+		w.count += n
+		l.windows[key] = w
+		status.allowed = true
+	}
+	status.remaining = limit - w.count
+	return status, nil
+}
+
+// sweep drops expired windows, at most once per window, so keys that stop
+// sending do not accumulate. The caller must hold l.mu.
+func (l *memoryRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+}
+
+// chirpRateLimit caps how many chirps each user may post per window.
+// Chirpy Red members get RedLimit instead of Limit. A limit of 0 turns the
+// check off for those users.
+type chirpRateLimit struct {
+	Limit    int
+	RedLimit int
+	Window   time.Duration
+}
+
+// enforceRateLimit records n events for key with limiter and sets the
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset (a Unix
+// time) headers. Over the limit it also writes a 429 with Retry-After and
+// msg, and returns false. Every rate-limited endpoint goes through here so
+// clients see the same headers everywhere.
+func enforceRateLimit(w http.ResponseWriter, r *http.Request, limiter rateLimiter, key string, n, limit int, msg string) bool {
+	status, err := limiter.allowN(r.Context(), key, n, limit)
+	if err != nil {
+		// Failing open: an unreachable limiter should not stop all traffic
+		logf(r.Context(), "Error checking rate limit for %s: %v", key, err)
+		return true
+	}
+
+	// Whole seconds, rounded up so a client that waits never comes back early
+	resetIn := max(1, int64(math.Ceil(status.resetIn.Seconds())))
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(status.limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(status.remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix()+resetIn, 10))
+	if !status.allowed {
+		w.Header().Set("Retry-After", strconv.FormatInt(resetIn, 10))
+		respondWithErrorCode(w, r, http.StatusTooManyRequests, errCodeRateLimited, msg)
+		return false
+	}
+	return true
+}
+
+// allowChirps reports whether userID may post n more chirps now, and
+// charges them to the rate limit if so. When the user would go over their
+// daily quota or rate limit it writes a 429 and returns false. Without a
+// quota or limiter every chirp is allowed.
+func (cfg *apiConfig) allowChirps(w http.ResponseWriter, r *http.Request, userID uuid.UUID, n int) bool {
+	if cfg.chirpLimiter == nil && cfg.dailyChirpQuota <= 0 {
+		return true
+	}
+
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return false
+	}
+	// The quota only reads, so it goes first and a refused chirp does not
+	// use up the rate limit
+	if !cfg.withinDailyQuota(w, r, user, n) {
+		return false
+	}
+	if cfg.chirpLimiter == nil {
+		return true
+	}
+
+	limit := cfg.chirpRateLimit.Limit
+	if user.IsChirpyRed {
+		limit = cfg.chirpRateLimit.RedLimit
+	}
+	if limit <= 0 {
+		return true
+	}
+
+	return enforceRateLimit(w, r, cfg.chirpLimiter, "chirps:"+userID.String(), n, limit, "Too many chirps, try again later")
+}
+
+// allowSignup reports whether the client behind r may create another
+// account now. Over the limit it writes a 429 and returns false. The check
+// is off unless a signup rate limit is configured.
+func (cfg *apiConfig) allowSignup(w http.ResponseWriter, r *http.Request) bool {
+	if cfg.signupLimiter == nil || cfg.signupRateLimit <= 0 {
+		return true
+	}
+	return enforceRateLimit(w, r, cfg.signupLimiter, "signups:"+cfg.clientIP(r), 1, cfg.signupRateLimit, "Too many signups, try again later")
+}
+
+// allowUsernameCheck reports whether the client behind r may check another
+// username's availability now, so the check cannot be used to walk the
+// user base. Over the limit it writes a 429 and returns false.
+func (cfg *apiConfig) allowUsernameCheck(w http.ResponseWriter, r *http.Request) bool {
+	if cfg.usernameLimiter == nil || cfg.usernameRateLimit <= 0 {
+		return true
+	}
+	return enforceRateLimit(w, r, cfg.usernameLimiter, "usernames:"+cfg.clientIP(r), 1, cfg.usernameRateLimit, "Too many username checks, try again later")
+}
+
+// chirpQuotaPeriod is the rolling period the daily chirp quota covers.
+const chirpQuotaPeriod = 24 * time.Hour
+
+// withinDailyQuota reports whether user may post n more chirps without
+// going over the daily quota. Chirpy Red members are not capped, and since
+// the flag is read on every request an upgrade lifts the cap at once.
+// Deleted chirps still count, so deleting cannot buy more posts. Over the
+// quota it writes a 429 with errCodeDailyQuotaExceeded and a
+// Retry-After of when enough chirps age out, and returns false.
+func (cfg *apiConfig) withinDailyQuota(w http.ResponseWriter, r *http.Request, user database.User, n int) bool {
+	quota := cfg.dailyChirpQuota
+	if quota <= 0 || user.IsChirpyRed {
+		return true
+	}
+	if n > quota {
+		respondWithErrorCode(w, r, http.StatusTooManyRequests, errCodeDailyQuotaExceeded, fmt.Sprintf("At most %d chirps can be posted per day", quota), quota)
+		return false
+	}
+
+	// The n chirps fit unless the one with quota-n newer chirps is still
+	// inside the period; once it ages out, they do
+	postedAt, err := cfg.dbQueries.GetNthNewestChirpTime(r.Context(), database.GetNthNewestChirpTimeParams{
+		UserID: user.ID,
+		Offset: int32(quota - n),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return true
+	}
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return false
+	}
+	retryAfter := time.Until(postedAt.Add(chirpQuotaPeriod))
+	if retryAfter <= 0 {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(retryAfter.Seconds())))))
+	respondWithErrorCode(w, r, http.StatusTooManyRequests, errCodeDailyQuotaExceeded, fmt.Sprintf("At most %d chirps can be posted per day", quota), quota)
+	return false
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+)
+
+// fakeClock is a settable time source for rate limiters.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestMemoryRateLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := newMemoryRateLimiter(time.Minute)
+	l.now = clock.Now
+
+	for i := range 3 {
+		status, _ := l.allow(t.Context(), "a", 3)
+		if want := (rateLimitStatus{allowed: true, limit: 3, remaining: 2 - i, resetIn: time.Minute}); status != want {
+			t.Fatalf("event %d: allow = %+v, want %+v", i+1, status, want)
+		}
+	}
+	clock.advance(20 * time.Second)
+	status, err := l.allow(t.Context(), "a", 3)
+	if want := (rateLimitStatus{limit: 3, resetIn: 40 * time.Second}); err != nil || status != want {
+		t.Fatalf("over the limit: allow = %+v, %v, want %+v", status, err, want)
+	}
+	if status, _ := l.allow(t.Context(), "b", 3); !status.allowed {
+		t.Error("another key should have its own window")
+	}
+
+	clock.advance(40 * time.Second)
+	if status, _ := l.allow(t.Context(), "a", 3); !status.allowed || status.remaining != 2 {
+		t.Errorf("the limit should reset once the window passes, got %+v", status)
+	}
+
+	// Expired windows are dropped
+	clock.advance(2 * time.Minute)
+	l.allow(t.Context(), "c", 3)
+	if _, ok := l.windows["b"]; ok {
+		t.Error("expired window was kept")
+	}
+}
+
+func TestChirpRateLimit(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	clock := &fakeClock{now: time.Now()}
+	limiter := newMemoryRateLimiter(5 * time.Minute)
+	limiter.now = clock.Now
+	cfg.chirpLimiter = limiter
+	cfg.chirpRateLimit = chirpRateLimit{Limit: 2, RedLimit: 4, Window: 5 * time.Minute}
+
+	user := db.addUser(t, "user@example.com")
+	red := db.addUser(t, "red@example.com")
+	db.UpgradeUserToChirpyRed(t.Context(), red.ID)
+
+	post := func(token string, n int) *http.Response {
+		t.Helper()
+		return postChirp(t, cfg, token, map[string]string{"body": fmt.Sprintf("Chirp %d", n)}).Result()
+	}
+
+	// The reset is a Unix time, window seconds from now
+	checkReset := func(res *http.Response, window time.Duration) {
+		t.Helper()
+		reset, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if earliest := time.Now().Add(window - time.Second).Unix(); err != nil || reset < earliest || reset > earliest+2 {
+			t.Errorf("X-RateLimit-Reset = %q, want about %d", res.Header.Get("X-RateLimit-Reset"), earliest+1)
+		}
+	}
+
+	token := makeTestToken(t, user.ID)
+	for i := range 2 {
+		res := post(token, i)
+		if res.StatusCode != http.StatusCreated {
+			t.Fatalf("chirp %d: got status %v want %v", i+1, res.StatusCode, http.StatusCreated)
+		}
+		if limit, remaining := res.Header.Get("X-RateLimit-Limit"), res.Header.Get("X-RateLimit-Remaining"); limit != "2" || remaining != strconv.Itoa(1-i) {
+			t.Errorf("chirp %d: X-RateLimit-Limit %q, X-RateLimit-Remaining %q, want 2 and %d", i+1, limit, remaining, 1-i)
+		}
+		checkReset(res, 5*time.Minute)
+	}
+	clock.advance(time.Minute)
+	res := post(token, 3)
+	if res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("over the limit: got status %v want %v", res.StatusCode, http.StatusTooManyRequests)
+	}
+	if got := res.Header.Get("Retry-After"); got != "240" {
+		t.Errorf("Retry-After = %q, want 240", got)
+	}
+	if got := res.Header.Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("over the limit: X-RateLimit-Remaining = %q, want 0", got)
+	}
+	checkReset(res, 4*time.Minute)
+
+	// Chirpy Red members get the higher limit
+	redToken := makeTestToken(t, red.ID)
+	for i := range 4 {
+		if res := post(redToken, i); res.StatusCode != http.StatusCreated {
+			t.Fatalf("red chirp %d: got status %v want %v", i+1, res.StatusCode, http.StatusCreated)
+		}
+	}
+	if res := post(redToken, 5); res.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("red over the limit: got status %v want %v", res.StatusCode, http.StatusTooManyRequests)
+	}
+
+	clock.advance(4 * time.Minute)
+	if res := post(token, 4); res.StatusCode != http.StatusCreated {
+		t.Errorf("after the window: got status %v want %v", res.StatusCode, http.StatusCreated)
+	}
+}
+
+func TestBulkChirpsShareRateLimit(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.chirpLimiter = newMemoryRateLimiter(5 * time.Minute)
+	cfg.chirpRateLimit = chirpRateLimit{Limit: 3, RedLimit: 5, Window: 5 * time.Minute}
+	user := db.addUser(t, "user@example.com")
+	red := db.addUser(t, "red@example.com")
+	db.UpgradeUserToChirpyRed(t.Context(), red.ID)
+
+	// A batch uses one chirp of the budget per item, shared with single posts
+	token := makeTestToken(t, user.ID)
+	if rr := postChirps(t, cfg, token, bulkItems("One", "Two")); rr.Code != http.StatusCreated || rr.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Fatalf("batch of 2: got status %v, X-RateLimit-Remaining %q, want %v and 1", rr.Code, rr.Header().Get("X-RateLimit-Remaining"), http.StatusCreated)
+	}
+	if rr := postChirp(t, cfg, token, map[string]string{"body": "Three"}); rr.Code != http.StatusCreated {
+		t.Fatalf("single chirp after the batch: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+	rr := postChirps(t, cfg, token, bulkItems("Four"))
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("batch over the limit: got status %v, Retry-After %q, want %v with Retry-After", rr.Code, rr.Header().Get("Retry-After"), http.StatusTooManyRequests)
+	}
+
+	// Chirpy Red members are capped by their own limit, and a batch that
+	// does not fit is refused whole without using any of it
+	redToken := makeTestToken(t, red.ID)
+	if rr := postChirps(t, cfg, redToken, bulkItems("1", "2", "3", "4", "5", "6")); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("red batch of 6: got status %v want %v", rr.Code, http.StatusTooManyRequests)
+	}
+	if rr := postChirps(t, cfg, redToken, bulkItems("1", "2", "3", "4", "5")); rr.Code != http.StatusCreated {
+		t.Errorf("red batch of 5: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+	if rr := postChirp(t, cfg, redToken, map[string]string{"body": "6"}); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("red chirp over the limit: got status %v want %v", rr.Code, http.StatusTooManyRequests)
+	}
+
+	if len(db.chirps) != 8 {
+		t.Errorf("stored %d chirps, want the 8 allowed", len(db.chirps))
+	}
+}
+
+func TestRejectedChirpsAreNotCharged(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.chirpLimiter = newMemoryRateLimiter(5 * time.Minute)
+	cfg.chirpRateLimit = chirpRateLimit{Limit: 2, Window: 5 * time.Minute}
+	cfg.duplicateChirpWindow = time.Minute
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	rr := postChirp(t, cfg, token, map[string]string{"body": "First"})
+	if rr.Code != http.StatusCreated || rr.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Fatalf("first chirp: got status %v, X-RateLimit-Remaining %q, want %v and 1", rr.Code, rr.Header().Get("X-RateLimit-Remaining"), http.StatusCreated)
+	}
+
+	// An invalid body and a double submit are refused before the limit
+	for _, body := range []string{strings.Repeat("a", 141), "First"} {
+		if rr := postChirp(t, cfg, token, map[string]string{"body": body}); rr.Code != http.StatusBadRequest && rr.Code != http.StatusConflict {
+			t.Fatalf("rejected chirp %q: got status %v", body, rr.Code)
+		}
+	}
+	if rr := postChirps(t, cfg, token, bulkItems("Fine", strings.Repeat("a", 141))); rr.Code != http.StatusBadRequest {
+		t.Fatalf("invalid batch: got status %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	rr = postChirp(t, cfg, token, map[string]string{"body": "Second"})
+	if rr.Code != http.StatusCreated || rr.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("second chirp: got status %v, X-RateLimit-Remaining %q, want %v and 0", rr.Code, rr.Header().Get("X-RateLimit-Remaining"), http.StatusCreated)
+	}
+}
+
+func TestSignupRateLimit(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	clock := &fakeClock{now: time.Now()}
+	limiter := newMemoryRateLimiter(time.Hour)
+	limiter.now = clock.Now
+	cfg.signupLimiter = limiter
+
+	signup := func(addr string, n int) *httptest.ResponseRecorder {
+		t.Helper()
+		body, err := json.Marshal(map[string]string{"email": fmt.Sprintf("user%d@example.com", n), "password": "password123"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/users", bytes.NewReader(body))
+		req.RemoteAddr = addr + ":4321"
+		rr := httptest.NewRecorder()
+		cfg.handlerCreateUser(rr, req)
+		return rr
+	}
+
+	// Off by default
+	for i := range 3 {
+		if rr := signup("192.0.2.1", i); rr.Code != http.StatusCreated || rr.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("signup %d without a limit: got status %v and X-RateLimit-Limit %q", i+1, rr.Code, rr.Header().Get("X-RateLimit-Limit"))
+		}
+	}
+
+	cfg.signupRateLimit = 2
+	for i := range 2 {
+		rr := signup("192.0.2.1", 10+i)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("signup %d under the limit: got status %v want %v", i+1, rr.Code, http.StatusCreated)
+		}
+		if got := rr.Header().Get("X-RateLimit-Remaining"); got != strconv.Itoa(1-i) {
+			t.Errorf("signup %d: X-RateLimit-Remaining = %q, want %d", i+1, got, 1-i)
+		}
+	}
+	clock.advance(15 * time.Minute)
+	rr := signup("192.0.2.1", 12)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("over the limit: got status %v want %v", rr.Code, http.StatusTooManyRequests)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "2700" {
+		t.Errorf("Retry-After = %q, want 2700", got)
+	}
+	if _, err := db.GetUserByEmail(t.Context(), "user12@example.com"); err == nil {
+		t.Error("the refused signup created a user")
+	}
+
+	// Each address has its own count, and the window starts over
+	if rr := signup("198.51.100.7", 13); rr.Code != http.StatusCreated {
+		t.Errorf("another address: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+	clock.advance(45 * time.Minute)
+	if rr := signup("192.0.2.1", 14); rr.Code != http.StatusCreated {
+		t.Errorf("after the window: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+}
+
+func TestDailyChirpQuota(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.dailyChirpQuota = 3
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	// One chirp from before the period, one that ages out in an hour and
+	// one, since deleted, that still counts
+	now := time.Now()
+	for _, age := range []time.Duration{25 * time.Hour, 23 * time.Hour, time.Hour} {
+		chirp, err := db.CreateChirpAt(t.Context(), database.CreateChirpAtParams{CreatedAt: now.Add(-age), Body: "Earlier", UserID: user.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if age == time.Hour {
+			db.DeleteChirp(t.Context(), chirp.ID)
+		}
+	}
+
+	post := func(token string) *httptest.ResponseRecorder {
+		t.Helper()
+		return postChirp(t, cfg, token, map[string]string{"body": "Hello"})
+	}
+	checkExceeded := func(rr *httptest.ResponseRecorder, retryAfter bool) {
+		t.Helper()
+		var errResp ErrorResponse
+		json.Unmarshal(rr.Body.Bytes(), &errResp)
+		if rr.Code != http.StatusTooManyRequests || errResp.Code != "daily_quota_exceeded" {
+			t.Fatalf("over the quota: got status %v and %+v, want a 429 with code daily_quota_exceeded", rr.Code, errResp)
+		}
+		// The chirp from 23 hours ago frees the next slot
+		seconds, err := strconv.Atoi(rr.Header().Get("Retry-After"))
+		if retryAfter && (err != nil || seconds < 3590 || seconds > 3600) {
+			t.Errorf("Retry-After = %q, want about an hour", rr.Header().Get("Retry-After"))
+		}
+	}
+
+	// At the limit: this chirp is the third in the period
+	if rr := post(token); rr.Code != http.StatusCreated {
+		t.Fatalf("chirp at the quota: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+	checkExceeded(post(token), true)
+
+	// The batch is counted as a whole
+	checkExceeded(postChirps(t, cfg, token, bulkItems("One", "Two", "Three", "Four")), false)
+	checkExceeded(postChirps(t, cfg, token, bulkItems("One")), true)
+
+	// Upgrading to Chirpy Red lifts the cap at once
+	db.UpgradeUserToChirpyRed(t.Context(), user.ID)
+	if rr := post(token); rr.Code != http.StatusCreated {
+		t.Errorf("Chirpy Red chirp: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+	if rr := postChirps(t, cfg, token, bulkItems("One", "Two", "Three", "Four")); rr.Code != http.StatusCreated {
+		t.Errorf("Chirpy Red batch: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+
+	// Other users have their own quota
+	other := db.addUser(t, "other@example.com")
+	if rr := postChirps(t, cfg, makeTestToken(t, other.ID), bulkItems("One", "Two", "Three")); rr.Code != http.StatusCreated {
+		t.Errorf("another user's batch: got status %v want %v", rr.Code, http.StatusCreated)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+)
+
+// refreshTokenTTL is how long a refresh token stays valid. Logins that ask
+// to be remembered get RememberMe, the rest Session. Rotation issues the new
+// token for the same duration, counted from the rotation.
+type refreshTokenTTL struct {
+	Session    time.Duration
+	RememberMe time.Duration
+}
+
+func (t refreshTokenTTL) lifetime(rememberMe bool) time.Duration {
+	if rememberMe {
+		return t.RememberMe
+	}
+	return t.Session
+}
+
+// storeRefreshToken saves the token described by arg, setting its expiry
+// from arg.RememberMe.
+func (cfg *apiConfig) storeRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	arg.ExpiresAt = time.Now().UTC().Add(cfg.refreshTokenTTL.lifetime(arg.RememberMe))
+	return cfg.dbQueries.CreateRefreshToken(ctx, arg)
+}
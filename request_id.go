@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID matches the incoming request IDs that are passed through.
+// Anything else, such as an ID with spaces or control characters that could
+// forge log lines, or one long enough to bloat every log entry, is replaced.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:+/=-]{1,128}$`)
+
+type requestIDKey struct{}
+
+// middlewareRequestID gives every request an ID: the one the client or a
+// proxy sent in X-Request-ID if it looks sane, otherwise a new UUID. The ID
+// is stored in the request context and echoed in the response header.
+func middlewareRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestID returns the ID middlewareRequestID assigned to the request
+// behind ctx, or "" outside a request.
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// logf logs like log.Printf, prefixed with the ID of the request behind
+// ctx so the line can be matched to the error a client reports.
+func logf(ctx context.Context, format string, args ...any) {
+	if id := requestID(ctx); id != "" {
+		format = "[" + id + "] " + format
+	}
+	log.Output(2, fmt.Sprintf(format, args...))
+}
+
+// respondWithError writes a JSON error response carrying the request ID.
+// A 500 gets errCodeInternal, so its message can be translated.
+func respondWithError(w http.ResponseWriter, r *http.Request, code int, msg string) {
+	errorCode := ""
+	if code == http.StatusInternalServerError {
+		errorCode = errCodeInternal
+	}
+	respondWithErrorCode(w, r, code, errorCode, msg)
+}
+
+// respondWithErrorCode is respondWithError with a machine-readable
+// errorCode for clients to act on. msg is in English and is translated by
+// errorCode when the client asks for another language; args fill in the
+// translation's fmt verbs.
+func respondWithErrorCode(w http.ResponseWriter, r *http.Request, code int, errorCode, msg string, args ...any) {
+	msg = localizeError(w, r, errorCode, msg, args...)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: msg, RequestID: requestID(r.Context()), Code: errorCode})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRequestIDPassthrough(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	mux := newTestMux(t, cfg, false)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/chirps/not-a-uuid", nil)
+	req.Header.Set(requestIDHeader, "proxy-1234")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get(requestIDHeader); got != "proxy-1234" {
+		t.Errorf("response %s = %q, want the incoming ID", requestIDHeader, got)
+	}
+	var body ErrorResponse
+	json.NewDecoder(rr.Body).Decode(&body)
+	if rr.Code != http.StatusBadRequest || body.RequestID != "proxy-1234" {
+		t.Errorf("error response = %v %+v, want a 400 carrying the request ID", rr.Code, body)
+	}
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	mux := newTestMux(t, cfg, false)
+
+	for _, incoming := range []string{
+		"",
+		"has spaces",
+		"forged\n2026/01/01 Error: everything is on fire",
+		strings.Repeat("a", 129),
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/chirps/not-a-uuid", nil)
+		if incoming != "" {
+			req.Header.Set(requestIDHeader, incoming)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		got := rr.Header().Get(requestIDHeader)
+		if _, err := uuid.Parse(got); err != nil {
+			t.Errorf("incoming %q: response ID %q is not a generated UUID", incoming, got)
+		}
+		var body ErrorResponse
+		json.NewDecoder(rr.Body).Decode(&body)
+		if body.RequestID != got {
+			t.Errorf("incoming %q: error body ID %q, header %q", incoming, body.RequestID, got)
+		}
+	}
+
+	// Each request gets its own ID
+	first, second := httptest.NewRecorder(), httptest.NewRecorder()
+	mux.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/api/v1/healthz", nil))
+	mux.ServeHTTP(second, httptest.NewRequest(http.MethodGet, "/api/v1/healthz", nil))
+	if first.Header().Get(requestIDHeader) == second.Header().Get(requestIDHeader) {
+		t.Error("two requests got the same ID")
+	}
+}
+
+func TestLogfIncludesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	previous := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(previous) })
+
+	handler := middlewareRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logf(r.Context(), "Error doing %s", "things")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestIDHeader, "abc-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(buf.String(), "[abc-123] Error doing things") {
+		t.Errorf("log output = %q, want the request ID prefix", buf.String())
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/AlexTLDR/chirpy/internal/auth"
+	"github.com/AlexTLDR/chirpy/internal/config"
+)
+
+const (
+	apiPrefix       = "/api/"
+	apiV1Prefix     = "/api/v1/"
+	legacyAPISunset = "Wed, 30 Jun 2027 00:00:00 GMT"
+)
+
+// route is a single ServeMux registration. Keeping the routes in a table
+// lets tests check them against the OpenAPI document.
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+func (cfg *apiConfig) routes(filepathRoot string, spaMode bool) []route {
+	// Access tokens issued with scopes only reach the routes those allow
+	routes := []route{
+		{"GET /app/", cfg.middlewareMetricsInc(http.StripPrefix("/app", appFileServer(filepathRoot, spaMode)))},
+		{"GET /media/{name}", http.HandlerFunc(cfg.handlerMedia)},
+		{"GET /api/healthz", http.HandlerFunc(handlerReadiness)},
+		{"GET /api/readyz", http.HandlerFunc(cfg.handlerReadyz)},
+		{"GET /api/openapi.json", http.HandlerFunc(handlerOpenAPI)},
+		{"GET /admin/metrics", cfg.requireScope(auth.ScopeAdmin, cfg.handlerMetrics)},
+		{"POST /admin/reset", cfg.requireScope(auth.ScopeAdmin, cfg.handlerReset)},
+		{"POST /admin/chirps/purge", cfg.requireScope(auth.ScopeAdmin, cfg.handlerPurgeChirps)},
+		{"GET /admin/reports", cfg.requireScope(auth.ScopeAdmin, cfg.handlerGetReports)},
+		{"POST /admin/reports/{chirpID}/resolve", cfg.requireScope(auth.ScopeAdmin, cfg.handlerResolveReports)},
+		{"POST /admin/users/{userID}/admin", cfg.requireScope(auth.ScopeAdmin, cfg.handlerGrantAdmin)},
+		{"DELETE /admin/users/{userID}/admin", cfg.requireScope(auth.ScopeAdmin, cfg.handlerRevokeAdmin)},
+		{"POST /admin/users/{userID}/ban", cfg.requireScope(auth.ScopeAdmin, cfg.handlerBanUser)},
+		{"DELETE /admin/users/{userID}/ban", cfg.requireScope(auth.ScopeAdmin, cfg.handlerUnbanUser)},
+		{"DELETE /admin/users/{userID}", cfg.requireScope(auth.ScopeAdmin, cfg.handlerDeleteUserKeepChirps)},
+		{"GET /admin/audit", cfg.requireScope(auth.ScopeAdmin, cfg.handlerGetAuditLog)},
+		{"GET /admin/webhook_events", cfg.requireScope(auth.ScopeAdmin, cfg.handlerGetWebhookEvents)},
+		{"GET /api/chirps", cfg.requireScope(auth.ScopeChirpsRead, cfg.handlerGetChirps)},
+		{"POST /api/chirps", cfg.requireScope(auth.ScopeChirpsWrite, cfg.handlerCreateChirp)},
+		{"POST /api/chirps/bulk", cfg.requireScope(auth.ScopeChirpsWrite, cfg.handlerCreateChirps)},
+		{"POST /api/chirps/bulk_delete", cfg.requireScope(auth.ScopeChirpsWrite, cfg.handlerDeleteChirps)},
+		{"GET /api/chirps/{chirpID}", cfg.requireScope(auth.ScopeChirpsRead, cfg.handlerGetChirpByID)},
+		{"PUT /api/chirps/{chirpID}", cfg.requireScope(auth.ScopeChirpsWrite, cfg.handlerUpdateChirp)},
+		{"DELETE /api/chirps/{chirpID}", cfg.requireScope(auth.ScopeChirpsWrite, cfg.handlerDeleteChirp)},
+		{"GET /api/chirps/{chirpID}/history", cfg.requireScope(auth.ScopeChirpsRead, cfg.handlerGetChirpHistory)},
+		{"GET /api/chirps/ws", cfg.requireScope(auth.ScopeChirpsRead, cfg.handlerChirpsWebSocket)},
+		{"GET /api/chirps/stream", cfg.requireScope(auth.ScopeChirpsRead, cfg.handlerChirpsStream)},
+		{"POST /api/chirps/{chirpID}/like", cfg.requireScope(auth.ScopeChirpsWrite, cfg.handlerLikeChirp)},
+		{"DELETE /api/chirps/{chirpID}/like", cfg.requireScope(auth.ScopeChirpsWrite, cfg.handlerUnlikeChirp)},
+		{"GET /api/chirps/{chirpID}/likes", cfg.requireScope(auth.ScopeChirpsRead, cfg.handlerGetChirpLikes)},
+		{"POST /api/chirps/{chirpID}/pin", cfg.requireScope(auth.ScopeChirpsWrite, cfg.handlerPinChirp)},
+		{"DELETE /api/chirps/{chirpID}/pin", cfg.requireScope(auth.ScopeChirpsWrite, cfg.handlerUnpinChirp)},
+		{"POST /api/chirps/{chirpID}/bookmark", cfg.requireScope(auth.ScopeChirpsWrite, cfg.handlerBookmarkChirp)},
+		{"DELETE /api/chirps/{chirpID}/bookmark", cfg.requireScope(auth.ScopeChirpsWrite, cfg.handlerUnbookmarkChirp)},
+		{"GET /api/bookmarks", cfg.requireScope(auth.ScopeChirpsRead, cfg.handlerGetBookmarks)},
+		{"POST /api/chirps/{chirpID}/report", cfg.requireScope(auth.ScopeChirpsWrite, cfg.handlerReportChirp)},
+		{"GET /api/chirps/{chirpID}/replies", cfg.requireScope(auth.ScopeChirpsRead, cfg.handlerGetChirpReplies)},
+		{"GET /api/hashtags/{tag}/chirps", cfg.requireScope(auth.ScopeChirpsRead, cfg.handlerGetHashtagChirps)},
+		{"GET /api/feed", cfg.requireScope(auth.ScopeChirpsRead, cfg.handlerGetFeed)},
+		{"GET /api/notifications", cfg.requireScope(auth.ScopeUsersRead, cfg.handlerGetNotifications)},
+		{"POST /api/notifications/read", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerMarkNotificationsRead)},
+		{"GET /api/users", cfg.requireScope(auth.ScopeUsersRead, cfg.handlerListUsers)},
+		{"POST /api/users", http.HandlerFunc(cfg.handlerCreateUser)},
+		{"GET /api/usernames/{name}/available", http.HandlerFunc(cfg.handlerUsernameAvailable)},
+		{"PUT /api/users", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerUpdateUser)},
+		{"GET /api/users/me/confirm_email", http.HandlerFunc(cfg.handlerConfirmEmail)},
+		{"POST /api/users/me/avatar", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerUploadAvatar)},
+		{"DELETE /api/users/me/avatar", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerDeleteAvatar)},
+		{"GET /api/users/me/export", cfg.requireScope(auth.ScopeUsersRead, cfg.handlerExportUser)},
+		{"GET /api/users/me/logins", cfg.requireScope(auth.ScopeUsersRead, cfg.handlerGetLoginHistory)},
+		{"PUT /api/users/me/privacy", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerSetPrivacy)},
+		{"POST /api/users/me/2fa/setup", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerSetupTwoFactor)},
+		{"POST /api/users/me/2fa/activate", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerActivateTwoFactor)},
+		{"POST /api/users/me/2fa/backup_codes", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerRegenerateBackupCodes)},
+		{"GET /api/follow_requests", cfg.requireScope(auth.ScopeUsersRead, cfg.handlerGetFollowRequests)},
+		{"POST /api/follow_requests/{userID}/approve", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerApproveFollowRequest)},
+		{"POST /api/follow_requests/{userID}/deny", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerDenyFollowRequest)},
+		{"POST /api/api_keys", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerCreateAPIKey)},
+		{"GET /api/api_keys", cfg.requireScope(auth.ScopeUsersRead, cfg.handlerGetAPIKeys)},
+		{"DELETE /api/api_keys/{keyID}", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerDeleteAPIKey)},
+		{"GET /api/users/{userID}", cfg.requireScope(auth.ScopeUsersRead, cfg.handlerGetUser)},
+		{"GET /api/users/{userID}/stats", cfg.requireScope(auth.ScopeUsersRead, cfg.handlerGetUserStats)},
+		{"POST /api/users/{userID}/follow", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerFollowUser)},
+		{"DELETE /api/users/{userID}/follow", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerUnfollowUser)},
+		{"GET /api/users/{userID}/followers", cfg.requireScope(auth.ScopeUsersRead, cfg.handlerGetFollowers)},
+		{"GET /api/users/{userID}/following", cfg.requireScope(auth.ScopeUsersRead, cfg.handlerGetFollowing)},
+		{"POST /api/users/{userID}/mute", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerMuteUser)},
+		{"DELETE /api/users/{userID}/mute", cfg.requireScope(auth.ScopeUsersWrite, cfg.handlerUnmuteUser)},
+		{"POST /api/login", http.HandlerFunc(cfg.handlerLogin)},
+		{"POST /api/login/magic", http.HandlerFunc(cfg.handlerRequestMagicLink)},
+		{"GET /api/login/magic/verify", http.HandlerFunc(cfg.handlerVerifyMagicLink)},
+		{"POST /api/login/magic/verify", http.HandlerFunc(cfg.handlerVerifyMagicLink)},
+		{"GET /api/oauth/google/login", http.HandlerFunc(cfg.handlerGoogleLogin)},
+		{"GET /api/oauth/google/callback", http.HandlerFunc(cfg.handlerGoogleCallback)},
+		{"POST /api/refresh", http.HandlerFunc(cfg.handlerRefresh)},
+		{"POST /api/revoke", http.HandlerFunc(cfg.handlerRevoke)},
+		{"POST /api/polka/webhooks", http.HandlerFunc(cfg.handlerPolkaWebhook)},
+	}
+
+	// Bots may use an API key instead of an access token for chirps
+	for i, rt := range routes {
+		_, path, _ := strings.Cut(rt.pattern, " ")
+		if strings.HasPrefix(path, "/api/chirps") {
+			routes[i].handler = middlewareAPIKey(rt.handler)
+		}
+	}
+
+	// Everything else needs the database, so it fails fast while that is
+	// down
+	for i, rt := range routes {
+		if !dbFreeRoutes[rt.pattern] {
+			routes[i].handler = cfg.middlewareDBAvailable(rt.handler)
+		}
+	}
+	return routes
+}
+
+// dbFreeRoutes are the routes that work without the database.
+var dbFreeRoutes = map[string]bool{
+	"GET /app/":                   true,
+	"GET /media/{name}":           true,
+	"GET /api/healthz":            true,
+	"GET /api/readyz":             true,
+	"GET /api/openapi.json":       true,
+	"GET /api/oauth/google/login": true,
+}
+
+// newServer returns the complete HTTP handler main serves: every route
+// with its middleware, the profiling endpoints when they are enabled, and
+// request IDs, HEAD, gzip and 405 responses around all of it. Tests drive it
+// through httptest to exercise real request flows.
+func newServer(cfg *apiConfig, conf config.Config) http.Handler {
+	mux := http.NewServeMux()
+	registerRoutes(mux, cfg.routes(conf.FilepathRoot, conf.SPAMode), conf.LegacyAPI)
+
+	// Profiling endpoints exist in dev, or elsewhere when asked for
+	pprofConfig(conf.Pprof).register(mux)
+
+	return middlewareRequestID(middlewareHEAD(middlewareGzip(trimTrailingSlash(mux, methodNotAllowed(mux)))))
+}
+
+// registerRoutes adds routes to mux. Everything under /api/ is served from
+// /api/v1/; when legacyAPI is set the unversioned paths stay available as
+// deprecated aliases backed by the same handlers.
+func registerRoutes(mux *http.ServeMux, routes []route, legacyAPI bool) {
+	for _, rt := range routes {
+		method, path, _ := strings.Cut(rt.pattern, " ")
+
+		if !strings.HasPrefix(path, apiPrefix) {
+			mux.Handle(rt.pattern, rt.handler)
+			continue
+		}
+
+		v1Path := apiV1Prefix + strings.TrimPrefix(path, apiPrefix)
+		mux.Handle(method+" "+v1Path, rt.handler)
+		if legacyAPI {
+			mux.Handle(rt.pattern, deprecatedAPI(rt.handler))
+		}
+	}
+}
+
+// methodNotAllowed wraps mux so requests that match a registered path but
+// not its method get the standard JSON error body. ServeMux already answers
+// these with a 405 and an Allow header listing the registered methods; only
+// its plain-text body is replaced. Every route must therefore carry a method.
+func methodNotAllowed(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern == "" {
+			probe := &headerRecorder{header: make(http.Header)}
+			h.ServeHTTP(probe, r)
+			if probe.status == http.StatusMethodNotAllowed {
+				w.Header().Set("Allow", probe.header.Get("Allow"))
+				respondWithError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// trimTrailingSlash serves an API path with a trailing slash that matches
+// no route as the same path without it, so /api/v1/chirps/ behaves like
+// /api/v1/chirps. Everything else, including deeper paths that match
+// nothing, goes to next unchanged.
+func trimTrailingSlash(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if strings.HasPrefix(path, apiPrefix) && strings.HasSuffix(path, "/") {
+			if _, pattern := mux.Handler(r); pattern == "" {
+				r2 := new(http.Request)
+				*r2 = *r
+				r2.URL = new(url.URL)
+				*r2.URL = *r.URL
+				r2.URL.Path = strings.TrimSuffix(path, "/")
+				r2.URL.RawPath = ""
+				r = r2
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// headerRecorder captures the status and headers of a response and drops
+// its body.
+type headerRecorder struct {
+	header http.Header
+	status int
+}
+
+func (h *headerRecorder) Header() http.Header { return h.header }
+
+func (h *headerRecorder) Write(p []byte) (int, error) { return len(p), nil }
+
+func (h *headerRecorder) WriteHeader(status int) {
+	if h.status == 0 {
+		h.status = status
+	}
+}
+
+// deprecatedAPI marks responses from the unversioned /api/ paths as
+// deprecated and points clients at the /api/v1/ equivalent.
+func deprecatedAPI(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		successor := apiV1Prefix + strings.TrimPrefix(r.URL.Path, apiPrefix)
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Sunset", legacyAPISunset)
+		w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestMux(t *testing.T, cfg *apiConfig, legacyAPI bool) http.Handler {
+	t.Helper()
+	mux := http.NewServeMux()
+	registerRoutes(mux, cfg.routes(".", false), legacyAPI)
+	return middlewareRequestID(trimTrailingSlash(mux, methodNotAllowed(mux)))
+}
+
+func TestVersionedAndLegacyRoutes(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	chirp := db.addChirp(t, user.ID, "Hello")
+	mux := newTestMux(t, cfg, true)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+makeTestToken(t, user.ID))
+		// Error bodies carry the request ID, so both requests share one
+		req.Header.Set(requestIDHeader, "versioned-routes")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/healthz", http.StatusOK},
+		{http.MethodGet, "/chirps", http.StatusOK},
+		{http.MethodGet, "/chirps/" + chirp.ID.String(), http.StatusOK},
+		{http.MethodGet, "/chirps/not-a-uuid", http.StatusBadRequest},
+		{http.MethodGet, "/chirps/" + chirp.ID.String() + "/likes", http.StatusOK},
+		{http.MethodGet, "/users/" + user.ID.String(), http.StatusOK},
+		{http.MethodGet, "/feed", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			legacy := serve(tt.method, "/api"+tt.path)
+			v1 := serve(tt.method, "/api/v1"+tt.path)
+
+			if legacy.Code != tt.want || v1.Code != tt.want {
+				t.Fatalf("status codes: legacy %v, v1 %v, want %v", legacy.Code, v1.Code, tt.want)
+			}
+			if legacy.Body.String() != v1.Body.String() {
+				t.Errorf("bodies differ:\nlegacy: %s\nv1:     %s", legacy.Body.String(), v1.Body.String())
+			}
+
+			if v1.Header().Get("Deprecation") != "" || v1.Header().Get("Sunset") != "" {
+				t.Errorf("v1 response should not be marked deprecated")
+			}
+			if legacy.Header().Get("Deprecation") != "true" || legacy.Header().Get("Sunset") != legacyAPISunset {
+				t.Errorf("legacy response missing deprecation headers: %v", legacy.Header())
+			}
+			if link := legacy.Header().Get("Link"); !strings.Contains(link, "</api/v1"+tt.path+">") {
+				t.Errorf("Link = %q, want successor /api/v1%s", link, tt.path)
+			}
+		})
+	}
+}
+
+func TestLegacyRoutesDisabled(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	mux := newTestMux(t, cfg, false)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/healthz", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("legacy route returned %v, want %v", rr.Code, http.StatusNotFound)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/healthz", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("v1 route returned %v, want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	chirp := db.addChirp(t, user.ID, "Hello")
+	mux := newTestMux(t, cfg, true)
+
+	tests := []struct {
+		method string
+		path   string
+		allow  string
+	}{
+		{http.MethodDelete, "/api/v1/login", "POST"},
+		{http.MethodGet, "/api/v1/revoke", "POST"},
+		{http.MethodGet, "/api/revoke", "POST"},
+		{http.MethodPut, "/api/v1/chirps", "GET, HEAD, POST"},
+		{http.MethodPost, "/api/v1/chirps/" + chirp.ID.String(), "DELETE, GET, HEAD, PUT"},
+		{http.MethodGet, "/api/v1/chirps/" + chirp.ID.String() + "/like", "DELETE, POST"},
+		{http.MethodPost, "/admin/metrics", "GET, HEAD"},
+		{http.MethodGet, "/admin/reset", "POST"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s: got status %v want %v", tt.method, tt.path, rr.Code, http.StatusMethodNotAllowed)
+			continue
+		}
+		if got := rr.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s %s: Allow = %q, want %q", tt.method, tt.path, got, tt.allow)
+		}
+		var errResp ErrorResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &errResp); err != nil || errResp.Error == "" {
+			t.Errorf("%s %s: expected JSON error body, got %q", tt.method, tt.path, rr.Body.String())
+		}
+	}
+
+	// Unknown paths are still plain 404s
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/nope", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("unknown path: got status %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestChirpPaths(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	chirp := db.addChirp(t, user.ID, "Hello")
+	mux := newTestMux(t, cfg, true)
+	id := chirp.ID.String()
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+		list   bool
+	}{
+		{http.MethodGet, "/api/v1/chirps", http.StatusOK, true},
+		{http.MethodGet, "/api/v1/chirps/", http.StatusOK, true},
+		{http.MethodGet, "/api/chirps/", http.StatusOK, true},
+		{http.MethodGet, "/api/v1/chirps/" + id, http.StatusOK, false},
+		{http.MethodGet, "/api/v1/chirps/" + id + "/", http.StatusOK, false},
+		{http.MethodGet, "/api/v1/chirps/not-a-uuid", http.StatusBadRequest, false},
+		{http.MethodGet, "/api/v1/chirps/x/y/z", http.StatusNotFound, false},
+		{http.MethodGet, "/api/v1/chirps/" + id + "/y/z/", http.StatusNotFound, false},
+		{http.MethodPut, "/api/v1/chirps/", http.StatusMethodNotAllowed, false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+makeTestToken(t, user.ID))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != tt.want {
+			t.Errorf("%s %s: got status %v want %v", tt.method, tt.path, rr.Code, tt.want)
+			continue
+		}
+		if rr.Code != http.StatusOK {
+			continue
+		}
+		if tt.list {
+			var chirps []Chirp
+			if err := json.Unmarshal(rr.Body.Bytes(), &chirps); err != nil || len(chirps) != 1 {
+				t.Errorf("%s %s: expected the chirp list, got %s", tt.method, tt.path, rr.Body)
+			}
+			continue
+		}
+		var got Chirp
+		if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil || got.ID != chirp.ID {
+			t.Errorf("%s %s: expected chirp %s, got %s", tt.method, tt.path, id, rr.Body)
+		}
+	}
+}
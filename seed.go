@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/auth"
+	"github.com/AlexTLDR/chirpy/internal/config"
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// seedPassword is the password of every account -seed creates.
+const seedPassword = "chirpy-seed"
+
+// seedSpan is how far back seeded chirps are spread.
+const seedSpan = 30 * 24 * time.Hour
+
+// seedEmail is the address of the nth sample user. The addresses are fixed
+// so seeding again reuses the accounts instead of piling up new ones.
+func seedEmail(n int) string {
+	return fmt.Sprintf("seed%d@example.com", n)
+}
+
+// seedBodies are the sample chirps; %s is replaced by a mention of another
+// sample user.
+var seedBodies = []string{
+	"Just setting up my chirpy",
+	"Coffee first, then #golang",
+	"Anyone else think #postgres is underrated?",
+	"Shipping on a Friday. What could go wrong? #devlife",
+	"Hey %s, lunch later?",
+	"Reading https://go.dev/blog today",
+	"Rain again. Good day for refactoring #devlife",
+	"%s have you tried the new build yet?",
+	"Hot take: tabs over spaces",
+	"Finally fixed that flaky test #golang",
+	"Weekend plans: nothing at all",
+	"Thanks %s for the review!",
+	"Is it still a side project if it has users?",
+	"Benchmarks don't lie, but they do mislead #performance",
+	"Good morning, chirpers",
+}
+
+// seedReplies are the bodies of sample replies.
+var seedReplies = []string{
+	"Agreed!",
+	"Not sure about that one",
+	"Same here",
+	"Ha, this is so true",
+	"Tell me more",
+}
+
+// seedSummary is what seed did.
+type seedSummary struct {
+	UsersCreated  int
+	UsersExisting int
+	Chirps        int
+	Replies       int
+}
+
+func (s seedSummary) String() string {
+	return fmt.Sprintf("created %d users (%d already existed) and %d chirps, %d of them replies; sign in as seed1@example.com (an admin) to seed%d@example.com with password %q",
+		s.UsersCreated, s.UsersExisting, s.Chirps, s.Replies, s.UsersCreated+s.UsersExisting, seedPassword)
+}
+
+// seed fills a dev database with sample data. The users are reused if they
+// already exist; the chirps are always added. Users and chirps are
+// validated and stored the way signup and posting do, but chirps are
+// backdated across the last seedSpan with random authors, and about one in
+// five replies to an earlier chirp.
+func (cfg *apiConfig) seed(ctx context.Context, opts config.Seed) (seedSummary, error) {
+	var summary seedSummary
+
+	// One hash serves every account; password hashing is slow by design
+	hashedPassword, err := auth.HashPassword(seedPassword, cfg.passwordHashing)
+	if err != nil {
+		return summary, err
+	}
+
+	users := make([]database.User, opts.Users)
+	for i := range users {
+		email := seedEmail(i + 1)
+		users[i], err = cfg.dbQueries.GetUserByEmail(withPrimary(ctx), email)
+		if err == nil {
+			summary.UsersExisting++
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return summary, err
+		}
+
+		username, err := normalizeUsername(defaultUsername(email))
+		if err != nil {
+			return summary, fmt.Errorf("username for %s: %w", email, err)
+		}
+		users[i], err = cfg.dbQueries.CreateUser(ctx, database.CreateUserParams{
+			Email:          email,
+			HashedPassword: sql.NullString{String: hashedPassword, Valid: true},
+			Username:       username,
+			DisplayName:    sql.NullString{String: fmt.Sprintf("Seed User %d", i+1), Valid: true},
+		})
+		if err != nil {
+			return summary, fmt.Errorf("creating %s: %w", email, err)
+		}
+		summary.UsersCreated++
+	}
+
+	// The first sample user is an admin, so a dev database always has one,
+	// including after /admin/reset deleted the last
+	_, err = cfg.dbQueries.SetUserAdmin(ctx, database.SetUserAdminParams{ID: users[0].ID, IsAdmin: true})
+	if err != nil {
+		return summary, fmt.Errorf("making %s an admin: %w", users[0].Email, err)
+	}
+
+	// Oldest first, so a reply always comes after its parent
+	now := time.Now().UTC()
+	times := make([]time.Time, opts.Chirps)
+	for i := range times {
+		times[i] = now.Add(-rand.N(seedSpan))
+	}
+	slices.SortFunc(times, time.Time.Compare)
+
+	var chirps []database.Chirp
+	for _, createdAt := range times {
+		author := users[rand.IntN(len(users))]
+		body := seedBodies[rand.IntN(len(seedBodies))]
+		if strings.Contains(body, "%s") {
+			body = fmt.Sprintf(body, "@"+users[rand.IntN(len(users))].Username)
+		}
+
+		var parent database.Chirp
+		if len(chirps) > 0 && rand.IntN(5) == 0 {
+			parent = chirps[rand.IntN(len(chirps))]
+			body = seedReplies[rand.IntN(len(seedReplies))]
+		}
+
+		limit, err := cfg.chirpLengthLimit(ctx, author.ID)
+		if err != nil {
+			return summary, err
+		}
+		cleaned, err := validateChirp(body, limit)
+		if err != nil {
+			return summary, fmt.Errorf("sample chirp %q: %w", body, err)
+		}
+
+		dbChirp, err := cfg.dbQueries.CreateChirpAt(ctx, database.CreateChirpAtParams{
+			CreatedAt:     createdAt,
+			Body:          cleaned,
+			UserID:        author.ID,
+			ParentChirpID: uuid.NullUUID{UUID: parent.ID, Valid: parent.ID != uuid.Nil},
+		})
+		if err != nil {
+			return summary, err
+		}
+		cfg.announceChirp(ctx, dbChirp, parent.UserID)
+		chirps = append(chirps, dbChirp)
+
+		summary.Chirps++
+		if dbChirp.ParentChirpID.Valid {
+			summary.Replies++
+		}
+	}
+	cfg.chirpCache.invalidate()
+
+	return summary, nil
+}
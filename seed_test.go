@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/auth"
+	"github.com/AlexTLDR/chirpy/internal/config"
+	"github.com/google/uuid"
+)
+
+func TestSeed(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	db.addUser(t, seedEmail(2))
+
+	summary, err := cfg.seed(t.Context(), config.Seed{Enabled: true, Users: 3, Chirps: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.UsersCreated != 2 || summary.UsersExisting != 1 || summary.Chirps != 50 {
+		t.Errorf("summary = %+v, want 2 users created, 1 reused and 50 chirps", summary)
+	}
+	if len(db.users) != 3 || len(db.chirps) != 50 {
+		t.Fatalf("store has %d users and %d chirps, want 3 and 50", len(db.users), len(db.chirps))
+	}
+
+	seeded, err := db.GetUserByEmail(t.Context(), seedEmail(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.CheckPasswordHash(seeded.HashedPassword.String, seedPassword); err != nil {
+		t.Errorf("seeded user does not accept the seed password: %v", err)
+	}
+	if !seeded.IsAdmin {
+		t.Errorf("%s is not an admin, want the first sample user to be one", seedEmail(1))
+	}
+
+	authors := map[uuid.UUID]bool{}
+	byID := map[uuid.UUID]time.Time{}
+	replies := 0
+	oldest := time.Now().Add(-seedSpan - time.Minute)
+	for _, chirp := range db.chirps {
+		authors[chirp.UserID] = true
+		byID[chirp.ID] = chirp.CreatedAt
+		if chirp.CreatedAt.Before(oldest) || chirp.CreatedAt.After(time.Now()) {
+			t.Errorf("chirp created at %v, want within the last %v", chirp.CreatedAt, seedSpan)
+		}
+		if chirp.ParentChirpID.Valid {
+			replies++
+			if byID[chirp.ParentChirpID.UUID].After(chirp.CreatedAt) {
+				t.Errorf("reply %s predates its parent", chirp.ID)
+			}
+		}
+	}
+	if replies != summary.Replies {
+		t.Errorf("store has %d replies, summary says %d", replies, summary.Replies)
+	}
+	if len(authors) < 2 {
+		t.Errorf("chirps by %d authors, want them spread across the sample users", len(authors))
+	}
+
+	// Seeding again reuses the users and adds chirps
+	summary, err = cfg.seed(t.Context(), config.Seed{Enabled: true, Users: 3, Chirps: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.UsersCreated != 0 || summary.UsersExisting != 3 || len(db.users) != 3 || len(db.chirps) != 60 {
+		t.Errorf("second seed: summary %+v, %d users and %d chirps, want 3 reused users and 60 chirps", summary, len(db.users), len(db.chirps))
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlexTLDR/chirpy/internal/config"
+)
+
+// newTestServer serves newServer over a real listener, configured as main
+// would be with the default settings.
+func newTestServer(t *testing.T, cfg *apiConfig) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(newServer(cfg, config.Default()))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// call sends body as JSON, with token as a bearer token when set, and
+// decodes the JSON response into out when it is not nil.
+func call(t *testing.T, srv *httptest.Server, method, path, token string, body, out any) *http.Response {
+	t.Helper()
+	var reqBody bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reqBody).Encode(body)
+	}
+	req, err := http.NewRequestWithContext(t.Context(), method, srv.URL+path, &reqBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		err = json.NewDecoder(resp.Body).Decode(out)
+		if err != nil {
+			t.Fatalf("%s %s: decoding response: %v", method, path, err)
+		}
+	}
+	return resp
+}
+
+func TestServerSignupToTimeline(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+
+	credentials := map[string]string{"email": "alice@example.com", "password": "hunter22"}
+	var user User
+	if resp := call(t, srv, http.MethodPost, "/api/v1/users", "", credentials, &user); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("signup: got status %v want %v", resp.StatusCode, http.StatusCreated)
+	}
+
+	var login struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+	}
+	if resp := call(t, srv, http.MethodPost, "/api/v1/login", "", credentials, &login); resp.StatusCode != http.StatusOK || login.Token == "" {
+		t.Fatalf("login: got status %v and token %q", resp.StatusCode, login.Token)
+	}
+	if login.ID != user.ID.String() {
+		t.Errorf("logged in as %s, want %s", login.ID, user.ID)
+	}
+
+	var chirp Chirp
+	resp := call(t, srv, http.MethodPost, "/api/v1/chirps", login.Token, map[string]string{"body": "Hello, #chirpy!"}, &chirp)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create chirp: got status %v want %v", resp.StatusCode, http.StatusCreated)
+	}
+	if resp.Header.Get(requestIDHeader) == "" {
+		t.Errorf("create chirp: no %s header", requestIDHeader)
+	}
+
+	var chirps []Chirp
+	if resp := call(t, srv, http.MethodGet, "/api/v1/chirps", login.Token, nil, &chirps); resp.StatusCode != http.StatusOK {
+		t.Fatalf("list chirps: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if len(chirps) != 1 || chirps[0].ID != chirp.ID || chirps[0].UserID != user.ID || chirps[0].Body != "Hello, #chirpy!" {
+		t.Errorf("list chirps = %+v, want the new chirp by %s", chirps, user.ID)
+	}
+
+	// Without a token the write is refused by the same stack
+	var errResp ErrorResponse
+	if resp := call(t, srv, http.MethodPost, "/api/v1/chirps", "", map[string]string{"body": "Anonymous"}, &errResp); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("anonymous chirp: got status %v want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+	if resp := call(t, srv, http.MethodPatch, "/api/v1/chirps", login.Token, nil, &errResp); resp.StatusCode != http.StatusMethodNotAllowed || errResp.Error == "" {
+		t.Errorf("PATCH /api/v1/chirps: got status %v and %+v, want a JSON 405", resp.StatusCode, errResp)
+	}
+}
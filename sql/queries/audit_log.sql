@@ -0,0 +1,17 @@
+-- name: CreateAuditLogEntry :exec
+INSERT INTO audit_log (id, created_at, actor_id, actor_token, action, target, request_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: GetAuditLog :many
+SELECT * FROM audit_log
+WHERE (sqlc.narg(action)::text IS NULL OR action = sqlc.narg(action))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
\ No newline at end of file
@@ -0,0 +1,26 @@
+-- name: CreateBookmark :exec
+INSERT INTO bookmarks (user_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: DeleteBookmark :exec
+DELETE FROM bookmarks
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: GetBookmarkedChirps :many
+-- Most recently bookmarked first. Bookmarks of deleted chirps, and of chirps
+-- the user may no longer see, stay in the table but are skipped.
+SELECT sqlc.embed(chirps), COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count
+FROM bookmarks
+INNER JOIN chirps ON chirps.id = bookmarks.chirp_id
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE bookmarks.user_id = $1 AND chirps.deleted_at IS NULL
+  AND can_view_user(chirps.user_id, bookmarks.user_id)
+GROUP BY chirps.id, bookmarks.created_at
+ORDER BY bookmarks.created_at DESC, chirps.id DESC
+LIMIT $2 OFFSET $3;
\ No newline at end of file
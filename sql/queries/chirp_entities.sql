@@ -0,0 +1,12 @@
+-- name: AddChirpEntity :exec
+INSERT INTO chirp_entities (chirp_id, type, start_index, end_index, value)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: DeleteChirpEntities :exec
+DELETE FROM chirp_entities
+WHERE chirp_id = $1;
+
+-- name: GetChirpEntities :many
+SELECT chirp_id, type, start_index, end_index, value FROM chirp_entities
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, start_index;
\ No newline at end of file
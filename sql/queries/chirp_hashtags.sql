@@ -0,0 +1,21 @@
+-- name: AddChirpHashtag :exec
+INSERT INTO chirp_hashtags (chirp_id, tag)
+VALUES ($1, $2)
+ON CONFLICT (chirp_id, tag) DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: GetChirpsByHashtag :many
+-- Only chirps viewer_id may see, which is NULL for an anonymous request.
+SELECT sqlc.embed(chirps), COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count
+FROM chirps
+INNER JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirp_hashtags.tag = sqlc.arg(tag) AND chirps.deleted_at IS NULL
+  AND can_view_user(chirps.user_id, sqlc.narg(viewer_id)::uuid)
+GROUP BY chirps.id
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
\ No newline at end of file
@@ -0,0 +1,35 @@
+-- name: LikeChirp :execrows
+INSERT INTO chirp_likes (chirp_id, user_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (chirp_id, user_id) DO NOTHING;
+
+-- name: UnlikeChirp :exec
+DELETE FROM chirp_likes
+WHERE chirp_id = $1 AND user_id = $2;
+
+-- name: GetLikedChirpIDs :many
+SELECT chirp_id FROM chirp_likes
+WHERE user_id = sqlc.arg(user_id)
+  AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetChirpLikers :many
+SELECT users.id, users.email, users.username, users.display_name, users.bio, users.avatar_url, users.is_chirpy_red
+FROM chirp_likes
+INNER JOIN users ON users.id = chirp_likes.user_id
+WHERE chirp_likes.chirp_id = $1
+ORDER BY chirp_likes.created_at ASC, users.id ASC
+LIMIT $2 OFFSET $3;
+
+-- name: GetUserLikeStats :one
+-- Likes on deleted chirps are not counted either way.
+SELECT
+    (SELECT COUNT(*) FROM chirp_likes
+     INNER JOIN chirps ON chirps.id = chirp_likes.chirp_id
+     WHERE chirps.user_id = $1 AND chirps.deleted_at IS NULL) AS likes_received,
+    (SELECT COUNT(*) FROM chirp_likes
+     INNER JOIN chirps ON chirps.id = chirp_likes.chirp_id
+     WHERE chirp_likes.user_id = $1 AND chirps.deleted_at IS NULL) AS likes_given;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- name: AddChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (chirp_id, user_id) DO NOTHING;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
+
+-- name: GetChirpMentions :many
+SELECT chirp_id, user_id FROM chirp_mentions
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, user_id;
+
+-- name: ResolveMentionHandles :many
+SELECT id, username AS handle
+FROM users
+WHERE username = ANY(sqlc.arg(handles)::text[]);
\ No newline at end of file
@@ -0,0 +1,24 @@
+-- name: CreateChirpReport :execrows
+INSERT INTO chirp_reports (chirp_id, reporter_id, reason, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT (chirp_id, reporter_id) DO NOTHING;
+
+-- name: GetReportedChirps :many
+SELECT sqlc.embed(chirps), COUNT(*) AS report_count,
+    ARRAY_REMOVE(ARRAY_AGG(chirp_reports.reason ORDER BY chirp_reports.created_at), '')::text[] AS reasons,
+    MIN(chirp_reports.created_at)::timestamp AS first_reported_at
+FROM chirp_reports
+INNER JOIN chirps ON chirps.id = chirp_reports.chirp_id
+WHERE chirp_reports.resolved_at IS NULL AND chirps.deleted_at IS NULL
+GROUP BY chirps.id
+ORDER BY report_count DESC, first_reported_at ASC, chirps.id ASC
+LIMIT $1 OFFSET $2;
+
+-- name: ResolveChirpReports :execrows
+UPDATE chirp_reports SET resolved_at = NOW()
+WHERE chirp_id = $1 AND resolved_at IS NULL;
\ No newline at end of file
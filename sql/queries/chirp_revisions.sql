@@ -0,0 +1,5 @@
+-- name: GetChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2 OFFSET $3;
\ No newline at end of file
@@ -1,30 +1,288 @@
+-- name: AddChirpViews :exec
+-- Adds views[i] to the view count of chirp_ids[i], for a whole batch in
+-- one statement. updated_at is left alone: a view is not an edit.
+UPDATE chirps SET view_count = chirps.view_count + views.n
+FROM unnest(sqlc.arg(chirp_ids)::uuid[], sqlc.arg(views)::bigint[]) AS views(chirp_id, n)
+WHERE chirps.id = views.chirp_id;
+
+-- name: CountChirps :one
+-- Chirps that are not deleted, for the admin metrics page.
+SELECT COUNT(*) FROM chirps
+WHERE deleted_at IS NULL;
+
+-- name: CountChirpsWithMedia :one
+-- Chirps, deleted or not, whose image is media_url, so an uploaded file
+-- still shown by one is not removed.
+SELECT COUNT(*) FROM chirps
+WHERE media_url = $1;
+
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_chirp_id, media_url, media_alt)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: CreateChirpAt :one
+-- CreateChirp with a given creation time, for backdated sample data.
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_chirp_id)
+VALUES (
+    gen_random_uuid(),
+    sqlc.arg(created_at),
+    sqlc.arg(created_at),
+    sqlc.arg(body),
+    sqlc.arg(user_id),
+    sqlc.arg(parent_chirp_id)
 )
 RETURNING *;
 
+-- name: CreateChirps :many
+-- A single statement, so either every body is stored or none is. Each chirp
+-- is a microsecond later than the one before it to keep the given order.
+INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+SELECT gen_random_uuid(),
+    NOW() + bodies.n * INTERVAL '1 microsecond',
+    NOW() + bodies.n * INTERVAL '1 microsecond',
+    bodies.body,
+    sqlc.arg(user_id)
+FROM unnest(sqlc.arg(bodies)::text[]) WITH ORDINALITY AS bodies(body, n)
+RETURNING *;
+
 -- name: GetChirps :many
-SELECT * FROM chirps
-ORDER BY created_at ASC;
+-- Newest first. The ID breaks ties between chirps created in the same
+-- microsecond, such as a bulk insert, so the order is always the same.
+SELECT sqlc.embed(chirps), COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count
+FROM chirps
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirps.deleted_at IS NULL
+  AND (sqlc.narg(created_from)::timestamp IS NULL OR chirps.created_at >= sqlc.narg(created_from))
+  AND (sqlc.narg(created_before)::timestamp IS NULL OR chirps.created_at < sqlc.narg(created_before))
+GROUP BY chirps.id
+ORDER BY chirps.created_at DESC, chirps.id DESC;
 
 -- name: GetChirpByID :one
-SELECT * FROM chirps
-WHERE id = $1;
+SELECT sqlc.embed(chirps), COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count
+FROM chirps
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+GROUP BY chirps.id;
+
+-- name: GetChirpByIDWithAuthor :one
+-- GetChirpByID plus the author's public profile.
+SELECT sqlc.embed(chirps), COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count,
+    users.email AS author_email, users.username AS author_username, users.display_name AS author_display_name, users.bio AS author_bio, users.avatar_url AS author_avatar_url, users.is_chirpy_red AS author_is_chirpy_red,
+    users.deleted_at IS NOT NULL AS author_deleted
+FROM chirps
+INNER JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+GROUP BY chirps.id, users.id;
 
 -- name: DeleteAllChirps :exec
 DELETE FROM chirps;
 
 -- name: DeleteChirp :exec
+-- Soft delete: the row stays for moderation but every read query skips it.
+-- Replies are detached and the author's pin cleared as a hard delete's
+-- ON DELETE SET NULL would.
+WITH deleted AS (
+    UPDATE chirps SET deleted_at = NOW(), updated_at = NOW()
+    WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+    RETURNING chirps.id
+), unpinned AS (
+    UPDATE users SET pinned_chirp_id = NULL
+    WHERE pinned_chirp_id IN (SELECT id FROM deleted)
+)
+UPDATE chirps SET parent_chirp_id = NULL
+WHERE parent_chirp_id IN (SELECT id FROM deleted);
+
+-- name: DeleteUserChirpsBefore :many
+-- DeleteChirp for each of the user's chirps created before the given time,
+-- in one statement. A reply deleted with its parent is left attached, so no
+-- row is updated twice. Returns the IDs of the deleted chirps.
+WITH deleted AS (
+    UPDATE chirps SET deleted_at = NOW(), updated_at = NOW()
+    WHERE chirps.user_id = sqlc.arg(user_id)
+      AND chirps.created_at < sqlc.arg(before)
+      AND chirps.deleted_at IS NULL
+    RETURNING chirps.id
+), unpinned AS (
+    UPDATE users SET pinned_chirp_id = NULL
+    WHERE pinned_chirp_id IN (SELECT id FROM deleted)
+), detached AS (
+    UPDATE chirps SET parent_chirp_id = NULL
+    WHERE parent_chirp_id IN (SELECT id FROM deleted)
+      AND id NOT IN (SELECT id FROM deleted)
+)
+SELECT id FROM deleted;
+
+-- name: DeleteUserChirpsByIDs :many
+-- DeleteChirp for each of chirp_ids that is one of the user's chirps, in
+-- one statement. A reply deleted with its parent is left attached, so no
+-- row is updated twice. Returns the IDs of the deleted chirps.
+WITH deleted AS (
+    UPDATE chirps SET deleted_at = NOW(), updated_at = NOW()
+    WHERE chirps.id = ANY(sqlc.arg(chirp_ids)::uuid[])
+      AND chirps.user_id = sqlc.arg(user_id)
+      AND chirps.deleted_at IS NULL
+    RETURNING chirps.id
+), unpinned AS (
+    UPDATE users SET pinned_chirp_id = NULL
+    WHERE pinned_chirp_id IN (SELECT id FROM deleted)
+), detached AS (
+    UPDATE chirps SET parent_chirp_id = NULL
+    WHERE parent_chirp_id IN (SELECT id FROM deleted)
+      AND id NOT IN (SELECT id FROM deleted)
+)
+SELECT id FROM deleted;
+
+-- name: CountChirpsBefore :one
+-- Chirps, deleted or not, that a purge with this cutoff would remove.
+SELECT COUNT(*) FROM chirps
+WHERE created_at < sqlc.arg(before);
+
+-- name: PurgeChirpsBefore :execrows
+-- Hard-deletes up to limit of the oldest chirps created before the cutoff,
+-- including soft-deleted ones. Their likes, bookmarks and other rows go
+-- with them by cascade, and replies and pins are cleared by the foreign
+-- keys.
 DELETE FROM chirps
-WHERE id = $1;
+WHERE id IN (
+    SELECT id FROM chirps
+    WHERE created_at < sqlc.arg(before)
+    ORDER BY created_at
+    LIMIT sqlc.arg('limit')
+);
 
 -- name: GetChirpsByUserID :many
-SELECT * FROM chirps
+SELECT sqlc.embed(chirps), COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count
+FROM chirps
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirps.user_id = sqlc.arg(user_id) AND chirps.deleted_at IS NULL
+  AND (sqlc.narg(created_from)::timestamp IS NULL OR chirps.created_at >= sqlc.narg(created_from))
+  AND (sqlc.narg(created_before)::timestamp IS NULL OR chirps.created_at < sqlc.narg(created_before))
+GROUP BY chirps.id
+ORDER BY chirps.created_at DESC, chirps.id DESC;
+
+-- name: GetChirpsSince :many
+-- Live streams are anonymous, so only public accounts' chirps are included.
+SELECT sqlc.embed(chirps), COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count
+FROM chirps
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE (chirps.created_at, chirps.id) > (
+        SELECT since.created_at, since.id FROM chirps AS since WHERE since.id = sqlc.arg(since_id)
+    )
+  AND (sqlc.narg(author_id)::uuid IS NULL OR chirps.user_id = sqlc.narg(author_id))
+  AND chirps.deleted_at IS NULL
+  AND can_view_user(chirps.user_id, NULL)
+GROUP BY chirps.id
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: GetChirpsWithAuthors :many
+-- GetChirps, optionally limited to one author, plus each author's public
+-- profile.
+SELECT sqlc.embed(chirps), COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count,
+    users.email AS author_email, users.username AS author_username, users.display_name AS author_display_name, users.bio AS author_bio, users.avatar_url AS author_avatar_url, users.is_chirpy_red AS author_is_chirpy_red,
+    users.deleted_at IS NOT NULL AS author_deleted
+FROM chirps
+INNER JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirps.deleted_at IS NULL
+  AND (sqlc.narg(author_id)::uuid IS NULL OR chirps.user_id = sqlc.narg(author_id))
+  AND (sqlc.narg(created_from)::timestamp IS NULL OR chirps.created_at >= sqlc.narg(created_from))
+  AND (sqlc.narg(created_before)::timestamp IS NULL OR chirps.created_at < sqlc.narg(created_before))
+GROUP BY chirps.id, users.id
+ORDER BY chirps.created_at DESC, chirps.id DESC;
+
+-- name: GetChirpReplies :many
+-- Only replies viewer_id may see, which is NULL for an anonymous request.
+SELECT sqlc.embed(chirps), COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count
+FROM chirps
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirps.parent_chirp_id = sqlc.arg(parent_id)::uuid AND chirps.deleted_at IS NULL
+  AND can_view_user(chirps.user_id, sqlc.narg(viewer_id)::uuid)
+GROUP BY chirps.id
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetFeed :many
+SELECT sqlc.embed(chirps), COUNT(chirp_likes.user_id) AS like_count,
+    (SELECT COUNT(*) FROM chirps AS replies WHERE replies.parent_chirp_id = chirps.id AND replies.deleted_at IS NULL) AS reply_count
+FROM chirps
+LEFT JOIN follows ON follows.followee_id = chirps.user_id
+    AND follows.follower_id = sqlc.arg(user_id)
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE (follows.follower_id IS NOT NULL
+       OR (sqlc.arg(include_self)::boolean AND chirps.user_id = sqlc.arg(user_id)))
+  AND NOT EXISTS (
+      SELECT 1 FROM mutes
+      WHERE mutes.muter_id = sqlc.arg(user_id) AND mutes.muted_id = chirps.user_id
+  )
+  AND can_view_user(chirps.user_id, sqlc.arg(user_id))
+  AND chirps.deleted_at IS NULL
+GROUP BY chirps.id
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: HasRecentDuplicateChirp :one
+-- Whether the user posted the same body, in reply to the same chirp or to
+-- none, since the given time. chirps_user_id_body_hash_idx serves it.
+SELECT EXISTS (
+    SELECT 1 FROM chirps
+    WHERE user_id = sqlc.arg(user_id)
+      AND md5(body) = md5(sqlc.arg(body))
+      AND body = sqlc.arg(body)
+      AND parent_chirp_id IS NOT DISTINCT FROM sqlc.narg(parent_chirp_id)
+      AND created_at >= sqlc.arg(since)
+      AND deleted_at IS NULL
+);
+
+-- name: UpdateChirp :one
+-- Records the current body as a revision in the same statement, so an edit
+-- never lands without its history.
+WITH previous AS (
+    INSERT INTO chirp_revisions (id, chirp_id, body, created_at)
+    SELECT gen_random_uuid(), chirps.id, chirps.body, chirps.updated_at
+    FROM chirps
+    WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+)
+UPDATE chirps SET body = $2, updated_at = NOW()
+WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+RETURNING *;
+
+-- name: GetNthNewestChirpTime :one
+-- When the user's chirp with Offset newer ones was posted, deleted or not,
+-- so the daily quota can tell when a slot frees up.
+SELECT created_at FROM chirps
 WHERE user_id = $1
-ORDER BY created_at ASC;
\ No newline at end of file
+ORDER BY created_at DESC
+OFFSET $2
+LIMIT 1;
+
+-- name: GetUserChirpStats :one
+SELECT COUNT(*) AS chirp_count, MIN(created_at) AS first_chirp_at, MAX(created_at) AS last_chirp_at
+FROM chirps
+WHERE user_id = $1 AND deleted_at IS NULL;
+
+-- name: GetUserChirpsAfter :many
+-- Keyset pagination over a user's chirps in creation order, for exports.
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id) AND deleted_at IS NULL
+  AND (created_at, id) > (sqlc.arg(after_created_at)::timestamp, sqlc.arg(after_id)::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
\ No newline at end of file
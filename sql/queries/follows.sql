@@ -0,0 +1,81 @@
+-- name: CreateFollow :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: DeleteFollow :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: CountFollowers :one
+SELECT COUNT(*) FROM follows
+WHERE followee_id = $1;
+
+-- name: CountFollowing :one
+SELECT COUNT(*) FROM follows
+WHERE follower_id = $1;
+
+-- name: GetFollowers :many
+SELECT users.id, users.email, users.username, users.display_name, users.bio, users.avatar_url, users.is_chirpy_red
+FROM follows
+INNER JOIN users ON users.id = follows.follower_id
+WHERE follows.followee_id = $1
+ORDER BY follows.created_at ASC, users.id ASC
+LIMIT $2 OFFSET $3;
+
+-- name: GetFollowing :many
+SELECT users.id, users.email, users.username, users.display_name, users.bio, users.avatar_url, users.is_chirpy_red
+FROM follows
+INNER JOIN users ON users.id = follows.followee_id
+WHERE follows.follower_id = $1
+ORDER BY follows.created_at ASC, users.id ASC
+LIMIT $2 OFFSET $3;
+
+-- name: CreateFollowRequest :execrows
+INSERT INTO follow_requests (requester_id, target_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (requester_id, target_id) DO NOTHING;
+
+-- name: DeleteFollowRequest :execrows
+DELETE FROM follow_requests
+WHERE requester_id = $1 AND target_id = $2;
+
+-- name: ApproveFollowRequest :execrows
+-- Turns the request into a follow in one statement. No row is affected
+-- when there was no such request.
+WITH approved AS (
+    DELETE FROM follow_requests
+    WHERE requester_id = $1 AND target_id = $2
+    RETURNING requester_id, target_id
+)
+INSERT INTO follows (follower_id, followee_id, created_at)
+SELECT approved.requester_id, approved.target_id, NOW() FROM approved
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: ApproveAllFollowRequests :exec
+-- For an account going public, which has nothing left to approve.
+WITH approved AS (
+    DELETE FROM follow_requests
+    WHERE target_id = $1
+    RETURNING requester_id, target_id
+)
+INSERT INTO follows (follower_id, followee_id, created_at)
+SELECT approved.requester_id, approved.target_id, NOW() FROM approved
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: GetFollowRequests :many
+-- Pending requests to follow target_id, oldest first.
+SELECT users.id, users.email, users.username, users.display_name, users.bio, users.avatar_url, users.is_chirpy_red, follow_requests.created_at AS requested_at
+FROM follow_requests
+INNER JOIN users ON users.id = follow_requests.requester_id
+WHERE follow_requests.target_id = $1
+ORDER BY follow_requests.created_at ASC, users.id ASC
+LIMIT $2 OFFSET $3;
\ No newline at end of file
@@ -0,0 +1,29 @@
+-- name: ClaimIdempotencyKey :one
+-- Reserves key for a new request. A live key returns no row; an expired one
+-- the cleanup job has not removed yet is taken over.
+INSERT INTO idempotency_keys (user_id, key, created_at, expires_at, request_hash)
+VALUES ($1, $2, NOW(), $3, $4)
+ON CONFLICT (user_id, key) DO UPDATE
+SET created_at = NOW(),
+    expires_at = EXCLUDED.expires_at,
+    request_hash = EXCLUDED.request_hash,
+    response = NULL
+WHERE idempotency_keys.expires_at <= NOW()
+RETURNING *;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE expires_at <= NOW();
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE user_id = $1 AND key = $2;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE user_id = $1 AND key = $2;
+
+-- name: SetIdempotencyKeyResponse :exec
+UPDATE idempotency_keys
+SET response = $3
+WHERE user_id = $1 AND key = $2;
\ No newline at end of file
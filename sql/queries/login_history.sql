@@ -0,0 +1,20 @@
+-- name: CreateLoginHistoryEntry :exec
+INSERT INTO login_history (id, user_id, created_at, ip, user_agent)
+VALUES (gen_random_uuid(), $1, NOW(), $2, $3);
+
+-- name: GetLoginHistory :many
+SELECT * FROM login_history
+WHERE user_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
+
+-- name: PruneLoginHistory :execrows
+-- Deletes all but each user's newest keep entries.
+DELETE FROM login_history
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC, id DESC) AS n
+        FROM login_history
+    ) ranked
+    WHERE n > sqlc.arg(keep)
+);
\ No newline at end of file
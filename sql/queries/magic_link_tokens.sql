@@ -0,0 +1,14 @@
+-- name: ConsumeMagicLinkToken :one
+-- Deletes the unexpired token hashing to token_hash and returns its user,
+-- so of two requests racing with the same link only one gets a row.
+DELETE FROM magic_link_tokens
+WHERE token_hash = $1 AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: CreateMagicLinkToken :exec
+INSERT INTO magic_link_tokens (token_hash, user_id, created_at, expires_at)
+VALUES ($1, $2, NOW(), $3);
+
+-- name: DeleteExpiredMagicLinkTokens :execrows
+DELETE FROM magic_link_tokens
+WHERE expires_at <= NOW();
\ No newline at end of file
@@ -0,0 +1,36 @@
+-- name: CreateNotification :exec
+INSERT INTO notifications (id, created_at, user_id, actor_id, type, chirp_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+);
+
+-- name: GetNotifications :many
+SELECT notifications.id, notifications.created_at, notifications.type, notifications.chirp_id, notifications.read_at,
+    users.id AS actor_id, users.email AS actor_email, users.username AS actor_username, users.display_name AS actor_display_name, users.bio AS actor_bio, users.avatar_url AS actor_avatar_url, users.is_chirpy_red AS actor_is_chirpy_red
+FROM notifications
+INNER JOIN users ON users.id = notifications.actor_id
+LEFT JOIN chirps ON chirps.id = notifications.chirp_id
+WHERE notifications.user_id = $1 AND chirps.deleted_at IS NULL
+ORDER BY notifications.created_at DESC, notifications.id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+LEFT JOIN chirps ON chirps.id = notifications.chirp_id
+WHERE notifications.user_id = $1 AND notifications.read_at IS NULL
+  AND chirps.deleted_at IS NULL;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications SET read_at = NOW()
+WHERE user_id = sqlc.arg(user_id)
+  AND id = ANY(sqlc.arg(ids)::uuid[])
+  AND read_at IS NULL;
\ No newline at end of file
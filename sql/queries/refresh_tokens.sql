@@ -1,15 +1,30 @@
+-- name: CountActiveRefreshTokens :one
+-- Tokens that could still be exchanged for an access token.
+SELECT COUNT(*) FROM refresh_tokens
+WHERE expires_at > NOW() AND revoked_at IS NULL;
+
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, remember_me, scopes)
 VALUES (
     $1,
     NOW(),
     NOW(),
     $2,
     $3,
-    NULL
+    NULL,
+    $4,
+    $5,
+    $6,
+    $7
 )
 RETURNING *;
 
+-- name: GetRefreshToken :one
+-- Returns the token whether or not it is still usable, so a revoked one
+-- can be told apart from one that never existed.
+SELECT * FROM refresh_tokens
+WHERE token = $1;
+
 -- name: GetUserFromRefreshToken :one
 SELECT users.* FROM users
 INNER JOIN refresh_tokens ON users.id = refresh_tokens.user_id
@@ -17,10 +32,30 @@ WHERE refresh_tokens.token = $1
   AND refresh_tokens.expires_at > NOW()
   AND refresh_tokens.revoked_at IS NULL;
 
--- name: RevokeRefreshToken :exec
+-- name: GetUserSessions :many
+-- Session metadata only; the token itself is a credential and stays here.
+SELECT created_at, expires_at FROM refresh_tokens
+WHERE user_id = $1 AND expires_at > NOW() AND revoked_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: RevokeRefreshToken :execrows
+-- Affects no rows if the token was already revoked, so only one of two
+-- concurrent rotations of the same token can succeed.
 UPDATE refresh_tokens 
 SET revoked_at = NOW(), updated_at = NOW()
-WHERE token = $1;
+WHERE token = $1 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :execrows
+-- Revokes every token rotated from the same login.
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+-- Signs the user out everywhere, e.g. after a password change.
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
 
 -- name: DeleteAllRefreshTokens :exec
 DELETE FROM refresh_tokens;
\ No newline at end of file
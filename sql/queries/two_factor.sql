@@ -0,0 +1,43 @@
+-- name: SetPendingTOTPSecret :execrows
+-- Starts, or starts over, setting up two-factor authentication with a new
+-- secret. Once it is enabled the secret stays, and no row is affected.
+INSERT INTO user_totp (user_id, secret, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret,
+    created_at = EXCLUDED.created_at
+WHERE user_totp.enabled_at IS NULL;
+
+-- name: GetUserTOTP :one
+SELECT * FROM user_totp
+WHERE user_id = $1;
+
+-- name: EnableUserTOTP :execrows
+-- Turns on two-factor authentication with the pending secret, unless a new
+-- setup replaced it in the meantime.
+UPDATE user_totp
+SET enabled_at = NOW()
+WHERE user_id = $1
+  AND secret = $2
+  AND enabled_at IS NULL;
+
+-- name: ReplaceBackupCodes :exec
+-- Swaps the user's backup codes for a new set in one statement, so the old
+-- codes stop working exactly when the new ones start.
+WITH deleted_backup_codes AS (
+    DELETE FROM backup_codes WHERE user_id = sqlc.arg(user_id)
+)
+INSERT INTO backup_codes (id, user_id, code_hash, created_at)
+SELECT gen_random_uuid(), sqlc.arg(user_id), code_hash, NOW()
+FROM unnest(sqlc.arg(code_hashes)::text[]) AS code_hash;
+
+-- name: GetUnusedBackupCodes :many
+SELECT id, code_hash FROM backup_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: UseBackupCode :execrows
+-- Burns a backup code. Of two logins racing with the same code only one
+-- affects the row.
+UPDATE backup_codes
+SET used_at = NOW()
+WHERE id = $1 AND used_at IS NULL;
\ No newline at end of file
@@ -1,29 +1,254 @@
+-- name: CreateOAuthUser :one
+-- Users signing in through an identity provider get no password.
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username, auth_provider)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    NULL,
+    $2,
+    $3
+)
+RETURNING *;
+
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username, display_name, bio)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5
 )
 RETURNING *;
 
+-- name: CountUsers :one
+SELECT COUNT(*) FROM users;
+
+-- name: CountUsersWithAvatar :one
+SELECT COUNT(*) FROM users
+WHERE avatar_url = $1;
+
 -- name: DeleteAllUsers :exec
 DELETE FROM users;
 
+-- name: DeleteUserKeepChirps :execrows
+-- Replaces the user with a tombstone that their chirps stay attached to.
+-- Everything identifying them is scrubbed, with the unique email and
+-- username rewritten from the ID (the username cut to 30 characters like
+-- any other), and the rest of their data goes as if the row had been
+-- deleted.
+WITH deleted_refresh_tokens AS (
+    DELETE FROM refresh_tokens WHERE user_id = $1
+), deleted_api_keys AS (
+    DELETE FROM api_keys WHERE user_id = $1
+), deleted_login_history AS (
+    DELETE FROM login_history WHERE user_id = $1
+), deleted_magic_link_tokens AS (
+    DELETE FROM magic_link_tokens WHERE user_id = $1
+), deleted_user_totp AS (
+    DELETE FROM user_totp WHERE user_id = $1
+), deleted_backup_codes AS (
+    DELETE FROM backup_codes WHERE user_id = $1
+), deleted_idempotency_keys AS (
+    DELETE FROM idempotency_keys WHERE user_id = $1
+), deleted_likes AS (
+    DELETE FROM chirp_likes WHERE user_id = $1
+), deleted_bookmarks AS (
+    DELETE FROM bookmarks WHERE user_id = $1
+), deleted_reports AS (
+    DELETE FROM chirp_reports WHERE reporter_id = $1
+), deleted_follows AS (
+    DELETE FROM follows WHERE follower_id = $1 OR followee_id = $1
+), deleted_follow_requests AS (
+    DELETE FROM follow_requests WHERE requester_id = $1 OR target_id = $1
+), deleted_mutes AS (
+    DELETE FROM mutes WHERE muter_id = $1 OR muted_id = $1
+), deleted_notifications AS (
+    DELETE FROM notifications WHERE user_id = $1 OR actor_id = $1
+)
+UPDATE users
+SET email = id::text || '@deleted.invalid',
+    username = 'deleted_' || LEFT(REPLACE(id::text, '-', ''), 22),
+    hashed_password = NULL,
+    display_name = NULL,
+    bio = NULL,
+    avatar_url = NULL,
+    pinned_chirp_id = NULL,
+    is_chirpy_red = FALSE,
+    is_admin = FALSE,
+    is_private = FALSE,
+    banned_at = NULL,
+    banned_until = NULL,
+    ban_reason = NULL,
+    pending_email = NULL,
+    pending_email_token_hash = NULL,
+    pending_email_expires_at = NULL,
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+  AND deleted_at IS NULL;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
 -- name: GetUserByEmail :one
 SELECT * FROM users
 WHERE email = $1;
 
+-- name: UsernameExists :one
+-- Whether any account, banned or not, holds username. Deleted accounts'
+-- usernames are rewritten from their ID, so they never match a valid one.
+SELECT EXISTS (SELECT 1 FROM users WHERE username = $1);
+
+-- name: CanViewUser :one
+-- Whether viewer_id, NULL for an anonymous request, may see author_id's
+-- chirps and profile. An unknown author is never visible.
+SELECT COALESCE(can_view_user(sqlc.arg(author_id)::uuid, sqlc.narg(viewer_id)::uuid), FALSE)::boolean AS visible;
+
+-- name: GetHiddenAuthorIDs :many
+-- The authors among author_ids whose chirps viewer_id may not see.
+SELECT author_id::uuid
+FROM unnest(sqlc.arg(author_ids)::uuid[]) AS author_id
+WHERE NOT can_view_user(author_id, sqlc.narg(viewer_id)::uuid);
+
+-- name: GetAccountStatus :one
+-- Whether the user's account was deleted, and whether they are banned
+-- right now.
+SELECT deleted_at IS NOT NULL AS deleted,
+    (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))::boolean AS banned
+FROM users
+WHERE id = $1;
+
+-- name: ListUsers :many
+-- The user directory: accounts that are neither deleted nor banned right
+-- now, oldest first, optionally only those with or without Chirpy Red.
+-- Only profile columns are read, never the password hash.
+SELECT id, created_at, email, username, display_name, bio, avatar_url, is_chirpy_red, is_private
+FROM users
+WHERE deleted_at IS NULL
+  AND NOT (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))
+  AND (sqlc.narg(is_chirpy_red)::boolean IS NULL OR is_chirpy_red = sqlc.narg(is_chirpy_red))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: BanUser :execrows
+-- A NULL banned_until bans the user until they are unbanned. Banning a
+-- banned user replaces their ban.
+UPDATE users
+SET banned_at = NOW(),
+    banned_until = sqlc.narg(banned_until),
+    ban_reason = sqlc.narg(ban_reason),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id);
+
+-- name: UnbanUser :execrows
+UPDATE users
+SET banned_at = NULL,
+    banned_until = NULL,
+    ban_reason = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserAdmin :execrows
+UPDATE users
+SET is_admin = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserAvatar :one
+UPDATE users
+SET avatar_url = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserPinnedChirp :execrows
+-- Only a live chirp of the user's own can be pinned; otherwise no row is
+-- updated.
+UPDATE users
+SET pinned_chirp_id = chirps.id,
+    updated_at = NOW()
+FROM chirps
+WHERE users.id = sqlc.arg(user_id)
+  AND chirps.id = sqlc.arg(chirp_id)
+  AND chirps.user_id = users.id
+  AND chirps.deleted_at IS NULL;
+
+-- name: SetUserPrivate :one
+UPDATE users
+SET is_private = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserPendingEmail :one
+-- Starts changing the user's email to pending_email, replacing any change
+-- already waiting. Nothing changes for logins until the token sent to the
+-- new address is confirmed.
+UPDATE users
+SET pending_email = sqlc.arg(pending_email)::text,
+    pending_email_token_hash = sqlc.arg(token_hash)::text,
+    pending_email_expires_at = sqlc.arg(expires_at)::timestamp,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ConfirmPendingEmail :one
+-- Makes the pending email of the user whose unexpired token hashes to
+-- token_hash their email, returning the address it replaced. The token is
+-- cleared, so it only works once.
+WITH old AS (
+    SELECT id, email FROM users
+    WHERE pending_email_token_hash = sqlc.arg(token_hash)::text
+      AND pending_email_expires_at > NOW()
+    FOR UPDATE
+)
+UPDATE users
+SET email = users.pending_email,
+    pending_email = NULL,
+    pending_email_token_hash = NULL,
+    pending_email_expires_at = NULL,
+    updated_at = NOW()
+FROM old
+WHERE users.id = old.id
+RETURNING users.id, users.email, old.email AS old_email;
+
+-- name: UnpinChirp :exec
+UPDATE users
+SET pinned_chirp_id = NULL,
+    updated_at = NOW()
+WHERE pinned_chirp_id = $1;
+
 -- name: UpdateUser :one
+-- Fields passed as NULL are left unchanged. An empty display_name or bio
+-- clears it. When expected_updated_at is set, the row is only updated if it
+-- has not changed since then; otherwise no row is returned.
 UPDATE users 
-SET email = $2, 
-    hashed_password = $3, 
+SET email = COALESCE(sqlc.narg(email), email), 
+    hashed_password = COALESCE(sqlc.narg(hashed_password), hashed_password), 
+    username = COALESCE(sqlc.narg(username), username),
+    display_name = NULLIF(COALESCE(sqlc.narg(display_name), display_name), ''),
+    bio = NULLIF(COALESCE(sqlc.narg(bio), bio), ''),
     updated_at = NOW()
 WHERE id = $1
+  AND (sqlc.narg(expected_updated_at)::timestamp IS NULL OR updated_at = sqlc.narg(expected_updated_at))
 RETURNING *;
 
+-- name: RehashUserPassword :execrows
+-- Replaces the user's password hash with new_hash, a hash of the same
+-- password at another cost, unless the password changed since old_hash was
+-- read. updated_at is left alone since nothing the user sees changed.
+UPDATE users
+SET hashed_password = sqlc.arg(new_hash)::text
+WHERE id = sqlc.arg(id)
+  AND hashed_password = sqlc.arg(old_hash)::text;
+
 -- name: UpgradeUserToChirpyRed :exec
 UPDATE users 
 SET is_chirpy_red = TRUE, 
@@ -0,0 +1,44 @@
+-- name: CreateWebhookEvent :one
+INSERT INTO webhook_events (id, created_at, updated_at, source, event, payload, next_attempt_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetDueWebhookEvents :many
+-- Pending events whose next attempt is due, oldest first.
+SELECT * FROM webhook_events
+WHERE status = 'pending' AND next_attempt_at <= NOW()
+ORDER BY next_attempt_at ASC, id ASC
+LIMIT $1;
+
+-- name: GetWebhookEvents :many
+SELECT * FROM webhook_events
+WHERE (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: MarkWebhookEventFailed :exec
+-- Records a failed attempt. The caller decides whether the event is retried
+-- (status 'pending' with a later next_attempt_at) or dead-lettered.
+UPDATE webhook_events
+SET attempts = attempts + 1,
+    status = $2,
+    next_attempt_at = $3,
+    last_error = $4,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkWebhookEventProcessed :exec
+UPDATE webhook_events
+SET attempts = attempts + 1,
+    status = 'processed',
+    last_error = '',
+    updated_at = NOW()
+WHERE id = $1;
\ No newline at end of file
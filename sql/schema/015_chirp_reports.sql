@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE chirp_reports (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    PRIMARY KEY (chirp_id, reporter_id)
+);
+
+-- +goose Down
+DROP TABLE chirp_reports;
\ No newline at end of file
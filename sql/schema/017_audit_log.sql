@@ -0,0 +1,17 @@
+-- +goose Up
+-- actor_id is deliberately not a foreign key: entries must outlive the
+-- users they mention, including a full reset.
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    actor_id UUID,
+    actor_token TEXT,
+    action TEXT NOT NULL,
+    target TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_log_action_created_at_idx ON audit_log (action, created_at DESC);
+
+-- +goose Down
+DROP TABLE audit_log;
\ No newline at end of file
@@ -0,0 +1,39 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN username TEXT;
+
+-- Existing users get their email's local part, sanitised the same way as
+-- defaultUsername, with _2, _3, ... appended on collisions or reserved words
+-- (see reservedUsernames). Oldest accounts get first pick.
+-- +goose StatementBegin
+DO $$
+DECLARE
+    u RECORD;
+    base TEXT;
+    candidate TEXT;
+    n INTEGER;
+BEGIN
+    FOR u IN SELECT id, email FROM users ORDER BY created_at, id LOOP
+        base := LEFT(REGEXP_REPLACE(LOWER(SPLIT_PART(u.email, '@', 1)), '[^a-z0-9_]', '_', 'g'), 25);
+        IF LENGTH(base) < 3 THEN
+            base := base || '_user';
+        END IF;
+        candidate := base;
+        n := 1;
+        WHILE candidate = ANY (ARRAY[
+            'admin', 'administrator', 'api', 'chirpy', 'help', 'login', 'logout',
+            'me', 'moderator', 'root', 'settings', 'signup', 'support', 'system'
+        ]) OR EXISTS (SELECT 1 FROM users WHERE username = candidate) LOOP
+            n := n + 1;
+            candidate := base || '_' || n;
+        END LOOP;
+        UPDATE users SET username = candidate WHERE id = u.id;
+    END LOOP;
+END $$;
+-- +goose StatementEnd
+
+ALTER TABLE users ALTER COLUMN username SET NOT NULL;
+CREATE UNIQUE INDEX users_username_key ON users (username);
+
+-- +goose Down
+DROP INDEX users_username_key;
+ALTER TABLE users DROP COLUMN username;
\ No newline at end of file
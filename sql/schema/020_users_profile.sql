@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN display_name TEXT;
+ALTER TABLE users ADD COLUMN bio TEXT;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN bio;
+ALTER TABLE users DROP COLUMN display_name;
\ No newline at end of file
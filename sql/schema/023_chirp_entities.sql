@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE chirp_entities (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    start_index INTEGER NOT NULL,
+    end_index INTEGER NOT NULL,
+    value TEXT NOT NULL,
+    PRIMARY KEY (chirp_id, start_index)
+);
+
+-- +goose Down
+DROP TABLE chirp_entities;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN pinned_chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN pinned_chirp_id;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE INDEX chirps_user_id_body_hash_idx ON chirps (user_id, md5(body), created_at);
+
+-- +goose Down
+DROP INDEX chirps_user_id_body_hash_idx;
\ No newline at end of file
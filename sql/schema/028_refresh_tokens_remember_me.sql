@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE refresh_tokens ADD COLUMN remember_me BOOLEAN NOT NULL DEFAULT false;
+
+-- Tokens issued so far were long-lived, so they keep rotating as such
+UPDATE refresh_tokens SET remember_me = true;
+
+-- +goose Down
+ALTER TABLE refresh_tokens DROP COLUMN remember_me;
\ No newline at end of file
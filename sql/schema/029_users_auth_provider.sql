@@ -0,0 +1,11 @@
+-- +goose Up
+-- Users who sign in through an identity provider have no password
+ALTER TABLE users ALTER COLUMN hashed_password DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN hashed_password DROP DEFAULT;
+ALTER TABLE users ADD COLUMN auth_provider TEXT NOT NULL DEFAULT 'password';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN auth_provider;
+UPDATE users SET hashed_password = 'unset' WHERE hashed_password IS NULL;
+ALTER TABLE users ALTER COLUMN hashed_password SET DEFAULT 'unset';
+ALTER TABLE users ALTER COLUMN hashed_password SET NOT NULL;
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- +goose Up
+-- NULL keeps the full access every token had before scopes existed
+ALTER TABLE refresh_tokens ADD COLUMN scopes TEXT[];
+
+-- +goose Down
+ALTER TABLE refresh_tokens DROP COLUMN scopes;
\ No newline at end of file
@@ -0,0 +1,44 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN is_private BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Follows of a private account wait here until its owner approves them
+CREATE TABLE follow_requests (
+    requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (requester_id, target_id),
+    CHECK (requester_id <> target_id)
+);
+
+CREATE INDEX follow_requests_target_id_idx ON follow_requests (target_id, created_at);
+
+ALTER TABLE notifications DROP CONSTRAINT notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check
+    CHECK (type IN ('like', 'reply', 'mention', 'follow', 'follow_request'));
+
+-- The one rule for who may see a user's chirps and profile: everyone for a
+-- public account, and only the owner and approved followers for a private
+-- one. viewer_id is NULL for anonymous requests. Every query that lists
+-- chirps to someone other than their author goes through it.
+-- +goose StatementBegin
+CREATE FUNCTION can_view_user(author_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+    SELECT NOT users.is_private
+        OR (viewer_id IS NOT NULL AND users.id = viewer_id)
+        OR EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = viewer_id AND follows.followee_id = users.id
+        )
+    FROM users
+    WHERE users.id = author_id
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION can_view_user(UUID, UUID);
+DELETE FROM notifications WHERE type = 'follow_request';
+ALTER TABLE notifications DROP CONSTRAINT notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check
+    CHECK (type IN ('like', 'reply', 'mention', 'follow'));
+DROP TABLE follow_requests;
+ALTER TABLE users DROP COLUMN is_private;
\ No newline at end of file
@@ -0,0 +1,42 @@
+-- +goose Up
+-- A user is banned from banned_at until banned_until, or until unbanned
+-- when banned_until is NULL. An expired ban needs no cleanup: everything
+-- that checks for one compares banned_until with NOW().
+ALTER TABLE users ADD COLUMN banned_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN banned_until TIMESTAMP;
+ALTER TABLE users ADD COLUMN ban_reason TEXT;
+
+-- A banned user's chirps and profile are hidden from everyone but them,
+-- on top of the rules for private accounts.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION can_view_user(author_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+    SELECT (viewer_id IS NOT NULL AND users.id = viewer_id)
+        OR ((users.banned_at IS NULL OR users.banned_until <= NOW())
+            AND (NOT users.is_private
+                OR EXISTS (
+                    SELECT 1 FROM follows
+                    WHERE follows.follower_id = viewer_id AND follows.followee_id = users.id
+                )))
+    FROM users
+    WHERE users.id = author_id
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION can_view_user(author_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+    SELECT NOT users.is_private
+        OR (viewer_id IS NOT NULL AND users.id = viewer_id)
+        OR EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = viewer_id AND follows.followee_id = users.id
+        )
+    FROM users
+    WHERE users.id = author_id
+$$;
+-- +goose StatementEnd
+ALTER TABLE users DROP COLUMN ban_reason;
+ALTER TABLE users DROP COLUMN banned_until;
+ALTER TABLE users DROP COLUMN banned_at;
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- +goose Up
+-- A user deleted with their chirps kept becomes a tombstone: the row stays
+-- for the chirps to point at, with everything identifying them scrubbed.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN deleted_at;
\ No newline at end of file
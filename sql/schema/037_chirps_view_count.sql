@@ -0,0 +1,7 @@
+-- +goose Up
+-- Views are counted in memory and added here in batches, so view_count
+-- can trail the real count by up to one flush interval.
+ALTER TABLE chirps ADD COLUMN view_count BIGINT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN view_count;
\ No newline at end of file
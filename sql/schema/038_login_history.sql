@@ -0,0 +1,15 @@
+-- +goose Up
+-- One row per successful login, for users to spot sign-ins that were not
+-- them. The cleanup job keeps only the latest entries for each user.
+CREATE TABLE login_history (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    ip TEXT NOT NULL,
+    user_agent TEXT NOT NULL
+);
+
+CREATE INDEX login_history_user_id_created_at_idx ON login_history (user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE login_history;
\ No newline at end of file
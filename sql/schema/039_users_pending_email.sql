@@ -0,0 +1,14 @@
+-- +goose Up
+-- A changed email waits in pending_email until the link sent to it is
+-- followed. Only the SHA-256 of the link's token is stored.
+ALTER TABLE users ADD COLUMN pending_email TEXT;
+ALTER TABLE users ADD COLUMN pending_email_token_hash TEXT;
+ALTER TABLE users ADD COLUMN pending_email_expires_at TIMESTAMP;
+
+CREATE UNIQUE INDEX users_pending_email_token_hash_idx ON users (pending_email_token_hash);
+
+-- +goose Down
+DROP INDEX users_pending_email_token_hash_idx;
+ALTER TABLE users DROP COLUMN pending_email_expires_at;
+ALTER TABLE users DROP COLUMN pending_email_token_hash;
+ALTER TABLE users DROP COLUMN pending_email;
\ No newline at end of file
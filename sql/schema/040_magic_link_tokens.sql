@@ -0,0 +1,14 @@
+-- +goose Up
+-- One-time login links. Only the SHA-256 of each token is stored; using a
+-- link deletes its row, and the cleanup job removes expired ones.
+CREATE TABLE magic_link_tokens (
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX magic_link_tokens_expires_at_idx ON magic_link_tokens (expires_at);
+
+-- +goose Down
+DROP TABLE magic_link_tokens;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+-- An optional image a chirp links to, with alt text for screen readers.
+-- Both are NULL for chirps without one.
+ALTER TABLE chirps ADD COLUMN media_url TEXT;
+ALTER TABLE chirps ADD COLUMN media_alt TEXT;
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN media_alt;
+ALTER TABLE chirps DROP COLUMN media_url;
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Tombstones left by DeleteUserKeepChirps used the whole ID in their
+-- username, 40 characters where every other username is at most 30. They
+-- are cut to the same 22 characters of the ID the query now uses.
+UPDATE users
+SET username = 'deleted_' || LEFT(REPLACE(id::text, '-', ''), 22)
+WHERE deleted_at IS NOT NULL
+  AND username = 'deleted_' || REPLACE(id::text, '-', '');
+
+-- +goose Down
+UPDATE users
+SET username = 'deleted_' || REPLACE(id::text, '-', '')
+WHERE deleted_at IS NOT NULL
+  AND username = 'deleted_' || LEFT(REPLACE(id::text, '-', ''), 22);
\ No newline at end of file
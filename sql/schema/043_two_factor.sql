@@ -0,0 +1,26 @@
+-- +goose Up
+-- A user's TOTP secret. The row is created when they start setting up
+-- two-factor authentication and only takes effect once enabled_at is set,
+-- after they have proved their authenticator app has the secret.
+CREATE TABLE user_totp (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Single-use codes that stand in for a TOTP code. Only bcrypt hashes are
+-- stored; a code is burnt by setting used_at.
+CREATE TABLE backup_codes (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX backup_codes_user_id_idx ON backup_codes (user_id) WHERE used_at IS NULL;
+
+-- +goose Down
+DROP TABLE backup_codes;
+DROP TABLE user_totp;
\ No newline at end of file
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// appFileServer serves the front end in root. Directories are only served
+// through their index.html, never listed. With spaMode set, paths that do
+// not match a file get the root index.html instead of a 404 so that a
+// single-page app's client-side routes survive a reload.
+func appFileServer(root string, spaMode bool) http.Handler {
+	fsys := noListingFS{http.Dir(root)}
+	files := http.FileServer(fsys)
+	if !spaMode {
+		return files
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Paths with ".." are left to FileServer, which rejects them
+		if !strings.Contains(r.URL.Path, "..") && !fsys.exists(r.URL.Path) {
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = "/"
+			r2.URL.RawPath = ""
+			files.ServeHTTP(w, r2)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// noListingFS hides directories that have no index.html, so FileServer
+// answers them with a 404 rather than a listing.
+type noListingFS struct {
+	fs http.FileSystem
+}
+
+func (n noListingFS) Open(name string) (http.File, error) {
+	f, err := n.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		index, err := n.fs.Open(path.Join(name, "index.html"))
+		if err != nil {
+			f.Close()
+			return nil, fs.ErrNotExist
+		}
+		index.Close()
+	}
+	return f, nil
+}
+
+func (n noListingFS) exists(name string) bool {
+	f, err := n.Open(name)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// staticRoot lays out a small front end in a directory next to a file that
+// must never be served.
+func staticRoot(t *testing.T) string {
+	t.Helper()
+	parent := t.TempDir()
+	root := filepath.Join(parent, "public")
+	files := map[string]string{
+		"secret.txt":               "do not serve",
+		"public/index.html":        "<html>app shell</html>",
+		"public/assets/app.js":     "console.log('app')",
+		"public/docs/index.html":   "<html>docs</html>",
+		"public/empty/placeholder": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(parent, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestAppFileServer(t *testing.T) {
+	root := staticRoot(t)
+
+	tests := []struct {
+		name    string
+		path    string
+		spaMode bool
+		code    int
+		body    string
+	}{
+		{"asset", "/app/assets/app.js", false, http.StatusOK, "console.log"},
+		{"asset in SPA mode", "/app/assets/app.js", true, http.StatusOK, "console.log"},
+		{"index", "/app/", false, http.StatusOK, "app shell"},
+		{"directory index", "/app/docs/", false, http.StatusOK, "docs"},
+		{"missing", "/app/profile/123", false, http.StatusNotFound, ""},
+		{"missing in SPA mode", "/app/profile/123", true, http.StatusOK, "app shell"},
+		{"missing asset in SPA mode", "/app/assets/gone.js", true, http.StatusOK, "app shell"},
+		{"directory listing", "/app/assets/", false, http.StatusNotFound, ""},
+		{"directory listing in SPA mode", "/app/empty/", true, http.StatusOK, "app shell"},
+	}
+	for _, tt := range tests {
+		handler := http.StripPrefix("/app", appFileServer(root, tt.spaMode))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rr.Code != tt.code || !strings.Contains(rr.Body.String(), tt.body) {
+			t.Errorf("%s: got %v %q, want %v containing %q", tt.name, rr.Code, rr.Body, tt.code, tt.body)
+		}
+		// FileServer wraps directory listings in <pre>
+		if strings.Contains(rr.Body.String(), "<pre>") {
+			t.Errorf("%s: response lists a directory: %q", tt.name, rr.Body)
+		}
+	}
+}
+
+func TestAppFileServerTraversal(t *testing.T) {
+	root := staticRoot(t)
+
+	for _, spaMode := range []bool{false, true} {
+		handler := http.StripPrefix("/app", appFileServer(root, spaMode))
+		for _, path := range []string{"/app/../secret.txt", "/app/%2e%2e/secret.txt", "/app/assets/../../secret.txt"} {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+			if strings.Contains(rr.Body.String(), "do not serve") {
+				t.Errorf("%s (SPA mode %v): got %v %q", path, spaMode, rr.Code, rr.Body)
+			}
+		}
+	}
+
+	// Through the router the path is cleaned before it reaches /app/
+	cfg, _ := newTestConfig(t)
+	mux := http.NewServeMux()
+	registerRoutes(mux, cfg.routes(root, true), true)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/app/../main.go", nil))
+	if rr.Code == http.StatusOK {
+		t.Errorf("/app/../main.go: got %v %q", rr.Code, rr.Body)
+	}
+}
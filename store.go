@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// store is the set of database queries the handlers depend on. It is
+// satisfied by *database.Queries, prepared or not, and lets tests swap in
+// an in-memory fake.
+type store interface {
+	CreateOAuthUser(ctx context.Context, arg database.CreateOAuthUserParams) (database.User, error)
+	CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error)
+	DeleteAllUsers(ctx context.Context) error
+	DeleteUserKeepChirps(ctx context.Context, id uuid.UUID) (int64, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error)
+	GetUserByEmail(ctx context.Context, email string) (database.User, error)
+	UsernameExists(ctx context.Context, username string) (bool, error)
+	UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error)
+	RehashUserPassword(ctx context.Context, arg database.RehashUserPasswordParams) (int64, error)
+	SetUserAdmin(ctx context.Context, arg database.SetUserAdminParams) (int64, error)
+	SetUserAvatar(ctx context.Context, arg database.SetUserAvatarParams) (database.User, error)
+	SetUserPinnedChirp(ctx context.Context, arg database.SetUserPinnedChirpParams) (int64, error)
+	UnpinChirp(ctx context.Context, pinnedChirpID uuid.NullUUID) error
+	CountUsers(ctx context.Context) (int64, error)
+	ListUsers(ctx context.Context, arg database.ListUsersParams) ([]database.ListUsersRow, error)
+	CountUsersWithAvatar(ctx context.Context, avatarUrl sql.NullString) (int64, error)
+	CountChirpsWithMedia(ctx context.Context, mediaUrl sql.NullString) (int64, error)
+	UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) error
+	SetUserPrivate(ctx context.Context, arg database.SetUserPrivateParams) (database.User, error)
+	SetUserPendingEmail(ctx context.Context, arg database.SetUserPendingEmailParams) (database.User, error)
+	ConfirmPendingEmail(ctx context.Context, tokenHash string) (database.ConfirmPendingEmailRow, error)
+	CanViewUser(ctx context.Context, arg database.CanViewUserParams) (bool, error)
+	GetHiddenAuthorIDs(ctx context.Context, arg database.GetHiddenAuthorIDsParams) ([]uuid.UUID, error)
+	BanUser(ctx context.Context, arg database.BanUserParams) (int64, error)
+	UnbanUser(ctx context.Context, id uuid.UUID) (int64, error)
+	GetAccountStatus(ctx context.Context, id uuid.UUID) (database.GetAccountStatusRow, error)
+
+	CountChirps(ctx context.Context) (int64, error)
+	CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error)
+	CreateChirpAt(ctx context.Context, arg database.CreateChirpAtParams) (database.Chirp, error)
+	CreateChirps(ctx context.Context, arg database.CreateChirpsParams) ([]database.Chirp, error)
+	GetChirps(ctx context.Context, arg database.GetChirpsParams) ([]database.GetChirpsRow, error)
+	GetChirpByID(ctx context.Context, id uuid.UUID) (database.GetChirpByIDRow, error)
+	GetChirpByIDWithAuthor(ctx context.Context, id uuid.UUID) (database.GetChirpByIDWithAuthorRow, error)
+	GetChirpsByUserID(ctx context.Context, arg database.GetChirpsByUserIDParams) ([]database.GetChirpsByUserIDRow, error)
+	GetChirpsSince(ctx context.Context, arg database.GetChirpsSinceParams) ([]database.GetChirpsSinceRow, error)
+	GetChirpsWithAuthors(ctx context.Context, arg database.GetChirpsWithAuthorsParams) ([]database.GetChirpsWithAuthorsRow, error)
+	GetChirpReplies(ctx context.Context, arg database.GetChirpRepliesParams) ([]database.GetChirpRepliesRow, error)
+	GetFeed(ctx context.Context, arg database.GetFeedParams) ([]database.GetFeedRow, error)
+	GetNthNewestChirpTime(ctx context.Context, arg database.GetNthNewestChirpTimeParams) (time.Time, error)
+	GetUserChirpStats(ctx context.Context, userID uuid.UUID) (database.GetUserChirpStatsRow, error)
+	GetUserChirpsAfter(ctx context.Context, arg database.GetUserChirpsAfterParams) ([]database.Chirp, error)
+	UpdateChirp(ctx context.Context, arg database.UpdateChirpParams) (database.Chirp, error)
+	HasRecentDuplicateChirp(ctx context.Context, arg database.HasRecentDuplicateChirpParams) (bool, error)
+	GetChirpRevisions(ctx context.Context, arg database.GetChirpRevisionsParams) ([]database.ChirpRevision, error)
+	DeleteChirp(ctx context.Context, id uuid.UUID) error
+	DeleteUserChirpsByIDs(ctx context.Context, arg database.DeleteUserChirpsByIDsParams) ([]uuid.UUID, error)
+	DeleteUserChirpsBefore(ctx context.Context, arg database.DeleteUserChirpsBeforeParams) ([]uuid.UUID, error)
+	CountChirpsBefore(ctx context.Context, before time.Time) (int64, error)
+	PurgeChirpsBefore(ctx context.Context, arg database.PurgeChirpsBeforeParams) (int64, error)
+	AddChirpViews(ctx context.Context, arg database.AddChirpViewsParams) error
+
+	LikeChirp(ctx context.Context, arg database.LikeChirpParams) (int64, error)
+	UnlikeChirp(ctx context.Context, arg database.UnlikeChirpParams) error
+	GetLikedChirpIDs(ctx context.Context, arg database.GetLikedChirpIDsParams) ([]uuid.UUID, error)
+	GetChirpLikers(ctx context.Context, arg database.GetChirpLikersParams) ([]database.GetChirpLikersRow, error)
+	GetUserLikeStats(ctx context.Context, userID uuid.UUID) (database.GetUserLikeStatsRow, error)
+
+	CreateBookmark(ctx context.Context, arg database.CreateBookmarkParams) error
+	DeleteBookmark(ctx context.Context, arg database.DeleteBookmarkParams) error
+	GetBookmarkedChirps(ctx context.Context, arg database.GetBookmarkedChirpsParams) ([]database.GetBookmarkedChirpsRow, error)
+
+	CreateChirpReport(ctx context.Context, arg database.CreateChirpReportParams) (int64, error)
+	GetReportedChirps(ctx context.Context, arg database.GetReportedChirpsParams) ([]database.GetReportedChirpsRow, error)
+	ResolveChirpReports(ctx context.Context, chirpID uuid.UUID) (int64, error)
+
+	AddChirpHashtag(ctx context.Context, arg database.AddChirpHashtagParams) error
+	DeleteChirpHashtags(ctx context.Context, chirpID uuid.UUID) error
+	GetChirpsByHashtag(ctx context.Context, arg database.GetChirpsByHashtagParams) ([]database.GetChirpsByHashtagRow, error)
+
+	AddChirpEntity(ctx context.Context, arg database.AddChirpEntityParams) error
+	DeleteChirpEntities(ctx context.Context, chirpID uuid.UUID) error
+	GetChirpEntities(ctx context.Context, chirpIds []uuid.UUID) ([]database.ChirpEntity, error)
+
+	AddChirpMention(ctx context.Context, arg database.AddChirpMentionParams) error
+	DeleteChirpMentions(ctx context.Context, chirpID uuid.UUID) error
+	GetChirpMentions(ctx context.Context, chirpIds []uuid.UUID) ([]database.ChirpMention, error)
+	ResolveMentionHandles(ctx context.Context, handles []string) ([]database.ResolveMentionHandlesRow, error)
+
+	CreateFollow(ctx context.Context, arg database.CreateFollowParams) (int64, error)
+	DeleteFollow(ctx context.Context, arg database.DeleteFollowParams) error
+	CountFollowers(ctx context.Context, followeeID uuid.UUID) (int64, error)
+	CountFollowing(ctx context.Context, followerID uuid.UUID) (int64, error)
+	GetFollowers(ctx context.Context, arg database.GetFollowersParams) ([]database.GetFollowersRow, error)
+	GetFollowing(ctx context.Context, arg database.GetFollowingParams) ([]database.GetFollowingRow, error)
+
+	CreateFollowRequest(ctx context.Context, arg database.CreateFollowRequestParams) (int64, error)
+	DeleteFollowRequest(ctx context.Context, arg database.DeleteFollowRequestParams) (int64, error)
+	ApproveFollowRequest(ctx context.Context, arg database.ApproveFollowRequestParams) (int64, error)
+	ApproveAllFollowRequests(ctx context.Context, targetID uuid.UUID) error
+	GetFollowRequests(ctx context.Context, arg database.GetFollowRequestsParams) ([]database.GetFollowRequestsRow, error)
+
+	CreateMute(ctx context.Context, arg database.CreateMuteParams) error
+	DeleteMute(ctx context.Context, arg database.DeleteMuteParams) error
+
+	CreateNotification(ctx context.Context, arg database.CreateNotificationParams) error
+	GetNotifications(ctx context.Context, arg database.GetNotificationsParams) ([]database.GetNotificationsRow, error)
+	CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error)
+	MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) error
+	MarkNotificationsRead(ctx context.Context, arg database.MarkNotificationsReadParams) error
+
+	CreateWebhookEvent(ctx context.Context, arg database.CreateWebhookEventParams) (database.WebhookEvent, error)
+	GetDueWebhookEvents(ctx context.Context, limit int32) ([]database.WebhookEvent, error)
+	GetWebhookEvents(ctx context.Context, arg database.GetWebhookEventsParams) ([]database.WebhookEvent, error)
+	MarkWebhookEventFailed(ctx context.Context, arg database.MarkWebhookEventFailedParams) error
+	MarkWebhookEventProcessed(ctx context.Context, id uuid.UUID) error
+
+	ClaimIdempotencyKey(ctx context.Context, arg database.ClaimIdempotencyKeyParams) (database.IdempotencyKey, error)
+	GetIdempotencyKey(ctx context.Context, arg database.GetIdempotencyKeyParams) (database.IdempotencyKey, error)
+	SetIdempotencyKeyResponse(ctx context.Context, arg database.SetIdempotencyKeyResponseParams) error
+	DeleteIdempotencyKey(ctx context.Context, arg database.DeleteIdempotencyKeyParams) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+
+	CreateAuditLogEntry(ctx context.Context, arg database.CreateAuditLogEntryParams) error
+	GetAuditLog(ctx context.Context, arg database.GetAuditLogParams) ([]database.AuditLog, error)
+
+	CountActiveRefreshTokens(ctx context.Context) (int64, error)
+	CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error)
+	GetRefreshToken(ctx context.Context, token string) (database.RefreshToken, error)
+	GetUserFromRefreshToken(ctx context.Context, token string) (database.User, error)
+	GetUserSessions(ctx context.Context, userID uuid.UUID) ([]database.GetUserSessionsRow, error)
+	RevokeRefreshToken(ctx context.Context, token string) (int64, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) (int64, error)
+	RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error
+
+	CreateLoginHistoryEntry(ctx context.Context, arg database.CreateLoginHistoryEntryParams) error
+	GetLoginHistory(ctx context.Context, arg database.GetLoginHistoryParams) ([]database.LoginHistory, error)
+	PruneLoginHistory(ctx context.Context, keep int64) (int64, error)
+
+	CreateMagicLinkToken(ctx context.Context, arg database.CreateMagicLinkTokenParams) error
+	ConsumeMagicLinkToken(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	DeleteExpiredMagicLinkTokens(ctx context.Context) (int64, error)
+
+	SetPendingTOTPSecret(ctx context.Context, arg database.SetPendingTOTPSecretParams) (int64, error)
+	GetUserTOTP(ctx context.Context, userID uuid.UUID) (database.UserTotp, error)
+	EnableUserTOTP(ctx context.Context, arg database.EnableUserTOTPParams) (int64, error)
+	ReplaceBackupCodes(ctx context.Context, arg database.ReplaceBackupCodesParams) error
+	GetUnusedBackupCodes(ctx context.Context, userID uuid.UUID) ([]database.GetUnusedBackupCodesRow, error)
+	UseBackupCode(ctx context.Context, id uuid.UUID) (int64, error)
+
+	CreateAPIKey(ctx context.Context, arg database.CreateAPIKeyParams) (database.ApiKey, error)
+	DeleteAPIKey(ctx context.Context, arg database.DeleteAPIKeyParams) (int64, error)
+	GetAPIKeys(ctx context.Context, userID uuid.UUID) ([]database.ApiKey, error)
+	GetUserIDByAPIKey(ctx context.Context, keyHash string) (uuid.UUID, error)
+}
+
+// prepareQueries prepares every query on db, so the server does not parse
+// and plan them again on each request. If that fails, for instance while a
+// migration is still pending, the queries are sent unprepared instead. The
+// result should be closed when db is.
+func prepareQueries(ctx context.Context, db *sql.DB, name string) *database.Queries {
+	queries, err := database.Prepare(ctx, db)
+	if err != nil {
+		log.Printf("Error preparing queries on the %s, running them unprepared: %v", name, err)
+		return database.New(db)
+	}
+	return queries
+}
@@ -0,0 +1,651 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// instrumentedStore is a store that records the count, errors and latency
+// of every query in metrics before returning the result of next.
+//
+// It has one method per store method, each a deferred observe around the
+// call, so adding a query to store means adding it here too.
+type instrumentedStore struct {
+	next    store
+	metrics *queryMetrics
+}
+
+var _ store = (*instrumentedStore)(nil)
+
+func instrumentStore(next store, metrics *queryMetrics) *instrumentedStore {
+	return &instrumentedStore{next: next, metrics: metrics}
+}
+
+func (s *instrumentedStore) CreateOAuthUser(ctx context.Context, arg database.CreateOAuthUserParams) (_ database.User, err error) {
+	defer s.metrics.observe("CreateOAuthUser", time.Now(), &err)
+	return s.next.CreateOAuthUser(ctx, arg)
+}
+
+func (s *instrumentedStore) CreateUser(ctx context.Context, arg database.CreateUserParams) (_ database.User, err error) {
+	defer s.metrics.observe("CreateUser", time.Now(), &err)
+	return s.next.CreateUser(ctx, arg)
+}
+
+func (s *instrumentedStore) DeleteAllUsers(ctx context.Context) (err error) {
+	defer s.metrics.observe("DeleteAllUsers", time.Now(), &err)
+	return s.next.DeleteAllUsers(ctx)
+}
+
+func (s *instrumentedStore) DeleteUserKeepChirps(ctx context.Context, id uuid.UUID) (_ int64, err error) {
+	defer s.metrics.observe("DeleteUserKeepChirps", time.Now(), &err)
+	return s.next.DeleteUserKeepChirps(ctx, id)
+}
+
+func (s *instrumentedStore) GetUserByID(ctx context.Context, id uuid.UUID) (_ database.User, err error) {
+	defer s.metrics.observe("GetUserByID", time.Now(), &err)
+	return s.next.GetUserByID(ctx, id)
+}
+
+func (s *instrumentedStore) GetUserByEmail(ctx context.Context, email string) (_ database.User, err error) {
+	defer s.metrics.observe("GetUserByEmail", time.Now(), &err)
+	return s.next.GetUserByEmail(ctx, email)
+}
+
+func (s *instrumentedStore) UsernameExists(ctx context.Context, username string) (_ bool, err error) {
+	defer s.metrics.observe("UsernameExists", time.Now(), &err)
+	return s.next.UsernameExists(ctx, username)
+}
+
+func (s *instrumentedStore) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (_ database.User, err error) {
+	defer s.metrics.observe("UpdateUser", time.Now(), &err)
+	return s.next.UpdateUser(ctx, arg)
+}
+
+func (s *instrumentedStore) RehashUserPassword(ctx context.Context, arg database.RehashUserPasswordParams) (_ int64, err error) {
+	defer s.metrics.observe("RehashUserPassword", time.Now(), &err)
+	return s.next.RehashUserPassword(ctx, arg)
+}
+
+func (s *instrumentedStore) SetUserAdmin(ctx context.Context, arg database.SetUserAdminParams) (_ int64, err error) {
+	defer s.metrics.observe("SetUserAdmin", time.Now(), &err)
+	return s.next.SetUserAdmin(ctx, arg)
+}
+
+func (s *instrumentedStore) SetUserAvatar(ctx context.Context, arg database.SetUserAvatarParams) (_ database.User, err error) {
+	defer s.metrics.observe("SetUserAvatar", time.Now(), &err)
+	return s.next.SetUserAvatar(ctx, arg)
+}
+
+func (s *instrumentedStore) SetUserPinnedChirp(ctx context.Context, arg database.SetUserPinnedChirpParams) (_ int64, err error) {
+	defer s.metrics.observe("SetUserPinnedChirp", time.Now(), &err)
+	return s.next.SetUserPinnedChirp(ctx, arg)
+}
+
+func (s *instrumentedStore) UnpinChirp(ctx context.Context, pinnedChirpID uuid.NullUUID) (err error) {
+	defer s.metrics.observe("UnpinChirp", time.Now(), &err)
+	return s.next.UnpinChirp(ctx, pinnedChirpID)
+}
+
+func (s *instrumentedStore) CountUsers(ctx context.Context) (_ int64, err error) {
+	defer s.metrics.observe("CountUsers", time.Now(), &err)
+	return s.next.CountUsers(ctx)
+}
+
+func (s *instrumentedStore) ListUsers(ctx context.Context, arg database.ListUsersParams) (_ []database.ListUsersRow, err error) {
+	defer s.metrics.observe("ListUsers", time.Now(), &err)
+	return s.next.ListUsers(ctx, arg)
+}
+
+func (s *instrumentedStore) CountChirpsWithMedia(ctx context.Context, mediaUrl sql.NullString) (_ int64, err error) {
+	defer s.metrics.observe("CountChirpsWithMedia", time.Now(), &err)
+	return s.next.CountChirpsWithMedia(ctx, mediaUrl)
+}
+
+func (s *instrumentedStore) CountUsersWithAvatar(ctx context.Context, avatarUrl sql.NullString) (_ int64, err error) {
+	defer s.metrics.observe("CountUsersWithAvatar", time.Now(), &err)
+	return s.next.CountUsersWithAvatar(ctx, avatarUrl)
+}
+
+func (s *instrumentedStore) UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) (err error) {
+	defer s.metrics.observe("UpgradeUserToChirpyRed", time.Now(), &err)
+	return s.next.UpgradeUserToChirpyRed(ctx, id)
+}
+
+func (s *instrumentedStore) SetUserPrivate(ctx context.Context, arg database.SetUserPrivateParams) (_ database.User, err error) {
+	defer s.metrics.observe("SetUserPrivate", time.Now(), &err)
+	return s.next.SetUserPrivate(ctx, arg)
+}
+
+func (s *instrumentedStore) SetUserPendingEmail(ctx context.Context, arg database.SetUserPendingEmailParams) (_ database.User, err error) {
+	defer s.metrics.observe("SetUserPendingEmail", time.Now(), &err)
+	return s.next.SetUserPendingEmail(ctx, arg)
+}
+
+func (s *instrumentedStore) ConfirmPendingEmail(ctx context.Context, tokenHash string) (_ database.ConfirmPendingEmailRow, err error) {
+	defer s.metrics.observe("ConfirmPendingEmail", time.Now(), &err)
+	return s.next.ConfirmPendingEmail(ctx, tokenHash)
+}
+
+func (s *instrumentedStore) CanViewUser(ctx context.Context, arg database.CanViewUserParams) (_ bool, err error) {
+	defer s.metrics.observe("CanViewUser", time.Now(), &err)
+	return s.next.CanViewUser(ctx, arg)
+}
+
+func (s *instrumentedStore) GetHiddenAuthorIDs(ctx context.Context, arg database.GetHiddenAuthorIDsParams) (_ []uuid.UUID, err error) {
+	defer s.metrics.observe("GetHiddenAuthorIDs", time.Now(), &err)
+	return s.next.GetHiddenAuthorIDs(ctx, arg)
+}
+
+func (s *instrumentedStore) BanUser(ctx context.Context, arg database.BanUserParams) (_ int64, err error) {
+	defer s.metrics.observe("BanUser", time.Now(), &err)
+	return s.next.BanUser(ctx, arg)
+}
+
+func (s *instrumentedStore) UnbanUser(ctx context.Context, id uuid.UUID) (_ int64, err error) {
+	defer s.metrics.observe("UnbanUser", time.Now(), &err)
+	return s.next.UnbanUser(ctx, id)
+}
+
+func (s *instrumentedStore) GetAccountStatus(ctx context.Context, id uuid.UUID) (_ database.GetAccountStatusRow, err error) {
+	defer s.metrics.observe("GetAccountStatus", time.Now(), &err)
+	return s.next.GetAccountStatus(ctx, id)
+}
+
+func (s *instrumentedStore) CountChirps(ctx context.Context) (_ int64, err error) {
+	defer s.metrics.observe("CountChirps", time.Now(), &err)
+	return s.next.CountChirps(ctx)
+}
+
+func (s *instrumentedStore) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (_ database.Chirp, err error) {
+	defer s.metrics.observe("CreateChirp", time.Now(), &err)
+	return s.next.CreateChirp(ctx, arg)
+}
+
+func (s *instrumentedStore) CreateChirpAt(ctx context.Context, arg database.CreateChirpAtParams) (_ database.Chirp, err error) {
+	defer s.metrics.observe("CreateChirpAt", time.Now(), &err)
+	return s.next.CreateChirpAt(ctx, arg)
+}
+
+func (s *instrumentedStore) CreateChirps(ctx context.Context, arg database.CreateChirpsParams) (_ []database.Chirp, err error) {
+	defer s.metrics.observe("CreateChirps", time.Now(), &err)
+	return s.next.CreateChirps(ctx, arg)
+}
+
+func (s *instrumentedStore) GetChirps(ctx context.Context, arg database.GetChirpsParams) (_ []database.GetChirpsRow, err error) {
+	defer s.metrics.observe("GetChirps", time.Now(), &err)
+	return s.next.GetChirps(ctx, arg)
+}
+
+func (s *instrumentedStore) GetChirpByID(ctx context.Context, id uuid.UUID) (_ database.GetChirpByIDRow, err error) {
+	defer s.metrics.observe("GetChirpByID", time.Now(), &err)
+	return s.next.GetChirpByID(ctx, id)
+}
+
+func (s *instrumentedStore) GetChirpByIDWithAuthor(ctx context.Context, id uuid.UUID) (_ database.GetChirpByIDWithAuthorRow, err error) {
+	defer s.metrics.observe("GetChirpByIDWithAuthor", time.Now(), &err)
+	return s.next.GetChirpByIDWithAuthor(ctx, id)
+}
+
+func (s *instrumentedStore) GetChirpsByUserID(ctx context.Context, arg database.GetChirpsByUserIDParams) (_ []database.GetChirpsByUserIDRow, err error) {
+	defer s.metrics.observe("GetChirpsByUserID", time.Now(), &err)
+	return s.next.GetChirpsByUserID(ctx, arg)
+}
+
+func (s *instrumentedStore) GetChirpsSince(ctx context.Context, arg database.GetChirpsSinceParams) (_ []database.GetChirpsSinceRow, err error) {
+	defer s.metrics.observe("GetChirpsSince", time.Now(), &err)
+	return s.next.GetChirpsSince(ctx, arg)
+}
+
+func (s *instrumentedStore) GetChirpsWithAuthors(ctx context.Context, arg database.GetChirpsWithAuthorsParams) (_ []database.GetChirpsWithAuthorsRow, err error) {
+	defer s.metrics.observe("GetChirpsWithAuthors", time.Now(), &err)
+	return s.next.GetChirpsWithAuthors(ctx, arg)
+}
+
+func (s *instrumentedStore) GetChirpReplies(ctx context.Context, arg database.GetChirpRepliesParams) (_ []database.GetChirpRepliesRow, err error) {
+	defer s.metrics.observe("GetChirpReplies", time.Now(), &err)
+	return s.next.GetChirpReplies(ctx, arg)
+}
+
+func (s *instrumentedStore) GetFeed(ctx context.Context, arg database.GetFeedParams) (_ []database.GetFeedRow, err error) {
+	defer s.metrics.observe("GetFeed", time.Now(), &err)
+	return s.next.GetFeed(ctx, arg)
+}
+
+func (s *instrumentedStore) GetNthNewestChirpTime(ctx context.Context, arg database.GetNthNewestChirpTimeParams) (_ time.Time, err error) {
+	defer s.metrics.observe("GetNthNewestChirpTime", time.Now(), &err)
+	return s.next.GetNthNewestChirpTime(ctx, arg)
+}
+
+func (s *instrumentedStore) GetUserChirpStats(ctx context.Context, userID uuid.UUID) (_ database.GetUserChirpStatsRow, err error) {
+	defer s.metrics.observe("GetUserChirpStats", time.Now(), &err)
+	return s.next.GetUserChirpStats(ctx, userID)
+}
+
+func (s *instrumentedStore) GetUserChirpsAfter(ctx context.Context, arg database.GetUserChirpsAfterParams) (_ []database.Chirp, err error) {
+	defer s.metrics.observe("GetUserChirpsAfter", time.Now(), &err)
+	return s.next.GetUserChirpsAfter(ctx, arg)
+}
+
+func (s *instrumentedStore) UpdateChirp(ctx context.Context, arg database.UpdateChirpParams) (_ database.Chirp, err error) {
+	defer s.metrics.observe("UpdateChirp", time.Now(), &err)
+	return s.next.UpdateChirp(ctx, arg)
+}
+
+func (s *instrumentedStore) HasRecentDuplicateChirp(ctx context.Context, arg database.HasRecentDuplicateChirpParams) (_ bool, err error) {
+	defer s.metrics.observe("HasRecentDuplicateChirp", time.Now(), &err)
+	return s.next.HasRecentDuplicateChirp(ctx, arg)
+}
+
+func (s *instrumentedStore) GetChirpRevisions(ctx context.Context, arg database.GetChirpRevisionsParams) (_ []database.ChirpRevision, err error) {
+	defer s.metrics.observe("GetChirpRevisions", time.Now(), &err)
+	return s.next.GetChirpRevisions(ctx, arg)
+}
+
+func (s *instrumentedStore) DeleteChirp(ctx context.Context, id uuid.UUID) (err error) {
+	defer s.metrics.observe("DeleteChirp", time.Now(), &err)
+	return s.next.DeleteChirp(ctx, id)
+}
+
+func (s *instrumentedStore) DeleteUserChirpsByIDs(ctx context.Context, arg database.DeleteUserChirpsByIDsParams) (_ []uuid.UUID, err error) {
+	defer s.metrics.observe("DeleteUserChirpsByIDs", time.Now(), &err)
+	return s.next.DeleteUserChirpsByIDs(ctx, arg)
+}
+
+func (s *instrumentedStore) DeleteUserChirpsBefore(ctx context.Context, arg database.DeleteUserChirpsBeforeParams) (_ []uuid.UUID, err error) {
+	defer s.metrics.observe("DeleteUserChirpsBefore", time.Now(), &err)
+	return s.next.DeleteUserChirpsBefore(ctx, arg)
+}
+
+func (s *instrumentedStore) CountChirpsBefore(ctx context.Context, before time.Time) (_ int64, err error) {
+	defer s.metrics.observe("CountChirpsBefore", time.Now(), &err)
+	return s.next.CountChirpsBefore(ctx, before)
+}
+
+func (s *instrumentedStore) PurgeChirpsBefore(ctx context.Context, arg database.PurgeChirpsBeforeParams) (_ int64, err error) {
+	defer s.metrics.observe("PurgeChirpsBefore", time.Now(), &err)
+	return s.next.PurgeChirpsBefore(ctx, arg)
+}
+
+func (s *instrumentedStore) AddChirpViews(ctx context.Context, arg database.AddChirpViewsParams) (err error) {
+	defer s.metrics.observe("AddChirpViews", time.Now(), &err)
+	return s.next.AddChirpViews(ctx, arg)
+}
+
+func (s *instrumentedStore) LikeChirp(ctx context.Context, arg database.LikeChirpParams) (_ int64, err error) {
+	defer s.metrics.observe("LikeChirp", time.Now(), &err)
+	return s.next.LikeChirp(ctx, arg)
+}
+
+func (s *instrumentedStore) UnlikeChirp(ctx context.Context, arg database.UnlikeChirpParams) (err error) {
+	defer s.metrics.observe("UnlikeChirp", time.Now(), &err)
+	return s.next.UnlikeChirp(ctx, arg)
+}
+
+func (s *instrumentedStore) GetLikedChirpIDs(ctx context.Context, arg database.GetLikedChirpIDsParams) (_ []uuid.UUID, err error) {
+	defer s.metrics.observe("GetLikedChirpIDs", time.Now(), &err)
+	return s.next.GetLikedChirpIDs(ctx, arg)
+}
+
+func (s *instrumentedStore) GetChirpLikers(ctx context.Context, arg database.GetChirpLikersParams) (_ []database.GetChirpLikersRow, err error) {
+	defer s.metrics.observe("GetChirpLikers", time.Now(), &err)
+	return s.next.GetChirpLikers(ctx, arg)
+}
+
+func (s *instrumentedStore) GetUserLikeStats(ctx context.Context, userID uuid.UUID) (_ database.GetUserLikeStatsRow, err error) {
+	defer s.metrics.observe("GetUserLikeStats", time.Now(), &err)
+	return s.next.GetUserLikeStats(ctx, userID)
+}
+
+func (s *instrumentedStore) CreateBookmark(ctx context.Context, arg database.CreateBookmarkParams) (err error) {
+	defer s.metrics.observe("CreateBookmark", time.Now(), &err)
+	return s.next.CreateBookmark(ctx, arg)
+}
+
+func (s *instrumentedStore) DeleteBookmark(ctx context.Context, arg database.DeleteBookmarkParams) (err error) {
+	defer s.metrics.observe("DeleteBookmark", time.Now(), &err)
+	return s.next.DeleteBookmark(ctx, arg)
+}
+
+func (s *instrumentedStore) GetBookmarkedChirps(ctx context.Context, arg database.GetBookmarkedChirpsParams) (_ []database.GetBookmarkedChirpsRow, err error) {
+	defer s.metrics.observe("GetBookmarkedChirps", time.Now(), &err)
+	return s.next.GetBookmarkedChirps(ctx, arg)
+}
+
+func (s *instrumentedStore) CreateChirpReport(ctx context.Context, arg database.CreateChirpReportParams) (_ int64, err error) {
+	defer s.metrics.observe("CreateChirpReport", time.Now(), &err)
+	return s.next.CreateChirpReport(ctx, arg)
+}
+
+func (s *instrumentedStore) GetReportedChirps(ctx context.Context, arg database.GetReportedChirpsParams) (_ []database.GetReportedChirpsRow, err error) {
+	defer s.metrics.observe("GetReportedChirps", time.Now(), &err)
+	return s.next.GetReportedChirps(ctx, arg)
+}
+
+func (s *instrumentedStore) ResolveChirpReports(ctx context.Context, chirpID uuid.UUID) (_ int64, err error) {
+	defer s.metrics.observe("ResolveChirpReports", time.Now(), &err)
+	return s.next.ResolveChirpReports(ctx, chirpID)
+}
+
+func (s *instrumentedStore) AddChirpHashtag(ctx context.Context, arg database.AddChirpHashtagParams) (err error) {
+	defer s.metrics.observe("AddChirpHashtag", time.Now(), &err)
+	return s.next.AddChirpHashtag(ctx, arg)
+}
+
+func (s *instrumentedStore) DeleteChirpHashtags(ctx context.Context, chirpID uuid.UUID) (err error) {
+	defer s.metrics.observe("DeleteChirpHashtags", time.Now(), &err)
+	return s.next.DeleteChirpHashtags(ctx, chirpID)
+}
+
+func (s *instrumentedStore) GetChirpsByHashtag(ctx context.Context, arg database.GetChirpsByHashtagParams) (_ []database.GetChirpsByHashtagRow, err error) {
+	defer s.metrics.observe("GetChirpsByHashtag", time.Now(), &err)
+	return s.next.GetChirpsByHashtag(ctx, arg)
+}
+
+func (s *instrumentedStore) AddChirpEntity(ctx context.Context, arg database.AddChirpEntityParams) (err error) {
+	defer s.metrics.observe("AddChirpEntity", time.Now(), &err)
+	return s.next.AddChirpEntity(ctx, arg)
+}
+
+func (s *instrumentedStore) DeleteChirpEntities(ctx context.Context, chirpID uuid.UUID) (err error) {
+	defer s.metrics.observe("DeleteChirpEntities", time.Now(), &err)
+	return s.next.DeleteChirpEntities(ctx, chirpID)
+}
+
+func (s *instrumentedStore) GetChirpEntities(ctx context.Context, chirpIds []uuid.UUID) (_ []database.ChirpEntity, err error) {
+	defer s.metrics.observe("GetChirpEntities", time.Now(), &err)
+	return s.next.GetChirpEntities(ctx, chirpIds)
+}
+
+func (s *instrumentedStore) AddChirpMention(ctx context.Context, arg database.AddChirpMentionParams) (err error) {
+	defer s.metrics.observe("AddChirpMention", time.Now(), &err)
+	return s.next.AddChirpMention(ctx, arg)
+}
+
+func (s *instrumentedStore) DeleteChirpMentions(ctx context.Context, chirpID uuid.UUID) (err error) {
+	defer s.metrics.observe("DeleteChirpMentions", time.Now(), &err)
+	return s.next.DeleteChirpMentions(ctx, chirpID)
+}
+
+func (s *instrumentedStore) GetChirpMentions(ctx context.Context, chirpIds []uuid.UUID) (_ []database.ChirpMention, err error) {
+	defer s.metrics.observe("GetChirpMentions", time.Now(), &err)
+	return s.next.GetChirpMentions(ctx, chirpIds)
+}
+
+func (s *instrumentedStore) ResolveMentionHandles(ctx context.Context, handles []string) (_ []database.ResolveMentionHandlesRow, err error) {
+	defer s.metrics.observe("ResolveMentionHandles", time.Now(), &err)
+	return s.next.ResolveMentionHandles(ctx, handles)
+}
+
+func (s *instrumentedStore) CreateFollow(ctx context.Context, arg database.CreateFollowParams) (_ int64, err error) {
+	defer s.metrics.observe("CreateFollow", time.Now(), &err)
+	return s.next.CreateFollow(ctx, arg)
+}
+
+func (s *instrumentedStore) DeleteFollow(ctx context.Context, arg database.DeleteFollowParams) (err error) {
+	defer s.metrics.observe("DeleteFollow", time.Now(), &err)
+	return s.next.DeleteFollow(ctx, arg)
+}
+
+func (s *instrumentedStore) CountFollowers(ctx context.Context, followeeID uuid.UUID) (_ int64, err error) {
+	defer s.metrics.observe("CountFollowers", time.Now(), &err)
+	return s.next.CountFollowers(ctx, followeeID)
+}
+
+func (s *instrumentedStore) CountFollowing(ctx context.Context, followerID uuid.UUID) (_ int64, err error) {
+	defer s.metrics.observe("CountFollowing", time.Now(), &err)
+	return s.next.CountFollowing(ctx, followerID)
+}
+
+func (s *instrumentedStore) GetFollowers(ctx context.Context, arg database.GetFollowersParams) (_ []database.GetFollowersRow, err error) {
+	defer s.metrics.observe("GetFollowers", time.Now(), &err)
+	return s.next.GetFollowers(ctx, arg)
+}
+
+func (s *instrumentedStore) GetFollowing(ctx context.Context, arg database.GetFollowingParams) (_ []database.GetFollowingRow, err error) {
+	defer s.metrics.observe("GetFollowing", time.Now(), &err)
+	return s.next.GetFollowing(ctx, arg)
+}
+
+func (s *instrumentedStore) CreateFollowRequest(ctx context.Context, arg database.CreateFollowRequestParams) (_ int64, err error) {
+	defer s.metrics.observe("CreateFollowRequest", time.Now(), &err)
+	return s.next.CreateFollowRequest(ctx, arg)
+}
+
+func (s *instrumentedStore) DeleteFollowRequest(ctx context.Context, arg database.DeleteFollowRequestParams) (_ int64, err error) {
+	defer s.metrics.observe("DeleteFollowRequest", time.Now(), &err)
+	return s.next.DeleteFollowRequest(ctx, arg)
+}
+
+func (s *instrumentedStore) ApproveFollowRequest(ctx context.Context, arg database.ApproveFollowRequestParams) (_ int64, err error) {
+	defer s.metrics.observe("ApproveFollowRequest", time.Now(), &err)
+	return s.next.ApproveFollowRequest(ctx, arg)
+}
+
+func (s *instrumentedStore) ApproveAllFollowRequests(ctx context.Context, targetID uuid.UUID) (err error) {
+	defer s.metrics.observe("ApproveAllFollowRequests", time.Now(), &err)
+	return s.next.ApproveAllFollowRequests(ctx, targetID)
+}
+
+func (s *instrumentedStore) GetFollowRequests(ctx context.Context, arg database.GetFollowRequestsParams) (_ []database.GetFollowRequestsRow, err error) {
+	defer s.metrics.observe("GetFollowRequests", time.Now(), &err)
+	return s.next.GetFollowRequests(ctx, arg)
+}
+
+func (s *instrumentedStore) CreateMute(ctx context.Context, arg database.CreateMuteParams) (err error) {
+	defer s.metrics.observe("CreateMute", time.Now(), &err)
+	return s.next.CreateMute(ctx, arg)
+}
+
+func (s *instrumentedStore) DeleteMute(ctx context.Context, arg database.DeleteMuteParams) (err error) {
+	defer s.metrics.observe("DeleteMute", time.Now(), &err)
+	return s.next.DeleteMute(ctx, arg)
+}
+
+func (s *instrumentedStore) CreateNotification(ctx context.Context, arg database.CreateNotificationParams) (err error) {
+	defer s.metrics.observe("CreateNotification", time.Now(), &err)
+	return s.next.CreateNotification(ctx, arg)
+}
+
+func (s *instrumentedStore) GetNotifications(ctx context.Context, arg database.GetNotificationsParams) (_ []database.GetNotificationsRow, err error) {
+	defer s.metrics.observe("GetNotifications", time.Now(), &err)
+	return s.next.GetNotifications(ctx, arg)
+}
+
+func (s *instrumentedStore) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (_ int64, err error) {
+	defer s.metrics.observe("CountUnreadNotifications", time.Now(), &err)
+	return s.next.CountUnreadNotifications(ctx, userID)
+}
+
+func (s *instrumentedStore) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) (err error) {
+	defer s.metrics.observe("MarkAllNotificationsRead", time.Now(), &err)
+	return s.next.MarkAllNotificationsRead(ctx, userID)
+}
+
+func (s *instrumentedStore) MarkNotificationsRead(ctx context.Context, arg database.MarkNotificationsReadParams) (err error) {
+	defer s.metrics.observe("MarkNotificationsRead", time.Now(), &err)
+	return s.next.MarkNotificationsRead(ctx, arg)
+}
+
+func (s *instrumentedStore) CreateWebhookEvent(ctx context.Context, arg database.CreateWebhookEventParams) (_ database.WebhookEvent, err error) {
+	defer s.metrics.observe("CreateWebhookEvent", time.Now(), &err)
+	return s.next.CreateWebhookEvent(ctx, arg)
+}
+
+func (s *instrumentedStore) GetDueWebhookEvents(ctx context.Context, limit int32) (_ []database.WebhookEvent, err error) {
+	defer s.metrics.observe("GetDueWebhookEvents", time.Now(), &err)
+	return s.next.GetDueWebhookEvents(ctx, limit)
+}
+
+func (s *instrumentedStore) GetWebhookEvents(ctx context.Context, arg database.GetWebhookEventsParams) (_ []database.WebhookEvent, err error) {
+	defer s.metrics.observe("GetWebhookEvents", time.Now(), &err)
+	return s.next.GetWebhookEvents(ctx, arg)
+}
+
+func (s *instrumentedStore) MarkWebhookEventFailed(ctx context.Context, arg database.MarkWebhookEventFailedParams) (err error) {
+	defer s.metrics.observe("MarkWebhookEventFailed", time.Now(), &err)
+	return s.next.MarkWebhookEventFailed(ctx, arg)
+}
+
+func (s *instrumentedStore) MarkWebhookEventProcessed(ctx context.Context, id uuid.UUID) (err error) {
+	defer s.metrics.observe("MarkWebhookEventProcessed", time.Now(), &err)
+	return s.next.MarkWebhookEventProcessed(ctx, id)
+}
+
+func (s *instrumentedStore) ClaimIdempotencyKey(ctx context.Context, arg database.ClaimIdempotencyKeyParams) (_ database.IdempotencyKey, err error) {
+	defer s.metrics.observe("ClaimIdempotencyKey", time.Now(), &err)
+	return s.next.ClaimIdempotencyKey(ctx, arg)
+}
+
+func (s *instrumentedStore) GetIdempotencyKey(ctx context.Context, arg database.GetIdempotencyKeyParams) (_ database.IdempotencyKey, err error) {
+	defer s.metrics.observe("GetIdempotencyKey", time.Now(), &err)
+	return s.next.GetIdempotencyKey(ctx, arg)
+}
+
+func (s *instrumentedStore) SetIdempotencyKeyResponse(ctx context.Context, arg database.SetIdempotencyKeyResponseParams) (err error) {
+	defer s.metrics.observe("SetIdempotencyKeyResponse", time.Now(), &err)
+	return s.next.SetIdempotencyKeyResponse(ctx, arg)
+}
+
+func (s *instrumentedStore) DeleteIdempotencyKey(ctx context.Context, arg database.DeleteIdempotencyKeyParams) (err error) {
+	defer s.metrics.observe("DeleteIdempotencyKey", time.Now(), &err)
+	return s.next.DeleteIdempotencyKey(ctx, arg)
+}
+
+func (s *instrumentedStore) DeleteExpiredIdempotencyKeys(ctx context.Context) (_ int64, err error) {
+	defer s.metrics.observe("DeleteExpiredIdempotencyKeys", time.Now(), &err)
+	return s.next.DeleteExpiredIdempotencyKeys(ctx)
+}
+
+func (s *instrumentedStore) CreateAuditLogEntry(ctx context.Context, arg database.CreateAuditLogEntryParams) (err error) {
+	defer s.metrics.observe("CreateAuditLogEntry", time.Now(), &err)
+	return s.next.CreateAuditLogEntry(ctx, arg)
+}
+
+func (s *instrumentedStore) GetAuditLog(ctx context.Context, arg database.GetAuditLogParams) (_ []database.AuditLog, err error) {
+	defer s.metrics.observe("GetAuditLog", time.Now(), &err)
+	return s.next.GetAuditLog(ctx, arg)
+}
+
+func (s *instrumentedStore) CountActiveRefreshTokens(ctx context.Context) (_ int64, err error) {
+	defer s.metrics.observe("CountActiveRefreshTokens", time.Now(), &err)
+	return s.next.CountActiveRefreshTokens(ctx)
+}
+
+func (s *instrumentedStore) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (_ database.RefreshToken, err error) {
+	defer s.metrics.observe("CreateRefreshToken", time.Now(), &err)
+	return s.next.CreateRefreshToken(ctx, arg)
+}
+
+func (s *instrumentedStore) GetRefreshToken(ctx context.Context, token string) (_ database.RefreshToken, err error) {
+	defer s.metrics.observe("GetRefreshToken", time.Now(), &err)
+	return s.next.GetRefreshToken(ctx, token)
+}
+
+func (s *instrumentedStore) GetUserFromRefreshToken(ctx context.Context, token string) (_ database.User, err error) {
+	defer s.metrics.observe("GetUserFromRefreshToken", time.Now(), &err)
+	return s.next.GetUserFromRefreshToken(ctx, token)
+}
+
+func (s *instrumentedStore) GetUserSessions(ctx context.Context, userID uuid.UUID) (_ []database.GetUserSessionsRow, err error) {
+	defer s.metrics.observe("GetUserSessions", time.Now(), &err)
+	return s.next.GetUserSessions(ctx, userID)
+}
+
+func (s *instrumentedStore) RevokeRefreshToken(ctx context.Context, token string) (_ int64, err error) {
+	defer s.metrics.observe("RevokeRefreshToken", time.Now(), &err)
+	return s.next.RevokeRefreshToken(ctx, token)
+}
+
+func (s *instrumentedStore) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) (_ int64, err error) {
+	defer s.metrics.observe("RevokeRefreshTokenFamily", time.Now(), &err)
+	return s.next.RevokeRefreshTokenFamily(ctx, familyID)
+}
+
+func (s *instrumentedStore) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) (err error) {
+	defer s.metrics.observe("RevokeUserRefreshTokens", time.Now(), &err)
+	return s.next.RevokeUserRefreshTokens(ctx, userID)
+}
+
+func (s *instrumentedStore) CreateLoginHistoryEntry(ctx context.Context, arg database.CreateLoginHistoryEntryParams) (err error) {
+	defer s.metrics.observe("CreateLoginHistoryEntry", time.Now(), &err)
+	return s.next.CreateLoginHistoryEntry(ctx, arg)
+}
+
+func (s *instrumentedStore) GetLoginHistory(ctx context.Context, arg database.GetLoginHistoryParams) (_ []database.LoginHistory, err error) {
+	defer s.metrics.observe("GetLoginHistory", time.Now(), &err)
+	return s.next.GetLoginHistory(ctx, arg)
+}
+
+func (s *instrumentedStore) PruneLoginHistory(ctx context.Context, keep int64) (_ int64, err error) {
+	defer s.metrics.observe("PruneLoginHistory", time.Now(), &err)
+	return s.next.PruneLoginHistory(ctx, keep)
+}
+
+func (s *instrumentedStore) CreateMagicLinkToken(ctx context.Context, arg database.CreateMagicLinkTokenParams) (err error) {
+	defer s.metrics.observe("CreateMagicLinkToken", time.Now(), &err)
+	return s.next.CreateMagicLinkToken(ctx, arg)
+}
+
+func (s *instrumentedStore) ConsumeMagicLinkToken(ctx context.Context, tokenHash string) (_ uuid.UUID, err error) {
+	defer s.metrics.observe("ConsumeMagicLinkToken", time.Now(), &err)
+	return s.next.ConsumeMagicLinkToken(ctx, tokenHash)
+}
+
+func (s *instrumentedStore) DeleteExpiredMagicLinkTokens(ctx context.Context) (_ int64, err error) {
+	defer s.metrics.observe("DeleteExpiredMagicLinkTokens", time.Now(), &err)
+	return s.next.DeleteExpiredMagicLinkTokens(ctx)
+}
+
+func (s *instrumentedStore) SetPendingTOTPSecret(ctx context.Context, arg database.SetPendingTOTPSecretParams) (_ int64, err error) {
+	defer s.metrics.observe("SetPendingTOTPSecret", time.Now(), &err)
+	return s.next.SetPendingTOTPSecret(ctx, arg)
+}
+
+func (s *instrumentedStore) GetUserTOTP(ctx context.Context, userID uuid.UUID) (_ database.UserTotp, err error) {
+	defer s.metrics.observe("GetUserTOTP", time.Now(), &err)
+	return s.next.GetUserTOTP(ctx, userID)
+}
+
+func (s *instrumentedStore) EnableUserTOTP(ctx context.Context, arg database.EnableUserTOTPParams) (_ int64, err error) {
+	defer s.metrics.observe("EnableUserTOTP", time.Now(), &err)
+	return s.next.EnableUserTOTP(ctx, arg)
+}
+
+func (s *instrumentedStore) ReplaceBackupCodes(ctx context.Context, arg database.ReplaceBackupCodesParams) (err error) {
+	defer s.metrics.observe("ReplaceBackupCodes", time.Now(), &err)
+	return s.next.ReplaceBackupCodes(ctx, arg)
+}
+
+func (s *instrumentedStore) GetUnusedBackupCodes(ctx context.Context, userID uuid.UUID) (_ []database.GetUnusedBackupCodesRow, err error) {
+	defer s.metrics.observe("GetUnusedBackupCodes", time.Now(), &err)
+	return s.next.GetUnusedBackupCodes(ctx, userID)
+}
+
+func (s *instrumentedStore) UseBackupCode(ctx context.Context, id uuid.UUID) (_ int64, err error) {
+	defer s.metrics.observe("UseBackupCode", time.Now(), &err)
+	return s.next.UseBackupCode(ctx, id)
+}
+
+func (s *instrumentedStore) CreateAPIKey(ctx context.Context, arg database.CreateAPIKeyParams) (_ database.ApiKey, err error) {
+	defer s.metrics.observe("CreateAPIKey", time.Now(), &err)
+	return s.next.CreateAPIKey(ctx, arg)
+}
+
+func (s *instrumentedStore) DeleteAPIKey(ctx context.Context, arg database.DeleteAPIKeyParams) (_ int64, err error) {
+	defer s.metrics.observe("DeleteAPIKey", time.Now(), &err)
+	return s.next.DeleteAPIKey(ctx, arg)
+}
+
+func (s *instrumentedStore) GetAPIKeys(ctx context.Context, userID uuid.UUID) (_ []database.ApiKey, err error) {
+	defer s.metrics.observe("GetAPIKeys", time.Now(), &err)
+	return s.next.GetAPIKeys(ctx, userID)
+}
+
+func (s *instrumentedStore) GetUserIDByAPIKey(ctx context.Context, keyHash string) (_ uuid.UUID, err error) {
+	defer s.metrics.observe("GetUserIDByAPIKey", time.Now(), &err)
+	return s.next.GetUserIDByAPIKey(ctx, keyHash)
+}
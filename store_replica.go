@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// replicaStore sends the read-heavy queries behind the chirp lists, chirp
+// lookups, user directory and metrics counts to a read replica, and everything else to the
+// primary it embeds. A replica can lag behind, so only reads that tolerate
+// a slightly stale answer are routed to it; a request that must see its
+// own writes asks for the primary with withPrimary.
+type replicaStore struct {
+	store
+	replica store
+}
+
+var _ store = (*replicaStore)(nil)
+
+func newReplicaStore(primary, replica store) *replicaStore {
+	return &replicaStore{store: primary, replica: replica}
+}
+
+type primaryKey struct{}
+
+// withPrimary makes the queries run with ctx go to the primary even where
+// replicaStore would use the replica, as for logins and for the lookups
+// that decide whether a write is allowed.
+func withPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// reader is the store for a read-only query run with ctx.
+func (s *replicaStore) reader(ctx context.Context) store {
+	if primary, _ := ctx.Value(primaryKey{}).(bool); primary {
+		return s.store
+	}
+	return s.replica
+}
+
+func (s *replicaStore) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	return s.reader(ctx).GetUserByEmail(ctx, email)
+}
+
+func (s *replicaStore) ListUsers(ctx context.Context, arg database.ListUsersParams) ([]database.ListUsersRow, error) {
+	return s.reader(ctx).ListUsers(ctx, arg)
+}
+
+func (s *replicaStore) CountUsers(ctx context.Context) (int64, error) {
+	return s.reader(ctx).CountUsers(ctx)
+}
+
+func (s *replicaStore) CountChirps(ctx context.Context) (int64, error) {
+	return s.reader(ctx).CountChirps(ctx)
+}
+
+func (s *replicaStore) CountActiveRefreshTokens(ctx context.Context) (int64, error) {
+	return s.reader(ctx).CountActiveRefreshTokens(ctx)
+}
+
+func (s *replicaStore) GetChirps(ctx context.Context, arg database.GetChirpsParams) ([]database.GetChirpsRow, error) {
+	return s.reader(ctx).GetChirps(ctx, arg)
+}
+
+func (s *replicaStore) GetChirpsWithAuthors(ctx context.Context, arg database.GetChirpsWithAuthorsParams) ([]database.GetChirpsWithAuthorsRow, error) {
+	return s.reader(ctx).GetChirpsWithAuthors(ctx, arg)
+}
+
+func (s *replicaStore) GetChirpByID(ctx context.Context, id uuid.UUID) (database.GetChirpByIDRow, error) {
+	return s.reader(ctx).GetChirpByID(ctx, id)
+}
+
+func (s *replicaStore) GetChirpByIDWithAuthor(ctx context.Context, id uuid.UUID) (database.GetChirpByIDWithAuthorRow, error) {
+	return s.reader(ctx).GetChirpByIDWithAuthor(ctx, id)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// queriesRun lists the queries m saw, by name.
+func queriesRun(m *queryMetrics) []string {
+	var names []string
+	for _, q := range m.snapshot() {
+		names = append(names, q.Query)
+	}
+	return names
+}
+
+func TestReplicaStoreRouting(t *testing.T) {
+	primaryMetrics, replicaMetrics := &queryMetrics{}, &queryMetrics{}
+	s := newReplicaStore(
+		instrumentStore(newFakeStore(), primaryMetrics),
+		instrumentStore(newFakeStore(), replicaMetrics),
+	)
+	ctx := t.Context()
+	id := uuid.New()
+
+	tests := []struct {
+		query   string
+		call    func()
+		replica bool
+	}{
+		{"GetChirps", func() { s.GetChirps(ctx, database.GetChirpsParams{}) }, true},
+		{"GetChirpsWithAuthors", func() { s.GetChirpsWithAuthors(ctx, database.GetChirpsWithAuthorsParams{}) }, true},
+		{"GetChirpByID", func() { s.GetChirpByID(ctx, id) }, true},
+		{"GetChirpByIDWithAuthor", func() { s.GetChirpByIDWithAuthor(ctx, id) }, true},
+		{"GetUserByEmail", func() { s.GetUserByEmail(ctx, "alice@example.com") }, true},
+		{"CountUsers", func() { s.CountUsers(ctx) }, true},
+		{"ListUsers", func() { s.ListUsers(ctx, database.ListUsersParams{Limit: 10}) }, true},
+		{"CountChirps", func() { s.CountChirps(ctx) }, true},
+		{"CountActiveRefreshTokens", func() { s.CountActiveRefreshTokens(ctx) }, true},
+		// Reads that must see the latest writes
+		{"GetUserByEmail", func() { s.GetUserByEmail(withPrimary(ctx), "alice@example.com") }, false},
+		{"GetChirpByID", func() { s.GetChirpByID(withPrimary(ctx), id) }, false},
+		{"GetUserFromRefreshToken", func() { s.GetUserFromRefreshToken(ctx, "token") }, false},
+		{"GetUserByID", func() { s.GetUserByID(ctx, id) }, false},
+		{"CreateChirp", func() { s.CreateChirp(ctx, database.CreateChirpParams{Body: "hi", UserID: id}) }, false},
+		{"DeleteChirp", func() { s.DeleteChirp(ctx, id) }, false},
+	}
+	for _, tt := range tests {
+		primaryMetrics.reset()
+		replicaMetrics.reset()
+		tt.call()
+
+		want, other := primaryMetrics, replicaMetrics
+		if tt.replica {
+			want, other = replicaMetrics, primaryMetrics
+		}
+		if got := queriesRun(want); !slices.Equal(got, []string{tt.query}) {
+			t.Errorf("%s (replica %v): ran %v on the store it belongs to", tt.query, tt.replica, got)
+		}
+		if got := queriesRun(other); len(got) != 0 {
+			t.Errorf("%s (replica %v): ran %v on the other store", tt.query, tt.replica, got)
+		}
+	}
+}
+
+func TestReplicaStoreHandlers(t *testing.T) {
+	cfg, primary := newTestConfig(t)
+	replica := newFakeStore()
+	primaryMetrics, replicaMetrics := &queryMetrics{}, &queryMetrics{}
+	cfg.dbQueries = newReplicaStore(
+		instrumentStore(primary, primaryMetrics),
+		instrumentStore(replica, replicaMetrics),
+	)
+	srv := newTestServer(t, cfg)
+
+	// The replica has not caught up with the signup or the chirp yet
+	credentials := map[string]string{"email": "alice@example.com", "password": "password123"}
+	var user User
+	if resp := call(t, srv, http.MethodPost, "/api/users", "", credentials, &user); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("signup: got status %v want %v", resp.StatusCode, http.StatusCreated)
+	}
+	chirp := primary.addChirp(t, user.ID, "Not replicated yet")
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	if resp := call(t, srv, http.MethodPost, "/api/login", "", credentials, &login); resp.StatusCode != http.StatusOK {
+		t.Fatalf("login: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+
+	var chirps []Chirp
+	if resp := call(t, srv, http.MethodGet, "/api/chirps", "", nil, &chirps); resp.StatusCode != http.StatusOK {
+		t.Fatalf("list chirps: got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if len(chirps) != 0 {
+		t.Errorf("listed %d chirps from the replica, want none", len(chirps))
+	}
+
+	if resp := call(t, srv, http.MethodDelete, "/api/chirps/"+chirp.ID.String(), login.Token, nil, nil); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("delete: got status %v want %v", resp.StatusCode, http.StatusNoContent)
+	}
+
+	if got := queriesRun(replicaMetrics); !slices.Contains(got, "GetChirps") || slices.Contains(got, "GetUserByEmail") || slices.Contains(got, "GetChirpByID") {
+		t.Errorf("replica ran %v, want GetChirps but not the login or delete lookups", got)
+	}
+	if got := queriesRun(primaryMetrics); !slices.Contains(got, "GetUserByEmail") || !slices.Contains(got, "GetChirpByID") || slices.Contains(got, "GetChirps") {
+		t.Errorf("primary ran %v, want the login and delete lookups but not GetChirps", got)
+	}
+	if _, err := primary.GetChirpByID(context.Background(), chirp.ID); err == nil {
+		t.Error("the chirp was not deleted on the primary")
+	}
+}
@@ -0,0 +1,2140 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/auth"
+	"github.com/AlexTLDR/chirpy/internal/config"
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const testJWTSecret = "test-secret"
+
+// uniqueViolation is the error Postgres returns for a duplicate value in the
+// named unique constraint.
+func uniqueViolation(constraint string) error {
+	return &pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint", Constraint: constraint}
+}
+
+// fakeStore is an in-memory implementation of store for handler tests.
+type fakeStore struct {
+	mu            sync.Mutex
+	users         []database.User
+	chirps        []database.Chirp
+	likes         []database.ChirpLike
+	bookmarks     []database.Bookmark
+	hashtags      []database.ChirpHashtag
+	mentions      []database.ChirpMention
+	entities      []database.ChirpEntity
+	revisions     []database.ChirpRevision
+	reports       []database.ChirpReport
+	follows       []database.Follow
+	followReqs    []database.FollowRequest
+	mutes         []database.Mute
+	notifications []database.Notification
+	webhookEvents []database.WebhookEvent
+	auditLog      []database.AuditLog
+	refreshTokens []database.RefreshToken
+	apiKeys       []database.ApiKey
+	idempotency   []database.IdempotencyKey
+	loginHistory  []database.LoginHistory
+	magicLinks    []database.MagicLinkToken
+	totp          []database.UserTotp
+	backupCodes   []database.BackupCode
+	lastNow       time.Time
+}
+
+var _ store = (*fakeStore)(nil)
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{}
+}
+
+// newTestConfig returns an apiConfig backed by a fresh fakeStore.
+func newTestConfig(t *testing.T) (*apiConfig, *fakeStore) {
+	t.Helper()
+	db := newFakeStore()
+	cfg := &apiConfig{
+		fileserverHits:  atomic.Int32{},
+		dbQueries:       db,
+		platform:        "dev",
+		jwtSecrets:      []string{testJWTSecret},
+		polkaKey:        "test-polka-key",
+		notifier:        storeNotifier{db: db},
+		chirpHub:        newChirpHub(),
+		chirpCache:      newChirpListCache(time.Minute),
+		mediaDir:        t.TempDir(),
+		refreshTokenTTL: refreshTokenTTL(config.Default().RefreshTokenTTL),
+		accessTokenTTL:  config.Default().AccessTokenTTL,
+		purgeBatchSize:  config.Default().PurgeBatchSize,
+		passwordHashing: config.Default().PasswordHashing,
+		startedAt:       time.Now(),
+	}
+	// Not started: tests drive it with runOnce
+	cfg.webhooks = newWebhookWorker(db, cfg.processWebhookEvent)
+	return cfg, db
+}
+
+// addUser inserts a user directly into the fake store.
+func (f *fakeStore) addUser(t *testing.T, email string) database.User {
+	t.Helper()
+	// Usernames are picked the way migration 019 backfills them
+	base := defaultUsername(email)
+	username := base
+	for n := 2; f.usernameTaken(username); n++ {
+		username = fmt.Sprintf("%s_%d", base, n)
+	}
+	user, err := f.CreateUser(context.Background(), database.CreateUserParams{
+		Email:          email,
+		HashedPassword: sql.NullString{String: "unset", Valid: true},
+		Username:       username,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	return user
+}
+
+func (f *fakeStore) usernameTaken(username string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if u.Username == username {
+			return true
+		}
+	}
+	return reservedUsernames[username]
+}
+
+// addAdmin inserts a user with the admin flag set, as a seed would.
+func (f *fakeStore) addAdmin(t *testing.T, email string) database.User {
+	t.Helper()
+	user := f.addUser(t, email)
+	if _, err := f.SetUserAdmin(context.Background(), database.SetUserAdminParams{ID: user.ID, IsAdmin: true}); err != nil {
+		t.Fatalf("Failed to make admin: %v", err)
+	}
+	user.IsAdmin = true
+	return user
+}
+
+// addChirp inserts a chirp directly into the fake store.
+func (f *fakeStore) addChirp(t *testing.T, userID uuid.UUID, body string) database.Chirp {
+	t.Helper()
+	chirp, err := f.CreateChirp(context.Background(), database.CreateChirpParams{
+		Body:   body,
+		UserID: userID,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create chirp: %v", err)
+	}
+	return chirp
+}
+
+// makeTestToken mints an access token for userID signed with testJWTSecret.
+func makeTestToken(t *testing.T, userID uuid.UUID) string {
+	t.Helper()
+	token, err := auth.MakeJWT(userID, testJWTSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to make JWT: %v", err)
+	}
+	return token
+}
+
+// paginate applies LIMIT/OFFSET semantics to an already ordered slice.
+func paginate[T any](items []T, limit, offset int32) []T {
+	if int(offset) >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if int(limit) < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// sortNewestFirst orders rows by created_at DESC, id DESC like the SQL
+// queries do.
+func sortNewestFirst[T any](rows []T, chirp func(T) database.Chirp) {
+	slices.SortStableFunc(rows, func(a, b T) int {
+		ca, cb := chirp(a), chirp(b)
+		if c := cb.CreatedAt.Compare(ca.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(cb.ID[:], ca.ID[:])
+	})
+}
+
+// now returns a strictly increasing timestamp so rows created back to back
+// still have a well-defined order. It must be called with f.mu held.
+func (f *fakeStore) now() time.Time {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if !now.After(f.lastNow) {
+		now = f.lastNow.Add(time.Microsecond)
+	}
+	f.lastNow = now
+	return now
+}
+
+func (f *fakeStore) CreateOAuthUser(ctx context.Context, arg database.CreateOAuthUserParams) (database.User, error) {
+	user, err := f.CreateUser(ctx, database.CreateUserParams{Email: arg.Email, Username: arg.Username})
+	if err != nil {
+		return database.User{}, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := slices.IndexFunc(f.users, func(u database.User) bool { return u.ID == user.ID })
+	f.users[i].AuthProvider = arg.AuthProvider
+	return f.users[i], nil
+}
+
+func (f *fakeStore) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if u.Email == arg.Email {
+			return database.User{}, uniqueViolation(emailConstraint)
+		}
+		if u.Username == arg.Username {
+			return database.User{}, uniqueViolation(usernameConstraint)
+		}
+	}
+	now := f.now()
+	user := database.User{
+		ID:             uuid.New(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+		Username:       arg.Username,
+		DisplayName:    arg.DisplayName,
+		Bio:            arg.Bio,
+		AuthProvider:   "password",
+	}
+	f.users = append(f.users, user)
+	return user, nil
+}
+
+func (f *fakeStore) DeleteAllUsers(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.users = nil
+	f.chirps = nil
+	f.likes = nil
+	f.bookmarks = nil
+	f.hashtags = nil
+	f.mentions = nil
+	f.entities = nil
+	f.revisions = nil
+	f.reports = nil
+	f.follows = nil
+	f.followReqs = nil
+	f.mutes = nil
+	f.notifications = nil
+	f.refreshTokens = nil
+	f.apiKeys = nil
+	f.idempotency = nil
+	f.loginHistory = nil
+	f.magicLinks = nil
+	f.totp = nil
+	f.backupCodes = nil
+	return nil
+}
+
+func (f *fakeStore) DeleteUserKeepChirps(ctx context.Context, id uuid.UUID) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := slices.IndexFunc(f.users, func(u database.User) bool { return u.ID == id && !u.DeletedAt.Valid })
+	if i < 0 {
+		return 0, nil
+	}
+	now := f.now()
+	f.users[i] = database.User{
+		ID:           id,
+		CreatedAt:    f.users[i].CreatedAt,
+		UpdatedAt:    now,
+		Email:        id.String() + "@deleted.invalid",
+		Username:     "deleted_" + strings.ReplaceAll(id.String(), "-", "")[:22],
+		AuthProvider: f.users[i].AuthProvider,
+		DeletedAt:    sql.NullTime{Time: now, Valid: true},
+	}
+	f.refreshTokens = slices.DeleteFunc(f.refreshTokens, func(t database.RefreshToken) bool { return t.UserID == id })
+	f.apiKeys = slices.DeleteFunc(f.apiKeys, func(k database.ApiKey) bool { return k.UserID == id })
+	f.idempotency = slices.DeleteFunc(f.idempotency, func(k database.IdempotencyKey) bool { return k.UserID == id })
+	f.loginHistory = slices.DeleteFunc(f.loginHistory, func(e database.LoginHistory) bool { return e.UserID == id })
+	f.magicLinks = slices.DeleteFunc(f.magicLinks, func(m database.MagicLinkToken) bool { return m.UserID == id })
+	f.totp = slices.DeleteFunc(f.totp, func(t database.UserTotp) bool { return t.UserID == id })
+	f.backupCodes = slices.DeleteFunc(f.backupCodes, func(c database.BackupCode) bool { return c.UserID == id })
+	f.likes = slices.DeleteFunc(f.likes, func(l database.ChirpLike) bool { return l.UserID == id })
+	f.bookmarks = slices.DeleteFunc(f.bookmarks, func(b database.Bookmark) bool { return b.UserID == id })
+	f.reports = slices.DeleteFunc(f.reports, func(r database.ChirpReport) bool { return r.ReporterID == id })
+	f.follows = slices.DeleteFunc(f.follows, func(fl database.Follow) bool { return fl.FollowerID == id || fl.FolloweeID == id })
+	f.followReqs = slices.DeleteFunc(f.followReqs, func(fr database.FollowRequest) bool { return fr.RequesterID == id || fr.TargetID == id })
+	f.mutes = slices.DeleteFunc(f.mutes, func(m database.Mute) bool { return m.MuterID == id || m.MutedID == id })
+	f.notifications = slices.DeleteFunc(f.notifications, func(n database.Notification) bool { return n.UserID == id || n.ActorID == id })
+	return 1, nil
+}
+
+func (f *fakeStore) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) UsernameExists(ctx context.Context, username string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if u.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeStore) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if u.ID == arg.ID && arg.ExpectedUpdatedAt.Valid && !u.UpdatedAt.Equal(arg.ExpectedUpdatedAt.Time) {
+			return database.User{}, sql.ErrNoRows
+		}
+	}
+	for _, u := range f.users {
+		if u.ID == arg.ID {
+			continue
+		}
+		if arg.Email.Valid && u.Email == arg.Email.String {
+			return database.User{}, uniqueViolation(emailConstraint)
+		}
+		if arg.Username.Valid && u.Username == arg.Username.String {
+			return database.User{}, uniqueViolation(usernameConstraint)
+		}
+	}
+	// Like the query, NULL arguments leave a field alone and an empty
+	// display name or bio clears it
+	update := func(field *string, value sql.NullString) {
+		if value.Valid {
+			*field = value.String
+		}
+	}
+	clearable := func(field *sql.NullString, value sql.NullString) {
+		if value.Valid {
+			*field = sql.NullString{String: value.String, Valid: value.String != ""}
+		}
+	}
+	for i, u := range f.users {
+		if u.ID == arg.ID {
+			update(&f.users[i].Email, arg.Email)
+			if arg.HashedPassword.Valid {
+				f.users[i].HashedPassword = arg.HashedPassword
+			}
+			update(&f.users[i].Username, arg.Username)
+			clearable(&f.users[i].DisplayName, arg.DisplayName)
+			clearable(&f.users[i].Bio, arg.Bio)
+			f.users[i].UpdatedAt = f.now()
+			return f.users[i], nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) RehashUserPassword(ctx context.Context, arg database.RehashUserPasswordParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, u := range f.users {
+		if u.ID == arg.ID && u.HashedPassword.Valid && u.HashedPassword.String == arg.OldHash {
+			f.users[i].HashedPassword.String = arg.NewHash
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (f *fakeStore) SetUserAdmin(ctx context.Context, arg database.SetUserAdminParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, u := range f.users {
+		if u.ID == arg.ID {
+			f.users[i].IsAdmin = arg.IsAdmin
+			f.users[i].UpdatedAt = f.now()
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (f *fakeStore) BanUser(ctx context.Context, arg database.BanUserParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, u := range f.users {
+		if u.ID == arg.ID {
+			f.users[i].BannedAt = sql.NullTime{Time: f.now(), Valid: true}
+			f.users[i].BannedUntil = arg.BannedUntil
+			f.users[i].BanReason = arg.BanReason
+			f.users[i].UpdatedAt = f.now()
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (f *fakeStore) UnbanUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, u := range f.users {
+		if u.ID == id {
+			f.users[i].BannedAt = sql.NullTime{}
+			f.users[i].BannedUntil = sql.NullTime{}
+			f.users[i].BanReason = sql.NullString{}
+			f.users[i].UpdatedAt = f.now()
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (f *fakeStore) GetAccountStatus(ctx context.Context, id uuid.UUID) (database.GetAccountStatusRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if u.ID == id {
+			return database.GetAccountStatusRow{Deleted: u.DeletedAt.Valid, Banned: banned(u)}, nil
+		}
+	}
+	return database.GetAccountStatusRow{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) ListUsers(ctx context.Context, arg database.ListUsersParams) ([]database.ListUsersRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.ListUsersRow
+	for _, u := range f.users {
+		if u.DeletedAt.Valid || banned(u) || (arg.IsChirpyRed.Valid && u.IsChirpyRed != arg.IsChirpyRed.Bool) {
+			continue
+		}
+		rows = append(rows, database.ListUsersRow{ID: u.ID, CreatedAt: u.CreatedAt, Email: u.Email, Username: u.Username, DisplayName: u.DisplayName, Bio: u.Bio, AvatarUrl: u.AvatarUrl, IsChirpyRed: u.IsChirpyRed, IsPrivate: u.IsPrivate})
+	}
+	slices.SortStableFunc(rows, func(a, b database.ListUsersRow) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+	return paginate(rows, arg.Limit, arg.Offset), nil
+}
+
+func (f *fakeStore) SetUserAvatar(ctx context.Context, arg database.SetUserAvatarParams) (database.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, u := range f.users {
+		if u.ID == arg.ID {
+			f.users[i].AvatarUrl = arg.AvatarUrl
+			f.users[i].UpdatedAt = f.now()
+			return f.users[i], nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) SetUserPinnedChirp(ctx context.Context, arg database.SetUserPinnedChirpParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pinnable := slices.ContainsFunc(f.chirps, func(c database.Chirp) bool {
+		return c.ID == arg.ChirpID && c.UserID == arg.UserID && !c.DeletedAt.Valid
+	})
+	if !pinnable {
+		return 0, nil
+	}
+	for i, u := range f.users {
+		if u.ID == arg.UserID {
+			f.users[i].PinnedChirpID = uuid.NullUUID{UUID: arg.ChirpID, Valid: true}
+			f.users[i].UpdatedAt = f.now()
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (f *fakeStore) UnpinChirp(ctx context.Context, pinnedChirpID uuid.NullUUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.unpin(pinnedChirpID.UUID)
+	return nil
+}
+
+// unpin clears every pin of chirpID. The caller must hold f.mu.
+func (f *fakeStore) unpin(chirpID uuid.UUID) {
+	for i, u := range f.users {
+		if u.PinnedChirpID.Valid && u.PinnedChirpID.UUID == chirpID {
+			f.users[i].PinnedChirpID = uuid.NullUUID{}
+			f.users[i].UpdatedAt = f.now()
+		}
+	}
+}
+
+func (f *fakeStore) CountUsers(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return int64(len(f.users)), nil
+}
+
+func (f *fakeStore) CountUsersWithAvatar(ctx context.Context, avatarUrl sql.NullString) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var count int64
+	for _, u := range f.users {
+		if u.AvatarUrl.Valid && u.AvatarUrl == avatarUrl {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeStore) CountChirpsWithMedia(ctx context.Context, mediaUrl sql.NullString) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var count int64
+	for _, c := range f.chirps {
+		if c.MediaUrl.Valid && c.MediaUrl == mediaUrl {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeStore) UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, u := range f.users {
+		if u.ID == id {
+			f.users[i].IsChirpyRed = true
+			f.users[i].UpdatedAt = f.now()
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) SetUserPrivate(ctx context.Context, arg database.SetUserPrivateParams) (database.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, u := range f.users {
+		if u.ID == arg.ID {
+			f.users[i].IsPrivate = arg.IsPrivate
+			f.users[i].UpdatedAt = f.now()
+			return f.users[i], nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) SetUserPendingEmail(ctx context.Context, arg database.SetUserPendingEmailParams) (database.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, u := range f.users {
+		if u.ID == arg.ID {
+			f.users[i].PendingEmail = sql.NullString{String: arg.PendingEmail, Valid: true}
+			f.users[i].PendingEmailTokenHash = sql.NullString{String: arg.TokenHash, Valid: true}
+			f.users[i].PendingEmailExpiresAt = sql.NullTime{Time: arg.ExpiresAt, Valid: true}
+			f.users[i].UpdatedAt = f.now()
+			return f.users[i], nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) ConfirmPendingEmail(ctx context.Context, tokenHash string) (database.ConfirmPendingEmailRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := slices.IndexFunc(f.users, func(u database.User) bool {
+		return u.PendingEmailTokenHash.Valid && u.PendingEmailTokenHash.String == tokenHash && u.PendingEmailExpiresAt.Time.After(time.Now())
+	})
+	if i < 0 {
+		return database.ConfirmPendingEmailRow{}, sql.ErrNoRows
+	}
+	newEmail := f.users[i].PendingEmail.String
+	if slices.ContainsFunc(f.users, func(u database.User) bool { return u.Email == newEmail }) {
+		return database.ConfirmPendingEmailRow{}, uniqueViolation(emailConstraint)
+	}
+	row := database.ConfirmPendingEmailRow{ID: f.users[i].ID, Email: newEmail, OldEmail: f.users[i].Email}
+	f.users[i].Email = newEmail
+	f.users[i].PendingEmail = sql.NullString{}
+	f.users[i].PendingEmailTokenHash = sql.NullString{}
+	f.users[i].PendingEmailExpiresAt = sql.NullTime{}
+	f.users[i].UpdatedAt = f.now()
+	return row, nil
+}
+
+// canViewUser mirrors the can_view_user database function, including an
+// unknown author being visible to no one. The caller must hold f.mu.
+func (f *fakeStore) canViewUser(authorID uuid.UUID, viewerID uuid.NullUUID) bool {
+	i := slices.IndexFunc(f.users, func(u database.User) bool { return u.ID == authorID })
+	if i < 0 {
+		return false
+	}
+	if viewerID.Valid && viewerID.UUID == authorID {
+		return true
+	}
+	if banned(f.users[i]) {
+		return false
+	}
+	if !f.users[i].IsPrivate {
+		return true
+	}
+	return viewerID.Valid && slices.ContainsFunc(f.follows, func(fl database.Follow) bool {
+		return fl.FollowerID == viewerID.UUID && fl.FolloweeID == authorID
+	})
+}
+
+// banned reports whether u is banned right now, as GetAccountStatus does.
+func banned(u database.User) bool {
+	return u.BannedAt.Valid && (!u.BannedUntil.Valid || u.BannedUntil.Time.After(time.Now()))
+}
+
+func (f *fakeStore) CanViewUser(ctx context.Context, arg database.CanViewUserParams) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.canViewUser(arg.AuthorID, arg.ViewerID), nil
+}
+
+func (f *fakeStore) GetHiddenAuthorIDs(ctx context.Context, arg database.GetHiddenAuthorIDsParams) ([]uuid.UUID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var hidden []uuid.UUID
+	for _, id := range arg.AuthorIds {
+		// Unknown authors are NULL in SQL and so neither hidden nor shown
+		known := slices.ContainsFunc(f.users, func(u database.User) bool { return u.ID == id })
+		if known && !f.canViewUser(id, arg.ViewerID) {
+			hidden = append(hidden, id)
+		}
+	}
+	return hidden, nil
+}
+
+func (f *fakeStore) CountChirps(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var count int64
+	for _, c := range f.chirps {
+		if !c.DeletedAt.Valid {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeStore) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	chirp := database.Chirp{
+		ID:            uuid.New(),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		Body:          arg.Body,
+		UserID:        arg.UserID,
+		ParentChirpID: arg.ParentChirpID,
+		MediaUrl:      arg.MediaUrl,
+		MediaAlt:      arg.MediaAlt,
+	}
+	f.chirps = append(f.chirps, chirp)
+	return chirp, nil
+}
+
+func (f *fakeStore) CreateChirpAt(ctx context.Context, arg database.CreateChirpAtParams) (database.Chirp, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	chirp := database.Chirp{
+		ID:            uuid.New(),
+		CreatedAt:     arg.CreatedAt,
+		UpdatedAt:     arg.CreatedAt,
+		Body:          arg.Body,
+		UserID:        arg.UserID,
+		ParentChirpID: arg.ParentChirpID,
+	}
+	f.chirps = append(f.chirps, chirp)
+	return chirp, nil
+}
+
+func (f *fakeStore) CreateChirps(ctx context.Context, arg database.CreateChirpsParams) ([]database.Chirp, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var chirps []database.Chirp
+	for _, body := range arg.Bodies {
+		now := f.now()
+		chirps = append(chirps, database.Chirp{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Body:      body,
+			UserID:    arg.UserID,
+		})
+	}
+	f.chirps = append(f.chirps, chirps...)
+	return chirps, nil
+}
+
+// chirpRow builds the aggregate row for c. It must be called with f.mu held.
+func (f *fakeStore) chirpRow(c database.Chirp) database.GetChirpsRow {
+	row := database.GetChirpsRow{Chirp: c}
+	for _, l := range f.likes {
+		if l.ChirpID == c.ID {
+			row.LikeCount++
+		}
+	}
+	for _, reply := range f.chirps {
+		if !reply.DeletedAt.Valid && reply.ParentChirpID.Valid && reply.ParentChirpID.UUID == c.ID {
+			row.ReplyCount++
+		}
+	}
+	return row
+}
+
+// chirpDeleted reports whether the chirp with id has been soft-deleted. It
+// must be called with f.mu held.
+func (f *fakeStore) chirpDeleted(id uuid.UUID) bool {
+	for _, c := range f.chirps {
+		if c.ID == id {
+			return c.DeletedAt.Valid
+		}
+	}
+	return false
+}
+
+// chirpAuthor returns the author of the chirp with id, or uuid.Nil if there
+// is no such chirp. It must be called with f.mu held.
+func (f *fakeStore) chirpAuthor(id uuid.UUID) uuid.UUID {
+	for _, c := range f.chirps {
+		if c.ID == id {
+			return c.UserID
+		}
+	}
+	return uuid.Nil
+}
+
+// inCreatedRange reports whether createdAt falls within the optional
+// [from, before) bounds of the chirp list queries.
+func inCreatedRange(createdAt time.Time, from, before sql.NullTime) bool {
+	return (!from.Valid || !createdAt.Before(from.Time)) && (!before.Valid || createdAt.Before(before.Time))
+}
+
+func (f *fakeStore) GetChirps(ctx context.Context, arg database.GetChirpsParams) ([]database.GetChirpsRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.GetChirpsRow
+	for _, c := range f.chirps {
+		if !c.DeletedAt.Valid && inCreatedRange(c.CreatedAt, arg.CreatedFrom, arg.CreatedBefore) {
+			rows = append(rows, f.chirpRow(c))
+		}
+	}
+	sortNewestFirst(rows, func(row database.GetChirpsRow) database.Chirp { return row.Chirp })
+	return rows, nil
+}
+
+func (f *fakeStore) GetChirpByID(ctx context.Context, id uuid.UUID) (database.GetChirpByIDRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.chirps {
+		if c.ID == id && !c.DeletedAt.Valid {
+			return database.GetChirpByIDRow(f.chirpRow(c)), nil
+		}
+	}
+	return database.GetChirpByIDRow{}, sql.ErrNoRows
+}
+
+// chirpWithAuthorRow is chirpRow plus the author's profile, as the
+// WithAuthor queries join it in. It must be called with f.mu held.
+func (f *fakeStore) chirpWithAuthorRow(c database.Chirp) database.GetChirpsWithAuthorsRow {
+	row := f.chirpRow(c)
+	withAuthor := database.GetChirpsWithAuthorsRow{Chirp: c, LikeCount: row.LikeCount, ReplyCount: row.ReplyCount}
+	for _, u := range f.users {
+		if u.ID == c.UserID {
+			withAuthor.AuthorEmail = u.Email
+			withAuthor.AuthorUsername = u.Username
+			withAuthor.AuthorDisplayName = u.DisplayName
+			withAuthor.AuthorBio = u.Bio
+			withAuthor.AuthorAvatarUrl = u.AvatarUrl
+			withAuthor.AuthorIsChirpyRed = u.IsChirpyRed
+			withAuthor.AuthorDeleted = u.DeletedAt.Valid
+		}
+	}
+	return withAuthor
+}
+
+func (f *fakeStore) GetChirpByIDWithAuthor(ctx context.Context, id uuid.UUID) (database.GetChirpByIDWithAuthorRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.chirps {
+		if c.ID == id && !c.DeletedAt.Valid {
+			return database.GetChirpByIDWithAuthorRow(f.chirpWithAuthorRow(c)), nil
+		}
+	}
+	return database.GetChirpByIDWithAuthorRow{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetChirpsWithAuthors(ctx context.Context, arg database.GetChirpsWithAuthorsParams) ([]database.GetChirpsWithAuthorsRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.GetChirpsWithAuthorsRow
+	for _, c := range f.chirps {
+		if c.DeletedAt.Valid || (arg.AuthorID.Valid && c.UserID != arg.AuthorID.UUID) {
+			continue
+		}
+		if inCreatedRange(c.CreatedAt, arg.CreatedFrom, arg.CreatedBefore) {
+			rows = append(rows, f.chirpWithAuthorRow(c))
+		}
+	}
+	sortNewestFirst(rows, func(row database.GetChirpsWithAuthorsRow) database.Chirp { return row.Chirp })
+	return rows, nil
+}
+
+func (f *fakeStore) GetChirpsByUserID(ctx context.Context, arg database.GetChirpsByUserIDParams) ([]database.GetChirpsByUserIDRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.GetChirpsByUserIDRow
+	for _, c := range f.chirps {
+		if c.UserID == arg.UserID && !c.DeletedAt.Valid && inCreatedRange(c.CreatedAt, arg.CreatedFrom, arg.CreatedBefore) {
+			rows = append(rows, database.GetChirpsByUserIDRow(f.chirpRow(c)))
+		}
+	}
+	sortNewestFirst(rows, func(row database.GetChirpsByUserIDRow) database.Chirp { return row.Chirp })
+	return rows, nil
+}
+
+func (f *fakeStore) GetChirpsSince(ctx context.Context, arg database.GetChirpsSinceParams) ([]database.GetChirpsSinceRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// Chirps are stored in creation order, so everything after the
+	// Last-Event-ID chirp is newer. An unknown ID yields nothing.
+	since := slices.IndexFunc(f.chirps, func(c database.Chirp) bool { return c.ID == arg.SinceID })
+	if since < 0 {
+		return nil, nil
+	}
+	var rows []database.GetChirpsSinceRow
+	for _, c := range f.chirps[since+1:] {
+		if c.DeletedAt.Valid || (arg.AuthorID.Valid && c.UserID != arg.AuthorID.UUID) || !f.canViewUser(c.UserID, uuid.NullUUID{}) {
+			continue
+		}
+		rows = append(rows, database.GetChirpsSinceRow(f.chirpRow(c)))
+	}
+	return paginate(rows, arg.Limit, 0), nil
+}
+
+func (f *fakeStore) GetChirpReplies(ctx context.Context, arg database.GetChirpRepliesParams) ([]database.GetChirpRepliesRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.GetChirpRepliesRow
+	for _, c := range f.chirps {
+		if !c.DeletedAt.Valid && c.ParentChirpID.Valid && c.ParentChirpID.UUID == arg.ParentID && f.canViewUser(c.UserID, arg.ViewerID) {
+			rows = append(rows, database.GetChirpRepliesRow(f.chirpRow(c)))
+		}
+	}
+	return paginate(rows, arg.Limit, arg.Offset), nil
+}
+
+func (f *fakeStore) GetFeed(ctx context.Context, arg database.GetFeedParams) ([]database.GetFeedRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	followees := map[uuid.UUID]bool{}
+	for _, fl := range f.follows {
+		if fl.FollowerID == arg.UserID {
+			followees[fl.FolloweeID] = true
+		}
+	}
+	muted := map[uuid.UUID]bool{}
+	for _, m := range f.mutes {
+		if m.MuterID == arg.UserID {
+			muted[m.MutedID] = true
+		}
+	}
+	var rows []database.GetFeedRow
+	for _, c := range f.chirps {
+		if c.DeletedAt.Valid || muted[c.UserID] {
+			continue
+		}
+		if !f.canViewUser(c.UserID, uuid.NullUUID{UUID: arg.UserID, Valid: true}) {
+			continue
+		}
+		if followees[c.UserID] || (arg.IncludeSelf && c.UserID == arg.UserID) {
+			rows = append(rows, database.GetFeedRow(f.chirpRow(c)))
+		}
+	}
+	sortNewestFirst(rows, func(row database.GetFeedRow) database.Chirp { return row.Chirp })
+	return paginate(rows, arg.Limit, arg.Offset), nil
+}
+
+func (f *fakeStore) GetNthNewestChirpTime(ctx context.Context, arg database.GetNthNewestChirpTimeParams) (time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var times []time.Time
+	for _, c := range f.chirps {
+		if c.UserID == arg.UserID {
+			times = append(times, c.CreatedAt)
+		}
+	}
+	slices.SortFunc(times, func(a, b time.Time) int { return b.Compare(a) })
+	if int(arg.Offset) >= len(times) {
+		return time.Time{}, sql.ErrNoRows
+	}
+	return times[arg.Offset], nil
+}
+
+func (f *fakeStore) GetUserChirpStats(ctx context.Context, userID uuid.UUID) (database.GetUserChirpStatsRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var stats database.GetUserChirpStatsRow
+	for _, c := range f.chirps {
+		if c.UserID != userID || c.DeletedAt.Valid {
+			continue
+		}
+		stats.ChirpCount++
+		if !stats.FirstChirpAt.Valid || c.CreatedAt.Before(stats.FirstChirpAt.Time) {
+			stats.FirstChirpAt = sql.NullTime{Time: c.CreatedAt, Valid: true}
+		}
+		if !stats.LastChirpAt.Valid || c.CreatedAt.After(stats.LastChirpAt.Time) {
+			stats.LastChirpAt = sql.NullTime{Time: c.CreatedAt, Valid: true}
+		}
+	}
+	return stats, nil
+}
+
+func (f *fakeStore) GetUserChirpsAfter(ctx context.Context, arg database.GetUserChirpsAfterParams) ([]database.Chirp, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var chirps []database.Chirp
+	for _, c := range f.chirps {
+		if c.UserID != arg.UserID || c.DeletedAt.Valid {
+			continue
+		}
+		after := cmp.Or(c.CreatedAt.Compare(arg.AfterCreatedAt), bytes.Compare(c.ID[:], arg.AfterID[:]))
+		if after > 0 {
+			chirps = append(chirps, c)
+		}
+	}
+	slices.SortFunc(chirps, func(a, b database.Chirp) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), bytes.Compare(a.ID[:], b.ID[:]))
+	})
+	return paginate(chirps, arg.Limit, 0), nil
+}
+
+func (f *fakeStore) UpdateChirp(ctx context.Context, arg database.UpdateChirpParams) (database.Chirp, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, c := range f.chirps {
+		if c.ID != arg.ID || c.DeletedAt.Valid {
+			continue
+		}
+		f.revisions = append(f.revisions, database.ChirpRevision{
+			ID:        uuid.New(),
+			ChirpID:   c.ID,
+			Body:      c.Body,
+			CreatedAt: c.UpdatedAt,
+		})
+		f.chirps[i].Body = arg.Body
+		f.chirps[i].UpdatedAt = f.now()
+		return f.chirps[i], nil
+	}
+	return database.Chirp{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetChirpRevisions(ctx context.Context, arg database.GetChirpRevisionsParams) ([]database.ChirpRevision, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.ChirpRevision
+	// Revisions are appended in edit order, so walk backwards for newest first
+	for i := len(f.revisions) - 1; i >= 0; i-- {
+		if f.revisions[i].ChirpID == arg.ChirpID {
+			rows = append(rows, f.revisions[i])
+		}
+	}
+	return paginate(rows, arg.Limit, arg.Offset), nil
+}
+
+func (f *fakeStore) HasRecentDuplicateChirp(ctx context.Context, arg database.HasRecentDuplicateChirpParams) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.ContainsFunc(f.chirps, func(c database.Chirp) bool {
+		return c.UserID == arg.UserID && c.Body == arg.Body && c.ParentChirpID == arg.ParentChirpID &&
+			!c.CreatedAt.Before(arg.Since) && !c.DeletedAt.Valid
+	}), nil
+}
+
+func (f *fakeStore) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleteChirps(func(c database.Chirp) bool { return c.ID == id })
+	return nil
+}
+
+func (f *fakeStore) DeleteUserChirpsByIDs(ctx context.Context, arg database.DeleteUserChirpsByIDsParams) ([]uuid.UUID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.deleteChirps(func(c database.Chirp) bool {
+		return c.UserID == arg.UserID && slices.Contains(arg.ChirpIds, c.ID)
+	}), nil
+}
+
+func (f *fakeStore) DeleteUserChirpsBefore(ctx context.Context, arg database.DeleteUserChirpsBeforeParams) ([]uuid.UUID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.deleteChirps(func(c database.Chirp) bool {
+		return c.UserID == arg.UserID && c.CreatedAt.Before(arg.Before)
+	}), nil
+}
+
+func (f *fakeStore) CountChirpsBefore(ctx context.Context, before time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var count int64
+	for _, c := range f.chirps {
+		if c.CreatedAt.Before(before) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeStore) PurgeChirpsBefore(ctx context.Context, arg database.PurgeChirpsBeforeParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var purged []database.Chirp
+	for _, c := range f.chirps {
+		if c.CreatedAt.Before(arg.Before) {
+			purged = append(purged, c)
+		}
+	}
+	// The oldest go first, as with the real query's ORDER BY
+	slices.SortFunc(purged, func(a, b database.Chirp) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	purged = purged[:min(len(purged), int(arg.Limit))]
+	isPurged := func(id uuid.UUID) bool {
+		return slices.ContainsFunc(purged, func(c database.Chirp) bool { return c.ID == id })
+	}
+
+	f.chirps = slices.DeleteFunc(f.chirps, func(c database.Chirp) bool { return isPurged(c.ID) })
+	// Cascades and SET NULLs of the foreign keys
+	f.likes = slices.DeleteFunc(f.likes, func(l database.ChirpLike) bool { return isPurged(l.ChirpID) })
+	f.bookmarks = slices.DeleteFunc(f.bookmarks, func(b database.Bookmark) bool { return isPurged(b.ChirpID) })
+	f.hashtags = slices.DeleteFunc(f.hashtags, func(h database.ChirpHashtag) bool { return isPurged(h.ChirpID) })
+	f.mentions = slices.DeleteFunc(f.mentions, func(m database.ChirpMention) bool { return isPurged(m.ChirpID) })
+	f.entities = slices.DeleteFunc(f.entities, func(e database.ChirpEntity) bool { return isPurged(e.ChirpID) })
+	f.revisions = slices.DeleteFunc(f.revisions, func(r database.ChirpRevision) bool { return isPurged(r.ChirpID) })
+	f.reports = slices.DeleteFunc(f.reports, func(r database.ChirpReport) bool { return isPurged(r.ChirpID) })
+	f.notifications = slices.DeleteFunc(f.notifications, func(n database.Notification) bool {
+		return n.ChirpID.Valid && isPurged(n.ChirpID.UUID)
+	})
+	for i, c := range f.chirps {
+		if c.ParentChirpID.Valid && isPurged(c.ParentChirpID.UUID) {
+			f.chirps[i].ParentChirpID = uuid.NullUUID{}
+		}
+	}
+	for i, u := range f.users {
+		if u.PinnedChirpID.Valid && isPurged(u.PinnedChirpID.UUID) {
+			f.users[i].PinnedChirpID = uuid.NullUUID{}
+		}
+	}
+	return int64(len(purged)), nil
+}
+
+func (f *fakeStore) AddChirpViews(ctx context.Context, arg database.AddChirpViewsParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, c := range f.chirps {
+		if j := slices.Index(arg.ChirpIds, c.ID); j >= 0 {
+			f.chirps[i].ViewCount += arg.Views[j]
+		}
+	}
+	return nil
+}
+
+// deleteChirps soft-deletes the live chirps that match and returns their
+// IDs. Callers hold f.mu.
+func (f *fakeStore) deleteChirps(match func(c database.Chirp) bool) []uuid.UUID {
+	now := f.now()
+	var deleted []uuid.UUID
+	for i, c := range f.chirps {
+		if !c.DeletedAt.Valid && match(c) {
+			f.chirps[i].DeletedAt = sql.NullTime{Time: now, Valid: true}
+			f.chirps[i].UpdatedAt = now
+			deleted = append(deleted, c.ID)
+		}
+	}
+	// Surviving replies are detached and pins cleared just like the real
+	// queries do
+	for i, c := range f.chirps {
+		if c.ParentChirpID.Valid && slices.Contains(deleted, c.ParentChirpID.UUID) && !slices.Contains(deleted, c.ID) {
+			f.chirps[i].ParentChirpID = uuid.NullUUID{}
+		}
+	}
+	for _, id := range deleted {
+		f.unpin(id)
+	}
+	return deleted
+}
+
+func (f *fakeStore) LikeChirp(ctx context.Context, arg database.LikeChirpParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, l := range f.likes {
+		if l.ChirpID == arg.ChirpID && l.UserID == arg.UserID {
+			return 0, nil
+		}
+	}
+	f.likes = append(f.likes, database.ChirpLike{
+		ChirpID:   arg.ChirpID,
+		UserID:    arg.UserID,
+		CreatedAt: f.now(),
+	})
+	return 1, nil
+}
+
+func (f *fakeStore) UnlikeChirp(ctx context.Context, arg database.UnlikeChirpParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.likes = slices.DeleteFunc(f.likes, func(l database.ChirpLike) bool {
+		return l.ChirpID == arg.ChirpID && l.UserID == arg.UserID
+	})
+	return nil
+}
+
+func (f *fakeStore) GetLikedChirpIDs(ctx context.Context, arg database.GetLikedChirpIDsParams) ([]uuid.UUID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ids []uuid.UUID
+	for _, l := range f.likes {
+		if l.UserID == arg.UserID && slices.Contains(arg.ChirpIds, l.ChirpID) {
+			ids = append(ids, l.ChirpID)
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeStore) GetChirpLikers(ctx context.Context, arg database.GetChirpLikersParams) ([]database.GetChirpLikersRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.GetChirpLikersRow
+	for _, l := range f.likes {
+		if l.ChirpID != arg.ChirpID {
+			continue
+		}
+		for _, u := range f.users {
+			if u.ID == l.UserID {
+				rows = append(rows, database.GetChirpLikersRow{ID: u.ID, Email: u.Email, Username: u.Username, DisplayName: u.DisplayName, Bio: u.Bio, AvatarUrl: u.AvatarUrl, IsChirpyRed: u.IsChirpyRed})
+			}
+		}
+	}
+	return paginate(rows, arg.Limit, arg.Offset), nil
+}
+
+func (f *fakeStore) GetUserLikeStats(ctx context.Context, userID uuid.UUID) (database.GetUserLikeStatsRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var stats database.GetUserLikeStatsRow
+	for _, l := range f.likes {
+		for _, c := range f.chirps {
+			if c.ID != l.ChirpID || c.DeletedAt.Valid {
+				continue
+			}
+			if c.UserID == userID {
+				stats.LikesReceived++
+			}
+			if l.UserID == userID {
+				stats.LikesGiven++
+			}
+		}
+	}
+	return stats, nil
+}
+
+func (f *fakeStore) CreateBookmark(ctx context.Context, arg database.CreateBookmarkParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, b := range f.bookmarks {
+		if b.UserID == arg.UserID && b.ChirpID == arg.ChirpID {
+			return nil
+		}
+	}
+	f.bookmarks = append(f.bookmarks, database.Bookmark{UserID: arg.UserID, ChirpID: arg.ChirpID, CreatedAt: f.now()})
+	return nil
+}
+
+func (f *fakeStore) DeleteBookmark(ctx context.Context, arg database.DeleteBookmarkParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bookmarks = slices.DeleteFunc(f.bookmarks, func(b database.Bookmark) bool {
+		return b.UserID == arg.UserID && b.ChirpID == arg.ChirpID
+	})
+	return nil
+}
+
+func (f *fakeStore) GetBookmarkedChirps(ctx context.Context, arg database.GetBookmarkedChirpsParams) ([]database.GetBookmarkedChirpsRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var bookmarks []database.Bookmark
+	for _, b := range f.bookmarks {
+		if b.UserID == arg.UserID && !f.chirpDeleted(b.ChirpID) && f.canViewUser(f.chirpAuthor(b.ChirpID), uuid.NullUUID{UUID: b.UserID, Valid: true}) {
+			bookmarks = append(bookmarks, b)
+		}
+	}
+	slices.SortFunc(bookmarks, func(a, b database.Bookmark) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	var rows []database.GetBookmarkedChirpsRow
+	for _, b := range paginate(bookmarks, arg.Limit, arg.Offset) {
+		for _, c := range f.chirps {
+			if c.ID == b.ChirpID {
+				rows = append(rows, database.GetBookmarkedChirpsRow(f.chirpRow(c)))
+			}
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) CreateChirpReport(ctx context.Context, arg database.CreateChirpReportParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, rep := range f.reports {
+		if rep.ChirpID == arg.ChirpID && rep.ReporterID == arg.ReporterID {
+			return 0, nil
+		}
+	}
+	f.reports = append(f.reports, database.ChirpReport{
+		ChirpID:    arg.ChirpID,
+		ReporterID: arg.ReporterID,
+		Reason:     arg.Reason,
+		CreatedAt:  f.now(),
+	})
+	return 1, nil
+}
+
+func (f *fakeStore) GetReportedChirps(ctx context.Context, arg database.GetReportedChirpsParams) ([]database.GetReportedChirpsRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.GetReportedChirpsRow
+	for _, c := range f.chirps {
+		if c.DeletedAt.Valid {
+			continue
+		}
+		row := database.GetReportedChirpsRow{Chirp: c, Reasons: []string{}}
+		for _, rep := range f.reports {
+			if rep.ChirpID != c.ID || rep.ResolvedAt.Valid {
+				continue
+			}
+			if row.ReportCount == 0 {
+				row.FirstReportedAt = rep.CreatedAt
+			}
+			row.ReportCount++
+			if rep.Reason != "" {
+				row.Reasons = append(row.Reasons, rep.Reason)
+			}
+		}
+		if row.ReportCount > 0 {
+			rows = append(rows, row)
+		}
+	}
+	slices.SortStableFunc(rows, func(a, b database.GetReportedChirpsRow) int {
+		if a.ReportCount != b.ReportCount {
+			return int(b.ReportCount - a.ReportCount)
+		}
+		return a.FirstReportedAt.Compare(b.FirstReportedAt)
+	})
+	return paginate(rows, arg.Limit, arg.Offset), nil
+}
+
+func (f *fakeStore) ResolveChirpReports(ctx context.Context, chirpID uuid.UUID) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	var resolved int64
+	for i, rep := range f.reports {
+		if rep.ChirpID == chirpID && !rep.ResolvedAt.Valid {
+			f.reports[i].ResolvedAt = sql.NullTime{Time: now, Valid: true}
+			resolved++
+		}
+	}
+	return resolved, nil
+}
+
+func (f *fakeStore) AddChirpHashtag(ctx context.Context, arg database.AddChirpHashtagParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	row := database.ChirpHashtag(arg)
+	if !slices.Contains(f.hashtags, row) {
+		f.hashtags = append(f.hashtags, row)
+	}
+	return nil
+}
+
+func (f *fakeStore) DeleteChirpHashtags(ctx context.Context, chirpID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hashtags = slices.DeleteFunc(f.hashtags, func(h database.ChirpHashtag) bool { return h.ChirpID == chirpID })
+	return nil
+}
+
+func (f *fakeStore) GetChirpsByHashtag(ctx context.Context, arg database.GetChirpsByHashtagParams) ([]database.GetChirpsByHashtagRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.GetChirpsByHashtagRow
+	for _, c := range f.chirps {
+		if !c.DeletedAt.Valid && slices.Contains(f.hashtags, database.ChirpHashtag{ChirpID: c.ID, Tag: arg.Tag}) && f.canViewUser(c.UserID, arg.ViewerID) {
+			rows = append(rows, database.GetChirpsByHashtagRow(f.chirpRow(c)))
+		}
+	}
+	sortNewestFirst(rows, func(row database.GetChirpsByHashtagRow) database.Chirp { return row.Chirp })
+	return paginate(rows, arg.Limit, arg.Offset), nil
+}
+
+func (f *fakeStore) AddChirpEntity(ctx context.Context, arg database.AddChirpEntityParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range f.entities {
+		if e.ChirpID == arg.ChirpID && e.StartIndex == arg.StartIndex {
+			return uniqueViolation("chirp_entities_pkey")
+		}
+	}
+	f.entities = append(f.entities, database.ChirpEntity(arg))
+	return nil
+}
+
+func (f *fakeStore) DeleteChirpEntities(ctx context.Context, chirpID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entities = slices.DeleteFunc(f.entities, func(e database.ChirpEntity) bool { return e.ChirpID == chirpID })
+	return nil
+}
+
+func (f *fakeStore) GetChirpEntities(ctx context.Context, chirpIds []uuid.UUID) ([]database.ChirpEntity, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.ChirpEntity
+	for _, e := range f.entities {
+		if slices.Contains(chirpIds, e.ChirpID) {
+			rows = append(rows, e)
+		}
+	}
+	slices.SortFunc(rows, func(a, b database.ChirpEntity) int {
+		return cmp.Or(bytes.Compare(a.ChirpID[:], b.ChirpID[:]), cmp.Compare(a.StartIndex, b.StartIndex))
+	})
+	return rows, nil
+}
+
+func (f *fakeStore) AddChirpMention(ctx context.Context, arg database.AddChirpMentionParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	row := database.ChirpMention(arg)
+	if !slices.Contains(f.mentions, row) {
+		f.mentions = append(f.mentions, row)
+	}
+	return nil
+}
+
+func (f *fakeStore) DeleteChirpMentions(ctx context.Context, chirpID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mentions = slices.DeleteFunc(f.mentions, func(m database.ChirpMention) bool { return m.ChirpID == chirpID })
+	return nil
+}
+
+func (f *fakeStore) GetChirpMentions(ctx context.Context, chirpIds []uuid.UUID) ([]database.ChirpMention, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.ChirpMention
+	for _, m := range f.mentions {
+		if slices.Contains(chirpIds, m.ChirpID) {
+			rows = append(rows, m)
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) ResolveMentionHandles(ctx context.Context, handles []string) ([]database.ResolveMentionHandlesRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.ResolveMentionHandlesRow
+	for _, u := range f.users {
+		if slices.Contains(handles, u.Username) {
+			rows = append(rows, database.ResolveMentionHandlesRow{ID: u.ID, Handle: u.Username})
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) CreateFollow(ctx context.Context, arg database.CreateFollowParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, fl := range f.follows {
+		if fl.FollowerID == arg.FollowerID && fl.FolloweeID == arg.FolloweeID {
+			return 0, nil
+		}
+	}
+	f.follows = append(f.follows, database.Follow{
+		FollowerID: arg.FollowerID,
+		FolloweeID: arg.FolloweeID,
+		CreatedAt:  f.now(),
+	})
+	return 1, nil
+}
+
+func (f *fakeStore) DeleteFollow(ctx context.Context, arg database.DeleteFollowParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.follows = slices.DeleteFunc(f.follows, func(fl database.Follow) bool {
+		return fl.FollowerID == arg.FollowerID && fl.FolloweeID == arg.FolloweeID
+	})
+	return nil
+}
+
+func (f *fakeStore) CountFollowers(ctx context.Context, followeeID uuid.UUID) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int64
+	for _, fl := range f.follows {
+		if fl.FolloweeID == followeeID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (f *fakeStore) CountFollowing(ctx context.Context, followerID uuid.UUID) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int64
+	for _, fl := range f.follows {
+		if fl.FollowerID == followerID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (f *fakeStore) GetFollowers(ctx context.Context, arg database.GetFollowersParams) ([]database.GetFollowersRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.GetFollowersRow
+	for _, fl := range f.follows {
+		if fl.FolloweeID != arg.FolloweeID {
+			continue
+		}
+		for _, u := range f.users {
+			if u.ID == fl.FollowerID {
+				rows = append(rows, database.GetFollowersRow{ID: u.ID, Email: u.Email, Username: u.Username, DisplayName: u.DisplayName, Bio: u.Bio, AvatarUrl: u.AvatarUrl, IsChirpyRed: u.IsChirpyRed})
+			}
+		}
+	}
+	return paginate(rows, arg.Limit, arg.Offset), nil
+}
+
+func (f *fakeStore) GetFollowing(ctx context.Context, arg database.GetFollowingParams) ([]database.GetFollowingRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.GetFollowingRow
+	for _, fl := range f.follows {
+		if fl.FollowerID != arg.FollowerID {
+			continue
+		}
+		for _, u := range f.users {
+			if u.ID == fl.FolloweeID {
+				rows = append(rows, database.GetFollowingRow{ID: u.ID, Email: u.Email, Username: u.Username, DisplayName: u.DisplayName, Bio: u.Bio, AvatarUrl: u.AvatarUrl, IsChirpyRed: u.IsChirpyRed})
+			}
+		}
+	}
+	return paginate(rows, arg.Limit, arg.Offset), nil
+}
+
+func (f *fakeStore) CreateFollowRequest(ctx context.Context, arg database.CreateFollowRequestParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, fr := range f.followReqs {
+		if fr.RequesterID == arg.RequesterID && fr.TargetID == arg.TargetID {
+			return 0, nil
+		}
+	}
+	f.followReqs = append(f.followReqs, database.FollowRequest{
+		RequesterID: arg.RequesterID,
+		TargetID:    arg.TargetID,
+		CreatedAt:   f.now(),
+	})
+	return 1, nil
+}
+
+func (f *fakeStore) DeleteFollowRequest(ctx context.Context, arg database.DeleteFollowRequestParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.deleteFollowRequests(func(fr database.FollowRequest) bool {
+		return fr.RequesterID == arg.RequesterID && fr.TargetID == arg.TargetID
+	}), nil
+}
+
+// deleteFollowRequests removes the matching follow requests and returns how
+// many there were. The caller must hold f.mu.
+func (f *fakeStore) deleteFollowRequests(match func(database.FollowRequest) bool) int64 {
+	before := len(f.followReqs)
+	f.followReqs = slices.DeleteFunc(f.followReqs, match)
+	return int64(before - len(f.followReqs))
+}
+
+func (f *fakeStore) ApproveFollowRequest(ctx context.Context, arg database.ApproveFollowRequestParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.approveFollowRequests(func(fr database.FollowRequest) bool {
+		return fr.RequesterID == arg.RequesterID && fr.TargetID == arg.TargetID
+	}), nil
+}
+
+func (f *fakeStore) ApproveAllFollowRequests(ctx context.Context, targetID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.approveFollowRequests(func(fr database.FollowRequest) bool { return fr.TargetID == targetID })
+	return nil
+}
+
+// approveFollowRequests turns the matching follow requests into follows and
+// returns how many follows were added. The caller must hold f.mu.
+func (f *fakeStore) approveFollowRequests(match func(database.FollowRequest) bool) int64 {
+	var approved int64
+	for _, fr := range f.followReqs {
+		if !match(fr) {
+			continue
+		}
+		following := slices.ContainsFunc(f.follows, func(fl database.Follow) bool {
+			return fl.FollowerID == fr.RequesterID && fl.FolloweeID == fr.TargetID
+		})
+		if !following {
+			f.follows = append(f.follows, database.Follow{FollowerID: fr.RequesterID, FolloweeID: fr.TargetID, CreatedAt: f.now()})
+			approved++
+		}
+	}
+	f.deleteFollowRequests(match)
+	return approved
+}
+
+func (f *fakeStore) GetFollowRequests(ctx context.Context, arg database.GetFollowRequestsParams) ([]database.GetFollowRequestsRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.GetFollowRequestsRow
+	for _, fr := range f.followReqs {
+		if fr.TargetID != arg.TargetID {
+			continue
+		}
+		for _, u := range f.users {
+			if u.ID == fr.RequesterID {
+				rows = append(rows, database.GetFollowRequestsRow{ID: u.ID, Email: u.Email, Username: u.Username, DisplayName: u.DisplayName, Bio: u.Bio, AvatarUrl: u.AvatarUrl, IsChirpyRed: u.IsChirpyRed, RequestedAt: fr.CreatedAt})
+			}
+		}
+	}
+	return paginate(rows, arg.Limit, arg.Offset), nil
+}
+
+func (f *fakeStore) CreateMute(ctx context.Context, arg database.CreateMuteParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, m := range f.mutes {
+		if m.MuterID == arg.MuterID && m.MutedID == arg.MutedID {
+			return nil
+		}
+	}
+	f.mutes = append(f.mutes, database.Mute{
+		MuterID:   arg.MuterID,
+		MutedID:   arg.MutedID,
+		CreatedAt: f.now(),
+	})
+	return nil
+}
+
+func (f *fakeStore) DeleteMute(ctx context.Context, arg database.DeleteMuteParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mutes = slices.DeleteFunc(f.mutes, func(m database.Mute) bool {
+		return m.MuterID == arg.MuterID && m.MutedID == arg.MutedID
+	})
+	return nil
+}
+
+func (f *fakeStore) CreateNotification(ctx context.Context, arg database.CreateNotificationParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.notifications = append(f.notifications, database.Notification{
+		ID:        uuid.New(),
+		CreatedAt: f.now(),
+		UserID:    arg.UserID,
+		ActorID:   arg.ActorID,
+		Type:      arg.Type,
+		ChirpID:   arg.ChirpID,
+	})
+	return nil
+}
+
+func (f *fakeStore) GetNotifications(ctx context.Context, arg database.GetNotificationsParams) ([]database.GetNotificationsRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.GetNotificationsRow
+	// Walk backwards so the newest notifications come first
+	for i := len(f.notifications) - 1; i >= 0; i-- {
+		n := f.notifications[i]
+		if n.UserID != arg.UserID || (n.ChirpID.Valid && f.chirpDeleted(n.ChirpID.UUID)) {
+			continue
+		}
+		for _, u := range f.users {
+			if u.ID == n.ActorID {
+				rows = append(rows, database.GetNotificationsRow{
+					ID:               n.ID,
+					CreatedAt:        n.CreatedAt,
+					Type:             n.Type,
+					ChirpID:          n.ChirpID,
+					ReadAt:           n.ReadAt,
+					ActorID:          u.ID,
+					ActorEmail:       u.Email,
+					ActorUsername:    u.Username,
+					ActorDisplayName: u.DisplayName,
+					ActorBio:         u.Bio,
+					ActorAvatarUrl:   u.AvatarUrl,
+					ActorIsChirpyRed: u.IsChirpyRed,
+				})
+			}
+		}
+	}
+	return paginate(rows, arg.Limit, arg.Offset), nil
+}
+
+func (f *fakeStore) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var count int64
+	for _, n := range f.notifications {
+		if n.UserID == userID && !n.ReadAt.Valid && !(n.ChirpID.Valid && f.chirpDeleted(n.ChirpID.UUID)) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeStore) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	for i, n := range f.notifications {
+		if n.UserID == userID && !n.ReadAt.Valid {
+			f.notifications[i].ReadAt = sql.NullTime{Time: now, Valid: true}
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) MarkNotificationsRead(ctx context.Context, arg database.MarkNotificationsReadParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	for i, n := range f.notifications {
+		if n.UserID == arg.UserID && !n.ReadAt.Valid && slices.Contains(arg.Ids, n.ID) {
+			f.notifications[i].ReadAt = sql.NullTime{Time: now, Valid: true}
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) CreateWebhookEvent(ctx context.Context, arg database.CreateWebhookEventParams) (database.WebhookEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	event := database.WebhookEvent{
+		ID:            uuid.New(),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		Source:        arg.Source,
+		Event:         arg.Event,
+		Payload:       arg.Payload,
+		Status:        "pending",
+		NextAttemptAt: now,
+	}
+	f.webhookEvents = append(f.webhookEvents, event)
+	return event, nil
+}
+
+func (f *fakeStore) GetDueWebhookEvents(ctx context.Context, limit int32) ([]database.WebhookEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	var events []database.WebhookEvent
+	for _, e := range f.webhookEvents {
+		if e.Status == "pending" && !e.NextAttemptAt.After(now) {
+			events = append(events, e)
+		}
+	}
+	slices.SortStableFunc(events, func(a, b database.WebhookEvent) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+	return paginate(events, limit, 0), nil
+}
+
+func (f *fakeStore) GetWebhookEvents(ctx context.Context, arg database.GetWebhookEventsParams) ([]database.WebhookEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var events []database.WebhookEvent
+	for i := len(f.webhookEvents) - 1; i >= 0; i-- {
+		e := f.webhookEvents[i]
+		if !arg.Status.Valid || e.Status == arg.Status.String {
+			events = append(events, e)
+		}
+	}
+	return paginate(events, arg.Limit, arg.Offset), nil
+}
+
+func (f *fakeStore) MarkWebhookEventFailed(ctx context.Context, arg database.MarkWebhookEventFailedParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, e := range f.webhookEvents {
+		if e.ID == arg.ID {
+			f.webhookEvents[i].Attempts++
+			f.webhookEvents[i].Status = arg.Status
+			f.webhookEvents[i].NextAttemptAt = arg.NextAttemptAt
+			f.webhookEvents[i].LastError = arg.LastError
+			f.webhookEvents[i].UpdatedAt = f.now()
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) MarkWebhookEventProcessed(ctx context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, e := range f.webhookEvents {
+		if e.ID == id {
+			f.webhookEvents[i].Attempts++
+			f.webhookEvents[i].Status = "processed"
+			f.webhookEvents[i].LastError = ""
+			f.webhookEvents[i].UpdatedAt = f.now()
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) ClaimIdempotencyKey(ctx context.Context, arg database.ClaimIdempotencyKeyParams) (database.IdempotencyKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	key := database.IdempotencyKey{
+		UserID:      arg.UserID,
+		Key:         arg.Key,
+		CreatedAt:   now,
+		ExpiresAt:   arg.ExpiresAt,
+		RequestHash: arg.RequestHash,
+	}
+	for i, k := range f.idempotency {
+		if k.UserID == arg.UserID && k.Key == arg.Key {
+			if k.ExpiresAt.After(now) {
+				return database.IdempotencyKey{}, sql.ErrNoRows
+			}
+			f.idempotency[i] = key
+			return key, nil
+		}
+	}
+	f.idempotency = append(f.idempotency, key)
+	return key, nil
+}
+
+func (f *fakeStore) GetIdempotencyKey(ctx context.Context, arg database.GetIdempotencyKeyParams) (database.IdempotencyKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, k := range f.idempotency {
+		if k.UserID == arg.UserID && k.Key == arg.Key {
+			return k, nil
+		}
+	}
+	return database.IdempotencyKey{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) SetIdempotencyKeyResponse(ctx context.Context, arg database.SetIdempotencyKeyResponseParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, k := range f.idempotency {
+		if k.UserID == arg.UserID && k.Key == arg.Key {
+			f.idempotency[i].Response = arg.Response
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) DeleteIdempotencyKey(ctx context.Context, arg database.DeleteIdempotencyKeyParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.idempotency = slices.DeleteFunc(f.idempotency, func(k database.IdempotencyKey) bool {
+		return k.UserID == arg.UserID && k.Key == arg.Key
+	})
+	return nil
+}
+
+func (f *fakeStore) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	before := len(f.idempotency)
+	now := time.Now()
+	f.idempotency = slices.DeleteFunc(f.idempotency, func(k database.IdempotencyKey) bool {
+		return !k.ExpiresAt.After(now)
+	})
+	return int64(before - len(f.idempotency)), nil
+}
+
+func (f *fakeStore) CreateAuditLogEntry(ctx context.Context, arg database.CreateAuditLogEntryParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auditLog = append(f.auditLog, database.AuditLog{
+		ID:         uuid.New(),
+		CreatedAt:  f.now(),
+		ActorID:    arg.ActorID,
+		ActorToken: arg.ActorToken,
+		Action:     arg.Action,
+		Target:     arg.Target,
+		RequestID:  arg.RequestID,
+	})
+	return nil
+}
+
+func (f *fakeStore) GetAuditLog(ctx context.Context, arg database.GetAuditLogParams) ([]database.AuditLog, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var entries []database.AuditLog
+	// Walk backwards so the newest entries come first
+	for i := len(f.auditLog) - 1; i >= 0; i-- {
+		entry := f.auditLog[i]
+		if !arg.Action.Valid || entry.Action == arg.Action.String {
+			entries = append(entries, entry)
+		}
+	}
+	return paginate(entries, arg.Limit, arg.Offset), nil
+}
+
+func (f *fakeStore) CountActiveRefreshTokens(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var count int64
+	for _, rt := range f.refreshTokens {
+		if rt.ExpiresAt.After(time.Now()) && !rt.RevokedAt.Valid {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeStore) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	token := database.RefreshToken{
+		Token:       arg.Token,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		UserID:      arg.UserID,
+		ExpiresAt:   arg.ExpiresAt,
+		FamilyID:    arg.FamilyID,
+		ParentToken: arg.ParentToken,
+		RememberMe:  arg.RememberMe,
+		Scopes:      arg.Scopes,
+	}
+	f.refreshTokens = append(f.refreshTokens, token)
+	return token, nil
+}
+
+func (f *fakeStore) GetRefreshToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, rt := range f.refreshTokens {
+		if rt.Token == token {
+			return rt, nil
+		}
+	}
+	return database.RefreshToken{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetUserFromRefreshToken(ctx context.Context, token string) (database.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, rt := range f.refreshTokens {
+		if rt.Token != token || rt.RevokedAt.Valid || !rt.ExpiresAt.After(time.Now().UTC()) {
+			continue
+		}
+		for _, u := range f.users {
+			if u.ID == rt.UserID {
+				return u, nil
+			}
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetUserSessions(ctx context.Context, userID uuid.UUID) ([]database.GetUserSessionsRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.GetUserSessionsRow
+	for _, rt := range f.refreshTokens {
+		if rt.UserID == userID && !rt.RevokedAt.Valid && rt.ExpiresAt.After(time.Now().UTC()) {
+			rows = append(rows, database.GetUserSessionsRow{CreatedAt: rt.CreatedAt, ExpiresAt: rt.ExpiresAt})
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) RevokeRefreshToken(ctx context.Context, token string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.revokeRefreshTokens(func(rt database.RefreshToken) bool { return rt.Token == token }), nil
+}
+
+func (f *fakeStore) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.revokeRefreshTokens(func(rt database.RefreshToken) bool { return rt.FamilyID == familyID }), nil
+}
+
+// revokeRefreshTokens revokes the unrevoked tokens that match and returns
+// how many there were. The caller must hold f.mu.
+func (f *fakeStore) revokeRefreshTokens(match func(database.RefreshToken) bool) int64 {
+	now := f.now()
+	var n int64
+	for i, rt := range f.refreshTokens {
+		if match(rt) && !rt.RevokedAt.Valid {
+			f.refreshTokens[i].RevokedAt = sql.NullTime{Time: now, Valid: true}
+			f.refreshTokens[i].UpdatedAt = now
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeStore) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.revokeRefreshTokens(func(rt database.RefreshToken) bool { return rt.UserID == userID })
+	return nil
+}
+
+func (f *fakeStore) CreateLoginHistoryEntry(ctx context.Context, arg database.CreateLoginHistoryEntryParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.loginHistory = append(f.loginHistory, database.LoginHistory{
+		ID:        uuid.New(),
+		UserID:    arg.UserID,
+		CreatedAt: f.now(),
+		Ip:        arg.Ip,
+		UserAgent: arg.UserAgent,
+	})
+	return nil
+}
+
+func (f *fakeStore) GetLoginHistory(ctx context.Context, arg database.GetLoginHistoryParams) ([]database.LoginHistory, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var entries []database.LoginHistory
+	// Entries are appended oldest first
+	for _, e := range slices.Backward(f.loginHistory) {
+		if e.UserID == arg.UserID && len(entries) < int(arg.Limit) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (f *fakeStore) PruneLoginHistory(ctx context.Context, keep int64) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	before := len(f.loginHistory)
+	kept := make(map[uuid.UUID]int64)
+	// Walk newest first so each user's newest entries are the ones kept
+	var entries []database.LoginHistory
+	for _, e := range slices.Backward(f.loginHistory) {
+		kept[e.UserID]++
+		if kept[e.UserID] <= keep {
+			entries = append(entries, e)
+		}
+	}
+	slices.Reverse(entries)
+	f.loginHistory = entries
+	return int64(before - len(f.loginHistory)), nil
+}
+
+func (f *fakeStore) CreateMagicLinkToken(ctx context.Context, arg database.CreateMagicLinkTokenParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.magicLinks = append(f.magicLinks, database.MagicLinkToken{
+		TokenHash: arg.TokenHash,
+		UserID:    arg.UserID,
+		CreatedAt: f.now(),
+		ExpiresAt: arg.ExpiresAt,
+	})
+	return nil
+}
+
+func (f *fakeStore) ConsumeMagicLinkToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := slices.IndexFunc(f.magicLinks, func(m database.MagicLinkToken) bool {
+		return m.TokenHash == tokenHash && m.ExpiresAt.After(time.Now())
+	})
+	if i < 0 {
+		return uuid.Nil, sql.ErrNoRows
+	}
+	userID := f.magicLinks[i].UserID
+	f.magicLinks = slices.Delete(f.magicLinks, i, i+1)
+	return userID, nil
+}
+
+func (f *fakeStore) DeleteExpiredMagicLinkTokens(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	before := len(f.magicLinks)
+	now := time.Now()
+	f.magicLinks = slices.DeleteFunc(f.magicLinks, func(m database.MagicLinkToken) bool {
+		return !m.ExpiresAt.After(now)
+	})
+	return int64(before - len(f.magicLinks)), nil
+}
+
+func (f *fakeStore) SetPendingTOTPSecret(ctx context.Context, arg database.SetPendingTOTPSecretParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pending := database.UserTotp{UserID: arg.UserID, Secret: arg.Secret, CreatedAt: f.now()}
+	i := slices.IndexFunc(f.totp, func(t database.UserTotp) bool { return t.UserID == arg.UserID })
+	if i < 0 {
+		f.totp = append(f.totp, pending)
+		return 1, nil
+	}
+	if f.totp[i].EnabledAt.Valid {
+		return 0, nil
+	}
+	f.totp[i] = pending
+	return 1, nil
+}
+
+func (f *fakeStore) GetUserTOTP(ctx context.Context, userID uuid.UUID) (database.UserTotp, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := slices.IndexFunc(f.totp, func(t database.UserTotp) bool { return t.UserID == userID })
+	if i < 0 {
+		return database.UserTotp{}, sql.ErrNoRows
+	}
+	return f.totp[i], nil
+}
+
+func (f *fakeStore) EnableUserTOTP(ctx context.Context, arg database.EnableUserTOTPParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := slices.IndexFunc(f.totp, func(t database.UserTotp) bool {
+		return t.UserID == arg.UserID && t.Secret == arg.Secret && !t.EnabledAt.Valid
+	})
+	if i < 0 {
+		return 0, nil
+	}
+	f.totp[i].EnabledAt = sql.NullTime{Time: f.now(), Valid: true}
+	return 1, nil
+}
+
+func (f *fakeStore) ReplaceBackupCodes(ctx context.Context, arg database.ReplaceBackupCodesParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.backupCodes = slices.DeleteFunc(f.backupCodes, func(c database.BackupCode) bool { return c.UserID == arg.UserID })
+	for _, hash := range arg.CodeHashes {
+		f.backupCodes = append(f.backupCodes, database.BackupCode{
+			ID:        uuid.New(),
+			UserID:    arg.UserID,
+			CodeHash:  hash,
+			CreatedAt: f.now(),
+		})
+	}
+	return nil
+}
+
+func (f *fakeStore) GetUnusedBackupCodes(ctx context.Context, userID uuid.UUID) ([]database.GetUnusedBackupCodesRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []database.GetUnusedBackupCodesRow
+	for _, c := range f.backupCodes {
+		if c.UserID == userID && !c.UsedAt.Valid {
+			rows = append(rows, database.GetUnusedBackupCodesRow{ID: c.ID, CodeHash: c.CodeHash})
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) UseBackupCode(ctx context.Context, id uuid.UUID) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := slices.IndexFunc(f.backupCodes, func(c database.BackupCode) bool { return c.ID == id && !c.UsedAt.Valid })
+	if i < 0 {
+		return 0, nil
+	}
+	f.backupCodes[i].UsedAt = sql.NullTime{Time: f.now(), Valid: true}
+	return 1, nil
+}
+
+func (f *fakeStore) CreateAPIKey(ctx context.Context, arg database.CreateAPIKeyParams) (database.ApiKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := database.ApiKey{
+		ID:        uuid.New(),
+		UserID:    arg.UserID,
+		Label:     arg.Label,
+		Prefix:    arg.Prefix,
+		KeyHash:   arg.KeyHash,
+		CreatedAt: f.now(),
+	}
+	f.apiKeys = append(f.apiKeys, key)
+	return key, nil
+}
+
+func (f *fakeStore) DeleteAPIKey(ctx context.Context, arg database.DeleteAPIKeyParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	before := len(f.apiKeys)
+	f.apiKeys = slices.DeleteFunc(f.apiKeys, func(k database.ApiKey) bool {
+		return k.ID == arg.ID && k.UserID == arg.UserID
+	})
+	return int64(before - len(f.apiKeys)), nil
+}
+
+func (f *fakeStore) GetAPIKeys(ctx context.Context, userID uuid.UUID) ([]database.ApiKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var keys []database.ApiKey
+	for _, k := range f.apiKeys {
+		if k.UserID == userID {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (f *fakeStore) GetUserIDByAPIKey(ctx context.Context, keyHash string) (uuid.UUID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, k := range f.apiKeys {
+		if k.KeyHash == keyHash {
+			return k.UserID, nil
+		}
+	}
+	return uuid.Nil, sql.ErrNoRows
+}
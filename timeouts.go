@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// serverTimeouts bound how long a client may take at each stage of a
+// connection, so slow or stalled clients cannot pin connections open.
+//
+// Write applies to every ordinary response. The live chirp streams lift it
+// for their own connection: the SSE handler clears its read and write
+// deadlines through http.ResponseController, and the WebSocket upgrade
+// clears them on the hijacked connection.
+type serverTimeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+}
+
+func (t serverTimeouts) apply(srv *http.Server) {
+	srv.ReadHeaderTimeout = t.ReadHeader
+	srv.ReadTimeout = t.Read
+	srv.WriteTimeout = t.Write
+	srv.IdleTimeout = t.Idle
+}
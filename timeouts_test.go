@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStalledClientIsDisconnected(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(handlerReadiness))
+	serverTimeouts{ReadHeader: 100 * time.Millisecond}.apply(srv.Config)
+	srv.Start()
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Send half a request and then go quiet
+	if _, err := io.WriteString(conn, "GET /api/healthz HTTP/1.1\r\nHost: localhost\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.ReadAll(conn)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		t.Fatal("server kept the stalled connection open")
+	}
+}
+
+func TestStreamOutlivesServerTimeouts(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+
+	srv := httptest.NewUnstartedServer(newTestMux(t, cfg, true))
+	serverTimeouts{Read: 100 * time.Millisecond, Write: 100 * time.Millisecond}.apply(srv.Config)
+	srv.Start()
+	defer srv.Close()
+
+	events := openChirpStream(t, srv, "")
+	time.Sleep(300 * time.Millisecond)
+	postChirp(t, cfg, makeTestToken(t, user.ID), map[string]any{"body": "still here"})
+	if got := nextChirpEvent(t, events); got.Body != "still here" {
+		t.Errorf("event = %+v, want the new chirp", got)
+	}
+
+	// End the open stream so the server can shut down
+	cfg.chirpHub.close()
+}
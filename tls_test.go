@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedPair writes a certificate and key for 127.0.0.1 to a temp
+// directory and returns their paths along with the parsed certificate.
+func writeSelfSignedPair(t *testing.T) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "chirpy test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, cert
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	certFile, keyFile, _ := writeSelfSignedPair(t)
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+
+	tlsConfig, err := loadTLSConfig("", "")
+	if err != nil || tlsConfig != nil {
+		t.Errorf("no files: got (%v, %v), want plain HTTP", tlsConfig, err)
+	}
+
+	tests := []struct {
+		name     string
+		certFile string
+		keyFile  string
+	}{
+		{"only cert", certFile, ""},
+		{"only key", "", keyFile},
+		{"unreadable cert", missing, keyFile},
+		{"unreadable key", certFile, missing},
+		{"swapped files", keyFile, certFile},
+	}
+	for _, tt := range tests {
+		if _, err := loadTLSConfig(tt.certFile, tt.keyFile); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	tlsConfig, err = loadTLSConfig(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("valid pair: %v", err)
+	}
+	if tlsConfig.MinVersion < tls.VersionTLS12 {
+		t.Errorf("MinVersion = %x, want at least TLS 1.2", tlsConfig.MinVersion)
+	}
+}
+
+func TestServeTLS(t *testing.T) {
+	certFile, keyFile, cert := writeSelfSignedPair(t)
+	tlsConfig, err := loadTLSConfig(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _ := newTestConfig(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: newTestMux(t, cfg, false), TLSConfig: tlsConfig}
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	resp, err := client.Get("https://" + ln.Addr().String() + "/api/v1/healthz")
+	if err != nil {
+		t.Fatalf("HTTPS request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if resp.TLS == nil || resp.TLS.Version < tls.VersionTLS12 {
+		t.Errorf("expected a TLS 1.2+ connection, got %+v", resp.TLS)
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		httpsPort string
+		host      string
+		want      string
+	}{
+		{"443", "example.com", "https://example.com/api/v1/chirps?sort=desc"},
+		{"443", "example.com:80", "https://example.com/api/v1/chirps?sort=desc"},
+		{"8443", "example.com:8080", "https://example.com:8443/api/v1/chirps?sort=desc"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/chirps?sort=desc", nil)
+		req.Host = tt.host
+		rr := httptest.NewRecorder()
+		redirectToHTTPS(tt.httpsPort).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusPermanentRedirect {
+			t.Errorf("got status %v want %v", rr.Code, http.StatusPermanentRedirect)
+		}
+		if got := rr.Header().Get("Location"); got != tt.want {
+			t.Errorf("Location = %q, want %q", got, tt.want)
+		}
+	}
+}
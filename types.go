@@ -1,19 +1,92 @@
 package main
 
 import (
+	"encoding/json"
 	"sync/atomic"
 	"time"
 
-	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/AlexTLDR/chirpy/internal/auth"
+	"github.com/AlexTLDR/chirpy/internal/mail"
 	"github.com/google/uuid"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
-	dbQueries      *database.Queries
+	pathHits       pathHitCounter
+	dbQueries      store
 	platform       string
-	jwtSecret      string
+	// jwtSecrets are tried in order to validate access tokens; the first
+	// signs new ones
+	jwtSecrets     []string
 	polkaKey       string
+	notifier       notifier
+	chirpHub       *chirpHub
+	chirpCache     *chirpListCache
+	webhooks       *webhookWorker
+	mailer         mail.Sender
+	mediaDir       string
+	chirpLimiter   rateLimiter
+	chirpRateLimit chirpRateLimit
+	// signupLimiter counts signups per client address against
+	// signupRateLimit; a limit of zero turns the check off
+	signupLimiter   rateLimiter
+	signupRateLimit int
+	// signupHoneypot pretends to accept signups that fill in the hidden
+	// website field instead of creating them
+	signupHoneypot bool
+	// usernameLimiter counts username availability checks per client
+	// address against usernameRateLimit; zero turns the check off
+	usernameLimiter   rateLimiter
+	usernameRateLimit int
+	// chirpMediaLocalOnly only lets chirps link to images uploaded under
+	// /media/
+	chirpMediaLocalOnly bool
+	// dailyChirpQuota caps the chirps a user without Chirpy Red may post
+	// in any chirpQuotaPeriod; zero turns the cap off
+	dailyChirpQuota int
+	// duplicateChirpWindow is how long an identical chirp by the same user
+	// is rejected for; zero allows duplicates
+	duplicateChirpWindow time.Duration
+	// chirpViews counts views of chirps; nil counts none
+	chirpViews *chirpViewCounter
+	// countListViews counts a view of every chirp in a list response too
+	countListViews bool
+	// purgeBatchSize is how many chirps the admin purge deletes per
+	// statement
+	purgeBatchSize int
+	// passwordHashing is how passwords are hashed; logins rehash
+	// passwords whose hash was made otherwise
+	passwordHashing auth.PasswordParams
+	// signupPrivacy hides from the signup response whether the email was
+	// already registered
+	signupPrivacy bool
+	// hideEmails leaves email out of every PublicUser
+	hideEmails bool
+	// directoryNeedsAuth turns anonymous callers of GET /api/users away
+	directoryNeedsAuth bool
+	// publicURL is the base of links in emails; empty uses the request's
+	// host
+	publicURL string
+	// trustProxy takes client addresses from X-Forwarded-For, for a server
+	// behind a reverse proxy
+	trustProxy bool
+	// refreshTokenReuse counts revoked refresh tokens presented again,
+	// each of which took its token family down
+	refreshTokenReuse atomic.Int64
+	refreshTokenTTL   refreshTokenTTL
+	// accessTokenTTL is how long an access token stays valid
+	accessTokenTTL time.Duration
+	// googleOAuth enables signing in with Google; nil disables it
+	googleOAuth *googleOAuth
+	// queryMetrics is fed by the instrumented store; nil when the store is
+	// not instrumented, as in tests
+	queryMetrics *queryMetrics
+	// dbHealth tracks whether the database answers pings; nil assumes it
+	// does
+	dbHealth *dbHealth
+	// startedAt is when the server started, for the uptime on the metrics
+	// page
+	startedAt time.Time
 }
 
 type User struct {
@@ -21,17 +94,328 @@ type User struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Email       string    `json:"email"`
+	Username    string    `json:"username"`
+	DisplayName *string   `json:"display_name"`
+	Bio         *string   `json:"bio"`
+	AvatarURL   *string   `json:"avatar_url"`
 	IsChirpyRed bool      `json:"is_chirpy_red"`
+	IsAdmin     bool      `json:"is_admin"`
+	IsPrivate   bool      `json:"is_private"`
+	// PendingEmail is the address an email change is waiting to be
+	// confirmed at
+	PendingEmail *string `json:"pending_email"`
 }
 
-type Chirp struct {
+// ConfirmEmailResponse is the response to confirming an email change.
+type ConfirmEmailResponse struct {
+	Email string `json:"email"`
+}
+
+// APIKey describes an API key without the key itself.
+type APIKey struct {
 	ID        uuid.UUID `json:"id"`
+	Label     string    `json:"label"`
+	Prefix    string    `json:"prefix"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreatedAPIKey is the response to creating an API key, the only one that
+// includes the key.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// TwoFactorSetup is the response to starting two-factor setup: the TOTP
+// secret, and the otpauth URI that carries it for a QR code.
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// BackupCodesResponse carries a new set of backup codes. It is the only
+// time the codes are shown.
+type BackupCodesResponse struct {
+	BackupCodes []string `json:"backup_codes"`
+}
+
+// Session describes an active refresh token without the token itself.
+type Session struct {
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Login is an entry in a user's login history.
+type Login struct {
+	CreatedAt time.Time `json:"created_at"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+}
+
+// PublicUser is the subset of a user's profile that is visible to others.
+// Email is left out when the server hides emails; clients show the
+// username and display name instead.
+type PublicUser struct {
+	ID          uuid.UUID `json:"id"`
+	Email       string    `json:"email,omitempty"`
+	Username    string    `json:"username"`
+	DisplayName *string   `json:"display_name"`
+	Bio         *string   `json:"bio"`
+	AvatarURL   *string   `json:"avatar_url"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+// UserProfile is the public view of a user returned by GET /api/users/{userID}.
+// The display name, bio, avatar and pinned chirp of a private account are
+// null for callers who may not see it.
+type UserProfile struct {
+	PublicUser
+	IsPrivate      bool   `json:"is_private"`
+	FollowerCount  int64  `json:"follower_count"`
+	FollowingCount int64  `json:"following_count"`
+	PinnedChirp    *Chirp `json:"pinned_chirp"`
+}
+
+// FollowRequest is a pending request to follow a private account, as listed
+// by GET /api/follow_requests.
+type FollowRequest struct {
+	Requester   PublicUser `json:"requester"`
+	RequestedAt time.Time  `json:"requested_at"`
+}
+
+// SignupAcceptedResponse is returned by POST /api/users in signup privacy
+// mode, whether or not the email was already registered.
+type SignupAcceptedResponse struct {
+	Message string `json:"message"`
+}
+
+// UsernameAvailability is returned by GET /api/usernames/{name}/available.
+// Reason says why an unavailable name cannot be had: "taken", "invalid" or
+// "reserved".
+type UsernameAvailability struct {
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// UserConflictResponse is returned by PUT /api/users when the account
+// changed since the updated_at the client sent. Current is the record as it
+// is now, for the client to merge its edit into.
+type UserConflictResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+	Current   User   `json:"current"`
+}
+
+// BulkChirpsResponse is returned by POST /api/chirps/bulk. Results line up
+// with the request items; Error is only set when nothing was created.
+type BulkChirpsResponse struct {
+	Error     string            `json:"error,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Results   []BulkChirpResult `json:"results"`
+}
+
+// BulkChirpResult is the outcome of one bulk item: the created chirp, or why
+// the item was rejected. Items that were valid in a rejected batch are empty.
+type BulkChirpResult struct {
+	Chirp  *Chirp            `json:"chirp,omitempty"`
+	Error  string            `json:"error,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// BulkDeleteChirpsResponse is returned by POST /api/chirps/bulk_delete.
+// Skipped lists the requested IDs that were not deleted because they are
+// not the caller's chirps or do not exist.
+type BulkDeleteChirpsResponse struct {
+	Deleted int         `json:"deleted"`
+	Skipped []uuid.UUID `json:"skipped"`
+}
+
+// PurgeChirpsResponse is returned by POST /admin/chirps/purge. In a dry
+// run no batches are executed and Deleted is how many chirps would be.
+type PurgeChirpsResponse struct {
+	Cutoff  time.Time `json:"cutoff"`
+	DryRun  bool      `json:"dry_run"`
+	Batches int       `json:"batches"`
+	Deleted int64     `json:"deleted"`
+}
+
+// UserStats is the activity summary returned by GET /api/users/{userID}/stats.
+type UserStats struct {
+	ChirpCount     int64      `json:"chirp_count"`
+	FirstChirpAt   *time.Time `json:"first_chirp_at"`
+	LastChirpAt    *time.Time `json:"last_chirp_at"`
+	FollowerCount  int64      `json:"follower_count"`
+	FollowingCount int64      `json:"following_count"`
+	LikesReceived  int64      `json:"likes_received"`
+	LikesGiven     int64      `json:"likes_given"`
+}
+
+type Chirp struct {
+	ID            uuid.UUID     `json:"id"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	Body          string        `json:"body"`
+	UserID        uuid.UUID     `json:"user_id"`
+	ParentChirpID *uuid.UUID    `json:"parent_chirp_id"`
+	Edited        bool          `json:"edited"`
+	LikeCount     int64         `json:"like_count"`
+	ReplyCount    int64         `json:"reply_count"`
+	ViewCount     int64         `json:"view_count"`
+	MediaURL      *string       `json:"media_url"`
+	MediaAlt      *string       `json:"media_alt"`
+	Mentions      []uuid.UUID   `json:"mentions"`
+	LikedByMe     *bool         `json:"liked_by_me,omitempty"`
+	Entities      ChirpEntities `json:"entities"`
+	// Author is only filled in for ?include=author
+	Author *ChirpAuthor `json:"author,omitempty"`
+}
+
+// ChirpAuthor is the author of a chirp as expanded by ?include=author. A
+// chirp kept after its author was deleted has {"deleted": true} instead.
+type ChirpAuthor struct {
+	*PublicUser
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// ChirpEntities are the parts of a chirp body that clients render
+// specially.
+type ChirpEntities struct {
+	URLs []URLEntity `json:"urls"`
+}
+
+// URLEntity is a link in a chirp body. Start and End are the character
+// offsets of the literal text, End exclusive, and URL its normalized form.
+type URLEntity struct {
+	URL   string `json:"url"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// ChirpRevision is an earlier version of an edited chirp.
+type ChirpRevision struct {
+	ID        uuid.UUID `json:"id"`
 	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditLogEntry is one privileged action as listed by GET /admin/audit.
+// Exactly one of ActorID and ActorToken is set.
+type AuditLogEntry struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ActorID    *uuid.UUID `json:"actor_id"`
+	ActorToken *string    `json:"actor_token"`
+	Action     string     `json:"action"`
+	Target     string     `json:"target"`
+	RequestID  string     `json:"request_id"`
+}
+
+// WebhookEvent is a stored webhook delivery as listed by
+// GET /admin/webhook_events.
+type WebhookEvent struct {
+	ID            uuid.UUID       `json:"id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	Source        string          `json:"source"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int32           `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error"`
+}
+
+// ReportedChirp is an entry in the admin moderation queue: a chirp with
+// open reports against it.
+type ReportedChirp struct {
+	Chirp           Chirp     `json:"chirp"`
+	ReportCount     int64     `json:"report_count"`
+	Reasons         []string  `json:"reasons"`
+	FirstReportedAt time.Time `json:"first_reported_at"`
+}
+
+// Notification is an entry in a user's activity list, such as a like on one
+// of their chirps or a new follower.
+type Notification struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Type      string     `json:"type"`
+	Actor     PublicUser `json:"actor"`
+	ChirpID   *uuid.UUID `json:"chirp_id"`
+	Read      bool       `json:"read"`
+}
+
+// NotificationsResponse is a page of notifications plus the caller's total
+// unread count across all pages.
+type NotificationsResponse struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int64          `json:"unread_count"`
+}
+
+// Metrics is the JSON form of GET /admin/metrics.
+type Metrics struct {
+	UptimeSeconds int64  `json:"uptime_seconds"`
+	GoVersion     string `json:"go_version"`
+	// The totals are null when the database could not be counted
+	Users               *int64     `json:"users"`
+	Chirps              *int64     `json:"chirps"`
+	ActiveRefreshTokens *int64     `json:"active_refresh_tokens"`
+	FileserverHits      int32      `json:"fileserver_hits"`
+	Paths               []PathHits `json:"paths"`
+	ChirpCache          struct {
+		Hits   int64 `json:"hits"`
+		Misses int64 `json:"misses"`
+	} `json:"chirp_cache"`
+	RefreshTokenReuse int64          `json:"refresh_token_reuse"`
+	Queries           []QueryMetrics `json:"queries"`
+}
+
+// PathHits is one row of the per-path file server hit table.
+type PathHits struct {
+	Path string `json:"path"`
+	Hits int64  `json:"hits"`
+}
+
+// Readiness is the body of GET /api/readyz.
+type Readiness struct {
+	Status string `json:"status"`
+	// Database is set while the database is unavailable
+	Database *DatabaseHealth `json:"database,omitempty"`
+}
+
+// DatabaseHealth describes a database outage: the last ping error, when
+// the outage began and when the database was last pinged.
+type DatabaseHealth struct {
+	Error     string    `json:"error"`
+	Since     time.Time `json:"since"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// QueryMetrics is the call count, error count and latency histogram of one
+// database query.
+type QueryMetrics struct {
+	Query        string          `json:"query"`
+	Calls        int64           `json:"calls"`
+	Errors       int64           `json:"errors"`
+	TotalSeconds float64         `json:"total_seconds"`
+	Latency      []LatencyBucket `json:"latency"`
+}
+
+// LatencyBucket counts the calls that took more than the previous bucket's
+// LE and at most LE seconds. The last bucket has no upper bound and an LE
+// of 0.
+type LatencyBucket struct {
+	LE    float64 `json:"le,omitempty"`
+	Count int64   `json:"count"`
 }
 
 type ErrorResponse struct {
-	Error string `json:"error"`
-}
\ No newline at end of file
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+	// Code identifies errors clients are expected to handle specifically
+	Code string `json:"code,omitempty"`
+	// Fields is set when several fields of the request are invalid
+	Fields map[string]string `json:"fields,omitempty"`
+	// Scope is the missing scope of an insufficient_scope error
+	Scope string `json:"scope,omitempty"`
+}
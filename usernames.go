@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 30
+)
+
+// reservedUsernames cannot be registered because they would be confused with
+// Chirpy itself or its staff. Migration 019 has a copy of this list for the
+// backfill.
+var reservedUsernames = map[string]bool{
+	"admin":         true,
+	"administrator": true,
+	"api":           true,
+	"chirpy":        true,
+	"help":          true,
+	"login":         true,
+	"logout":        true,
+	"me":            true,
+	"moderator":     true,
+	"root":          true,
+	"settings":      true,
+	"signup":        true,
+	"support":       true,
+	"system":        true,
+}
+
+// usernameConstraint is the unique index on users.username.
+const usernameConstraint = "users_username_key"
+
+// usernameAttempts bounds how many generated usernames signup tries before
+// giving up.
+const usernameAttempts = 5
+
+var (
+	errUsernameChars    = errors.New("may only contain lowercase letters, digits and underscores")
+	errUsernameLength   = fmt.Errorf("must be between %d and %d characters", minUsernameLength, maxUsernameLength)
+	errUsernameReserved = errors.New("is reserved")
+)
+
+// normalizeUsername lowercases username so that handles are matched the same
+// way as @mentions, then checks it is 3-30 characters of [a-z0-9_] and not
+// reserved. The error completes the sentence "Username ...".
+func normalizeUsername(username string) (string, error) {
+	username = strings.ToLower(username)
+	for _, r := range username {
+		if !isUsernameRune(r) {
+			return "", errUsernameChars
+		}
+	}
+	if len(username) < minUsernameLength || len(username) > maxUsernameLength {
+		return "", errUsernameLength
+	}
+	if reservedUsernames[username] {
+		return "", errUsernameReserved
+	}
+	return username, nil
+}
+
+func isUsernameRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_'
+}
+
+// defaultUsername derives a username for an account that signed up without
+// one from the local part of its email. It leaves room for a numeric suffix
+// in case the name is taken; migration 019 backfills existing users the same
+// way.
+func defaultUsername(email string) string {
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+	base := []rune{}
+	for _, r := range local {
+		if !isUsernameRune(r) {
+			r = '_'
+		}
+		base = append(base, r)
+	}
+	if len(base) > 25 {
+		base = base[:25]
+	}
+	if len(base) < minUsernameLength {
+		base = append(base, []rune("_user")...)
+	}
+	return string(base)
+}
+
+// usernameCandidate is the username to try on the given attempt when
+// picking one with defaultUsername: the default first, then the default with
+// a random suffix.
+func usernameCandidate(base string, attempt int) string {
+	if attempt == 0 && !reservedUsernames[base] {
+		return base
+	}
+	return fmt.Sprintf("%s_%d", base, 1000+rand.IntN(9000))
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a duplicate
+// value for the named unique constraint or index.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation && pqErr.Constraint == constraint
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeUsername(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   error
+	}{
+		{"alice", "alice", nil},
+		{"Alice_99", "alice_99", nil},
+		{"bob", "bob", nil},
+		{strings.Repeat("a", 30), strings.Repeat("a", 30), nil},
+		{"al", "", errUsernameLength},
+		{strings.Repeat("a", 31), "", errUsernameLength},
+		{"", "", errUsernameLength},
+		{"first.last", "", errUsernameChars},
+		{"with space", "", errUsernameChars},
+		{"dash-ed", "", errUsernameChars},
+		{"zoë", "", errUsernameChars},
+		{"admin", "", errUsernameReserved},
+		{"ADMIN", "", errUsernameReserved},
+		{"support", "", errUsernameReserved},
+		{"admin_", "admin_", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeUsername(tt.input)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("normalizeUsername(%q) = %q, %v; want %q, %v", tt.input, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestDefaultUsername(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"alice@example.com", "alice"},
+		{"First.Last+tag@example.com", "first_last_tag"},
+		{"al@example.com", "al_user"},
+		{"@example.com", "_user"},
+		{strings.Repeat("x", 40) + "@example.com", strings.Repeat("x", 25)},
+	}
+
+	for _, tt := range tests {
+		if got := defaultUsername(tt.email); got != tt.want {
+			t.Errorf("defaultUsername(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+
+	// Generated names are always valid, apart from reserved ones which
+	// usernameCandidate never returns
+	for _, email := range []string{"a@example.com", "zoë@example.com", strings.Repeat("y", 99) + "@x"} {
+		for attempt := range 3 {
+			if _, err := normalizeUsername(usernameCandidate(defaultUsername(email), attempt)); err != nil {
+				t.Errorf("candidate %d for %q is invalid: %v", attempt, email, err)
+			}
+		}
+	}
+	if got := usernameCandidate(defaultUsername("admin@example.com"), 0); got == "admin" {
+		t.Error("usernameCandidate returned a reserved username")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// fieldErrors collects validation failures by JSON field name so a request
+// can be rejected with all of them at once instead of one per attempt. The
+// zero value is ready to use.
+type fieldErrors struct {
+	fields   map[string]string
+	messages []string
+}
+
+// add records that field failed. label names the field in the error
+// message and reason says what is wrong with it, e.g. "is required". Only
+// the first failure of each field is kept.
+func (e *fieldErrors) add(field, label, reason string) {
+	if _, ok := e.fields[field]; ok {
+		return
+	}
+	if e.fields == nil {
+		e.fields = make(map[string]string)
+	}
+	e.fields[field] = reason
+	e.messages = append(e.messages, label+" "+reason)
+}
+
+// respond writes a 400 for the collected failures and returns true, or
+// returns false if there are none. A single failure keeps the plain
+// {"error": ...} shape; several are summarised in error and listed by field
+// in fields.
+func (e *fieldErrors) respond(w http.ResponseWriter, r *http.Request) bool {
+	switch len(e.messages) {
+	case 0:
+		return false
+	case 1:
+		respondWithError(w, r, http.StatusBadRequest, e.messages[0])
+		return true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error:     strings.Join(e.messages, "; "),
+		RequestID: requestID(r.Context()),
+		Fields:    e.fields,
+	})
+	return true
+}
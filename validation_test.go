@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// checkFieldErrors asserts that rr is a 400 listing exactly the given fields,
+// or the plain single-error shape when only one field is expected.
+func checkFieldErrors(t *testing.T, name string, rr *httptest.ResponseRecorder, want map[string]string) {
+	t.Helper()
+	var got ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("%s: failed to decode error: %v", name, err)
+	}
+	if rr.Code != http.StatusBadRequest || got.Error == "" {
+		t.Errorf("%s: got %v %+v, want a 400 with an error message", name, rr.Code, got)
+		return
+	}
+	if len(want) == 1 {
+		if got.Fields != nil {
+			t.Errorf("%s: a single failure should not list fields, got %v", name, got.Fields)
+		}
+		for _, reason := range want {
+			if !strings.HasSuffix(got.Error, reason) {
+				t.Errorf("%s: error = %q, want it to end in %q", name, got.Error, reason)
+			}
+		}
+		return
+	}
+	if !maps.Equal(got.Fields, want) {
+		t.Errorf("%s: fields = %v, want %v", name, got.Fields, want)
+	}
+}
+
+func TestCreateUserFieldErrors(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+
+	tests := []struct {
+		name    string
+		payload map[string]string
+		want    map[string]string
+	}{
+		{"empty body", map[string]string{}, map[string]string{
+			"email":    "is required",
+			"password": "is required",
+		}},
+		{"only password missing", map[string]string{"email": "a@example.com"}, map[string]string{
+			"password": "is required",
+		}},
+		{"everything wrong", map[string]string{
+			"username":     "No Spaces",
+			"display_name": strings.Repeat("x", maxDisplayNameLength+1),
+			"bio":          "tab\there",
+		}, map[string]string{
+			"email":        "is required",
+			"password":     "is required",
+			"username":     errUsernameChars.Error(),
+			"display_name": "must be at most 50 characters",
+			"bio":          "must not contain control characters",
+		}},
+	}
+	for _, tt := range tests {
+		checkFieldErrors(t, tt.name, postUser(t, cfg, tt.payload), tt.want)
+	}
+}
+
+func TestUpdateUserFieldErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	tests := []struct {
+		name    string
+		payload map[string]string
+		want    map[string]string
+	}{
+		{"blank credentials", map[string]string{"email": "", "password": ""}, map[string]string{
+			"email":            "is required",
+			"password":         "is required",
+			"current_password": "is required to change the email or password",
+		}},
+		{"bad username only", map[string]string{"username": "x"}, map[string]string{
+			"username": errUsernameLength.Error(),
+		}},
+		{"profile and username", map[string]string{
+			"username": "admin",
+			"bio":      strings.Repeat("b", maxBioLength+1),
+		}, map[string]string{
+			"username": errUsernameReserved.Error(),
+			"bio":      "must be at most 200 characters",
+		}},
+	}
+	for _, tt := range tests {
+		checkFieldErrors(t, tt.name, putUser(t, cfg, token, tt.payload), tt.want)
+	}
+
+	// Nothing was applied by the rejected requests
+	stored, _ := db.GetUserByID(t.Context(), user.ID)
+	if stored.Email != "user@example.com" || stored.Bio.Valid {
+		t.Errorf("rejected updates changed the user: %+v", stored)
+	}
+}
+
+func TestCreateChirpFieldErrors(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	token := makeTestToken(t, user.ID)
+
+	tests := []struct {
+		name    string
+		payload map[string]any
+		want    map[string]string
+	}{
+		{"missing body and parent", map[string]any{"parent_chirp_id": uuid.New()}, map[string]string{
+			"body":            "is required",
+			"parent_chirp_id": "not found",
+		}},
+		{"too long with missing parent", map[string]any{
+			"body":            strings.Repeat("a", maxChirpLength+1),
+			"parent_chirp_id": uuid.New(),
+		}, map[string]string{
+			"body":            "is too long (max 140 characters)",
+			"parent_chirp_id": "not found",
+		}},
+		{"only missing parent", map[string]any{"body": "Hi", "parent_chirp_id": uuid.New()}, map[string]string{
+			"parent_chirp_id": "not found",
+		}},
+	}
+	for _, tt := range tests {
+		checkFieldErrors(t, tt.name, postChirp(t, cfg, token, tt.payload), tt.want)
+	}
+}
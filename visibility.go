@@ -0,0 +1,121 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// errCodePrivateAccount is the error code of a request for details of a
+// private account the caller has not been approved to follow.
+const errCodePrivateAccount = "private_account"
+
+// Who may see a private or banned account's chirps and profile is decided
+// by the can_view_user database function alone: the queries that list
+// chirps call it directly, and everything else goes through the helpers
+// below.
+
+// viewer returns the caller as the viewer argument of the visibility
+// queries, which is NULL for anonymous requests.
+func (cfg *apiConfig) viewer(r *http.Request) uuid.NullUUID {
+	viewerID, ok := cfg.viewerID(r)
+	return uuid.NullUUID{UUID: viewerID, Valid: ok}
+}
+
+// canView reports whether the caller may see authorID's chirps and profile
+// details. Authors can always see their own without a query.
+func (cfg *apiConfig) canView(r *http.Request, authorID uuid.UUID) (bool, error) {
+	viewer := cfg.viewer(r)
+	if viewer.Valid && viewer.UUID == authorID {
+		return true, nil
+	}
+	return cfg.dbQueries.CanViewUser(r.Context(), database.CanViewUserParams{
+		AuthorID: authorID,
+		ViewerID: viewer,
+	})
+}
+
+// getVisibleChirp is GetChirpByID for a chirp read or acted on by the
+// caller. A chirp the caller may not see is reported as sql.ErrNoRows, so
+// it cannot be told apart from one that does not exist.
+func (cfg *apiConfig) getVisibleChirp(r *http.Request, chirpID uuid.UUID) (database.GetChirpByIDRow, error) {
+	row, err := cfg.dbQueries.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		return database.GetChirpByIDRow{}, err
+	}
+	visible, err := cfg.canView(r, row.Chirp.UserID)
+	if err != nil {
+		return database.GetChirpByIDRow{}, err
+	}
+	if !visible {
+		return database.GetChirpByIDRow{}, sql.ErrNoRows
+	}
+	return row, nil
+}
+
+// filterVisible drops the chirps whose author the caller may not see, with
+// one query for the whole slice. chirps may be shared with the cache, so
+// the result is a new slice whenever anything is dropped.
+func (cfg *apiConfig) filterVisible(r *http.Request, chirps []Chirp) ([]Chirp, error) {
+	var authorIDs []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, chirp := range chirps {
+		if !seen[chirp.UserID] {
+			seen[chirp.UserID] = true
+			authorIDs = append(authorIDs, chirp.UserID)
+		}
+	}
+	if len(authorIDs) == 0 {
+		return chirps, nil
+	}
+
+	hiddenIDs, err := cfg.dbQueries.GetHiddenAuthorIDs(r.Context(), database.GetHiddenAuthorIDsParams{
+		AuthorIds: authorIDs,
+		ViewerID:  cfg.viewer(r),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(hiddenIDs) == 0 {
+		return chirps, nil
+	}
+
+	hidden := make(map[uuid.UUID]bool, len(hiddenIDs))
+	for _, id := range hiddenIDs {
+		hidden[id] = true
+	}
+	visible := make([]Chirp, 0, len(chirps))
+	for _, chirp := range chirps {
+		if !hidden[chirp.UserID] {
+			visible = append(visible, chirp)
+		}
+	}
+	return visible, nil
+}
+
+// requireVisibleAccount checks that the caller may see userID's account
+// details, writing a 403 with errCodePrivateAccount when they may not, or
+// errCodeAccountSuspended when that is because userID is banned.
+func (cfg *apiConfig) requireVisibleAccount(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	visible, err := cfg.canView(r, userID)
+	if err != nil {
+		respondWithDBError(w, r, err)
+		return false
+	}
+	if !visible {
+		suspended, err := cfg.isSuspended(r, userID)
+		if err != nil {
+			respondWithDBError(w, r, err)
+			return false
+		}
+		if suspended {
+			respondWithErrorCode(w, r, http.StatusForbidden, errCodeAccountSuspended, "This account is suspended")
+			return false
+		}
+		respondWithErrorCode(w, r, http.StatusForbidden, errCodePrivateAccount, "This account is private")
+		return false
+	}
+	return true
+}
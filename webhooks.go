@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const webhookSourcePolka = "polka"
+
+// Webhook event states. Events start out pending and end up processed, or
+// dead once they have failed too many times.
+const (
+	webhookPending   = "pending"
+	webhookProcessed = "processed"
+	webhookDead      = "dead"
+)
+
+// polkaWebhook is the body of a Polka webhook delivery. It is stored as-is
+// and decoded again when the event is processed.
+type polkaWebhook struct {
+	Event string `json:"event"`
+	Data  struct {
+		UserID string `json:"user_id"`
+	} `json:"data"`
+}
+
+// webhookWorker processes persisted webhook deliveries in the background,
+// retrying failures with exponential backoff. Handlers only store the
+// delivery and call notify, so a slow or failing database never makes the
+// sender give up on an event.
+type webhookWorker struct {
+	db      store
+	process func(ctx context.Context, event database.WebhookEvent) error
+
+	pollInterval time.Duration
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	maxAttempts  int32
+	batchSize    int32
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newWebhookWorker(db store, process func(ctx context.Context, event database.WebhookEvent) error) *webhookWorker {
+	return &webhookWorker{
+		db:           db,
+		process:      process,
+		pollInterval: 5 * time.Second,
+		baseBackoff:  2 * time.Second,
+		maxBackoff:   time.Hour,
+		maxAttempts:  8,
+		batchSize:    20,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// start runs the worker until stop is called. Besides being woken by
+// notify, it polls so that retries come due without new deliveries.
+func (w *webhookWorker) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.pollInterval)
+		defer ticker.Stop()
+		for {
+			w.runOnce(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-w.wake:
+			}
+		}
+	}()
+}
+
+// stop shuts the worker down, letting the event in progress finish. Events
+// that were not reached stay pending for the next start.
+func (w *webhookWorker) stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	<-w.done
+}
+
+// notify tells the worker a new event is waiting. It never blocks and a
+// nil worker ignores it.
+func (w *webhookWorker) notify() {
+	if w == nil {
+		return
+	}
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// runOnce processes one batch of due events.
+func (w *webhookWorker) runOnce(ctx context.Context) {
+	events, err := w.db.GetDueWebhookEvents(ctx, w.batchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error loading webhook events: %v", err)
+		}
+		return
+	}
+
+	for _, event := range events {
+		if ctx.Err() != nil {
+			return
+		}
+		// Finish the event even if shutdown starts halfway through it
+		w.handle(context.WithoutCancel(ctx), event)
+	}
+}
+
+func (w *webhookWorker) handle(ctx context.Context, event database.WebhookEvent) {
+	err := w.process(ctx, event)
+	if err == nil {
+		err = w.db.MarkWebhookEventProcessed(ctx, event.ID)
+		if err != nil {
+			log.Printf("Error marking webhook event %s processed: %v", event.ID, err)
+		}
+		return
+	}
+
+	attempts := event.Attempts + 1
+	status := webhookPending
+	if attempts >= w.maxAttempts {
+		status = webhookDead
+		log.Printf("Webhook event %s failed %d times, giving up: %v", event.ID, attempts, err)
+	}
+	markErr := w.db.MarkWebhookEventFailed(ctx, database.MarkWebhookEventFailedParams{
+		ID:            event.ID,
+		Status:        status,
+		NextAttemptAt: time.Now().UTC().Add(w.backoff(attempts)),
+		LastError:     err.Error(),
+	})
+	if markErr != nil {
+		log.Printf("Error recording failure of webhook event %s: %v", event.ID, markErr)
+	}
+}
+
+// backoff is the delay before the next try after the given number of
+// failed attempts: baseBackoff doubled for every earlier failure, capped at
+// maxBackoff.
+func (w *webhookWorker) backoff(attempts int32) time.Duration {
+	delay := w.baseBackoff
+	for i := int32(1); i < attempts && delay < w.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, w.maxBackoff)
+}
+
+// processWebhookEvent applies a stored webhook delivery. Events Chirpy does
+// not act on are accepted so they do not sit in the queue.
+func (cfg *apiConfig) processWebhookEvent(ctx context.Context, event database.WebhookEvent) error {
+	if event.Source != webhookSourcePolka || event.Event != "user.upgraded" {
+		return nil
+	}
+
+	var delivery polkaWebhook
+	err := json.Unmarshal(event.Payload, &delivery)
+	if err != nil {
+		return fmt.Errorf("decoding payload: %w", err)
+	}
+	userID, err := uuid.Parse(delivery.Data.UserID)
+	if err != nil {
+		return fmt.Errorf("parsing user ID: %w", err)
+	}
+
+	err = cfg.dbQueries.UpgradeUserToChirpyRed(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("upgrading user %s: %w", userID, err)
+	}
+	cfg.writeAudit(ctx, "", auditEntry{ActorToken: auditActorPolka, Action: auditUserUpgrade, Target: userID.String()})
+	return nil
+}
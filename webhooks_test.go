@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlexTLDR/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// flakyUpgradeStore is a fakeStore that fails the next `failures` upgrades.
+type flakyUpgradeStore struct {
+	*fakeStore
+	failures atomic.Int32
+}
+
+func (s *flakyUpgradeStore) UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) error {
+	if s.failures.Add(-1) >= 0 {
+		return errors.New("connection reset")
+	}
+	return s.fakeStore.UpgradeUserToChirpyRed(ctx, id)
+}
+
+// sendUpgradeWebhook delivers a user.upgraded event for userID.
+func sendUpgradeWebhook(t *testing.T, cfg *apiConfig, userID string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{
+		"event": "user.upgraded",
+		"data":  map[string]string{"user_id": userID},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/polka/webhooks", bytes.NewReader(body))
+	req.Header.Set("Authorization", "ApiKey "+cfg.polkaKey)
+	rr := httptest.NewRecorder()
+	cfg.handlerPolkaWebhook(rr, req)
+	return rr
+}
+
+// webhookEvents returns every stored event, newest first.
+func webhookEvents(t *testing.T, db *fakeStore) []database.WebhookEvent {
+	t.Helper()
+	events, err := db.GetWebhookEvents(t.Context(), database.GetWebhookEventsParams{Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func isChirpyRed(t *testing.T, db *fakeStore, userID uuid.UUID) bool {
+	t.Helper()
+	user, err := db.GetUserByID(t.Context(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.IsChirpyRed
+}
+
+func TestWebhookIsProcessedAsynchronously(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+
+	if rr := sendUpgradeWebhook(t, cfg, user.ID.String()); rr.Code != http.StatusNoContent {
+		t.Fatalf("webhook returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	events := webhookEvents(t, db)
+	if len(events) != 1 || events[0].Status != webhookPending || events[0].Event != "user.upgraded" {
+		t.Fatalf("expected one pending event, got %+v", events)
+	}
+	if isChirpyRed(t, db, user.ID) {
+		t.Error("the handler should only enqueue the upgrade")
+	}
+
+	cfg.webhooks.runOnce(t.Context())
+	if !isChirpyRed(t, db, user.ID) {
+		t.Error("user should be upgraded once the event is processed")
+	}
+	if events := webhookEvents(t, db); events[0].Status != webhookProcessed || events[0].Attempts != 1 {
+		t.Errorf("event after processing = %+v, want processed after one attempt", events[0])
+	}
+
+	// Other events and malformed deliveries are not stored
+	for _, payload := range []string{
+		`{"event": "user.payment_failed", "data": {"user_id": "` + user.ID.String() + `"}}`,
+		`{"event": "user.upgraded", "data": {"user_id": "nope"}}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/polka/webhooks", bytes.NewReader([]byte(payload)))
+		req.Header.Set("Authorization", "ApiKey "+cfg.polkaKey)
+		cfg.handlerPolkaWebhook(httptest.NewRecorder(), req)
+	}
+	if events := webhookEvents(t, db); len(events) != 1 {
+		t.Errorf("expected ignored deliveries not to be stored, got %d events", len(events))
+	}
+}
+
+func TestWebhookRetriesWithBackoff(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	flaky := &flakyUpgradeStore{fakeStore: db}
+	flaky.failures.Store(1)
+	cfg.dbQueries = flaky
+	user := db.addUser(t, "user@example.com")
+	sendUpgradeWebhook(t, cfg, user.ID.String())
+
+	cfg.webhooks.baseBackoff = time.Hour
+	cfg.webhooks.runOnce(t.Context())
+	event := webhookEvents(t, db)[0]
+	if event.Status != webhookPending || event.Attempts != 1 || event.LastError == "" {
+		t.Fatalf("event after a failure = %+v, want pending with the error recorded", event)
+	}
+	if until := time.Until(event.NextAttemptAt); until < 59*time.Minute {
+		t.Errorf("next attempt in %v, want about an hour", until)
+	}
+
+	// Not due yet
+	cfg.webhooks.runOnce(t.Context())
+	if webhookEvents(t, db)[0].Attempts != 1 || isChirpyRed(t, db, user.ID) {
+		t.Fatal("event was retried before its backoff elapsed")
+	}
+
+	cfg.webhooks.baseBackoff = 0
+	db.mu.Lock()
+	db.webhookEvents[0].NextAttemptAt = time.Now().Add(-time.Second)
+	db.mu.Unlock()
+	cfg.webhooks.runOnce(t.Context())
+	if event := webhookEvents(t, db)[0]; event.Status != webhookProcessed || event.Attempts != 2 || event.LastError != "" {
+		t.Errorf("event after retry = %+v, want processed after two attempts", event)
+	}
+	if !isChirpyRed(t, db, user.ID) {
+		t.Error("user should be upgraded by the retry")
+	}
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	flaky := &flakyUpgradeStore{fakeStore: db}
+	flaky.failures.Store(100)
+	cfg.dbQueries = flaky
+	user := db.addUser(t, "user@example.com")
+	admin := db.addAdmin(t, "admin@example.com")
+	sendUpgradeWebhook(t, cfg, user.ID.String())
+
+	cfg.webhooks.baseBackoff = 0
+	cfg.webhooks.maxAttempts = 3
+	for range 5 {
+		cfg.webhooks.runOnce(t.Context())
+	}
+	event := webhookEvents(t, db)[0]
+	if event.Status != webhookDead || event.Attempts != 3 {
+		t.Fatalf("event = %+v, want dead after 3 attempts", event)
+	}
+
+	// Dead events are listed for admins
+	req := httptest.NewRequest(http.MethodGet, "/admin/webhook_events?status=dead", nil)
+	req.Header.Set("Authorization", "Bearer "+makeTestToken(t, admin.ID))
+	rr := httptest.NewRecorder()
+	cfg.handlerGetWebhookEvents(rr, req)
+	var listed []WebhookEvent
+	json.NewDecoder(rr.Body).Decode(&listed)
+	if rr.Code != http.StatusOK || len(listed) != 1 || listed[0].ID != event.ID || !strings.Contains(listed[0].LastError, "connection reset") {
+		t.Errorf("dead letters = %v %+v, want the failed event", rr.Code, listed)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/webhook_events?status=lost", nil)
+	req.Header.Set("Authorization", "Bearer "+makeTestToken(t, admin.ID))
+	rr = httptest.NewRecorder()
+	cfg.handlerGetWebhookEvents(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown status: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	w := newWebhookWorker(nil, nil)
+	w.baseBackoff = time.Second
+	w.maxBackoff = 10 * time.Second
+	for attempts, want := range map[int32]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		4:  8 * time.Second,
+		5:  10 * time.Second,
+		60: 10 * time.Second,
+	} {
+		if got := w.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+// unstorableWebhookStore is a fakeStore that cannot persist deliveries.
+type unstorableWebhookStore struct {
+	*fakeStore
+}
+
+func (unstorableWebhookStore) CreateWebhookEvent(ctx context.Context, arg database.CreateWebhookEventParams) (database.WebhookEvent, error) {
+	return database.WebhookEvent{}, sql.ErrConnDone
+}
+
+func TestWebhookStoreFailureAsksForRetry(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	cfg.dbQueries = unstorableWebhookStore{db}
+	user := db.addUser(t, "user@example.com")
+
+	if rr := sendUpgradeWebhook(t, cfg, user.ID.String()); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %v want %v so Polka retries", rr.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestWebhookUserLookup(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	// An unknown user is final: Polka should not retry
+	if rr := sendUpgradeWebhook(t, cfg, uuid.NewString()); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown user: got status %v want %v", rr.Code, http.StatusNotFound)
+	}
+
+	// A database failure is not: Polka should retry
+	user := db.addUser(t, "user@example.com")
+	cfg.dbQueries = failingLookups{fakeStore: db, failing: "GetUserByID"}
+	if rr := sendUpgradeWebhook(t, cfg, user.ID.String()); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("failing lookup: got status %v want %v", rr.Code, http.StatusServiceUnavailable)
+	}
+
+	if events := webhookEvents(t, db); len(events) != 0 {
+		t.Errorf("expected neither delivery to be stored, got %+v", events)
+	}
+}
+
+func TestWebhookWorkerLifecycle(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := db.addUser(t, "user@example.com")
+	cfg.webhooks.pollInterval = time.Hour
+	cfg.webhooks.start()
+
+	// notify wakes the worker without waiting for the next poll
+	sendUpgradeWebhook(t, cfg, user.ID.String())
+	deadline := time.Now().Add(5 * time.Second)
+	for !isChirpyRed(t, db, user.ID) {
+		if time.Now().After(deadline) {
+			t.Fatal("worker did not process the event")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		cfg.webhooks.stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop did not return")
+	}
+}